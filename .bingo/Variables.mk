@@ -17,6 +17,12 @@ GO     ?= $(shell which go)
 #	@echo "Running bingo"
 #	@$(BINGO) <flags/args..>
 #
+APPLYCONFIGURATION_GEN := $(GOBIN)/applyconfiguration-gen-v0.30.2
+$(APPLYCONFIGURATION_GEN): $(BINGO_DIR)/applyconfiguration-gen.mod
+	@# Install binary/ries using Go 1.14+ build command. This is using bwplotka/bingo-controlled, separate go module with pinned dependencies.
+	@echo "(re)installing $(GOBIN)/applyconfiguration-gen-v0.30.2"
+	@cd $(BINGO_DIR) && GOWORK=off $(GO) build -mod=mod -modfile=applyconfiguration-gen.mod -o=$(GOBIN)/applyconfiguration-gen-v0.30.2 "k8s.io/code-generator/cmd/applyconfiguration-gen"
+
 BINGO := $(GOBIN)/bingo-v0.8.0
 $(BINGO): $(BINGO_DIR)/bingo.mod
 	@# Install binary/ries using Go 1.14+ build command. This is using bwplotka/bingo-controlled, separate go module with pinned dependencies.
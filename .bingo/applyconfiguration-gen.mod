@@ -0,0 +1,5 @@
+module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.22.0
+
+require k8s.io/code-generator v0.30.2 // cmd/applyconfiguration-gen
@@ -18,6 +18,7 @@ package v1alpha2
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type SourceType string
@@ -26,15 +27,19 @@ const (
 	SourceTypeImage      SourceType = "image"
 	SourceTypeGit        SourceType = "git"
 	SourceTypeConfigMaps SourceType = "configMaps"
+	SourceTypeSecrets    SourceType = "secrets"
 	SourceTypeHTTP       SourceType = "http"
+	SourceTypeInline     SourceType = "inline"
 
 	TypeUnpacked = "Unpacked"
 
-	ReasonUnpackPending             = "UnpackPending"
-	ReasonUnpacking                 = "Unpacking"
-	ReasonUnpackSuccessful          = "UnpackSuccessful"
-	ReasonUnpackFailed              = "UnpackFailed"
-	ReasonProcessingFinalizerFailed = "ProcessingFinalizerFailed"
+	ReasonUnpackPending               = "UnpackPending"
+	ReasonUnpacking                   = "Unpacking"
+	ReasonUnpackSuccessful            = "UnpackSuccessful"
+	ReasonUnpackFailed                = "UnpackFailed"
+	ReasonUnpackTransientError        = "UnpackTransientError"
+	ReasonSignatureVerificationFailed = "SignatureVerificationFailed"
+	ReasonProcessingFinalizerFailed   = "ProcessingFinalizerFailed"
 
 	PhasePending   = "Pending"
 	PhaseUnpacking = "Unpacking"
@@ -52,8 +57,19 @@ type BundleSource struct {
 	// ConfigMaps is a list of config map references and their relative
 	// directory paths that represent a bundle filesystem.
 	ConfigMaps []ConfigMapSource `json:"configMaps,omitempty"`
+	// Secrets is a list of secret references and their relative directory
+	// paths that represent a bundle filesystem, for bundles that contain
+	// sensitive values.
+	Secrets []SecretSource `json:"secrets,omitempty"`
 	//  HTTP is the remote location that backs the content of this Bundle.
 	HTTP *HTTPSource `json:"http,omitempty"`
+	// Inline is bundle content that is embedded in the BundleDeployment itself.
+	Inline *InlineSource `json:"inline,omitempty"`
+	// BundleDigest is the digest of the unpacked bundle content, computed from
+	// the sorted paths and content hashes of its files. It is only populated in
+	// status.resolvedSource, and is identical for any two sources that provide
+	// the same content. Setting it in spec.source is rejected.
+	BundleDigest string `json:"bundleDigest,omitempty"`
 }
 
 type ImageSource struct {
@@ -69,6 +85,19 @@ type ImageSource struct {
 	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
 	// CertificateData contains the PEM data of the certificate that is to be used for the TLS connection
 	CertificateData string `json:"certificateData,omitempty"`
+	// Proxy configures the proxy that requests for the image are sent through,
+	// instead of the proxy that the provisioner is configured with by its HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables.
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+	// NodeLocal reads the image from the containerd content store of the
+	// cluster nodes instead of pulling it from a registry, for images that
+	// were loaded onto the nodes in advance. It requires the node image
+	// server to be deployed.
+	// +optional
+	NodeLocal bool `json:"nodeLocal,omitempty"`
+	// PathFilters restricts which files of the image are kept in the unpacked bundle.
+	PathFilters `json:",inline"`
 }
 
 type GitSource struct {
@@ -85,6 +114,29 @@ type GitSource struct {
 	Ref GitRef `json:"ref"`
 	// Auth configures the authorization method if necessary.
 	Auth Authorization `json:"auth,omitempty"`
+	// Proxy configures the proxy that requests for the repository over http(s) are sent through,
+	// instead of the proxy that the provisioner is configured with by its HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables.
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+	// Retry configures how cloning the repository is retried.
+	Retry RetryPolicy `json:"retry,omitempty"`
+	// PathFilters restricts which files of the repository directory are kept in the unpacked bundle.
+	PathFilters `json:",inline"`
+	// Verify configures the verification of the signature of the checked out
+	// commit, or of the tag if Ref is an annotated tag.
+	Verify *GitVerification `json:"verify,omitempty"`
+}
+
+// GitVerification configures the verification of git signatures. A commit or
+// tag that is unsigned, or whose signature was not made by one of the allowed
+// keys, fails to unpack with the SignatureVerificationFailed reason.
+type GitVerification struct {
+	// Secret references a secret in the namespace that the provisioner is deployed in,
+	// which holds the keys that are allowed to sign. The secret is expected to contain
+	// `data.gpg-keys` with armored OpenPGP public keys, `data.ssh-allowed-signers` with
+	// SSH public keys in the allowed signers format of ssh-keygen, or both.
+	Secret corev1.LocalObjectReference `json:"secret"`
 }
 
 type ConfigMapSource struct {
@@ -95,11 +147,75 @@ type ConfigMapSource struct {
 	Path string `json:"path,omitempty"`
 }
 
+type SecretSource struct {
+	// Secret is a reference to a secret in the rukpak system namespace
+	Secret corev1.LocalObjectReference `json:"secret"`
+	// Path is the relative directory path within the bundle where the files
+	// from the secret will be present when the bundle is unpacked.
+	Path string `json:"path,omitempty"`
+}
+
 type HTTPSource struct {
 	// URL is where the bundle contents is.
 	URL string `json:"url"`
 	// Auth configures the authorization method if necessary.
 	Auth Authorization `json:"auth,omitempty"`
+	// CertificateData contains the PEM data of the certificate authorities that are trusted, in addition to the
+	// system ones, to verify the certificate of the server, e.g. of a private chart repository.
+	CertificateData string `json:"certificateData,omitempty"`
+	// Proxy configures the proxy that requests for the archive are sent through,
+	// instead of the proxy that the provisioner is configured with by its HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables.
+	// +optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+	// Retry configures how downloading the archive is retried.
+	Retry RetryPolicy `json:"retry,omitempty"`
+	// PathFilters restricts which files of the archive are kept in the unpacked bundle.
+	PathFilters `json:",inline"`
+}
+
+type InlineSource struct {
+	// Manifests is a list of plain manifests that make up the bundle. Each
+	// entry may contain multiple YAML documents.
+	Manifests []string `json:"manifests,omitempty"`
+	// Gzipped is a gzip-compressed stream of YAML documents that make up the
+	// bundle, for bundles that would otherwise not fit in the BundleDeployment.
+	// Its decompressed size is limited to 4MiB. Exactly one of Manifests and
+	// Gzipped must be set.
+	Gzipped []byte `json:"gzipped,omitempty"`
+}
+
+// RetryPolicy configures how fetching the content of a source is retried when
+// it fails with a transient error, such as a network failure or a server
+// error. Permanent errors, such as a missing repository or archive, are not
+// retried.
+type RetryPolicy struct {
+	//+kubebuilder:validation:Minimum:=1
+	//+kubebuilder:validation:Maximum:=10
+	//
+	// Attempts is the maximum number of attempts to fetch the content in a
+	// single unpack. Defaults to 1, which disables retries.
+	Attempts int32 `json:"attempts,omitempty"`
+	// Backoff is the delay before the first retry, which doubles with every
+	// subsequent retry up to 1m. Defaults to 1s, and may be at most 1m.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+	// Timeout limits the time of all attempts together. It may be at most 5m,
+	// which is also the limit if it is unset and more than one attempt is
+	// configured.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// PathFilters restricts the files that are kept when bundle content is unpacked.
+// Patterns are relative to the bundle root and use the syntax described in
+// https://pkg.go.dev/path#Match. A pattern that matches a directory also
+// matches every file within that directory.
+type PathFilters struct {
+	// IncludePaths is a list of patterns of the files to keep. If unset,
+	// all files are kept.
+	IncludePaths []string `json:"includePaths,omitempty"`
+	// ExcludePaths is a list of patterns of the files to drop. Exclusions
+	// take precedence over inclusions.
+	ExcludePaths []string `json:"excludePaths,omitempty"`
 }
 
 type GitRef struct {
@@ -114,13 +230,32 @@ type GitRef struct {
 	Commit string `json:"commit,omitempty"`
 }
 
+// ProxyConfig configures a proxy in the format of the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables. A ProxyConfig without any field set
+// sends requests directly, even if the provisioner is configured with a proxy.
+type ProxyConfig struct {
+	// HTTPProxy is the URL of the proxy for requests to http URLs.
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the URL of the proxy for requests to https URLs.
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy is a comma-separated list of host names, domains, IP addresses
+	// and CIDR ranges that are requested directly rather than through the proxy.
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
 type Authorization struct {
-	// Secret contains reference to the secret that has authorization information and is in the namespace that the provisioner is deployed.
-	// The secret is expected to contain `data.username` and `data.password` for the username and password, respectively for http(s) scheme.
+	// Secret contains reference to the secret that has authorization information and is in the namespace that the provisioner is deployed,
+	// unless Namespace is set. The secret is expected to contain `data.username` and `data.password` for the username and password, respectively for http(s) scheme.
 	// Refer to https://kubernetes.io/docs/concepts/configuration/secret/#basic-authentication-secret
+	// For the HTTPSource, the secret may instead contain `data.token` for a bearer token. It may also contain keys
+	// prefixed with `header.`, such as `data.header.X-Api-Key`, that set the request header named by the rest of the key.
 	// For the ssh authorization of the GitSource, the secret is expected to contain `data.ssh-privatekey` and `data.ssh-knownhosts` for the ssh privatekey and the host entry in the known_hosts file respectively.
 	// Refer to https://kubernetes.io/docs/concepts/configuration/secret/#ssh-authentication-secrets
 	Secret corev1.LocalObjectReference `json:"secret,omitempty"`
+	// Namespace is the namespace of the secret. It defaults to the namespace that the provisioner is deployed in.
+	// Secrets in other namespaces can only be referenced when a SecretReferenceGrant in their namespace allows it.
+	//+optional
+	Namespace string `json:"namespace,omitempty"`
 	// InsecureSkipVerify controls whether a client verifies the server's certificate chain and host name. If InsecureSkipVerify
 	// is true, the clone operation will accept any certificate presented by the server and any host name in that
 	// certificate. In this mode, TLS is susceptible to machine-in-the-middle attacks unless custom verification is
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha2
 
 import (
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -30,9 +31,35 @@ const (
 	TypeHasValidBundle = "HasValidBundle"
 	TypeHealthy        = "Healthy"
 	TypeInstalled      = "Installed"
+	// TypeContentServed is set to True once the unpacked bundle content was
+	// verified to be retrievable through the external address of the content
+	// server. status.contentURL is only published after that.
+	TypeContentServed = "ContentServed"
+	// TypeDeleting is set to True once a BundleDeployment is being deleted.
+	// Its reason names the cleanup step that the deletion waits for, and its
+	// message the error of that step, if any.
+	TypeDeleting = "Deleting"
+	// TypeTestsPassed reports the outcome of the last requested run of the
+	// test hooks of the release. It is removed when a new revision of the
+	// release is installed.
+	TypeTestsPassed = "TestsPassed"
+	// TypeRollbackPerformed is set to True when an upgrade was rolled back
+	// because an analysis query was breached during the soak period.
+	TypeRollbackPerformed = "RollbackPerformed"
+	// TypeUpgradePending is set to True while the content of a new source is
+	// unpacked for a BundleDeployment that already has a version installed.
+	TypeUpgradePending = "UpgradePending"
 
 	ReasonBundleLoadFailed          = "BundleLoadFailed"
+	ReasonBundleTooLarge            = "BundleTooLarge"
+	ReasonContentNotRetrievable     = "ContentNotRetrievable"
+	ReasonContentRetrievable        = "ContentRetrievable"
 	ReasonCreateDynamicWatchFailed  = "CreateDynamicWatchFailed"
+	ReasonCRDsNotEstablished        = "CRDsNotEstablished"
+	ReasonCRDValidationFailed       = "CRDValidationFailed"
+	ReasonDegraded                  = "Degraded"
+	ReasonAnalysisBreached          = "AnalysisBreached"
+	ReasonApplyPending              = "ApplyPending"
 	ReasonErrorGettingClient        = "ErrorGettingClient"
 	ReasonErrorGettingReleaseState  = "ErrorGettingReleaseState"
 	ReasonHealthy                   = "Healthy"
@@ -40,13 +67,52 @@ const (
 	ReasonInstallationStatusUnknown = "InstallationStatusUnknown"
 	ReasonInstallationSucceeded     = "InstallationSucceeded"
 	ReasonInstallFailed             = "InstallFailed"
+	ReasonInstallNamespaceNotFound  = "InstallNamespaceNotFound"
 	ReasonObjectLookupFailure       = "ObjectLookupFailure"
+	ReasonProgressing               = "Progressing"
 	ReasonReadingContentFailed      = "ReadingContentFailed"
 	ReasonReconcileFailed           = "ReconcileFailed"
-	ReasonUnhealthy                 = "Unhealthy"
+	ReasonReleaseUninstalling       = "ReleaseUninstalling"
+	ReasonRequirementsNotMet        = "RequirementsNotMet"
+	ReasonRollbackFailed            = "RollbackFailed"
+	ReasonStoragePurging            = "StoragePurging"
+	ReasonStorageUnavailable        = "StorageUnavailable"
+	ReasonTestsFailed               = "TestsFailed"
+	ReasonTestsSucceeded            = "TestsSucceeded"
+	ReasonUnpackCachePurging        = "UnpackCachePurging"
+	ReasonUpgradeBlocked            = "UpgradeBlocked"
 	ReasonUpgradeFailed             = "UpgradeFailed"
+	ReasonUpgrading                 = "Upgrading"
+
+	// Deprecated: the Healthy condition uses ReasonDegraded for unhealthy
+	// objects and ReasonProgressing for objects that are still rolling out.
+	ReasonUnhealthy = "Unhealthy"
 )
 
+// TestRequestedAnnotation requests a run of the test hooks of the release of
+// a BundleDeployment when it is set to a value that the test hooks have not
+// been run for yet, e.g. the current time.
+const TestRequestedAnnotation = "core.rukpak.io/test-requested-at"
+
+// SkipFinalizerCleanupAnnotation, when set to "true" on a BundleDeployment
+// that is being deleted, removes the finalizers that purge its stored bundle
+// content and unpack cache without running them. It unsticks deletions whose
+// storage backend is gone for good, and may leave content behind otherwise.
+// Setting it requires the skip-finalizer-cleanup verb on the
+// BundleDeployment.
+const SkipFinalizerCleanupAnnotation = "core.rukpak.io/skip-finalizer-cleanup"
+
+// ResourcePolicyAnnotation, when set to ResourcePolicyKeep on an object of a
+// bundle, keeps the object when an upgrade removes it from the bundle and
+// when the BundleDeployment is deleted, like the helm.sh/resource-policy
+// annotation of Helm, which is honored as well. Kept objects are no longer
+// managed by the provisioner once they are left behind.
+const ResourcePolicyAnnotation = "core.rukpak.io/resource-policy"
+
+// ResourcePolicyKeep is the value of ResourcePolicyAnnotation that keeps an
+// object.
+const ResourcePolicyKeep = "keep"
+
 // BundleDeploymentSpec defines the desired state of BundleDeployment
 type BundleDeploymentSpec struct {
 	//+kubebuilder:validation:Pattern:=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
@@ -54,14 +120,29 @@ type BundleDeploymentSpec struct {
 	//
 	// installNamespace is the namespace where the bundle should be installed. However, note that
 	// the bundle may contain resources that are cluster-scoped or that are
-	// installed in a different namespace. This namespace is expected to exist.
+	// installed in a different namespace. This namespace is expected to exist,
+	// unless installNamespacePolicy is CreateIfMissing.
 	InstallNamespace string `json:"installNamespace"`
 
+	//+kubebuilder:Optional
+	//+kubebuilder:validation:Enum:=MustExist;CreateIfMissing
+	// InstallNamespacePolicy defines what happens when the install namespace
+	// does not exist. Defaults to MustExist.
+	InstallNamespacePolicy InstallNamespacePolicy `json:"installNamespacePolicy,omitempty"`
+
 	//+kubebuilder:validation:Pattern:=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$
 	//
 	// provisionerClassName sets the name of the provisioner that should reconcile this BundleDeployment.
 	ProvisionerClassName string `json:"provisionerClassName"`
 
+	//+kubebuilder:Optional
+	// Format is the format of the bundle: plain+v0, registry+v1 or helm+v3.
+	// The auto provisioner converts the bundle with the handler of the
+	// format, rather than detecting the format from the layout of the
+	// bundle. The other provisioners support a single format, which the
+	// format must match if it is set.
+	Format BundleFormat `json:"format,omitempty"`
+
 	// source defines the configuration for the underlying Bundle content.
 	Source BundleSource `json:"source"`
 
@@ -73,6 +154,165 @@ type BundleDeploymentSpec struct {
 	//+kubebuilder:Optional
 	// Preflight defines the configuration of preflight checks.
 	Preflight *PreflightConfig `json:"preflight,omitempty"`
+
+	//+kubebuilder:Optional
+	// Analysis defines queries that are evaluated after an upgrade to decide
+	// whether the upgrade must be rolled back.
+	Analysis *AnalysisConfig `json:"analysis,omitempty"`
+
+	//+kubebuilder:Optional
+	// VersionPolicy restricts upgrades based on the versions of the installed
+	// and the new bundle.
+	VersionPolicy *VersionPolicy `json:"versionPolicy,omitempty"`
+
+	//+kubebuilder:Optional
+	//+kubebuilder:validation:Enum:=Background;Foreground;Force
+	// UninstallPolicy defines how the objects of the release are deleted when
+	// the BundleDeployment is deleted. Defaults to Background.
+	UninstallPolicy UninstallPolicy `json:"uninstallPolicy,omitempty"`
+
+	//+kubebuilder:Optional
+	// Labels are added to the labels of every object of the bundle, e.g. to
+	// attribute the objects to a team or cost center. They take precedence
+	// over the labels that the bundle sets.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	//+kubebuilder:Optional
+	// Annotations are added to the annotations of every object of the
+	// bundle. They take precedence over the annotations that the bundle sets.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	//+kubebuilder:Optional
+	// Target is the cluster that the objects of the bundle are applied to.
+	// The bundle is unpacked and rendered in the cluster of the
+	// BundleDeployment either way, and its Helm release is stored there.
+	// Defaults to the cluster of the BundleDeployment.
+	Target *Target `json:"target,omitempty"`
+}
+
+// Target is a remote cluster, such as a workload cluster that is managed by
+// Cluster API, that the objects of a BundleDeployment are applied to.
+type Target struct {
+	// KubeconfigSecretRef references the secret with the kubeconfig that the
+	// provisioner connects to the cluster with. The secret must be in the
+	// namespace that the provisioner is deployed in.
+	KubeconfigSecretRef KubeconfigSecretReference `json:"kubeconfigSecretRef"`
+}
+
+// KubeconfigSecretReference references a key of a secret with a kubeconfig.
+type KubeconfigSecretReference struct {
+	//+kubebuilder:validation:MinLength:=1
+	// Name is the name of the secret.
+	Name string `json:"name"`
+
+	//+kubebuilder:Optional
+	// Key is the key of the kubeconfig in the data of the secret. Defaults
+	// to value, the key of the kubeconfig secrets of Cluster API.
+	Key string `json:"key,omitempty"`
+}
+
+// BundleFormat is the format of a bundle.
+type BundleFormat string
+
+const (
+	FormatPlain    BundleFormat = "plain+v0"
+	FormatRegistry BundleFormat = "registry+v1"
+	FormatHelm     BundleFormat = "helm+v3"
+)
+
+// SupportedFormats are the bundle formats that the provisioners support.
+var SupportedFormats = []BundleFormat{FormatPlain, FormatRegistry, FormatHelm}
+
+// InstallNamespacePolicy defines what happens when the install namespace of a
+// BundleDeployment does not exist.
+type InstallNamespacePolicy string
+
+const (
+	// InstallNamespacePolicyMustExist waits for the install namespace to be
+	// created, and reports the Installed condition with the
+	// InstallNamespaceNotFound reason meanwhile.
+	InstallNamespacePolicyMustExist InstallNamespacePolicy = "MustExist"
+	// InstallNamespacePolicyCreateIfMissing makes the provisioner create the
+	// install namespace. The namespace is not part of the release, and is
+	// kept when the BundleDeployment is deleted.
+	InstallNamespacePolicyCreateIfMissing InstallNamespacePolicy = "CreateIfMissing"
+)
+
+// UninstallPolicy defines how the objects of the release of a deleted
+// BundleDeployment are deleted.
+type UninstallPolicy string
+
+const (
+	// UninstallPolicyBackground leaves the objects of the release to the
+	// garbage collector, which deletes them after the BundleDeployment is
+	// gone.
+	UninstallPolicyBackground UninstallPolicy = "Background"
+	// UninstallPolicyForeground makes the provisioner delete the objects of
+	// the release, and keeps the BundleDeployment until they are gone.
+	UninstallPolicyForeground UninstallPolicy = "Foreground"
+	// UninstallPolicyForce makes the provisioner delete the objects of the
+	// release without waiting for them to be gone, and skip the objects whose
+	// API is not served anymore. It is meant for BundleDeployments whose
+	// deletion is stuck, e.g. because an API group of the release was
+	// removed.
+	UninstallPolicyForce UninstallPolicy = "Force"
+)
+
+// VersionPolicy restricts the transitions between bundle versions. It only
+// applies to bundles that declare a semantic version, such as the version of
+// a Helm chart or of a ClusterServiceVersion. Blocked upgrades are reported
+// by the Installed condition with the UpgradeBlocked reason, and the
+// installed bundle remains in place.
+type VersionPolicy struct {
+	//+kubebuilder:Optional
+	// AllowDowngrades permits upgrades to a lower version.
+	AllowDowngrades bool `json:"allowDowngrades,omitempty"`
+
+	//+kubebuilder:Optional
+	// AllowMajorVersionSkips permits upgrades that skip a major version, e.g.
+	// from 1.x to 3.x.
+	AllowMajorVersionSkips bool `json:"allowMajorVersionSkips,omitempty"`
+
+	//+kubebuilder:Optional
+	// ForceVersion permits the upgrade to this version regardless of the
+	// policy.
+	ForceVersion string `json:"forceVersion,omitempty"`
+}
+
+// AnalysisConfig holds the configuration for the analysis that runs after an
+// upgrade. For the duration of the soak period, the queries are evaluated
+// periodically against a Prometheus endpoint. If any query returns a result,
+// the upgrade is rolled back to the previous release and the RollbackPerformed
+// condition is set. The rolled back release is kept until the spec of the
+// BundleDeployment changes.
+type AnalysisConfig struct {
+	//+kubebuilder:validation:Pattern:=`^https?://`
+	//
+	// PrometheusURL is the base URL of the Prometheus HTTP API, e.g.
+	// https://prometheus-k8s.monitoring.svc:9091.
+	PrometheusURL string `json:"prometheusURL"`
+
+	// SoakPeriod is how long after an upgrade the queries are evaluated.
+	SoakPeriod metav1.Duration `json:"soakPeriod"`
+
+	//+kubebuilder:Optional
+	// Interval is how often the queries are evaluated during the soak period.
+	// Defaults to 30s.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	//+kubebuilder:validation:MinItems:=1
+	//
+	// Queries are the PromQL queries that detect a breach. Like the expression
+	// of an alerting rule, a query is breached when it returns any samples.
+	Queries []AnalysisQuery `json:"queries"`
+}
+
+// AnalysisQuery is a PromQL query that detects a breach.
+type AnalysisQuery struct {
+	// Name identifies the query in conditions.
+	Name string `json:"name"`
+	// Expr is the PromQL expression to evaluate.
+	Expr string `json:"expr"`
 }
 
 // PreflightConfig holds the configuration for the preflight checks.
@@ -95,8 +335,178 @@ type BundleDeploymentStatus struct {
 	ResolvedSource     *BundleSource      `json:"resolvedSource,omitempty"`
 	ContentURL         string             `json:"contentURL,omitempty"`
 	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+
+	// ObjectApplyResults contains the per-object outcomes of the most recent
+	// apply of the release. Failed objects are retried on the next reconcile
+	// before the rest of the release is reconciled again, and the objects that
+	// are not retried are recorded as skipped until then. This includes the
+	// objects of a failed install or upgrade, whose release is deployed once
+	// its failed objects are applied rather than installed or upgraded again.
+	ObjectApplyResults []ObjectApplyResult `json:"objectApplyResults,omitempty"`
+	// ReconcileContinuation records where the object-level reconcile of an
+	// already installed release stopped after it exceeded its time budget.
+	// The next reconcile continues from there rather than starting over, so
+	// that large releases are reconciled over several shorter reconciles.
+	// Only reconciles of an unchanged release are budgeted. Installs and
+	// upgrades apply every object of the release in a single Helm action that
+	// is not interrupted, and neither are retries of objects that failed.
+	ReconcileContinuation *ReconcileContinuation `json:"reconcileContinuation,omitempty"`
+	// BundleMetadata describes the currently installed bundle, as declared by
+	// the bundle content. It is only populated for bundle formats that carry
+	// such metadata.
+	BundleMetadata *BundleMetadata `json:"bundleMetadata,omitempty"`
+	// TestRequest is the value of the core.rukpak.io/test-requested-at
+	// annotation that the test hooks of the release were last run for.
+	TestRequest string `json:"testRequest,omitempty"`
+	// GeneratedRBAC lists the ServiceAccounts and RBAC objects of the
+	// installed release that were generated for this BundleDeployment alone,
+	// such as by the registry provisioner when its generateRBAC config is set.
+	GeneratedRBAC []RBACObjectReference `json:"generatedRBAC,omitempty"`
+	// ContentSize is the total size in bytes of the files of the stored
+	// bundle content.
+	ContentSize int64 `json:"contentSize,omitempty"`
+	// LastFailure describes the failure of the most recent reconcile in a
+	// machine-readable form, so that automation does not need to parse the
+	// messages of the conditions. It is cleared by the next reconcile that
+	// succeeds.
+	LastFailure *Failure `json:"lastFailure,omitempty"`
+	// NextReconcileTime is when the controller retries the reconcile of the
+	// BundleDeployment after the most recent reconcile failed. Failed
+	// reconciles are retried with exponential backoff. It is cleared by the
+	// next reconcile that succeeds, and is not set for failures that are not
+	// retried.
+	NextReconcileTime *metav1.Time `json:"nextReconcileTime,omitempty"`
+	// PrunedObjects lists the objects that the most recent upgrade deleted
+	// because the bundle has no objects of their kind anymore, although
+	// Helm did not delete them, e.g. because they were left behind by an
+	// earlier upgrade that failed. It is replaced by the next upgrade.
+	PrunedObjects []ObjectReference `json:"prunedObjects,omitempty"`
+	// SourceHash identifies the spec.source that resolvedSource was resolved
+	// from, so that changes of other fields of the spec are not mistaken for
+	// a change of the source.
+	SourceHash string `json:"sourceHash,omitempty"`
+}
+
+type FailurePhase string
+
+const (
+	FailurePhaseUnpack    FailurePhase = "Unpack"
+	FailurePhaseInstall   FailurePhase = "Install"
+	FailurePhaseUninstall FailurePhase = "Uninstall"
+	FailurePhaseReconcile FailurePhase = "Reconcile"
+)
+
+// Failure is a failed reconcile of a BundleDeployment.
+type Failure struct {
+	// Reason classifies the failure. It is the reason of the condition that
+	// reports the failure, such as UnpackFailed or UpgradeFailed.
+	Reason string `json:"reason"`
+	//+kubebuilder:validation:Enum:=Unpack;Install;Uninstall;Reconcile
+	//
+	// Phase is the phase of the reconcile that failed: Unpack for the source,
+	// Install for the install or upgrade of the release, Uninstall for the
+	// deletion of the BundleDeployment, and Reconcile for anything else.
+	Phase FailurePhase `json:"phase"`
+	// ConditionType is the type of the condition that reports the failure,
+	// whose message describes it.
+	ConditionType string `json:"conditionType,omitempty"`
+	// Object references the object of the bundle that failed to apply, if
+	// the failure is caused by a single object.
+	Object *ObjectReference `json:"object,omitempty"`
+	// Time is when the failure last occurred.
+	Time metav1.Time `json:"time"`
+	// RetryCount is the number of consecutive reconciles that failed with
+	// the same reason in the same phase, less one.
+	RetryCount int32 `json:"retryCount"`
 }
 
+// ObjectReference references an object of a bundle.
+type ObjectReference struct {
+	// APIVersion is the API version of the object.
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the object.
+	Kind string `json:"kind"`
+	// Namespace is the namespace of the object, empty for cluster-scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the object.
+	Name string `json:"name"`
+}
+
+// RBACObjectReference identifies a ServiceAccount or RBAC object.
+type RBACObjectReference struct {
+	// Kind is the kind of the object, e.g. ServiceAccount or ClusterRole.
+	Kind string `json:"kind"`
+	// Namespace is the namespace of the object, empty for cluster-scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the object.
+	Name string `json:"name"`
+}
+
+// BundleMetadata describes an installed bundle.
+type BundleMetadata struct {
+	// PackageName is the name of the package that the bundle belongs to.
+	PackageName string `json:"packageName,omitempty"`
+	// Version is the version of the bundle.
+	Version string `json:"version,omitempty"`
+	// ProvidedAPIs is the list of APIs that the bundle provides.
+	ProvidedAPIs []metav1.GroupVersionKind `json:"providedAPIs,omitempty"`
+	// Channels are the channels of the package that the bundle is in.
+	Channels []string `json:"channels,omitempty"`
+	// DefaultChannel is the channel of the package that is installed if no
+	// other channel is chosen.
+	DefaultChannel string `json:"defaultChannel,omitempty"`
+	// RelatedImages are the images that the bundle runs or references, e.g.
+	// for mirroring them into a disconnected registry.
+	RelatedImages []RelatedImage `json:"relatedImages,omitempty"`
+}
+
+// RelatedImage is an image that a bundle runs or references.
+type RelatedImage struct {
+	// Name is the name that the bundle gives the image, if any.
+	Name string `json:"name,omitempty"`
+	// Image is the reference of the image.
+	Image string `json:"image"`
+}
+
+// ReconcileContinuation is the position at which an interrupted object-level
+// reconcile continues.
+type ReconcileContinuation struct {
+	// Revision is the release revision whose objects are being reconciled.
+	Revision int `json:"revision"`
+	// NextObject is the index of the next object of the release manifest to
+	// reconcile.
+	NextObject int `json:"nextObject"`
+}
+
+type ObjectApplyResultType string
+
+const (
+	ObjectApplyResultCreated ObjectApplyResultType = "Created"
+	ObjectApplyResultUpdated ObjectApplyResultType = "Updated"
+	ObjectApplyResultFailed  ObjectApplyResultType = "Failed"
+	ObjectApplyResultSkipped ObjectApplyResultType = "Skipped"
+)
+
+// ObjectApplyResult is the outcome of applying a single object of a bundle.
+type ObjectApplyResult struct {
+	// APIVersion is the API version of the object.
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the object.
+	Kind string `json:"kind"`
+	// Namespace is the namespace of the object, empty for cluster-scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the object.
+	Name string `json:"name"`
+	//+kubebuilder:validation:Enum:=Created;Updated;Failed;Skipped
+	//
+	// Result is the outcome of applying the object.
+	Result ObjectApplyResultType `json:"result"`
+	// Message contains the reason the object failed to apply or was skipped.
+	Message string `json:"message,omitempty"`
+}
+
+//+genclient
+//+genclient:nonNamespaced
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster,shortName={"bd","bds"}
@@ -117,6 +527,25 @@ func (b *BundleDeployment) ProvisionerClassName() string {
 	return b.Spec.ProvisionerClassName
 }
 
+// CurrentCondition returns the condition of the given type if it was observed
+// for the current generation of the BundleDeployment, or nil if it is not set
+// or stale.
+func (b *BundleDeployment) CurrentCondition(conditionType string) *metav1.Condition {
+	cond := meta.FindStatusCondition(b.Status.Conditions, conditionType)
+	if cond == nil || cond.ObservedGeneration != b.Generation {
+		return nil
+	}
+	return cond
+}
+
+// IsConditionStale reports whether the condition of the given type is set,
+// but was observed for an earlier generation of the BundleDeployment, i.e.
+// it does not reflect the latest changes of the spec yet.
+func (b *BundleDeployment) IsConditionStale(conditionType string) bool {
+	cond := meta.FindStatusCondition(b.Status.Conditions, conditionType)
+	return cond != nil && cond.ObservedGeneration < b.Generation
+}
+
 //+kubebuilder:object:root=true
 
 // BundleDeploymentList contains a list of BundleDeployment
@@ -0,0 +1,156 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	BundleDeploymentSetGVK  = SchemeBuilder.GroupVersion.WithKind("BundleDeploymentSet")
+	BundleDeploymentSetKind = BundleDeploymentSetGVK.Kind
+)
+
+const (
+	// BundleDeploymentSetLabel is set on the BundleDeployments of a
+	// BundleDeploymentSet to the name of the set.
+	BundleDeploymentSetLabel = "core.rukpak.io/bundledeploymentset"
+	// TargetClusterLabel is set on the BundleDeployments of a
+	// BundleDeploymentSet to the name of the kubeconfig secret of the
+	// cluster that they target.
+	TargetClusterLabel = "core.rukpak.io/target-cluster"
+
+	// TypeRolledOut is set to True on a BundleDeploymentSet once the
+	// BundleDeployments of all selected clusters are installed.
+	TypeRolledOut = "RolledOut"
+
+	ReasonNoClustersSelected = "NoClustersSelected"
+	ReasonRolloutFailed      = "RolloutFailed"
+	ReasonRolloutProgressing = "RolloutProgressing"
+	ReasonRolloutSucceeded   = "RolloutSucceeded"
+)
+
+// BundleDeploymentSetSpec defines the BundleDeployments of a
+// BundleDeploymentSet.
+type BundleDeploymentSetSpec struct {
+	// ClusterSelector selects the clusters that the bundle is deployed to by
+	// the labels of their kubeconfig secrets. The kubeconfig secrets of all
+	// clusters are in the namespace that the provisioner is deployed in, and
+	// every selected secret is a cluster that gets a BundleDeployment.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	//+kubebuilder:Optional
+	// KubeconfigKey is the key of the kubeconfig in the data of the selected
+	// secrets. Defaults to value, the key of the kubeconfig secrets of
+	// Cluster API.
+	KubeconfigKey string `json:"kubeconfigKey,omitempty"`
+
+	// Template is the spec of the BundleDeployments. Their target is set to
+	// the cluster of each BundleDeployment, so the template must not set
+	// one.
+	Template BundleDeploymentSpec `json:"template"`
+
+	//+kubebuilder:Optional
+	// Overlays change the config of the BundleDeployments of some clusters,
+	// e.g. to configure the bundle for the region of a cluster. The overlays
+	// that select a cluster are applied in order.
+	Overlays []ClusterOverlay `json:"overlays,omitempty"`
+}
+
+// ClusterOverlay changes the config of the BundleDeployments of the clusters
+// that it selects.
+type ClusterOverlay struct {
+	// ClusterSelector selects the clusters that the overlay applies to by
+	// the labels of their kubeconfig secrets. Selecting a single cluster by
+	// its name takes a label that names the cluster, such as the
+	// cluster.x-k8s.io/cluster-name label of Cluster API.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	//+kubebuilder:pruning:PreserveUnknownFields
+	//
+	// Config is merged into the config of the template as a JSON merge
+	// patch, so null removes a key of the template.
+	Config runtime.RawExtension `json:"config,omitempty"`
+}
+
+// BundleDeploymentSetStatus defines the observed state of
+// BundleDeploymentSet
+type BundleDeploymentSetStatus struct {
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+
+	// Clusters is the number of selected clusters.
+	Clusters int32 `json:"clusters,omitempty"`
+	// InstalledClusters is the number of selected clusters whose
+	// BundleDeployment has installed the current template.
+	InstalledClusters int32 `json:"installedClusters,omitempty"`
+	// ClusterStatuses is the rollout status of every selected cluster.
+	ClusterStatuses []ClusterRolloutStatus `json:"clusterStatuses,omitempty"`
+}
+
+// ClusterRolloutStatus is the rollout status of a BundleDeploymentSet on a
+// cluster.
+type ClusterRolloutStatus struct {
+	// Cluster is the name of the kubeconfig secret of the cluster.
+	Cluster string `json:"cluster"`
+	// BundleDeployment is the name of the BundleDeployment of the cluster.
+	BundleDeployment string `json:"bundleDeployment"`
+	// Installed is the status of the Installed condition of the
+	// BundleDeployment. It is Unknown until the BundleDeployment was
+	// reconciled since its spec last changed.
+	Installed metav1.ConditionStatus `json:"installed"`
+	// Reason and Message are the reason and message of the Installed
+	// condition, or why the BundleDeployment could not be created or
+	// updated.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+//+genclient
+//+genclient:nonNamespaced
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName={"bdset","bdsets"}
+//+kubebuilder:printcolumn:name=Clusters,type=integer,JSONPath=`.status.clusters`
+//+kubebuilder:printcolumn:name=Installed,type=integer,JSONPath=`.status.installedClusters`
+//+kubebuilder:printcolumn:name="Rollout State",type=string,JSONPath=`.status.conditions[?(.type=="RolledOut")].reason`
+//+kubebuilder:printcolumn:name=Age,type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BundleDeploymentSet deploys a bundle to a fleet of clusters. It creates a
+// BundleDeployment from its template for every cluster that it selects,
+// which targets the cluster, and aggregates the status of their rollouts.
+type BundleDeploymentSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BundleDeploymentSetSpec   `json:"spec"`
+	Status BundleDeploymentSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BundleDeploymentSetList contains a list of BundleDeploymentSet
+type BundleDeploymentSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BundleDeploymentSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BundleDeploymentSet{}, &BundleDeploymentSetList{})
+}
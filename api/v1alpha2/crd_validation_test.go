@@ -0,0 +1,166 @@
+package v1alpha2_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+const crdDir = "../../manifests/base/apis/crds"
+
+// loadValidator returns a validator for the schema of the CRD in crdFile
+// after the kustomize JSON patches in patchFile are applied to it, like the
+// CRD that is installed.
+func loadValidator(t *testing.T, crdFile, patchFile string) validation.SchemaValidator {
+	t.Helper()
+
+	crdYAML, err := os.ReadFile(filepath.Join(crdDir, crdFile))
+	require.NoError(t, err)
+	crdJSON, err := yaml.YAMLToJSON(crdYAML)
+	require.NoError(t, err)
+
+	patchYAML, err := os.ReadFile(filepath.Join(crdDir, "patches", patchFile))
+	require.NoError(t, err)
+	patchJSON, err := yaml.YAMLToJSON(patchYAML)
+	require.NoError(t, err)
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	require.NoError(t, err)
+	crdJSON, err = patch.Apply(crdJSON)
+	require.NoError(t, err)
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	require.NoError(t, yaml.Unmarshal(crdJSON, crd))
+	require.Len(t, crd.Spec.Versions, 1)
+
+	converted := &apiextensions.CustomResourceValidation{}
+	require.NoError(t, apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(crd.Spec.Versions[0].Schema, converted, nil))
+	validator, _, err := validation.NewSchemaValidator(converted.OpenAPIV3Schema)
+	require.NoError(t, err)
+	return validator
+}
+
+func validate(t *testing.T, validator validation.SchemaValidator, obj runtime.Object) error {
+	t.Helper()
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	dropNulls(u)
+	return validation.ValidateCustomResource(field.NewPath(""), u, validator).ToAggregate()
+}
+
+// dropNulls removes the null fields of u, like the API server does for fields
+// that are not nullable before it validates an object.
+func dropNulls(u map[string]interface{}) {
+	for k, v := range u {
+		switch v := v.(type) {
+		case nil:
+			delete(u, k)
+		case map[string]interface{}:
+			dropNulls(v)
+		}
+	}
+}
+
+// sources returns a valid source of every source type.
+func sources() map[rukpakv1alpha2.SourceType]rukpakv1alpha2.BundleSource {
+	return map[rukpakv1alpha2.SourceType]rukpakv1alpha2.BundleSource{
+		rukpakv1alpha2.SourceTypeImage: {
+			Type:  rukpakv1alpha2.SourceTypeImage,
+			Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/operatorhubio/prometheus:v0.47.0"},
+		},
+		rukpakv1alpha2.SourceTypeGit: {
+			Type: rukpakv1alpha2.SourceTypeGit,
+			Git: &rukpakv1alpha2.GitSource{
+				Repository: "https://github.com/exdx/combo-bundle",
+				Ref:        rukpakv1alpha2.GitRef{Tag: "v0.0.1"},
+			},
+		},
+		rukpakv1alpha2.SourceTypeConfigMaps: {
+			Type: rukpakv1alpha2.SourceTypeConfigMaps,
+			ConfigMaps: []rukpakv1alpha2.ConfigMapSource{{
+				ConfigMap: corev1.LocalObjectReference{Name: "combo"},
+			}},
+		},
+		rukpakv1alpha2.SourceTypeSecrets: {
+			Type: rukpakv1alpha2.SourceTypeSecrets,
+			Secrets: []rukpakv1alpha2.SecretSource{{
+				Secret: corev1.LocalObjectReference{Name: "combo"},
+			}},
+		},
+		rukpakv1alpha2.SourceTypeHTTP: {
+			Type: rukpakv1alpha2.SourceTypeHTTP,
+			HTTP: &rukpakv1alpha2.HTTPSource{URL: "https://example.com/bundle.tgz"},
+		},
+		rukpakv1alpha2.SourceTypeInline: {
+			Type: rukpakv1alpha2.SourceTypeInline,
+			Inline: &rukpakv1alpha2.InlineSource{
+				Manifests: []string{"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: combo\n"},
+			},
+		},
+	}
+}
+
+func TestBundleDeploymentSourceValidation(t *testing.T) {
+	validator := loadValidator(t, "core.rukpak.io_bundledeployments.yaml", "bundledeployment_validation.yaml")
+
+	for sourceType, source := range sources() {
+		t.Run(string(sourceType), func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha2.GroupVersion.String(), Kind: "BundleDeployment"},
+				ObjectMeta: metav1.ObjectMeta{Name: "combo"},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "default",
+					ProvisionerClassName: "core-rukpak-io-plain",
+					Source:               source,
+				},
+			}
+			require.NoError(t, validate(t, validator, bd))
+		})
+	}
+
+	t.Run("no source", func(t *testing.T) {
+		bd := &rukpakv1alpha2.BundleDeployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha2.GroupVersion.String(), Kind: "BundleDeployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "combo"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{
+				InstallNamespace:     "default",
+				ProvisionerClassName: "core-rukpak-io-plain",
+				Source:               rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeInline},
+			},
+		}
+		require.ErrorContains(t, validate(t, validator, bd), "must validate one and only one schema (oneOf). Found none valid")
+	})
+}
+
+func TestBundleDeploymentSetSourceValidation(t *testing.T) {
+	validator := loadValidator(t, "core.rukpak.io_bundledeploymentsets.yaml", "bundledeploymentset_validation.yaml")
+
+	for sourceType, source := range sources() {
+		t.Run(string(sourceType), func(t *testing.T) {
+			set := &rukpakv1alpha2.BundleDeploymentSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rukpakv1alpha2.GroupVersion.String(), Kind: "BundleDeploymentSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "combo"},
+				Spec: rukpakv1alpha2.BundleDeploymentSetSpec{
+					Template: rukpakv1alpha2.BundleDeploymentSpec{
+						InstallNamespace:     "default",
+						ProvisionerClassName: "core-rukpak-io-plain",
+						Source:               source,
+					},
+				},
+			}
+			require.NoError(t, validate(t, validator, set))
+		})
+	}
+}
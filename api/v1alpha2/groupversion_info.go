@@ -14,9 +14,6 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package v1alpha2 contains API Schema definitions for the core v1alpha2 API group
-// +kubebuilder:object:generate=true
-// +groupName=core.rukpak.io
 package v1alpha2
 
 import (
@@ -28,6 +25,10 @@ var (
 	// GroupVersion is group version used to register these objects
 	GroupVersion = schema.GroupVersion{Group: "core.rukpak.io", Version: "v1alpha2"}
 
+	// SchemeGroupVersion is the name of GroupVersion that generated apply
+	// configurations refer to.
+	SchemeGroupVersion = GroupVersion
+
 	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
 	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
 
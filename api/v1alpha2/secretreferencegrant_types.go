@@ -0,0 +1,77 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretReferenceGrantSpec defines which BundleDeployments may reference
+// which secrets in the namespace of the grant.
+type SecretReferenceGrantSpec struct {
+	// BundleDeployments are the names of the BundleDeployments that may
+	// reference the secrets of the grant in .spec.source.git.auth or
+	// .spec.source.http.auth.
+	//+kubebuilder:validation:MinItems:=1
+	BundleDeployments []string `json:"bundleDeployments"`
+	// Secrets are the names of the secrets that may be referenced. All
+	// secrets in the namespace of the grant may be referenced when it is
+	// empty.
+	//+optional
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+//+genclient
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Namespaced
+//+kubebuilder:printcolumn:name=Age,type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecretReferenceGrant allows BundleDeployments to reference auth secrets in
+// its namespace. Secrets in namespaces other than the namespace that the
+// provisioner is deployed in can only be referenced when a grant in their
+// namespace allows it, so that the owners of a namespace decide which of its
+// secrets the provisioner may read on behalf of a BundleDeployment.
+type SecretReferenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SecretReferenceGrantSpec `json:"spec"`
+}
+
+// Allows reports whether the grant allows the BundleDeployment with the given
+// name to reference the secret with the given name.
+func (g *SecretReferenceGrant) Allows(bundleDeployment, secret string) bool {
+	if !slices.Contains(g.Spec.BundleDeployments, bundleDeployment) {
+		return false
+	}
+	return len(g.Spec.Secrets) == 0 || slices.Contains(g.Spec.Secrets, secret)
+}
+
+//+kubebuilder:object:root=true
+
+// SecretReferenceGrantList contains a list of SecretReferenceGrant
+type SecretReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretReferenceGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretReferenceGrant{}, &SecretReferenceGrantList{})
+}
@@ -25,6 +25,47 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisConfig) DeepCopyInto(out *AnalysisConfig) {
+	*out = *in
+	out.SoakPeriod = in.SoakPeriod
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Queries != nil {
+		in, out := &in.Queries, &out.Queries
+		*out = make([]AnalysisQuery, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisConfig.
+func (in *AnalysisConfig) DeepCopy() *AnalysisConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AnalysisQuery) DeepCopyInto(out *AnalysisQuery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AnalysisQuery.
+func (in *AnalysisQuery) DeepCopy() *AnalysisQuery {
+	if in == nil {
+		return nil
+	}
+	out := new(AnalysisQuery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Authorization) DeepCopyInto(out *Authorization) {
 	*out = *in
@@ -100,6 +141,116 @@ func (in *BundleDeploymentList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BundleDeploymentSet) DeepCopyInto(out *BundleDeploymentSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleDeploymentSet.
+func (in *BundleDeploymentSet) DeepCopy() *BundleDeploymentSet {
+	if in == nil {
+		return nil
+	}
+	out := new(BundleDeploymentSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BundleDeploymentSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BundleDeploymentSetList) DeepCopyInto(out *BundleDeploymentSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BundleDeploymentSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleDeploymentSetList.
+func (in *BundleDeploymentSetList) DeepCopy() *BundleDeploymentSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(BundleDeploymentSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BundleDeploymentSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BundleDeploymentSetSpec) DeepCopyInto(out *BundleDeploymentSetSpec) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	in.Template.DeepCopyInto(&out.Template)
+	if in.Overlays != nil {
+		in, out := &in.Overlays, &out.Overlays
+		*out = make([]ClusterOverlay, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleDeploymentSetSpec.
+func (in *BundleDeploymentSetSpec) DeepCopy() *BundleDeploymentSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BundleDeploymentSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BundleDeploymentSetStatus) DeepCopyInto(out *BundleDeploymentSetStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterStatuses != nil {
+		in, out := &in.ClusterStatuses, &out.ClusterStatuses
+		*out = make([]ClusterRolloutStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleDeploymentSetStatus.
+func (in *BundleDeploymentSetStatus) DeepCopy() *BundleDeploymentSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BundleDeploymentSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BundleDeploymentSpec) DeepCopyInto(out *BundleDeploymentSpec) {
 	*out = *in
@@ -110,6 +261,35 @@ func (in *BundleDeploymentSpec) DeepCopyInto(out *BundleDeploymentSpec) {
 		*out = new(PreflightConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Analysis != nil {
+		in, out := &in.Analysis, &out.Analysis
+		*out = new(AnalysisConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VersionPolicy != nil {
+		in, out := &in.VersionPolicy, &out.VersionPolicy
+		*out = new(VersionPolicy)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(Target)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleDeploymentSpec.
@@ -137,6 +317,40 @@ func (in *BundleDeploymentStatus) DeepCopyInto(out *BundleDeploymentStatus) {
 		*out = new(BundleSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ObjectApplyResults != nil {
+		in, out := &in.ObjectApplyResults, &out.ObjectApplyResults
+		*out = make([]ObjectApplyResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReconcileContinuation != nil {
+		in, out := &in.ReconcileContinuation, &out.ReconcileContinuation
+		*out = new(ReconcileContinuation)
+		**out = **in
+	}
+	if in.BundleMetadata != nil {
+		in, out := &in.BundleMetadata, &out.BundleMetadata
+		*out = new(BundleMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GeneratedRBAC != nil {
+		in, out := &in.GeneratedRBAC, &out.GeneratedRBAC
+		*out = make([]RBACObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(Failure)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NextReconcileTime != nil {
+		in, out := &in.NextReconcileTime, &out.NextReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PrunedObjects != nil {
+		in, out := &in.PrunedObjects, &out.PrunedObjects
+		*out = make([]ObjectReference, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleDeploymentStatus.
@@ -149,28 +363,68 @@ func (in *BundleDeploymentStatus) DeepCopy() *BundleDeploymentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BundleMetadata) DeepCopyInto(out *BundleMetadata) {
+	*out = *in
+	if in.ProvidedAPIs != nil {
+		in, out := &in.ProvidedAPIs, &out.ProvidedAPIs
+		*out = make([]v1.GroupVersionKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RelatedImages != nil {
+		in, out := &in.RelatedImages, &out.RelatedImages
+		*out = make([]RelatedImage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundleMetadata.
+func (in *BundleMetadata) DeepCopy() *BundleMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(BundleMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BundleSource) DeepCopyInto(out *BundleSource) {
 	*out = *in
 	if in.Image != nil {
 		in, out := &in.Image, &out.Image
 		*out = new(ImageSource)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Git != nil {
 		in, out := &in.Git, &out.Git
 		*out = new(GitSource)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ConfigMaps != nil {
 		in, out := &in.ConfigMaps, &out.ConfigMaps
 		*out = make([]ConfigMapSource, len(*in))
 		copy(*out, *in)
 	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]SecretSource, len(*in))
+		copy(*out, *in)
+	}
 	if in.HTTP != nil {
 		in, out := &in.HTTP, &out.HTTP
 		*out = new(HTTPSource)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		*out = new(InlineSource)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -199,6 +453,38 @@ func (in *CRDUpgradeSafetyPreflightConfig) DeepCopy() *CRDUpgradeSafetyPreflight
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterOverlay) DeepCopyInto(out *ClusterOverlay) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	in.Config.DeepCopyInto(&out.Config)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterOverlay.
+func (in *ClusterOverlay) DeepCopy() *ClusterOverlay {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterOverlay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterRolloutStatus) DeepCopyInto(out *ClusterRolloutStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRolloutStatus.
+func (in *ClusterRolloutStatus) DeepCopy() *ClusterRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigMapSource) DeepCopyInto(out *ConfigMapSource) {
 	*out = *in
@@ -215,6 +501,27 @@ func (in *ConfigMapSource) DeepCopy() *ConfigMapSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Failure) DeepCopyInto(out *Failure) {
+	*out = *in
+	if in.Object != nil {
+		in, out := &in.Object, &out.Object
+		*out = new(ObjectReference)
+		**out = **in
+	}
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Failure.
+func (in *Failure) DeepCopy() *Failure {
+	if in == nil {
+		return nil
+	}
+	out := new(Failure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitRef) DeepCopyInto(out *GitRef) {
 	*out = *in
@@ -235,6 +542,18 @@ func (in *GitSource) DeepCopyInto(out *GitSource) {
 	*out = *in
 	out.Ref = in.Ref
 	out.Auth = in.Auth
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		**out = **in
+	}
+	in.Retry.DeepCopyInto(&out.Retry)
+	in.PathFilters.DeepCopyInto(&out.PathFilters)
+	if in.Verify != nil {
+		in, out := &in.Verify, &out.Verify
+		*out = new(GitVerification)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSource.
@@ -247,10 +566,33 @@ func (in *GitSource) DeepCopy() *GitSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitVerification) DeepCopyInto(out *GitVerification) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitVerification.
+func (in *GitVerification) DeepCopy() *GitVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(GitVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HTTPSource) DeepCopyInto(out *HTTPSource) {
 	*out = *in
 	out.Auth = in.Auth
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		**out = **in
+	}
+	in.Retry.DeepCopyInto(&out.Retry)
+	in.PathFilters.DeepCopyInto(&out.PathFilters)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPSource.
@@ -266,6 +608,12 @@ func (in *HTTPSource) DeepCopy() *HTTPSource {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageSource) DeepCopyInto(out *ImageSource) {
 	*out = *in
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		**out = **in
+	}
+	in.PathFilters.DeepCopyInto(&out.PathFilters)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSource.
@@ -278,6 +626,101 @@ func (in *ImageSource) DeepCopy() *ImageSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InlineSource) DeepCopyInto(out *InlineSource) {
+	*out = *in
+	if in.Manifests != nil {
+		in, out := &in.Manifests, &out.Manifests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Gzipped != nil {
+		in, out := &in.Gzipped, &out.Gzipped
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InlineSource.
+func (in *InlineSource) DeepCopy() *InlineSource {
+	if in == nil {
+		return nil
+	}
+	out := new(InlineSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigSecretReference) DeepCopyInto(out *KubeconfigSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigSecretReference.
+func (in *KubeconfigSecretReference) DeepCopy() *KubeconfigSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectApplyResult) DeepCopyInto(out *ObjectApplyResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectApplyResult.
+func (in *ObjectApplyResult) DeepCopy() *ObjectApplyResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectApplyResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
+func (in *ObjectReference) DeepCopy() *ObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathFilters) DeepCopyInto(out *PathFilters) {
+	*out = *in
+	if in.IncludePaths != nil {
+		in, out := &in.IncludePaths, &out.IncludePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludePaths != nil {
+		in, out := &in.ExcludePaths, &out.ExcludePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathFilters.
+func (in *PathFilters) DeepCopy() *PathFilters {
+	if in == nil {
+		return nil
+	}
+	out := new(PathFilters)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreflightConfig) DeepCopyInto(out *PreflightConfig) {
 	*out = *in
@@ -297,3 +740,218 @@ func (in *PreflightConfig) DeepCopy() *PreflightConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyConfig.
+func (in *ProxyConfig) DeepCopy() *ProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RBACObjectReference) DeepCopyInto(out *RBACObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RBACObjectReference.
+func (in *RBACObjectReference) DeepCopy() *RBACObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RBACObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelatedImage) DeepCopyInto(out *RelatedImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelatedImage.
+func (in *RelatedImage) DeepCopy() *RelatedImage {
+	if in == nil {
+		return nil
+	}
+	out := new(RelatedImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcileContinuation) DeepCopyInto(out *ReconcileContinuation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcileContinuation.
+func (in *ReconcileContinuation) DeepCopy() *ReconcileContinuation {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcileContinuation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReferenceGrant) DeepCopyInto(out *SecretReferenceGrant) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReferenceGrant.
+func (in *SecretReferenceGrant) DeepCopy() *SecretReferenceGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReferenceGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretReferenceGrant) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReferenceGrantList) DeepCopyInto(out *SecretReferenceGrantList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretReferenceGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReferenceGrantList.
+func (in *SecretReferenceGrantList) DeepCopy() *SecretReferenceGrantList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReferenceGrantList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretReferenceGrantList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReferenceGrantSpec) DeepCopyInto(out *SecretReferenceGrantSpec) {
+	*out = *in
+	if in.BundleDeployments != nil {
+		in, out := &in.BundleDeployments, &out.BundleDeployments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReferenceGrantSpec.
+func (in *SecretReferenceGrantSpec) DeepCopy() *SecretReferenceGrantSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReferenceGrantSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretSource) DeepCopyInto(out *SecretSource) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretSource.
+func (in *SecretSource) DeepCopy() *SecretSource {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Target) DeepCopyInto(out *Target) {
+	*out = *in
+	out.KubeconfigSecretRef = in.KubeconfigSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Target.
+func (in *Target) DeepCopy() *Target {
+	if in == nil {
+		return nil
+	}
+	out := new(Target)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionPolicy) DeepCopyInto(out *VersionPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionPolicy.
+func (in *VersionPolicy) DeepCopy() *VersionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,34 +17,44 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/x509"
 	"flag"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	crfinalizer "sigs.k8s.io/controller-runtime/pkg/finalizer"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
 	"github.com/operator-framework/rukpak/internal/controllers/bundledeployment"
+	"github.com/operator-framework/rukpak/internal/externaladdress"
+	"github.com/operator-framework/rukpak/internal/metrics"
+	"github.com/operator-framework/rukpak/internal/releasegc"
+	"github.com/operator-framework/rukpak/internal/target"
 	"github.com/operator-framework/rukpak/internal/version"
 	"github.com/operator-framework/rukpak/pkg/finalizer"
-	"github.com/operator-framework/rukpak/pkg/handler"
+	"github.com/operator-framework/rukpak/pkg/installreport"
 	"github.com/operator-framework/rukpak/pkg/provisioner/helm"
 	"github.com/operator-framework/rukpak/pkg/source"
 	"github.com/operator-framework/rukpak/pkg/storage"
@@ -65,27 +75,71 @@ func init() {
 
 func main() {
 	var (
-		httpBindAddr         string
-		httpExternalAddr     string
-		bundleCAFile         string
-		enableLeaderElection bool
-		probeAddr            string
-		systemNamespace      string
-		unpackCacheDir       string
-		rukpakVersion        bool
-		storageDirectory     string
+		httpBindAddr            string
+		httpExternalAddr        string
+		discoverExternalAddr    bool
+		bundleCAFile            string
+		enableLeaderElection    bool
+		probeAddr               string
+		systemNamespace         string
+		unpackCacheDir          string
+		nodeImageURL            string
+		rukpakVersion           bool
+		storageDirectory        string
+		storageCompression      string
+		storageRetainedSources  int
+		storageMaxBundleSize    string
+		reportSigningKeyFile    string
+		disableStorageFinalizer bool
+		storageGCInterval       time.Duration
+		maxHistory              int
+		kubeAPIQPS              float64
+		kubeAPIBurst            int
+		helmClientQPS           float64
+		helmClientBurst         int
+		reconcileBudget         time.Duration
+		shutdownGracePeriod     time.Duration
+		chartCacheSize          int
+		testTimeout             time.Duration
+		releaseGCInterval       time.Duration
+		generateNameKinds       string
+		prunedFields            string
+		argoCDTracking          string
+		clusterDomain           string
 	)
 	flag.StringVar(&httpBindAddr, "http-bind-address", ":8080", "The address the http server binds to.")
 	flag.StringVar(&httpExternalAddr, "http-external-address", "http://localhost:8080", "The external address at which the http server is reachable.")
+	flag.BoolVar(&discoverExternalAddr, "discover-http-external-address", false, fmt.Sprintf("Discover the external address at which the http server is reachable from the Service or Ingress in the system namespace that is annotated with %s=true, instead of using --http-external-address. Content URLs follow changes of the discovered address.", externaladdress.AnnotationKey))
 	flag.StringVar(&bundleCAFile, "bundle-ca-file", "", "The file containing the certificate authority for connecting to bundle content servers.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&unpackCacheDir, "unpack-cache-dir", "/var/cache/unpack", "Configures the directory that gets used to unpack and cache Bundle contents.")
+	flag.StringVar(&nodeImageURL, "node-image-url", "", "The URL of the node image server that node-local images are read from. Node-local images are not supported if unset.")
 	flag.StringVar(&systemNamespace, "system-namespace", "", "Configures the namespace that gets used to deploy system resources.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&rukpakVersion, "version", false, "Displays rukpak version information")
 	flag.StringVar(&storageDirectory, "storage-dir", storage.DefaultBundleCacheDir, "Configures the directory that is used to store Bundle contents.")
+	flag.StringVar(&storageCompression, "storage-compression", string(storage.CompressionGzip), "The compression of stored bundle contents: gzip, zstd or none. Bundles whose contents are mostly compressed already are stored uncompressed regardless.")
+	flag.IntVar(&storageRetainedSources, "storage-retained-sources", 0, "The number of previous sources of each BundleDeployment whose bundle contents are retained, so that rolling back to one of them restores its contents rather than unpacking it again. Zero disables retention.")
+	flag.StringVar(&storageMaxBundleSize, "storage-max-bundle-size", "", "The maximum total size of the files of the bundle content of each BundleDeployment, e.g. 512Mi. Larger bundles fail to unpack, and the content stored before is kept. Bundles are not limited if unset.")
+	flag.StringVar(&reportSigningKeyFile, "install-report-signing-key", "", "The file containing the PEM encoded PKCS #8 private key that install reports are signed with. Install reports are not signed if unset.")
+	flag.BoolVar(&disableStorageFinalizer, "disable-storage-finalizer", false, "Do not add the finalizer that deletes the stored bundle content of deleted BundleDeployments, and garbage collect the content of deleted BundleDeployments periodically instead. Only use this when the storage directory is shared by all replicas.")
+	flag.DurationVar(&storageGCInterval, "storage-gc-interval", 10*time.Minute, "The interval at which the stored bundle content of deleted BundleDeployments is garbage collected when --disable-storage-finalizer is set.")
+	flag.IntVar(&maxHistory, "helm-max-history", 10, "The maximum number of release revisions that are kept per BundleDeployment. Zero means no limit. Values lower than 2 prevent rolling back upgrades that breach analysis queries.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 20, "The maximum queries per second of the controller's client to the apiserver. A negative value disables client-side throttling.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 30, "The maximum burst of queries of the controller's client to the apiserver.")
+	flag.Float64Var(&helmClientQPS, "helm-client-qps", 20, "The maximum queries per second to the apiserver of the client that installs and upgrades the release of each BundleDeployment. A negative value disables client-side throttling.")
+	flag.IntVar(&helmClientBurst, "helm-client-burst", 30, "The maximum burst of queries to the apiserver of the client that installs and upgrades the release of each BundleDeployment.")
+	flag.DurationVar(&reconcileBudget, "reconcile-budget", 0, "How long the object-level reconcile of an installed release may take before the rest of its objects are reconciled in a later reconcile. Zero means no limit.")
+	flag.DurationVar(&shutdownGracePeriod, "shutdown-grace-period", 20*time.Second, "How long reconciles that are in flight when the provisioner is stopped may continue, so that helm installs and upgrades are not interrupted halfway and their outcome is written to the status. The termination grace period of the pod must be longer by at least 5 seconds.")
+	flag.IntVar(&chartCacheSize, "chart-cache-size", 64, "The maximum number of converted bundles per provisioner that are kept in memory, so that reconciles of unchanged bundles do not read and parse their stored contents again. Zero disables the cache.")
+	flag.DurationVar(&testTimeout, "test-timeout", 5*time.Minute, "How long each test hook of a release is waited for when a test run of a BundleDeployment is requested.")
+	flag.DurationVar(&releaseGCInterval, "release-gc-interval", 10*time.Minute, "The interval at which the helm release secrets of deleted BundleDeployments are garbage collected.")
+	flag.StringVar(&generateNameKinds, "generate-name-kinds", "", "A comma-separated list of kinds, in the Kind.group format, whose objects may use generateName. The generateName of such objects is replaced by a name with a suffix derived from the BundleDeployment name.")
+	flag.StringVar(&prunedFields, "pruned-fields", "", `A comma-separated list of fields that are removed from the objects of bundles before they are applied, in the Kind.group=/json/pointer format, e.g. "Deployment.apps=/spec/replicas". Fields of the "*" kind are removed from objects of every kind. Fields that the API server populates, such as status, are always removed.`)
+	flag.StringVar(&argoCDTracking, "argocd-tracking-method", "", `Marks the objects of releases as resources of the Argo CD application that their BundleDeployment belongs to, with the given Argo CD resource tracking method: "label", "annotation" or "annotation+label". Disabled if unset.`)
+	flag.StringVar(&clusterDomain, "cluster-domain", helm.DefaultClusterDomain, "The DNS domain of the cluster, which is substituted for $(RUKPAK_CLUSTER_DOMAIN) in the values of BundleDeployments.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -98,7 +152,11 @@ func main() {
 	}
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-	setupLog.Info("starting up the provisioner", "git commit", version.String())
+	setupLog.Info("starting up the provisioner", "git commit", version.String(), "fips", version.FIPS())
+	if err := version.CheckFIPS(); err != nil {
+		setupLog.Error(err, "unable to start in FIPS mode")
+		os.Exit(1)
+	}
 
 	dependentRequirement, err := labels.NewRequirement(util.CoreOwnerKindKey, selection.In, []string{rukpakv1alpha2.BundleDeploymentKind})
 	if err != nil {
@@ -108,6 +166,11 @@ func main() {
 	dependentSelector := labels.NewSelector().Add(*dependentRequirement)
 
 	cfg := ctrl.GetConfigOrDie()
+	cfg.QPS = float32(kubeAPIQPS)
+	cfg.Burst = kubeAPIBurst
+	helmCfg := rest.CopyConfig(cfg)
+	helmCfg.QPS = float32(helmClientQPS)
+	helmCfg.Burst = helmClientBurst
 	if systemNamespace == "" {
 		systemNamespace = util.PodNamespace()
 	}
@@ -118,9 +181,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	compression, err := storage.ParseCompression(storageCompression)
+	if err != nil {
+		setupLog.Error(err, "invalid storage compression")
+		os.Exit(1)
+	}
+	var maxBundleSize int64
+	if storageMaxBundleSize != "" {
+		q, err := resource.ParseQuantity(storageMaxBundleSize)
+		if err != nil {
+			setupLog.Error(err, "invalid storage max bundle size")
+			os.Exit(1)
+		}
+		maxBundleSize = q.Value()
+	}
 	localStorage := &storage.LocalDirectory{
-		RootDirectory: storageDirectory,
-		URL:           *storageURL,
+		RootDirectory:  storageDirectory,
+		URL:            *storageURL,
+		Compression:    compression,
+		RetentionLimit: storageRetainedSources,
+		MaxBundleSize:  maxBundleSize,
 	}
 
 	var rootCAs *x509.CertPool
@@ -136,7 +216,9 @@ func main() {
 		storage.WithRootCAs(rootCAs),
 		storage.WithBearerToken(cfg.BearerToken),
 	)
-	bundleStorage := storage.WithFallbackLoader(localStorage, httpLoader)
+	// Writes to an unavailable storage are refused for a backoff period by
+	// every BundleDeployment rather than retried by each of them.
+	bundleStorage := storage.WithAvailabilityTracking(storage.WithFallbackLoader(localStorage, httpLoader), ctrl.Log.WithName("storage"))
 
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme,
@@ -153,6 +235,8 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "helm.core.rukpak.io",
+		// Controllers wait for in-flight reconciles to finish before they stop.
+		GracefulShutdownTimeout: ptr.To(shutdownGracePeriod + bundledeployment.ShutdownGracePeriodHeadroom),
 		Cache: cache.Options{
 			ByObject: map[client.Object]cache.ByObject{
 				&rukpakv1alpha2.BundleDeployment{}: {},
@@ -169,6 +253,10 @@ func main() {
 		setupLog.Error(err, "unable to create manager")
 		os.Exit(1)
 	}
+	if discoverExternalAddr {
+		discoverer := &externaladdress.Discoverer{Reader: mgr.GetClient(), Namespace: systemNamespace}
+		localStorage.ExternalAddress = discoverer.Address
+	}
 
 	// This finalizer logic MUST be co-located with this main
 	// controller logic because it deals with cleaning up bundle data
@@ -180,16 +268,29 @@ func main() {
 	//
 	// If the bundle cache is backed by a storage implementation that allows
 	// multiple writers from different processes (e.g. a ReadWriteMany volume or
-	// an S3 bucket), we could have separate processes for finalizer handling
-	// and the primary helm provisioner controller. For now, the assumption is
-	// that we are not using such an implementation.
+	// an S3 bucket), the finalizer can be disabled with
+	// --disable-storage-finalizer. The content of deleted bundles is then
+	// garbage collected in the background by every replica instead.
 	bundleFinalizers := crfinalizer.NewFinalizers()
-	if err := bundleFinalizers.Register(finalizer.DeleteCachedBundleKey, &finalizer.DeleteCachedBundle{Storage: bundleStorage}); err != nil {
+	if disableStorageFinalizer {
+		// Release bundles that still carry the finalizer from before it was
+		// disabled, since it would otherwise never be removed.
+		bundleFinalizers = finalizer.WithRemovedKeys(bundleFinalizers, finalizer.DeleteCachedBundleKey)
+		if err := mgr.Add(&storage.GarbageCollector{
+			Storage:  localStorage,
+			Reader:   mgr.GetAPIReader(),
+			Interval: storageGCInterval,
+			Log:      ctrl.Log.WithName("storage-gc"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up storage garbage collector")
+			os.Exit(1)
+		}
+	} else if err := bundleFinalizers.Register(finalizer.DeleteCachedBundleKey, &finalizer.DeleteCachedBundle{Storage: bundleStorage}); err != nil {
 		setupLog.Error(err, "unable to register finalizer", "finalizerKey", finalizer.DeleteCachedBundleKey)
 		os.Exit(1)
 	}
 
-	unpacker, err := source.NewDefaultUnpacker(mgr, systemNamespace, unpackCacheDir)
+	unpacker, err := source.NewDefaultUnpacker(mgr, systemNamespace, unpackCacheDir, nodeImageURL)
 	if err != nil {
 		setupLog.Error(err, "unable to setup bundle unpacker")
 		os.Exit(1)
@@ -210,7 +311,7 @@ func main() {
 	systemNamespaceMapper := func(obj client.Object) (string, error) {
 		return systemNamespace, nil
 	}
-	cfgGetter, err := helmclient.NewActionConfigGetter(mgr.GetConfig(), mgr.GetRESTMapper(),
+	cfgGetter, err := helmclient.NewActionConfigGetter(helmCfg, mgr.GetRESTMapper(),
 		helmclient.ClientNamespaceMapper(bdNamespaceMapper),
 		helmclient.StorageNamespaceMapper(systemNamespaceMapper),
 	)
@@ -218,28 +319,112 @@ func main() {
 		setupLog.Error(err, "unable to create action config getter")
 		os.Exit(1)
 	}
-	acg, err := helmclient.NewActionClientGetter(cfgGetter)
+	targetClusters := &target.Clusters{
+		Reader:    mgr.GetClient(),
+		Namespace: systemNamespace,
+		Scheme:    mgr.GetScheme(),
+		Selector:  dependentSelector,
+		QPS:       helmCfg.QPS,
+		Burst:     helmCfg.Burst,
+		Log:       ctrl.Log.WithName("target-clusters"),
+	}
+	if err := mgr.Add(targetClusters); err != nil {
+		setupLog.Error(err, "unable to set up target clusters")
+		os.Exit(1)
+	}
+	cfgGetter = targetClusters.ActionConfigGetter(cfgGetter)
+
+	// Failed releases are kept rather than rolled back, so that the objects
+	// that failed to apply can be retried on their own.
+	acg, err := helmclient.NewActionClientGetter(cfgGetter, helmclient.WithFailureRollbacks(false))
 	if err != nil {
 		setupLog.Error(err, "unable to create action client getter")
 		os.Exit(1)
 	}
+	if err := mgr.Add(&releasegc.GarbageCollector{
+		Reader:    mgr.GetAPIReader(),
+		Writer:    mgr.GetClient(),
+		Namespace: systemNamespace,
+		Interval:  releaseGCInterval,
+		Log:       ctrl.Log.WithName("release-gc"),
+	}); err != nil {
+		setupLog.Error(err, "unable to set up release garbage collector")
+		os.Exit(1)
+	}
+	argoCDTrackingMethod, err := bundledeployment.ParseArgoCDTrackingMethod(argoCDTracking)
+	if err != nil {
+		setupLog.Error(err, "invalid Argo CD tracking method")
+		os.Exit(1)
+	}
+	prunedKindFields, err := util.ParseKindFields(prunedFields)
+	if err != nil {
+		setupLog.Error(err, "invalid pruned fields")
+		os.Exit(1)
+	}
 	commonBDProvisionerOptions := []bundledeployment.Option{
 		bundledeployment.WithFinalizers(bundleFinalizers),
 		bundledeployment.WithActionClientGetter(acg),
 		bundledeployment.WithStorage(bundleStorage),
+		bundledeployment.WithContentChecker(httpLoader),
 		bundledeployment.WithUnpacker(unpacker),
+		bundledeployment.WithMaxHistory(maxHistory),
+		bundledeployment.WithReconcileBudget(reconcileBudget),
+		bundledeployment.WithShutdownGracePeriod(shutdownGracePeriod),
+		bundledeployment.WithChartCacheSize(chartCacheSize),
+		bundledeployment.WithReleaseTester(&bundledeployment.HelmReleaseTester{ActionConfigGetter: cfgGetter, Timeout: testTimeout}),
+		bundledeployment.WithReleaseCompleter(&bundledeployment.HelmReleaseCompleter{ActionConfigGetter: cfgGetter}),
+		bundledeployment.WithGenerateNameKinds(util.ParseGroupKinds(generateNameKinds)...),
+		bundledeployment.WithPrunedFields(prunedKindFields),
+		bundledeployment.WithArgoCDTracking(argoCDTrackingMethod),
+		bundledeployment.WithTargetClusters(targetClusters),
+	}
+	if reportSigningKeyFile != "" {
+		signer, err := installreport.LoadSigner(reportSigningKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load install report signing key")
+			os.Exit(1)
+		}
+		commonBDProvisionerOptions = append(commonBDProvisionerOptions, bundledeployment.WithInstallReportSigner(signer))
+	}
+	if discoverExternalAddr {
+		commonBDProvisionerOptions = append(commonBDProvisionerOptions, bundledeployment.WithExternalAddressDiscovery())
+	}
+	if storageRetainedSources > 0 {
+		commonBDProvisionerOptions = append(commonBDProvisionerOptions, bundledeployment.WithRetainer(localStorage))
+	}
+
+	if err := util.IndexSecretReferences(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to create index", "index", util.SecretReferencesIndex)
+		os.Exit(1)
 	}
 
 	if err := bundledeployment.SetupWithManager(mgr, systemNamespace, append(
 		commonBDProvisionerOptions,
 		bundledeployment.WithProvisionerID(helm.ProvisionerID),
-		bundledeployment.WithHandler(handler.HandlerFunc(helm.HandleBundleDeployment)),
+		bundledeployment.WithHandler(helm.NewHandler(clusterDomain)),
 	)...); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", rukpakv1alpha2.BundleDeploymentKind, "provisionerID", helm.ProvisionerID)
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	if err := metrics.RegisterClientThrottleMetrics(ctrlmetrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register client throttle metrics")
+		os.Exit(1)
+	}
+	if err := metrics.RegisterBundleDeploymentMetrics(ctrlmetrics.Registry); err != nil {
+		setupLog.Error(err, "unable to register bundledeployment metrics")
+		os.Exit(1)
+	}
+	if err := ctrlmetrics.Registry.Register(metrics.NewConditionCollector(mgr.GetClient(), helm.ProvisionerID)); err != nil {
+		setupLog.Error(err, "unable to register bundledeployment condition metrics")
+		os.Exit(1)
+	}
+	if err := ctrlmetrics.Registry.Register(metrics.NewStorageAvailabilityGauge(bundleStorage.Available)); err != nil {
+		setupLog.Error(err, "unable to register bundle storage availability metric")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -248,6 +433,12 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	// Replicas that are not the leader serve bundle content too, so they are
+	// only ready once the storage that the leader writes to is accessible.
+	if err := mgr.AddReadyzCheck("storage", localStorage.Check); err != nil {
+		setupLog.Error(err, "unable to set up storage ready check")
+		os.Exit(1)
+	}
 
 	ctx := ctrl.SetupSignalHandler()
 	setupLog.Info("starting manager")
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/namespaces"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/operator-framework/rukpak/internal/nodeimage"
+	"github.com/operator-framework/rukpak/internal/version"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var (
+		listenAddr          string
+		containerdAddress   string
+		containerdNamespace string
+		workDir             string
+		rukpakVersion       bool
+	)
+	flag.StringVar(&listenAddr, "listen-address", ":8080", "The address the image server binds to.")
+	flag.StringVar(&containerdAddress, "containerd-address", "/run/containerd/containerd.sock", "The address of the containerd socket of the node.")
+	flag.StringVar(&containerdNamespace, "containerd-namespace", "k8s.io", "The containerd namespace that the images of the node are stored in.")
+	flag.StringVar(&workDir, "work-dir", os.TempDir(), "The directory that images are unpacked into before they are served.")
+	flag.BoolVar(&rukpakVersion, "version", false, "Displays rukpak version information")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	if rukpakVersion {
+		fmt.Println(version.String())
+		os.Exit(0)
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	setupLog.Info("starting up the node image server", "git commit", version.String(), "fips", version.FIPS(), "containerd", containerdAddress)
+	if err := version.CheckFIPS(); err != nil {
+		setupLog.Error(err, "unable to start in FIPS mode")
+		os.Exit(1)
+	}
+
+	client, err := containerd.New(containerdAddress, containerd.WithDefaultNamespace(containerdNamespace))
+	if err != nil {
+		setupLog.Error(err, "unable to connect to containerd")
+		os.Exit(1)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(nodeimage.Path, &nodeimage.Server{
+		Images:  client.ImageService(),
+		Content: client.ContentStore(),
+		WorkDir: workDir,
+		Log:     ctrl.Log.WithName("nodeimage"),
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := &http.Server{
+		Addr: listenAddr,
+		// The containerd namespace is taken from the request context by
+		// the image and content stores.
+		BaseContext: func(_ net.Listener) context.Context {
+			return namespaces.WithNamespace(context.Background(), containerdNamespace)
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			setupLog.Error(err, "failed to shut down the node image server")
+		}
+	}()
+	err = srv.ListenAndServe()
+	client.Close()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		setupLog.Error(err, "problem running the node image server")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/fanout"
+)
+
+const fanoutFieldManager = "rukpakctl-fanout"
+
+func newFanoutCmd() *cobra.Command {
+	var (
+		opts       fanout.Options
+		authSecret string
+		checkout   string
+		apply      bool
+		prune      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "fanout <name> <repository>",
+		Short: "Generate a BundleDeployment for every bundle directory of a git repository",
+		Long: `Generate a BundleDeployment for every subdirectory of the root directory of a git repository, so that
+a repository with a directory per application is deployed as a whole.
+
+The BundleDeployment of a directory is named after the directory, prefixed with --name-prefix, installs into the
+namespace of the same name, and is labeled with ` + fanout.Label + `=<name>. A ` + fanout.ConfigFile + ` file in
+the directory, containing a partial BundleDeployment, overrides these conventions and sets further fields.
+
+The BundleDeployments are printed, unless --apply is set. The directories are read from a clone of the repository,
+or from the local checkout given with --checkout.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Name, opts.Repository = args[0], args[1]
+			if authSecret != "" {
+				opts.Auth.Secret = corev1.LocalObjectReference{Name: authSecret}
+			}
+			if prune && !apply {
+				return fmt.Errorf("--prune requires --apply")
+			}
+			if checkout == "" {
+				dir, err := cloneRepository(cmd.Context(), opts.Repository, opts.Ref)
+				if err != nil {
+					return err
+				}
+				defer os.RemoveAll(dir)
+				checkout = dir
+			}
+			bds, err := fanout.Generate(os.DirFS(checkout), opts)
+			if err != nil {
+				return err
+			}
+
+			if !apply {
+				for _, bd := range bds {
+					data, err := yaml.Marshal(bd)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "---\n%s", data)
+				}
+				return nil
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			return applyFanout(cmd, cl, opts.Name, bds, prune)
+		},
+	}
+	cmd.Flags().StringVar(&opts.Ref.Branch, "branch", "", "The branch of the repository that the BundleDeployments are sourced from.")
+	cmd.Flags().StringVar(&opts.Ref.Tag, "tag", "", "The tag of the repository that the BundleDeployments are sourced from.")
+	cmd.Flags().StringVar(&opts.Ref.Commit, "commit", "", "The commit of the repository that the BundleDeployments are sourced from.")
+	cmd.MarkFlagsOneRequired("branch", "tag", "commit")
+	cmd.MarkFlagsMutuallyExclusive("branch", "tag", "commit")
+	cmd.Flags().StringVar(&authSecret, "auth-secret", "", "The name of the secret in the system namespace that the git sources authorize with.")
+	cmd.Flags().StringVar(&opts.Root, "root", ".", "The directory of the repository whose subdirectories are the bundle directories.")
+	cmd.Flags().StringVar(&opts.NamePrefix, "name-prefix", "", "The prefix of the names of the generated BundleDeployments.")
+	cmd.Flags().StringVar(&opts.ProvisionerClassName, "provisioner-class", "", "The provisioner class of the BundleDeployments whose directory does not configure one.")
+	cmd.Flags().StringVar(&checkout, "checkout", "", "A local checkout of the repository to read the directories from instead of cloning it.")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Apply the BundleDeployments to the cluster with server-side apply.")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete the BundleDeployments of the fan-out whose directory no longer exists. Requires --apply.")
+	return cmd
+}
+
+// cloneRepository clones ref of repository into a temporary directory and
+// returns its path.
+func cloneRepository(ctx context.Context, repository string, ref rukpakv1alpha2.GitRef) (string, error) {
+	dir, err := os.MkdirTemp("", "rukpakctl-fanout-")
+	if err != nil {
+		return "", err
+	}
+	cloneOpts := &git.CloneOptions{URL: repository, Tags: git.NoTags}
+	switch {
+	case ref.Branch != "":
+		cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(ref.Branch)
+		cloneOpts.SingleBranch, cloneOpts.Depth = true, 1
+	case ref.Tag != "":
+		cloneOpts.ReferenceName = plumbing.NewTagReferenceName(ref.Tag)
+		cloneOpts.SingleBranch, cloneOpts.Depth = true, 1
+	}
+	repo, err := git.PlainCloneContext(ctx, dir, false, cloneOpts)
+	if err == nil && ref.Commit != "" {
+		var wt *git.Worktree
+		if wt, err = repo.Worktree(); err == nil {
+			err = wt.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(ref.Commit)})
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("clone repository %q: %v", repository, err)
+	}
+	return dir, nil
+}
+
+func applyFanout(cmd *cobra.Command, cl client.Client, name string, bds []rukpakv1alpha2.BundleDeployment, prune bool) error {
+	generated := map[string]bool{}
+	for i := range bds {
+		bd := &bds[i]
+		if err := cl.Patch(cmd.Context(), bd, client.Apply, client.FieldOwner(fanoutFieldManager), client.ForceOwnership); err != nil {
+			return fmt.Errorf("apply bundle deployment %q: %v", bd.Name, err)
+		}
+		generated[bd.Name] = true
+		fmt.Fprintf(cmd.OutOrStdout(), "bundle deployment %q applied\n", bd.Name)
+	}
+	if !prune {
+		return nil
+	}
+
+	existing := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := cl.List(cmd.Context(), existing, client.MatchingLabels{fanout.Label: name}); err != nil {
+		return fmt.Errorf("list bundle deployments of fan-out %q: %v", name, err)
+	}
+	for i := range existing.Items {
+		bd := &existing.Items[i]
+		if generated[bd.Name] {
+			continue
+		}
+		if err := cl.Delete(cmd.Context(), bd); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("delete bundle deployment %q: %v", bd.Name, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "bundle deployment %q deleted\n", bd.Name)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/version"
+	"github.com/operator-framework/rukpak/pkg/snapshot"
+)
+
+func main() {
+	cmd := &cobra.Command{
+		Use:          "rukpakctl",
+		Short:        "Manage rukpak BundleDeployments",
+		Version:      version.String(),
+		SilenceUsage: true,
+	}
+	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
+	cmd.AddCommand(newSnapshotCmd(), newRestoreCmd(), newTestCmd(), newFanoutCmd(), newStatusCmd())
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newSnapshotCmd() *cobra.Command {
+	var (
+		output          string
+		systemNamespace string
+	)
+	cmd := &cobra.Command{
+		Use:   "snapshot <bundle-deployment>",
+		Short: "Capture the resolved source, config and rendered manifest of a BundleDeployment",
+		Long: `Capture the resolved source, config and rendered manifest of a BundleDeployment into a
+portable archive, which can be restored with "rukpakctl restore".`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if output == "" {
+				output = name + ".snapshot.tgz"
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			bd := &rukpakv1alpha2.BundleDeployment{}
+			if err := cl.Get(cmd.Context(), types.NamespacedName{Name: name}, bd); err != nil {
+				return fmt.Errorf("get bundle deployment %q: %v", name, err)
+			}
+			manifest, err := deployedManifest(systemNamespace, name)
+			if err != nil {
+				return err
+			}
+			s, err := snapshot.New(bd, manifest)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := snapshot.Write(f, s); err != nil {
+				return fmt.Errorf("write snapshot: %v", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "snapshot of bundle deployment %q with bundle digest %s written to %s\n", name, s.Digest(), output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", `The file the snapshot is written to. Defaults to "<bundle-deployment>.snapshot.tgz".`)
+	cmd.Flags().StringVar(&systemNamespace, "system-namespace", "rukpak-system", "The namespace that the provisioners store releases in.")
+	return cmd
+}
+
+func newRestoreCmd() *cobra.Command {
+	var overwrite bool
+	cmd := &cobra.Command{
+		Use:   "restore <snapshot-file>",
+		Short: "Restore a BundleDeployment from a snapshot",
+		Long: `Restore a BundleDeployment from a snapshot that was taken with "rukpakctl snapshot".
+
+The source of the restored BundleDeployment is pinned to the resolved source of the snapshot, and provisioners refuse
+to deploy bundle content whose digest differs from the digest of the snapshot.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			s, err := snapshot.Read(f)
+			if err != nil {
+				return err
+			}
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			desired := s.Restore()
+			existing := &rukpakv1alpha2.BundleDeployment{}
+			err = cl.Get(cmd.Context(), types.NamespacedName{Name: desired.Name}, existing)
+			switch {
+			case client.IgnoreNotFound(err) != nil:
+				return fmt.Errorf("get bundle deployment %q: %v", desired.Name, err)
+			case err != nil:
+				if err := cl.Create(cmd.Context(), desired); err != nil {
+					return fmt.Errorf("create bundle deployment %q: %v", desired.Name, err)
+				}
+			case !overwrite:
+				return fmt.Errorf("bundle deployment %q already exists; use --overwrite to restore it anyway", desired.Name)
+			default:
+				existing.Labels = desired.Labels
+				existing.Annotations = desired.Annotations
+				existing.Spec = desired.Spec
+				if err := cl.Update(cmd.Context(), existing); err != nil {
+					return fmt.Errorf("update bundle deployment %q: %v", desired.Name, err)
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "bundle deployment %q restored with bundle digest %s\n", desired.Name, s.Digest())
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Replace the spec of the BundleDeployment if it already exists.")
+	return cmd
+}
+
+func newTestCmd() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "test <bundle-deployment>",
+		Short: "Run the test hooks of the release of a BundleDeployment",
+		Long: `Request a run of the test hooks of the release of a BundleDeployment, like "helm test" does, and wait
+for its outcome, which the provisioner reports in the TestsPassed condition of the BundleDeployment.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			bd := &rukpakv1alpha2.BundleDeployment{}
+			if err := cl.Get(cmd.Context(), types.NamespacedName{Name: name}, bd); err != nil {
+				return fmt.Errorf("get bundle deployment %q: %v", name, err)
+			}
+			requested := time.Now().UTC().Format(time.RFC3339Nano)
+			patch := client.MergeFrom(bd.DeepCopy())
+			metav1.SetMetaDataAnnotation(&bd.ObjectMeta, rukpakv1alpha2.TestRequestedAnnotation, requested)
+			if err := cl.Patch(cmd.Context(), bd, patch); err != nil {
+				return fmt.Errorf("request test run of bundle deployment %q: %v", name, err)
+			}
+			if timeout <= 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "test run of bundle deployment %q requested\n", name)
+				return nil
+			}
+
+			var cond *metav1.Condition
+			err = wait.PollUntilContextTimeout(cmd.Context(), time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+				if err := cl.Get(ctx, types.NamespacedName{Name: name}, bd); err != nil {
+					return false, err
+				}
+				if bd.Status.TestRequest != requested {
+					return false, nil
+				}
+				cond = meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
+				return cond != nil, nil
+			})
+			if err != nil {
+				return fmt.Errorf("wait for test run of bundle deployment %q: %v", name, err)
+			}
+			if cond.Status != metav1.ConditionTrue {
+				return errors.New(cond.Message)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), cond.Message)
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "How long to wait for the outcome of the test run. Zero returns once the test run is requested.")
+	return cmd
+}
+
+func newClient() (client.Client, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	scheme := runtime.NewScheme()
+	if err := rukpakv1alpha2.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// deployedManifest returns the rendered manifest of the deployed release of
+// the named BundleDeployment.
+func deployedManifest(systemNamespace, name string) (string, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	cs, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+	releases := storage.Init(driver.NewSecrets(cs.CoreV1().Secrets(systemNamespace)))
+	rel, err := releases.Deployed(name)
+	if err != nil {
+		return "", fmt.Errorf("get deployed release of bundle deployment %q: %v", name, err)
+	}
+	return rel.Manifest, nil
+}
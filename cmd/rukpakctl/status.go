@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/statusstream"
+)
+
+func newStatusCmd() *cobra.Command {
+	var (
+		watch    bool
+		server   string
+		token    string
+		caFile   string
+		insecure bool
+	)
+	cmd := &cobra.Command{
+		Use:   "status <bundle-deployment>",
+		Short: "Show the conditions of a BundleDeployment",
+		Long: `Show the conditions of a BundleDeployment.
+
+With --watch, the conditions are followed until the command is interrupted. They are read from the status stream
+of the core webserver at --server rather than from a watch against the apiserver. Reading the stream requires the
+permissions of the bundledeployment-status-watcher cluster role, and is authenticated with --token, or with the
+bearer token of the kubeconfig if unset.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "TYPE\tSTATUS\tREASON\tMESSAGE")
+			if !watch {
+				cl, err := newClient()
+				if err != nil {
+					return err
+				}
+				bd := &rukpakv1alpha2.BundleDeployment{}
+				if err := cl.Get(cmd.Context(), types.NamespacedName{Name: name}, bd); err != nil {
+					return fmt.Errorf("get bundle deployment %q: %v", name, err)
+				}
+				for _, c := range bd.Status.Conditions {
+					writeCondition(w, c)
+				}
+				return w.Flush()
+			}
+
+			if token == "" {
+				var err error
+				if token, err = kubeconfigToken(); err != nil {
+					return err
+				}
+			}
+			httpClient, err := statusStreamClient(caFile, insecure)
+			if err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			err = statusstream.Watch(cmd.Context(), httpClient, server, token, name, func(ev statusstream.Event) error {
+				writeCondition(w, ev.Condition)
+				return w.Flush()
+			})
+			if err != nil {
+				return fmt.Errorf("watch bundle deployment %q: %v", name, err)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Follow the conditions of the BundleDeployment as they change.")
+	cmd.Flags().StringVar(&server, "server", "https://core.rukpak-system.svc", "The address of the core webserver that serves the status stream. Used with --watch.")
+	cmd.Flags().StringVar(&token, "token", "", "The bearer token that the status stream is requested with. Defaults to the token of the kubeconfig. Used with --watch.")
+	cmd.Flags().StringVar(&caFile, "certificate-authority", "", "The file containing the certificate authority of the serving certificate of the core webserver. Used with --watch.")
+	cmd.Flags().BoolVar(&insecure, "insecure-skip-tls-verify", false, "Do not verify the serving certificate of the core webserver. Used with --watch.")
+	cmd.MarkFlagsMutuallyExclusive("certificate-authority", "insecure-skip-tls-verify")
+	return cmd
+}
+
+func writeCondition(w io.Writer, c metav1.Condition) {
+	// Messages may span lines, which would break the columns.
+	message := strings.Join(strings.Fields(c.Message), " ")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, message)
+}
+
+// kubeconfigToken returns the bearer token of the kubeconfig, if any.
+func kubeconfigToken() (string, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	if cfg.BearerToken != "" || cfg.BearerTokenFile == "" {
+		return cfg.BearerToken, nil
+	}
+	data, err := os.ReadFile(cfg.BearerTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read bearer token: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func statusStreamClient(caFile string, insecure bool) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure} // nolint:gosec
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read certificate authority: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %q", caFile)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -39,6 +40,9 @@ func main() {
 				fmt.Println(version.String())
 				os.Exit(0)
 			}
+			if err := version.CheckFIPS(); err != nil {
+				log.Fatalf("unable to start in FIPS mode: %v", err)
+			}
 			var err error
 			bundleDir, err = filepath.Abs(bundleDir)
 			if err != nil {
@@ -103,8 +107,11 @@ func main() {
 				log.Fatal(err)
 			}
 
+			// The digest lets the provisioner verify that the content it
+			// reads from the logs of the pod is the content written here.
 			bundleMap := map[string]interface{}{
-				"content": buf.Bytes(),
+				"content":       buf.Bytes(),
+				"contentDigest": fmt.Sprintf("sha256:%x", sha256.Sum256(buf.Bytes())),
 			}
 			enc := json.NewEncoder(os.Stdout)
 			if err := enc.Encode(bundleMap); err != nil {
@@ -17,16 +17,20 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -55,12 +59,30 @@ func main() {
 	var systemNamespace string
 	var rukpakVersion bool
 	var enableHTTP2 bool
+	var enableCertRotation bool
+	var certDir string
+	var certSecretName string
+	var webhookServiceName string
+	var webhookConfigurationName string
+	var mutatingWebhookConfigurationName string
+	var requireImageDigests bool
+	var resolveImageDigests bool
+	var quotaPolicyFile string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&systemNamespace, "system-namespace", "", "Configures the namespace that gets used to deploy system resources.")
 	flag.BoolVar(&rukpakVersion, "version", false, "Displays rukpak version information")
 	flag.BoolVar(&enableHTTP2, "enable-http2", enableHTTP2, "If HTTP/2 should be enabled for the webhook servers.")
+	flag.BoolVar(&enableCertRotation, "enable-cert-rotation", false, "Generate and rotate the webhook serving certificate instead of relying on an external certificate manager.")
+	flag.StringVar(&certDir, "cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"), "The directory the webhook server reads its serving certificate from.")
+	flag.StringVar(&certSecretName, "cert-secret-name", "rukpak-webhook-certificate", "The name of the secret in the system namespace that stores the generated webhook certificates. Only used when --enable-cert-rotation is set.")
+	flag.StringVar(&webhookServiceName, "webhook-service-name", "rukpak-webhook-service", "The name of the service in the system namespace that exposes the webhook server. Only used when --enable-cert-rotation is set.")
+	flag.StringVar(&webhookConfigurationName, "webhook-configuration-name", "rukpak-validating-webhook-configuration", "The name of the validating webhook configuration to inject the CA bundle into. Only used when --enable-cert-rotation is set.")
+	flag.StringVar(&mutatingWebhookConfigurationName, "mutating-webhook-configuration-name", "rukpak-mutating-webhook-configuration", "The name of the mutating webhook configuration to inject the CA bundle into. Only used when --enable-cert-rotation is set.")
+	flag.BoolVar(&requireImageDigests, "require-image-digests", false, "Reject BundleDeployments whose image source references its image by a tag rather than a digest.")
+	flag.BoolVar(&resolveImageDigests, "resolve-image-digests", false, "Replace the tag of the image source of a BundleDeployment with the digest that it points to when the BundleDeployment is admitted. Combined with --require-image-digests, tags are accepted but pinned to a digest.")
+	flag.StringVar(&quotaPolicyFile, "quota-policy-file", "", "The path of a file that limits the number of BundleDeployments and their total bundle storage per tenant. No quota is enforced if unset.")
 
 	opts := zap.Options{
 		Development: true,
@@ -74,7 +96,11 @@ func main() {
 	}
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-	setupLog.Info("starting up the rukpak webhooks", "git commit", version.String())
+	setupLog.Info("starting up the rukpak webhooks", "git commit", version.String(), "fips", version.FIPS())
+	if err := version.CheckFIPS(); err != nil {
+		setupLog.Error(err, "unable to start in FIPS mode")
+		os.Exit(1)
+	}
 
 	cfg := ctrl.GetConfigOrDie()
 	if systemNamespace == "" {
@@ -90,9 +116,46 @@ func main() {
 	}
 
 	webhookServer := crwebhook.NewServer(crwebhook.Options{
+		CertDir: certDir,
 		TLSOpts: []func(config *tls.Config){disableHTTP2},
 	})
 
+	var certRotator *webhook.CertRotator
+	if enableCertRotation {
+		// The manager's cache is not running yet, so use a direct client to
+		// provision the initial certificate before the webhook server starts.
+		cl, err := client.New(cfg, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create client")
+			os.Exit(1)
+		}
+		certRotator = &webhook.CertRotator{
+			Client: cl,
+			Secret: types.NamespacedName{Namespace: systemNamespace, Name: certSecretName},
+			DNSNames: []string{
+				fmt.Sprintf("%s.%s.svc", webhookServiceName, systemNamespace),
+				fmt.Sprintf("%s.%s.svc.cluster.local", webhookServiceName, systemNamespace),
+			},
+			WebhookConfigurationName:         webhookConfigurationName,
+			MutatingWebhookConfigurationName: mutatingWebhookConfigurationName,
+			CertDir:                          certDir,
+		}
+		if err := certRotator.Ensure(context.Background()); err != nil {
+			setupLog.Error(err, "unable to provision webhook certificates")
+			os.Exit(1)
+		}
+	}
+
+	var quotaPolicy *webhook.QuotaPolicy
+	if quotaPolicyFile != "" {
+		var err error
+		quotaPolicy, err = webhook.LoadQuotaPolicy(quotaPolicyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load quota policy")
+			os.Exit(1)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                server.Options{BindAddress: metricsAddr},
@@ -106,20 +169,44 @@ func main() {
 	}
 
 	if err = (&webhook.BundleDeployment{
-		Client:          mgr.GetClient(),
-		SystemNamespace: systemNamespace,
+		Client:              mgr.GetClient(),
+		SystemNamespace:     systemNamespace,
+		RequireImageDigests: requireImageDigests,
+		QuotaPolicy:         quotaPolicy,
 	}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", rukpakv1alpha2.BundleDeploymentKind)
 		os.Exit(1)
 	}
+	if err = (&webhook.ImageDigestResolver{
+		Enabled:       resolveImageDigests,
+		AuthNamespace: systemNamespace,
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ImageDigestResolver")
+		os.Exit(1)
+	}
 	if err = (&webhook.ConfigMap{
-		Client: mgr.GetClient(),
+		Client:          mgr.GetClient(),
+		SystemNamespace: systemNamespace,
 	}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "ConfigMap")
 		os.Exit(1)
 	}
+	if err = (&webhook.Secret{
+		Client:          mgr.GetClient(),
+		SystemNamespace: systemNamespace,
+	}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Secret")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
+	if certRotator != nil {
+		if err := mgr.Add(certRotator); err != nil {
+			setupLog.Error(err, "unable to set up webhook certificate rotation")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
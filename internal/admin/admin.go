@@ -0,0 +1,394 @@
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/coreconfig"
+	"github.com/operator-framework/rukpak/pkg/source"
+	"github.com/operator-framework/rukpak/pkg/storage"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// PathPrefix is the path under which the admin API is served.
+	PathPrefix = "/admin/v1"
+
+	// UnpackRequestedAnnotation is set on a BundleDeployment when a re-unpack
+	// is requested through the admin API. Changing it triggers a reconcile of
+	// the BundleDeployment.
+	UnpackRequestedAnnotation = "core.rukpak.io/unpack-requested-at"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeployments,verbs=get;list;watch;patch
+
+// Storage is the bundle storage that is exposed by the admin API.
+type Storage interface {
+	storage.Lister
+	Delete(ctx context.Context, owner client.Object) error
+	Size(ctx context.Context, owner client.Object) (int64, error)
+}
+
+// Bundle describes the stored content of a BundleDeployment.
+type Bundle struct {
+	// Name is the name of the BundleDeployment that the content belongs to.
+	Name string `json:"name"`
+	// Digest is the digest of the unpacked content, as reported in the
+	// resolved source of the BundleDeployment. It is empty if the
+	// BundleDeployment does not exist anymore.
+	Digest string `json:"digest,omitempty"`
+	// Size is the size in bytes of the stored content archive.
+	Size int64 `json:"size"`
+	// Orphaned is true if the BundleDeployment does not exist anymore.
+	Orphaned bool `json:"orphaned,omitempty"`
+}
+
+// Handler serves the admin API, which allows operational tooling to inspect
+// and manage the stored bundle content without exec access to the pod:
+//
+//	GET    /admin/v1/bundles                    lists the stored bundles
+//	GET    /admin/v1/bundles/{name}             describes a stored bundle
+//	POST   /admin/v1/bundles/{name}/unpack      forces a re-unpack of a bundle
+//	DELETE /admin/v1/bundles/{name}             purges the content of an orphaned bundle
+//	POST   /admin/v1/bundles/{name}/signed-url  issues an expiring signed content URL of a bundle
+//	GET    /admin/v1/loglevel                   returns the log level
+//	PUT    /admin/v1/loglevel                   changes the log level
+//
+// Signed content URLs are only issued if a URL signer is configured, and the
+// log level can only be changed if it is configured.
+type Handler struct {
+	cl       client.Client
+	store    Storage
+	unpacker source.Unpacker
+	signer   *storage.URLSigner
+	logLevel LogLevel
+	mux      *http.ServeMux
+}
+
+// LogLevel is the level of the logger of the process, such as a
+// zap.AtomicLevel, which the admin API changes at runtime.
+type LogLevel interface {
+	Level() zapcore.Level
+	SetLevel(zapcore.Level)
+}
+
+func NewHandler(cl client.Client, store Storage, unpacker source.Unpacker, signer *storage.URLSigner, logLevel LogLevel) *Handler {
+	h := &Handler{cl: cl, store: store, unpacker: unpacker, signer: signer, logLevel: logLevel, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET "+PathPrefix+"/bundles", h.listBundles)
+	h.mux.HandleFunc("GET "+PathPrefix+"/bundles/{name}", h.getBundle)
+	h.mux.HandleFunc("POST "+PathPrefix+"/bundles/{name}/unpack", h.unpackBundle)
+	h.mux.HandleFunc("DELETE "+PathPrefix+"/bundles/{name}", h.purgeBundle)
+	h.mux.HandleFunc("POST "+PathPrefix+"/bundles/{name}/signed-url", h.signContentURL)
+	h.mux.HandleFunc("GET "+PathPrefix+"/loglevel", h.getLogLevel)
+	h.mux.HandleFunc("PUT "+PathPrefix+"/loglevel", h.setLogLevel)
+	return h
+}
+
+func (h *Handler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.mux.ServeHTTP(resp, req)
+}
+
+func (h *Handler) listBundles(resp http.ResponseWriter, req *http.Request) {
+	names, err := h.store.List(req.Context())
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("list stored bundles: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sort.Strings(names)
+	bundles := make([]Bundle, 0, len(names))
+	for _, name := range names {
+		bundle, err := h.describe(req.Context(), name)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		bundles = append(bundles, *bundle)
+	}
+	writeJSON(resp, http.StatusOK, bundles)
+}
+
+func (h *Handler) getBundle(resp http.ResponseWriter, req *http.Request) {
+	name, ok := bundleName(resp, req)
+	if !ok {
+		return
+	}
+	bundle, err := h.describe(req.Context(), name)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if bundle.Orphaned && bundle.Size == 0 {
+		http.Error(resp, fmt.Sprintf("bundle %q not found", name), http.StatusNotFound)
+		return
+	}
+	writeJSON(resp, http.StatusOK, bundle)
+}
+
+// unpackBundle purges the unpack cache of the BundleDeployment and triggers a
+// reconcile of it, so that its content is fetched from its source again.
+func (h *Handler) unpackBundle(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	name, ok := bundleName(resp, req)
+	if !ok {
+		return
+	}
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	if err := h.cl.Get(ctx, types.NamespacedName{Name: name}, bd); err != nil {
+		writeGetError(resp, name, err)
+		return
+	}
+	if err := h.unpacker.Cleanup(ctx, bd); err != nil {
+		http.Error(resp, fmt.Sprintf("purge unpack cache of bundle %q: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	patch := client.MergeFrom(bd.DeepCopy())
+	metav1.SetMetaDataAnnotation(&bd.ObjectMeta, UnpackRequestedAnnotation, time.Now().UTC().Format(time.RFC3339Nano))
+	if err := h.cl.Patch(ctx, bd, patch); err != nil {
+		http.Error(resp, fmt.Sprintf("request reconcile of bundle %q: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	log.FromContext(ctx).Info("requested re-unpack", "bundleDeployment", name)
+	resp.WriteHeader(http.StatusAccepted)
+}
+
+// purgeBundle deletes the stored content of a BundleDeployment that does not
+// exist anymore. The content of existing BundleDeployments is stored again on
+// every reconcile, so it is refreshed with a re-unpack instead.
+func (h *Handler) purgeBundle(resp http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	name, ok := bundleName(resp, req)
+	if !ok {
+		return
+	}
+	err := h.cl.Get(ctx, types.NamespacedName{Name: name}, &rukpakv1alpha2.BundleDeployment{})
+	switch {
+	case err == nil:
+		http.Error(resp, fmt.Sprintf("bundle %q is in use by its BundleDeployment; request a re-unpack instead", name), http.StatusConflict)
+		return
+	case !apierrors.IsNotFound(err):
+		writeGetError(resp, name, err)
+		return
+	}
+	if err := h.store.Delete(ctx, ownerOf(name)); err != nil {
+		http.Error(resp, fmt.Sprintf("purge bundle %q: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	log.FromContext(ctx).Info("purged stored bundle", "bundleDeployment", name)
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// SignedURL is an expiring signed content URL of a bundle.
+type SignedURL struct {
+	// URL is the signed content URL, which can be fetched without cluster
+	// RBAC permissions until it expires.
+	URL string `json:"url"`
+	// ExpiresAt is the time at which the URL expires.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// signContentURL issues a signed URL for the published content URL of the
+// BundleDeployment.
+func (h *Handler) signContentURL(resp http.ResponseWriter, req *http.Request) {
+	if h.signer == nil {
+		http.Error(resp, "signed content URLs are disabled", http.StatusNotFound)
+		return
+	}
+	ctx := req.Context()
+	name, ok := bundleName(resp, req)
+	if !ok {
+		return
+	}
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	if err := h.cl.Get(ctx, types.NamespacedName{Name: name}, bd); err != nil {
+		writeGetError(resp, name, err)
+		return
+	}
+	if bd.Status.ContentURL == "" {
+		http.Error(resp, fmt.Sprintf("bundle deployment %q has no content URL yet", name), http.StatusConflict)
+		return
+	}
+	signed, expires, err := h.signer.Sign(bd.Status.ContentURL, time.Now())
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("sign content URL of bundle %q: %v", name, err), http.StatusInternalServerError)
+		return
+	}
+	log.FromContext(ctx).Info("issued signed content URL", "bundleDeployment", name, "expiresAt", expires)
+	writeJSON(resp, http.StatusOK, SignedURL{URL: signed, ExpiresAt: metav1.NewTime(expires)})
+}
+
+// LogLevelSetting is the log level of the process, in the format of the
+// --zap-log-level flag: debug, info, error, panic, or an integer greater than
+// zero for increasingly verbose debug logs.
+type LogLevelSetting struct {
+	Level string `json:"level"`
+}
+
+func (h *Handler) getLogLevel(resp http.ResponseWriter, _ *http.Request) {
+	if h.logLevel == nil {
+		http.Error(resp, "changing the log level is disabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(resp, http.StatusOK, LogLevelSetting{Level: formatLogLevel(h.logLevel.Level())})
+}
+
+// setLogLevel changes the log level until it is changed again or the process
+// restarts.
+func (h *Handler) setLogLevel(resp http.ResponseWriter, req *http.Request) {
+	if h.logLevel == nil {
+		http.Error(resp, "changing the log level is disabled", http.StatusNotFound)
+		return
+	}
+	var setting LogLevelSetting
+	if err := json.NewDecoder(req.Body).Decode(&setting); err != nil {
+		http.Error(resp, fmt.Sprintf("decode log level: %v", err), http.StatusBadRequest)
+		return
+	}
+	level, err := coreconfig.ParseLogLevel(setting.Level)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	previous := h.logLevel.Level()
+	h.logLevel.SetLevel(level)
+	log.FromContext(req.Context()).Info("changed log level", "from", formatLogLevel(previous), "to", formatLogLevel(level))
+	writeJSON(resp, http.StatusOK, LogLevelSetting{Level: formatLogLevel(level)})
+}
+
+// formatLogLevel formats a level like coreconfig.ParseLogLevel parses it.
+func formatLogLevel(level zapcore.Level) string {
+	if level < zapcore.DebugLevel {
+		return strconv.Itoa(-int(level))
+	}
+	return level.String()
+}
+
+// describe returns the stored content of the named BundleDeployment. A
+// missing archive is reported with a size of zero, since a report may be
+// stored without content.
+func (h *Handler) describe(ctx context.Context, name string) (*Bundle, error) {
+	bundle := &Bundle{Name: name}
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	if err := h.cl.Get(ctx, types.NamespacedName{Name: name}, bd); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get bundle deployment %q: %v", name, err)
+		}
+		bundle.Orphaned = true
+	} else if bd.Status.ResolvedSource != nil {
+		bundle.Digest = bd.Status.ResolvedSource.BundleDigest
+	}
+	size, err := h.store.Size(ctx, ownerOf(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("get size of bundle %q: %v", name, err)
+	}
+	bundle.Size = size
+	return bundle, nil
+}
+
+// bundleName returns the BundleDeployment name of the request path. Names
+// that are not valid object names, such as percent-encoded ".." segments, are
+// rejected before they reach the cluster or the storage, which uses them as
+// directory names.
+func bundleName(resp http.ResponseWriter, req *http.Request) (string, bool) {
+	name := req.PathValue("name")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		http.Error(resp, fmt.Sprintf("invalid bundle deployment name %q: %s", name, strings.Join(errs, ", ")), http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+func ownerOf(name string) client.Object {
+	return &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func writeGetError(resp http.ResponseWriter, name string, err error) {
+	if apierrors.IsNotFound(err) {
+		http.Error(resp, fmt.Sprintf("bundle deployment %q not found", name), http.StatusNotFound)
+		return
+	}
+	http.Error(resp, fmt.Sprintf("get bundle deployment %q: %v", name, err), http.StatusInternalServerError)
+}
+
+func writeJSON(resp http.ResponseWriter, status int, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	_ = json.NewEncoder(resp).Encode(v)
+}
+
+var _ manager.LeaderElectionRunnable = &Server{}
+
+// Server serves a handler over TLS and only accepts clients that present a
+// certificate signed by one of the client certificate authorities.
+type Server struct {
+	Addr      string
+	Handler   http.Handler
+	TLSConfig *tls.Config
+}
+
+// NewTLSConfig returns a TLS config that requires client certificates signed
+// by a certificate authority in clientCAFile. The serving certificate is
+// obtained from getCertificate, so that it can be rotated.
+func NewTLSConfig(clientCAFile string, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*tls.Config, error) {
+	clientCAs, err := util.LoadCertPool(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate authorities: %v", err)
+	}
+	return &tls.Config{
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		ClientCAs:      clientCAs,
+		GetCertificate: getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}, nil
+}
+
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := tls.Listen("tcp", s.Addr, s.TLSConfig)
+	if err != nil {
+		return err
+	}
+	return s.serve(ctx, ln)
+}
+
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler,
+		ReadHeaderTimeout: 30 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
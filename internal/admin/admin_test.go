@@ -0,0 +1,269 @@
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/storage"
+	rukpaktesting "github.com/operator-framework/rukpak/pkg/testing"
+)
+
+func newTestHandler(t *testing.T) (*Handler, client.Client, *storage.LocalDirectory, *rukpaktesting.Unpacker) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "live"},
+		Status: rukpakv1alpha2.BundleDeploymentStatus{
+			ResolvedSource: &rukpakv1alpha2.BundleSource{BundleDigest: "sha256:abc"},
+			ContentURL:     "https://core.rukpak-system.svc/bundles/live.tgz",
+		},
+	}).Build()
+	// The storage is nested in the test directory, so that a request that
+	// escapes it cannot delete anything outside of the test directory.
+	store := &storage.LocalDirectory{RootDirectory: filepath.Join(t.TempDir(), "a", "b", "storage")}
+	require.NoError(t, os.MkdirAll(store.RootDirectory, 0700))
+	bundle := fstest.MapFS{"manifests/cm.yaml": &fstest.MapFile{Data: []byte("kind: ConfigMap")}}
+	for _, name := range []string{"live", "orphan"} {
+		require.NoError(t, store.Store(context.Background(), ownerOf(name), bundle))
+	}
+	unpacker := &rukpaktesting.Unpacker{}
+	signer := &storage.URLSigner{Key: []byte("0123456789abcdef0123456789abcdef"), TTL: time.Minute}
+	return NewHandler(cl, store, unpacker, signer, zap.NewAtomicLevelAt(zapcore.InfoLevel)), cl, store, unpacker
+}
+
+func TestListBundles(t *testing.T) {
+	h, _, store, _ := newTestHandler(t)
+	size, err := store.Size(context.Background(), ownerOf("live"))
+	require.NoError(t, err)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, PathPrefix+"/bundles", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var bundles []Bundle
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &bundles))
+	require.Equal(t, []Bundle{
+		{Name: "live", Digest: "sha256:abc", Size: size},
+		{Name: "orphan", Size: size, Orphaned: true},
+	}, bundles)
+}
+
+func TestGetBundle(t *testing.T) {
+	h, _, _, _ := newTestHandler(t)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, PathPrefix+"/bundles/live", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var bundle Bundle
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &bundle))
+	require.Equal(t, "sha256:abc", bundle.Digest)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, PathPrefix+"/bundles/missing", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestUnpackBundle(t *testing.T) {
+	h, cl, _, unpacker := newTestHandler(t)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, PathPrefix+"/bundles/live/unpack", nil))
+	require.Equal(t, http.StatusAccepted, resp.Code)
+	require.Equal(t, []string{"live"}, unpacker.CleanedUp())
+
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	require.NoError(t, cl.Get(context.Background(), types.NamespacedName{Name: "live"}, bd))
+	require.Contains(t, bd.Annotations, UnpackRequestedAnnotation)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, PathPrefix+"/bundles/orphan/unpack", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestPurgeBundle(t *testing.T) {
+	h, _, store, _ := newTestHandler(t)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, PathPrefix+"/bundles/live", nil))
+	require.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, PathPrefix+"/bundles/orphan", nil))
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	names, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"live"}, names)
+}
+
+func TestRejectsPathTraversal(t *testing.T) {
+	h, _, store, unpacker := newTestHandler(t)
+	sentinel := filepath.Join(filepath.Dir(store.RootDirectory), "sentinel")
+	require.NoError(t, os.WriteFile(sentinel, nil, 0600))
+
+	for _, name := range []string{"%2E%2E", "..%2F..%2Ftmp", "%2E%2E%2F%2E%2E", "live%2F..", "Live"} {
+		for _, r := range []struct{ method, path string }{
+			{http.MethodGet, "/bundles/" + name},
+			{http.MethodPost, "/bundles/" + name + "/unpack"},
+			{http.MethodDelete, "/bundles/" + name},
+			{http.MethodPost, "/bundles/" + name + "/signed-url"},
+		} {
+			t.Run(r.method+" "+r.path, func(t *testing.T) {
+				resp := httptest.NewRecorder()
+				h.ServeHTTP(resp, httptest.NewRequest(r.method, PathPrefix+r.path, nil))
+				require.Equal(t, http.StatusBadRequest, resp.Code)
+			})
+		}
+	}
+
+	_, err := os.Stat(sentinel)
+	require.NoError(t, err)
+	names, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"live", "orphan"}, names)
+	require.Empty(t, unpacker.CleanedUp())
+}
+
+func TestSignContentURL(t *testing.T) {
+	h, _, _, _ := newTestHandler(t)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, PathPrefix+"/bundles/live/signed-url", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var signed SignedURL
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &signed))
+	require.Contains(t, signed.URL, "https://core.rukpak-system.svc/signed/bundles/live.tgz?expires=")
+	require.WithinDuration(t, time.Now().Add(time.Minute), signed.ExpiresAt.Time, 2*time.Second)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, PathPrefix+"/bundles/orphan/signed-url", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	h.signer = nil
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, PathPrefix+"/bundles/live/signed-url", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestLogLevel(t *testing.T) {
+	h, _, _, _ := newTestHandler(t)
+	getLevel := func() string {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, PathPrefix+"/loglevel", nil))
+		require.Equal(t, http.StatusOK, resp.Code)
+		var setting LogLevelSetting
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &setting))
+		return setting.Level
+	}
+	setLevel := func(body string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, PathPrefix+"/loglevel", strings.NewReader(body)))
+		return resp
+	}
+
+	require.Equal(t, "info", getLevel())
+	require.Equal(t, http.StatusOK, setLevel(`{"level":"5"}`).Code)
+	require.Equal(t, "5", getLevel())
+	require.Equal(t, zapcore.Level(-5), h.logLevel.Level())
+	require.Equal(t, http.StatusOK, setLevel(`{"level":"debug"}`).Code)
+	require.Equal(t, "debug", getLevel())
+	require.Equal(t, http.StatusBadRequest, setLevel(`{"level":"verbose"}`).Code)
+	require.Equal(t, "debug", getLevel())
+
+	h.logLevel = nil
+	require.Equal(t, http.StatusNotFound, setLevel(`{"level":"info"}`).Code)
+}
+
+func TestServerRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCertificate(t, nil, nil, "ca")
+	serverCert := tlsCertificate(newCertificate(t, ca, caKey, "127.0.0.1"))
+	clientCert := tlsCertificate(newCertificate(t, ca, caKey, "client"))
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600))
+
+	tlsConfig, err := NewTLSConfig(caFile, func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &serverCert, nil })
+	require.NoError(t, err)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	srv := &Server{Handler: http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		resp.WriteHeader(http.StatusOK)
+	})}
+	go func() { _ = srv.serve(ctx, ln) }()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca)
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		cl := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs, Certificates: certs}}}
+		return cl.Get("https://" + ln.Addr().String() + PathPrefix + "/bundles")
+	}
+
+	resp, err := get(clientCert)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = get()
+	require.Error(t, err)
+}
+
+func newCertificate(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func tlsCertificate(cert *x509.Certificate, key *ecdsa.PrivateKey) tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Analyzer evaluates analysis queries.
+type Analyzer interface {
+	// Breached evaluates the PromQL expression expr against the Prometheus
+	// HTTP API at prometheusURL and reports whether it returned any samples.
+	Breached(ctx context.Context, prometheusURL, expr string) (bool, error)
+}
+
+// Prometheus is an Analyzer that uses the instant query endpoint of the
+// Prometheus HTTP API.
+type Prometheus struct {
+	// Client is the http client used to send queries. If unset, a client with
+	// a 10s timeout is used.
+	Client *http.Client
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// queryResponse is the subset of the Prometheus query response envelope
+// that is needed to decide whether a query returned any samples.
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string            `json:"resultType"`
+		Result     []json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (p *Prometheus) Breached(ctx context.Context, prometheusURL, expr string) (bool, error) {
+	endpoint := strings.TrimSuffix(prometheusURL, "/") + "/api/v1/query?" + url.Values{"query": {expr}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, fmt.Errorf("create prometheus query request: %v", err)
+	}
+	cl := p.Client
+	if cl == nil {
+		cl = defaultClient
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("prometheus query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("read prometheus query response: %v", err)
+	}
+	var qr queryResponse
+	if err := json.Unmarshal(body, &qr); err != nil {
+		return false, fmt.Errorf("unexpected prometheus query response with status %q: %v", resp.Status, err)
+	}
+	if qr.Status != "success" {
+		return false, fmt.Errorf("prometheus query failed: %s: %s", qr.ErrorType, qr.Error)
+	}
+	switch qr.Data.ResultType {
+	case "vector", "matrix":
+		return len(qr.Data.Result) > 0, nil
+	default:
+		return false, fmt.Errorf("unsupported prometheus result type %q: queries must return an instant vector", qr.Data.ResultType)
+	}
+}
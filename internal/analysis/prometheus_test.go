@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusBreached(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		response    string
+		expected    bool
+		expectedErr string
+	}{
+		{
+			description: "empty vector",
+			response:    `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		},
+		{
+			description: "non-empty vector",
+			response:    `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"1"]}]}}`,
+			expected:    true,
+		},
+		{
+			description: "scalar",
+			response:    `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"1"]}}`,
+			expectedErr: "unsupported prometheus result type",
+		},
+		{
+			description: "query error",
+			response:    `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			expectedErr: "bad_data: parse error",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/api/v1/query", r.URL.Path)
+				require.Equal(t, `rate(errors_total[5m]) > 1`, r.URL.Query().Get("query"))
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			breached, err := (&Prometheus{}).Breached(context.Background(), srv.URL+"/", `rate(errors_total[5m]) > 1`)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, breached)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/installreport"
+)
+
+const (
+	defaultAnalysisInterval = 30 * time.Second
+
+	// rollbackDescriptionPrefix marks the description of releases that were
+	// created by rolling back a release after a breached analysis.
+	rollbackDescriptionPrefix = "Rollback to "
+)
+
+// rollbackPinned returns true if the installed release was rolled back after
+// a breached analysis and the spec has not changed since, in which case the
+// rolled back release must not be upgraded again.
+func rollbackPinned(bd *rukpakv1alpha2.BundleDeployment) bool {
+	cond := bd.CurrentCondition(rukpakv1alpha2.TypeRollbackPerformed)
+	return cond != nil && cond.Status == metav1.ConditionTrue
+}
+
+// analyze evaluates the analysis queries of bd while rel is within its soak
+// period, and rolls rel back to the previous release if any query is
+// breached. It returns whether rel was rolled back, and when the queries
+// should be evaluated again.
+func (c *controller) analyze(ctx context.Context, cl helmclient.ActionInterface, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release, post *postrenderer) (bool, ctrl.Result, error) {
+	cfg := bd.Spec.Analysis
+	if cfg == nil || rel.Version <= 1 || rel.Info == nil || strings.HasPrefix(rel.Info.Description, rollbackDescriptionPrefix) || rollbackPinned(bd) {
+		return false, ctrl.Result{}, nil
+	}
+	remaining := time.Until(rel.Info.LastDeployed.Time.Add(cfg.SoakPeriod.Duration))
+	if remaining <= 0 {
+		return false, ctrl.Result{}, nil
+	}
+
+	l := log.FromContext(ctx)
+	for _, q := range cfg.Queries {
+		breached, err := c.analyzer.Breached(ctx, cfg.PrometheusURL, q.Expr)
+		if err != nil {
+			// An unavailable Prometheus must not roll back the upgrade, so the
+			// query is retried on the next evaluation instead.
+			l.Error(err, "failed to evaluate analysis query", "query", q.Name)
+			continue
+		}
+		if !breached {
+			continue
+		}
+
+		previous, err := cl.Get(bd.Name, func(get *action.Get) error {
+			get.Version = rel.Version - 1
+			return nil
+		})
+		var rolledBack *release.Release
+		if err == nil {
+			if err := c.storeUpgradeDiff(ctx, bd, rel.Version+1, rel.Manifest, previous.Manifest); err != nil {
+				l.Error(err, "failed to store upgrade diff", "revision", rel.Version+1)
+			}
+			rolledBack, err = cl.Upgrade(bd.Name, bd.Spec.InstallNamespace, previous.Chart, previous.Config, func(upgrade *action.Upgrade) error {
+				upgrade.Description = fmt.Sprintf("%s%d", rollbackDescriptionPrefix, previous.Version)
+				return nil
+			}, c.upgradeMaxHistory, helmclient.AppendUpgradePostRenderer(post))
+		}
+		if err != nil {
+			err = fmt.Errorf("roll back revision %d after analysis query %q was breached: %v", rel.Version, q.Name, err)
+			meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+				Type:               rukpakv1alpha2.TypeRollbackPerformed,
+				Status:             metav1.ConditionFalse,
+				Reason:             rukpakv1alpha2.ReasonRollbackFailed,
+				Message:            err.Error(),
+				ObservedGeneration: bd.Generation,
+			})
+			return false, ctrl.Result{}, err
+		}
+		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+			Type:               rukpakv1alpha2.TypeRollbackPerformed,
+			Status:             metav1.ConditionTrue,
+			Reason:             rukpakv1alpha2.ReasonAnalysisBreached,
+			Message:            fmt.Sprintf("Analysis query %q was breached during the soak period of revision %d, rolled back to revision %d", q.Name, rel.Version, previous.Version),
+			ObservedGeneration: bd.Generation,
+		})
+		if err := c.storeInstallReport(ctx, bd, rolledBack, installreport.ActionRollback); err != nil {
+			l.Error(err, "failed to store install report", "revision", rolledBack.Version)
+		}
+		return true, ctrl.Result{}, nil
+	}
+
+	interval := defaultAnalysisInterval
+	if cfg.Interval != nil && cfg.Interval.Duration > 0 {
+		interval = cfg.Interval.Duration
+	}
+	return false, ctrl.Result{RequeueAfter: min(interval, remaining)}, nil
+}
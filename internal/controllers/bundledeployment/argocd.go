@@ -0,0 +1,129 @@
+package bundledeployment
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// ArgoCDTrackingMethod is how the objects of releases are marked as resources
+// of an Argo CD application. The methods are named like the values of the
+// application.resourceTrackingMethod setting of Argo CD.
+type ArgoCDTrackingMethod string
+
+const (
+	ArgoCDTrackingLabel              ArgoCDTrackingMethod = "label"
+	ArgoCDTrackingAnnotation         ArgoCDTrackingMethod = "annotation"
+	ArgoCDTrackingAnnotationAndLabel ArgoCDTrackingMethod = "annotation+label"
+)
+
+const (
+	argoCDInstanceLabel            = "app.kubernetes.io/instance"
+	argoCDTrackingIDAnnotation     = "argocd.argoproj.io/tracking-id"
+	argoCDCompareOptionsAnnotation = "argocd.argoproj.io/compare-options"
+	argoCDSyncOptionsAnnotation    = "argocd.argoproj.io/sync-options"
+)
+
+// ParseArgoCDTrackingMethod parses s as an ArgoCDTrackingMethod. An empty s
+// disables tracking.
+func ParseArgoCDTrackingMethod(s string) (ArgoCDTrackingMethod, error) {
+	switch m := ArgoCDTrackingMethod(s); m {
+	case "", ArgoCDTrackingLabel, ArgoCDTrackingAnnotation, ArgoCDTrackingAnnotationAndLabel:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown Argo CD tracking method %q: must be one of %q, %q or %q", s, ArgoCDTrackingLabel, ArgoCDTrackingAnnotation, ArgoCDTrackingAnnotationAndLabel)
+}
+
+// argoCDTracking marks the objects of a release as resources of the Argo CD
+// application that the BundleDeployment belongs to.
+type argoCDTracking struct {
+	method           ArgoCDTrackingMethod
+	appName          string
+	installNamespace string
+	// isNamespaced reports whether an object is namespaced, so that the
+	// tracking IDs of namespaced objects without a namespace name the
+	// install namespace, like Argo CD expects.
+	isNamespaced func(*unstructured.Unstructured) bool
+}
+
+// newArgoCDTracking returns the tracking of the objects of bd, or nil if
+// method disables tracking.
+func newArgoCDTracking(method ArgoCDTrackingMethod, bd *rukpakv1alpha2.BundleDeployment, isNamespaced func(*unstructured.Unstructured) bool) *argoCDTracking {
+	if method == "" {
+		return nil
+	}
+	return &argoCDTracking{
+		method:           method,
+		appName:          argoCDAppName(bd),
+		installNamespace: bd.Spec.InstallNamespace,
+		isNamespaced:     isNamespaced,
+	}
+}
+
+// argoCDAppName returns the name of the Argo CD application that bd belongs
+// to: the application that tracks bd itself, if any, or else an application
+// named like bd.
+func argoCDAppName(bd *rukpakv1alpha2.BundleDeployment) string {
+	if id := bd.Annotations[argoCDTrackingIDAnnotation]; id != "" {
+		app, _, _ := strings.Cut(id, ":")
+		return app
+	}
+	if app := bd.Labels[argoCDInstanceLabel]; app != "" {
+		return app
+	}
+	return bd.Name
+}
+
+// apply marks obj as a resource of the application. Unless obj says
+// otherwise, Argo CD neither reports it as out of sync nor prunes it, since it
+// is not part of the manifests of the application.
+func (t *argoCDTracking) apply(obj *unstructured.Unstructured) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if t.method != ArgoCDTrackingAnnotation {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[argoCDInstanceLabel] = t.appName
+		obj.SetLabels(labels)
+	}
+	if t.method != ArgoCDTrackingLabel {
+		annotations[argoCDTrackingIDAnnotation] = t.trackingID(obj)
+	}
+	if _, ok := annotations[argoCDCompareOptionsAnnotation]; !ok {
+		annotations[argoCDCompareOptionsAnnotation] = "IgnoreExtraneous"
+	}
+	if _, ok := annotations[argoCDSyncOptionsAnnotation]; !ok {
+		annotations[argoCDSyncOptionsAnnotation] = "Prune=false"
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// trackingID returns the tracking ID of obj in the
+// "<app>:<group>/<kind>:<namespace>/<name>" format of Argo CD.
+func (t *argoCDTracking) trackingID(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	namespace := obj.GetNamespace()
+	if namespace == "" && t.isNamespaced(obj) {
+		namespace = t.installNamespace
+	}
+	return fmt.Sprintf("%s:%s/%s:%s/%s", t.appName, gvk.Group, gvk.Kind, namespace, obj.GetName())
+}
+
+// isNamespacedIn returns a function that reports whether an object is
+// namespaced in the cluster of cl. Objects of kinds that are not served yet,
+// such as the objects of CRDs of the same release, are assumed to be
+// namespaced, like most custom resources are.
+func isNamespacedIn(cl client.Client) func(*unstructured.Unstructured) bool {
+	return func(obj *unstructured.Unstructured) bool {
+		namespaced, err := cl.IsObjectNamespaced(obj)
+		return err != nil || namespaced
+	}
+}
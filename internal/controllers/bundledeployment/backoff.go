@@ -0,0 +1,90 @@
+package bundledeployment
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+var _ ratelimiter.RateLimiter = &backoff{}
+
+// backoff is the rate limiter of the queue of the controller. The backoff of
+// a failed reconcile is determined by the reconcile itself, before the
+// controller requeues the request, so that the time of the retry can be
+// published in the status of the BundleDeployment.
+type backoff struct {
+	limiter workqueue.RateLimiter
+
+	mu      sync.Mutex
+	pending map[interface{}]time.Duration
+}
+
+func newBackoff() *backoff {
+	return &backoff{limiter: workqueue.DefaultControllerRateLimiter(), pending: map[interface{}]time.Duration{}}
+}
+
+// next returns how long the controller backs off before it retries item,
+// whose reconcile failed. The next call of When for item returns the same
+// delay.
+func (b *backoff) next(item interface{}) time.Duration {
+	delay := b.limiter.When(item)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[item] = delay
+	return delay
+}
+
+func (b *backoff) When(item interface{}) time.Duration {
+	b.mu.Lock()
+	delay, ok := b.pending[item]
+	delete(b.pending, item)
+	b.mu.Unlock()
+	if ok {
+		return delay
+	}
+	return b.limiter.When(item)
+}
+
+func (b *backoff) Forget(item interface{}) {
+	b.mu.Lock()
+	delete(b.pending, item)
+	b.mu.Unlock()
+	b.limiter.Forget(item)
+}
+
+func (b *backoff) NumRequeues(item interface{}) int {
+	return b.limiter.NumRequeues(item)
+}
+
+// ignoreFailureStatusUpdates filters updates of BundleDeployments that only
+// change the fields of the status that record a failed reconcile. Otherwise,
+// recording a failure would trigger the retry right away, before the backoff
+// expires.
+func ignoreFailureStatusUpdates() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldBD, oldOK := e.ObjectOld.(*rukpakv1alpha2.BundleDeployment)
+			newBD, newOK := e.ObjectNew.(*rukpakv1alpha2.BundleDeployment)
+			if !oldOK || !newOK {
+				return true
+			}
+			return !equality.Semantic.DeepEqual(withoutFailureStatus(oldBD), withoutFailureStatus(newBD))
+		},
+	}
+}
+
+func withoutFailureStatus(bd *rukpakv1alpha2.BundleDeployment) *rukpakv1alpha2.BundleDeployment {
+	bd = bd.DeepCopy()
+	bd.ResourceVersion = ""
+	bd.ManagedFields = nil
+	bd.Status.LastFailure = nil
+	bd.Status.NextReconcileTime = nil
+	return bd
+}
@@ -3,11 +3,15 @@ package bundledeployment
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
@@ -16,6 +20,8 @@ import (
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -23,8 +29,12 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/lru"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
@@ -34,15 +44,26 @@ import (
 	crhandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/yaml"
 
 	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
-	"github.com/operator-framework/rukpak/internal/healthchecks"
+	"github.com/operator-framework/rukpak/internal/analysis"
+	"github.com/operator-framework/rukpak/internal/externaladdress"
+	"github.com/operator-framework/rukpak/internal/metrics"
+	"github.com/operator-framework/rukpak/internal/patches"
+	"github.com/operator-framework/rukpak/internal/requirements"
+	"github.com/operator-framework/rukpak/internal/target"
+	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
 	"github.com/operator-framework/rukpak/pkg/features"
 	"github.com/operator-framework/rukpak/pkg/handler"
+	"github.com/operator-framework/rukpak/pkg/healthchecks"
 	helmpredicate "github.com/operator-framework/rukpak/pkg/helm-operator-plugins/predicate"
+	"github.com/operator-framework/rukpak/pkg/preflights/crdvalidation"
+	"github.com/operator-framework/rukpak/pkg/snapshot"
 	unpackersource "github.com/operator-framework/rukpak/pkg/source"
 	"github.com/operator-framework/rukpak/pkg/storage"
 	"github.com/operator-framework/rukpak/pkg/util"
@@ -102,6 +123,162 @@ func WithActionClientGetter(acg helmclient.ActionClientGetter) Option {
 	}
 }
 
+// WithArgoCDTracking marks the objects of releases as resources of the Argo CD
+// application that their BundleDeployment belongs to, with the given tracking
+// method. An empty method disables tracking.
+func WithArgoCDTracking(method ArgoCDTrackingMethod) Option {
+	return func(c *controller) {
+		c.argoCDTrackingMethod = method
+	}
+}
+
+// WithReleaseTester configures the tester that runs the test hooks of a
+// release when a test run is requested with the test-requested annotation.
+func WithReleaseTester(t ReleaseTester) Option {
+	return func(c *controller) {
+		c.tester = t
+	}
+}
+
+// WithReleaseCompleter configures the completer that marks a failed release as
+// deployed once its failed objects have been applied one at a time. Without a
+// completer, failed releases are upgraded again as a whole.
+func WithReleaseCompleter(rc ReleaseCompleter) Option {
+	return func(c *controller) {
+		c.completer = rc
+	}
+}
+
+func WithAnalyzer(a analysis.Analyzer) Option {
+	return func(c *controller) {
+		c.analyzer = a
+	}
+}
+
+// WithInstallReportSigner configures the key that install reports are signed
+// with. Install reports are not signed if no signer is configured.
+func WithInstallReportSigner(s crypto.Signer) Option {
+	return func(c *controller) {
+		c.reportSigner = s
+	}
+}
+
+// WithMetadataOnlyWatches configures the kinds of dependent resources that are
+// watched by their metadata only, instead of ConfigMaps and Secrets. Every
+// update of such a resource triggers a reconcile, so only kinds without a
+// status should be watched by their metadata.
+func WithMetadataOnlyWatches(gvks ...schema.GroupVersionKind) Option {
+	return func(c *controller) {
+		c.metadataOnlyGVKs = make(map[schema.GroupVersionKind]struct{}, len(gvks))
+		for _, gvk := range gvks {
+			c.metadataOnlyGVKs[gvk] = struct{}{}
+		}
+	}
+}
+
+// WithMaxHistory configures the maximum number of release revisions that are
+// kept per BundleDeployment. Older revisions are pruned on upgrades. Zero
+// means no limit.
+func WithMaxHistory(maxHistory int) Option {
+	return func(c *controller) {
+		c.maxHistory = maxHistory
+	}
+}
+
+// WithGenerateNameKinds configures the kinds of objects whose generateName is
+// replaced by a name with a suffix that is derived from the name of the
+// BundleDeployment. This allows bundles to use generateName for objects that
+// would otherwise collide when the bundle is installed more than once, while
+// keeping the names stable across upgrades.
+func WithGenerateNameKinds(gks ...schema.GroupKind) Option {
+	return func(c *controller) {
+		c.generateNameKinds = make(map[schema.GroupKind]struct{}, len(gks))
+		for _, gk := range gks {
+			c.generateNameKinds[gk] = struct{}{}
+		}
+	}
+}
+
+// WithPrunedFields configures fields that are removed from the objects of
+// bundles of the given kinds before they are applied, in addition to the
+// fields that the API server populates, which are always removed. Fields of
+// AllKinds are removed from objects of every kind.
+func WithPrunedFields(fields map[schema.GroupKind][][]string) Option {
+	return func(c *controller) {
+		c.prunedFields = fields
+	}
+}
+
+// WithCluster configures how the capabilities of the cluster are detected
+// when the requirements declared by the config of a BundleDeployment are
+// evaluated. By default, the discovery API of the API server is used.
+func WithCluster(cluster requirements.Cluster) Option {
+	return func(c *controller) {
+		c.cluster = cluster
+	}
+}
+
+// WithReconcileBudget configures how long the object-level reconcile of an
+// installed release may take before it is continued in a later reconcile, so
+// that releases with many objects do not hold a worker for minutes. Zero
+// means no limit.
+func WithReconcileBudget(budget time.Duration) Option {
+	return func(c *controller) {
+		c.reconcileBudget = budget
+	}
+}
+
+// WithChartCacheSize configures how many converted bundles are kept in memory,
+// so that reconciles of unchanged bundles do not read and parse their stored
+// content again. Zero disables the cache.
+func WithChartCacheSize(size int) Option {
+	return func(c *controller) {
+		c.chartCache = nil
+		if size > 0 {
+			c.chartCache = lru.New(size)
+		}
+	}
+}
+
+// WithContentChecker configures the checker that verifies that unpacked
+// bundle content is retrievable at its content URL before the URL is
+// published in the status of the BundleDeployment.
+func WithContentChecker(checker storage.Checker) Option {
+	return func(c *controller) {
+		c.contentChecker = checker
+	}
+}
+
+// WithExternalAddressDiscovery reconciles every BundleDeployment again when
+// the Service or Ingress that the external address of the content server is
+// discovered from changes, so that their content URLs follow the address.
+func WithExternalAddressDiscovery() Option {
+	return func(c *controller) {
+		c.discoverExternalAddress = true
+	}
+}
+
+// WithConcurrencyLimiter limits the concurrent reconciles of the controller,
+// and of every other controller that shares the limiter, to the limit of
+// limiter, which can be changed while the controllers run. The controller
+// then runs MaxConcurrentReconcilesLimit workers.
+func WithConcurrencyLimiter(limiter *ConcurrencyLimiter) Option {
+	return func(c *controller) {
+		c.limiter = limiter
+	}
+}
+
+// WithTargetClusters configures the connections to the remote clusters that
+// BundleDeployments with a target apply their objects to. BundleDeployments
+// with a target fail to install if no connections are configured. The
+// action client getter must create the objects of their releases in the
+// target clusters as well, see target.Clusters.ActionConfigGetter.
+func WithTargetClusters(clusters *target.Clusters) Option {
+	return func(c *controller) {
+		c.targets = clusters
+	}
+}
+
 func WithPreflights(preflights ...Preflight) Option {
 	return func(c *controller) {
 		c.preflights = preflights
@@ -109,10 +286,26 @@ func WithPreflights(preflights ...Preflight) Option {
 }
 
 func SetupWithManager(mgr manager.Manager, systemNamespace string, opts ...Option) error {
+	dc, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
 	c := &controller{
 		cl:               mgr.GetClient(),
 		cache:            mgr.GetCache(),
+		analyzer:         &analysis.Prometheus{},
+		cluster:          &requirements.Discovery{Client: dc},
 		dynamicWatchGVKs: map[schema.GroupVersionKind]struct{}{},
+		remoteWatchGVKs:  map[*target.Cluster]map[schema.GroupVersionKind]struct{}{},
+		metadataOnlyGVKs: map[schema.GroupVersionKind]struct{}{
+			corev1.SchemeGroupVersion.WithKind("ConfigMap"): {},
+			corev1.SchemeGroupVersion.WithKind("Secret"):    {},
+		},
+		// Neither Helm nor the object reconcile set a field manager, so
+		// the changes of the provisioner are attributed to the base name
+		// of its binary.
+		fieldManager: filepath.Base(os.Args[0]),
+		backoff:      newBackoff(),
 	}
 
 	for _, o := range opts {
@@ -125,14 +318,33 @@ func SetupWithManager(mgr manager.Manager, systemNamespace string, opts ...Optio
 
 	controllerName := fmt.Sprintf("controller.bundledeployment.%s", c.provisionerID)
 	l := mgr.GetLogger().WithName(controllerName)
-	controller, err := ctrl.NewControllerManagedBy(mgr).
+	c.recorder = mgr.GetEventRecorderFor(controllerName)
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(controllerName).
 		For(&rukpakv1alpha2.BundleDeployment{}, builder.WithPredicates(
-			util.BundleDeploymentProvisionerFilter(c.provisionerID)),
+			util.BundleDeploymentProvisionerFilter(c.provisionerID),
+			ignoreFailureStatusUpdates()),
 		).
 		Watches(&corev1.Pod{}, util.MapOwneeToOwnerProvisionerHandler(mgr.GetClient(), l, c.provisionerID, &rukpakv1alpha2.BundleDeployment{})).
 		Watches(&corev1.ConfigMap{}, util.MapConfigMapToBundleDeploymentHandler(mgr.GetClient(), systemNamespace, c.provisionerID)).
-		Build(c)
+		// Secrets are watched by their metadata only, so that their content
+		// is not cached. Their events are mapped through an index, see
+		// util.IndexSecretReferences.
+		Watches(&corev1.Secret{}, util.MapSecretToBundleDeploymentHandler(mgr.GetClient(), systemNamespace, c.provisionerID),
+			builder.OnlyMetadata, builder.WithPredicates(util.NamespaceFilter(systemNamespace))).
+		Watches(&corev1.Namespace{}, util.MapNamespaceToBundleDeploymentHandler(mgr.GetClient(), c.provisionerID))
+	if c.discoverExternalAddress {
+		allBundleDeployments := util.MapToAllBundleDeploymentsHandler(mgr.GetClient(), c.provisionerID)
+		b = b.
+			Watches(&corev1.Service{}, allBundleDeployments, builder.WithPredicates(externaladdress.Predicate(systemNamespace))).
+			Watches(&networkingv1.Ingress{}, allBundleDeployments, builder.WithPredicates(externaladdress.Predicate(systemNamespace)))
+	}
+	controllerOpts := crcontroller.Options{RateLimiter: c.backoff, NewQueue: c.newQueue}
+	if c.limiter != nil {
+		controllerOpts.MaxConcurrentReconciles = MaxConcurrentReconcilesLimit
+	}
+	b = b.WithOptions(controllerOpts)
+	controller, err := b.Build(c)
 	if err != nil {
 		return err
 	}
@@ -186,21 +398,50 @@ type controller struct {
 	handler       handler.Handler
 	provisionerID string
 	acg           helmclient.ActionClientGetter
+	tester        ReleaseTester
+	completer     ReleaseCompleter
 	storage       storage.Storage
 
-	preflights []Preflight
+	contentChecker          storage.Checker
+	retainer                storage.Retainer
+	discoverExternalAddress bool
+
+	preflights      []Preflight
+	maxHistory      int
+	reconcileBudget time.Duration
+	limiter         *ConcurrencyLimiter
+	backoff         *backoff
+
+	shutdownGracePeriod time.Duration
+
+	generateNameKinds    map[schema.GroupKind]struct{}
+	prunedFields         map[schema.GroupKind][][]string
+	argoCDTrackingMethod ArgoCDTrackingMethod
+	analyzer             analysis.Analyzer
+	cluster              requirements.Cluster
+	targets              *target.Clusters
+
+	reportSigner crypto.Signer
+	chartCache   *lru.Cache
 
 	unpacker          unpackersource.Unpacker
 	controller        crcontroller.Controller
 	finalizers        crfinalizer.Finalizers
 	dynamicWatchMutex sync.RWMutex
 	dynamicWatchGVKs  map[schema.GroupVersionKind]struct{}
+	remoteWatchGVKs   map[*target.Cluster]map[schema.GroupVersionKind]struct{}
+	metadataOnlyGVKs  map[schema.GroupVersionKind]struct{}
+
+	recorder     record.EventRecorder
+	fieldManager string
 }
 
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeployments/finalizers,verbs=update
 //+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeployments,verbs=list;watch
 //+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeployments/status,verbs=update;patch
 //+kubebuilder:rbac:verbs=get,urls=/bundles/*
+//+kubebuilder:rbac:verbs=get,urls=/metrics
 //+kubebuilder:rbac:groups=core,resources=pods,verbs=list;watch;create;delete
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=list;watch
 //+kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
@@ -214,17 +455,51 @@ type controller struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
 func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	l := log.FromContext(ctx)
-	l.V(1).Info("starting reconciliation")
-	defer l.V(1).Info("ending reconciliation")
+	if c.limiter != nil {
+		if err := c.limiter.Acquire(ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+		defer c.limiter.Release()
+	}
+	// Reconciles that started before the controller stops are finished, so
+	// that their outcome is written to the status.
+	ctx, cancel := withGracePeriod(ctx, c.shutdownGracePeriod)
+	defer cancel()
 
 	existingBD := &rukpakv1alpha2.BundleDeployment{}
 	if err := c.cl.Get(ctx, req.NamespacedName, existingBD); err != nil {
+		if apierrors.IsNotFound(err) {
+			metrics.ForgetBundleDeployment(req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// The logger of the context already carries the reconcile ID. The UID
+	// tells apart BundleDeployments that were recreated under the same name.
+	// Helm logs with the logger of the context as well.
+	l := log.FromContext(ctx).WithValues("bundleDeploymentUID", existingBD.UID)
+	ctx = log.IntoContext(ctx, l)
+	l.V(1).Info("starting reconciliation")
+	defer l.V(1).Info("ending reconciliation")
+
 	reconciledBD := existingBD.DeepCopy()
 	res, reconcileErr := c.reconcile(ctx, reconciledBD)
+	if reconcileErr != nil {
+		c.tagFailedConditions(reconciledBD, existingBD.Status.Conditions, crcontroller.ReconcileIDFromContext(ctx))
+		setLastFailure(reconciledBD, metav1.Now())
+	} else {
+		reconciledBD.Status.LastFailure = nil
+	}
+	// Only failed reconciles are retried with backoff. Requeues after a
+	// delay are regular checks, not retries.
+	reconciledBD.Status.NextReconcileTime = nil
+	if reconcileErr != nil && !errors.Is(reconcileErr, reconcile.TerminalError(nil)) {
+		next := metav1.NewTime(time.Now().Add(c.backoff.next(req)).Truncate(time.Second))
+		reconciledBD.Status.NextReconcileTime = &next
+	}
+	if reconcileErr == nil && res.IsZero() && meta.IsStatusConditionFalse(reconciledBD.Status.Conditions, rukpakv1alpha2.TypeContentServed) {
+		res = ctrl.Result{RequeueAfter: contentServedRecheckInterval}
+	}
 
 	// Do checks before any Update()s, as Update() may modify the resource structure!
 	updateStatus := !equality.Semantic.DeepEqual(existingBD.Status, reconciledBD.Status)
@@ -250,73 +525,164 @@ func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	return res, reconcileErr
 }
 
-// nolint:unparam
-// Today we always return ctrl.Result{} and an error.
-// But in the future we might update this function
-// to return different results (e.g. requeue).
 func (c *controller) reconcile(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (ctrl.Result, error) {
+	// Transient unpack states must leave the resolved source in status
+	// untouched unless the source changed.
+	sourceChanged := sourceChangedSinceResolved(bd)
+	// Every condition is written with the generation it was observed for.
+	// The status helpers below, which only get the status, read it from
+	// status.observedGeneration.
 	bd.Status.ObservedGeneration = bd.Generation
 
 	// handle finalizers.
+	c.skipFinalizerCleanup(bd)
 	_, err := c.finalizers.Finalize(ctx, bd)
+	remaining := 0
+	if bd.DeletionTimestamp != nil {
+		if err == nil {
+			remaining, err = c.uninstall(ctx, bd)
+		}
+		setDeletingCondition(bd, remaining, err)
+	} else {
+		syncUninstallFinalizer(bd)
+	}
 	if err != nil {
 		bd.Status.ResolvedSource = nil
+		bd.Status.SourceHash = ""
 		bd.Status.ContentURL = ""
+		bd.Status.ContentSize = 0
+		meta.RemoveStatusCondition(&bd.Status.Conditions, rukpakv1alpha2.TypeContentServed)
 		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha2.TypeUnpacked,
-			Status:  metav1.ConditionUnknown,
-			Reason:  rukpakv1alpha2.ReasonProcessingFinalizerFailed,
-			Message: err.Error(),
+			Type:               rukpakv1alpha2.TypeUnpacked,
+			Status:             metav1.ConditionUnknown,
+			Reason:             rukpakv1alpha2.ReasonProcessingFinalizerFailed,
+			Message:            err.Error(),
+			ObservedGeneration: bd.Generation,
 		})
 		return ctrl.Result{}, err
 	}
+	// Content must neither be unpacked nor installed again once it was
+	// purged, e.g. while a foreground deletion waits for the objects of the
+	// release to be deleted.
+	if bd.DeletionTimestamp != nil {
+		if remaining > 0 {
+			return ctrl.Result{RequeueAfter: uninstallRecheckInterval}, nil
+		}
+		return ctrl.Result{}, nil
+	}
 
-	unpackResult, err := c.unpacker.Unpack(ctx, bd)
-	if err != nil {
-		return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, fmt.Errorf("source bundle content: %v", err))
+	unpackResult := c.restoreRetained(ctx, bd)
+	restored := unpackResult != nil
+	if !restored {
+		unpackResult, err = c.unpacker.Unpack(ctx, bd)
+		if err != nil {
+			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, fmt.Errorf("source bundle content: %w", err))
+		}
 	}
+	// The bundle may stream its content from temporary files, which are
+	// released once the reconcile is done with it.
+	defer unpackResult.Close()
 
 	switch unpackResult.State {
 	case unpackersource.StatePending:
-		updateStatusUnpackPending(&bd.Status, unpackResult)
+		updateStatusUnpackPending(&bd.Status, sourceChanged, bd.Spec.Source, unpackResult)
 		// There must a limit to number of retries if status is stuck at
 		// unpack pending.
 		return ctrl.Result{}, nil
 	case unpackersource.StateUnpacking:
-		updateStatusUnpacking(&bd.Status, unpackResult)
+		updateStatusUnpacking(&bd.Status, sourceChanged, bd.Spec.Source, unpackResult)
 		return ctrl.Result{}, nil
 	case unpackersource.StateUnpacked:
+		if err := verifySnapshotDigest(bd, unpackResult); err != nil {
+			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, err)
+		}
+		// The size is recorded for the admission webhook, which limits the
+		// total bundle storage of each tenant. It is computed once and
+		// passed on with the bundle, so that the storage does not walk the
+		// bundle again to enforce its size limit.
+		contentSize, err := util.SizeFS(unpackResult.Bundle)
+		if err != nil {
+			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, err)
+		}
+		unpackResult.Bundle = util.WithSize(unpackResult.Bundle, contentSize)
 		if err := c.storage.Store(ctx, bd, unpackResult.Bundle); err != nil {
-			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, fmt.Errorf("persist bundle content: %v", err))
+			// The storage backs off globally while it is unavailable, so the
+			// BundleDeployment is retried once it does rather than with its
+			// own backoff.
+			var unavailable *storage.UnavailableError
+			if errors.As(err, &unavailable) {
+				_ = updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, err)
+				return ctrl.Result{RequeueAfter: time.Until(unavailable.RetryAt)}, nil
+			}
+			// The same source unpacks to a bundle of the same size, so it is
+			// not retried until the BundleDeployment changes.
+			var tooLarge *storage.BundleTooLargeError
+			if errors.As(err, &tooLarge) {
+				return ctrl.Result{}, reconcile.TerminalError(updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, fmt.Errorf("persist bundle content: %w", err)))
+			}
+			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, fmt.Errorf("persist bundle content: %v", err))
+		}
+		// Restored content is not retained again, which would make its
+		// source the most recently retained one, whose content is unpacked
+		// rather than restored by the next reconcile.
+		if !restored {
+			c.retain(ctx, bd, unpackResult)
 		}
+		bd.Status.ContentSize = contentSize
 		contentURL, err := c.storage.URLFor(ctx, bd)
 		if err != nil {
-			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, fmt.Errorf("get content URL: %v", err))
+			return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, fmt.Errorf("get content URL: %v", err))
 		}
-		updateStatusUnpacked(&bd.Status, unpackResult, contentURL)
+		updateStatusUnpacked(&bd.Status, bd.Spec.Source, unpackResult, c.publishContentURL(ctx, bd, contentURL))
 	default:
-		return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, fmt.Errorf("unknown unpack state %q: %v", unpackResult.State, err))
+		return ctrl.Result{}, updateStatusUnpackFailing(&bd.Status, sourceChanged, bd.Spec.Source, fmt.Errorf("unknown unpack state %q: %v", unpackResult.State, err))
 	}
 
-	bundleFS, err := c.storage.Load(ctx, bd)
+	chrt, values, cached := c.cachedChartFor(bd)
+	if !cached {
+		bundleFS, err := c.storage.Load(ctx, bd)
+		if err != nil {
+			meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+				Type:               rukpakv1alpha2.TypeHasValidBundle,
+				Status:             metav1.ConditionFalse,
+				Reason:             rukpakv1alpha2.ReasonBundleLoadFailed,
+				Message:            err.Error(),
+				ObservedGeneration: bd.Generation,
+			})
+			return ctrl.Result{}, err
+		}
+
+		chrt, values, err = c.handler.Handle(ctx, bundleFS, bd)
+		// The chart holds all that is needed of the bundle from here on.
+		_ = bundleFS.Close()
+		if err != nil {
+			meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+				Type:               rukpakv1alpha2.TypeInstalled,
+				Status:             metav1.ConditionFalse,
+				Reason:             rukpakv1alpha2.ReasonInstallFailed,
+				Message:            err.Error(),
+				ObservedGeneration: bd.Generation,
+			})
+			return ctrl.Result{}, err
+		}
+		c.cacheChart(bd, chrt, values)
+	}
+
+	unmet, err := c.unmetRequirements(ctx, bd, chrt)
 	if err != nil {
-		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha2.TypeHasValidBundle,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha2.ReasonBundleLoadFailed,
-			Message: err.Error(),
-		})
+		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
 		return ctrl.Result{}, err
 	}
+	if len(unmet) > 0 {
+		// Unmet requirements are not an error: the install stays pending
+		// until the cluster gains the missing capabilities.
+		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonRequirementsNotMet, fmt.Sprintf("Pending until the cluster meets the requirements of the bundle: %s", strings.Join(unmet, "; ")))
+		return ctrl.Result{RequeueAfter: requirementsRecheckInterval}, nil
+	}
 
-	chrt, values, err := c.handler.Handle(ctx, bundleFS, bd)
+	objPatches, err := patches.FromConfig(bd.Spec.Config)
 	if err != nil {
-		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha2.TypeInstalled,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha2.ReasonInstallFailed,
-			Message: err.Error(),
-		})
+		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
 		return ctrl.Result{}, err
 	}
 
@@ -325,61 +691,197 @@ func (c *controller) reconcile(ctx context.Context, bd *rukpakv1alpha2.BundleDep
 		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonErrorGettingClient, err.Error())
 		return ctrl.Result{}, err
 	}
+	remote, err := c.targetCluster(ctx, bd)
+	if err != nil {
+		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonErrorGettingClient, err.Error())
+		return ctrl.Result{}, err
+	}
+	targetClient := c.cl
+	if remote != nil {
+		targetClient = remote.GetClient()
+	}
 
 	post := &postrenderer{
 		labels: map[string]string{
 			util.CoreOwnerKindKey: rukpakv1alpha2.BundleDeploymentKind,
 			util.CoreOwnerNameKey: bd.GetName(),
 		},
+		owner:              bd.UID,
+		remote:             remote != nil,
+		generateNameKinds:  c.generateNameKinds,
+		prunedFields:       c.prunedFields,
+		extraLabels:        bd.Spec.Labels,
+		extraAnnotations:   bd.Spec.Annotations,
+		generateNameSuffix: generateNameSuffix(bd),
+		argoCD:             newArgoCDTracking(c.argoCDTrackingMethod, bd, isNamespacedIn(targetClient)),
+		patches:            objPatches,
+		installNamespace:   bd.Spec.InstallNamespace,
+	}
+
+	if err := c.ensureInstallNamespace(ctx, bd); err != nil {
+		var notFound errInstallNamespaceNotFound
+		if errors.As(err, &notFound) {
+			// The namespace watch triggers a reconcile once the namespace
+			// is created.
+			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallNamespaceNotFound, err.Error())
+			if remote != nil {
+				// Namespaces of remote clusters are not watched.
+				return ctrl.Result{RequeueAfter: requirementsRecheckInterval}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
+		return ctrl.Result{}, err
 	}
 
 	rel, desiredRel, state, err := c.getReleaseState(cl, bd, chrt, values, post)
+	if err != nil && isResourceNotFoundErr(err) && len(post.crds) > 0 {
+		// Helm cannot build the objects of a release whose kinds are defined
+		// by CRDs of the same release before those CRDs exist.
+		var pending []string
+		if pending, err = c.ensureCRDs(ctx, bd, post.crds); err == nil {
+			if len(pending) > 0 {
+				return waitForCRDs(bd, pending), nil
+			}
+			rel, desiredRel, state, err = c.getReleaseState(cl, bd, chrt, values, post)
+		}
+	}
 	if err != nil {
 		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonErrorGettingReleaseState, err.Error())
 		return ctrl.Result{}, err
 	}
+	metrics.RecordRenderedManifest(bd.Name, post.renderedBytes, post.renderedObjects)
+	if state == stateNeedsUpgrade && rollbackPinned(bd) {
+		state = stateUnchanged
+	}
+	if state == stateNeedsUpgrade {
+		if err := checkVersionPolicy(bd.Spec.VersionPolicy, rel.Chart, chrt); err != nil {
+			// The upgrade stays blocked until the spec changes, so there is
+			// no point in retrying.
+			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonUpgradeBlocked, err.Error())
+			return ctrl.Result{}, nil
+		}
+	}
 
-	for _, preflight := range c.preflights {
+	// The preflights check the CRDs of the cluster of the BundleDeployment,
+	// so they are skipped for BundleDeployments with a remote target.
+	preflights := c.preflights
+	if remote != nil {
+		preflights = nil
+	}
+	for _, preflight := range preflights {
 		switch state {
 		case stateNeedsInstall:
 			err := preflight.Install(ctx, desiredRel)
 			if err != nil {
-				setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
+				setInstalledAndHealthyFalse(bd, preflightFailureReason(err), err.Error())
 				return ctrl.Result{}, err
 			}
 		case stateNeedsUpgrade:
 			err := preflight.Upgrade(ctx, desiredRel)
 			if err != nil {
-				setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
+				setInstalledAndHealthyFalse(bd, preflightFailureReason(err), err.Error())
 				return ctrl.Result{}, err
 			}
 		}
 	}
 
+	if state == stateNeedsInstall || state == stateNeedsUpgrade {
+		pending, err := c.ensureCRDs(ctx, bd, post.crds)
+		if err != nil {
+			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
+			return ctrl.Result{}, err
+		}
+		if len(pending) > 0 {
+			return waitForCRDs(bd, pending), nil
+		}
+	}
+
 	switch state {
 	case stateNeedsInstall:
+		bd.Status.ObjectApplyResults = nil
+		bd.Status.ReconcileContinuation = nil
 		rel, err = cl.Install(bd.Name, bd.Spec.InstallNamespace, chrt, values, func(install *action.Install) error {
 			install.CreateNamespace = false
 			return nil
 		}, helmclient.AppendInstallPostRenderer(post))
+		if err == nil {
+			recordApplyResults(ctx, bd, nil, rel)
+		} else {
+			rel, err = c.applyFailedRelease(ctx, cl, bd, nil, err)
+		}
 		if err != nil {
 			if isResourceNotFoundErr(err) {
 				err = errRequiredResourceNotFound{err}
+			} else {
+				err = c.findCollisions(ctx, bd, desiredRel, err)
 			}
 			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonInstallFailed, err.Error())
 			return ctrl.Result{}, err
 		}
+		meta.RemoveStatusCondition(&bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
 	case stateNeedsUpgrade:
-		rel, err = cl.Upgrade(bd.Name, bd.Spec.InstallNamespace, chrt, values, helmclient.AppendUpgradePostRenderer(post))
+		bd.Status.ObjectApplyResults = nil
+		bd.Status.ReconcileContinuation = nil
+		if err := c.storeUpgradeDiff(ctx, bd, desiredRel.Version, rel.Manifest, desiredRel.Manifest); err != nil {
+			// Like the install report, the diff is for audits only and must
+			// not block the upgrade.
+			log.FromContext(ctx).Error(err, "failed to store upgrade diff", "revision", desiredRel.Version)
+		}
+		previous := rel
+		rel, err = cl.Upgrade(bd.Name, bd.Spec.InstallNamespace, chrt, values, c.upgradeMaxHistory, helmclient.AppendUpgradePostRenderer(post))
+		if err == nil {
+			recordApplyResults(ctx, bd, previous, rel)
+		} else {
+			rel, err = c.applyFailedRelease(ctx, cl, bd, previous, err)
+		}
 		if err != nil {
 			if isResourceNotFoundErr(err) {
 				err = errRequiredResourceNotFound{err}
+			} else {
+				err = c.findCollisions(ctx, bd, desiredRel, err)
 			}
 			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonUpgradeFailed, err.Error())
 			return ctrl.Result{}, err
 		}
+		c.completeUpgrade(ctx, cl, targetClient, bd, rel)
+	case stateNeedsRetry:
+		// Only the objects that failed to apply are retried, so that the
+		// objects of the release that were applied are not applied again.
+		reason := rukpakv1alpha2.ReasonUpgradeFailed
+		if rel.Version == 1 {
+			reason = rukpakv1alpha2.ReasonInstallFailed
+		}
+		previous, err := cl.Get(bd.Name, func(get *action.Get) error {
+			get.Version = rel.Version - 1
+			return nil
+		})
+		if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+			setInstalledAndHealthyFalse(bd, reason, err.Error())
+			return ctrl.Result{}, err
+		}
+		if err := reconcileObjects(cl, bd, rel, previous, 0); err != nil {
+			if isResourceNotFoundErr(err) {
+				err = errRequiredResourceNotFound{err}
+			}
+			setInstalledAndHealthyFalse(bd, reason, err.Error())
+			return ctrl.Result{}, err
+		}
+		if rel, err = c.completer.Complete(ctx, bd, rel); err != nil {
+			setInstalledAndHealthyFalse(bd, reason, err.Error())
+			return ctrl.Result{}, err
+		}
+		// The retry completed the install or upgrade of the release, which is
+		// reported like one.
+		state = stateNeedsInstall
+		if reason == rukpakv1alpha2.ReasonUpgradeFailed {
+			state = stateNeedsUpgrade
+			c.completeUpgrade(ctx, cl, targetClient, bd, rel)
+		} else {
+			meta.RemoveStatusCondition(&bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
+		}
 	case stateUnchanged:
-		if err := cl.Reconcile(rel); err != nil {
+		if err := reconcileObjects(cl, bd, rel, rel, c.reconcileBudget); err != nil {
 			if isResourceNotFoundErr(err) {
 				err = errRequiredResourceNotFound{err}
 			}
@@ -389,103 +891,245 @@ func (c *controller) reconcile(ctx context.Context, bd *rukpakv1alpha2.BundleDep
 	default:
 		return ctrl.Result{}, fmt.Errorf("unexpected release state %q", state)
 	}
+	bd.Status.BundleMetadata = util.BundleMetadataFromChart(rel.Chart)
 
 	relObjects, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
 	if err != nil {
 		setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonCreateDynamicWatchFailed, err.Error())
 		return ctrl.Result{}, err
 	}
+	bd.Status.GeneratedRBAC = generatedRBAC(relObjects)
 
 	for _, obj := range relObjects {
-		uMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if remote != nil {
+			err = c.watchRemoteDependent(remote, gvk)
+		} else {
+			err = c.watchDependent(bd, gvk)
+		}
 		if err != nil {
 			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonCreateDynamicWatchFailed, err.Error())
 			return ctrl.Result{}, err
 		}
+	}
+	installed := metav1.Condition{
+		Type:               rukpakv1alpha2.TypeInstalled,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha2.ReasonInstallationSucceeded,
+		Message:            fmt.Sprintf("Instantiated bundle %s successfully", bd.GetName()),
+		ObservedGeneration: bd.Generation,
+	}
+	if pending := util.ChartPending(rel.Chart); pending != "" {
+		// The handler converted only part of the bundle. The rest is added
+		// by a later reconcile, which the objects that it waits for trigger
+		// when they change.
+		installed.Status = metav1.ConditionFalse
+		installed.Reason = rukpakv1alpha2.ReasonApplyPending
+		installed.Message = pending
+	}
+	meta.SetStatusCondition(&bd.Status.Conditions, installed)
+	if installed.Status == metav1.ConditionTrue && rel.Info != nil {
+		metrics.RecordSuccessfulInstall(bd.Name, rel.Info.LastDeployed.Time)
+	}
 
-		unstructuredObj := &unstructured.Unstructured{Object: uMap}
-		if err := func() error {
-			c.dynamicWatchMutex.Lock()
-			defer c.dynamicWatchMutex.Unlock()
-
-			_, isWatched := c.dynamicWatchGVKs[unstructuredObj.GroupVersionKind()]
-			if !isWatched {
-				if err := c.controller.Watch(
-					source.Kind(
-						c.cache,
-						unstructuredObj,
-						crhandler.TypedEnqueueRequestForOwner[*unstructured.Unstructured](
-							c.cl.Scheme(),
-							c.cl.RESTMapper(),
-							bd,
-							crhandler.OnlyControllerOwner(),
-						),
-						helmpredicate.DependentPredicateFuncs[*unstructured.Unstructured](),
-					),
-				); err != nil {
-					return err
-				}
-				c.dynamicWatchGVKs[unstructuredObj.GroupVersionKind()] = struct{}{}
-			}
-			return nil
-		}(); err != nil {
-			setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonCreateDynamicWatchFailed, err.Error())
-			return ctrl.Result{}, err
+	if action, ok := reportActions[state]; ok {
+		if err := c.storeInstallReport(ctx, bd, rel, action); err != nil {
+			// The release has been deployed at this point, so a missing report
+			// must not cause the install or upgrade to be retried.
+			log.FromContext(ctx).Error(err, "failed to store install report", "revision", rel.Version)
 		}
 	}
-	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-		Type:    rukpakv1alpha2.TypeInstalled,
-		Status:  metav1.ConditionTrue,
-		Reason:  rukpakv1alpha2.ReasonInstallationSucceeded,
-		Message: fmt.Sprintf("Instantiated bundle %s successfully", bd.GetName()),
-	})
+
+	rolledBack, res, err := c.analyze(ctx, cl, bd, rel, post)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if rolledBack {
+		// Reconcile the rolled back release from scratch.
+		return ctrl.Result{Requeue: true}, nil
+	}
+	c.runRequestedTests(ctx, bd, rel)
+	if bd.Status.ReconcileContinuation != nil && res.IsZero() {
+		// Continue the object-level reconcile after the BundleDeployments
+		// that are already queued had their turn. Requeue would back off
+		// exponentially with every slice of a large release.
+		res = ctrl.Result{RequeueAfter: reconcileContinuationDelay}
+	}
 
 	if features.RukpakFeatureGate.Enabled(features.BundleDeploymentHealth) {
-		if err = healthchecks.AreObjectsHealthy(ctx, c.cl, relObjects); err != nil {
-			meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-				Type:    rukpakv1alpha2.TypeHealthy,
-				Status:  metav1.ConditionFalse,
-				Reason:  rukpakv1alpha2.ReasonUnhealthy,
-				Message: err.Error(),
-			})
+		progressing, err := setHealthyCondition(bd, healthchecks.Evaluate(ctx, targetClient, relObjects))
+		if err != nil {
 			return ctrl.Result{}, err
 		}
+		if progressing {
+			return recheckHealth(res), nil
+		}
+	}
+
+	return res, nil
+}
+
+// completeUpgrade prunes the objects that the upgrade of bd to rel left
+// behind, and removes the conditions that described the previous release.
+func (c *controller) completeUpgrade(ctx context.Context, cl helmclient.ActionInterface, targetClient client.Client, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release) {
+	pruned, err := pruneOrphans(ctx, cl, targetClient, bd, rel)
+	if err != nil {
+		// The upgrade succeeded, so it must not be retried. Objects that
+		// were not pruned are pruned by the next upgrade, as long as a
+		// stored revision has objects of their kind.
+		log.FromContext(ctx).Error(err, "failed to prune orphaned objects", "revision", rel.Version)
+	}
+	c.recordPruned(bd, pruned)
+	meta.RemoveStatusCondition(&bd.Status.Conditions, rukpakv1alpha2.TypeRollbackPerformed)
+	meta.RemoveStatusCondition(&bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
+}
+
+// watchDependent makes sure that the dependent resources of the given kind are
+// watched. Kinds in metadataOnlyGVKs are watched by their metadata only, which
+// avoids caching the full content of resources such as Secrets.
+func (c *controller) watchDependent(bd *rukpakv1alpha2.BundleDeployment, gvk schema.GroupVersionKind) error {
+	c.dynamicWatchMutex.Lock()
+	defer c.dynamicWatchMutex.Unlock()
+
+	if _, isWatched := c.dynamicWatchGVKs[gvk]; isWatched {
+		return nil
+	}
+
+	var src source.Source
+	if _, metadataOnly := c.metadataOnlyGVKs[gvk]; metadataOnly {
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(gvk)
+		src = source.Kind(
+			c.cache,
+			obj,
+			driftHandler[*metav1.PartialObjectMetadata]{
+				TypedEventHandler: crhandler.TypedEnqueueRequestForOwner[*metav1.PartialObjectMetadata](
+					c.cl.Scheme(),
+					c.cl.RESTMapper(),
+					bd,
+					crhandler.OnlyControllerOwner(),
+				),
+				c: c,
+			},
+			helmpredicate.DependentMetadataPredicateFuncs(),
+		)
+	} else {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		src = source.Kind(
+			c.cache,
+			obj,
+			driftHandler[*unstructured.Unstructured]{
+				TypedEventHandler: crhandler.TypedEnqueueRequestForOwner[*unstructured.Unstructured](
+					c.cl.Scheme(),
+					c.cl.RESTMapper(),
+					bd,
+					crhandler.OnlyControllerOwner(),
+				),
+				c: c,
+			},
+			helmpredicate.DependentPredicateFuncs[*unstructured.Unstructured](),
+		)
+	}
+	if err := c.controller.Watch(src); err != nil {
+		return err
+	}
+	c.dynamicWatchGVKs[gvk] = struct{}{}
+	return nil
+}
+
+// upgradeMaxHistory prunes the release revisions that exceed maxHistory when
+// the release is upgraded.
+func (c *controller) upgradeMaxHistory(upgrade *action.Upgrade) error {
+	upgrade.MaxHistory = c.maxHistory
+	return nil
+}
+
+// setHealthyCondition sets the Healthy condition from the health of the release
+// objects. Objects that are still rolling out are not a failure, so they set the
+// condition to Unknown rather than False and progressing is returned so that the
+// caller can check again. An error is returned if any object is degraded.
+func setHealthyCondition(bd *rukpakv1alpha2.BundleDeployment, results healthchecks.Results) (bool, error) {
+	switch results.Status() {
+	case healthchecks.StatusDegraded:
+		err := results.Err()
+		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+			Type:               rukpakv1alpha2.TypeHealthy,
+			Status:             metav1.ConditionFalse,
+			Reason:             rukpakv1alpha2.ReasonDegraded,
+			Message:            err.Error(),
+			ObservedGeneration: bd.Generation,
+		})
+		return false, err
+	case healthchecks.StatusProgressing:
 		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha2.TypeHealthy,
-			Status:  metav1.ConditionTrue,
-			Reason:  rukpakv1alpha2.ReasonHealthy,
-			Message: "BundleDeployment is healthy",
+			Type:               rukpakv1alpha2.TypeHealthy,
+			Status:             metav1.ConditionUnknown,
+			Reason:             rukpakv1alpha2.ReasonProgressing,
+			Message:            results.Err().Error(),
+			ObservedGeneration: bd.Generation,
 		})
+		return true, nil
 	}
+	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeHealthy,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha2.ReasonHealthy,
+		Message:            "BundleDeployment is healthy",
+		ObservedGeneration: bd.Generation,
+	})
+	return false, nil
+}
 
-	return ctrl.Result{}, nil
+// recheckHealth returns res with a requeue after healthRecheckInterval, or
+// earlier if res already requeues earlier. Requeue would back off
+// exponentially for as long as the objects are rolling out.
+func recheckHealth(res ctrl.Result) ctrl.Result {
+	if res.RequeueAfter == 0 || res.RequeueAfter > healthRecheckInterval {
+		res.RequeueAfter = healthRecheckInterval
+	}
+	return res
 }
 
 // setInstalledAndHealthyFalse sets the Installed and if the feature gate is enabled, the Healthy conditions to False,
 // and allows to set the Installed condition reason and message.
 func setInstalledAndHealthyFalse(bd *rukpakv1alpha2.BundleDeployment, installedConditionReason, installedConditionMessage string) {
 	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-		Type:    rukpakv1alpha2.TypeInstalled,
-		Status:  metav1.ConditionFalse,
-		Reason:  installedConditionReason,
-		Message: installedConditionMessage,
+		Type:               rukpakv1alpha2.TypeInstalled,
+		Status:             metav1.ConditionFalse,
+		Reason:             installedConditionReason,
+		Message:            installedConditionMessage,
+		ObservedGeneration: bd.Generation,
 	})
 
 	if features.RukpakFeatureGate.Enabled(features.BundleDeploymentHealth) {
 		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
-			Type:    rukpakv1alpha2.TypeHealthy,
-			Status:  metav1.ConditionFalse,
-			Reason:  rukpakv1alpha2.ReasonInstallationStatusFalse,
-			Message: "Installed condition is false",
+			Type:               rukpakv1alpha2.TypeHealthy,
+			Status:             metav1.ConditionFalse,
+			Reason:             rukpakv1alpha2.ReasonInstallationStatusFalse,
+			Message:            "Installed condition is false",
+			ObservedGeneration: bd.Generation,
 		})
 	}
 }
 
+// preflightFailureReason returns the reason of the Installed condition for a
+// failed preflight check. Invalid CRDs are reported distinctly, since they
+// can only be fixed in the bundle.
+func preflightFailureReason(err error) string {
+	var crdErr *crdvalidation.Error
+	if errors.As(err, &crdErr) {
+		return rukpakv1alpha2.ReasonCRDValidationFailed
+	}
+	return rukpakv1alpha2.ReasonInstallFailed
+}
+
 type releaseState string
 
 const (
 	stateNeedsInstall releaseState = "NeedsInstall"
 	stateNeedsUpgrade releaseState = "NeedsUpgrade"
+	stateNeedsRetry   releaseState = "NeedsRetry"
 	stateUnchanged    releaseState = "Unchanged"
 	stateError        releaseState = "Error"
 )
@@ -518,9 +1162,239 @@ func (c *controller) getReleaseState(cl helmclient.ActionInterface, bd *rukpakv1
 		currentRelease.Info.Status == release.StatusSuperseded {
 		relState = stateNeedsUpgrade
 	}
+	if relState == stateNeedsUpgrade && c.completer != nil &&
+		desiredRelease.Manifest == currentRelease.Manifest &&
+		currentRelease.Info.Status == release.StatusFailed &&
+		hasFailedObjects(bd) {
+		relState = stateNeedsRetry
+	}
 	return currentRelease, desiredRelease, relState, nil
 }
 
+// hasFailedObjects returns whether the most recent apply of the release of bd
+// recorded objects that failed to apply.
+func hasFailedObjects(bd *rukpakv1alpha2.BundleDeployment) bool {
+	for _, res := range bd.Status.ObjectApplyResults {
+		if res.Result == rukpakv1alpha2.ObjectApplyResultFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// hookFailures are the errors of Helm installs and upgrades whose hooks
+// failed. Since the objects of such releases are not applied one at a time
+// without their hooks, they are installed or upgraded again as a whole.
+var hookFailures = []string{
+	"failed pre-install",
+	"failed post-install",
+	"pre-upgrade hooks failed",
+	"post-upgrade hooks failed",
+}
+
+// applyFailedRelease applies the objects of the release that a failed install
+// or upgrade recorded one at a time, so that the status records which objects
+// failed and the next reconcile retries only those. The objects of previous,
+// the release that was upgraded, are recorded as updated. If every object is
+// applied, the release is marked as deployed and returned. Otherwise, or if
+// the failure left no failed release behind, installErr is returned.
+func (c *controller) applyFailedRelease(ctx context.Context, cl helmclient.ActionInterface, bd *rukpakv1alpha2.BundleDeployment, previous *release.Release, installErr error) (*release.Release, error) {
+	for _, hookFailure := range hookFailures {
+		if strings.Contains(installErr.Error(), hookFailure) {
+			return nil, installErr
+		}
+	}
+	failed, err := cl.Get(bd.Name)
+	if err != nil || failed.Info.Status != release.StatusFailed || (previous != nil && failed.Version <= previous.Version) {
+		// The install or upgrade failed before it applied any object.
+		return nil, installErr
+	}
+	if err := reconcileObjects(cl, bd, failed, previous, 0); err != nil {
+		return nil, installErr
+	}
+	if c.completer == nil {
+		return nil, installErr
+	}
+	completed, err := c.completer.Complete(ctx, bd, failed)
+	if err != nil {
+		return nil, fmt.Errorf("%v: complete the release after its objects were applied: %w", installErr, err)
+	}
+	return completed, nil
+}
+
+// reconcileObjects applies the objects of rel one at a time so that a single
+// failing object does not prevent the remaining objects from being reconciled.
+// The outcome of every object is recorded in the status: objects of previous,
+// the release that existed before rel, were updated, and all other objects
+// were created. If the previous reconcile recorded failures, only that failed
+// subset is retried, and the remaining objects are recorded as skipped.
+//
+// If budget is positive, objects are applied in batches of reconcileBatchSize
+// until the budget is exceeded. The position of the next object is then
+// recorded in the status, and the next reconcile continues from there.
+func reconcileObjects(cl helmclient.ActionInterface, bd *rukpakv1alpha2.BundleDeployment, rel, previous *release.Release, budget time.Duration) error {
+	relObjects, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return err
+	}
+	existing, err := releaseObjectKeys(previous)
+	if err != nil {
+		return err
+	}
+
+	recorded := map[string]rukpakv1alpha2.ObjectApplyResult{}
+	retry := map[string]struct{}{}
+	for _, res := range bd.Status.ObjectApplyResults {
+		key := objectResultKey(res)
+		recorded[key] = res
+		if res.Result == rukpakv1alpha2.ObjectApplyResultFailed {
+			retry[key] = struct{}{}
+		}
+	}
+
+	// Retries are not budgeted, and leave an interrupted reconcile to be
+	// continued once they succeed.
+	start := 0
+	if len(retry) == 0 {
+		if cont := bd.Status.ReconcileContinuation; cont != nil && cont.Revision == rel.Version && cont.NextObject < len(relObjects) {
+			start = cont.NextObject
+		}
+		bd.Status.ReconcileContinuation = nil
+	}
+	deadline := time.Now().Add(budget)
+
+	var (
+		results []rukpakv1alpha2.ObjectApplyResult
+		errs    []error
+	)
+	// The outcomes of the objects that the interrupted reconcile applied
+	// are kept.
+	for _, obj := range relObjects[:start] {
+		if res, ok := recorded[objectResultKey(newObjectApplyResult(obj))]; ok {
+			results = append(results, res)
+		}
+	}
+	for i := start; i < len(relObjects); i++ {
+		if len(retry) == 0 && budget > 0 && i > start && (i-start)%reconcileBatchSize == 0 && time.Now().After(deadline) {
+			bd.Status.ReconcileContinuation = &rukpakv1alpha2.ReconcileContinuation{Revision: rel.Version, NextObject: i}
+			break
+		}
+		obj := relObjects[i]
+		res := newObjectApplyResult(obj)
+		key := objectResultKey(res)
+		if _, isRetry := retry[key]; len(retry) > 0 && !isRetry {
+			res.Result = rukpakv1alpha2.ObjectApplyResultSkipped
+			res.Message = "Waiting for the failed objects of the release to be applied"
+			results = append(results, res)
+			continue
+		}
+
+		res.Result = rukpakv1alpha2.ObjectApplyResultCreated
+		if _, ok := existing[key]; ok {
+			res.Result = rukpakv1alpha2.ObjectApplyResultUpdated
+		}
+		if err := applyObject(cl, rel, obj); err != nil {
+			res.Result = rukpakv1alpha2.ObjectApplyResultFailed
+			res.Message = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+		results = append(results, res)
+	}
+	bd.Status.ObjectApplyResults = results
+	return utilerrors.NewAggregate(errs)
+}
+
+// recordApplyResults records the outcomes of installing or upgrading to rel.
+func recordApplyResults(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, previous, rel *release.Release) {
+	results, err := releaseApplyResults(previous, rel)
+	if err != nil {
+		// The release is installed, so the outcomes are left out rather
+		// than failing the reconcile.
+		log.FromContext(ctx).Error(err, "failed to record object apply results", "revision", rel.Version)
+	}
+	bd.Status.ObjectApplyResults = results
+}
+
+// applyObject applies a single object of rel.
+func applyObject(cl helmclient.ActionInterface, rel *release.Release, obj client.Object) error {
+	objManifest, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return cl.Reconcile(&release.Release{Name: rel.Name, Namespace: rel.Namespace, Manifest: string(objManifest)})
+}
+
+// releaseObjectKeys returns the result keys of the objects of rel, if any.
+func releaseObjectKeys(rel *release.Release) (map[string]struct{}, error) {
+	keys := map[string]struct{}{}
+	if rel == nil {
+		return keys, nil
+	}
+	objs, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		keys[objectResultKey(newObjectApplyResult(obj))] = struct{}{}
+	}
+	return keys, nil
+}
+
+// releaseApplyResults returns the outcomes of installing or upgrading to rel.
+// The objects of the previous release, if any, were updated, and all other
+// objects were created.
+func releaseApplyResults(previous, rel *release.Release) ([]rukpakv1alpha2.ObjectApplyResult, error) {
+	existing, err := releaseObjectKeys(previous)
+	if err != nil {
+		return nil, err
+	}
+	relObjects, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]rukpakv1alpha2.ObjectApplyResult, 0, len(relObjects))
+	for _, obj := range relObjects {
+		res := newObjectApplyResult(obj)
+		res.Result = rukpakv1alpha2.ObjectApplyResultCreated
+		if _, ok := existing[objectResultKey(res)]; ok {
+			res.Result = rukpakv1alpha2.ObjectApplyResultUpdated
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func newObjectApplyResult(obj client.Object) rukpakv1alpha2.ObjectApplyResult {
+	apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	return rukpakv1alpha2.ObjectApplyResult{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+func objectResultKey(res rukpakv1alpha2.ObjectApplyResult) string {
+	if res.Namespace == "" {
+		return fmt.Sprintf("%s %s %s", res.APIVersion, res.Kind, res.Name)
+	}
+	return fmt.Sprintf("%s %s %s/%s", res.APIVersion, res.Kind, res.Namespace, res.Name)
+}
+
+const (
+	// reconcileBatchSize is the number of objects that are applied between
+	// checks of the reconcile budget.
+	reconcileBatchSize = 50
+
+	// reconcileContinuationDelay is how long an interrupted object-level
+	// reconcile waits before it is continued.
+	reconcileContinuationDelay = time.Second
+
+	// healthRecheckInterval is how often the health of the objects of a
+	// release is checked again while they are rolling out.
+	healthRecheckInterval = 10 * time.Second
+)
+
 type errRequiredResourceNotFound struct {
 	error
 }
@@ -555,13 +1429,75 @@ func isResourceNotFoundErr(err error) bool {
 	return strings.Contains(err.Error(), "the server could not find the requested resource")
 }
 
+// generatedRBAC returns references to the objects of a release that were
+// generated for its BundleDeployment alone.
+func generatedRBAC(objs []client.Object) []rukpakv1alpha2.RBACObjectReference {
+	var refs []rukpakv1alpha2.RBACObjectReference
+	for _, obj := range objs {
+		if obj.GetLabels()[util.GeneratedRBACKey] != "true" {
+			continue
+		}
+		refs = append(refs, rukpakv1alpha2.RBACObjectReference{
+			Kind:      obj.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		})
+	}
+	return refs
+}
+
 type postrenderer struct {
 	labels  map[string]string
 	cascade postrender.PostRenderer
+
+	// owner is the UID of the BundleDeployment, whose owner reference is
+	// removed from objects that are kept by their resource policy.
+	owner types.UID
+
+	// remote is set when the objects are applied to a remote cluster, where
+	// the owner reference to the BundleDeployment would make the garbage
+	// collector delete them right away.
+	remote bool
+
+	// extraLabels and extraAnnotations are the labels and annotations of
+	// the BundleDeployment spec. They override the labels and annotations
+	// of the bundle, but not the owner labels.
+	extraLabels      map[string]string
+	extraAnnotations map[string]string
+
+	// generateNameKinds are the kinds of objects whose generateName is
+	// replaced by a name with generateNameSuffix.
+	generateNameKinds  map[schema.GroupKind]struct{}
+	generateNameSuffix string
+
+	// prunedFields are the fields that are removed from objects of a kind,
+	// in addition to the fields that the API server populates.
+	prunedFields map[schema.GroupKind][][]string
+
+	// argoCD marks the objects as resources of an Argo CD application, if
+	// set.
+	argoCD *argoCDTracking
+
+	// patches are the JSON patches of the config, which are applied to
+	// their targets before anything else changes the objects. Objects
+	// without a namespace are in installNamespace.
+	patches          []patches.Patch
+	installNamespace string
+
+	// crds are the CRDs of the most recently rendered manifest.
+	crds []*apiextensionsv1.CustomResourceDefinition
+
+	// renderedBytes and renderedObjects are the size and the number of
+	// objects of the most recently rendered manifest, after post-rendering.
+	renderedBytes   int
+	renderedObjects int
 }
 
 func (p *postrenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
 	var buf bytes.Buffer
+	p.crds = nil
+	p.renderedObjects = 0
+	patched := make([]bool, len(p.patches))
 	dec := apimachyaml.NewYAMLOrJSONDecoder(renderedManifests, 1024)
 	for {
 		obj := unstructured.Unstructured{}
@@ -572,12 +1508,49 @@ func (p *postrenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, erro
 		if err != nil {
 			return nil, err
 		}
-		obj.SetLabels(util.MergeMaps(obj.GetLabels(), p.labels))
+		for i := range p.patches {
+			if !p.patches[i].Matches(&obj, p.installNamespace) {
+				continue
+			}
+			if err := p.patches[i].Apply(&obj); err != nil {
+				return nil, err
+			}
+			patched[i] = true
+		}
+		pruneFields(&obj, p.prunedFields)
+		setGeneratedName(&obj, p.generateNameKinds, p.generateNameSuffix)
+		if len(p.extraAnnotations) > 0 {
+			obj.SetAnnotations(util.MergeMaps(obj.GetAnnotations(), p.extraAnnotations))
+		}
+		obj.SetLabels(util.MergeMaps(obj.GetLabels(), p.extraLabels, p.labels))
+		keepResource(&obj, p.owner)
+		if p.remote {
+			removeOwnerReference(&obj, p.owner)
+		}
+		if p.argoCD != nil {
+			p.argoCD.apply(&obj)
+		}
+		if obj.GroupVersionKind() == apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition") {
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+				return nil, fmt.Errorf("convert CRD %q: %v", obj.GetName(), err)
+			}
+			p.crds = append(p.crds, crd)
+		}
 		b, err := obj.MarshalJSON()
 		if err != nil {
 			return nil, err
 		}
 		buf.Write(b)
+		p.renderedObjects++
+	}
+	p.renderedBytes = buf.Len()
+	for i, ok := range patched {
+		if !ok {
+			// A patch whose target is misspelled would be ignored silently
+			// otherwise.
+			return nil, fmt.Errorf("patches[%d] matches no object: the bundle has no %s", i, p.patches[i].Target)
+		}
 	}
 	if p.cascade != nil {
 		return p.cascade.Run(&buf)
@@ -592,47 +1565,164 @@ func checkForUnexpectedFieldChange(a, b rukpakv1alpha2.BundleDeployment) bool {
 	return !equality.Semantic.DeepEqual(a, b)
 }
 
-func updateStatusUnpackFailing(status *rukpakv1alpha2.BundleDeploymentStatus, err error) error {
-	status.ResolvedSource = nil
-	status.ContentURL = ""
+// verifySnapshotDigest fails if bd was restored from a snapshot and the
+// unpacked content differs from the content that the snapshot was taken of.
+func verifySnapshotDigest(bd *rukpakv1alpha2.BundleDeployment, result *unpackersource.Result) error {
+	want, ok := bd.Annotations[snapshot.DigestAnnotationKey]
+	if !ok || result.ResolvedSource == nil {
+		return nil
+	}
+	if got := result.ResolvedSource.BundleDigest; got != want {
+		return rukpakerrors.NewUnrecoverable(fmt.Errorf("unpacked bundle digest %q does not match the digest %q of the restored snapshot", got, want))
+	}
+	return nil
+}
+
+// updateStatusUnpackFailing sets the Unpacked condition to False with the
+// UnpackTransientError reason if err is transient, such as a network failure,
+// with the SignatureVerificationFailed reason if the signature of the source
+// could not be verified, and with the UnpackFailed reason otherwise.
+func updateStatusUnpackFailing(status *rukpakv1alpha2.BundleDeploymentStatus, sourceChanged bool, source rukpakv1alpha2.BundleSource, err error) error {
+	updateStatusSource(status, sourceChanged, source, nil)
+	reason := rukpakv1alpha2.ReasonUnpackFailed
+	var transient *rukpakerrors.Transient
+	var unavailable *storage.UnavailableError
+	var tooLarge *storage.BundleTooLargeError
+	switch {
+	case errors.As(err, &transient):
+		reason = rukpakv1alpha2.ReasonUnpackTransientError
+	case errors.As(err, &unavailable):
+		reason = rukpakv1alpha2.ReasonStorageUnavailable
+	case errors.As(err, &tooLarge):
+		reason = rukpakv1alpha2.ReasonBundleTooLarge
+	case errors.Is(err, unpackersource.ErrSignatureVerification):
+		reason = rukpakv1alpha2.ReasonSignatureVerificationFailed
+	}
+	metrics.RecordUnpackFailure(source.Type, reason)
 	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
-		Type:    rukpakv1alpha2.TypeUnpacked,
-		Status:  metav1.ConditionFalse,
-		Reason:  rukpakv1alpha2.ReasonUnpackFailed,
-		Message: err.Error(),
+		Type:               rukpakv1alpha2.TypeUnpacked,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: status.ObservedGeneration,
 	})
 	return err
 }
 
-func updateStatusUnpackPending(status *rukpakv1alpha2.BundleDeploymentStatus, result *unpackersource.Result) {
-	status.ResolvedSource = nil
-	status.ContentURL = ""
+func updateStatusUnpackPending(status *rukpakv1alpha2.BundleDeploymentStatus, sourceChanged bool, source rukpakv1alpha2.BundleSource, result *unpackersource.Result) {
+	updateStatusSource(status, sourceChanged, source, result.ResolvedSource)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeUnpacked,
+		Status:             metav1.ConditionFalse,
+		Reason:             rukpakv1alpha2.ReasonUnpackPending,
+		Message:            result.Message,
+		ObservedGeneration: status.ObservedGeneration,
+	})
+}
+
+func updateStatusUnpacking(status *rukpakv1alpha2.BundleDeploymentStatus, sourceChanged bool, source rukpakv1alpha2.BundleSource, result *unpackersource.Result) {
+	updateStatusSource(status, sourceChanged, source, result.ResolvedSource)
 	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
-		Type:    rukpakv1alpha2.TypeUnpacked,
-		Status:  metav1.ConditionFalse,
-		Reason:  rukpakv1alpha2.ReasonUnpackPending,
-		Message: result.Message,
+		Type:               rukpakv1alpha2.TypeUnpacked,
+		Status:             metav1.ConditionFalse,
+		Reason:             rukpakv1alpha2.ReasonUnpacking,
+		Message:            result.Message,
+		ObservedGeneration: status.ObservedGeneration,
 	})
 }
 
-func updateStatusUnpacking(status *rukpakv1alpha2.BundleDeploymentStatus, result *unpackersource.Result) {
-	status.ResolvedSource = nil
-	status.ContentURL = ""
+// updateStatusSource updates the resolved source and content URL in status
+// while the content of source is not unpacked. They are left untouched unless
+// the source changed, so that consumers of the status do not observe them
+// flapping to empty values during routine reconciles. If the source changed
+// and a version is installed, they keep describing the installed version and
+// the UpgradePending condition records the incoming source, resolved if
+// possible. Otherwise, they are cleared.
+func updateStatusSource(status *rukpakv1alpha2.BundleDeploymentStatus, sourceChanged bool, source rukpakv1alpha2.BundleSource, resolved *rukpakv1alpha2.BundleSource) {
+	upgradePending := meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUpgradePending) != nil
+	if !sourceChanged && !upgradePending {
+		return
+	}
+	if sourceChanged && (status.ResolvedSource == nil || !meta.IsStatusConditionTrue(status.Conditions, rukpakv1alpha2.TypeInstalled)) {
+		status.ResolvedSource = nil
+		status.SourceHash = ""
+		status.ContentURL = ""
+		status.ContentSize = 0
+		meta.RemoveStatusCondition(&status.Conditions, rukpakv1alpha2.TypeUpgradePending)
+		meta.RemoveStatusCondition(&status.Conditions, rukpakv1alpha2.TypeContentServed)
+		return
+	}
+	if resolved != nil {
+		source = *resolved
+	}
 	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
-		Type:    rukpakv1alpha2.TypeUnpacked,
-		Status:  metav1.ConditionFalse,
-		Reason:  rukpakv1alpha2.ReasonUnpacking,
-		Message: result.Message,
+		Type:               rukpakv1alpha2.TypeUpgradePending,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha2.ReasonUpgrading,
+		Message:            fmt.Sprintf("unpacking %s", sourceRef(source)),
+		ObservedGeneration: status.ObservedGeneration,
 	})
 }
 
-func updateStatusUnpacked(status *rukpakv1alpha2.BundleDeploymentStatus, result *unpackersource.Result, contentURL string) {
+// sourceRef returns a human-readable reference to the content of source.
+func sourceRef(source rukpakv1alpha2.BundleSource) string {
+	switch {
+	case source.Type == rukpakv1alpha2.SourceTypeImage && source.Image != nil:
+		return fmt.Sprintf("image %s", source.Image.Ref)
+	case source.Type == rukpakv1alpha2.SourceTypeGit && source.Git != nil:
+		ref := source.Git.Ref.Commit
+		if ref == "" {
+			ref = source.Git.Ref.Tag
+		}
+		if ref == "" {
+			ref = source.Git.Ref.Branch
+		}
+		return fmt.Sprintf("git repository %s@%s", source.Git.Repository, ref)
+	case source.Type == rukpakv1alpha2.SourceTypeHTTP && source.HTTP != nil:
+		return source.HTTP.URL
+	case source.Type == rukpakv1alpha2.SourceTypeConfigMaps:
+		names := make([]string, 0, len(source.ConfigMaps))
+		for _, cm := range source.ConfigMaps {
+			names = append(names, cm.ConfigMap.Name)
+		}
+		return fmt.Sprintf("configmaps %s", strings.Join(names, ", "))
+	case source.Type == rukpakv1alpha2.SourceTypeSecrets:
+		names := make([]string, 0, len(source.Secrets))
+		for _, secret := range source.Secrets {
+			names = append(names, secret.Secret.Name)
+		}
+		return fmt.Sprintf("secrets %s", strings.Join(names, ", "))
+	case source.Type == rukpakv1alpha2.SourceTypeInline:
+		return "inline manifests"
+	}
+	return fmt.Sprintf("%s source", source.Type)
+}
+
+// sourceChangedSinceResolved reports whether spec.source of bd differs from
+// the source that status.resolvedSource was resolved from. Other changes of
+// the spec, such as of its config, leave the resolved source untouched.
+// Statuses that were written before the source was recorded fall back to
+// whether the spec changed since the last reconcile.
+func sourceChangedSinceResolved(bd *rukpakv1alpha2.BundleDeployment) bool {
+	if bd.Status.SourceHash == "" {
+		return bd.Status.ObservedGeneration != bd.Generation
+	}
+	hash, err := sourceHash(bd.Spec.Source)
+	return err != nil || hash != bd.Status.SourceHash
+}
+
+func updateStatusUnpacked(status *rukpakv1alpha2.BundleDeploymentStatus, source rukpakv1alpha2.BundleSource, result *unpackersource.Result, contentURL string) {
 	status.ResolvedSource = result.ResolvedSource
+	// The hash is only used to detect changes of the source, which are
+	// assumed if it cannot be computed.
+	status.SourceHash, _ = sourceHash(source)
 	status.ContentURL = contentURL
+	meta.RemoveStatusCondition(&status.Conditions, rukpakv1alpha2.TypeUpgradePending)
 	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
-		Type:    rukpakv1alpha2.TypeUnpacked,
-		Status:  metav1.ConditionTrue,
-		Reason:  rukpakv1alpha2.ReasonUnpackSuccessful,
-		Message: result.Message,
+		Type:               rukpakv1alpha2.TypeUnpacked,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha2.ReasonUnpackSuccessful,
+		Message:            result.Message,
+		ObservedGeneration: status.ObservedGeneration,
 	})
 }
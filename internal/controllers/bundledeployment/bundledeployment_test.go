@@ -2,18 +2,63 @@ package bundledeployment
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	helmstorage "helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	crfinalizer "sigs.k8s.io/controller-runtime/pkg/finalizer"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/patches"
+	"github.com/operator-framework/rukpak/internal/requirements"
+	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
+	"github.com/operator-framework/rukpak/pkg/finalizer"
+	"github.com/operator-framework/rukpak/pkg/handler"
+	"github.com/operator-framework/rukpak/pkg/healthchecks"
+	"github.com/operator-framework/rukpak/pkg/installreport"
+	"github.com/operator-framework/rukpak/pkg/snapshot"
+	unpackersource "github.com/operator-framework/rukpak/pkg/source"
+	"github.com/operator-framework/rukpak/pkg/storage"
+	rukpaktesting "github.com/operator-framework/rukpak/pkg/testing"
 	"github.com/operator-framework/rukpak/pkg/util"
 )
 
@@ -117,5 +162,2082 @@ var _ = Describe("BundleDeployment", func() {
 			})
 
 		})
+
+		Context("with labels and annotations of the spec", func() {
+			BeforeEach(func() {
+				postren = &postrenderer{
+					labels: map[string]string{
+						util.CoreOwnerKindKey: rukpakv1alpha2.BundleDeploymentKind,
+						util.CoreOwnerNameKey: "test-owner",
+					},
+					extraLabels: map[string]string{
+						"example.com/cost-center": "cc-1234",
+						"testKey":                 "overridden",
+						util.CoreOwnerNameKey:     "other-owner",
+					},
+					extraAnnotations: map[string]string{"example.com/team": "platform"},
+				}
+				pod = corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "testPod",
+						Labels:      map[string]string{"testKey": "testValue"},
+						Annotations: map[string]string{"bundleKey": "bundleValue"},
+					},
+				}
+				pod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+			})
+
+			It("merges them over the bundle's but not over the owner labels", func() {
+				Expect(json.NewEncoder(&inBuf).Encode(pod)).To(Succeed())
+				outBuf, err := postren.Run(&inBuf)
+				Expect(err).NotTo(HaveOccurred())
+
+				renderedPod := &corev1.Pod{}
+				Expect(json.Unmarshal(outBuf.Bytes(), renderedPod)).To(Succeed())
+				Expect(renderedPod.GetLabels()).To(Equal(map[string]string{
+					"example.com/cost-center":   "cc-1234",
+					"testKey":                   "overridden",
+					"core.rukpak.io/owner-kind": "BundleDeployment",
+					"core.rukpak.io/owner-name": "test-owner",
+				}))
+				Expect(renderedPod.GetAnnotations()).To(Equal(map[string]string{
+					"bundleKey":        "bundleValue",
+					"example.com/team": "platform",
+				}))
+			})
+		})
+
+		Context("with Argo CD tracking", func() {
+			var bd *rukpakv1alpha2.BundleDeployment
+
+			render := func(method ArgoCDTrackingMethod, objs ...client.Object) []*unstructured.Unstructured {
+				var in bytes.Buffer
+				for _, obj := range objs {
+					Expect(json.NewEncoder(&in).Encode(obj)).To(Succeed())
+				}
+				p := &postrenderer{argoCD: newArgoCDTracking(method, bd, func(u *unstructured.Unstructured) bool {
+					return u.GetKind() != "ClusterRole"
+				})}
+				out, err := p.Run(&in)
+				Expect(err).NotTo(HaveOccurred())
+				rendered, err := util.ManifestObjects(out, "rendered")
+				Expect(err).NotTo(HaveOccurred())
+				var result []*unstructured.Unstructured
+				for _, obj := range rendered {
+					result = append(result, obj.(*unstructured.Unstructured))
+				}
+				return result
+			}
+
+			BeforeEach(func() {
+				bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+				bd.Spec.InstallNamespace = "ns"
+				pod = corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "testPod"}}
+				pod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+			})
+
+			It("adds tracking IDs that name the install namespace of namespaced objects", func() {
+				role := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "role"}}
+				role.SetGroupVersionKind(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"))
+				objs := render(ArgoCDTrackingAnnotation, &pod, role)
+				Expect(objs[0].GetAnnotations()).To(Equal(map[string]string{
+					"argocd.argoproj.io/tracking-id":     "test:/Pod:ns/testPod",
+					"argocd.argoproj.io/compare-options": "IgnoreExtraneous",
+					"argocd.argoproj.io/sync-options":    "Prune=false",
+				}))
+				Expect(objs[0].GetLabels()).NotTo(HaveKey("app.kubernetes.io/instance"))
+				Expect(objs[1].GetAnnotations()).To(HaveKeyWithValue("argocd.argoproj.io/tracking-id", "test:rbac.authorization.k8s.io/ClusterRole:/role"))
+			})
+
+			It("uses the application that tracks the BundleDeployment", func() {
+				bd.Annotations = map[string]string{"argocd.argoproj.io/tracking-id": "platform:core.rukpak.io/BundleDeployment:/test"}
+				objs := render(ArgoCDTrackingAnnotationAndLabel, &pod)
+				Expect(objs[0].GetAnnotations()).To(HaveKeyWithValue("argocd.argoproj.io/tracking-id", "platform:/Pod:ns/testPod"))
+				Expect(objs[0].GetLabels()).To(HaveKeyWithValue("app.kubernetes.io/instance", "platform"))
+			})
+
+			It("keeps the compare and sync options of objects", func() {
+				pod.Annotations = map[string]string{"argocd.argoproj.io/sync-options": "Prune=true"}
+				objs := render(ArgoCDTrackingLabel, &pod)
+				Expect(objs[0].GetAnnotations()).To(HaveKeyWithValue("argocd.argoproj.io/sync-options", "Prune=true"))
+				Expect(objs[0].GetAnnotations()).NotTo(HaveKey("argocd.argoproj.io/tracking-id"))
+				Expect(objs[0].GetLabels()).To(HaveKeyWithValue("app.kubernetes.io/instance", "test"))
+			})
+		})
+
+		Context("with resource policies", func() {
+			const owner = types.UID("bd-uid")
+
+			render := func(annotations map[string]string, remote bool) *unstructured.Unstructured {
+				pod = corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:        "testPod",
+					Annotations: annotations,
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "core.rukpak.io/v1alpha2", Kind: "BundleDeployment", Name: "test", UID: owner},
+						{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"},
+					},
+				}}
+				pod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+				var in bytes.Buffer
+				Expect(json.NewEncoder(&in).Encode(&pod)).To(Succeed())
+				out, err := (&postrenderer{owner: owner, remote: remote}).Run(&in)
+				Expect(err).NotTo(HaveOccurred())
+				obj := &unstructured.Unstructured{}
+				Expect(json.Unmarshal(out.Bytes(), obj)).To(Succeed())
+				return obj
+			}
+
+			It("delegates the rukpak resource policy to Helm and drops the owner reference to the BundleDeployment", func() {
+				obj := render(map[string]string{rukpakv1alpha2.ResourcePolicyAnnotation: rukpakv1alpha2.ResourcePolicyKeep}, false)
+				Expect(obj.GetAnnotations()).To(HaveKeyWithValue("helm.sh/resource-policy", "keep"))
+				Expect(obj.GetOwnerReferences()).To(ConsistOf(HaveField("UID", types.UID("other-uid"))))
+			})
+
+			It("drops the owner reference of objects that Helm keeps", func() {
+				obj := render(map[string]string{"helm.sh/resource-policy": "keep"}, false)
+				Expect(obj.GetAnnotations()).NotTo(HaveKey(rukpakv1alpha2.ResourcePolicyAnnotation))
+				Expect(obj.GetOwnerReferences()).To(HaveLen(1))
+			})
+
+			It("leaves objects without a keep policy alone", func() {
+				obj := render(map[string]string{rukpakv1alpha2.ResourcePolicyAnnotation: "delete"}, false)
+				Expect(obj.GetAnnotations()).NotTo(HaveKey("helm.sh/resource-policy"))
+				Expect(obj.GetOwnerReferences()).To(HaveLen(2))
+			})
+
+			It("drops the owner reference to the BundleDeployment from objects of remote clusters", func() {
+				obj := render(nil, true)
+				Expect(obj.GetAnnotations()).NotTo(HaveKey("helm.sh/resource-policy"))
+				Expect(obj.GetOwnerReferences()).To(ConsistOf(HaveField("UID", types.UID("other-uid"))))
+			})
+		})
+
+		Context("with patches", func() {
+			render := func(config string) (*unstructured.Unstructured, error) {
+				objPatches, err := patches.FromConfig(runtime.RawExtension{Raw: []byte(config)})
+				Expect(err).NotTo(HaveOccurred())
+				in := bytes.NewBufferString("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: operator\nspec:\n  replicas: 1\n")
+				out, err := (&postrenderer{
+					labels:           map[string]string{util.CoreOwnerNameKey: "test"},
+					patches:          objPatches,
+					installNamespace: "test-ns",
+				}).Run(in)
+				if err != nil {
+					return nil, err
+				}
+				obj := &unstructured.Unstructured{}
+				Expect(json.Unmarshal(out.Bytes(), obj)).To(Succeed())
+				return obj, nil
+			}
+
+			It("patches the targeted object before adding labels", func() {
+				obj, err := render(`{"patches":[{"target":{"kind":"Deployment","name":"operator","namespace":"test-ns"},"patch":[{"op":"replace","path":"/spec/replicas","value":3},{"op":"add","path":"/metadata/labels","value":{"core.rukpak.io/owner-name":"other"}}]}]}`)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(obj.Object).To(HaveKeyWithValue("spec", HaveKeyWithValue("replicas", BeNumerically("==", 3))))
+				Expect(obj.GetLabels()).To(HaveKeyWithValue(util.CoreOwnerNameKey, "test"))
+			})
+
+			It("fails for patches that match no object", func() {
+				_, err := render(`{"patches":[{"target":{"kind":"Deployment","name":"operator","namespace":"other-ns"},"patch":[{"op":"remove","path":"/spec/replicas"}]}]}`)
+				Expect(err).To(MatchError(ContainSubstring("patches[0] matches no object: the bundle has no Deployment other-ns/operator")))
+			})
+
+			It("fails for patches that do not apply", func() {
+				_, err := render(`{"patches":[{"target":{"kind":"Deployment","name":"operator"},"patch":[{"op":"remove","path":"/spec/paused"}]}]}`)
+				Expect(err).To(MatchError(ContainSubstring("apply patch to Deployment operator")))
+			})
+		})
+	})
+
+	var _ = Describe("reconcileObjects", func() {
+		const manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-a
+  namespace: ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-b
+  namespace: ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-c
+  namespace: ns
+`
+		var (
+			cl       *fakeActionClient
+			bd       *rukpakv1alpha2.BundleDeployment
+			rel      *release.Release
+			previous *release.Release
+		)
+
+		BeforeEach(func() {
+			cl = &fakeActionClient{failing: map[string]error{}}
+			bd = &rukpakv1alpha2.BundleDeployment{}
+			rel = &release.Release{Name: "test", Namespace: "ns", Manifest: manifest}
+			previous = &release.Release{Name: "test", Namespace: "ns", Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-a\n  namespace: ns\n"}
+		})
+
+		result := func(name string, typ rukpakv1alpha2.ObjectApplyResultType, message string) rukpakv1alpha2.ObjectApplyResult {
+			return rukpakv1alpha2.ObjectApplyResult{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: name, Result: typ, Message: message}
+		}
+
+		It("applies every object when none fail", func() {
+			Expect(reconcileObjects(cl, bd, rel, previous, 0)).To(Succeed())
+			Expect(cl.reconciled).To(Equal([]string{"cm-a", "cm-b", "cm-c"}))
+			Expect(bd.Status.ObjectApplyResults).To(Equal([]rukpakv1alpha2.ObjectApplyResult{
+				result("cm-a", rukpakv1alpha2.ObjectApplyResultUpdated, ""),
+				result("cm-b", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+				result("cm-c", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+			}))
+		})
+
+		It("continues past a failing object and records the failure", func() {
+			cl.failing["cm-b"] = errors.New("boom")
+
+			err := reconcileObjects(cl, bd, rel, previous, 0)
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+			Expect(cl.reconciled).To(Equal([]string{"cm-a", "cm-b", "cm-c"}))
+			Expect(bd.Status.ObjectApplyResults).To(Equal([]rukpakv1alpha2.ObjectApplyResult{
+				result("cm-a", rukpakv1alpha2.ObjectApplyResultUpdated, ""),
+				result("cm-b", rukpakv1alpha2.ObjectApplyResultFailed, "boom"),
+				result("cm-c", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+			}))
+		})
+
+		It("retries only the previously failed objects and records the others as skipped", func() {
+			cl.failing["cm-b"] = errors.New("boom")
+			Expect(reconcileObjects(cl, bd, rel, previous, 0)).NotTo(Succeed())
+
+			cl.reconciled = nil
+			delete(cl.failing, "cm-b")
+			Expect(reconcileObjects(cl, bd, rel, previous, 0)).To(Succeed())
+			Expect(cl.reconciled).To(Equal([]string{"cm-b"}))
+			skipped := "Waiting for the failed objects of the release to be applied"
+			Expect(bd.Status.ObjectApplyResults).To(Equal([]rukpakv1alpha2.ObjectApplyResult{
+				result("cm-a", rukpakv1alpha2.ObjectApplyResultSkipped, skipped),
+				result("cm-b", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+				result("cm-c", rukpakv1alpha2.ObjectApplyResultSkipped, skipped),
+			}))
+
+			cl.reconciled = nil
+			Expect(reconcileObjects(cl, bd, rel, rel, 0)).To(Succeed())
+			Expect(cl.reconciled).To(Equal([]string{"cm-a", "cm-b", "cm-c"}))
+			Expect(bd.Status.ObjectApplyResults).To(HaveEach(HaveField("Result", rukpakv1alpha2.ObjectApplyResultUpdated)))
+		})
+
+		It("continues an interrupted reconcile of a release that exceeds its budget", func() {
+			var manifest strings.Builder
+			for i := 0; i < 2*reconcileBatchSize+10; i++ {
+				fmt.Fprintf(&manifest, "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-%d\n  namespace: ns\n", i)
+			}
+			rel = &release.Release{Name: "test", Namespace: "ns", Version: 1, Manifest: manifest.String()}
+
+			Expect(reconcileObjects(cl, bd, rel, rel, time.Nanosecond)).To(Succeed())
+			Expect(cl.reconciled).To(HaveLen(reconcileBatchSize))
+			Expect(bd.Status.ReconcileContinuation).To(Equal(&rukpakv1alpha2.ReconcileContinuation{Revision: 1, NextObject: reconcileBatchSize}))
+
+			cl.reconciled = nil
+			Expect(reconcileObjects(cl, bd, rel, rel, time.Nanosecond)).To(Succeed())
+			Expect(cl.reconciled).To(HaveLen(reconcileBatchSize))
+			Expect(cl.reconciled[0]).To(Equal(fmt.Sprintf("cm-%d", reconcileBatchSize)))
+			Expect(bd.Status.ReconcileContinuation.NextObject).To(Equal(2 * reconcileBatchSize))
+
+			cl.reconciled = nil
+			Expect(reconcileObjects(cl, bd, rel, rel, time.Nanosecond)).To(Succeed())
+			Expect(cl.reconciled).To(HaveLen(10))
+			Expect(bd.Status.ReconcileContinuation).To(BeNil())
+			Expect(bd.Status.ObjectApplyResults).To(HaveLen(2*reconcileBatchSize + 10))
+		})
+
+		It("starts over when the release changed since the reconcile was interrupted", func() {
+			bd.Status.ReconcileContinuation = &rukpakv1alpha2.ReconcileContinuation{Revision: 1, NextObject: 2}
+			rel.Version = 2
+
+			Expect(reconcileObjects(cl, bd, rel, rel, time.Hour)).To(Succeed())
+			Expect(cl.reconciled).To(Equal([]string{"cm-a", "cm-b", "cm-c"}))
+			Expect(bd.Status.ReconcileContinuation).To(BeNil())
+		})
+
+		It("records the objects of an upgrade that were in the previous release as updated", func() {
+			previous.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-b\n  namespace: ns\n"
+
+			results, err := releaseApplyResults(previous, rel)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(Equal([]rukpakv1alpha2.ObjectApplyResult{
+				result("cm-a", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+				result("cm-b", rukpakv1alpha2.ObjectApplyResultUpdated, ""),
+				result("cm-c", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+			}))
+
+			results, err = releaseApplyResults(nil, rel)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveEach(HaveField("Result", rukpakv1alpha2.ObjectApplyResultCreated)))
+		})
+	})
+
+	var _ = Describe("ensureCRDs", func() {
+		var (
+			c         *controller
+			mapper    *meta.DefaultRESTMapper
+			bd        *rukpakv1alpha2.BundleDeployment
+			existing  *apiextensionsv1.CustomResourceDefinition
+			establish bool
+		)
+
+		newCRD := func(group, kind string) *apiextensionsv1.CustomResourceDefinition {
+			return &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: strings.ToLower(kind) + "s." + group},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group:    group,
+					Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: kind, Plural: strings.ToLower(kind) + "s"},
+					Scope:    apiextensionsv1.NamespaceScoped,
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: "v1", Served: true, Storage: true}},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(apiextensionsv1.AddToScheme(scheme)).To(Succeed())
+			mapper = meta.NewDefaultRESTMapper(nil)
+			mapper.Add(apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"), meta.RESTScopeRoot)
+			mapper.Add(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"}, meta.RESTScopeNamespace)
+
+			existing = newCRD("example.com", "Gadget")
+			existing.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}}
+
+			// The fake apiserver establishes created CRDs right away, unless
+			// establish is unset.
+			establish = true
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(existing).WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok && !establish {
+						crd.CreationTimestamp = metav1.Now()
+					} else if ok {
+						crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}}
+						mapper.Add(schema.GroupVersionKind{Group: crd.Spec.Group, Version: "v1", Kind: crd.Spec.Names.Kind}, meta.RESTScopeNamespace)
+					}
+					return cl.Create(ctx, obj, opts...)
+				},
+			}).Build()
+			c = &controller{cl: cl}
+			bd = &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "ns"},
+			}
+		})
+
+		It("creates missing CRDs for Helm to adopt and checks that they are established", func() {
+			Expect(c.ensureCRDs(context.Background(), bd, []*apiextensionsv1.CustomResourceDefinition{newCRD("example.com", "Gadget"), newCRD("example.com", "Widget")})).To(BeEmpty())
+
+			created := &apiextensionsv1.CustomResourceDefinition{}
+			Expect(c.cl.Get(context.Background(), client.ObjectKey{Name: "widgets.example.com"}, created)).To(Succeed())
+			Expect(created.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "Helm"))
+			Expect(created.Annotations).To(HaveKeyWithValue("meta.helm.sh/release-name", "test"))
+			Expect(created.Annotations).To(HaveKeyWithValue("meta.helm.sh/release-namespace", "ns"))
+
+			_, err := mapper.RESTMapping(schema.GroupKind{Group: "example.com", Kind: "Widget"}, "v1")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("leaves existing CRDs to Helm", func() {
+			Expect(c.ensureCRDs(context.Background(), bd, []*apiextensionsv1.CustomResourceDefinition{newCRD("example.com", "Gadget")})).To(BeEmpty())
+
+			current := &apiextensionsv1.CustomResourceDefinition{}
+			Expect(c.cl.Get(context.Background(), client.ObjectKeyFromObject(existing), current)).To(Succeed())
+			Expect(current.Labels).NotTo(HaveKey("app.kubernetes.io/managed-by"))
+		})
+
+		It("returns the CRDs that are not established yet without waiting for them", func() {
+			establish = false
+			Expect(c.ensureCRDs(context.Background(), bd, []*apiextensionsv1.CustomResourceDefinition{newCRD("example.com", "Gadget"), newCRD("example.com", "Widget")})).To(Equal([]string{"widgets.example.com"}))
+
+			res := waitForCRDs(bd, []string{"widgets.example.com"})
+			Expect(res.RequeueAfter).To(Equal(crdEstablishedRecheckInterval))
+			installed := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeInstalled)
+			Expect(installed).NotTo(BeNil())
+			Expect(installed.Reason).To(Equal(rukpakv1alpha2.ReasonCRDsNotEstablished))
+			Expect(installed.Message).To(ContainSubstring("widgets.example.com"))
+		})
+
+		It("fails for CRDs that are not established in time", func() {
+			existing.Status.Conditions = nil
+			existing.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * crdEstablishedTimeout))
+			Expect(c.cl.Status().Update(context.Background(), existing)).To(Succeed())
+
+			_, err := c.ensureCRDs(context.Background(), bd, []*apiextensionsv1.CustomResourceDefinition{newCRD("example.com", "Gadget")})
+			Expect(err).To(MatchError(ContainSubstring(`CRD "gadgets.example.com" is not established`)))
+		})
+
+		It("collects the rendered CRDs in the postrenderer", func() {
+			post := &postrenderer{}
+			var in bytes.Buffer
+			Expect(json.NewEncoder(&in).Encode(map[string]interface{}{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": map[string]interface{}{"name": "widgets.example.com"}})).To(Succeed())
+			Expect(json.NewEncoder(&in).Encode(map[string]interface{}{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": map[string]interface{}{"name": "widget"}})).To(Succeed())
+			_, err := post.Run(&in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(post.crds).To(HaveLen(1))
+			Expect(post.crds[0].Name).To(Equal("widgets.example.com"))
+		})
+
+		It("measures the rendered manifest in the postrenderer", func() {
+			post := &postrenderer{}
+			in := bytes.NewBufferString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n")
+			out, err := post.Run(in)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(post.renderedObjects).To(Equal(2))
+			Expect(post.renderedBytes).To(Equal(out.Len()))
+		})
+	})
+
+	var _ = Describe("findCollisions", func() {
+		const manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mine
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: theirs
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unmanaged
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new
+`
+		var (
+			c  *controller
+			bd *rukpakv1alpha2.BundleDeployment
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+			mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+			owned := func(name, owner string) *corev1.ConfigMap {
+				cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}}
+				if owner != "" {
+					cm.Labels = map[string]string{util.CoreOwnerKindKey: rukpakv1alpha2.BundleDeploymentKind, util.CoreOwnerNameKey: owner}
+				}
+				return cm
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(
+				owned("mine", "test"),
+				owned("theirs", "other"),
+				owned("unmanaged", ""),
+			).Build()
+			c = &controller{cl: cl}
+			bd = &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "ns"},
+			}
+		})
+
+		It("names the owners of colliding objects", func() {
+			installErr := errors.New("invalid ownership metadata")
+			err := c.findCollisions(context.Background(), bd, &release.Release{Name: "test", Manifest: manifest}, installErr)
+			Expect(err).To(MatchError(`objects collide with existing objects: ConfigMap ns/theirs is managed by BundleDeployment "other"; ConfigMap ns/unmanaged exists and is not managed by a BundleDeployment`))
+			Expect(errors.Is(err, installErr)).To(BeTrue())
+		})
+
+		It("returns the install error if no objects collide", func() {
+			installErr := errors.New("timed out")
+			err := c.findCollisions(context.Background(), bd, &release.Release{Name: "test", Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: new\n"}, installErr)
+			Expect(err).To(Equal(installErr))
+		})
+	})
+
+	var _ = Describe("generateName", func() {
+		It("names objects of the configured kinds", func() {
+			post := &postrenderer{
+				generateNameKinds:  map[schema.GroupKind]struct{}{{Kind: "Job"}: {}},
+				generateNameSuffix: generateNameSuffix(&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}),
+			}
+			var in bytes.Buffer
+			Expect(json.NewEncoder(&in).Encode(map[string]interface{}{"apiVersion": "v1", "kind": "Job", "metadata": map[string]interface{}{"generateName": "migrate-"}})).To(Succeed())
+			Expect(json.NewEncoder(&in).Encode(map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "metadata": map[string]interface{}{"generateName": "debug-"}})).To(Succeed())
+			out, err := post.Run(&in)
+			Expect(err).NotTo(HaveOccurred())
+
+			objs, err := util.ManifestObjects(out, "rendered")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(objs).To(HaveLen(2))
+			Expect(objs[0].GetName()).To(Equal("migrate-" + post.generateNameSuffix))
+			Expect(objs[0].GetGenerateName()).To(BeEmpty())
+			Expect(objs[1].GetName()).To(BeEmpty())
+			Expect(objs[1].GetGenerateName()).To(Equal("debug-"))
+		})
+
+		It("derives a stable suffix from the bundle deployment name", func() {
+			a := generateNameSuffix(&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "a"}})
+			Expect(a).To(HaveLen(generateNameSuffixLength))
+			Expect(generateNameSuffix(&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "a"}})).To(Equal(a))
+			Expect(generateNameSuffix(&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "b"}})).NotTo(Equal(a))
+		})
+	})
+
+	var _ = Describe("analyze", func() {
+		var (
+			c        *controller
+			analyzer *fakeAnalyzer
+			cl       *fakeActionClient
+			bd       *rukpakv1alpha2.BundleDeployment
+			previous *release.Release
+			rel      *release.Release
+		)
+
+		BeforeEach(func() {
+			analyzer = &fakeAnalyzer{breached: map[string]bool{}}
+			c = &controller{analyzer: analyzer, storage: &storage.LocalDirectory{RootDirectory: GinkgoT().TempDir()}}
+			previous = &release.Release{Name: "test", Version: 1, Chart: &chart.Chart{Metadata: &chart.Metadata{Version: "1.0.0"}}, Config: map[string]interface{}{"a": "b"}, Manifest: "kind: ConfigMap\n"}
+			cl = &fakeActionClient{releases: map[int]*release.Release{1: previous}}
+			bd = &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 2},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace: "ns",
+					Analysis: &rukpakv1alpha2.AnalysisConfig{
+						PrometheusURL: "http://prometheus",
+						SoakPeriod:    metav1.Duration{Duration: 10 * time.Minute},
+						Queries:       []rukpakv1alpha2.AnalysisQuery{{Name: "errors", Expr: "errors > 0"}},
+					},
+				},
+			}
+			rel = &release.Release{Name: "test", Version: 2, Info: &release.Info{LastDeployed: helmtime.Now()}, Manifest: "kind: Secret\n"}
+		})
+
+		It("requeues during the soak period when no query is breached", func() {
+			rolledBack, res, err := c.analyze(context.Background(), cl, bd, rel, &postrenderer{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeFalse())
+			Expect(res.RequeueAfter).To(Equal(defaultAnalysisInterval))
+			Expect(cl.upgrades).To(BeEmpty())
+		})
+
+		It("does not evaluate queries after the soak period", func() {
+			rel.Info.LastDeployed = helmtime.Time{Time: time.Now().Add(-time.Hour)}
+			analyzer.breached["errors > 0"] = true
+			rolledBack, res, err := c.analyze(context.Background(), cl, bd, rel, &postrenderer{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeFalse())
+			Expect(res.RequeueAfter).To(BeZero())
+			Expect(analyzer.evaluated).To(BeEmpty())
+		})
+
+		It("rolls back to the previous release when a query is breached", func() {
+			analyzer.breached["errors > 0"] = true
+			rolledBack, _, err := c.analyze(context.Background(), cl, bd, rel, &postrenderer{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeTrue())
+			Expect(cl.upgrades).To(HaveLen(1))
+			Expect(cl.upgrades[0].Chart).To(Equal(previous.Chart))
+			Expect(cl.upgrades[0].Config).To(Equal(previous.Config))
+			Expect(cl.upgrades[0].Info.Description).To(Equal("Rollback to 1"))
+
+			cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeRollbackPerformed)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonAnalysisBreached))
+			Expect(rollbackPinned(bd)).To(BeTrue())
+
+			By("recording an install report for the rollback")
+			data, err := os.ReadFile(filepath.Join(c.storage.(*storage.LocalDirectory).RootDirectory, "test", "report"))
+			Expect(err).NotTo(HaveOccurred())
+			var env installreport.Envelope
+			Expect(json.Unmarshal(data, &env)).To(Succeed())
+			var report installreport.Report
+			Expect(json.Unmarshal(env.Payload, &report)).To(Succeed())
+			Expect(report.Action).To(Equal(installreport.ActionRollback))
+
+			By("recording the diff of the rollback")
+			diff, err := os.ReadFile(filepath.Join(c.storage.(*storage.LocalDirectory).RootDirectory, "test", "diffs", "3"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(diff)).To(Equal("--- revision-2\n+++ revision-3\n@@ -1 +1 @@\n-kind: Secret\n+kind: ConfigMap\n"))
+
+			bd.Generation++
+			Expect(rollbackPinned(bd)).To(BeFalse())
+		})
+
+		It("does not analyze a rolled back release", func() {
+			rel.Info.Description = "Rollback to 1"
+			analyzer.breached["errors > 0"] = true
+			rolledBack, _, err := c.analyze(context.Background(), cl, bd, rel, &postrenderer{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rolledBack).To(BeFalse())
+			Expect(analyzer.evaluated).To(BeEmpty())
+		})
+
+		It("reports a failed rollback", func() {
+			delete(cl.releases, 1)
+			analyzer.breached["errors > 0"] = true
+			_, _, err := c.analyze(context.Background(), cl, bd, rel, &postrenderer{})
+			Expect(err).To(MatchError(ContainSubstring(`analysis query "errors" was breached`)))
+			cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeRollbackPerformed)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonRollbackFailed))
+		})
+	})
+})
+
+var _ = DescribeTable("checkVersionPolicy",
+	func(policy *rukpakv1alpha2.VersionPolicy, from, to, expectedErr string) {
+		err := checkVersionPolicy(policy, &chart.Chart{Metadata: &chart.Metadata{Version: from}}, &chart.Chart{Metadata: &chart.Metadata{Version: to}})
+		if expectedErr == "" {
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		}
+	},
+	Entry("no policy", nil, "2.0.0", "1.0.0", ""),
+	Entry("upgrade", &rukpakv1alpha2.VersionPolicy{}, "1.0.0", "1.1.0", ""),
+	Entry("next major version", &rukpakv1alpha2.VersionPolicy{}, "1.2.0", "2.0.0", ""),
+	Entry("downgrade", &rukpakv1alpha2.VersionPolicy{}, "1.2.0", "1.1.0", "upgrade from version 1.2.0 to 1.1.0 is a downgrade"),
+	Entry("allowed downgrade", &rukpakv1alpha2.VersionPolicy{AllowDowngrades: true}, "1.2.0", "1.1.0", ""),
+	Entry("skipped major version", &rukpakv1alpha2.VersionPolicy{}, "1.2.0", "3.0.0", "skips major version 2"),
+	Entry("allowed major version skip", &rukpakv1alpha2.VersionPolicy{AllowMajorVersionSkips: true}, "1.2.0", "3.0.0", ""),
+	Entry("forced version", &rukpakv1alpha2.VersionPolicy{ForceVersion: "1.1.0"}, "1.2.0", "1.1.0", ""),
+	Entry("unversioned bundle", &rukpakv1alpha2.VersionPolicy{}, "", "1.0.0", ""),
+	Entry("invalid version", &rukpakv1alpha2.VersionPolicy{}, "1.0.0", "latest", "not a semantic version"),
+)
+
+var _ = Describe("unpack status", func() {
+	var (
+		status   *rukpakv1alpha2.BundleDeploymentStatus
+		source   rukpakv1alpha2.BundleSource
+		resolved *rukpakv1alpha2.BundleSource
+	)
+
+	BeforeEach(func() {
+		resolved = &rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle@sha256:1"}}
+		status = &rukpakv1alpha2.BundleDeploymentStatus{ResolvedSource: resolved, ContentURL: "https://example.com/bundles/test.tgz"}
+		source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v2"}}
+	})
+
+	It("clears the resolved source when the source changed and no version is installed", func() {
+		updateStatusUnpacking(status, true, source, &unpackersource.Result{State: unpackersource.StateUnpacking})
+		Expect(status.ResolvedSource).To(BeNil())
+		Expect(status.ContentURL).To(BeEmpty())
+		Expect(meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUpgradePending)).To(BeNil())
+	})
+
+	It("keeps the resolved source when the source did not change", func() {
+		updateStatusUnpackPending(status, false, source, &unpackersource.Result{State: unpackersource.StatePending})
+		Expect(status.ResolvedSource).To(Equal(resolved))
+		updateStatusUnpacking(status, false, source, &unpackersource.Result{State: unpackersource.StateUnpacking})
+		Expect(status.ResolvedSource).To(Equal(resolved))
+		Expect(updateStatusUnpackFailing(status, false, source, errors.New("registry unavailable"))).To(HaveOccurred())
+		Expect(status.ResolvedSource).To(Equal(resolved))
+		Expect(status.ContentURL).To(Equal("https://example.com/bundles/test.tgz"))
+		Expect(meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUpgradePending)).To(BeNil())
+	})
+
+	It("tells transient unpack errors from other unpack errors", func() {
+		Expect(updateStatusUnpackFailing(status, false, source, fmt.Errorf("source bundle content: %w", rukpakerrors.NewTransient(errors.New("connection reset"))))).To(HaveOccurred())
+		Expect(meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUnpacked).Reason).To(Equal(rukpakv1alpha2.ReasonUnpackTransientError))
+		Expect(updateStatusUnpackFailing(status, false, source, fmt.Errorf("source bundle content: %w", rukpakerrors.NewUnrecoverable(errors.New("404 Not Found"))))).To(HaveOccurred())
+		Expect(meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUnpacked).Reason).To(Equal(rukpakv1alpha2.ReasonUnpackFailed))
+	})
+
+	It("reports bundles over the size limit", func() {
+		Expect(updateStatusUnpackFailing(status, false, source, fmt.Errorf("persist bundle content: %w", &storage.BundleTooLargeError{Size: 2, MaxSize: 1}))).To(HaveOccurred())
+		Expect(meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUnpacked).Reason).To(Equal(rukpakv1alpha2.ReasonBundleTooLarge))
+	})
+
+	It("keeps the installed version and reports the pending upgrade", func() {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded})
+
+		updateStatusUnpackPending(status, true, source, &unpackersource.Result{State: unpackersource.StatePending})
+		Expect(status.ResolvedSource).To(Equal(resolved))
+		Expect(status.ContentURL).To(Equal("https://example.com/bundles/test.tgz"))
+		Expect(meta.IsStatusConditionTrue(status.Conditions, rukpakv1alpha2.TypeInstalled)).To(BeTrue())
+		cond := meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUpgradePending)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonUpgrading))
+		Expect(cond.Message).To(Equal("unpacking image quay.io/example/bundle:v2"))
+
+		incoming := &rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle@sha256:2"}}
+		updateStatusUnpacking(status, false, source, &unpackersource.Result{State: unpackersource.StateUnpacking, ResolvedSource: incoming})
+		Expect(status.ResolvedSource).To(Equal(resolved))
+		cond = meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUpgradePending)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Message).To(Equal("unpacking image quay.io/example/bundle@sha256:2"))
+
+		updateStatusUnpacked(status, source, &unpackersource.Result{State: unpackersource.StateUnpacked, ResolvedSource: incoming}, "https://example.com/bundles/test.tgz")
+		Expect(status.SourceHash).NotTo(BeEmpty())
+		Expect(status.ResolvedSource).To(Equal(incoming))
+		Expect(meta.FindStatusCondition(status.Conditions, rukpakv1alpha2.TypeUpgradePending)).To(BeNil())
+	})
+
+	It("does not flap the resolved source during routine reconciles", func() {
+		bd := &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 2},
+			Status: rukpakv1alpha2.BundleDeploymentStatus{
+				ObservedGeneration: 2,
+				ResolvedSource:     resolved,
+				ContentURL:         "https://example.com/bundles/test.tgz",
+			},
+		}
+		unpacker := &rukpaktesting.Unpacker{}
+		c := &controller{finalizers: crfinalizer.NewFinalizers(), unpacker: unpacker}
+
+		for _, result := range []*unpackersource.Result{
+			{State: unpackersource.StatePending},
+			{State: unpackersource.StateUnpacking},
+			nil,
+		} {
+			if result == nil {
+				unpacker.SetError(bd.Name, errors.New("registry unavailable"))
+			} else {
+				unpacker.SetResult(bd.Name, result)
+			}
+			_, _ = c.reconcile(context.Background(), bd)
+			Expect(bd.Status.ResolvedSource).To(Equal(resolved))
+			Expect(bd.Status.ContentURL).To(Equal("https://example.com/bundles/test.tgz"))
+		}
+
+		bd.Generation = 3
+		unpacker.SetResult(bd.Name, &unpackersource.Result{State: unpackersource.StateUnpacking})
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bd.Status.ResolvedSource).To(BeNil())
+		Expect(bd.Status.ContentURL).To(BeEmpty())
+	})
+
+	It("leaves the resolved source untouched when only the config changed", func() {
+		source := rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"}}
+		hash, err := sourceHash(source)
+		Expect(err).NotTo(HaveOccurred())
+		bd := &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 3},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{
+				Source: source,
+				Config: runtime.RawExtension{Raw: []byte(`{"values":{"replicas":2}}`)},
+			},
+			Status: rukpakv1alpha2.BundleDeploymentStatus{
+				ObservedGeneration: 2,
+				ResolvedSource:     resolved,
+				SourceHash:         hash,
+				ContentURL:         "https://example.com/bundles/test.tgz",
+			},
+		}
+		unpacker := &rukpaktesting.Unpacker{}
+		unpacker.SetResult(bd.Name, &unpackersource.Result{State: unpackersource.StateUnpacking})
+		c := &controller{finalizers: crfinalizer.NewFinalizers(), unpacker: unpacker}
+
+		_, err = c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bd.Status.ResolvedSource).To(Equal(resolved))
+		Expect(bd.Status.SourceHash).To(Equal(hash))
+		Expect(bd.Status.ContentURL).To(Equal("https://example.com/bundles/test.tgz"))
+		Expect(meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeUpgradePending)).To(BeNil())
+
+		// A change of the source is still detected in later reconciles.
+		bd.Generation = 4
+		bd.Spec.Source.Image.Ref = "quay.io/example/bundle:v2"
+		_, err = c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bd.Status.ResolvedSource).To(BeNil())
+		Expect(bd.Status.SourceHash).To(BeEmpty())
+	})
+
+	It("writes conditions with the generation they were observed for", func() {
+		bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 4}}
+		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded, ObservedGeneration: 3})
+		unpacker := &rukpaktesting.Unpacker{}
+		unpacker.SetResult(bd.Name, &unpackersource.Result{State: unpackersource.StateUnpacking})
+		c := &controller{finalizers: crfinalizer.NewFinalizers(), unpacker: unpacker}
+
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeUnpacked).ObservedGeneration).To(Equal(int64(4)))
+		Expect(bd.CurrentCondition(rukpakv1alpha2.TypeUnpacked)).NotTo(BeNil())
+		Expect(bd.IsConditionStale(rukpakv1alpha2.TypeUnpacked)).To(BeFalse())
+		Expect(bd.CurrentCondition(rukpakv1alpha2.TypeInstalled)).To(BeNil())
+		Expect(bd.IsConditionStale(rukpakv1alpha2.TypeInstalled)).To(BeTrue())
+		Expect(bd.IsConditionStale(rukpakv1alpha2.TypeHealthy)).To(BeFalse())
+	})
+
+	It("refuses content that differs from the content of a restored snapshot", func() {
+		bd := &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{snapshot.DigestAnnotationKey: "sha256:snapshot"},
+			},
+		}
+		unpacker := &rukpaktesting.Unpacker{}
+		unpacker.SetResult(bd.Name, &unpackersource.Result{
+			State:          unpackersource.StateUnpacked,
+			ResolvedSource: &rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, BundleDigest: "sha256:other"},
+		})
+		c := &controller{finalizers: crfinalizer.NewFinalizers(), unpacker: unpacker}
+
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).To(HaveOccurred())
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeUnpacked)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonUnpackFailed))
+		Expect(cond.Message).To(ContainSubstring(`unpacked bundle digest "sha256:other" does not match the digest "sha256:snapshot" of the restored snapshot`))
+	})
+})
+
+var _ = Describe("requirements", func() {
+	var (
+		c    *controller
+		bd   *rukpakv1alpha2.BundleDeployment
+		chrt *chart.Chart
+	)
+
+	BeforeEach(func() {
+		chrt = &chart.Chart{}
+		unpacker := &rukpaktesting.Unpacker{}
+		unpacker.SetResult("test", &unpackersource.Result{State: unpackersource.StateUnpacked, Bundle: fstest.MapFS{}})
+		c = &controller{
+			finalizers: crfinalizer.NewFinalizers(),
+			unpacker:   unpacker,
+			storage:    &rukpaktesting.Storage{},
+			handler: handler.HandlerFunc(func(context.Context, fs.FS, *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+				return chrt, nil, nil
+			}),
+			cluster: &fakeCluster{
+				apis:      map[schema.GroupVersionKind]bool{{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}: true},
+				version:   "1.28.0",
+				openShift: "4.15.3",
+			},
+		}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	})
+
+	It("keeps the install pending while requirements are not met", func() {
+		bd.Spec.Config.Raw = []byte(`{"requiredAPIs":[{"group":"route.openshift.io","version":"v1","kind":"Route"}],"requiredCapabilities":{"minKubernetesVersion":"1.29"}}`)
+		res, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(requirementsRecheckInterval))
+
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeInstalled)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonRequirementsNotMet))
+		Expect(cond.Message).To(ContainSubstring("kind Route of API route.openshift.io/v1 is not served; Kubernetes version 1.28.0 is older than 1.29"))
+	})
+
+	It("does not report requirements that are met", func() {
+		bd.Spec.Config.Raw = []byte(`{"values":"a: b","requiredAPIs":[{"group":"monitoring.coreos.com","version":"v1","kind":"ServiceMonitor"}],"requiredCapabilities":{"minKubernetesVersion":"1.27"}}`)
+		Expect(c.unmetRequirements(context.Background(), bd, chrt)).To(BeEmpty())
+	})
+
+	It("keeps the install pending while requirements of the bundle are not met", func() {
+		requirements.AnnotateChart(chrt, requirements.Capabilities{MinKubernetesVersion: "1.25.0", MaxOpenShiftVersion: "4.14"})
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeInstalled)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonRequirementsNotMet))
+		Expect(cond.Message).To(HaveSuffix(": OpenShift version 4.15.3 is newer than 4.14"))
+	})
+
+	It("does not detect capabilities without requirements", func() {
+		c.cluster = nil
+		bd.Spec.Config.Raw = []byte(`{"values":"a: b"}`)
+		Expect(c.unmetRequirements(context.Background(), bd, chrt)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("chart cache", func() {
+	var (
+		c        *controller
+		bd       *rukpakv1alpha2.BundleDeployment
+		unpacker *rukpaktesting.Unpacker
+		handled  int
+	)
+
+	unpacked := func(digest string) *unpackersource.Result {
+		return &unpackersource.Result{
+			State:          unpackersource.StateUnpacked,
+			Bundle:         fstest.MapFS{},
+			ResolvedSource: &rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, BundleDigest: digest},
+		}
+	}
+
+	BeforeEach(func() {
+		handled = 0
+		unpacker = &rukpaktesting.Unpacker{}
+		unpacker.SetResult("test", unpacked("sha256:1"))
+		c = &controller{
+			finalizers: crfinalizer.NewFinalizers(),
+			unpacker:   unpacker,
+			storage:    &rukpaktesting.Storage{},
+			handler: handler.HandlerFunc(func(context.Context, fs.FS, *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+				handled++
+				return &chart.Chart{}, nil, nil
+			}),
+			cluster: &fakeCluster{version: "1.28.0"},
+		}
+		WithChartCacheSize(2)(c)
+		// Unmet requirements end the reconcile right after the bundle is
+		// converted.
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		bd.Spec.Config.Raw = []byte(`{"requiredCapabilities":{"minKubernetesVersion":"1.29"}}`)
+	})
+
+	reconcile := func() {
+		res, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(requirementsRecheckInterval))
+	}
+
+	It("converts unchanged bundles once", func() {
+		reconcile()
+		reconcile()
+		Expect(handled).To(Equal(1))
+	})
+
+	It("converts bundles again when their content changed", func() {
+		reconcile()
+		unpacker.SetResult("test", unpacked("sha256:2"))
+		reconcile()
+		Expect(handled).To(Equal(2))
+	})
+
+	It("converts bundles again when the spec changed", func() {
+		reconcile()
+		bd.Spec.InstallNamespace = "other"
+		reconcile()
+		Expect(handled).To(Equal(2))
+	})
+
+	It("converts bundles on every reconcile when disabled", func() {
+		WithChartCacheSize(0)(c)
+		reconcile()
+		reconcile()
+		Expect(handled).To(Equal(2))
+	})
+})
+
+var _ = Describe("drift events", func() {
+	var (
+		c        *controller
+		acg      *rukpaktesting.ActionClientGetter
+		recorder *record.FakeRecorder
+		obj      *unstructured.Unstructured
+	)
+
+	managedFields := func(manager string, seconds int64) []metav1.ManagedFieldsEntry {
+		t := metav1.Unix(seconds, 0)
+		return []metav1.ManagedFieldsEntry{
+			{Manager: "core", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: time.Unix(1, 0)}},
+			{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &t},
+		}
+	}
+
+	BeforeEach(func() {
+		bd := &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{ProvisionerClassName: "test-provisioner", InstallNamespace: "test-ns"},
+		}
+		scheme := runtime.NewScheme()
+		Expect(rukpakv1alpha2.AddToScheme(scheme)).To(Succeed())
+		acg = &rukpaktesting.ActionClientGetter{}
+		recorder = record.NewFakeRecorder(10)
+		c = &controller{
+			cl:            fake.NewClientBuilder().WithScheme(scheme).WithObjects(bd).Build(),
+			acg:           acg,
+			provisionerID: "test-provisioner",
+			recorder:      recorder,
+			fieldManager:  "core",
+		}
+
+		chrt := &chart.Chart{
+			Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test", Version: "1.0.0"},
+			Templates: []*chart.File{{Name: "templates/config.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")}},
+		}
+		_, err := acg.ActionClient("test").Install("test", "test-ns", chrt, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj = &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace("test-ns")
+		obj.SetName("config")
+		obj.SetLabels(map[string]string{util.CoreOwnerKindKey: rukpakv1alpha2.BundleDeploymentKind, util.CoreOwnerNameKey: "test"})
+		obj.SetManagedFields(managedFields("core", 1))
+	})
+
+	It("records modifications by other managers", func() {
+		modified := obj.DeepCopy()
+		modified.SetManagedFields(managedFields("kubectl-edit", 2))
+		c.recordModified(context.Background(), obj, modified)
+		Expect(recorder.Events).To(Receive(Equal("Warning DependentObjectModified ConfigMap test-ns/config was modified by kubectl-edit")))
+	})
+
+	It("ignores modifications by the provisioner", func() {
+		modified := obj.DeepCopy()
+		modified.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "core", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: time.Unix(2, 0)}}})
+		c.recordModified(context.Background(), obj, modified)
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("ignores status updates", func() {
+		modified := obj.DeepCopy()
+		fields := managedFields("kube-controller-manager", 2)
+		fields[1].Subresource = "status"
+		modified.SetManagedFields(fields)
+		c.recordModified(context.Background(), obj, modified)
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("ignores objects of BundleDeployments of other provisioners", func() {
+		c.provisionerID = "other-provisioner"
+		modified := obj.DeepCopy()
+		modified.SetManagedFields(managedFields("kubectl-edit", 2))
+		c.recordModified(context.Background(), obj, modified)
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("records deletions of objects of the release", func() {
+		c.recordDeleted(context.Background(), obj)
+		Expect(recorder.Events).To(Receive(Equal("Warning DependentObjectDeleted ConfigMap test-ns/config was deleted")))
+	})
+
+	It("ignores deletions of objects that are not part of the release anymore", func() {
+		obj.SetName("removed")
+		c.recordDeleted(context.Background(), obj)
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("orphan pruning", func() {
+	var (
+		bd           *rukpakv1alpha2.BundleDeployment
+		cl           *fakeActionClient
+		targetClient client.Client
+		rel          *release.Release
+	)
+
+	object := func(kind, name string, annotations map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace("test-ns")
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{util.CoreOwnerKindKey: rukpakv1alpha2.BundleDeploymentKind, util.CoreOwnerNameKey: "test"})
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	releaseAnnotations := func(extra ...string) map[string]string {
+		annotations := map[string]string{"meta.helm.sh/release-name": "test", "meta.helm.sh/release-namespace": "test-ns"}
+		for i := 0; i+1 < len(extra); i += 2 {
+			annotations[extra[i]] = extra[i+1]
+		}
+		return annotations
+	}
+	manifest := func(kinds ...string) string {
+		docs := make([]string, 0, len(kinds))
+		for _, kind := range kinds {
+			docs = append(docs, fmt.Sprintf("apiVersion: v1\nkind: %s\nmetadata:\n  name: %s\n  namespace: test-ns\n", kind, strings.ToLower(kind)))
+		}
+		return strings.Join(docs, "---\n")
+	}
+
+	BeforeEach(func() {
+		bd = &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "test-ns"},
+		}
+		// Revision 2 failed part way, so its upgrade left the Service of
+		// revision 1 behind.
+		cl = &fakeActionClient{releases: map[int]*release.Release{
+			1: {Name: "test", Version: 1, Manifest: manifest("ConfigMap", "Service")},
+			2: {Name: "test", Version: 2, Manifest: manifest("ConfigMap")},
+		}}
+		rel = &release.Release{Name: "test", Version: 3, Manifest: manifest("ConfigMap")}
+		targetClient = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+			object("ConfigMap", "configmap", releaseAnnotations()),
+			object("Service", "service", releaseAnnotations()),
+			object("Service", "kept", releaseAnnotations(rukpakv1alpha2.ResourcePolicyAnnotation, rukpakv1alpha2.ResourcePolicyKeep)),
+			object("Service", "unmanaged", nil),
+		).Build()
+	})
+
+	exists := func(kind, name string) bool {
+		obj := object(kind, name, nil)
+		err := targetClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		return err == nil
+	}
+
+	It("prunes objects of kinds that earlier revisions had", func() {
+		pruned, err := pruneOrphans(context.Background(), cl, targetClient, bd, rel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pruned).To(Equal([]rukpakv1alpha2.ObjectReference{{APIVersion: "v1", Kind: "Service", Namespace: "test-ns", Name: "service"}}))
+		Expect(exists("Service", "service")).To(BeFalse())
+	})
+
+	It("leaves kept objects, objects that are not part of the release and objects of current kinds", func() {
+		_, err := pruneOrphans(context.Background(), cl, targetClient, bd, rel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists("Service", "kept")).To(BeTrue())
+		Expect(exists("Service", "unmanaged")).To(BeTrue())
+		Expect(exists("ConfigMap", "configmap")).To(BeTrue())
+	})
+
+	It("stops at revisions that are not stored anymore", func() {
+		delete(cl.releases, 2)
+		pruned, err := pruneOrphans(context.Background(), cl, targetClient, bd, rel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pruned).To(BeEmpty())
+		Expect(exists("Service", "service")).To(BeTrue())
+	})
+
+	It("skips kinds that the cluster does not serve anymore", func() {
+		cl.releases[1].Manifest = manifest("ConfigMap") + "---\napiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget\n"
+		pruned, err := pruneOrphans(context.Background(), cl, targetClient, bd, rel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pruned).To(BeEmpty())
+	})
+
+	It("records pruned objects", func() {
+		recorder := record.NewFakeRecorder(10)
+		c := &controller{recorder: recorder}
+		c.recordPruned(bd, []rukpakv1alpha2.ObjectReference{{APIVersion: "v1", Kind: "Service", Namespace: "test-ns", Name: "service"}})
+		Expect(bd.Status.PrunedObjects).To(HaveLen(1))
+		Expect(recorder.Events).To(Receive(Equal("Normal OrphanedObjectsPruned pruned objects of kinds that were removed from the bundle: Service test-ns/service")))
+
+		c.recordPruned(bd, nil)
+		Expect(bd.Status.PrunedObjects).To(BeEmpty())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("failed condition correlation", func() {
+	var (
+		c        *controller
+		recorder *record.FakeRecorder
+		bd       *rukpakv1alpha2.BundleDeployment
+	)
+
+	failed := func(message string) metav1.Condition {
+		return metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionFalse, Reason: rukpakv1alpha2.ReasonInstallFailed, Message: message}
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		c = &controller{recorder: recorder}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		bd.Status.Conditions = []metav1.Condition{
+			{Type: rukpakv1alpha2.TypeUnpacked, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonUnpackSuccessful},
+			failed("install failed"),
+		}
+	})
+
+	It("tags new failures with the reconcile ID and records an event", func() {
+		c.tagFailedConditions(bd, nil, "abc")
+		Expect(bd.Status.Conditions[0].Message).To(BeEmpty())
+		Expect(bd.Status.Conditions[1].Message).To(Equal("install failed (reconcileID: abc)"))
+		Expect(recorder.Events).To(Receive(And(
+			HavePrefix("Warning InstallFailed install failed"),
+			ContainSubstring(ReconcileIDAnnotation+":abc"),
+		)))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("keeps the reconcile ID of repeated failures", func() {
+		existing := []metav1.Condition{failed("install failed (reconcileID: abc)")}
+		c.tagFailedConditions(bd, existing, "def")
+		Expect(bd.Status.Conditions[1].Message).To(Equal("install failed (reconcileID: abc)"))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("replaces the reconcile ID of changed failures", func() {
+		bd.Status.Conditions[1] = failed("install failed again (reconcileID: abc)")
+		existing := []metav1.Condition{failed("install failed (reconcileID: abc)")}
+		c.tagFailedConditions(bd, existing, "def")
+		Expect(bd.Status.Conditions[1].Message).To(Equal("install failed again (reconcileID: def)"))
+		Expect(recorder.Events).To(Receive())
+	})
+})
+
+var _ = Describe("last failure", func() {
+	var bd *rukpakv1alpha2.BundleDeployment
+
+	BeforeEach(func() {
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		bd.Status.Conditions = []metav1.Condition{
+			{Type: rukpakv1alpha2.TypeUnpacked, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonUnpackSuccessful},
+			{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionFalse, Reason: rukpakv1alpha2.ReasonUpgradeFailed},
+		}
+		bd.Status.ObjectApplyResults = []rukpakv1alpha2.ObjectApplyResult{
+			{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "applied", Result: rukpakv1alpha2.ObjectApplyResultUpdated},
+			{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "failed", Result: rukpakv1alpha2.ObjectApplyResultFailed},
+		}
+	})
+
+	It("records the reason, phase and object of the failure", func() {
+		now := metav1.Now()
+		setLastFailure(bd, now)
+		Expect(bd.Status.LastFailure).To(Equal(&rukpakv1alpha2.Failure{
+			Reason:        rukpakv1alpha2.ReasonUpgradeFailed,
+			Phase:         rukpakv1alpha2.FailurePhaseInstall,
+			ConditionType: rukpakv1alpha2.TypeInstalled,
+			Object:        &rukpakv1alpha2.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "failed"},
+			Time:          now,
+		}))
+	})
+
+	It("counts retries of the same failure", func() {
+		setLastFailure(bd, metav1.Now())
+		setLastFailure(bd, metav1.Now())
+		Expect(bd.Status.LastFailure.RetryCount).To(Equal(int32(1)))
+
+		bd.Status.Conditions[0] = metav1.Condition{Type: rukpakv1alpha2.TypeUnpacked, Status: metav1.ConditionFalse, Reason: rukpakv1alpha2.ReasonUnpackFailed}
+		setLastFailure(bd, metav1.Now())
+		Expect(bd.Status.LastFailure.Phase).To(Equal(rukpakv1alpha2.FailurePhaseUnpack))
+		Expect(bd.Status.LastFailure.Reason).To(Equal(rukpakv1alpha2.ReasonUnpackFailed))
+		Expect(bd.Status.LastFailure.RetryCount).To(BeZero())
+	})
+})
+
+var _ = Describe("backoff", func() {
+	It("retries after the delay that was published", func() {
+		b := newBackoff()
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}
+		first := b.next(req)
+		Expect(b.When(req)).To(Equal(first))
+
+		second := b.next(req)
+		Expect(second).To(BeNumerically(">", first))
+		Expect(b.When(req)).To(Equal(second))
+
+		b.Forget(req)
+		Expect(b.next(req)).To(Equal(first))
+	})
+
+	It("ignores updates of the failure status", func() {
+		oldBD := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test", ResourceVersion: "1"}}
+		newBD := oldBD.DeepCopy()
+		newBD.ResourceVersion = "2"
+		next := metav1.Now()
+		newBD.Status.NextReconcileTime = &next
+		newBD.Status.LastFailure = &rukpakv1alpha2.Failure{Reason: rukpakv1alpha2.ReasonUpgradeFailed}
+
+		p := ignoreFailureStatusUpdates()
+		Expect(p.Update(event.UpdateEvent{ObjectOld: oldBD, ObjectNew: newBD})).To(BeFalse())
+
+		newBD.Generation = 2
+		Expect(p.Update(event.UpdateEvent{ObjectOld: oldBD, ObjectNew: newBD})).To(BeTrue())
+	})
+})
+
+var _ = Describe("bundle content retention", func() {
+	var (
+		ctx    context.Context
+		c      *controller
+		store  *storage.LocalDirectory
+		bd     *rukpakv1alpha2.BundleDeployment
+		v1, v2 rukpakv1alpha2.BundleSource
+	)
+
+	retain := func(source rukpakv1alpha2.BundleSource, content string) {
+		bd.Spec.Source = source
+		Expect(store.Store(ctx, bd, fstest.MapFS{"content": &fstest.MapFile{Data: []byte(content)}})).To(Succeed())
+		c.retain(ctx, bd, &unpackersource.Result{ResolvedSource: &source})
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		store = &storage.LocalDirectory{RootDirectory: GinkgoT().TempDir(), RetentionLimit: 2}
+		c = &controller{storage: store, retainer: store}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		v1 = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "example.com/bundle:v1"}}
+		v2 = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "example.com/bundle:v2"}}
+		retain(v1, "v1")
+		retain(v2, "v2")
+	})
+
+	It("restores the content of a previous source", func() {
+		bd.Spec.Source = v1
+		result := c.restoreRetained(ctx, bd)
+		Expect(result).NotTo(BeNil())
+		Expect(result.State).To(Equal(unpackersource.StateUnpacked))
+		Expect(result.ResolvedSource).To(Equal(&v1))
+		Expect(fs.ReadFile(result.Bundle, "content")).To(Equal([]byte("v1")))
+		Expect(result.Close()).To(Succeed())
+	})
+
+	It("unpacks the current source", func() {
+		bd.Spec.Source = v2
+		Expect(c.restoreRetained(ctx, bd)).To(BeNil())
+	})
+
+	It("unpacks sources that are not retained", func() {
+		bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: "example.com/bundle:v3"}}
+		Expect(c.restoreRetained(ctx, bd)).To(BeNil())
+	})
+
+	It("keeps restoring the content of a previous source on later reconciles", func() {
+		unpacker := &rukpaktesting.Unpacker{}
+		c.finalizers = crfinalizer.NewFinalizers()
+		c.unpacker = unpacker
+		// The handler stops the reconcile once the content is stored.
+		c.handler = handler.HandlerFunc(func(context.Context, fs.FS, *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+			return nil, nil, errors.New("not installed")
+		})
+
+		bd.Spec.Source = v1
+		for i := 0; i < 2; i++ {
+			_, err := c.reconcile(ctx, bd)
+			Expect(err).To(MatchError("not installed"))
+			Expect(unpacker.Unpacked()).To(BeEmpty())
+			Expect(bd.Status.ResolvedSource).To(Equal(&v1))
+			Expect(meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeUnpacked).Message).To(ContainSubstring("Restored"))
+			loaded, err := store.Load(ctx, bd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.ReadFile(loaded, "content")).To(Equal([]byte("v1")))
+			Expect(loaded.Close()).To(Succeed())
+		}
+	})
+})
+
+var _ = Describe("startup prioritization", func() {
+	It("ranks BundleDeployments by the outcome of their previous reconcile", func() {
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		Expect(startupPriorityOf(bd)).To(Equal(startupPriorityPending))
+
+		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue})
+		Expect(startupPriorityOf(bd)).To(Equal(startupPrioritySteady))
+
+		bd.Status.LastFailure = &rukpakv1alpha2.Failure{Reason: rukpakv1alpha2.ReasonReconcileFailed}
+		Expect(startupPriorityOf(bd)).To(Equal(startupPriorityFailing))
+	})
+
+	It("queues failing BundleDeployments behind the others during the startup window", func() {
+		priorities := map[string]startupPriority{
+			"failing": startupPriorityFailing,
+			"pending": startupPriorityPending,
+			"steady":  startupPrioritySteady,
+		}
+		q := newStartupQueue(workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), func(item interface{}) startupPriority {
+			return priorities[item.(string)]
+		})
+		q.step = 10 * time.Millisecond
+		defer q.ShutDown()
+
+		for _, item := range []string{"failing", "pending", "steady"} {
+			q.Add(item)
+		}
+		for _, expected := range []string{"steady", "pending", "failing"} {
+			item, _ := q.Get()
+			Expect(item).To(Equal(expected))
+			q.Done(item)
+		}
+	})
+
+	It("does not hold back requests after the startup window", func() {
+		q := newStartupQueue(workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), func(interface{}) startupPriority {
+			return startupPriorityFailing
+		})
+		q.step = time.Hour
+		q.until = time.Now()
+		defer q.ShutDown()
+
+		q.Add("failing")
+		Expect(q.Len()).To(Equal(1))
+	})
+})
+
+var _ = Describe("shutdown grace period", func() {
+	It("keeps the reconcile running for the grace period after the controller stops", func() {
+		parent, stop := context.WithCancel(context.Background())
+		ctx, cancel := withGracePeriod(parent, 50*time.Millisecond)
+		defer cancel()
+
+		stop()
+		Consistently(ctx.Done(), 20*time.Millisecond).ShouldNot(BeClosed())
+		Eventually(ctx.Done()).Should(BeClosed())
+		Expect(context.Cause(ctx)).To(MatchError(errShutdownGracePeriodExpired))
+	})
+
+	It("is canceled when the reconcile ends", func() {
+		ctx, cancel := withGracePeriod(context.Background(), time.Hour)
+		cancel()
+		Expect(ctx.Done()).To(BeClosed())
+	})
+
+	It("is canceled with the controller without a grace period", func() {
+		parent, stop := context.WithCancel(context.Background())
+		ctx, cancel := withGracePeriod(parent, 0)
+		defer cancel()
+
+		stop()
+		Expect(ctx.Done()).To(BeClosed())
+	})
+})
+
+var _ = Describe("field pruning", func() {
+	It("removes server-populated and pruned fields", func() {
+		obj := &unstructured.Unstructured{}
+		Expect(obj.UnmarshalJSON([]byte(`{
+			"apiVersion": "apps/v1",
+			"kind": "Deployment",
+			"metadata": {
+				"name": "test",
+				"uid": "1234",
+				"resourceVersion": "5",
+				"creationTimestamp": "2024-01-01T00:00:00Z",
+				"managedFields": [{"manager": "kubectl"}],
+				"annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{}", "example.com/team": "a"}
+			},
+			"spec": {"replicas": 3, "paused": false},
+			"status": {"replicas": 3}
+		}`))).To(Succeed())
+
+		pruneFields(obj, map[schema.GroupKind][][]string{
+			{Group: "apps", Kind: "Deployment"}: {{"spec", "replicas"}},
+			{Kind: "Service"}:                   {{"spec", "paused"}},
+			AllKinds:                            {{"metadata", "annotations", "example.com/team"}},
+		})
+		Expect(obj.Object).To(Equal(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"spec":       map[string]interface{}{"paused": false},
+		}))
+	})
+})
+
+var _ = Describe("install namespace", func() {
+	var (
+		c  *controller
+		bd *rukpakv1alpha2.BundleDeployment
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		c = &controller{cl: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}).Build()}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "missing"}}
+	})
+
+	It("accepts existing namespaces", func() {
+		bd.Spec.InstallNamespace = "existing"
+		Expect(c.ensureInstallNamespace(context.Background(), bd)).To(Succeed())
+	})
+
+	It("reports missing namespaces by default", func() {
+		err := c.ensureInstallNamespace(context.Background(), bd)
+		Expect(err).To(MatchError(errInstallNamespaceNotFound{"missing"}))
+		Expect(c.cl.Get(context.Background(), client.ObjectKey{Name: "missing"}, &corev1.Namespace{})).NotTo(Succeed())
+	})
+
+	It("creates missing namespaces with the CreateIfMissing policy", func() {
+		bd.Spec.InstallNamespacePolicy = rukpakv1alpha2.InstallNamespacePolicyCreateIfMissing
+		Expect(c.ensureInstallNamespace(context.Background(), bd)).To(Succeed())
+		Expect(c.cl.Get(context.Background(), client.ObjectKey{Name: "missing"}, &corev1.Namespace{})).To(Succeed())
+	})
+})
+
+var _ = Describe("deletion", func() {
+	var (
+		c          *controller
+		bd         *rukpakv1alpha2.BundleDeployment
+		storageErr error
+		unpacker   *rukpaktesting.Unpacker
+	)
+
+	BeforeEach(func() {
+		storageErr = nil
+		finalizers := crfinalizer.NewFinalizers()
+		Expect(finalizers.Register(finalizer.DeleteCachedBundleKey, finalizerFunc(func() error { return storageErr }))).To(Succeed())
+		Expect(finalizers.Register(finalizer.CleanupUnpackCacheKey, finalizerFunc(func() error { return nil }))).To(Succeed())
+		unpacker = &rukpaktesting.Unpacker{}
+		unpacker.SetError("test", errors.New("must not unpack"))
+		c = &controller{finalizers: finalizers, unpacker: unpacker}
+
+		now := metav1.Now()
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{
+			Name:              "test",
+			DeletionTimestamp: &now,
+			Finalizers:        []string{finalizer.DeleteCachedBundleKey, finalizer.CleanupUnpackCacheKey, metav1.FinalizerDeleteDependents},
+		}}
+	})
+
+	It("reports the step that failed", func() {
+		storageErr = errors.New("permission denied")
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).To(HaveOccurred())
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeDeleting)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonStoragePurging))
+		Expect(cond.Message).To(Equal(`purging the stored bundle content: finalizer "core.rukpak.io/delete-cached-bundle" failed: permission denied`))
+		Expect(bd.Finalizers).To(ConsistOf(finalizer.DeleteCachedBundleKey, metav1.FinalizerDeleteDependents))
+	})
+
+	It("waits for the garbage collector once the finalizers are done, without unpacking again", func() {
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeDeleting)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonReleaseUninstalling))
+		Expect(bd.Finalizers).To(ConsistOf(metav1.FinalizerDeleteDependents))
+	})
+
+	It("skips the storage cleanup when requested by the annotation", func() {
+		recorder := record.NewFakeRecorder(10)
+		c.recorder = recorder
+		storageErr = errors.New("storage backend is gone")
+		bd.Annotations = map[string]string{rukpakv1alpha2.SkipFinalizerCleanupAnnotation: "true"}
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bd.Finalizers).To(ConsistOf(metav1.FinalizerDeleteDependents))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning FinalizerCleanupSkipped skipped finalizers core.rukpak.io/delete-cached-bundle, core.rukpak.io/cleanup-unpack-cache")))
+	})
+
+	It("is not set on BundleDeployments that are not being deleted", func() {
+		bd.DeletionTimestamp = nil
+		_, _ = c.reconcile(context.Background(), bd)
+		Expect(meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeDeleting)).To(BeNil())
+	})
+})
+
+var _ = Describe("uninstall policy", func() {
+	var (
+		c  *controller
+		cl client.Client
+		bd *rukpakv1alpha2.BundleDeployment
+	)
+
+	configMap := func(name string, finalizers ...string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: name, Finalizers: finalizers}}
+	}
+
+	install := func(templates ...string) {
+		chrt := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test", Version: "1.0.0"}}
+		for i, t := range templates {
+			chrt.Templates = append(chrt.Templates, &chart.File{Name: fmt.Sprintf("templates/%d.yaml", i), Data: []byte(t)})
+		}
+		cl, err := c.acg.ActionClientFor(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cl.Install(bd.Name, bd.Spec.InstallNamespace, chrt, nil)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	const (
+		configTemplate     = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"
+		keptTemplate       = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: kept\n  annotations:\n    helm.sh/resource-policy: keep\n"
+		rukpakKeptTemplate = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: rukpak-kept\n  annotations:\n    core.rukpak.io/resource-policy: keep\n"
+		widgetTemplate     = "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget\n"
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(rukpakv1alpha2.AddToScheme(scheme)).To(Succeed())
+		mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion})
+		mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+		cl = fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(configMap("config", "test/hold"), configMap("kept"), configMap("rukpak-kept")).Build()
+		c = &controller{cl: cl, acg: &rukpaktesting.ActionClientGetter{}, finalizers: crfinalizer.NewFinalizers()}
+
+		now := metav1.Now()
+		bd = &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", DeletionTimestamp: &now, Finalizers: []string{uninstallReleaseFinalizer}},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "test-ns", UninstallPolicy: rukpakv1alpha2.UninstallPolicyForeground},
+		}
+	})
+
+	It("adds the uninstall finalizer only for the Foreground and Force policies", func() {
+		bd.DeletionTimestamp = nil
+		bd.Finalizers = nil
+		for _, policy := range []rukpakv1alpha2.UninstallPolicy{rukpakv1alpha2.UninstallPolicyForeground, rukpakv1alpha2.UninstallPolicyForce} {
+			bd.Spec.UninstallPolicy = policy
+			syncUninstallFinalizer(bd)
+			Expect(bd.Finalizers).To(ConsistOf(uninstallReleaseFinalizer))
+		}
+		bd.Spec.UninstallPolicy = ""
+		syncUninstallFinalizer(bd)
+		Expect(bd.Finalizers).To(BeEmpty())
+	})
+
+	It("adds the uninstall finalizer for every policy when the objects are applied to a remote cluster", func() {
+		bd.DeletionTimestamp = nil
+		bd.Finalizers = nil
+		bd.Spec.UninstallPolicy = ""
+		bd.Spec.Target = &rukpakv1alpha2.Target{KubeconfigSecretRef: rukpakv1alpha2.KubeconfigSecretReference{Name: "workload-kubeconfig"}}
+		syncUninstallFinalizer(bd)
+		Expect(bd.Finalizers).To(ConsistOf(uninstallReleaseFinalizer))
+	})
+
+	It("waits for the objects of the release to be gone with the Foreground policy", func() {
+		install(configTemplate, keptTemplate, rukpakKeptTemplate)
+
+		res, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(Equal(uninstallRecheckInterval))
+		Expect(bd.Finalizers).To(ConsistOf(uninstallReleaseFinalizer))
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeDeleting)
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonReleaseUninstalling))
+		Expect(cond.Message).To(Equal("waiting for 1 objects of the release to be deleted"))
+
+		held := configMap("config")
+		Expect(cl.Get(context.Background(), client.ObjectKeyFromObject(held), held)).To(Succeed())
+		held.Finalizers = nil
+		Expect(cl.Update(context.Background(), held)).To(Succeed())
+
+		res, err = c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+		Expect(bd.Finalizers).To(BeEmpty())
+		Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: "test-ns", Name: "kept"}, &corev1.ConfigMap{})).To(Succeed())
+		Expect(cl.Get(context.Background(), client.ObjectKey{Namespace: "test-ns", Name: "rukpak-kept"}, &corev1.ConfigMap{})).To(Succeed())
+	})
+
+	It("fails on objects whose API is not served with the Foreground policy", func() {
+		install(widgetTemplate)
+
+		_, err := c.reconcile(context.Background(), bd)
+		Expect(err).To(MatchError(ContainSubstring("set spec.uninstallPolicy to Force")))
+		Expect(bd.Finalizers).To(ConsistOf(uninstallReleaseFinalizer))
+	})
+
+	It("neither waits for objects nor fails on objects whose API is not served with the Force policy", func() {
+		bd.Spec.UninstallPolicy = rukpakv1alpha2.UninstallPolicyForce
+		install(configTemplate, widgetTemplate)
+
+		res, err := c.reconcile(context.Background(), bd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.IsZero()).To(BeTrue())
+		Expect(bd.Finalizers).To(BeEmpty())
+		Expect(meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeDeleting).Message).To(Equal("the objects of the release were deleted"))
+	})
+})
+
+type finalizerFunc func() error
+
+func (f finalizerFunc) Finalize(context.Context, client.Object) (crfinalizer.Result, error) {
+	return crfinalizer.Result{}, f()
+}
+
+var _ = Describe("content served", func() {
+	const contentURL = "https://rukpak.example.com/bundles/test.tgz"
+	var (
+		c       *controller
+		bd      *rukpakv1alpha2.BundleDeployment
+		checker *fakeChecker
+	)
+
+	BeforeEach(func() {
+		checker = &fakeChecker{}
+		c = &controller{contentChecker: checker}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	})
+
+	It("publishes the content URL once the content was retrieved", func() {
+		Expect(c.publishContentURL(context.Background(), bd, contentURL)).To(Equal(contentURL))
+		Expect(checker.checked).To(Equal([]string{contentURL}))
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeContentServed)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonContentRetrievable))
+	})
+
+	It("does not check a content URL that was verified already", func() {
+		bd.Status.ContentURL = c.publishContentURL(context.Background(), bd, contentURL)
+		Expect(c.publishContentURL(context.Background(), bd, contentURL)).To(Equal(contentURL))
+		Expect(checker.checked).To(HaveLen(1))
+	})
+
+	It("withholds the content URL when the content cannot be retrieved", func() {
+		checker.err = errors.New(`unexpected response status "404 Not Found"`)
+		Expect(c.publishContentURL(context.Background(), bd, contentURL)).To(BeEmpty())
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeContentServed)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonContentNotRetrievable))
+		Expect(cond.Message).To(ContainSubstring(contentURL))
+		Expect(cond.Message).To(ContainSubstring("404 Not Found"))
+	})
+
+	It("publishes the content URL unchecked without a checker", func() {
+		c.contentChecker = nil
+		Expect(c.publishContentURL(context.Background(), bd, contentURL)).To(Equal(contentURL))
+		Expect(bd.Status.Conditions).To(BeEmpty())
+	})
+})
+
+type fakeChecker struct {
+	checked []string
+	err     error
+}
+
+func (f *fakeChecker) Check(_ context.Context, url string) error {
+	f.checked = append(f.checked, url)
+	return f.err
+}
+
+var _ = Describe("test hooks", func() {
+	var (
+		c      *controller
+		bd     *rukpakv1alpha2.BundleDeployment
+		rel    *release.Release
+		tester *fakeTester
+	)
+
+	BeforeEach(func() {
+		rel = &release.Release{Name: "test", Version: 2, Hooks: []*release.Hook{
+			{Name: "smoke", Events: []release.HookEvent{release.HookTest}},
+			{Name: "migrate", Events: []release.HookEvent{release.HookPreUpgrade}},
+		}}
+		tester = &fakeTester{rel: rel}
+		c = &controller{tester: tester}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	})
+
+	It("does not run tests that were not requested", func() {
+		c.runRequestedTests(context.Background(), bd, rel)
+		Expect(tester.runs).To(BeZero())
+		Expect(bd.Status.Conditions).To(BeEmpty())
+	})
+
+	It("runs requested tests once", func() {
+		metav1.SetMetaDataAnnotation(&bd.ObjectMeta, rukpakv1alpha2.TestRequestedAnnotation, "1")
+		c.runRequestedTests(context.Background(), bd, rel)
+		c.runRequestedTests(context.Background(), bd, rel)
+		Expect(tester.runs).To(Equal(1))
+		Expect(bd.Status.TestRequest).To(Equal("1"))
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonTestsSucceeded))
+		Expect(cond.Message).To(Equal("1 test hooks of revision 2 passed"))
+
+		metav1.SetMetaDataAnnotation(&bd.ObjectMeta, rukpakv1alpha2.TestRequestedAnnotation, "2")
+		c.runRequestedTests(context.Background(), bd, rel)
+		Expect(tester.runs).To(Equal(2))
+	})
+
+	It("reports failed tests without retrying them", func() {
+		tester.err = errors.New(`pod smoke failed`)
+		metav1.SetMetaDataAnnotation(&bd.ObjectMeta, rukpakv1alpha2.TestRequestedAnnotation, "1")
+		c.runRequestedTests(context.Background(), bd, rel)
+		c.runRequestedTests(context.Background(), bd, rel)
+		Expect(tester.runs).To(Equal(1))
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonTestsFailed))
+		Expect(cond.Message).To(Equal("Test hooks of revision 2 failed: pod smoke failed"))
+	})
+
+	It("reports requested tests as failed without a tester", func() {
+		c.tester = nil
+		metav1.SetMetaDataAnnotation(&bd.ObjectMeta, rukpakv1alpha2.TestRequestedAnnotation, "1")
+		c.runRequestedTests(context.Background(), bd, rel)
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeTestsPassed)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(rukpakv1alpha2.ReasonTestsFailed))
+	})
+})
+
+var _ = Describe("failed releases", func() {
+	const manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-a
+  namespace: ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-b
+  namespace: ns
+`
+	var (
+		c          *controller
+		cl         *fakeActionClient
+		completer  *fakeCompleter
+		bd         *rukpakv1alpha2.BundleDeployment
+		previous   *release.Release
+		failed     *release.Release
+		upgradeErr error
+	)
+
+	BeforeEach(func() {
+		previous = &release.Release{Name: "test", Namespace: "ns", Version: 1, Info: &release.Info{Status: release.StatusDeployed},
+			Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-a\n  namespace: ns\n"}
+		failed = &release.Release{Name: "test", Namespace: "ns", Version: 2, Info: &release.Info{Status: release.StatusFailed}, Manifest: manifest}
+		cl = &fakeActionClient{failing: map[string]error{}, releases: map[int]*release.Release{0: failed, 1: previous}, manifest: manifest}
+		completer = &fakeCompleter{}
+		c = &controller{completer: completer}
+		bd = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		upgradeErr = errors.New(`Upgrade "test" failed: cannot patch "cm-b"`)
+	})
+
+	result := func(name string, typ rukpakv1alpha2.ObjectApplyResultType, message string) rukpakv1alpha2.ObjectApplyResult {
+		return rukpakv1alpha2.ObjectApplyResult{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: name, Result: typ, Message: message}
+	}
+
+	It("records which objects of a failed upgrade failed to apply", func() {
+		cl.failing["cm-b"] = errors.New("boom")
+
+		rel, err := c.applyFailedRelease(context.Background(), cl, bd, previous, upgradeErr)
+		Expect(err).To(MatchError(upgradeErr))
+		Expect(rel).To(BeNil())
+		Expect(completer.completed).To(BeEmpty())
+		Expect(bd.Status.ObjectApplyResults).To(Equal([]rukpakv1alpha2.ObjectApplyResult{
+			result("cm-a", rukpakv1alpha2.ObjectApplyResultUpdated, ""),
+			result("cm-b", rukpakv1alpha2.ObjectApplyResultFailed, "boom"),
+		}))
+	})
+
+	It("completes a failed release whose objects all apply", func() {
+		rel, err := c.applyFailedRelease(context.Background(), cl, bd, previous, upgradeErr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rel.Info.Status).To(Equal(release.StatusDeployed))
+		Expect(completer.completed).To(Equal([]int{2}))
+		Expect(bd.Status.ObjectApplyResults).To(Equal([]rukpakv1alpha2.ObjectApplyResult{
+			result("cm-a", rukpakv1alpha2.ObjectApplyResultUpdated, ""),
+			result("cm-b", rukpakv1alpha2.ObjectApplyResultCreated, ""),
+		}))
+	})
+
+	It("does not complete failed releases without a completer", func() {
+		c.completer = nil
+
+		_, err := c.applyFailedRelease(context.Background(), cl, bd, previous, upgradeErr)
+		Expect(err).To(MatchError(upgradeErr))
+		Expect(bd.Status.ObjectApplyResults).To(HaveLen(2))
+	})
+
+	It("does not apply the objects of releases whose hooks failed", func() {
+		hookErr := errors.New(`pre-upgrade hooks failed: job migrate failed`)
+
+		_, err := c.applyFailedRelease(context.Background(), cl, bd, previous, hookErr)
+		Expect(err).To(MatchError(hookErr))
+		Expect(cl.reconciled).To(BeEmpty())
+		Expect(bd.Status.ObjectApplyResults).To(BeEmpty())
+	})
+
+	It("does not apply objects when the upgrade left no failed release behind", func() {
+		cl.releases[0] = previous
+
+		_, err := c.applyFailedRelease(context.Background(), cl, bd, previous, upgradeErr)
+		Expect(err).To(MatchError(upgradeErr))
+		Expect(cl.reconciled).To(BeEmpty())
+	})
+
+	It("retries the failed objects of a failed release rather than upgrading it again", func() {
+		bd.Status.ObjectApplyResults = []rukpakv1alpha2.ObjectApplyResult{
+			result("cm-a", rukpakv1alpha2.ObjectApplyResultUpdated, ""),
+			result("cm-b", rukpakv1alpha2.ObjectApplyResultFailed, "boom"),
+		}
+		_, _, state, err := c.getReleaseState(cl, bd, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsRetry))
+
+		By("upgrading releases whose manifest changed")
+		cl.manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-c\n  namespace: ns\n"
+		_, _, state, err = c.getReleaseState(cl, bd, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+
+		By("upgrading failed releases without failed objects")
+		cl.manifest = manifest
+		bd.Status.ObjectApplyResults = nil
+		_, _, state, err = c.getReleaseState(cl, bd, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(stateNeedsUpgrade))
+	})
+
+	It("marks a completed release as deployed and the previous one as superseded", func() {
+		cfg := &action.Configuration{Releases: helmstorage.Init(driver.NewMemory())}
+		previous.Info.Status = release.StatusDeployed
+		Expect(cfg.Releases.Create(previous)).To(Succeed())
+		Expect(cfg.Releases.Create(failed)).To(Succeed())
+		hc := &HelmReleaseCompleter{ActionConfigGetter: fakeActionConfigGetter{cfg: cfg}}
+
+		rel, err := hc.Complete(context.Background(), bd, failed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rel.Info.Status).To(Equal(release.StatusDeployed))
+		Expect(rel.Info.Description).To(Equal("Upgrade complete"))
+		superseded, err := cfg.Releases.Get("test", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(superseded.Info.Status).To(Equal(release.StatusSuperseded))
+
+		_, err = hc.Complete(context.Background(), bd, previous)
+		Expect(err).To(MatchError(ContainSubstring("revision 1 is no longer the failed latest revision")))
 	})
 })
+
+// fakeCompleter marks releases as deployed and records their revisions.
+type fakeCompleter struct {
+	completed []int
+}
+
+func (f *fakeCompleter) Complete(_ context.Context, _ *rukpakv1alpha2.BundleDeployment, rel *release.Release) (*release.Release, error) {
+	f.completed = append(f.completed, rel.Version)
+	completed := *rel
+	completed.Info = &release.Info{Status: release.StatusDeployed}
+	return &completed, nil
+}
+
+type fakeActionConfigGetter struct {
+	cfg *action.Configuration
+}
+
+func (f fakeActionConfigGetter) ActionConfigFor(context.Context, client.Object) (*action.Configuration, error) {
+	return f.cfg, nil
+}
+
+type fakeTester struct {
+	rel  *release.Release
+	err  error
+	runs int
+}
+
+func (f *fakeTester) Test(context.Context, *rukpakv1alpha2.BundleDeployment) (*release.Release, error) {
+	f.runs++
+	return f.rel, f.err
+}
+
+var _ = DescribeTable("setHealthyCondition",
+	func(results healthchecks.Results, expectedStatus metav1.ConditionStatus, expectedReason string, expectedProgressing, expectedErr bool) {
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		progressing, err := setHealthyCondition(bd, results)
+		Expect(progressing).To(Equal(expectedProgressing))
+		Expect(err != nil).To(Equal(expectedErr))
+
+		cond := meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeHealthy)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Status).To(Equal(expectedStatus))
+		Expect(cond.Reason).To(Equal(expectedReason))
+	},
+	Entry("healthy",
+		healthchecks.Results{{Name: "a", Status: healthchecks.StatusHealthy}},
+		metav1.ConditionTrue, rukpakv1alpha2.ReasonHealthy, false, false),
+	Entry("rollout in flight",
+		healthchecks.Results{{Name: "a", Status: healthchecks.StatusHealthy}, {Name: "b", Status: healthchecks.StatusProgressing, Message: "rolling out"}},
+		metav1.ConditionUnknown, rukpakv1alpha2.ReasonProgressing, true, false),
+	Entry("failed object",
+		healthchecks.Results{{Name: "a", Status: healthchecks.StatusProgressing}, {Name: "b", Status: healthchecks.StatusDegraded, Message: "failed"}},
+		metav1.ConditionFalse, rukpakv1alpha2.ReasonDegraded, false, true),
+)
+
+var _ = DescribeTable("recheckHealth",
+	func(res, expected reconcile.Result) {
+		Expect(recheckHealth(res)).To(Equal(expected))
+	},
+	Entry("no requeue", reconcile.Result{}, reconcile.Result{RequeueAfter: healthRecheckInterval}),
+	Entry("earlier requeue", reconcile.Result{RequeueAfter: time.Second}, reconcile.Result{RequeueAfter: time.Second}),
+	Entry("later requeue", reconcile.Result{RequeueAfter: time.Hour}, reconcile.Result{RequeueAfter: healthRecheckInterval}),
+)
+
+// fakeAnalyzer reports the expressions in breached as breached and records
+// the evaluated expressions.
+type fakeAnalyzer struct {
+	breached  map[string]bool
+	evaluated []string
+}
+
+func (f *fakeAnalyzer) Breached(_ context.Context, _, expr string) (bool, error) {
+	f.evaluated = append(f.evaluated, expr)
+	return f.breached[expr], nil
+}
+
+// fakeCluster serves the APIs in apis and runs the given Kubernetes and
+// OpenShift versions with no feature gates enabled.
+type fakeCluster struct {
+	apis      map[schema.GroupVersionKind]bool
+	version   string
+	openShift string
+}
+
+func (f *fakeCluster) ServesAPI(_ context.Context, gvk schema.GroupVersionKind) (bool, error) {
+	return f.apis[gvk], nil
+}
+
+func (f *fakeCluster) Version(_ context.Context) (*version.Version, error) {
+	return version.ParseGeneric(f.version)
+}
+
+func (f *fakeCluster) FeatureGates(_ context.Context) (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+
+func (f *fakeCluster) OpenShiftVersion(_ context.Context) (*version.Version, error) {
+	if f.openShift == "" {
+		return nil, nil
+	}
+	return version.ParseGeneric(f.openShift)
+}
+
+var _ helmclient.ActionInterface = &fakeActionClient{}
+
+// fakeActionClient records the names of reconciled objects and fails
+// reconciliation of the objects listed in failing.
+//
+// Get returns the revisions in releases, and Upgrade records the chart and
+// description of each upgrade in upgrades. Upgraded releases have manifest as
+// their manifest.
+type fakeActionClient struct {
+	failing    map[string]error
+	reconciled []string
+	releases   map[int]*release.Release
+	upgrades   []*release.Release
+	manifest   string
+}
+
+func (f *fakeActionClient) Get(_ string, opts ...helmclient.GetOption) (*release.Release, error) {
+	get := &action.Get{}
+	for _, o := range opts {
+		if err := o(get); err != nil {
+			return nil, err
+		}
+	}
+	if rel, ok := f.releases[get.Version]; ok {
+		return rel, nil
+	}
+	return nil, driver.ErrReleaseNotFound
+}
+
+func (f *fakeActionClient) Install(string, string, *chart.Chart, map[string]interface{}, ...helmclient.InstallOption) (*release.Release, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeActionClient) Upgrade(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.UpgradeOption) (*release.Release, error) {
+	upgrade := &action.Upgrade{}
+	for _, o := range opts {
+		if err := o(upgrade); err != nil {
+			return nil, err
+		}
+	}
+	rel := &release.Release{Name: name, Namespace: namespace, Chart: chrt, Config: vals, Manifest: f.manifest, Info: &release.Info{Description: upgrade.Description}}
+	f.upgrades = append(f.upgrades, rel)
+	return rel, nil
+}
+
+func (f *fakeActionClient) Uninstall(string, ...helmclient.UninstallOption) (*release.UninstallReleaseResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeActionClient) Reconcile(rel *release.Release) error {
+	objs, err := util.ManifestObjects(strings.NewReader(rel.Manifest), "test")
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		f.reconciled = append(f.reconciled, obj.GetName())
+		if err := f.failing[obj.GetName()]; err != nil {
+			return err
+		}
+	}
+	return nil
+}
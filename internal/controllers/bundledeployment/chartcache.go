@@ -0,0 +1,69 @@
+package bundledeployment
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// chartCacheKey identifies the result of converting bundle content into a
+// chart. Handlers may derive the chart and values from the spec of the
+// BundleDeployment as well, e.g. from its name, install namespace or config,
+// so the key includes the name and a digest of the spec next to the digest of
+// the bundle content.
+type chartCacheKey struct {
+	name         string
+	bundleDigest string
+	specDigest   string
+}
+
+type cachedChart struct {
+	chart  *chart.Chart
+	values chartutil.Values
+}
+
+func chartCacheKeyFor(bd *rukpakv1alpha2.BundleDeployment) (chartCacheKey, bool) {
+	if bd.Status.ResolvedSource == nil || bd.Status.ResolvedSource.BundleDigest == "" {
+		return chartCacheKey{}, false
+	}
+	spec, err := json.Marshal(bd.Spec)
+	if err != nil {
+		return chartCacheKey{}, false
+	}
+	return chartCacheKey{
+		name:         bd.Name,
+		bundleDigest: bd.Status.ResolvedSource.BundleDigest,
+		specDigest:   fmt.Sprintf("%x", sha256.Sum256(spec)),
+	}, true
+}
+
+// cachedChartFor returns the chart and values that the bundle content of bd
+// was last converted into, if they are still cached.
+func (c *controller) cachedChartFor(bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, bool) {
+	if c.chartCache == nil {
+		return nil, nil, false
+	}
+	key, ok := chartCacheKeyFor(bd)
+	if !ok {
+		return nil, nil, false
+	}
+	cached, ok := c.chartCache.Get(key)
+	if !ok {
+		return nil, nil, false
+	}
+	return cached.(cachedChart).chart, cached.(cachedChart).values, true
+}
+
+func (c *controller) cacheChart(bd *rukpakv1alpha2.BundleDeployment, chrt *chart.Chart, values chartutil.Values) {
+	if c.chartCache == nil {
+		return
+	}
+	if key, ok := chartCacheKeyFor(bd); ok {
+		c.chartCache.Add(key, cachedChart{chart: chrt, values: values})
+	}
+}
@@ -0,0 +1,124 @@
+package bundledeployment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const generateNameSuffixLength = 5
+
+// errObjectCollision is returned when objects of a release already exist and
+// are managed by another BundleDeployment or by nothing at all.
+type errObjectCollision struct {
+	collisions []string
+	err        error
+}
+
+func (err errObjectCollision) Error() string {
+	return fmt.Sprintf("objects collide with existing objects: %s", strings.Join(err.collisions, "; "))
+}
+
+func (err errObjectCollision) Unwrap() error {
+	return err.err
+}
+
+// findCollisions returns an errObjectCollision that names the owners of the
+// existing objects that the objects of rel collide with, or installErr if
+// there are no such objects. It is meant to explain install and upgrade
+// failures, since the ownership errors of Helm only name the Helm release.
+func (c *controller) findCollisions(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release, installErr error) error {
+	if rel == nil {
+		return installErr
+	}
+	objs, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return installErr
+	}
+	cl, err := c.targetClient(ctx, bd)
+	if err != nil {
+		return installErr
+	}
+
+	var collisions []string
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		key := client.ObjectKeyFromObject(obj)
+		if key.Namespace == "" {
+			if namespaced, err := cl.IsObjectNamespaced(obj); err == nil && namespaced {
+				key.Namespace = bd.Spec.InstallNamespace
+			}
+		}
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(gvk)
+		if err := cl.Get(ctx, key, existing); err != nil {
+			continue
+		}
+		owner := objectOwner(existing)
+		if owner == bd.Name {
+			continue
+		}
+		desc := gvk.Kind + " " + key.Name
+		if key.Namespace != "" {
+			desc = gvk.Kind + " " + key.Namespace + "/" + key.Name
+		}
+		if owner == "" {
+			collisions = append(collisions, fmt.Sprintf("%s exists and is not managed by a BundleDeployment", desc))
+		} else {
+			collisions = append(collisions, fmt.Sprintf("%s is managed by BundleDeployment %q", desc, owner))
+		}
+	}
+	if len(collisions) == 0 {
+		return installErr
+	}
+	return errObjectCollision{collisions: collisions, err: installErr}
+}
+
+// objectOwner returns the name of the BundleDeployment that manages obj, or an
+// empty string if it is not managed by a BundleDeployment.
+func objectOwner(obj client.Object) string {
+	if obj.GetLabels()[util.CoreOwnerKindKey] == rukpakv1alpha2.BundleDeploymentKind {
+		if name := obj.GetLabels()[util.CoreOwnerNameKey]; name != "" {
+			return name
+		}
+	}
+	if ref := metav1.GetControllerOf(obj); ref != nil && ref.Kind == rukpakv1alpha2.BundleDeploymentKind {
+		if gv, err := schema.ParseGroupVersion(ref.APIVersion); err == nil && gv.Group == rukpakv1alpha2.GroupVersion.Group {
+			return ref.Name
+		}
+	}
+	return ""
+}
+
+// generateNameSuffix returns the suffix that is appended to the generateName
+// of the objects of the given BundleDeployment. It is derived from the name of
+// the BundleDeployment, so that the names are stable across upgrades but
+// differ between BundleDeployments that install the same bundle.
+func generateNameSuffix(bd *rukpakv1alpha2.BundleDeployment) string {
+	sum := sha256.Sum256([]byte(bd.Name))
+	return hex.EncodeToString(sum[:])[:generateNameSuffixLength]
+}
+
+// setGeneratedName replaces the generateName of obj with a name if its kind
+// is in kinds, since Helm can only track objects with a name.
+func setGeneratedName(obj *unstructured.Unstructured, kinds map[schema.GroupKind]struct{}, suffix string) {
+	if obj.GetName() != "" || obj.GetGenerateName() == "" {
+		return
+	}
+	if _, ok := kinds[obj.GroupVersionKind().GroupKind()]; !ok {
+		return
+	}
+	obj.SetName(obj.GetGenerateName() + suffix)
+	obj.SetGenerateName("")
+}
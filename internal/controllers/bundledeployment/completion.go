@@ -0,0 +1,66 @@
+package bundledeployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// ReleaseCompleter marks the failed latest release of a BundleDeployment as
+// deployed once the objects that failed to apply have been applied one at a
+// time, so that the install or upgrade is not repeated as a whole.
+type ReleaseCompleter interface {
+	// Complete marks rel, the failed latest release, as deployed and the
+	// releases that were deployed before it as superseded, like a successful
+	// install or upgrade does. It returns the deployed release.
+	Complete(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release) (*release.Release, error)
+}
+
+// HelmReleaseCompleter completes releases in the release storage of Helm.
+type HelmReleaseCompleter struct {
+	ActionConfigGetter helmclient.ActionConfigGetter
+}
+
+func (c *HelmReleaseCompleter) Complete(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release) (*release.Release, error) {
+	cfg, err := c.ActionConfigGetter.ActionConfigFor(ctx, bd)
+	if err != nil {
+		return nil, err
+	}
+	last, err := cfg.Releases.Last(bd.Name)
+	if err != nil {
+		return nil, err
+	}
+	if last.Version != rel.Version || last.Info.Status != release.StatusFailed {
+		return nil, fmt.Errorf("revision %d is no longer the failed latest revision of the release", rel.Version)
+	}
+
+	deployed, err := cfg.Releases.DeployedAll(bd.Name)
+	if err != nil && !errors.Is(err, driver.ErrNoDeployedReleases) {
+		return nil, err
+	}
+	for _, d := range deployed {
+		d.SetStatus(release.StatusSuperseded, "superseded by new release")
+		if err := cfg.Releases.Update(d); err != nil {
+			return nil, err
+		}
+	}
+
+	description := "Upgrade complete"
+	if last.Version == 1 {
+		description = "Install complete"
+	}
+	last.SetStatus(release.StatusDeployed, description)
+	last.Info.LastDeployed = helmtime.Now()
+	if err := cfg.Releases.Update(last); err != nil {
+		return nil, err
+	}
+	return last, nil
+}
@@ -0,0 +1,79 @@
+package bundledeployment
+
+import (
+	"context"
+	"sync"
+)
+
+// MaxConcurrentReconcilesLimit is the number of workers of controllers that
+// are configured with a ConcurrencyLimiter, and thereby the highest limit
+// that takes effect.
+const MaxConcurrentReconcilesLimit = 32
+
+// ConcurrencyLimiter limits the number of concurrent reconciles of the
+// controllers that share it. Unlike the number of workers of a controller,
+// its limit can be changed while the controllers are running.
+type ConcurrencyLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+// NewConcurrencyLimiter returns a ConcurrencyLimiter that allows limit
+// concurrent reconciles.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{}
+	l.cond = sync.NewCond(&l.mu)
+	l.SetLimit(limit)
+	return l
+}
+
+// SetLimit changes the number of allowed concurrent reconciles. Lowering it
+// does not interrupt reconciles that are running already. Limits lower than
+// one are raised to one.
+func (l *ConcurrencyLimiter) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+// Limit returns the number of allowed concurrent reconciles.
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Acquire waits until a reconcile may start, or until ctx is done.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.cond.Broadcast()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	l.active++
+	return nil
+}
+
+// Release ends a reconcile that was started with Acquire.
+func (l *ConcurrencyLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.cond.Broadcast()
+}
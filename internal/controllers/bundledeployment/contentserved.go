@@ -0,0 +1,49 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// contentServedRecheckInterval is how long a BundleDeployment whose content
+// could not be retrieved at its content URL waits before it is checked again.
+const contentServedRecheckInterval = 30 * time.Second
+
+// publishContentURL sets the content URL of bd to contentURL once the content
+// was verified to be retrievable there, and records the outcome of the check
+// in the ContentServed condition. A misconfigured external address of the
+// content server thus surfaces in the condition rather than as a content URL
+// that clients fail to retrieve. The check is skipped if the content URL was
+// already verified, and entirely if no content checker is configured.
+func (c *controller) publishContentURL(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, contentURL string) string {
+	if c.contentChecker == nil {
+		return contentURL
+	}
+	if bd.Status.ContentURL == contentURL && meta.IsStatusConditionTrue(bd.Status.Conditions, rukpakv1alpha2.TypeContentServed) {
+		return contentURL
+	}
+	if err := c.contentChecker.Check(ctx, contentURL); err != nil {
+		meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+			Type:               rukpakv1alpha2.TypeContentServed,
+			Status:             metav1.ConditionFalse,
+			Reason:             rukpakv1alpha2.ReasonContentNotRetrievable,
+			Message:            fmt.Sprintf("bundle content is not retrievable at %s, check the external address of the content server: %v", contentURL, err),
+			ObservedGeneration: bd.Generation,
+		})
+		return ""
+	}
+	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeContentServed,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha2.ReasonContentRetrievable,
+		Message:            fmt.Sprintf("bundle content is retrievable at %s", contentURL),
+		ObservedGeneration: bd.Generation,
+	})
+	return contentURL
+}
@@ -0,0 +1,46 @@
+package bundledeployment
+
+import (
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// ReconcileIDAnnotation is set on the events that are emitted during a
+// reconcile to the ID of that reconcile, which controller-runtime also logs
+// with every log line of the reconcile as "reconcileID".
+const ReconcileIDAnnotation = "core.rukpak.io/reconcile-id"
+
+var reconcileIDSuffix = regexp.MustCompile(` \(reconcileID: [^)]*\)$`)
+
+// tagFailedConditions appends the ID of the current reconcile to the messages
+// of the false conditions of a failed reconcile, so that the logs of the
+// reconcile that a failure was reported by can be found, and records an event
+// for each of them. Conditions that report the same failure as before keep the
+// ID of the reconcile that reported it first, so that repeated failures do not
+// update the status on every reconcile.
+func (c *controller) tagFailedConditions(bd *rukpakv1alpha2.BundleDeployment, existing []metav1.Condition, reconcileID types.UID) {
+	if reconcileID == "" {
+		return
+	}
+	for i := range bd.Status.Conditions {
+		cond := &bd.Status.Conditions[i]
+		if cond.Status != metav1.ConditionFalse {
+			continue
+		}
+		message := reconcileIDSuffix.ReplaceAllString(cond.Message, "")
+		if old := meta.FindStatusCondition(existing, cond.Type); old != nil && old.Status == cond.Status && old.Reason == cond.Reason &&
+			reconcileIDSuffix.ReplaceAllString(old.Message, "") == message {
+			cond.Message = old.Message
+			continue
+		}
+		cond.Message = fmt.Sprintf("%s (reconcileID: %s)", message, reconcileID)
+		c.recorder.AnnotatedEventf(bd, map[string]string{ReconcileIDAnnotation: string(reconcileID)}, corev1.EventTypeWarning, cond.Reason, "%s", message)
+	}
+}
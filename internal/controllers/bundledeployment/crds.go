@@ -0,0 +1,128 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// crdEstablishedTimeout is how long the CRDs of a release may take to be
+	// established after they were created, which matches the wait that Helm
+	// performs for the CRDs in the crds directory of a chart.
+	crdEstablishedTimeout = 60 * time.Second
+
+	// crdEstablishedRecheckInterval is how often a release whose CRDs are not
+	// established yet is checked again.
+	crdEstablishedRecheckInterval = time.Second
+)
+
+// ensureCRDs creates the CRDs of a release that do not exist yet, and returns
+// the names of the CRDs of the release that are not established yet or whose
+// served versions cannot be mapped yet. The custom resources of the release
+// can be applied right away once none are left, rather than on a reconcile
+// that is triggered by an error. CRDs that are not established within
+// crdEstablishedTimeout of their creation fail the reconcile instead.
+//
+// Existing CRDs are left for Helm to update, which keeps them subject to the
+// preflight checks. Created CRDs carry the Helm ownership metadata of the
+// release, so that Helm adopts them when the release is installed or upgraded.
+func (c *controller) ensureCRDs(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, crds []*apiextensionsv1.CustomResourceDefinition) ([]string, error) {
+	if len(crds) == 0 {
+		return nil, nil
+	}
+	cl, err := c.targetClient(ctx, bd)
+	if err != nil {
+		return nil, err
+	}
+	for _, crd := range crds {
+		err := cl.Get(ctx, client.ObjectKeyFromObject(crd), &apiextensionsv1.CustomResourceDefinition{})
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("get CRD %q: %v", crd.Name, err)
+		}
+		crd = crd.DeepCopy()
+		crd.SetLabels(util.MergeMaps(crd.GetLabels(), map[string]string{"app.kubernetes.io/managed-by": "Helm"}))
+		crd.SetAnnotations(util.MergeMaps(crd.GetAnnotations(), map[string]string{
+			"meta.helm.sh/release-name":      bd.Name,
+			"meta.helm.sh/release-namespace": bd.Spec.InstallNamespace,
+		}))
+		if err := cl.Create(ctx, crd); err != nil && !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("create CRD %q: %v", crd.Name, err)
+		}
+	}
+
+	var pending []string
+	for _, crd := range crds {
+		ready, err := crdReady(ctx, cl, crd)
+		if err != nil {
+			return nil, err
+		}
+		if !ready {
+			pending = append(pending, crd.Name)
+		}
+	}
+	return pending, nil
+}
+
+// crdReady returns whether crd is established in the cluster of cl and all
+// of its served versions are known to the REST mapper of cl, which Helm and
+// the dynamic watches use as well.
+func crdReady(ctx context.Context, cl client.Client, crd *apiextensionsv1.CustomResourceDefinition) (bool, error) {
+	current := &apiextensionsv1.CustomResourceDefinition{}
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(crd), current); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	established := false
+	for _, cond := range current.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+			established = true
+		}
+	}
+	if !established {
+		if age := time.Since(current.CreationTimestamp.Time); age > crdEstablishedTimeout {
+			return false, fmt.Errorf("CRD %q is not established %s after its creation", crd.Name, age.Round(time.Second))
+		}
+		return false, nil
+	}
+	gk := schema.GroupKind{Group: current.Spec.Group, Kind: current.Spec.Names.Kind}
+	for _, v := range current.Spec.Versions {
+		if !v.Served {
+			continue
+		}
+		if _, err := cl.RESTMapper().RESTMapping(gk, v.Name); err != nil {
+			if meta.IsNoMatchError(err) {
+				// Mappers that cache discovery until they are reset do not
+				// pick up new kinds by themselves. The default mapper instead
+				// reloads the group of a kind that it does not know.
+				if mapper, ok := cl.RESTMapper().(meta.ResettableRESTMapper); ok {
+					mapper.Reset()
+				}
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// waitForCRDs reports that the release of bd waits for its pending CRDs to be
+// established, and checks them again after crdEstablishedRecheckInterval
+// rather than blocking the reconcile until they are.
+func waitForCRDs(bd *rukpakv1alpha2.BundleDeployment, pending []string) ctrl.Result {
+	setInstalledAndHealthyFalse(bd, rukpakv1alpha2.ReasonCRDsNotEstablished, fmt.Sprintf("Waiting for CRDs %s to be established", strings.Join(pending, ", ")))
+	return ctrl.Result{RequeueAfter: crdEstablishedRecheckInterval}
+}
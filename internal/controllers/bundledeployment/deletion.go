@@ -0,0 +1,67 @@
+package bundledeployment
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/finalizer"
+)
+
+// setDeletingCondition records the progress of the deletion of bd in the
+// Deleting condition, so that deletions that are stuck in a finalizer can be
+// told apart. Finalizers only remove their key once they succeeded, so the
+// first key that is left names the step that the deletion waits for, and
+// finalizeErr is the error of that step. remaining is the number of objects
+// of the release that the uninstall waits for. Unless the uninstall policy
+// makes the provisioner delete the objects of the release, they are left for
+// the garbage collector to delete.
+func setDeletingCondition(bd *rukpakv1alpha2.BundleDeployment, remaining int, finalizeErr error) {
+	reason, message := rukpakv1alpha2.ReasonReleaseUninstalling, "the objects of the release are deleted by the garbage collector"
+	switch {
+	case controllerutil.ContainsFinalizer(bd, finalizer.DeleteCachedBundleKey):
+		reason, message = rukpakv1alpha2.ReasonStoragePurging, "purging the stored bundle content"
+	case controllerutil.ContainsFinalizer(bd, finalizer.CleanupUnpackCacheKey):
+		reason, message = rukpakv1alpha2.ReasonUnpackCachePurging, "purging the unpack cache"
+	case remaining > 0:
+		message = fmt.Sprintf("waiting for %d objects of the release to be deleted", remaining)
+	case controllerutil.ContainsFinalizer(bd, uninstallReleaseFinalizer):
+		message = "deleting the objects of the release"
+	case bd.Spec.UninstallPolicy == rukpakv1alpha2.UninstallPolicyForeground || bd.Spec.UninstallPolicy == rukpakv1alpha2.UninstallPolicyForce:
+		message = "the objects of the release were deleted"
+	}
+	if finalizeErr != nil {
+		message = fmt.Sprintf("%s: %v", message, finalizeErr)
+	}
+	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeDeleting,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: bd.Generation,
+	})
+}
+
+// skipFinalizerCleanup removes the finalizers that purge the stored bundle
+// content and the unpack cache of bd without running them, if bd is being
+// deleted and the skip is requested by its annotation, and records an event
+// naming the skipped finalizers.
+func (c *controller) skipFinalizerCleanup(bd *rukpakv1alpha2.BundleDeployment) {
+	if bd.DeletionTimestamp == nil || bd.Annotations[rukpakv1alpha2.SkipFinalizerCleanupAnnotation] != "true" {
+		return
+	}
+	var skipped []string
+	for _, key := range []string{finalizer.DeleteCachedBundleKey, finalizer.CleanupUnpackCacheKey} {
+		if controllerutil.RemoveFinalizer(bd, key) {
+			skipped = append(skipped, key)
+		}
+	}
+	if len(skipped) > 0 {
+		c.recorder.Eventf(bd, corev1.EventTypeWarning, "FinalizerCleanupSkipped", "skipped finalizers %s as requested by the %s annotation; stored content may be left behind", strings.Join(skipped, ", "), rukpakv1alpha2.SkipFinalizerCleanupAnnotation)
+	}
+}
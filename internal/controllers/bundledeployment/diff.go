@@ -0,0 +1,48 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// storeUpgradeDiff records a unified diff between the manifest of the current
+// release and the manifest of the given revision, which is about to be
+// deployed, so that the changes of an upgrade can be reviewed after the fact.
+func (c *controller) storeUpgradeDiff(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, revision int, currentManifest, manifest string) error {
+	diff, err := manifestDiff(revision, currentManifest, manifest)
+	if err != nil {
+		return err
+	}
+	return c.storage.StoreDiff(ctx, bd, revision, []byte(diff))
+}
+
+// manifestDiff returns a unified diff between the manifests of the revision
+// preceding the given revision and the revision itself.
+func manifestDiff(revision int, from, to string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(from),
+		B:        splitLines(to),
+		FromFile: fmt.Sprintf("revision-%d", revision-1),
+		ToFile:   fmt.Sprintf("revision-%d", revision),
+		Context:  3,
+	})
+}
+
+// splitLines splits s into lines that keep their line endings. Unlike
+// difflib.SplitLines, it does not add an empty line after a final newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
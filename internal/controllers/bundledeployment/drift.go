@@ -0,0 +1,139 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	crhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// reasonDependentModified is the reason of the events that record a
+	// dependent object being modified by someone other than the provisioner.
+	reasonDependentModified = "DependentObjectModified"
+
+	// reasonDependentDeleted is the reason of the events that record a
+	// dependent object being deleted by someone other than the provisioner.
+	reasonDependentDeleted = "DependentObjectDeleted"
+)
+
+// driftHandler enqueues the owners of dependent objects like the wrapped
+// handler, and records an event on the owning BundleDeployment when a
+// dependent object drifts from its release, before the next reconcile reverts
+// the drift.
+type driftHandler[T client.Object] struct {
+	crhandler.TypedEventHandler[T]
+	c *controller
+}
+
+func (h driftHandler[T]) Update(ctx context.Context, e event.TypedUpdateEvent[T], q workqueue.RateLimitingInterface) {
+	h.c.recordModified(ctx, e.ObjectOld, e.ObjectNew)
+	h.TypedEventHandler.Update(ctx, e, q)
+}
+
+func (h driftHandler[T]) Delete(ctx context.Context, e event.TypedDeleteEvent[T], q workqueue.RateLimitingInterface) {
+	h.c.recordDeleted(ctx, e.Object)
+	h.TypedEventHandler.Delete(ctx, e, q)
+}
+
+// recordModified records an event on the owner of newObj if the update was
+// made by a field manager other than the provisioner. The managers that made
+// an update are those whose managed fields entries changed with it; entries
+// of the status subresource are ignored, since status changes are not drift.
+func (c *controller) recordModified(ctx context.Context, oldObj, newObj client.Object) {
+	var managers []string
+	for _, entry := range newObj.GetManagedFields() {
+		if entry.Subresource == "status" || entry.Manager == c.fieldManager || hasManagedFieldsEntry(oldObj, entry) {
+			continue
+		}
+		managers = append(managers, entry.Manager)
+	}
+	if len(managers) == 0 {
+		return
+	}
+	bd := c.driftOwner(ctx, newObj)
+	if bd == nil {
+		return
+	}
+	c.recorder.Eventf(bd, corev1.EventTypeWarning, reasonDependentModified, "%s was modified by %s", describeDependent(newObj), strings.Join(managers, ", "))
+}
+
+// recordDeleted records an event on the owner of obj if obj is still part of
+// the latest release of its owner. Objects that the provisioner deletes, by
+// uninstalling a release or by upgrading it to a revision without them, are
+// not part of that release anymore.
+func (c *controller) recordDeleted(ctx context.Context, obj client.Object) {
+	bd := c.driftOwner(ctx, obj)
+	if bd == nil {
+		return
+	}
+	cl, err := c.acg.ActionClientFor(ctx, bd)
+	if err != nil {
+		return
+	}
+	rel, err := cl.Get(bd.Name)
+	if err != nil {
+		return
+	}
+	relObjects, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "parse release manifest to detect drift", "bundleDeployment", bd.Name)
+		return
+	}
+	gk := obj.GetObjectKind().GroupVersionKind().GroupKind()
+	for _, relObj := range relObjects {
+		namespace := relObj.GetNamespace()
+		if namespace == "" && obj.GetNamespace() != "" {
+			namespace = bd.Spec.InstallNamespace
+		}
+		if relObj.GetObjectKind().GroupVersionKind().GroupKind() == gk && relObj.GetName() == obj.GetName() && namespace == obj.GetNamespace() {
+			c.recorder.Eventf(bd, corev1.EventTypeWarning, reasonDependentDeleted, "%s was deleted", describeDependent(obj))
+			return
+		}
+	}
+}
+
+// driftOwner returns the BundleDeployment of this provisioner that manages
+// obj, or nil if there is none or it is being deleted.
+func (c *controller) driftOwner(ctx context.Context, obj client.Object) *rukpakv1alpha2.BundleDeployment {
+	name := objectOwner(obj)
+	if name == "" {
+		return nil
+	}
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	if err := c.cl.Get(ctx, client.ObjectKey{Name: name}, bd); err != nil {
+		return nil
+	}
+	if bd.DeletionTimestamp != nil || bd.Spec.ProvisionerClassName != c.provisionerID {
+		return nil
+	}
+	return bd
+}
+
+func hasManagedFieldsEntry(obj client.Object, entry metav1.ManagedFieldsEntry) bool {
+	for _, e := range obj.GetManagedFields() {
+		if equality.Semantic.DeepEqual(e, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeDependent(obj client.Object) string {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", kind, obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,70 @@
+package bundledeployment
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// failurePhases are the conditions that report failures of the phases of a
+// reconcile, in the order that a reconcile goes through them. The first of
+// them that is not true reports the failure of a reconcile.
+var failurePhases = []struct {
+	conditionType string
+	phase         rukpakv1alpha2.FailurePhase
+}{
+	{rukpakv1alpha2.TypeUnpacked, rukpakv1alpha2.FailurePhaseUnpack},
+	{rukpakv1alpha2.TypeInstalled, rukpakv1alpha2.FailurePhaseInstall},
+}
+
+// setLastFailure records the failure of a reconcile in status.lastFailure,
+// based on the conditions that the reconcile set. The retry count of the
+// previous failure is incremented if the reconcile failed the same way.
+func setLastFailure(bd *rukpakv1alpha2.BundleDeployment, now metav1.Time) {
+	failure := &rukpakv1alpha2.Failure{
+		Reason: rukpakv1alpha2.ReasonReconcileFailed,
+		Phase:  rukpakv1alpha2.FailurePhaseReconcile,
+		Time:   now,
+	}
+	if cond, phase := failedCondition(bd); cond != nil {
+		failure.Reason = cond.Reason
+		failure.Phase = phase
+		failure.ConditionType = cond.Type
+	}
+	for _, result := range bd.Status.ObjectApplyResults {
+		if result.Result == rukpakv1alpha2.ObjectApplyResultFailed {
+			failure.Object = &rukpakv1alpha2.ObjectReference{
+				APIVersion: result.APIVersion,
+				Kind:       result.Kind,
+				Namespace:  result.Namespace,
+				Name:       result.Name,
+			}
+			break
+		}
+	}
+	if last := bd.Status.LastFailure; last != nil && last.Reason == failure.Reason && last.Phase == failure.Phase {
+		failure.RetryCount = last.RetryCount + 1
+	}
+	bd.Status.LastFailure = failure
+}
+
+// failedCondition returns the condition that reports the failure of a
+// reconcile of bd, and the phase that failed. Reconciles of BundleDeployments
+// that are being deleted only fail in the uninstall phase.
+func failedCondition(bd *rukpakv1alpha2.BundleDeployment) (*metav1.Condition, rukpakv1alpha2.FailurePhase) {
+	if bd.DeletionTimestamp != nil {
+		return meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeDeleting), rukpakv1alpha2.FailurePhaseUninstall
+	}
+	for _, p := range failurePhases {
+		if cond := meta.FindStatusCondition(bd.Status.Conditions, p.conditionType); cond != nil && cond.Status != metav1.ConditionTrue {
+			return cond, p.phase
+		}
+	}
+	for i := range bd.Status.Conditions {
+		if bd.Status.Conditions[i].Status == metav1.ConditionFalse {
+			return &bd.Status.Conditions[i], rukpakv1alpha2.FailurePhaseReconcile
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,53 @@
+package bundledeployment
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// helmResourcePolicyAnnotation is the annotation with which Helm keeps
+// objects when an upgrade removes them from the release and when the release
+// is uninstalled.
+const helmResourcePolicyAnnotation = "helm.sh/resource-policy"
+
+// isKept reports whether an object with the given annotations is kept by its
+// resource policy, either the one of rukpak or the one of Helm.
+func isKept(annotations map[string]string) bool {
+	return annotations[rukpakv1alpha2.ResourcePolicyAnnotation] == rukpakv1alpha2.ResourcePolicyKeep ||
+		annotations[helmResourcePolicyAnnotation] == rukpakv1alpha2.ResourcePolicyKeep
+}
+
+// keepResource prepares a rendered object that is kept by its resource
+// policy to be left behind. The rukpak resource policy is delegated to Helm,
+// which keeps objects that an upgrade removes from the release only by its
+// own annotation, and the owner reference to the BundleDeployment is removed,
+// so that the garbage collector does not delete the object along with the
+// BundleDeployment.
+func keepResource(obj *unstructured.Unstructured, owner types.UID) {
+	annotations := obj.GetAnnotations()
+	if !isKept(annotations) {
+		return
+	}
+	if annotations[helmResourcePolicyAnnotation] != rukpakv1alpha2.ResourcePolicyKeep {
+		annotations[helmResourcePolicyAnnotation] = rukpakv1alpha2.ResourcePolicyKeep
+		obj.SetAnnotations(annotations)
+	}
+	removeOwnerReference(obj, owner)
+}
+
+// removeOwnerReference removes the owner reference with the given UID from
+// obj.
+func removeOwnerReference(obj *unstructured.Unstructured, owner types.UID) {
+	refs := obj.GetOwnerReferences()
+	kept := refs[:0]
+	for _, ref := range refs {
+		if ref.UID != owner || owner == "" {
+			kept = append(kept, ref)
+		}
+	}
+	if len(kept) != len(refs) {
+		obj.SetOwnerReferences(kept)
+	}
+}
@@ -0,0 +1,54 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// errInstallNamespaceNotFound is returned by ensureInstallNamespace when the
+// install namespace does not exist and must not be created.
+type errInstallNamespaceNotFound struct {
+	namespace string
+}
+
+func (err errInstallNamespaceNotFound) Error() string {
+	return fmt.Sprintf("install namespace %q does not exist; create it or set spec.installNamespacePolicy to %s", err.namespace, rukpakv1alpha2.InstallNamespacePolicyCreateIfMissing)
+}
+
+// ensureInstallNamespace checks that the install namespace of bd exists, and
+// creates it if the install namespace policy of bd allows it. Namespaces that
+// are being deleted are treated as missing, since nothing can be installed
+// into them.
+func (c *controller) ensureInstallNamespace(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) error {
+	cl, err := c.targetClient(ctx, bd)
+	if err != nil {
+		return err
+	}
+	ns := &corev1.Namespace{}
+	err = cl.Get(ctx, client.ObjectKey{Name: bd.Spec.InstallNamespace}, ns)
+	switch {
+	case err == nil && ns.DeletionTimestamp.IsZero():
+		return nil
+	case err == nil:
+		return errInstallNamespaceNotFound{bd.Spec.InstallNamespace}
+	case !apierrors.IsNotFound(err):
+		return fmt.Errorf("get install namespace: %v", err)
+	case bd.Spec.InstallNamespacePolicy != rukpakv1alpha2.InstallNamespacePolicyCreateIfMissing:
+		return errInstallNamespaceNotFound{bd.Spec.InstallNamespace}
+	}
+
+	ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: bd.Spec.InstallNamespace}}
+	if err := cl.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create install namespace: %v", err)
+	}
+	log.FromContext(ctx).Info("created install namespace", "namespace", bd.Spec.InstallNamespace)
+	return nil
+}
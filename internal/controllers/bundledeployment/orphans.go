@@ -0,0 +1,164 @@
+package bundledeployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+// reasonOrphansPruned is the reason of the events that record the objects
+// that were deleted by pruneOrphans.
+const reasonOrphansPruned = "OrphanedObjectsPruned"
+
+// pruneOrphans deletes the objects of the kinds that the stored revisions of
+// the release before rel had, but rel has none of, and that still carry the
+// owner labels of bd and the release annotations of Helm. Helm only deletes
+// the objects of the revision that it upgrades from, so objects of earlier
+// revisions, e.g. those left behind by a failed upgrade, would stay around
+// forever otherwise. Objects that are kept by their resource policy are
+// left alone. It returns the pruned objects.
+func pruneOrphans(ctx context.Context, cl helmclient.ActionInterface, targetClient client.Client, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release) ([]rukpakv1alpha2.ObjectReference, error) {
+	removed, err := removedKinds(cl, bd, rel)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pruned []rukpakv1alpha2.ObjectReference
+		errs   []error
+	)
+	for _, gvk := range removed {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := targetClient.List(ctx, list, client.MatchingLabels{
+			util.CoreOwnerKindKey: rukpakv1alpha2.BundleDeploymentKind,
+			util.CoreOwnerNameKey: bd.Name,
+		}); err != nil {
+			if meta.IsNoMatchError(err) {
+				// The kind is gone from the cluster, and its objects with it.
+				continue
+			}
+			errs = append(errs, fmt.Errorf("list %s: %v", gvk.Kind, err))
+			continue
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if !isReleaseObject(obj, bd) || isKept(obj.GetAnnotations()) || obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			if err := targetClient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("delete %s: %v", describeDependent(obj), err))
+				continue
+			}
+			pruned = append(pruned, rukpakv1alpha2.ObjectReference{
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       obj.GetKind(),
+				Namespace:  obj.GetNamespace(),
+				Name:       obj.GetName(),
+			})
+		}
+	}
+	return pruned, utilerrors.NewAggregate(errs)
+}
+
+// removedKinds returns the kinds of the objects of the stored revisions of
+// the release before rel that rel has no objects of. Kinds are compared by
+// group and kind, so that a new version of a kind does not count as a
+// removal. The version of a removed kind is the one of its latest revision.
+func removedKinds(cl helmclient.ActionInterface, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release) ([]schema.GroupVersionKind, error) {
+	current, err := manifestKinds(rel)
+	if err != nil {
+		return nil, err
+	}
+	removed := map[schema.GroupKind]schema.GroupVersionKind{}
+	// Revisions beyond the max history are not stored anymore, which ends
+	// the walk.
+	for version := rel.Version - 1; version > 0; version-- {
+		previous, err := cl.Get(bd.Name, func(get *action.Get) error {
+			get.Version = version
+			return nil
+		})
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get revision %d: %v", version, err)
+		}
+		kinds, err := manifestKinds(previous)
+		if err != nil {
+			return nil, err
+		}
+		for gk, gvk := range kinds {
+			if _, ok := current[gk]; ok {
+				continue
+			}
+			if _, ok := removed[gk]; !ok {
+				removed[gk] = gvk
+			}
+		}
+	}
+
+	gvks := make([]schema.GroupVersionKind, 0, len(removed))
+	for _, gvk := range removed {
+		gvks = append(gvks, gvk)
+	}
+	sort.Slice(gvks, func(i, j int) bool { return gvks[i].String() < gvks[j].String() })
+	return gvks, nil
+}
+
+func manifestKinds(rel *release.Release) (map[schema.GroupKind]schema.GroupVersionKind, error) {
+	objs, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest-%d", rel.Name, rel.Version))
+	if err != nil {
+		return nil, err
+	}
+	kinds := map[schema.GroupKind]schema.GroupVersionKind{}
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		kinds[gvk.GroupKind()] = gvk
+	}
+	return kinds, nil
+}
+
+// isReleaseObject reports whether Helm created or adopted obj for the
+// release of bd. Other objects with the owner labels of bd, such as its
+// unpack pods, are not part of the release.
+func isReleaseObject(obj client.Object, bd *rukpakv1alpha2.BundleDeployment) bool {
+	annotations := obj.GetAnnotations()
+	return annotations["meta.helm.sh/release-name"] == bd.Name && annotations["meta.helm.sh/release-namespace"] == bd.Spec.InstallNamespace
+}
+
+// recordPruned records the objects that an upgrade of bd pruned in its
+// status and in an event.
+func (c *controller) recordPruned(bd *rukpakv1alpha2.BundleDeployment, pruned []rukpakv1alpha2.ObjectReference) {
+	bd.Status.PrunedObjects = pruned
+	if len(pruned) == 0 {
+		return
+	}
+	described := make([]string, 0, len(pruned))
+	for _, ref := range pruned {
+		if ref.Namespace == "" {
+			described = append(described, fmt.Sprintf("%s %s", ref.Kind, ref.Name))
+		} else {
+			described = append(described, fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name))
+		}
+	}
+	c.recorder.Eventf(bd, corev1.EventTypeNormal, reasonOrphansPruned, "pruned objects of kinds that were removed from the bundle: %s", strings.Join(described, ", "))
+}
@@ -0,0 +1,45 @@
+package bundledeployment
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// serverPopulatedFields are the fields that the API server populates, which
+// manifests exported from a cluster, e.g. with kubectl get -o yaml, contain.
+// Applying them fails, conflicts with the API server or causes spurious
+// diffs, so they are stripped from the objects of every bundle.
+var serverPopulatedFields = [][]string{
+	{"status"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "selfLink"},
+	{"metadata", "uid"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+}
+
+// AllKinds is the kind of the pruned fields that are pruned from objects of
+// every kind.
+var AllKinds = schema.GroupKind{Kind: "*"}
+
+// pruneFields removes the server-populated fields from obj, and the fields
+// that are pruned from objects of its kind or of AllKinds.
+func pruneFields(obj *unstructured.Unstructured, pruned map[schema.GroupKind][][]string) {
+	for _, field := range serverPopulatedFields {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	for _, field := range pruned[obj.GroupVersionKind().GroupKind()] {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	for _, field := range pruned[AllKinds] {
+		unstructured.RemoveNestedField(obj.Object, field...)
+	}
+	if len(obj.GetAnnotations()) == 0 {
+		obj.SetAnnotations(nil)
+	}
+}
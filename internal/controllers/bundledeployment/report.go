@@ -0,0 +1,87 @@
+package bundledeployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/installreport"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+// reportActions maps the release states that deploy a new release to the
+// action recorded in its install report.
+var reportActions = map[releaseState]installreport.Action{
+	stateNeedsInstall: installreport.ActionInstall,
+	stateNeedsUpgrade: installreport.ActionUpgrade,
+}
+
+// storeInstallReport records an install report for rel, which was deployed
+// by action.
+func (c *controller) storeInstallReport(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release, action installreport.Action) error {
+	objs, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return err
+	}
+	report := &installreport.Report{
+		BundleDeployment: bd.Name,
+		Generation:       bd.Generation,
+		Provisioner:      c.provisionerID,
+		RequestedBy:      specManager(bd),
+		Time:             metav1.Now(),
+		Action:           action,
+		Revision:         rel.Version,
+		Source:           bd.Status.ResolvedSource,
+		ManifestDigest:   installreport.ManifestDigest(rel.Manifest),
+		Objects:          make([]installreport.Object, 0, len(objs)),
+		Conditions:       bd.Status.Conditions,
+	}
+	if rel.Info != nil {
+		report.Time = metav1.NewTime(rel.Info.LastDeployed.Time)
+	}
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		report.Objects = append(report.Objects, installreport.Object{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		})
+	}
+
+	data, err := installreport.Sign(report, c.reportSigner)
+	if err != nil {
+		return err
+	}
+	return c.storage.StoreReport(ctx, bd, data)
+}
+
+// specManager returns the most recent field manager that owns fields of the
+// BundleDeployment spec.
+func specManager(bd *rukpakv1alpha2.BundleDeployment) string {
+	var latest *metav1.ManagedFieldsEntry
+	for i, entry := range bd.ManagedFields {
+		if entry.Subresource != "" || entry.Time == nil || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields["f:spec"]; !ok {
+			continue
+		}
+		if latest == nil || !entry.Time.Before(latest.Time) {
+			latest = &bd.ManagedFields[i]
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return latest.Manager
+}
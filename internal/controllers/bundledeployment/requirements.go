@@ -0,0 +1,50 @@
+package bundledeployment
+
+import (
+	"context"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/requirements"
+)
+
+// requirementsRecheckInterval is how often the requirements of a pending
+// BundleDeployment are evaluated again. Capabilities such as APIs provided by
+// CRDs can appear at any time, and there is no event to watch for most of
+// them.
+const requirementsRecheckInterval = time.Minute
+
+// unmetRequirements returns a description of every requirement declared by
+// the config of bd, or by the bundle that chrt was converted from, that the
+// cluster does not meet.
+func (c *controller) unmetRequirements(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, chrt *chart.Chart) ([]string, error) {
+	configReqs, err := requirements.FromConfig(bd.Spec.Config)
+	if err != nil {
+		return nil, err
+	}
+	bundleReqs, err := requirements.FromChart(chrt)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmet []string
+	for _, reqs := range []*requirements.Requirements{configReqs, bundleReqs} {
+		if reqs.IsEmpty() {
+			continue
+		}
+		// The requirements are met by the cluster that the objects of the
+		// bundle are applied to.
+		cluster, err := c.targetRequirementsCluster(ctx, bd)
+		if err != nil {
+			return nil, err
+		}
+		u, err := requirements.Unmet(ctx, cluster, reqs)
+		if err != nil {
+			return nil, err
+		}
+		unmet = append(unmet, u...)
+	}
+	return unmet, nil
+}
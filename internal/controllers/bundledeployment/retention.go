@@ -0,0 +1,102 @@
+package bundledeployment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	unpackersource "github.com/operator-framework/rukpak/pkg/source"
+	"github.com/operator-framework/rukpak/pkg/storage"
+)
+
+// WithRetainer configures the storage that retains the bundle content of the
+// previous sources of BundleDeployments, which is restored rather than
+// unpacked again when a BundleDeployment is rolled back to one of them.
+func WithRetainer(retainer storage.Retainer) Option {
+	return func(c *controller) {
+		c.retainer = retainer
+	}
+}
+
+// sourceHash identifies a source, in the retained content of a
+// BundleDeployment and in its status.
+func sourceHash(source rukpakv1alpha2.BundleSource) (string, error) {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// restoreRetained returns the retained content of the source of bd, or nil if
+// it is not restored. Content is only restored when bd was rolled back to a
+// previous source, i.e. not for the source whose content was retained most
+// recently, so that the content of the current source is still unpacked and
+// follows references that move, such as image tags and git branches.
+//
+// Retained content is an optimization, so failures to restore it are logged
+// and the source is unpacked instead.
+func (c *controller) restoreRetained(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) *unpackersource.Result {
+	if c.retainer == nil {
+		return nil
+	}
+	l := log.FromContext(ctx)
+	key, err := sourceHash(bd.Spec.Source)
+	if err != nil {
+		l.Error(err, "failed to identify the source in the retained bundle content")
+		return nil
+	}
+	retained, err := c.retainer.Retained(ctx, bd)
+	if err != nil {
+		l.Error(err, "failed to list the retained bundle content")
+		return nil
+	}
+	if len(retained) == 0 || retained[0] == key || !slices.Contains(retained, key) {
+		return nil
+	}
+	bundle, metadata, err := c.retainer.LoadRetained(ctx, bd, key)
+	if err != nil {
+		l.Error(err, "failed to load the retained bundle content")
+		return nil
+	}
+	resolvedSource := &rukpakv1alpha2.BundleSource{}
+	if err := json.Unmarshal(metadata, resolvedSource); err != nil {
+		_ = bundle.Close()
+		l.Error(err, "failed to decode the resolved source of the retained bundle content")
+		return nil
+	}
+	return &unpackersource.Result{
+		Bundle:         bundle,
+		Closer:         bundle,
+		ResolvedSource: resolvedSource,
+		State:          unpackersource.StateUnpacked,
+		Message:        "Restored the retained bundle content of the source",
+	}
+}
+
+// retain retains the content that was just stored for bd, along with the
+// resolved source that it was unpacked from.
+func (c *controller) retain(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, result *unpackersource.Result) {
+	if c.retainer == nil || result.ResolvedSource == nil {
+		return
+	}
+	l := log.FromContext(ctx)
+	key, err := sourceHash(bd.Spec.Source)
+	if err != nil {
+		l.Error(err, "failed to identify the source in the retained bundle content")
+		return
+	}
+	metadata, err := json.Marshal(result.ResolvedSource)
+	if err != nil {
+		l.Error(err, "failed to encode the resolved source of the bundle content")
+		return
+	}
+	if err := c.retainer.Retain(ctx, bd, key, metadata); err != nil {
+		l.Error(err, "failed to retain the bundle content")
+	}
+}
@@ -0,0 +1,51 @@
+package bundledeployment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ShutdownGracePeriodHeadroom is how much longer than the shutdown grace
+// period of its controllers the manager should wait for them to stop, so that
+// the status of reconciles that finish at the end of the grace period is still
+// written.
+const ShutdownGracePeriodHeadroom = 5 * time.Second
+
+var errShutdownGracePeriodExpired = errors.New("shutdown grace period expired")
+
+// WithShutdownGracePeriod lets reconciles that are in flight when the
+// controller stops continue for up to gracePeriod, so that helm installs and
+// upgrades are not interrupted halfway and their outcome is written to the
+// status. No reconciles are started once the controller stops. Zero
+// interrupts reconciles right away.
+func WithShutdownGracePeriod(gracePeriod time.Duration) Option {
+	return func(c *controller) {
+		c.shutdownGracePeriod = gracePeriod
+	}
+}
+
+// withGracePeriod returns a context with the values of ctx that is canceled
+// gracePeriod after ctx is done, rather than together with it.
+func withGracePeriod(ctx context.Context, gracePeriod time.Duration) (context.Context, context.CancelFunc) {
+	if gracePeriod <= 0 {
+		return context.WithCancel(ctx)
+	}
+	graceful, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		log.FromContext(graceful).Info("controller is stopping, waiting for the reconcile to finish", "gracePeriod", gracePeriod)
+		timer := time.NewTimer(gracePeriod)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel(errShutdownGracePeriodExpired)
+		case <-graceful.Done():
+		}
+	})
+	return graceful, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
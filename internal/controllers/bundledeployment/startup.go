@@ -0,0 +1,112 @@
+package bundledeployment
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+const (
+	// startupWindow is how long after the controller starts the requests of
+	// BundleDeployments are ordered by the outcome of their previous
+	// reconcile. It covers the initial lists of the watches of the
+	// controller, which enqueue every BundleDeployment at once.
+	startupWindow = time.Minute
+	// startupPriorityStep is how long the requests of a priority are held
+	// back behind those of the next higher priority.
+	startupPriorityStep = time.Second
+)
+
+// startupPriority orders the reconciles of BundleDeployments after a restart.
+// Lower priorities are reconciled later.
+type startupPriority int
+
+const (
+	// startupPrioritySteady is the priority of BundleDeployments that were
+	// installed successfully, which are reconciled first to restore the
+	// steady state quickly.
+	startupPrioritySteady startupPriority = iota
+	// startupPriorityPending is the priority of BundleDeployments that were
+	// not installed yet, but did not fail either.
+	startupPriorityPending
+	// startupPriorityFailing is the priority of BundleDeployments whose
+	// previous reconcile failed, which are reconciled last so that
+	// persistent failures do not hold up the others.
+	startupPriorityFailing
+)
+
+func startupPriorityOf(bd *rukpakv1alpha2.BundleDeployment) startupPriority {
+	switch {
+	case bd.Status.LastFailure != nil:
+		return startupPriorityFailing
+	case meta.IsStatusConditionTrue(bd.Status.Conditions, rukpakv1alpha2.TypeInstalled):
+		return startupPrioritySteady
+	default:
+		return startupPriorityPending
+	}
+}
+
+// startupQueue is the queue of the controller. Until the startup window
+// ends, requests that are added are held back by startupPriorityStep for
+// every priority that they are below startupPrioritySteady, so that they
+// are queued behind the requests of higher priorities. Afterwards, and for
+// requeues, it behaves like the queue that it wraps.
+type startupQueue struct {
+	workqueue.RateLimitingInterface
+
+	priority func(item interface{}) startupPriority
+	step     time.Duration
+	until    time.Time
+	now      func() time.Time
+}
+
+func newStartupQueue(queue workqueue.RateLimitingInterface, priority func(item interface{}) startupPriority) *startupQueue {
+	return &startupQueue{
+		RateLimitingInterface: queue,
+		priority:              priority,
+		step:                  startupPriorityStep,
+		until:                 time.Now().Add(startupWindow),
+		now:                   time.Now,
+	}
+}
+
+func (q *startupQueue) Add(item interface{}) {
+	if q.now().Before(q.until) {
+		if p := q.priority(item); p > startupPrioritySteady {
+			q.RateLimitingInterface.AddAfter(item, time.Duration(p)*q.step)
+			return
+		}
+	}
+	q.RateLimitingInterface.Add(item)
+}
+
+// newQueue constructs the queue of the controller once it starts.
+func (c *controller) newQueue(controllerName string, rateLimiter ratelimiter.RateLimiter) workqueue.RateLimitingInterface {
+	queue := workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{
+		Name: controllerName,
+	})
+	return newStartupQueue(queue, c.startupPriority)
+}
+
+// startupPriority looks up the priority of the BundleDeployment of a request
+// in the cache. Requests of BundleDeployments that are not found are not held
+// back.
+func (c *controller) startupPriority(item interface{}) startupPriority {
+	req, ok := item.(reconcile.Request)
+	if !ok {
+		return startupPrioritySteady
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), startupPriorityStep)
+	defer cancel()
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	if err := c.cl.Get(ctx, req.NamespacedName, bd); err != nil {
+		return startupPrioritySteady
+	}
+	return startupPriorityOf(bd)
+}
@@ -0,0 +1,127 @@
+package bundledeployment
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/requirements"
+	"github.com/operator-framework/rukpak/internal/target"
+	helmpredicate "github.com/operator-framework/rukpak/pkg/helm-operator-plugins/predicate"
+)
+
+var errTargetsUnsupported = errors.New("this provisioner does not support spec.target")
+
+// targetCluster returns the remote cluster that bd applies its objects to,
+// or nil if it applies them to the cluster that it is in.
+func (c *controller) targetCluster(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (*target.Cluster, error) {
+	if bd.Spec.Target == nil {
+		return nil, nil
+	}
+	if c.targets == nil {
+		return nil, errTargetsUnsupported
+	}
+	return c.targets.For(ctx, bd)
+}
+
+// targetClient returns a client of the cluster that bd applies its objects
+// to.
+func (c *controller) targetClient(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (client.Client, error) {
+	remote, err := c.targetCluster(ctx, bd)
+	if err != nil || remote == nil {
+		return c.cl, err
+	}
+	return remote.GetClient(), nil
+}
+
+// targetRequirementsCluster returns the cluster that the requirements of bd
+// are evaluated against.
+func (c *controller) targetRequirementsCluster(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (requirements.Cluster, error) {
+	remote, err := c.targetCluster(ctx, bd)
+	if err != nil || remote == nil {
+		return c.cluster, err
+	}
+	return &requirements.Discovery{Client: remote.Discovery}, nil
+}
+
+// watchRemoteDependent makes sure that the dependent resources of the given
+// kind are watched in the remote cluster. The objects in remote clusters have
+// no owner references, so their BundleDeployment is found by their owner
+// labels instead.
+func (c *controller) watchRemoteDependent(remote *target.Cluster, gvk schema.GroupVersionKind) error {
+	c.dynamicWatchMutex.Lock()
+	defer c.dynamicWatchMutex.Unlock()
+
+	// The watches of a cluster end when it is disconnected, e.g. because its
+	// kubeconfig changed, and are set up again on the new connection.
+	for cluster := range c.remoteWatchGVKs {
+		if cluster.Stopped() {
+			delete(c.remoteWatchGVKs, cluster)
+		}
+	}
+	if _, isWatched := c.remoteWatchGVKs[remote][gvk]; isWatched {
+		return nil
+	}
+
+	var src source.Source
+	if _, metadataOnly := c.metadataOnlyGVKs[gvk]; metadataOnly {
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(gvk)
+		src = source.Kind(
+			remote.GetCache(),
+			obj,
+			driftHandler[*metav1.PartialObjectMetadata]{
+				TypedEventHandler: crhandler.TypedEnqueueRequestsFromMapFunc(mapRemoteDependent[*metav1.PartialObjectMetadata](c)),
+				c:                 c,
+			},
+			helmpredicate.DependentMetadataPredicateFuncs(),
+		)
+	} else {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		src = source.Kind(
+			remote.GetCache(),
+			obj,
+			driftHandler[*unstructured.Unstructured]{
+				TypedEventHandler: crhandler.TypedEnqueueRequestsFromMapFunc(mapRemoteDependent[*unstructured.Unstructured](c)),
+				c:                 c,
+			},
+			helmpredicate.DependentPredicateFuncs[*unstructured.Unstructured](),
+		)
+	}
+	if err := c.controller.Watch(src); err != nil {
+		return err
+	}
+	if c.remoteWatchGVKs[remote] == nil {
+		c.remoteWatchGVKs[remote] = map[schema.GroupVersionKind]struct{}{}
+	}
+	c.remoteWatchGVKs[remote][gvk] = struct{}{}
+	return nil
+}
+
+// mapRemoteDependent enqueues the BundleDeployment of this provisioner that
+// the owner labels of a dependent object in a remote cluster name.
+func mapRemoteDependent[T client.Object](c *controller) crhandler.TypedMapFunc[T] {
+	return func(ctx context.Context, obj T) []reconcile.Request {
+		name := objectOwner(obj)
+		if name == "" {
+			return nil
+		}
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		if err := c.cl.Get(ctx, client.ObjectKey{Name: name}, bd); err != nil {
+			return nil
+		}
+		if bd.Spec.ProvisionerClassName != c.provisionerID || bd.Spec.Target == nil {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(bd)}}
+	}
+}
@@ -0,0 +1,106 @@
+package bundledeployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// defaultTestTimeout is how long HelmReleaseTester waits for each test hook
+// unless a timeout is configured.
+const defaultTestTimeout = 5 * time.Minute
+
+// ReleaseTester runs the test hooks of the latest release of a
+// BundleDeployment.
+type ReleaseTester interface {
+	// Test runs the test hooks and returns the release with the outcome of
+	// each hook. It returns an error if any test hook failed.
+	Test(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (*release.Release, error)
+}
+
+// HelmReleaseTester runs test hooks like "helm test" does.
+type HelmReleaseTester struct {
+	ActionConfigGetter helmclient.ActionConfigGetter
+	// Timeout is how long each test hook is waited for. Defaults to five
+	// minutes.
+	Timeout time.Duration
+}
+
+func (t *HelmReleaseTester) Test(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (*release.Release, error) {
+	cfg, err := t.ActionConfigGetter.ActionConfigFor(ctx, bd)
+	if err != nil {
+		return nil, err
+	}
+	test := action.NewReleaseTesting(cfg)
+	test.Namespace = bd.Spec.InstallNamespace
+	test.Timeout = t.Timeout
+	if test.Timeout <= 0 {
+		test.Timeout = defaultTestTimeout
+	}
+	return test.Run(bd.Name)
+}
+
+// runRequestedTests runs the test hooks of rel if a test run was requested
+// with the test-requested annotation since the last run, and reports their
+// outcome in the TestsPassed condition. Failing tests are not an error of the
+// reconcile, so they are not retried until the next request.
+func (c *controller) runRequestedTests(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, rel *release.Release) {
+	requested := bd.Annotations[rukpakv1alpha2.TestRequestedAnnotation]
+	if requested == "" || requested == bd.Status.TestRequest {
+		return
+	}
+	bd.Status.TestRequest = requested
+
+	if c.tester == nil {
+		setTestsPassedFalse(bd, "Test hooks are not supported by this provisioner")
+		return
+	}
+	log.FromContext(ctx).Info("running test hooks", "revision", rel.Version)
+	tested, err := c.tester.Test(ctx, bd)
+	if err != nil {
+		setTestsPassedFalse(bd, fmt.Sprintf("Test hooks of revision %d failed: %v", rel.Version, err))
+		return
+	}
+	tests := 0
+	for _, h := range tested.Hooks {
+		if isTestHook(h) {
+			tests++
+		}
+	}
+	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeTestsPassed,
+		Status:             metav1.ConditionTrue,
+		Reason:             rukpakv1alpha2.ReasonTestsSucceeded,
+		Message:            fmt.Sprintf("%d test hooks of revision %d passed", tests, tested.Version),
+		ObservedGeneration: bd.Generation,
+	})
+}
+
+func setTestsPassedFalse(bd *rukpakv1alpha2.BundleDeployment, message string) {
+	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeTestsPassed,
+		Status:             metav1.ConditionFalse,
+		Reason:             rukpakv1alpha2.ReasonTestsFailed,
+		Message:            message,
+		ObservedGeneration: bd.Generation,
+	})
+}
+
+func isTestHook(h *release.Hook) bool {
+	for _, e := range h.Events {
+		if e == release.HookTest {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package bundledeployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// uninstallReleaseFinalizer holds the deletion of BundleDeployments whose
+	// uninstall policy makes the provisioner delete the objects of their
+	// release.
+	uninstallReleaseFinalizer = "core.rukpak.io/uninstall-release"
+
+	// uninstallRecheckInterval is how long the deletion of a BundleDeployment
+	// with the Foreground uninstall policy waits before it checks again
+	// whether the objects of its release are gone.
+	uninstallRecheckInterval = 5 * time.Second
+)
+
+// syncUninstallFinalizer adds the uninstall finalizer to bd if its uninstall
+// policy makes the provisioner delete the objects of its release, and removes
+// it otherwise. The provisioner deletes the objects of releases in remote
+// clusters regardless of the policy, since there is no garbage collector
+// that would delete them along with the BundleDeployment.
+func syncUninstallFinalizer(bd *rukpakv1alpha2.BundleDeployment) {
+	switch {
+	case bd.Spec.Target != nil:
+		controllerutil.AddFinalizer(bd, uninstallReleaseFinalizer)
+	case bd.Spec.UninstallPolicy == rukpakv1alpha2.UninstallPolicyForeground, bd.Spec.UninstallPolicy == rukpakv1alpha2.UninstallPolicyForce:
+		controllerutil.AddFinalizer(bd, uninstallReleaseFinalizer)
+	default:
+		controllerutil.RemoveFinalizer(bd, uninstallReleaseFinalizer)
+	}
+}
+
+// uninstall deletes the objects of the latest release of bd according to its
+// uninstall policy, and removes the uninstall finalizer once it is done. It
+// returns the number of objects that the deletion still waits for.
+//
+// With the Foreground policy, objects are deleted with foreground propagation
+// and are waited for until they are gone, and objects whose API is not served
+// fail the uninstall. With the Force policy, objects are not waited for, and
+// objects whose API is not served are skipped. The objects of releases in
+// remote clusters with the Background policy are deleted with background
+// propagation and are not waited for either. Objects that are kept by their
+// resource policy are left alone, like Helm does.
+func (c *controller) uninstall(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (int, error) {
+	if !controllerutil.ContainsFinalizer(bd, uninstallReleaseFinalizer) {
+		return 0, nil
+	}
+	cl, err := c.acg.ActionClientFor(ctx, bd)
+	if err != nil {
+		return 0, err
+	}
+	rel, err := cl.Get(bd.Name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		controllerutil.RemoveFinalizer(bd, uninstallReleaseFinalizer)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	objs, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return 0, err
+	}
+
+	force := bd.Spec.UninstallPolicy == rukpakv1alpha2.UninstallPolicyForce
+	foreground := bd.Spec.UninstallPolicy == rukpakv1alpha2.UninstallPolicyForeground
+	propagation := metav1.DeletePropagationBackground
+	if foreground {
+		propagation = metav1.DeletePropagationForeground
+	}
+	var (
+		remaining int
+		errs      []error
+	)
+	for _, obj := range objs {
+		if isKept(obj.GetAnnotations()) {
+			continue
+		}
+		err := c.deleteReleaseObject(ctx, bd, obj, propagation)
+		switch {
+		case apierrors.IsNotFound(err):
+		case meta.IsNoMatchError(err) && force:
+		case meta.IsNoMatchError(err):
+			errs = append(errs, fmt.Errorf("delete %s: %w; set spec.uninstallPolicy to %s to skip objects whose API is not served", describeDependent(obj), err, rukpakv1alpha2.UninstallPolicyForce))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("delete %s: %w", describeDependent(obj), err))
+		case foreground:
+			// Deleting an object that is still being deleted succeeds, so
+			// the object is gone once it is not found anymore.
+			remaining++
+		}
+	}
+	if len(errs) > 0 {
+		return remaining, utilerrors.NewAggregate(errs)
+	}
+	if remaining == 0 {
+		controllerutil.RemoveFinalizer(bd, uninstallReleaseFinalizer)
+	}
+	return remaining, nil
+}
+
+func (c *controller) deleteReleaseObject(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, obj client.Object, propagation metav1.DeletionPropagation) error {
+	cl, err := c.targetClient(ctx, bd)
+	if err != nil {
+		return err
+	}
+	if obj.GetNamespace() == "" {
+		namespaced, err := cl.IsObjectNamespaced(obj)
+		if err != nil {
+			return err
+		}
+		if namespaced {
+			obj.SetNamespace(bd.Spec.InstallNamespace)
+		}
+	}
+	return cl.Delete(ctx, obj, client.PropagationPolicy(propagation))
+}
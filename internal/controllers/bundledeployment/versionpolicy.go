@@ -0,0 +1,49 @@
+package bundledeployment
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"helm.sh/helm/v3/pkg/chart"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// checkVersionPolicy returns an error explaining why policy does not permit
+// the upgrade from the bundle version of installed to that of desired. Bundles
+// that do not declare a version are not subject to the policy.
+func checkVersionPolicy(policy *rukpakv1alpha2.VersionPolicy, installed, desired *chart.Chart) error {
+	if policy == nil {
+		return nil
+	}
+	from, to := chartVersion(installed), chartVersion(desired)
+	if from == "" || to == "" || from == to || policy.ForceVersion == to {
+		return nil
+	}
+	fromVersion, err := semver.ParseTolerant(from)
+	if err != nil {
+		return fmt.Errorf("installed bundle version %q is not a semantic version: %v", from, err)
+	}
+	toVersion, err := semver.ParseTolerant(to)
+	if err != nil {
+		return fmt.Errorf("bundle version %q is not a semantic version: %v", to, err)
+	}
+
+	var violation string
+	switch {
+	case toVersion.LT(fromVersion) && !policy.AllowDowngrades:
+		violation = "is a downgrade"
+	case toVersion.Major > fromVersion.Major+1 && !policy.AllowMajorVersionSkips:
+		violation = fmt.Sprintf("skips major version %d", fromVersion.Major+1)
+	default:
+		return nil
+	}
+	return fmt.Errorf("upgrade from version %s to %s %s, which is not permitted by spec.versionPolicy; set spec.versionPolicy.forceVersion to %q to allow it", from, to, violation, to)
+}
+
+func chartVersion(chrt *chart.Chart) string {
+	if chrt == nil || chrt.Metadata == nil {
+		return ""
+	}
+	return chrt.Metadata.Version
+}
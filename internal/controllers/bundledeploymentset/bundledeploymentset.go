@@ -0,0 +1,300 @@
+// Package bundledeploymentset implements the controller of
+// BundleDeploymentSets, which deploy a bundle to a fleet of clusters by
+// creating a BundleDeployment that targets each cluster that they select.
+package bundledeploymentset
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	crhandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// maxBundleDeploymentNameLength must be aligned with the BundleDeployment
+// CRD metadata.name length validation, defined in:
+// <repoRoot>/manifests/base/apis/crds/patches/bundledeployment_validation.yaml
+const maxBundleDeploymentNameLength = 52
+
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeploymentsets,verbs=list;watch
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeploymentsets/status,verbs=update;patch
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=bundledeployments,verbs=list;watch;create;update;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=list;watch
+
+type controller struct {
+	cl client.Client
+	// namespace is the namespace of the kubeconfig secrets of the clusters.
+	namespace string
+}
+
+// SetupWithManager sets up the controller of BundleDeploymentSets, which
+// select the kubeconfig secrets in systemNamespace.
+func SetupWithManager(mgr manager.Manager, systemNamespace string) error {
+	c := &controller{cl: mgr.GetClient(), namespace: systemNamespace}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("controller.bundledeploymentset").
+		For(&rukpakv1alpha2.BundleDeploymentSet{}).
+		Owns(&rukpakv1alpha2.BundleDeployment{}).
+		Watches(&corev1.Secret{}, crhandler.EnqueueRequestsFromMapFunc(c.mapSecret)).
+		Complete(c)
+}
+
+// mapSecret enqueues every BundleDeploymentSet for events of the secrets in
+// the namespace of the kubeconfig secrets, since a secret that is created,
+// deleted or relabeled may be selected by any of them.
+func (c *controller) mapSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != c.namespace {
+		return nil
+	}
+	sets := &rukpakv1alpha2.BundleDeploymentSetList{}
+	if err := c.cl.List(ctx, sets); err != nil {
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(sets.Items))
+	for _, set := range sets.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&set)})
+	}
+	return requests
+}
+
+func (c *controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	set := &rukpakv1alpha2.BundleDeploymentSet{}
+	if err := c.cl.Get(ctx, req.NamespacedName, set); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// The BundleDeployments of a deleted set are deleted by the garbage
+	// collector, since the set is their owner.
+	if !set.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	existingStatus := set.Status.DeepCopy()
+	reconcileErr := c.reconcile(ctx, set)
+	set.Status.ObservedGeneration = set.Generation
+	if !equality.Semantic.DeepEqual(*existingStatus, set.Status) {
+		if err := c.cl.Status().Update(ctx, set); err != nil {
+			return ctrl.Result{}, utilerrors.NewAggregate([]error{reconcileErr, err})
+		}
+	}
+	return ctrl.Result{}, reconcileErr
+}
+
+// overlay is a ClusterOverlay with a parsed selector.
+type overlay struct {
+	selector labels.Selector
+	config   []byte
+}
+
+func (c *controller) reconcile(ctx context.Context, set *rukpakv1alpha2.BundleDeploymentSet) error {
+	secrets, overlays, err := c.selectClusters(ctx, set)
+	if err != nil {
+		set.Status.Clusters, set.Status.InstalledClusters, set.Status.ClusterStatuses = 0, 0, nil
+		setRolledOut(set, metav1.ConditionFalse, rukpakv1alpha2.ReasonReconcileFailed, err.Error())
+		return err
+	}
+
+	var (
+		statuses []rukpakv1alpha2.ClusterRolloutStatus
+		errs     []error
+	)
+	names := map[string]struct{}{}
+	for i := range secrets {
+		secret := &secrets[i]
+		bd, err := c.apply(ctx, set, secret, overlays)
+		names[bd.Name] = struct{}{}
+		statuses = append(statuses, clusterStatus(secret.Name, bd, err))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %q: %v", secret.Name, err))
+		}
+	}
+	if err := c.deleteUnselected(ctx, set, names); err != nil {
+		errs = append(errs, err)
+	}
+
+	summarize(set, statuses)
+	return utilerrors.NewAggregate(errs)
+}
+
+// selectClusters returns the kubeconfig secrets of the clusters that set
+// selects, sorted by name, and its overlays.
+func (c *controller) selectClusters(ctx context.Context, set *rukpakv1alpha2.BundleDeploymentSet) ([]corev1.Secret, []overlay, error) {
+	if set.Spec.Template.Target != nil {
+		return nil, nil, reconcile.TerminalError(errors.New("spec.template.target must not be set, since it is set to the cluster of each BundleDeployment"))
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&set.Spec.ClusterSelector)
+	if err != nil {
+		return nil, nil, reconcile.TerminalError(fmt.Errorf("invalid cluster selector: %v", err))
+	}
+	overlays := make([]overlay, 0, len(set.Spec.Overlays))
+	for i, o := range set.Spec.Overlays {
+		overlaySelector, err := metav1.LabelSelectorAsSelector(&o.ClusterSelector)
+		if err != nil {
+			return nil, nil, reconcile.TerminalError(fmt.Errorf("invalid cluster selector of overlay %d: %v", i, err))
+		}
+		overlays = append(overlays, overlay{selector: overlaySelector, config: o.Config.Raw})
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.cl.List(ctx, secrets, client.InNamespace(c.namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, nil, fmt.Errorf("list kubeconfig secrets: %v", err)
+	}
+	sort.Slice(secrets.Items, func(i, j int) bool { return secrets.Items[i].Name < secrets.Items[j].Name })
+	return secrets.Items, overlays, nil
+}
+
+// apply creates or updates the BundleDeployment of the cluster of the given
+// kubeconfig secret. The returned BundleDeployment is named even if it could
+// not be created or updated.
+func (c *controller) apply(ctx context.Context, set *rukpakv1alpha2.BundleDeploymentSet, secret *corev1.Secret, overlays []overlay) (*rukpakv1alpha2.BundleDeployment, error) {
+	bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: bundleDeploymentName(set.Name, secret.Name)}}
+	spec, err := bundleDeploymentSpec(set, secret, overlays)
+	if err != nil {
+		return bd, err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, c.cl, bd, func() error {
+		if bd.ResourceVersion != "" && !metav1.IsControlledBy(bd, set) {
+			return fmt.Errorf("bundledeployment %q already exists and does not belong to the set", bd.Name)
+		}
+		metav1.SetMetaDataLabel(&bd.ObjectMeta, rukpakv1alpha2.BundleDeploymentSetLabel, set.Name)
+		metav1.SetMetaDataLabel(&bd.ObjectMeta, rukpakv1alpha2.TargetClusterLabel, secret.Name)
+		bd.Spec = *spec
+		return controllerutil.SetControllerReference(set, bd, c.cl.Scheme())
+	})
+	return bd, err
+}
+
+// bundleDeploymentSpec returns the spec of the BundleDeployment of the
+// cluster of the given kubeconfig secret: the template of set, which targets
+// the cluster, with the config of the overlays that select the cluster.
+func bundleDeploymentSpec(set *rukpakv1alpha2.BundleDeploymentSet, secret *corev1.Secret, overlays []overlay) (*rukpakv1alpha2.BundleDeploymentSpec, error) {
+	spec := set.Spec.Template.DeepCopy()
+	spec.Target = &rukpakv1alpha2.Target{
+		KubeconfigSecretRef: rukpakv1alpha2.KubeconfigSecretReference{Name: secret.Name, Key: set.Spec.KubeconfigKey},
+	}
+	for i, o := range overlays {
+		if len(o.config) == 0 || !o.selector.Matches(labels.Set(secret.Labels)) {
+			continue
+		}
+		config := spec.Config.Raw
+		if len(config) == 0 {
+			config = []byte("{}")
+		}
+		merged, err := jsonpatch.MergePatch(config, o.config)
+		if err != nil {
+			return nil, fmt.Errorf("apply config of overlay %d: %v", i, err)
+		}
+		spec.Config.Raw = merged
+	}
+	return spec, nil
+}
+
+// bundleDeploymentName returns the name of the BundleDeployment of a set for
+// a cluster. Names that are too long are shortened and suffixed with a hash
+// of the full name, so that they stay unique.
+func bundleDeploymentName(set, cluster string) string {
+	name := set + "-" + cluster
+	if len(name) <= maxBundleDeploymentNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return strings.TrimRight(name[:maxBundleDeploymentNameLength-len(hash)-1], "-.") + "-" + hash
+}
+
+// deleteUnselected deletes the BundleDeployments of set whose cluster is not
+// selected anymore.
+func (c *controller) deleteUnselected(ctx context.Context, set *rukpakv1alpha2.BundleDeploymentSet, selected map[string]struct{}) error {
+	bds := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := c.cl.List(ctx, bds, client.MatchingLabels{rukpakv1alpha2.BundleDeploymentSetLabel: set.Name}); err != nil {
+		return fmt.Errorf("list bundledeployments: %v", err)
+	}
+	var errs []error
+	for i := range bds.Items {
+		bd := &bds.Items[i]
+		if _, ok := selected[bd.Name]; ok || !metav1.IsControlledBy(bd, set) || !bd.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := c.cl.Delete(ctx, bd); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("delete bundledeployment %q: %v", bd.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// clusterStatus returns the rollout status of a cluster from its
+// BundleDeployment, or from the error that creating or updating it failed
+// with.
+func clusterStatus(cluster string, bd *rukpakv1alpha2.BundleDeployment, err error) rukpakv1alpha2.ClusterRolloutStatus {
+	status := rukpakv1alpha2.ClusterRolloutStatus{Cluster: cluster, BundleDeployment: bd.Name, Installed: metav1.ConditionUnknown}
+	if err != nil {
+		status.Installed = metav1.ConditionFalse
+		status.Reason = rukpakv1alpha2.ReasonReconcileFailed
+		status.Message = err.Error()
+		return status
+	}
+	if cond := bd.CurrentCondition(rukpakv1alpha2.TypeInstalled); cond != nil {
+		status.Installed, status.Reason, status.Message = cond.Status, cond.Reason, cond.Message
+	}
+	return status
+}
+
+// summarize sets the aggregated rollout status of set from the rollout
+// statuses of its clusters.
+func summarize(set *rukpakv1alpha2.BundleDeploymentSet, statuses []rukpakv1alpha2.ClusterRolloutStatus) {
+	var installed int32
+	var failed []string
+	for _, s := range statuses {
+		switch s.Installed {
+		case metav1.ConditionTrue:
+			installed++
+		case metav1.ConditionFalse:
+			failed = append(failed, s.Cluster)
+		}
+	}
+	set.Status.Clusters = int32(len(statuses))
+	set.Status.InstalledClusters = installed
+	set.Status.ClusterStatuses = statuses
+
+	switch {
+	case len(statuses) == 0:
+		setRolledOut(set, metav1.ConditionFalse, rukpakv1alpha2.ReasonNoClustersSelected, "no kubeconfig secret matches the cluster selector")
+	case len(failed) > 0:
+		setRolledOut(set, metav1.ConditionFalse, rukpakv1alpha2.ReasonRolloutFailed,
+			fmt.Sprintf("installation failed on %d of %d clusters: %s", len(failed), len(statuses), strings.Join(failed, ", ")))
+	case int(installed) < len(statuses):
+		setRolledOut(set, metav1.ConditionFalse, rukpakv1alpha2.ReasonRolloutProgressing,
+			fmt.Sprintf("installed on %d of %d clusters", installed, len(statuses)))
+	default:
+		setRolledOut(set, metav1.ConditionTrue, rukpakv1alpha2.ReasonRolloutSucceeded,
+			fmt.Sprintf("installed on all %d clusters", len(statuses)))
+	}
+}
+
+func setRolledOut(set *rukpakv1alpha2.BundleDeploymentSet, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&set.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeRolledOut,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: set.Generation,
+	})
+}
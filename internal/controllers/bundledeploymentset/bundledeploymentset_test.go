@@ -0,0 +1,214 @@
+package bundledeploymentset
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func kubeconfigSecret(namespace, name string, labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Data:       map[string][]byte{"value": []byte("kubeconfig")},
+	}
+}
+
+func newController(t *testing.T, objs ...client.Object) (*controller, client.Client) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&rukpakv1alpha2.BundleDeploymentSet{}, &rukpakv1alpha2.BundleDeployment{}).
+		Build()
+	return &controller{cl: cl, namespace: "rukpak-system"}, cl
+}
+
+func setInstalled(t *testing.T, cl client.Client, name string, status metav1.ConditionStatus, reason string) {
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: name}, bd))
+	meta.SetStatusCondition(&bd.Status.Conditions, metav1.Condition{
+		Type:               rukpakv1alpha2.TypeInstalled,
+		Status:             status,
+		Reason:             reason,
+		ObservedGeneration: bd.Generation,
+	})
+	require.NoError(t, cl.Status().Update(context.Background(), bd))
+}
+
+func TestReconcile(t *testing.T) {
+	set := &rukpakv1alpha2.BundleDeploymentSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator"},
+		Spec: rukpakv1alpha2.BundleDeploymentSetSpec{
+			ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			Template: rukpakv1alpha2.BundleDeploymentSpec{
+				InstallNamespace:     "operator-system",
+				ProvisionerClassName: "core-rukpak-io-helm",
+				Source: rukpakv1alpha2.BundleSource{
+					Type:  rukpakv1alpha2.SourceTypeImage,
+					Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/operator/bundle:v1"},
+				},
+				Config: runtime.RawExtension{Raw: []byte(`{"region":"none","replicas":1}`)},
+			},
+			Overlays: []rukpakv1alpha2.ClusterOverlay{
+				{
+					ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}},
+					Config:          runtime.RawExtension{Raw: []byte(`{"region":"eu"}`)},
+				},
+				{
+					ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"cluster.x-k8s.io/cluster-name": "paris"}},
+					Config:          runtime.RawExtension{Raw: []byte(`{"replicas":null}`)},
+				},
+			},
+		},
+	}
+	paris := kubeconfigSecret("rukpak-system", "paris-kubeconfig", map[string]string{"env": "prod", "region": "eu", "cluster.x-k8s.io/cluster-name": "paris"})
+	c, cl := newController(t,
+		set,
+		paris,
+		kubeconfigSecret("rukpak-system", "ohio-kubeconfig", map[string]string{"env": "prod", "region": "us"}),
+		kubeconfigSecret("rukpak-system", "dev-kubeconfig", map[string]string{"env": "dev"}),
+		kubeconfigSecret("other", "stray-kubeconfig", map[string]string{"env": "prod"}),
+	)
+	ctx := context.Background()
+	reconcileSet := func() *rukpakv1alpha2.BundleDeploymentSet {
+		_, err := c.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Name: "operator"}})
+		require.NoError(t, err)
+		got := &rukpakv1alpha2.BundleDeploymentSet{}
+		require.NoError(t, cl.Get(ctx, client.ObjectKey{Name: "operator"}, got))
+		return got
+	}
+
+	got := reconcileSet()
+	bds := &rukpakv1alpha2.BundleDeploymentList{}
+	require.NoError(t, cl.List(ctx, bds))
+	require.Len(t, bds.Items, 2, "only the kubeconfig secrets of the system namespace are clusters")
+	configs := map[string]string{}
+	for _, bd := range bds.Items {
+		require.True(t, metav1.IsControlledBy(&bd, got))
+		require.Equal(t, "operator", bd.Labels[rukpakv1alpha2.BundleDeploymentSetLabel])
+		require.Equal(t, bd.Name, "operator-"+bd.Labels[rukpakv1alpha2.TargetClusterLabel])
+		require.Equal(t, bd.Labels[rukpakv1alpha2.TargetClusterLabel], bd.Spec.Target.KubeconfigSecretRef.Name)
+		require.Equal(t, "quay.io/operator/bundle:v1", bd.Spec.Source.Image.Ref)
+		configs[bd.Name] = string(bd.Spec.Config.Raw)
+	}
+	require.Equal(t, map[string]string{
+		"operator-ohio-kubeconfig":  `{"region":"none","replicas":1}`,
+		"operator-paris-kubeconfig": `{"region":"eu"}`,
+	}, configs, "the overlays that select a cluster are merged into the config in order")
+	require.Equal(t, int32(2), got.Status.Clusters)
+	require.Equal(t, int32(0), got.Status.InstalledClusters)
+	cond := meta.FindStatusCondition(got.Status.Conditions, rukpakv1alpha2.TypeRolledOut)
+	require.Equal(t, rukpakv1alpha2.ReasonRolloutProgressing, cond.Reason)
+	require.Equal(t, metav1.ConditionUnknown, got.Status.ClusterStatuses[0].Installed)
+
+	setInstalled(t, cl, "operator-ohio-kubeconfig", metav1.ConditionTrue, rukpakv1alpha2.ReasonInstallationSucceeded)
+	setInstalled(t, cl, "operator-paris-kubeconfig", metav1.ConditionFalse, rukpakv1alpha2.ReasonInstallFailed)
+	got = reconcileSet()
+	require.Equal(t, int32(1), got.Status.InstalledClusters)
+	cond = meta.FindStatusCondition(got.Status.Conditions, rukpakv1alpha2.TypeRolledOut)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, rukpakv1alpha2.ReasonRolloutFailed, cond.Reason)
+	require.Equal(t, "installation failed on 1 of 2 clusters: paris-kubeconfig", cond.Message)
+	require.Equal(t, []rukpakv1alpha2.ClusterRolloutStatus{
+		{Cluster: "ohio-kubeconfig", BundleDeployment: "operator-ohio-kubeconfig", Installed: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded},
+		{Cluster: "paris-kubeconfig", BundleDeployment: "operator-paris-kubeconfig", Installed: metav1.ConditionFalse, Reason: rukpakv1alpha2.ReasonInstallFailed},
+	}, got.Status.ClusterStatuses)
+
+	setInstalled(t, cl, "operator-paris-kubeconfig", metav1.ConditionTrue, rukpakv1alpha2.ReasonInstallationSucceeded)
+	got = reconcileSet()
+	cond = meta.FindStatusCondition(got.Status.Conditions, rukpakv1alpha2.TypeRolledOut)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+	require.Equal(t, "installed on all 2 clusters", cond.Message)
+
+	paris.Labels["env"] = "dev"
+	require.NoError(t, cl.Update(ctx, paris))
+	got = reconcileSet()
+	err := cl.Get(ctx, client.ObjectKey{Name: "operator-paris-kubeconfig"}, &rukpakv1alpha2.BundleDeployment{})
+	require.True(t, apierrors.IsNotFound(err), "the BundleDeployment of a cluster that is not selected anymore is deleted")
+	require.Equal(t, int32(1), got.Status.Clusters)
+}
+
+func TestReconcileErrors(t *testing.T) {
+	template := rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "operator-system", ProvisionerClassName: "core-rukpak-io-plain"}
+	for _, tc := range []struct {
+		name           string
+		spec           rukpakv1alpha2.BundleDeploymentSetSpec
+		objs           []client.Object
+		expectTerminal bool
+		expectErr      string
+		expectReason   string
+	}{
+		{
+			name: "template with target",
+			spec: rukpakv1alpha2.BundleDeploymentSetSpec{
+				Template: rukpakv1alpha2.BundleDeploymentSpec{
+					Target: &rukpakv1alpha2.Target{KubeconfigSecretRef: rukpakv1alpha2.KubeconfigSecretReference{Name: "workload-kubeconfig"}},
+				},
+			},
+			expectTerminal: true,
+			expectErr:      "spec.template.target must not be set",
+			expectReason:   rukpakv1alpha2.ReasonReconcileFailed,
+		},
+		{
+			name: "invalid selector",
+			spec: rukpakv1alpha2.BundleDeploymentSetSpec{
+				ClusterSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Near"}}},
+				Template:        template,
+			},
+			expectTerminal: true,
+			expectErr:      "invalid cluster selector",
+			expectReason:   rukpakv1alpha2.ReasonReconcileFailed,
+		},
+		{
+			name: "BundleDeployment of another owner",
+			spec: rukpakv1alpha2.BundleDeploymentSetSpec{Template: template},
+			objs: []client.Object{
+				kubeconfigSecret("rukpak-system", "workload-kubeconfig", nil),
+				&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "operator-workload-kubeconfig"}, Spec: template},
+			},
+			expectErr:    `bundledeployment "operator-workload-kubeconfig" already exists and does not belong to the set`,
+			expectReason: rukpakv1alpha2.ReasonRolloutFailed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			set := &rukpakv1alpha2.BundleDeploymentSet{ObjectMeta: metav1.ObjectMeta{Name: "operator"}, Spec: tc.spec}
+			c, cl := newController(t, append(tc.objs, set)...)
+			_, err := c.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "operator"}})
+			require.ErrorContains(t, err, tc.expectErr)
+			require.Equal(t, tc.expectTerminal, errors.Is(err, reconcile.TerminalError(nil)))
+
+			got := &rukpakv1alpha2.BundleDeploymentSet{}
+			require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "operator"}, got))
+			cond := meta.FindStatusCondition(got.Status.Conditions, rukpakv1alpha2.TypeRolledOut)
+			require.Equal(t, metav1.ConditionFalse, cond.Status)
+			require.Equal(t, tc.expectReason, cond.Reason)
+		})
+	}
+}
+
+func TestBundleDeploymentName(t *testing.T) {
+	require.Equal(t, "operator-workload-kubeconfig", bundleDeploymentName("operator", "workload-kubeconfig"))
+
+	long := bundleDeploymentName("monitoring-operator", "production-eu-west-1-workload-kubeconfig")
+	require.Len(t, long, maxBundleDeploymentNameLength)
+	require.True(t, strings.HasPrefix(long, "monitoring-operator-production-eu-west-1-w"))
+	require.NotEqual(t, long, bundleDeploymentName("monitoring-operator", "production-eu-west-1-workload-kubeconfig2"),
+		"shortened names of different clusters differ")
+}
@@ -0,0 +1,199 @@
+// Package coreconfig implements the versioned configuration file of the core
+// provisioner, which configures it declaratively instead of with command line
+// flags.
+package coreconfig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	APIVersion = "config.core.rukpak.io/v1alpha1"
+	Kind       = "CoreConfiguration"
+)
+
+// Configuration is the configuration file of the core provisioner. Every
+// field corresponds to a command line flag, which takes precedence over the
+// field when it is set as well. Unset fields leave the flag at its default.
+//
+// LogLevel and MaxConcurrentReconciles are reloaded when the file changes.
+// Changes of any other field take effect on the next restart.
+type Configuration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// LogLevel is the verbosity of the logs: debug, info, error, or an
+	// integer greater than zero for increasingly verbose debug logs.
+	LogLevel string `json:"logLevel,omitempty"`
+	// MaxConcurrentReconciles limits the number of BundleDeployments that are
+	// reconciled concurrently by all provisioners of the process.
+	MaxConcurrentReconciles *int `json:"maxConcurrentReconciles,omitempty"`
+
+	SystemNamespace        string           `json:"systemNamespace,omitempty"`
+	LeaderElection         *bool            `json:"leaderElection,omitempty"`
+	HealthProbeBindAddress string           `json:"healthProbeBindAddress,omitempty"`
+	KubeAPI                ClientConnection `json:"kubeAPI,omitempty"`
+
+	HTTP    HTTP    `json:"http,omitempty"`
+	Storage Storage `json:"storage,omitempty"`
+	Unpack  Unpack  `json:"unpack,omitempty"`
+	Helm    Helm    `json:"helm,omitempty"`
+
+	ReconcileBudget     *metav1.Duration `json:"reconcileBudget,omitempty"`
+	ShutdownGracePeriod *metav1.Duration `json:"shutdownGracePeriod,omitempty"`
+	ChartCacheSize      *int             `json:"chartCacheSize,omitempty"`
+}
+
+// ClientConnection configures a client of the apiserver.
+type ClientConnection struct {
+	QPS   *float64 `json:"qps,omitempty"`
+	Burst *int     `json:"burst,omitempty"`
+}
+
+// HTTP configures the server that serves bundle content.
+type HTTP struct {
+	BindAddress             string `json:"bindAddress,omitempty"`
+	ExternalAddress         string `json:"externalAddress,omitempty"`
+	DiscoverExternalAddress *bool  `json:"discoverExternalAddress,omitempty"`
+	BundleCAFile            string `json:"bundleCAFile,omitempty"`
+}
+
+// Storage configures the storage of unpacked bundle content.
+type Storage struct {
+	Directory        string           `json:"directory,omitempty"`
+	Compression      string           `json:"compression,omitempty"`
+	DisableFinalizer *bool            `json:"disableFinalizer,omitempty"`
+	GCInterval       *metav1.Duration `json:"gcInterval,omitempty"`
+	RetainedSources  *int             `json:"retainedSources,omitempty"`
+	// MaxBundleSize limits the total size of the files of the bundle
+	// content of each BundleDeployment.
+	MaxBundleSize *resource.Quantity `json:"maxBundleSize,omitempty"`
+}
+
+// Unpack configures how bundle sources are unpacked.
+type Unpack struct {
+	CacheDir     string `json:"cacheDir,omitempty"`
+	NodeImageURL string `json:"nodeImageURL,omitempty"`
+}
+
+// Helm configures the Helm releases of BundleDeployments.
+type Helm struct {
+	MaxHistory        *int             `json:"maxHistory,omitempty"`
+	Client            ClientConnection `json:"client,omitempty"`
+	TestTimeout       *metav1.Duration `json:"testTimeout,omitempty"`
+	ReleaseGCInterval *metav1.Duration `json:"releaseGCInterval,omitempty"`
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*Configuration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read configuration: %v", err)
+	}
+	return parse(data)
+}
+
+func parse(data []byte) (*Configuration, error) {
+	cfg := &Configuration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse configuration: %v", err)
+	}
+	if cfg.APIVersion != APIVersion || cfg.Kind != Kind {
+		return nil, fmt.Errorf("unsupported configuration %s, %s: expected %s, %s", cfg.APIVersion, cfg.Kind, APIVersion, Kind)
+	}
+	if cfg.LogLevel != "" {
+		if _, err := ParseLogLevel(cfg.LogLevel); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.MaxConcurrentReconciles != nil && *cfg.MaxConcurrentReconciles < 1 {
+		return nil, fmt.Errorf("maxConcurrentReconciles must be at least 1")
+	}
+	return cfg, nil
+}
+
+// ApplyToFlags sets the flags of fs to the values of the configuration,
+// except for flags that were set on the command line already.
+func (c *Configuration) ApplyToFlags(fs *pflag.FlagSet) error {
+	for _, v := range c.flagValues() {
+		if fs.Changed(v.name) {
+			continue
+		}
+		if err := fs.Set(v.name, v.value); err != nil {
+			return fmt.Errorf("apply configuration to flag --%s: %v", v.name, err)
+		}
+	}
+	return nil
+}
+
+type flagValue struct {
+	name  string
+	value string
+}
+
+// flagValues returns the flag values of the fields that are set.
+func (c *Configuration) flagValues() []flagValue {
+	var values []flagValue
+	str := func(name, value string) {
+		if value != "" {
+			values = append(values, flagValue{name, value})
+		}
+	}
+	boolean := func(name string, value *bool) {
+		if value != nil {
+			str(name, strconv.FormatBool(*value))
+		}
+	}
+	integer := func(name string, value *int) {
+		if value != nil {
+			str(name, strconv.Itoa(*value))
+		}
+	}
+	float := func(name string, value *float64) {
+		if value != nil {
+			str(name, strconv.FormatFloat(*value, 'f', -1, 64))
+		}
+	}
+	duration := func(name string, value *metav1.Duration) {
+		if value != nil {
+			str(name, value.Duration.String())
+		}
+	}
+
+	str("zap-log-level", c.LogLevel)
+	integer("max-concurrent-reconciles", c.MaxConcurrentReconciles)
+	str("system-namespace", c.SystemNamespace)
+	boolean("leader-elect", c.LeaderElection)
+	str("health-probe-bind-address", c.HealthProbeBindAddress)
+	float("kube-api-qps", c.KubeAPI.QPS)
+	integer("kube-api-burst", c.KubeAPI.Burst)
+	str("http-bind-address", c.HTTP.BindAddress)
+	str("http-external-address", c.HTTP.ExternalAddress)
+	boolean("discover-http-external-address", c.HTTP.DiscoverExternalAddress)
+	str("bundle-ca-file", c.HTTP.BundleCAFile)
+	str("provisioner-storage-dir", c.Storage.Directory)
+	str("storage-compression", c.Storage.Compression)
+	boolean("disable-storage-finalizer", c.Storage.DisableFinalizer)
+	duration("storage-gc-interval", c.Storage.GCInterval)
+	integer("storage-retained-sources", c.Storage.RetainedSources)
+	if c.Storage.MaxBundleSize != nil {
+		str("storage-max-bundle-size", c.Storage.MaxBundleSize.String())
+	}
+	str("unpack-cache-dir", c.Unpack.CacheDir)
+	str("node-image-url", c.Unpack.NodeImageURL)
+	integer("helm-max-history", c.Helm.MaxHistory)
+	float("helm-client-qps", c.Helm.Client.QPS)
+	integer("helm-client-burst", c.Helm.Client.Burst)
+	duration("test-timeout", c.Helm.TestTimeout)
+	duration("release-gc-interval", c.Helm.ReleaseGCInterval)
+	duration("reconcile-budget", c.ReconcileBudget)
+	duration("shutdown-grace-period", c.ShutdownGracePeriod)
+	integer("chart-cache-size", c.ChartCacheSize)
+	return values
+}
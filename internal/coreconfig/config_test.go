@@ -0,0 +1,93 @@
+package coreconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestApplyToFlags(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	systemNamespace := fs.String("system-namespace", "", "")
+	maxHistory := fs.Int("helm-max-history", 10, "")
+	storageGCInterval := fs.Duration("storage-gc-interval", 10*time.Minute, "")
+	disableStorageFinalizer := fs.Bool("disable-storage-finalizer", false, "")
+	kubeAPIQPS := fs.Float64("kube-api-qps", 20, "")
+	chartCacheSize := fs.Int("chart-cache-size", 64, "")
+	shutdownGracePeriod := fs.Duration("shutdown-grace-period", 20*time.Second, "")
+	storageMaxBundleSize := fs.String("storage-max-bundle-size", "", "")
+	require.NoError(t, fs.Parse([]string{"--helm-max-history=5"}))
+
+	cfg, err := parse([]byte(`
+apiVersion: config.core.rukpak.io/v1alpha1
+kind: CoreConfiguration
+systemNamespace: rukpak-system
+storage:
+  disableFinalizer: true
+  gcInterval: 1m
+  maxBundleSize: 512Mi
+kubeAPI:
+  qps: 12.5
+helm:
+  maxHistory: 3
+shutdownGracePeriod: 2m
+`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.ApplyToFlags(fs))
+
+	require.Equal(t, "rukpak-system", *systemNamespace)
+	require.Equal(t, 5, *maxHistory, "flags set on the command line take precedence")
+	require.Equal(t, time.Minute, *storageGCInterval)
+	require.True(t, *disableStorageFinalizer)
+	require.Equal(t, 12.5, *kubeAPIQPS)
+	require.Equal(t, 64, *chartCacheSize, "unset fields keep the default")
+	require.Equal(t, 2*time.Minute, *shutdownGracePeriod)
+	require.Equal(t, "512Mi", *storageMaxBundleSize)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		data      string
+		expectErr string
+	}{
+		{
+			name:      "wrong kind",
+			data:      "apiVersion: config.core.rukpak.io/v1alpha1\nkind: HelmConfiguration\n",
+			expectErr: "unsupported configuration",
+		},
+		{
+			name:      "unknown field",
+			data:      "apiVersion: config.core.rukpak.io/v1alpha1\nkind: CoreConfiguration\nmaxHistory: 3\n",
+			expectErr: `unknown field "maxHistory"`,
+		},
+		{
+			name:      "invalid log level",
+			data:      "apiVersion: config.core.rukpak.io/v1alpha1\nkind: CoreConfiguration\nlogLevel: verbose\n",
+			expectErr: `invalid log level "verbose"`,
+		},
+		{
+			name:      "no concurrency",
+			data:      "apiVersion: config.core.rukpak.io/v1alpha1\nkind: CoreConfiguration\nmaxConcurrentReconciles: 0\n",
+			expectErr: "maxConcurrentReconciles must be at least 1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parse([]byte(tc.data))
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := ParseLogLevel("info")
+	require.NoError(t, err)
+	require.Equal(t, zapcore.InfoLevel, level)
+
+	level, err = ParseLogLevel("3")
+	require.NoError(t, err)
+	require.Equal(t, zapcore.Level(-3), level)
+}
@@ -0,0 +1,107 @@
+package coreconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var _ manager.LeaderElectionRunnable = &Watcher{}
+
+// Watcher reloads the configuration file when it changes, and hands the
+// configuration to Reload. The file is polled rather than watched, so that
+// updates of mounted ConfigMaps, which replace a symlink, are noticed too.
+//
+// Reload is only called with configurations that are valid. Changes of fields
+// that are not reloaded are logged, since they require a restart.
+type Watcher struct {
+	Path     string
+	Interval time.Duration
+	Reload   func(*Configuration)
+	Log      logr.Logger
+
+	data   []byte
+	config *Configuration
+}
+
+// NewWatcher returns a watcher for the file at path, whose current content
+// is cfg.
+func NewWatcher(path string, cfg *Configuration, reload func(*Configuration), log logr.Logger) (*Watcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read configuration: %v", err)
+	}
+	return &Watcher{Path: path, Interval: 10 * time.Second, Reload: reload, Log: log, data: data, config: cfg}, nil
+}
+
+// NeedLeaderElection returns false, since every replica reloads its own
+// configuration.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *Watcher) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(_ context.Context) {
+		w.check()
+	}, w.Interval)
+	return nil
+}
+
+func (w *Watcher) check() {
+	data, err := os.ReadFile(w.Path)
+	if err != nil {
+		w.Log.Error(err, "failed to read configuration file", "path", w.Path)
+		return
+	}
+	if bytes.Equal(data, w.data) {
+		return
+	}
+	w.data = data
+	cfg, err := parse(data)
+	if err != nil {
+		w.Log.Error(err, "ignoring invalid configuration file", "path", w.Path)
+		return
+	}
+	if !reflect.DeepEqual(withoutReloadable(cfg), withoutReloadable(w.config)) {
+		w.Log.Info("configuration file changed fields that only take effect after a restart", "path", w.Path)
+	}
+	w.config = cfg
+	w.Log.Info("reloading configuration file", "path", w.Path)
+	w.Reload(cfg)
+}
+
+func withoutReloadable(cfg *Configuration) Configuration {
+	out := *cfg
+	out.LogLevel = ""
+	out.MaxConcurrentReconciles = nil
+	return out
+}
+
+// ParseLogLevel parses a log level in the format of the --zap-log-level flag.
+func ParseLogLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	case "panic":
+		return zapcore.PanicLevel, nil
+	}
+	verbosity, err := strconv.Atoi(level)
+	if err != nil || verbosity <= 0 {
+		return 0, fmt.Errorf("invalid log level %q: must be debug, info, error, panic or an integer greater than zero", level)
+	}
+	return zapcore.Level(int8(-verbosity)), nil
+}
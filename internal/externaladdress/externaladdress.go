@@ -0,0 +1,183 @@
+// Package externaladdress discovers the external address of the content
+// server of a provisioner from an annotated Service or Ingress, so that the
+// content URLs of BundleDeployments follow changes of its IP or hostname.
+package externaladdress
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// AnnotationKey marks the Service or Ingress that the external address of
+// the content server is discovered from, when set to "true".
+const AnnotationKey = "core.rukpak.io/content-server"
+
+// Discoverer discovers the external address of the content server from the
+// Service or Ingress in Namespace that is annotated with AnnotationKey.
+//
+// The address of an Ingress is the host of its first rule, or else the
+// hostname or IP of its load balancer, with the https scheme if the Ingress
+// terminates TLS for that host. The address of a Service is the hostname or
+// IP of its load balancer, or else its cluster DNS name, with the port named
+// https, or else its first port.
+type Discoverer struct {
+	// Reader should read from a cache, since the address is discovered
+	// whenever a content URL is built.
+	Reader    client.Reader
+	Namespace string
+}
+
+// Address returns the external address of the content server.
+func (d *Discoverer) Address(ctx context.Context) (*url.URL, error) {
+	var annotated []client.Object
+	ingresses := &networkingv1.IngressList{}
+	if err := d.Reader.List(ctx, ingresses, client.InNamespace(d.Namespace)); err != nil {
+		return nil, fmt.Errorf("list ingresses: %v", err)
+	}
+	for i := range ingresses.Items {
+		if IsAnnotated(&ingresses.Items[i]) {
+			annotated = append(annotated, &ingresses.Items[i])
+		}
+	}
+	services := &corev1.ServiceList{}
+	if err := d.Reader.List(ctx, services, client.InNamespace(d.Namespace)); err != nil {
+		return nil, fmt.Errorf("list services: %v", err)
+	}
+	for i := range services.Items {
+		if IsAnnotated(&services.Items[i]) {
+			annotated = append(annotated, &services.Items[i])
+		}
+	}
+
+	switch len(annotated) {
+	case 0:
+		return nil, fmt.Errorf("no service or ingress in namespace %q is annotated with %s=true", d.Namespace, AnnotationKey)
+	case 1:
+	default:
+		return nil, fmt.Errorf("found %d services and ingresses in namespace %q annotated with %s=true, expected one", len(annotated), d.Namespace, AnnotationKey)
+	}
+	switch obj := annotated[0].(type) {
+	case *networkingv1.Ingress:
+		return ingressAddress(obj)
+	case *corev1.Service:
+		return serviceAddress(obj)
+	}
+	return nil, fmt.Errorf("unexpected object type %T", annotated[0])
+}
+
+func ingressAddress(ing *networkingv1.Ingress) (*url.URL, error) {
+	host := ""
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host != "" {
+			host = rule.Host
+			break
+		}
+	}
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if host != "" {
+			break
+		}
+		host = firstNonEmpty(lb.Hostname, lb.IP)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("ingress %q has neither a host nor a load balancer address yet", ing.Name)
+	}
+	scheme := "http"
+	for _, tls := range ing.Spec.TLS {
+		if len(tls.Hosts) == 0 || contains(tls.Hosts, host) {
+			scheme = "https"
+			break
+		}
+	}
+	return &url.URL{Scheme: scheme, Host: host}, nil
+}
+
+func serviceAddress(svc *corev1.Service) (*url.URL, error) {
+	if len(svc.Spec.Ports) == 0 {
+		return nil, fmt.Errorf("service %q has no ports", svc.Name)
+	}
+	port := svc.Spec.Ports[0]
+	for _, p := range svc.Spec.Ports {
+		if p.Name == "https" {
+			port = p
+			break
+		}
+	}
+
+	host := fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace)
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		host = ""
+		for _, lb := range svc.Status.LoadBalancer.Ingress {
+			if host = firstNonEmpty(lb.Hostname, lb.IP); host != "" {
+				break
+			}
+		}
+		if host == "" {
+			return nil, fmt.Errorf("service %q has no load balancer address yet", svc.Name)
+		}
+	}
+	scheme := "http"
+	if port.Name == "https" || port.Port == 443 || (port.AppProtocol != nil && *port.AppProtocol == "https") {
+		scheme = "https"
+	}
+	if (scheme == "https" && port.Port != 443) || (scheme == "http" && port.Port != 80) {
+		host = net.JoinHostPort(host, strconv.Itoa(int(port.Port)))
+	}
+	return &url.URL{Scheme: scheme, Host: host}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func contains(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAnnotated reports whether obj is annotated as the object that the
+// external address of the content server is discovered from.
+func IsAnnotated(obj client.Object) bool {
+	return obj.GetAnnotations()[AnnotationKey] == "true"
+}
+
+// Predicate passes events of objects in namespace that are, or were before
+// an update, annotated with AnnotationKey, i.e. the events that may change
+// the discovered address.
+func Predicate(namespace string) predicate.Predicate {
+	inNamespace := func(obj client.Object) bool {
+		return obj.GetNamespace() == namespace
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return inNamespace(e.Object) && IsAnnotated(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return inNamespace(e.ObjectNew) && (IsAnnotated(e.ObjectOld) || IsAnnotated(e.ObjectNew))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return inNamespace(e.Object) && IsAnnotated(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return inNamespace(e.Object) && IsAnnotated(e.Object)
+		},
+	}
+}
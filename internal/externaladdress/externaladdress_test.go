@@ -0,0 +1,112 @@
+package externaladdress
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func annotated(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Namespace: "rukpak-system", Name: name, Annotations: map[string]string{AnnotationKey: "true"}}
+}
+
+func TestAddress(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		objects     []client.Object
+		expected    string
+		expectedErr string
+	}{
+		{
+			description: "ingress with TLS host",
+			objects: []client.Object{&networkingv1.Ingress{
+				ObjectMeta: annotated("core"),
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{{Host: "rukpak.example.com"}},
+					TLS:   []networkingv1.IngressTLS{{Hosts: []string{"rukpak.example.com"}}},
+				},
+			}},
+			expected: "https://rukpak.example.com",
+		},
+		{
+			description: "ingress with load balancer address",
+			objects: []client.Object{&networkingv1.Ingress{
+				ObjectMeta: annotated("core"),
+				Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+					Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: "203.0.113.7"}},
+				}},
+			}},
+			expected: "http://203.0.113.7",
+		},
+		{
+			description: "ingress without address",
+			objects:     []client.Object{&networkingv1.Ingress{ObjectMeta: annotated("core")}},
+			expectedErr: `ingress "core" has neither a host nor a load balancer address yet`,
+		},
+		{
+			description: "cluster IP service",
+			objects: []client.Object{&corev1.Service{
+				ObjectMeta: annotated("core"),
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+					{Name: "metrics", Port: 8080},
+					{Name: "https", Port: 443},
+				}},
+			}},
+			expected: "https://core.rukpak-system.svc",
+		},
+		{
+			description: "load balancer service with non-default port",
+			objects: []client.Object{&corev1.Service{
+				ObjectMeta: annotated("core"),
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 8080}},
+				},
+				Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+					Ingress: []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}},
+				}},
+			}},
+			expected: "http://lb.example.com:8080",
+		},
+		{
+			description: "no annotated object in the namespace",
+			objects: []client.Object{
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "core"}},
+				&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other", Annotations: map[string]string{AnnotationKey: "true"}},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 443}}},
+				},
+			},
+			expectedErr: `no service or ingress in namespace "rukpak-system" is annotated with core.rukpak.io/content-server=true`,
+		},
+		{
+			description: "more than one annotated object",
+			objects: []client.Object{
+				&corev1.Service{ObjectMeta: annotated("core"), Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 443}}}},
+				&networkingv1.Ingress{ObjectMeta: annotated("core")},
+			},
+			expectedErr: "found 2 services and ingresses",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			d := &Discoverer{
+				Reader:    fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.objects...).Build(),
+				Namespace: "rukpak-system",
+			}
+			addr, err := d.Address(context.Background())
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, addr.String())
+		})
+	}
+}
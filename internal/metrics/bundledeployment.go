@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+var (
+	unpackFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rukpak_unpack_failures_total",
+		Help: "The number of reconciles that failed to unpack the source of a BundleDeployment. Broken down by source type and the reason of the Unpacked condition.",
+	}, []string{"source_type", "reason"})
+
+	lastSuccessfulInstall = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rukpak_bundledeployment_last_successful_install_timestamp",
+		Help: "The time of the most recent successful install or upgrade of the release of a BundleDeployment, in seconds since the epoch.",
+	}, []string{"name"})
+
+	renderedManifestBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rukpak_bundledeployment_rendered_manifest_bytes",
+		Help: "The size of the manifest that the bundle of a BundleDeployment was most recently rendered to, in bytes.",
+	}, []string{"name"})
+
+	renderedObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rukpak_bundledeployment_rendered_objects",
+		Help: "The number of objects that the bundle of a BundleDeployment was most recently rendered to.",
+	}, []string{"name"})
+)
+
+// RegisterBundleDeploymentMetrics registers the metrics that the
+// BundleDeployment controllers record while they reconcile, so that
+// dashboards can break down unpack failures by source type and track how long
+// ago BundleDeployments were last installed or upgraded, and which bundles
+// render to the largest manifests.
+func RegisterBundleDeploymentMetrics(registry prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{unpackFailures, lastSuccessfulInstall, renderedManifestBytes, renderedObjects} {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordUnpackFailure counts a failure to unpack a source of the given type,
+// which set the Unpacked condition with reason.
+func RecordUnpackFailure(sourceType rukpakv1alpha2.SourceType, reason string) {
+	unpackFailures.WithLabelValues(string(sourceType), reason).Inc()
+}
+
+// RecordSuccessfulInstall records when the release of the named
+// BundleDeployment was last installed or upgraded successfully.
+func RecordSuccessfulInstall(name string, deployed time.Time) {
+	lastSuccessfulInstall.WithLabelValues(name).Set(float64(deployed.Unix()))
+}
+
+// RecordRenderedManifest records the size and the number of objects of the
+// manifest that the bundle of the named BundleDeployment was rendered to.
+// Large manifests dominate the release storage, the time it takes to apply
+// the release and the memory of the watch caches.
+func RecordRenderedManifest(name string, bytes, objects int) {
+	renderedManifestBytes.WithLabelValues(name).Set(float64(bytes))
+	renderedObjects.WithLabelValues(name).Set(float64(objects))
+}
+
+// ForgetBundleDeployment drops the series of a BundleDeployment that no
+// longer exists.
+func ForgetBundleDeployment(name string) {
+	lastSuccessfulInstall.DeleteLabelValues(name)
+	renderedManifestBytes.DeleteLabelValues(name)
+	renderedObjects.DeleteLabelValues(name)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestBundleDeploymentMetrics(t *testing.T) {
+	unpackFailures.Reset()
+	lastSuccessfulInstall.Reset()
+	renderedManifestBytes.Reset()
+	renderedObjects.Reset()
+	registry := prometheus.NewRegistry()
+	require.NoError(t, RegisterBundleDeploymentMetrics(registry))
+
+	RecordUnpackFailure(rukpakv1alpha2.SourceTypeImage, rukpakv1alpha2.ReasonUnpackFailed)
+	RecordUnpackFailure(rukpakv1alpha2.SourceTypeImage, rukpakv1alpha2.ReasonUnpackFailed)
+	RecordUnpackFailure(rukpakv1alpha2.SourceTypeGit, rukpakv1alpha2.ReasonUnpackFailed)
+	require.Equal(t, 2.0, testutil.ToFloat64(unpackFailures.WithLabelValues("image", rukpakv1alpha2.ReasonUnpackFailed)))
+	require.Equal(t, 1.0, testutil.ToFloat64(unpackFailures.WithLabelValues("git", rukpakv1alpha2.ReasonUnpackFailed)))
+
+	RecordSuccessfulInstall("a", time.Unix(1700000000, 0))
+	RecordSuccessfulInstall("b", time.Unix(1700000100, 0))
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP rukpak_bundledeployment_last_successful_install_timestamp The time of the most recent successful install or upgrade of the release of a BundleDeployment, in seconds since the epoch.
+# TYPE rukpak_bundledeployment_last_successful_install_timestamp gauge
+rukpak_bundledeployment_last_successful_install_timestamp{name="a"} 1.7e+09
+rukpak_bundledeployment_last_successful_install_timestamp{name="b"} 1.7000001e+09
+`), "rukpak_bundledeployment_last_successful_install_timestamp"))
+
+	RecordRenderedManifest("a", 2048, 12)
+	RecordRenderedManifest("a", 4096, 20)
+	require.Equal(t, 4096.0, testutil.ToFloat64(renderedManifestBytes.WithLabelValues("a")))
+	require.Equal(t, 20.0, testutil.ToFloat64(renderedObjects.WithLabelValues("a")))
+
+	ForgetBundleDeployment("a")
+	count, err := testutil.GatherAndCount(registry, "rukpak_bundledeployment_last_successful_install_timestamp")
+	require.NoError(t, err)
+	require.Equal(t, 1, count, "the series of deleted BundleDeployments are dropped")
+	count, err = testutil.GatherAndCount(registry, "rukpak_bundledeployment_rendered_manifest_bytes", "rukpak_bundledeployment_rendered_objects")
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+const listTimeout = 10 * time.Second
+
+var (
+	conditionDesc = prometheus.NewDesc(
+		"rukpak_bundledeployment_status_condition",
+		"The condition of a BundleDeployment. For every condition, one series per status is reported, with a value of 1 for the current status and 0 otherwise.",
+		[]string{"name", "type", "status", "reason"},
+		nil,
+	)
+
+	backoffDesc = prometheus.NewDesc(
+		"rukpak_bundledeployments_in_backoff",
+		"The number of BundleDeployments whose last reconcile failed and that wait for the retry, according to their status.nextReconcileTime.",
+		nil,
+		nil,
+	)
+
+	conditionStatuses = []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionUnknown}
+)
+
+// ConditionCollector is a prometheus.Collector that reports the conditions of
+// BundleDeployments in the style of kube-state-metrics, so that alerting and
+// recording rules can key off conditions such as Installed or Healthy. It
+// also reports how many BundleDeployments back off before their failed
+// reconcile is retried.
+//
+// The conditions are read when metrics are collected, so the reader should be
+// backed by a cache.
+type ConditionCollector struct {
+	reader         client.Reader
+	provisionerIDs map[string]struct{}
+	now            func() time.Time
+}
+
+// NewConditionCollector returns a collector for the conditions of the
+// BundleDeployments that are reconciled by the given provisioners.
+func NewConditionCollector(reader client.Reader, provisionerIDs ...string) *ConditionCollector {
+	c := &ConditionCollector{reader: reader, provisionerIDs: map[string]struct{}{}, now: time.Now}
+	for _, id := range provisionerIDs {
+		c.provisionerIDs[id] = struct{}{}
+	}
+	return c
+}
+
+func (c *ConditionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- conditionDesc
+	ch <- backoffDesc
+}
+
+func (c *ConditionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	bds := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := c.reader.List(ctx, bds); err != nil {
+		ch <- prometheus.NewInvalidMetric(conditionDesc, err)
+		ch <- prometheus.NewInvalidMetric(backoffDesc, err)
+		return
+	}
+	now := c.now()
+	inBackoff := 0
+	for _, bd := range bds.Items {
+		if _, ok := c.provisionerIDs[bd.Spec.ProvisionerClassName]; !ok {
+			continue
+		}
+		if next := bd.Status.NextReconcileTime; next != nil && next.Time.After(now) {
+			inBackoff++
+		}
+		for _, cond := range bd.Status.Conditions {
+			for _, status := range conditionStatuses {
+				value := 0.0
+				if cond.Status == status {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(conditionDesc, prometheus.GaugeValue, value,
+					bd.Name, cond.Type, strings.ToLower(string(status)), cond.Reason)
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(backoffDesc, prometheus.GaugeValue, float64(inBackoff))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestConditionCollector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	retry := metav1.NewTime(now.Add(time.Minute))
+	expired := metav1.NewTime(now.Add(-time.Minute))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "plain"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{ProvisionerClassName: "core-rukpak-io-plain"},
+			Status: rukpakv1alpha2.BundleDeploymentStatus{Conditions: []metav1.Condition{
+				{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded},
+			}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "failing"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{ProvisionerClassName: "core-rukpak-io-plain"},
+			Status:     rukpakv1alpha2.BundleDeploymentStatus{NextReconcileTime: &retry},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "retried"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{ProvisionerClassName: "core-rukpak-io-plain"},
+			Status:     rukpakv1alpha2.BundleDeploymentStatus{NextReconcileTime: &expired},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "helm"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{ProvisionerClassName: "core-rukpak-io-helm"},
+			Status: rukpakv1alpha2.BundleDeploymentStatus{Conditions: []metav1.Condition{
+				{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionFalse, Reason: rukpakv1alpha2.ReasonInstallFailed},
+			}},
+		},
+	).Build()
+
+	expected := `
+# HELP rukpak_bundledeployment_status_condition The condition of a BundleDeployment. For every condition, one series per status is reported, with a value of 1 for the current status and 0 otherwise.
+# TYPE rukpak_bundledeployment_status_condition gauge
+rukpak_bundledeployment_status_condition{name="plain",reason="InstallationSucceeded",status="false",type="Installed"} 0
+rukpak_bundledeployment_status_condition{name="plain",reason="InstallationSucceeded",status="true",type="Installed"} 1
+rukpak_bundledeployment_status_condition{name="plain",reason="InstallationSucceeded",status="unknown",type="Installed"} 0
+# HELP rukpak_bundledeployments_in_backoff The number of BundleDeployments whose last reconcile failed and that wait for the retry, according to their status.nextReconcileTime.
+# TYPE rukpak_bundledeployments_in_backoff gauge
+rukpak_bundledeployments_in_backoff 1
+`
+	collector := NewConditionCollector(cl, "core-rukpak-io-plain")
+	collector.now = func() time.Time { return now }
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
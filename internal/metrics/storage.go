@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewStorageAvailabilityGauge returns a gauge that is 1 while the bundle
+// storage is available and 0 while writes to it are refused because it is
+// unavailable, e.g. because its disk is full.
+func NewStorageAvailabilityGauge(available func() bool) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "rukpak_bundle_storage_available",
+		Help: "Whether the bundle storage is available (1) or unavailable and backing off (0).",
+	}, func() float64 {
+		if available() {
+			return 1
+		}
+		return 0
+	})
+}
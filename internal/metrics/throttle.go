@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+var rateLimiterLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rest_client_rate_limiter_duration_seconds",
+	Help:    "How long requests to the apiserver waited for the client-side rate limiter, in seconds. Broken down by verb and host.",
+	Buckets: []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+}, []string{"verb", "host"})
+
+// RegisterClientThrottleMetrics registers a histogram of the time that
+// requests of the kube clients of the process spent waiting for their
+// client-side rate limiter, so that QPS and burst settings can be tuned.
+//
+// client-go only accepts its metrics once, and controller-runtime already
+// registers the ones it reports, so the rate limiter metric is set directly.
+func RegisterClientThrottleMetrics(registry prometheus.Registerer) error {
+	if err := registry.Register(rateLimiterLatency); err != nil {
+		return err
+	}
+	clientmetrics.RateLimiterLatency = latencyAdapter{rateLimiterLatency}
+	return nil
+}
+
+type latencyAdapter struct {
+	metric *prometheus.HistogramVec
+}
+
+func (l latencyAdapter) Observe(_ context.Context, verb string, u url.URL, latency time.Duration) {
+	l.metric.WithLabelValues(verb, u.Host).Observe(latency.Seconds())
+}
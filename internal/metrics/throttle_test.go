@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+func TestRegisterClientThrottleMetrics(t *testing.T) {
+	previous := clientmetrics.RateLimiterLatency
+	t.Cleanup(func() { clientmetrics.RateLimiterLatency = previous })
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, RegisterClientThrottleMetrics(registry))
+
+	clientmetrics.RateLimiterLatency.Observe(context.Background(), "POST", url.URL{Host: "10.96.0.1:443"}, 2*time.Second)
+
+	count, err := testutil.GatherAndCount(registry, "rest_client_rate_limiter_duration_seconds")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
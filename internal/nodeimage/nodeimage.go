@@ -0,0 +1,129 @@
+// Package nodeimage serves the content of images from the containerd content
+// store of a node, so that bundles can be unpacked from images that were
+// loaded onto the nodes of a cluster without a reachable registry.
+package nodeimage
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/containerd/containerd/archive"
+	"github.com/containerd/containerd/archive/compression"
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/platforms"
+	"github.com/distribution/reference"
+	"github.com/go-logr/logr"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// Path is the path that images are served at. The image reference is
+	// passed in the ref query parameter.
+	Path = "/images"
+	// DigestHeader is the response header that carries the digest of the
+	// served image.
+	DigestHeader = "Rukpak-Image-Digest"
+)
+
+// Server serves the filesystem of images in the content store of a node as
+// gzipped tar archives. Only images that are present in the image store are
+// served; images are never pulled.
+type Server struct {
+	Images  images.Store
+	Content content.Provider
+	// WorkDir is the directory that images are unpacked into before they
+	// are served.
+	WorkDir string
+	Log     logr.Logger
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	named, err := reference.ParseDockerRef(r.URL.Query().Get("ref"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid image reference: %v", err), http.StatusBadRequest)
+		return
+	}
+	l := s.Log.WithValues("ref", named.String())
+
+	img, err := s.Images.Get(r.Context(), named.String())
+	if errdefs.IsNotFound(err) {
+		http.Error(w, fmt.Sprintf("image %q not found on node", named.String()), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		l.Error(err, "failed to get image")
+		http.Error(w, fmt.Sprintf("get image: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	dir, err := os.MkdirTemp(s.WorkDir, "image-")
+	if err != nil {
+		l.Error(err, "failed to create unpack directory")
+		http.Error(w, fmt.Sprintf("create unpack directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if err := s.unpack(r.Context(), img.Target, dir); err != nil {
+		l.Error(err, "failed to unpack image")
+		http.Error(w, fmt.Sprintf("unpack image: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(DigestHeader, img.Target.Digest.String())
+	w.Header().Set("Content-Type", "application/gzip")
+	if err := util.FSToTarGZ(w, util.DirFS(dir)); err != nil {
+		// The status has already been written, so the client sees a
+		// truncated archive.
+		l.Error(err, "failed to write image content")
+	}
+}
+
+// unpack applies the layers of the image manifest for the platform of the
+// node to dir.
+func (s *Server) unpack(ctx context.Context, target ocispec.Descriptor, dir string) error {
+	manifest, err := images.Manifest(ctx, s.Content, target, platforms.Default())
+	if err != nil {
+		return fmt.Errorf("get image manifest: %v", err)
+	}
+	for _, layer := range manifest.Layers {
+		if err := s.applyLayer(ctx, layer, dir); err != nil {
+			return fmt.Errorf("apply layer %s: %v", layer.Digest, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) applyLayer(ctx context.Context, layer ocispec.Descriptor, dir string) error {
+	ra, err := s.Content.ReaderAt(ctx, layer)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return errors.New("layer content not found, the image may have been unpacked and its content garbage collected")
+		}
+		return err
+	}
+	defer ra.Close()
+	rc, err := compression.DecompressStream(content.NewReader(ra))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = archive.Apply(ctx, dir, rc, archive.WithFilter(func(th *tar.Header) (bool, error) {
+		th.Uid = os.Getuid()
+		th.Gid = os.Getgid()
+		return true, nil
+	}))
+	return err
+}
@@ -0,0 +1,119 @@
+package nodeimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/go-logr/logr"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+type fakeImageStore struct {
+	images.Store
+	images map[string]images.Image
+}
+
+func (s *fakeImageStore) Get(_ context.Context, name string) (images.Image, error) {
+	img, ok := s.images[name]
+	if !ok {
+		return images.Image{}, errdefs.ErrNotFound
+	}
+	return img, nil
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	store, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	writeBlob := func(mediaType string, data []byte) ocispec.Descriptor {
+		desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(data), Size: int64(len(data))}
+		require.NoError(t, content.WriteBlob(ctx, store, desc.Digest.String(), bytes.NewReader(data), desc))
+		return desc
+	}
+
+	manifestYAML := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"
+	var layer bytes.Buffer
+	gzw := gzip.NewWriter(&layer)
+	tw := tar.NewWriter(gzw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/configmap.yaml", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(manifestYAML))}))
+	_, err = tw.Write([]byte(manifestYAML))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	imageConfig, err := json.Marshal(ocispec.Image{Platform: ocispec.Platform{Architecture: runtime.GOARCH, OS: runtime.GOOS}})
+	require.NoError(t, err)
+	config := writeBlob(ocispec.MediaTypeImageConfig, imageConfig)
+	layerDesc := writeBlob(ocispec.MediaTypeImageLayerGzip, layer.Bytes())
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	require.NoError(t, err)
+	target := writeBlob(ocispec.MediaTypeImageManifest, manifest)
+
+	srv := httptest.NewServer(&Server{
+		Images: &fakeImageStore{images: map[string]images.Image{
+			"quay.io/example/bundle:v1": {Name: "quay.io/example/bundle:v1", Target: target},
+		}},
+		Content: store,
+		WorkDir: t.TempDir(),
+		Log:     logr.Discard(),
+	})
+	defer srv.Close()
+
+	get := func(ref string) *http.Response {
+		resp, err := http.Get(srv.URL + Path + "?" + url.Values{"ref": {ref}}.Encode())
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("serves the image filesystem", func(t *testing.T) {
+		resp := get("quay.io/example/bundle:v1")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, target.Digest.String(), resp.Header.Get(DigestHeader))
+
+		gzr, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		bundleFS, err := util.TarToFS(gzr)
+		require.NoError(t, err)
+		data, err := fs.ReadFile(bundleFS, "manifests/configmap.yaml")
+		require.NoError(t, err)
+		require.Equal(t, manifestYAML, string(data))
+	})
+
+	t.Run("image not on node", func(t *testing.T) {
+		resp := get("quay.io/example/bundle:v2")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		resp := get("Invalid Reference")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -2,6 +2,24 @@
 // the operator-framework/operator-registry repository.
 package registry
 
+import "encoding/json"
+
+const (
+	// PropertyTypePackage is the type of the property that declares the
+	// package name and version of a bundle.
+	PropertyTypePackage = "olm.package"
+	// PropertyTypeGVK is the type of the properties that declare the APIs
+	// provided by a bundle.
+	PropertyTypeGVK = "olm.gvk"
+	// PropertyTypeMaxOpenShiftVersion is the type of the property that
+	// declares the latest OpenShift version that a bundle supports.
+	PropertyTypeMaxOpenShiftVersion = "olm.maxOpenShiftVersion"
+
+	// PropertiesAnnotationKey is the CSV annotation that declares properties
+	// as a JSON list, as an alternative to metadata/properties.yaml.
+	PropertiesAnnotationKey = "olm.properties"
+)
+
 // AnnotationsFile holds annotation information about a bundle
 type AnnotationsFile struct {
 	// annotations is a list of annotations for a given bundle
@@ -21,3 +39,34 @@ type Annotations struct {
 	// has a single channel, then that channel is implicitly the default.
 	DefaultChannelName string `json:"operators.operatorframework.io.bundle.channel.default.v1" yaml:"operators.operatorframework.io.bundle.channel.default.v1"`
 }
+
+// PropertiesFile holds the properties declared by a bundle
+type PropertiesFile struct {
+	// Properties is a list of properties of a given bundle
+	Properties []Property `json:"properties" yaml:"properties"`
+}
+
+// Property is a typed property of a bundle
+type Property struct {
+	// Type is the type of the property, ala `olm.package`.
+	Type string `json:"type" yaml:"type"`
+
+	// Value is the type-specific value of the property.
+	Value json.RawMessage `json:"value" yaml:"value"`
+}
+
+// PackageProperty is the value of an `olm.package` property
+type PackageProperty struct {
+	// PackageName is the name of the package the bundle belongs to.
+	PackageName string `json:"packageName" yaml:"packageName"`
+
+	// Version is the version of the bundle.
+	Version string `json:"version" yaml:"version"`
+}
+
+// GVKProperty is the value of an `olm.gvk` property
+type GVKProperty struct {
+	Group   string `json:"group" yaml:"group"`
+	Kind    string `json:"kind" yaml:"kind"`
+	Version string `json:"version" yaml:"version"`
+}
@@ -0,0 +1,109 @@
+package patches
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Patch is a JSON patch (RFC 6902) of a single object of a bundle. Patches
+// are declared by the patches key of the config of a BundleDeployment, so
+// that a field of an object, such as its replicas or image, can be changed
+// without changing the bundle.
+type Patch struct {
+	// Target selects the object that the patch applies to.
+	Target Target `json:"target"`
+	// Patch is the list of JSON patch operations.
+	Patch json.RawMessage `json:"patch"`
+
+	operations jsonpatch.Patch
+}
+
+// Target selects an object of a bundle.
+type Target struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Namespace is the namespace of the object. Objects without a namespace
+	// are in the install namespace. If empty, the target matches objects of
+	// any namespace.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (t Target) String() string {
+	if t.Namespace == "" {
+		return fmt.Sprintf("%s %s", t.Kind, t.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", t.Kind, t.Namespace, t.Name)
+}
+
+// FromConfig returns the patches declared by the config of a
+// BundleDeployment. Other keys of the config are ignored.
+func FromConfig(config runtime.RawExtension) ([]Patch, error) {
+	if len(config.Raw) == 0 {
+		return nil, nil
+	}
+	var c struct {
+		Patches []Patch `json:"patches,omitempty"`
+	}
+	if err := json.Unmarshal(config.Raw, &c); err != nil {
+		return nil, fmt.Errorf("parse patches: %v", err)
+	}
+	var errs []error
+	for i := range c.Patches {
+		p := &c.Patches[i]
+		if p.Target.Kind == "" || p.Target.Name == "" {
+			errs = append(errs, fmt.Errorf("patches[%d].target must set kind and name", i))
+		}
+		ops, err := jsonpatch.DecodePatch(p.Patch)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("patches[%d].patch is invalid: %v", i, err))
+			continue
+		}
+		if len(ops) == 0 {
+			errs = append(errs, fmt.Errorf("patches[%d].patch must have at least one operation", i))
+		}
+		p.operations = ops
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	return c.Patches, nil
+}
+
+// Matches reports whether obj is the target of the patch. Objects without a
+// namespace are in installNamespace.
+func (p *Patch) Matches(obj *unstructured.Unstructured, installNamespace string) bool {
+	if obj.GetKind() != p.Target.Kind || obj.GetName() != p.Target.Name {
+		return false
+	}
+	if p.Target.Namespace == "" {
+		return true
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = installNamespace
+	}
+	return namespace == p.Target.Namespace
+}
+
+// Apply applies the patch to obj.
+func (p *Patch) Apply(obj *unstructured.Unstructured) error {
+	doc, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	patched, err := p.operations.Apply(doc)
+	if err != nil {
+		return fmt.Errorf("apply patch to %s: %v", p.Target, err)
+	}
+	patchedObj := &unstructured.Unstructured{}
+	if err := patchedObj.UnmarshalJSON(patched); err != nil {
+		return fmt.Errorf("apply patch to %s: %v", p.Target, err)
+	}
+	obj.Object = patchedObj.Object
+	return nil
+}
@@ -0,0 +1,106 @@
+package patches
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFromConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		config      string
+		expected    int
+		expectedErr string
+	}{
+		{
+			name:   "empty",
+			config: "",
+		},
+		{
+			name:   "other keys only",
+			config: `{"values":"foo: bar"}`,
+		},
+		{
+			name:     "patches",
+			config:   `{"values":"foo: bar","patches":[{"target":{"kind":"Deployment","name":"operator"},"patch":[{"op":"replace","path":"/spec/replicas","value":3}]}]}`,
+			expected: 1,
+		},
+		{
+			name:        "target without name",
+			config:      `{"patches":[{"target":{"kind":"Deployment"},"patch":[{"op":"remove","path":"/spec/replicas"}]}]}`,
+			expectedErr: "patches[0].target must set kind and name",
+		},
+		{
+			name:        "patch that is not a list of operations",
+			config:      `{"patches":[{"target":{"kind":"Deployment","name":"operator"},"patch":{"spec":{"replicas":3}}}]}`,
+			expectedErr: "patches[0].patch is invalid",
+		},
+		{
+			name:        "patch without operations",
+			config:      `{"patches":[{"target":{"kind":"Deployment","name":"operator"},"patch":[]}]}`,
+			expectedErr: "patches[0].patch must have at least one operation",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			patches, err := FromConfig(runtime.RawExtension{Raw: []byte(tc.config)})
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, patches, tc.expected)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind("Deployment")
+	obj.SetName("operator")
+
+	for _, tc := range []struct {
+		name     string
+		target   Target
+		expected bool
+	}{
+		{name: "any namespace", target: Target{Kind: "Deployment", Name: "operator"}, expected: true},
+		{name: "install namespace", target: Target{Kind: "Deployment", Name: "operator", Namespace: "install-ns"}, expected: true},
+		{name: "other namespace", target: Target{Kind: "Deployment", Name: "operator", Namespace: "other-ns"}},
+		{name: "other kind", target: Target{Kind: "StatefulSet", Name: "operator"}},
+		{name: "other name", target: Target{Kind: "Deployment", Name: "webhook"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Patch{Target: tc.target}
+			require.Equal(t, tc.expected, p.Matches(obj, "install-ns"))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	patches, err := FromConfig(runtime.RawExtension{Raw: []byte(`{"patches":[{"target":{"kind":"Deployment","name":"operator"},"patch":[
+		{"op":"replace","path":"/spec/template/spec/containers/0/image","value":"quay.io/example/operator:v2"},
+		{"op":"test","path":"/spec/replicas","value":1}
+	]}]}`)})
+	require.NoError(t, err)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "operator"},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "operator", "image": "quay.io/example/operator:v1"},
+			}}},
+		},
+	}}
+	require.NoError(t, patches[0].Apply(obj))
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	require.Equal(t, "quay.io/example/operator:v2", containers[0].(map[string]interface{})["image"])
+
+	require.NoError(t, unstructured.SetNestedField(obj.Object, int64(2), "spec", "replicas"))
+	require.ErrorContains(t, patches[0].Apply(obj), "apply patch to Deployment operator")
+}
@@ -0,0 +1,96 @@
+package releasegc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+var _ manager.LeaderElectionRunnable = &GarbageCollector{}
+
+// releaseSecretSelector matches the secrets that the Helm secrets storage
+// driver stores releases in.
+var releaseSecretSelector = client.MatchingLabels{"owner": "helm"}
+
+// GarbageCollector periodically deletes the Helm release secrets of
+// BundleDeployments that no longer exist. Release secrets are owned by their
+// BundleDeployment, but they are left behind if the BundleDeployment was
+// deleted with the orphan propagation policy or before owner references were
+// set on them.
+//
+// Only release secrets that are controlled by a BundleDeployment are deleted.
+// Deletes are idempotent, so the collector runs on every replica rather than
+// only on the leader.
+type GarbageCollector struct {
+	// Reader should read from the API server rather than a cache, so that the
+	// releases of a BundleDeployment that was just created are not collected,
+	// and so that release secrets are not cached.
+	Reader client.Reader
+	Writer client.Writer
+	// Namespace is the namespace that releases are stored in.
+	Namespace string
+	Interval  time.Duration
+	Log       logr.Logger
+}
+
+func (gc *GarbageCollector) NeedLeaderElection() bool {
+	return false
+}
+
+func (gc *GarbageCollector) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := gc.Collect(ctx); err != nil {
+			gc.Log.Error(err, "failed to garbage collect helm release secrets")
+		}
+	}, gc.Interval)
+	return nil
+}
+
+// Collect deletes the release secrets that are controlled by a
+// BundleDeployment that does not exist anymore.
+func (gc *GarbageCollector) Collect(ctx context.Context) error {
+	secrets := &metav1.PartialObjectMetadataList{}
+	secrets.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("SecretList"))
+	if err := gc.Reader.List(ctx, secrets, client.InNamespace(gc.Namespace), releaseSecretSelector); err != nil {
+		return fmt.Errorf("list release secrets: %v", err)
+	}
+	if len(secrets.Items) == 0 {
+		return nil
+	}
+
+	bds := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := gc.Reader.List(ctx, bds); err != nil {
+		return fmt.Errorf("list bundle deployments: %v", err)
+	}
+	live := map[types.UID]struct{}{}
+	for _, bd := range bds.Items {
+		live[bd.UID] = struct{}{}
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		owner := metav1.GetControllerOf(secret)
+		if owner == nil || owner.APIVersion != rukpakv1alpha2.GroupVersion.String() || owner.Kind != rukpakv1alpha2.BundleDeploymentKind {
+			continue
+		}
+		if _, ok := live[owner.UID]; ok {
+			continue
+		}
+		secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+		if err := gc.Writer.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("delete release secret %q: %v", secret.Name, err)
+		}
+		gc.Log.V(1).Info("deleted release secret of removed bundle deployment", "secret", secret.Name, "bundleDeployment", owner.Name)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package releasegc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestCollect(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	live := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "live", UID: "live-uid"}}
+	releaseSecret := func(name string, labels map[string]string, owner *metav1.OwnerReference) *corev1.Secret {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "rukpak-system", Labels: labels}}
+		if owner != nil {
+			secret.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return secret
+	}
+	bdOwner := func(name string, uid types.UID) *metav1.OwnerReference {
+		return &metav1.OwnerReference{
+			APIVersion: rukpakv1alpha2.GroupVersion.String(),
+			Kind:       rukpakv1alpha2.BundleDeploymentKind,
+			Name:       name,
+			UID:        uid,
+			Controller: ptr.To(true),
+		}
+	}
+	helmLabels := map[string]string{"owner": "helm"}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		live,
+		releaseSecret("sh.helm.release.v1.live.v1", helmLabels, bdOwner("live", "live-uid")),
+		releaseSecret("sh.helm.release.v1.deleted.v1", helmLabels, bdOwner("deleted", "deleted-uid")),
+		// Same name as a live BundleDeployment, but owned by a previous incarnation.
+		releaseSecret("sh.helm.release.v1.live.v0", helmLabels, bdOwner("live", "old-uid")),
+		releaseSecret("sh.helm.release.v1.unowned.v1", helmLabels, nil),
+		releaseSecret("unrelated", nil, bdOwner("deleted", "deleted-uid")),
+	).Build()
+
+	gc := &GarbageCollector{Reader: cl, Writer: cl, Namespace: "rukpak-system", Log: logr.Discard()}
+	require.NoError(t, gc.Collect(context.Background()))
+
+	for name, expectDeleted := range map[string]bool{
+		"sh.helm.release.v1.live.v1":    false,
+		"sh.helm.release.v1.deleted.v1": true,
+		"sh.helm.release.v1.live.v0":    true,
+		"sh.helm.release.v1.unowned.v1": false,
+		"unrelated":                     false,
+	} {
+		err := cl.Get(context.Background(), client.ObjectKey{Namespace: "rukpak-system", Name: name}, &corev1.Secret{})
+		if expectDeleted {
+			require.True(t, apierrors.IsNotFound(err), "expected secret %q to be deleted, got %v", name, err)
+		} else {
+			require.NoError(t, err, "expected secret %q to be kept", name)
+		}
+	}
+}
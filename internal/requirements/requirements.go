@@ -0,0 +1,317 @@
+package requirements
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/common/expfmt"
+	"helm.sh/helm/v3/pkg/chart"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+)
+
+// featureEnabledMetric is the API server metric that reports whether a
+// feature gate is enabled. It is available since Kubernetes 1.26.
+const featureEnabledMetric = "kubernetes_feature_enabled"
+
+// clusterVersionName is the name of the ClusterVersion that describes an
+// OpenShift cluster.
+const clusterVersionName = "version"
+
+// The chart annotations that record the requirements declared by a bundle.
+const (
+	minKubernetesVersionAnnotationKey = "core.rukpak.io/min-kubernetes-version"
+	maxOpenShiftVersionAnnotationKey  = "core.rukpak.io/max-openshift-version"
+)
+
+var clusterVersionGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterVersion"}
+
+// Requirements are the capabilities that a cluster must have before a bundle
+// is installed. They are declared by the requiredAPIs and
+// requiredCapabilities keys of the config of a BundleDeployment.
+type Requirements struct {
+	// APIs are the kinds that the API server must serve.
+	APIs []metav1.GroupVersionKind `json:"requiredAPIs,omitempty"`
+	// Capabilities are the properties that the API server must have.
+	Capabilities Capabilities `json:"requiredCapabilities,omitempty"`
+}
+
+// Capabilities are properties of the API server.
+type Capabilities struct {
+	// MinKubernetesVersion is the lowest Kubernetes version that the API
+	// server may run, e.g. 1.27.
+	MinKubernetesVersion string `json:"minKubernetesVersion,omitempty"`
+	// FeatureGates are the Kubernetes feature gates that must be enabled.
+	FeatureGates []string `json:"featureGates,omitempty"`
+	// MaxOpenShiftVersion is the latest OpenShift version that the cluster
+	// may run, e.g. 4.14. Only the major and minor version are compared, and
+	// clusters that do not run OpenShift always meet it.
+	MaxOpenShiftVersion string `json:"maxOpenShiftVersion,omitempty"`
+}
+
+// FromConfig returns the requirements declared by the config of a
+// BundleDeployment. Other keys of the config are ignored.
+func FromConfig(config runtime.RawExtension) (*Requirements, error) {
+	reqs := &Requirements{}
+	if len(config.Raw) == 0 {
+		return reqs, nil
+	}
+	if err := json.Unmarshal(config.Raw, reqs); err != nil {
+		return nil, fmt.Errorf("parse requirements: %v", err)
+	}
+	return reqs, reqs.Validate()
+}
+
+// Validate checks that the requirements are well-formed.
+func (r *Requirements) Validate() error {
+	var errs []error
+	for i, gvk := range r.APIs {
+		if gvk.Version == "" || gvk.Kind == "" {
+			errs = append(errs, fmt.Errorf("requiredAPIs[%d] must set version and kind", i))
+		}
+	}
+	if v := r.Capabilities.MinKubernetesVersion; v != "" {
+		if _, err := version.ParseGeneric(v); err != nil {
+			errs = append(errs, fmt.Errorf("requiredCapabilities.minKubernetesVersion is invalid: %v", err))
+		}
+	}
+	if v := r.Capabilities.MaxOpenShiftVersion; v != "" {
+		if _, err := version.ParseGeneric(v); err != nil {
+			errs = append(errs, fmt.Errorf("requiredCapabilities.maxOpenShiftVersion is invalid: %v", err))
+		}
+	}
+	for i, gate := range r.Capabilities.FeatureGates {
+		if gate == "" {
+			errs = append(errs, fmt.Errorf("requiredCapabilities.featureGates[%d] must not be empty", i))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// IsEmpty reports whether there are no requirements.
+func (r *Requirements) IsEmpty() bool {
+	return len(r.APIs) == 0 && r.Capabilities.MinKubernetesVersion == "" && len(r.Capabilities.FeatureGates) == 0 &&
+		r.Capabilities.MaxOpenShiftVersion == ""
+}
+
+// AnnotateChart records caps in the annotations of chrt, so that handlers can
+// declare the requirements of the bundles that they convert to charts.
+func AnnotateChart(chrt *chart.Chart, caps Capabilities) {
+	for key, value := range map[string]string{
+		minKubernetesVersionAnnotationKey: caps.MinKubernetesVersion,
+		maxOpenShiftVersionAnnotationKey:  caps.MaxOpenShiftVersion,
+	} {
+		if value == "" {
+			continue
+		}
+		if chrt.Metadata == nil {
+			chrt.Metadata = &chart.Metadata{}
+		}
+		if chrt.Metadata.Annotations == nil {
+			chrt.Metadata.Annotations = map[string]string{}
+		}
+		chrt.Metadata.Annotations[key] = value
+	}
+}
+
+// FromChart returns the requirements recorded in the annotations of chrt by
+// AnnotateChart.
+func FromChart(chrt *chart.Chart) (*Requirements, error) {
+	reqs := &Requirements{}
+	if chrt == nil || chrt.Metadata == nil {
+		return reqs, nil
+	}
+	reqs.Capabilities.MinKubernetesVersion = chrt.Metadata.Annotations[minKubernetesVersionAnnotationKey]
+	reqs.Capabilities.MaxOpenShiftVersion = chrt.Metadata.Annotations[maxOpenShiftVersionAnnotationKey]
+	if err := reqs.Validate(); err != nil {
+		return nil, fmt.Errorf("bundle declares invalid requirements: %v", err)
+	}
+	return reqs, nil
+}
+
+// Cluster detects the capabilities of a cluster.
+type Cluster interface {
+	// ServesAPI reports whether the API server serves the given kind.
+	ServesAPI(ctx context.Context, gvk schema.GroupVersionKind) (bool, error)
+	// Version returns the Kubernetes version of the API server.
+	Version(ctx context.Context) (*version.Version, error)
+	// FeatureGates returns whether each feature gate known to the API server
+	// is enabled.
+	FeatureGates(ctx context.Context) (map[string]bool, error)
+	// OpenShiftVersion returns the OpenShift version of the cluster, or nil
+	// if the cluster does not run OpenShift.
+	OpenShiftVersion(ctx context.Context) (*version.Version, error)
+}
+
+// Unmet returns a description of every requirement that the cluster does not
+// meet. Capabilities that no requirement refers to are not detected.
+func Unmet(ctx context.Context, cluster Cluster, reqs *Requirements) ([]string, error) {
+	var unmet []string
+	for _, gvk := range reqs.APIs {
+		gvk := schema.GroupVersionKind(gvk)
+		served, err := cluster.ServesAPI(ctx, gvk)
+		if err != nil {
+			return nil, fmt.Errorf("detect API %s: %v", gvk, err)
+		}
+		if !served {
+			unmet = append(unmet, fmt.Sprintf("kind %s of API %s is not served", gvk.Kind, gvk.GroupVersion()))
+		}
+	}
+
+	if minVersion := reqs.Capabilities.MinKubernetesVersion; minVersion != "" {
+		required, err := version.ParseGeneric(minVersion)
+		if err != nil {
+			return nil, fmt.Errorf("parse minimum Kubernetes version: %v", err)
+		}
+		actual, err := cluster.Version(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("detect Kubernetes version: %v", err)
+		}
+		if actual.LessThan(required) {
+			unmet = append(unmet, fmt.Sprintf("Kubernetes version %s is older than %s", actual, minVersion))
+		}
+	}
+
+	if maxVersion := reqs.Capabilities.MaxOpenShiftVersion; maxVersion != "" {
+		parsed, err := version.ParseGeneric(maxVersion)
+		if err != nil {
+			return nil, fmt.Errorf("parse maximum OpenShift version: %v", err)
+		}
+		actual, err := cluster.OpenShiftVersion(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("detect OpenShift version: %v", err)
+		}
+		// Like OLM, ignore the patch version, so that a maximum of 4.14
+		// allows every 4.14.z release.
+		if actual != nil && version.MajorMinor(parsed.Major(), parsed.Minor()).LessThan(version.MajorMinor(actual.Major(), actual.Minor())) {
+			unmet = append(unmet, fmt.Sprintf("OpenShift version %s is newer than %s", actual, maxVersion))
+		}
+	}
+
+	if len(reqs.Capabilities.FeatureGates) > 0 {
+		gates, err := cluster.FeatureGates(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("detect feature gates: %v", err)
+		}
+		for _, gate := range reqs.Capabilities.FeatureGates {
+			if !gates[gate] {
+				unmet = append(unmet, fmt.Sprintf("feature gate %s is not enabled", gate))
+			}
+		}
+	}
+	return unmet, nil
+}
+
+// Discovery is a Cluster that uses the discovery API and the metrics
+// endpoint of the API server, and the ClusterVersion of OpenShift clusters.
+type Discovery struct {
+	Client discovery.DiscoveryInterface
+}
+
+func (d *Discovery) ServesAPI(_ context.Context, gvk schema.GroupVersionKind) (bool, error) {
+	resources, err := d.Client.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == gvk.Kind {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *Discovery) Version(_ context.Context) (*version.Version, error) {
+	info, err := d.Client.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	return version.ParseGeneric(info.GitVersion)
+}
+
+func (d *Discovery) FeatureGates(ctx context.Context) (map[string]bool, error) {
+	data, err := d.Client.RESTClient().Get().AbsPath("/metrics").Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	return parseFeatureGates(data)
+}
+
+func (d *Discovery) OpenShiftVersion(ctx context.Context) (*version.Version, error) {
+	served, err := d.ServesAPI(ctx, clusterVersionGVK)
+	if err != nil || !served {
+		return nil, err
+	}
+	data, err := d.Client.RESTClient().Get().AbsPath("/apis", clusterVersionGVK.Group, clusterVersionGVK.Version, "clusterversions", clusterVersionName).Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	return parseClusterVersion(data)
+}
+
+// clusterVersion is the subset of the OpenShift ClusterVersion that is
+// needed to determine the version of the cluster.
+type clusterVersion struct {
+	Status struct {
+		Desired struct {
+			Version string `json:"version"`
+		} `json:"desired"`
+		History []struct {
+			State   string `json:"state"`
+			Version string `json:"version"`
+		} `json:"history"`
+	} `json:"status"`
+}
+
+// parseClusterVersion returns the version of an OpenShift cluster from its
+// ClusterVersion. During an update, the cluster runs the version of the most
+// recent completed update rather than the desired version.
+func parseClusterVersion(data []byte) (*version.Version, error) {
+	var cv clusterVersion
+	if err := json.Unmarshal(data, &cv); err != nil {
+		return nil, fmt.Errorf("parse ClusterVersion: %v", err)
+	}
+	for _, update := range cv.Status.History {
+		if update.State == "Completed" {
+			return version.ParseGeneric(update.Version)
+		}
+	}
+	if cv.Status.Desired.Version == "" {
+		return nil, errors.New("ClusterVersion does not report a version")
+	}
+	return version.ParseGeneric(cv.Status.Desired.Version)
+}
+
+// parseFeatureGates returns the feature gates reported by the metrics of the
+// API server, in the Prometheus text format.
+func parseFeatureGates(data []byte) (map[string]bool, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse metrics: %v", err)
+	}
+	family, ok := families[featureEnabledMetric]
+	if !ok {
+		return nil, errors.New("the API server does not report feature gates, which requires Kubernetes 1.26 or later")
+	}
+	gates := map[string]bool{}
+	for _, m := range family.GetMetric() {
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "name" {
+				gates[label.GetValue()] = m.GetGauge().GetValue() == 1
+			}
+		}
+	}
+	return gates, nil
+}
@@ -0,0 +1,189 @@
+package requirements
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+	apimachineryversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestFromConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		config      string
+		expected    *Requirements
+		expectedErr string
+	}{
+		{
+			name:     "empty",
+			config:   "",
+			expected: &Requirements{},
+		},
+		{
+			name:     "other keys only",
+			config:   `{"values":"foo: bar"}`,
+			expected: &Requirements{},
+		},
+		{
+			name:   "requirements",
+			config: `{"values":"foo: bar","requiredAPIs":[{"group":"monitoring.coreos.com","version":"v1","kind":"ServiceMonitor"}],"requiredCapabilities":{"minKubernetesVersion":"1.27","featureGates":["ValidatingAdmissionPolicy"]}}`,
+			expected: &Requirements{
+				APIs: []metav1.GroupVersionKind{{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}},
+				Capabilities: Capabilities{
+					MinKubernetesVersion: "1.27",
+					FeatureGates:         []string{"ValidatingAdmissionPolicy"},
+				},
+			},
+		},
+		{
+			name:        "API without kind",
+			config:      `{"requiredAPIs":[{"group":"apps","version":"v1"}]}`,
+			expectedErr: "requiredAPIs[0] must set version and kind",
+		},
+		{
+			name:        "invalid version",
+			config:      `{"requiredCapabilities":{"minKubernetesVersion":"latest"}}`,
+			expectedErr: "requiredCapabilities.minKubernetesVersion is invalid",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reqs, err := FromConfig(runtime.RawExtension{Raw: []byte(tc.config)})
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, reqs)
+		})
+	}
+}
+
+func TestUnmet(t *testing.T) {
+	fake := &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "monitoring.coreos.com/v1",
+		APIResources: []metav1.APIResource{{Name: "servicemonitors", Kind: "ServiceMonitor"}},
+	}}}
+	cluster := &fakeCluster{
+		Discovery: Discovery{Client: &fakediscovery.FakeDiscovery{Fake: fake, FakedServerVersion: &apimachineryversion.Info{GitVersion: "v1.28.3+k3s1"}}},
+		gates:     map[string]bool{"ValidatingAdmissionPolicy": true, "InPlacePodVerticalScaling": false},
+	}
+
+	unmet, err := Unmet(context.Background(), cluster, &Requirements{
+		APIs: []metav1.GroupVersionKind{{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}},
+		Capabilities: Capabilities{
+			MinKubernetesVersion: "1.27",
+			FeatureGates:         []string{"ValidatingAdmissionPolicy"},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, unmet)
+
+	unmet, err = Unmet(context.Background(), cluster, &Requirements{
+		APIs: []metav1.GroupVersionKind{
+			{Group: "monitoring.coreos.com", Version: "v1", Kind: "PodMonitor"},
+			{Group: "route.openshift.io", Version: "v1", Kind: "Route"},
+		},
+		Capabilities: Capabilities{
+			MinKubernetesVersion: "1.29",
+			FeatureGates:         []string{"InPlacePodVerticalScaling", "Unknown"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"kind PodMonitor of API monitoring.coreos.com/v1 is not served",
+		"kind Route of API route.openshift.io/v1 is not served",
+		"Kubernetes version 1.28.3 is older than 1.29",
+		"feature gate InPlacePodVerticalScaling is not enabled",
+		"feature gate Unknown is not enabled",
+	}, unmet)
+}
+
+func TestUnmetOpenShiftVersion(t *testing.T) {
+	reqs := &Requirements{Capabilities: Capabilities{MaxOpenShiftVersion: "4.14"}}
+	for _, tc := range []struct {
+		openShift string
+		expected  []string
+	}{
+		{openShift: "", expected: nil},
+		{openShift: "4.13.5", expected: nil},
+		{openShift: "4.14.20", expected: nil},
+		{openShift: "4.15.0", expected: []string{"OpenShift version 4.15.0 is newer than 4.14"}},
+	} {
+		cluster := &fakeCluster{}
+		if tc.openShift != "" {
+			cluster.openShift = version.MustParseGeneric(tc.openShift)
+		}
+		unmet, err := Unmet(context.Background(), cluster, reqs)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, unmet, tc.openShift)
+	}
+}
+
+func TestChartAnnotations(t *testing.T) {
+	chrt := &chart.Chart{}
+	AnnotateChart(chrt, Capabilities{})
+	require.Nil(t, chrt.Metadata)
+
+	caps := Capabilities{MinKubernetesVersion: "1.25.0", MaxOpenShiftVersion: "4.14"}
+	AnnotateChart(chrt, caps)
+	reqs, err := FromChart(chrt)
+	require.NoError(t, err)
+	require.Equal(t, &Requirements{Capabilities: caps}, reqs)
+
+	chrt.Metadata.Annotations[maxOpenShiftVersionAnnotationKey] = "latest"
+	_, err = FromChart(chrt)
+	require.ErrorContains(t, err, "bundle declares invalid requirements")
+}
+
+func TestParseClusterVersion(t *testing.T) {
+	v, err := parseClusterVersion([]byte(`{"status":{"desired":{"version":"4.15.2"},"history":[{"state":"Partial","version":"4.15.2"},{"state":"Completed","version":"4.14.10"}]}}`))
+	require.NoError(t, err)
+	require.Equal(t, "4.14.10", v.String())
+
+	v, err = parseClusterVersion([]byte(`{"status":{"desired":{"version":"4.15.2"}}}`))
+	require.NoError(t, err)
+	require.Equal(t, "4.15.2", v.String())
+
+	_, err = parseClusterVersion([]byte(`{"status":{}}`))
+	require.ErrorContains(t, err, "does not report a version")
+}
+
+func TestParseFeatureGates(t *testing.T) {
+	gates, err := parseFeatureGates([]byte(`# HELP kubernetes_feature_enabled [BETA] This metric records the data about the stage and enablement of a k8s feature.
+# TYPE kubernetes_feature_enabled gauge
+kubernetes_feature_enabled{name="APIPriorityAndFairness",stage=""} 1
+kubernetes_feature_enabled{name="InPlacePodVerticalScaling",stage="ALPHA"} 0
+# HELP apiserver_requests_total Counter of apiserver requests.
+# TYPE apiserver_requests_total counter
+apiserver_requests_total{code="200"} 12
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"APIPriorityAndFairness": true, "InPlacePodVerticalScaling": false}, gates)
+
+	_, err = parseFeatureGates([]byte("apiserver_requests_total 12\n"))
+	require.ErrorContains(t, err, "does not report feature gates")
+}
+
+// fakeCluster detects APIs and the version by discovery, but serves feature
+// gates and the OpenShift version from its fields, since the fake discovery
+// client has no REST client.
+type fakeCluster struct {
+	Discovery
+	gates     map[string]bool
+	openShift *version.Version
+}
+
+func (c *fakeCluster) FeatureGates(_ context.Context) (map[string]bool, error) {
+	return c.gates, nil
+}
+
+func (c *fakeCluster) OpenShiftVersion(_ context.Context) (*version.Version, error) {
+	return c.openShift, nil
+}
@@ -0,0 +1,288 @@
+package statusstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/updater"
+)
+
+const (
+	// Path is the path at which the status stream is served.
+	Path = "/watch/bundledeployments"
+
+	// NameQueryParam is the query parameter used to limit the stream
+	// to a single BundleDeployment.
+	NameQueryParam = "name"
+
+	eventTypeCondition = "condition"
+	subscriberBuffer   = 64
+	keepAliveInterval  = 30 * time.Second
+)
+
+// Event describes the transition of a single BundleDeployment condition.
+type Event struct {
+	// BundleDeployment is the name of the BundleDeployment whose condition changed.
+	BundleDeployment string `json:"bundleDeployment"`
+	// Generation is the generation of the BundleDeployment at the time of the transition.
+	Generation int64 `json:"generation"`
+	// Condition is the new state of the condition.
+	Condition metav1.Condition `json:"condition"`
+}
+
+type subscriber struct {
+	name   string
+	events chan Event
+}
+
+// Server streams BundleDeployment condition transitions to HTTP clients using
+// server-sent events. Clients receive the current conditions of the matching
+// BundleDeployments when they connect, followed by every subsequent transition.
+// This allows dashboards and CLIs to follow status changes without each of them
+// establishing its own watch against the apiserver.
+type Server struct {
+	reader client.Reader
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+func NewServer() *Server {
+	return &Server{subscribers: map[*subscriber]struct{}{}}
+}
+
+// SetupWithManager registers the server with the BundleDeployment informer of
+// the manager's cache. It must be called before the manager is started.
+func (s *Server) SetupWithManager(mgr manager.Manager) error {
+	informer, err := mgr.GetCache().GetInformer(context.Background(), &rukpakv1alpha2.BundleDeployment{})
+	if err != nil {
+		return fmt.Errorf("get bundledeployment informer: %v", err)
+	}
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldBD, ok := oldObj.(*rukpakv1alpha2.BundleDeployment)
+			if !ok {
+				return
+			}
+			newBD, ok := newObj.(*rukpakv1alpha2.BundleDeployment)
+			if !ok {
+				return
+			}
+			for _, ev := range conditionTransitions(oldBD, newBD) {
+				s.broadcast(ev)
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("add bundledeployment event handler: %v", err)
+	}
+	s.reader = mgr.GetCache()
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	if s.reader == nil {
+		http.Error(w, "status stream is not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := r.URL.Query().Get(NameQueryParam)
+
+	// Subscribe before reading the current state so that no transition that
+	// happens in between is lost.
+	sub := s.subscribe(name)
+	defer s.unsubscribe(sub)
+
+	initial, err := s.currentConditions(r.Context(), name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	l := log.FromContext(r.Context()).WithName("statusstream")
+	for _, ev := range initial {
+		if err := writeEvent(w, ev); err != nil {
+			l.V(1).Info("failed to write event", "error", err)
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-sub.events:
+			if !ok {
+				// The subscriber fell too far behind and was dropped.
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				l.V(1).Info("failed to write event", "error", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *Server) currentConditions(ctx context.Context, name string) ([]Event, error) {
+	bds := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := s.reader.List(ctx, bds); err != nil {
+		return nil, fmt.Errorf("list bundledeployments: %v", err)
+	}
+	var events []Event
+	for i := range bds.Items {
+		bd := &bds.Items[i]
+		if name != "" && bd.Name != name {
+			continue
+		}
+		for _, c := range bd.Status.Conditions {
+			events = append(events, Event{BundleDeployment: bd.Name, Generation: bd.Generation, Condition: c})
+		}
+	}
+	return events, nil
+}
+
+func (s *Server) subscribe(name string) *subscriber {
+	sub := &subscriber{name: name, events: make(chan Event, subscriberBuffer)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (s *Server) broadcast(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		if sub.name != "" && sub.name != ev.BundleDeployment {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			// Never block the informer on a slow client. Drop the
+			// subscriber instead; it can reconnect to resynchronize.
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// conditionTransitions returns an event for every condition of newBD that
+// was added or changed compared to oldBD.
+func conditionTransitions(oldBD, newBD *rukpakv1alpha2.BundleDeployment) []Event {
+	var events []Event
+	for _, c := range newBD.Status.Conditions {
+		if prev := meta.FindStatusCondition(oldBD.Status.Conditions, c.Type); prev != nil && updater.ConditionsSemanticallyEqual(*prev, c) {
+			continue
+		}
+		events = append(events, Event{BundleDeployment: newBD.Name, Generation: newBD.Generation, Condition: c})
+	}
+	return events
+}
+
+func writeEvent(w http.ResponseWriter, ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventTypeCondition, data)
+	return err
+}
+
+// Watch follows the status stream served at serverURL, and calls fn for every
+// event until the stream ends, ctx is done or fn returns an error. If token is
+// set, it authenticates the request as a bearer token. If name is set, only
+// the events of the BundleDeployment of that name are streamed.
+func Watch(ctx context.Context, cl *http.Client, serverURL, token, name string, fn func(Event) error) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("parse status stream URL: %v", err)
+	}
+	u = u.JoinPath(Path)
+	if name != "" {
+		u.RawQuery = url.Values{NameQueryParam: {name}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %q from %s", resp.Status, u.Redacted())
+	}
+
+	// Every event that the server writes has a single data line, and lines
+	// of other fields or comments are skipped.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("decode status stream event: %v", err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,172 @@
+package statusstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestConditionTransitions(t *testing.T) {
+	installed := metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded}
+	unpacked := metav1.Condition{Type: rukpakv1alpha2.TypeUnpacked, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonUnpackSuccessful}
+	failed := metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionFalse, Reason: rukpakv1alpha2.ReasonInstallFailed}
+
+	for _, tt := range []struct {
+		description string
+		old         []metav1.Condition
+		new         []metav1.Condition
+		expected    []metav1.Condition
+	}{
+		{
+			description: "no changes",
+			old:         []metav1.Condition{installed, unpacked},
+			new:         []metav1.Condition{installed, unpacked},
+		},
+		{
+			description: "added condition",
+			old:         []metav1.Condition{unpacked},
+			new:         []metav1.Condition{unpacked, installed},
+			expected:    []metav1.Condition{installed},
+		},
+		{
+			description: "changed condition",
+			old:         []metav1.Condition{installed, unpacked},
+			new:         []metav1.Condition{failed, unpacked},
+			expected:    []metav1.Condition{failed},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			oldBD := &rukpakv1alpha2.BundleDeployment{Status: rukpakv1alpha2.BundleDeploymentStatus{Conditions: tt.old}}
+			newBD := &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Status:     rukpakv1alpha2.BundleDeploymentStatus{Conditions: tt.new},
+			}
+			var actual []metav1.Condition
+			for _, ev := range conditionTransitions(oldBD, newBD) {
+				require.Equal(t, "test", ev.BundleDeployment)
+				actual = append(actual, ev.Condition)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	unpacked := metav1.Condition{Type: rukpakv1alpha2.TypeUnpacked, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonUnpackSuccessful}
+	s := NewServer()
+	s.reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "watched"},
+			Status:     rukpakv1alpha2.BundleDeploymentStatus{Conditions: []metav1.Condition{unpacked}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored"},
+			Status:     rukpakv1alpha2.BundleDeploymentStatus{Conditions: []metav1.Condition{unpacked}},
+		},
+	).Build()
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+Path+"?"+NameQueryParam+"=watched", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() Event {
+		var ev Event
+		for events.Scan() {
+			line := events.Text()
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				require.NoError(t, json.Unmarshal([]byte(data), &ev))
+				return ev
+			}
+		}
+		require.NoError(t, events.Err())
+		t.Fatal("stream ended unexpectedly")
+		return ev
+	}
+
+	ev := next()
+	require.Equal(t, "watched", ev.BundleDeployment)
+	require.Equal(t, rukpakv1alpha2.TypeUnpacked, ev.Condition.Type)
+
+	installed := metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded}
+	s.broadcast(Event{BundleDeployment: "ignored", Condition: installed})
+	s.broadcast(Event{BundleDeployment: "watched", Condition: installed})
+
+	ev = next()
+	require.Equal(t, "watched", ev.BundleDeployment)
+	require.Equal(t, rukpakv1alpha2.TypeInstalled, ev.Condition.Type)
+}
+
+func TestWatch(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	unpacked := metav1.Condition{Type: rukpakv1alpha2.TypeUnpacked, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonUnpackSuccessful}
+	s := NewServer()
+	s.reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "watched"},
+			Status:     rukpakv1alpha2.BundleDeploymentStatus{Conditions: []metav1.Condition{unpacked}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "ignored"},
+			Status:     rukpakv1alpha2.BundleDeploymentStatus{Conditions: []metav1.Condition{unpacked}},
+		},
+	).Build()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	err := Watch(context.Background(), srv.Client(), srv.URL, "", "watched", func(Event) error { return nil })
+	require.ErrorContains(t, err, "401 Unauthorized")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	installed := metav1.Condition{Type: rukpakv1alpha2.TypeInstalled, Status: metav1.ConditionTrue, Reason: rukpakv1alpha2.ReasonInstallationSucceeded}
+	var events []Event
+	err = Watch(ctx, srv.Client(), srv.URL, "token", "watched", func(ev Event) error {
+		events = append(events, ev)
+		if len(events) == 1 {
+			s.broadcast(Event{BundleDeployment: "ignored", Condition: installed})
+			s.broadcast(Event{BundleDeployment: "watched", Condition: installed})
+			return nil
+		}
+		cancel()
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, events, 2)
+	require.Equal(t, "watched", events[0].BundleDeployment)
+	require.Equal(t, unpacked.Type, events[0].Condition.Type)
+	require.Equal(t, "watched", events[1].BundleDeployment)
+	require.Equal(t, installed.Type, events[1].Condition.Type)
+}
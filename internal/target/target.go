@@ -0,0 +1,274 @@
+// Package target connects to the remote clusters that BundleDeployments with
+// a target apply the objects of their bundles to.
+package target
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+	"helm.sh/helm/v3/pkg/action"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// DefaultKubeconfigKey is the key of the kubeconfig in the data of a
+// kubeconfig secret, unless the reference to the secret names another key.
+// Cluster API stores the kubeconfigs of workload clusters under this key.
+const DefaultKubeconfigKey = "value"
+
+var _ manager.LeaderElectionRunnable = &Clusters{}
+
+// Clusters connects to the remote clusters that BundleDeployments target. It
+// keeps a client and a cache for every kubeconfig secret, which are shared by
+// all BundleDeployments that reference the secret, and connects to the
+// cluster again when the kubeconfig in the secret changes or the secret is
+// deleted.
+//
+// Clusters must be added to the manager, which stops the caches of the
+// clusters when it stops.
+type Clusters struct {
+	// Reader reads the kubeconfig secrets.
+	Reader client.Reader
+	// Namespace is the namespace of the kubeconfig secrets.
+	Namespace string
+	Scheme    *runtime.Scheme
+	// Selector selects the namespaced objects that the caches of the
+	// clusters hold, like the cache of the manager does.
+	Selector labels.Selector
+	// QPS and Burst configure the rate limits of the clients of the clusters.
+	QPS   float32
+	Burst int
+	Log   logr.Logger
+
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	clusters map[string]*Cluster
+}
+
+// Cluster is a connection to a remote cluster.
+type Cluster struct {
+	cluster.Cluster
+	// Discovery is a discovery client of the cluster.
+	Discovery discovery.DiscoveryInterface
+
+	kubeconfig []byte
+	acg        helmclient.ActionConfigGetter
+	started    chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// Stopped reports whether the connection to the cluster was closed, because
+// its kubeconfig changed or Clusters was stopped. A stopped cluster does not
+// deliver events of its cache anymore.
+func (c *Cluster) Stopped() bool {
+	return c.ctx.Err() != nil
+}
+
+// NeedLeaderElection returns false, since the caches of the clusters are
+// only started by the reconciles of the controllers.
+func (c *Clusters) NeedLeaderElection() bool {
+	return false
+}
+
+// Start waits until ctx is done and stops the caches of all clusters.
+func (c *Clusters) Start(ctx context.Context) error {
+	<-ctx.Done()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	c.cancel()
+	c.clusters = nil
+	return nil
+}
+
+// init creates the context that the caches of the clusters run with. It is
+// created lazily rather than in Start, since the controllers may reconcile
+// before the manager gets to start Clusters.
+func (c *Clusters) init() {
+	if c.ctx == nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// For returns the cluster that bd targets.
+func (c *Clusters) For(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) (*Cluster, error) {
+	if bd.Spec.Target == nil {
+		return nil, fmt.Errorf("BundleDeployment %q has no target", bd.Name)
+	}
+	ref := bd.Spec.Target.KubeconfigSecretRef
+	key := ref.Key
+	if key == "" {
+		key = DefaultKubeconfigKey
+	}
+	id := ref.Name + "/" + key
+
+	secret := &corev1.Secret{}
+	err := c.Reader.Get(ctx, client.ObjectKey{Namespace: c.Namespace, Name: ref.Name}, secret)
+	if apierrors.IsNotFound(err) {
+		c.forget(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get kubeconfig secret %q: %v", ref.Name, err)
+	}
+	kubeconfig, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret %q has no key %q", ref.Name, key)
+	}
+
+	cl, err := c.connect(id, kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("connect to target cluster of kubeconfig secret %q: %v", ref.Name, err)
+	}
+	// The cache must have started before its client can read from it.
+	select {
+	case <-cl.started:
+		return cl, nil
+	case <-cl.ctx.Done():
+		return nil, fmt.Errorf("target cluster of kubeconfig secret %q was disconnected", ref.Name)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// connect returns the cluster of the kubeconfig secret with the given ID,
+// and connects to it if there is no connection with the given kubeconfig.
+func (c *Clusters) connect(id string, kubeconfig []byte) (*Cluster, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	if c.ctx.Err() != nil {
+		return nil, errors.New("the provisioner is stopping")
+	}
+	existing, ok := c.clusters[id]
+	if ok && bytes.Equal(existing.kubeconfig, kubeconfig) {
+		return existing, nil
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("parse kubeconfig: %v", err)
+	}
+	restConfig.QPS = c.QPS
+	restConfig.Burst = c.Burst
+	log := c.Log.WithValues("kubeconfigSecret", id)
+	cl, err := cluster.New(restConfig, func(o *cluster.Options) {
+		o.Scheme = c.Scheme
+		o.Logger = log
+		o.Cache = cache.Options{
+			DefaultNamespaces: map[string]cache.Config{
+				cache.AllNamespaces: {LabelSelector: c.Selector},
+			},
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	// The releases are stored in the cluster of the BundleDeployment, so
+	// only the objects of the release are created with this config.
+	acg, err := helmclient.NewActionConfigGetter(restConfig, cl.GetRESTMapper(),
+		helmclient.ClientNamespaceMapper(installNamespace),
+		helmclient.DisableStorageOwnerRefInjection(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	target := &Cluster{Cluster: cl, Discovery: dc, kubeconfig: kubeconfig, acg: acg, started: make(chan struct{}), ctx: ctx, cancel: cancel}
+	go func() {
+		if err := cl.Start(ctx); err != nil {
+			log.Error(err, "target cluster cache stopped")
+		}
+	}()
+	go func() {
+		// Nothing reads from the cache before it has started, so there
+		// are no informers to wait for yet.
+		if cl.GetCache().WaitForCacheSync(ctx) {
+			close(target.started)
+		}
+	}()
+	if ok {
+		log.Info("kubeconfig changed, reconnecting to target cluster")
+		existing.cancel()
+	}
+	if c.clusters == nil {
+		c.clusters = map[string]*Cluster{}
+	}
+	c.clusters[id] = target
+	return target, nil
+}
+
+// forget disconnects from the cluster of the kubeconfig secret with the
+// given ID.
+func (c *Clusters) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.clusters[id]; ok {
+		existing.cancel()
+		delete(c.clusters, id)
+	}
+}
+
+func installNamespace(obj client.Object) (string, error) {
+	bd, ok := obj.(*rukpakv1alpha2.BundleDeployment)
+	if !ok {
+		return "", fmt.Errorf("cannot derive namespace from object of type %T", obj)
+	}
+	return bd.Spec.InstallNamespace, nil
+}
+
+// ActionConfigGetter returns an action config getter that delegates
+// BundleDeployments without a target to local. The action configs of
+// BundleDeployments with a target create the objects of their releases in
+// the target cluster, but store the releases like the action configs of
+// local do.
+func (c *Clusters) ActionConfigGetter(local helmclient.ActionConfigGetter) helmclient.ActionConfigGetter {
+	return &actionConfigGetter{local: local, clusters: c}
+}
+
+type actionConfigGetter struct {
+	local    helmclient.ActionConfigGetter
+	clusters *Clusters
+}
+
+func (g *actionConfigGetter) ActionConfigFor(ctx context.Context, obj client.Object) (*action.Configuration, error) {
+	bd, ok := obj.(*rukpakv1alpha2.BundleDeployment)
+	if !ok || bd.Spec.Target == nil {
+		return g.local.ActionConfigFor(ctx, obj)
+	}
+	localCfg, err := g.local.ActionConfigFor(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	target, err := g.clusters.For(ctx, bd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := target.acg.ActionConfigFor(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Releases = localCfg.Releases
+	return cfg, nil
+}
@@ -0,0 +1,138 @@
+package target
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func kubeconfig(server string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: workload
+  cluster:
+    server: %s
+contexts:
+- name: workload
+  context:
+    cluster: workload
+    user: admin
+current-context: workload
+users:
+- name: admin
+  user:
+    token: secret
+`, server))
+}
+
+func newClusters(t *testing.T, objs ...client.Object) (*Clusters, client.Client) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	clusters := &Clusters{
+		Reader:    cl,
+		Namespace: "rukpak-system",
+		Scheme:    scheme,
+		Selector:  labels.Everything(),
+		Log:       logr.Discard(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = clusters.Start(ctx) }()
+	t.Cleanup(cancel)
+	return clusters, cl
+}
+
+func targeting(secret, key string) *rukpakv1alpha2.BundleDeployment {
+	return &rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: rukpakv1alpha2.BundleDeploymentSpec{
+			InstallNamespace: "test-ns",
+			Target:           &rukpakv1alpha2.Target{KubeconfigSecretRef: rukpakv1alpha2.KubeconfigSecretReference{Name: secret, Key: key}},
+		},
+	}
+}
+
+func TestClustersFor(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "workload-kubeconfig"},
+		Data: map[string][]byte{
+			DefaultKubeconfigKey: kubeconfig("https://workload.example.com:6443"),
+			"admin":              kubeconfig("https://admin.example.com:6443"),
+		},
+	}
+	clusters, cl := newClusters(t, secret)
+	ctx := context.Background()
+
+	first, err := clusters.For(ctx, targeting("workload-kubeconfig", ""))
+	require.NoError(t, err)
+	require.Equal(t, "https://workload.example.com:6443", first.GetConfig().Host)
+	again, err := clusters.For(ctx, targeting("workload-kubeconfig", DefaultKubeconfigKey))
+	require.NoError(t, err)
+	require.Same(t, first, again, "BundleDeployments of the same kubeconfig share the cluster")
+
+	admin, err := clusters.For(ctx, targeting("workload-kubeconfig", "admin"))
+	require.NoError(t, err)
+	require.Equal(t, "https://admin.example.com:6443", admin.GetConfig().Host)
+
+	_, err = clusters.For(ctx, targeting("workload-kubeconfig", "missing"))
+	require.ErrorContains(t, err, `kubeconfig secret "workload-kubeconfig" has no key "missing"`)
+
+	secret.Data[DefaultKubeconfigKey] = kubeconfig("https://rotated.example.com:6443")
+	require.NoError(t, cl.Update(ctx, secret))
+	rotated, err := clusters.For(ctx, targeting("workload-kubeconfig", ""))
+	require.NoError(t, err)
+	require.NotSame(t, first, rotated)
+	require.Equal(t, "https://rotated.example.com:6443", rotated.GetConfig().Host)
+	require.True(t, first.Stopped(), "the cluster of the old kubeconfig is disconnected")
+
+	require.NoError(t, cl.Delete(ctx, secret))
+	_, err = clusters.For(ctx, targeting("workload-kubeconfig", ""))
+	require.ErrorContains(t, err, `get kubeconfig secret "workload-kubeconfig"`)
+	require.True(t, rotated.Stopped(), "the cluster of a deleted kubeconfig secret is disconnected")
+	require.False(t, admin.Stopped())
+}
+
+type actionConfigGetterFunc func(context.Context, client.Object) (*action.Configuration, error)
+
+func (f actionConfigGetterFunc) ActionConfigFor(ctx context.Context, obj client.Object) (*action.Configuration, error) {
+	return f(ctx, obj)
+}
+
+func TestActionConfigGetter(t *testing.T) {
+	clusters, _ := newClusters(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "workload-kubeconfig"},
+		Data:       map[string][]byte{DefaultKubeconfigKey: kubeconfig("https://workload.example.com:6443")},
+	})
+	local := &action.Configuration{Releases: storage.Init(driver.NewMemory())}
+	acg := clusters.ActionConfigGetter(actionConfigGetterFunc(func(context.Context, client.Object) (*action.Configuration, error) {
+		return &action.Configuration{Releases: local.Releases}, nil
+	}))
+
+	cfg, err := acg.ActionConfigFor(context.Background(), &rukpakv1alpha2.BundleDeployment{})
+	require.NoError(t, err)
+	require.Nil(t, cfg.RESTClientGetter, "BundleDeployments without a target use the local action config")
+
+	cfg, err = acg.ActionConfigFor(context.Background(), targeting("workload-kubeconfig", ""))
+	require.NoError(t, err)
+	require.Same(t, local.Releases, cfg.Releases, "releases are stored like the releases of local BundleDeployments")
+	restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
+	require.NoError(t, err)
+	require.Equal(t, "https://workload.example.com:6443", restConfig.Host)
+}
@@ -0,0 +1,29 @@
+package version
+
+import "errors"
+
+// FIPS reports whether the binary was built in FIPS mode, with
+// GOEXPERIMENT=boringcrypto. In FIPS mode, the cryptography of the binary,
+// including the SHA-256 digests of bundle content, is done by the FIPS 140
+// validated BoringCrypto module, and TLS is restricted to FIPS-approved
+// versions, cipher suites, curves and signature algorithms.
+func FIPS() bool {
+	return fipsBuild
+}
+
+// CheckFIPS returns an error if the binary was built in FIPS mode, but the
+// BoringCrypto module is not in use, so that binaries of FIPS builds never
+// fall back to cryptography that is not validated.
+func CheckFIPS() error {
+	if fipsBuild && !boringEnabled() {
+		return errors.New("built in FIPS mode, but the BoringCrypto module is not in use")
+	}
+	return nil
+}
+
+func fipsMode() string {
+	if fipsBuild {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,15 @@
+//go:build goexperiment.boringcrypto
+
+package version
+
+import (
+	"crypto/boring"
+	// Restricts TLS to FIPS-approved settings.
+	_ "crypto/tls/fipsonly"
+)
+
+const fipsBuild = true
+
+func boringEnabled() bool {
+	return boring.Enabled()
+}
@@ -0,0 +1,9 @@
+//go:build !goexperiment.boringcrypto
+
+package version
+
+const fipsBuild = false
+
+func boringEnabled() bool {
+	return false
+}
@@ -17,7 +17,7 @@ var (
 )
 
 func String() string {
-	return fmt.Sprintf("revision: %q, date: %q, state: %q", gitCommit, commitDate, repoState)
+	return fmt.Sprintf("revision: %q, date: %q, state: %q, fips: %q", gitCommit, commitDate, repoState, fipsMode())
 }
 
 func init() {
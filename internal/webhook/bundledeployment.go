@@ -19,23 +19,50 @@ package webhook
 import (
 	"context"
 	"fmt"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/patches"
+	"github.com/operator-framework/rukpak/internal/requirements"
+	"github.com/operator-framework/rukpak/pkg/provisioner/config"
+	"github.com/operator-framework/rukpak/pkg/provisioner/helm"
+	"github.com/operator-framework/rukpak/pkg/provisioner/plain"
+	"github.com/operator-framework/rukpak/pkg/provisioner/registry"
+	"github.com/operator-framework/rukpak/pkg/source"
 )
 
+// AllowMigrationAnnotation, when set to "true" on a BundleDeployment, allows
+// an update to change its provisionerClassName or installNamespace. Neither
+// provisioner cleans up the release installed under the previous values, so
+// whoever sets it is responsible for removing the previous release and its
+// objects.
+const AllowMigrationAnnotation = "core.rukpak.io/allow-migration"
+
 type BundleDeployment struct {
 	Client          client.Client
 	SystemNamespace string
+	// RequireImageDigests rejects image sources that reference their image
+	// by a tag rather than a digest, since the image behind a tag can change.
+	RequireImageDigests bool
+	// QuotaPolicy, if set, limits the BundleDeployments that each tenant may
+	// create.
+	QuotaPolicy *QuotaPolicy
 }
 
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=list;watch
@@ -44,13 +71,102 @@ type BundleDeployment struct {
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (b *BundleDeployment) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	bundleDeployment := obj.(*rukpakv1alpha2.BundleDeployment)
-	return b.checkBundleDeploymentSource(ctx, bundleDeployment)
+	if err := b.checkQuota(ctx, bundleDeployment); err != nil {
+		return nil, err
+	}
+	if err := b.checkSkipFinalizerCleanup(ctx, nil, bundleDeployment); err != nil {
+		return nil, err
+	}
+	return b.checkBundleDeploymentSource(ctx, nil, bundleDeployment)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (b *BundleDeployment) ValidateUpdate(ctx context.Context, _ runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+func (b *BundleDeployment) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	oldBundle := oldObj.(*rukpakv1alpha2.BundleDeployment)
 	newBundle := newObj.(*rukpakv1alpha2.BundleDeployment)
-	return b.checkBundleDeploymentSource(ctx, newBundle)
+	warnings, err := checkImmutableFields(oldBundle, newBundle)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkSkipFinalizerCleanup(ctx, oldBundle, newBundle); err != nil {
+		return nil, err
+	}
+	// Moving a BundleDeployment to another tenant counts against the quota
+	// of that tenant like creating it would.
+	if b.QuotaPolicy != nil && b.QuotaPolicy.tenant(oldBundle) != b.QuotaPolicy.tenant(newBundle) {
+		if err := b.checkQuota(ctx, newBundle); err != nil {
+			return nil, err
+		}
+	}
+	sourceWarnings, err := b.checkBundleDeploymentSource(ctx, oldBundle, newBundle)
+	return append(warnings, sourceWarnings...), err
+}
+
+// checkImmutableFields rejects changes of the provisionerClassName,
+// installNamespace and target of a BundleDeployment, unless the update is
+// annotated with AllowMigrationAnnotation. Changing either hands the
+// BundleDeployment over to a different release, or applies the release to a
+// different cluster, and the objects installed before are orphaned.
+func checkImmutableFields(oldBundle, newBundle *rukpakv1alpha2.BundleDeployment) (admission.Warnings, error) {
+	var changed []string
+	if oldBundle.Spec.ProvisionerClassName != newBundle.Spec.ProvisionerClassName {
+		changed = append(changed, fmt.Sprintf("bundledeployment.spec.provisionerClassName from %q to %q", oldBundle.Spec.ProvisionerClassName, newBundle.Spec.ProvisionerClassName))
+	}
+	if oldBundle.Spec.InstallNamespace != newBundle.Spec.InstallNamespace {
+		changed = append(changed, fmt.Sprintf("bundledeployment.spec.installNamespace from %q to %q", oldBundle.Spec.InstallNamespace, newBundle.Spec.InstallNamespace))
+	}
+	if targetName(oldBundle) != targetName(newBundle) {
+		changed = append(changed, fmt.Sprintf("bundledeployment.spec.target from %s to %s", targetName(oldBundle), targetName(newBundle)))
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if newBundle.GetAnnotations()[AllowMigrationAnnotation] != "true" {
+		return nil, fmt.Errorf("cannot change %s: the field is immutable unless the %s=true annotation is set, and the previously installed release is then left behind", strings.Join(changed, " and "), AllowMigrationAnnotation)
+	}
+	return admission.Warnings{fmt.Sprintf("changing %s: the previously installed release and its objects are not removed and must be cleaned up manually", strings.Join(changed, " and "))}, nil
+}
+
+// provisionerFormats are the bundle formats of the core provisioners that
+// support a single format. The auto provisioner supports every format, and
+// the formats of other provisioners are unknown.
+var provisionerFormats = map[string]rukpakv1alpha2.BundleFormat{
+	plain.ProvisionerID:    rukpakv1alpha2.FormatPlain,
+	registry.ProvisionerID: rukpakv1alpha2.FormatRegistry,
+	helm.ProvisionerID:     rukpakv1alpha2.FormatHelm,
+	// Config bundles have the layout of plain+v0 bundles.
+	config.ProvisionerID: rukpakv1alpha2.FormatPlain,
+}
+
+// validateFormat rejects unknown bundle formats, and formats that the
+// provisioner does not support.
+func validateFormat(provisionerClassName string, format rukpakv1alpha2.BundleFormat) error {
+	if format == "" {
+		return nil
+	}
+	supported := make([]string, 0, len(rukpakv1alpha2.SupportedFormats))
+	for _, f := range rukpakv1alpha2.SupportedFormats {
+		supported = append(supported, string(f))
+	}
+	if !slices.Contains(rukpakv1alpha2.SupportedFormats, format) {
+		return fmt.Errorf("bundledeployment.spec.format %q is not supported: supported formats are %s", format, strings.Join(supported, ", "))
+	}
+	if want, ok := provisionerFormats[provisionerClassName]; ok && format != want {
+		return fmt.Errorf("bundledeployment.spec.format %q is not supported by provisioner %q, which only supports %s", format, provisionerClassName, want)
+	}
+	return nil
+}
+
+// targetName describes the cluster that the objects of bd are applied to.
+func targetName(bd *rukpakv1alpha2.BundleDeployment) string {
+	if bd.Spec.Target == nil {
+		return "the local cluster"
+	}
+	ref := bd.Spec.Target.KubeconfigSecretRef
+	if ref.Key == "" {
+		return fmt.Sprintf("kubeconfig secret %q", ref.Name)
+	}
+	return fmt.Sprintf("kubeconfig secret %q, key %q", ref.Name, ref.Key)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -58,12 +174,42 @@ func (b *BundleDeployment) ValidateDelete(_ context.Context, _ runtime.Object) (
 	return nil, nil
 }
 
-func (b *BundleDeployment) checkBundleDeploymentSource(ctx context.Context, bundleDeployment *rukpakv1alpha2.BundleDeployment) (admission.Warnings, error) {
+// checkBundleDeploymentSource validates the spec of bundleDeployment. oldBundle
+// is the BundleDeployment before an update, or nil on creation.
+func (b *BundleDeployment) checkBundleDeploymentSource(ctx context.Context, oldBundle, bundleDeployment *rukpakv1alpha2.BundleDeployment) (admission.Warnings, error) {
+	if _, err := requirements.FromConfig(bundleDeployment.Spec.Config); err != nil {
+		return nil, fmt.Errorf("bundledeployment.spec.config is invalid: %v", err)
+	}
+	if _, err := patches.FromConfig(bundleDeployment.Spec.Config); err != nil {
+		return nil, fmt.Errorf("bundledeployment.spec.config is invalid: %v", err)
+	}
+	if bundleDeployment.Spec.ProvisionerClassName == registry.ProvisionerID {
+		if _, err := registry.ConfigFrom(bundleDeployment); err != nil {
+			return nil, fmt.Errorf("bundledeployment.spec.config is invalid: %v", err)
+		}
+	}
+	if err := validateObjectMetadata(bundleDeployment.Spec.Labels, bundleDeployment.Spec.Annotations); err != nil {
+		return nil, err
+	}
+	if err := validateFormat(bundleDeployment.Spec.ProvisionerClassName, bundleDeployment.Spec.Format); err != nil {
+		return nil, err
+	}
+	if bundleDeployment.Spec.Source.BundleDigest != "" {
+		return nil, fmt.Errorf("bundledeployment.spec.source.bundleDigest must not be set: it is only reported in bundledeployment.status.resolvedSource")
+	}
 	switch typ := bundleDeployment.Spec.Source.Type; typ {
 	case rukpakv1alpha2.SourceTypeImage:
 		if bundleDeployment.Spec.Source.Image == nil {
 			return nil, fmt.Errorf("bundledeployment.spec.source.image must be set for source type \"image\"")
 		}
+		if err := validatePathFilters("bundledeployment.spec.source.image", bundleDeployment.Spec.Source.Image.PathFilters); err != nil {
+			return nil, err
+		}
+		if b.RequireImageDigests && !imageRefUnchangedFrom(oldBundle, bundleDeployment.Spec.Source.Image.Ref) {
+			if err := validateImageDigest(bundleDeployment.Spec.Source.Image); err != nil {
+				return nil, err
+			}
+		}
 	case rukpakv1alpha2.SourceTypeGit:
 		if bundleDeployment.Spec.Source.Git == nil {
 			return nil, fmt.Errorf("bundledeployment.spec.source.git must be set for source type \"git\"")
@@ -71,6 +217,33 @@ func (b *BundleDeployment) checkBundleDeploymentSource(ctx context.Context, bund
 		if strings.HasPrefix(filepath.Clean(bundleDeployment.Spec.Source.Git.Directory), "../") {
 			return nil, fmt.Errorf(`bundledeployment.spec.source.git.directory begins with "../": directory must define path within the repository`)
 		}
+		if err := validatePathFilters("bundledeployment.spec.source.git", bundleDeployment.Spec.Source.Git.PathFilters); err != nil {
+			return nil, err
+		}
+		if verify := bundleDeployment.Spec.Source.Git.Verify; verify != nil && verify.Secret.Name == "" {
+			return nil, fmt.Errorf("bundledeployment.spec.source.git.verify.secret.name must be set")
+		}
+		if err := validateRetryPolicy("bundledeployment.spec.source.git.retry", bundleDeployment.Spec.Source.Git.Retry); err != nil {
+			return nil, err
+		}
+	case rukpakv1alpha2.SourceTypeHTTP:
+		if bundleDeployment.Spec.Source.HTTP == nil {
+			return nil, fmt.Errorf("bundledeployment.spec.source.http must be set for source type \"http\"")
+		}
+		if err := validatePathFilters("bundledeployment.spec.source.http", bundleDeployment.Spec.Source.HTTP.PathFilters); err != nil {
+			return nil, err
+		}
+		if err := validateRetryPolicy("bundledeployment.spec.source.http.retry", bundleDeployment.Spec.Source.HTTP.Retry); err != nil {
+			return nil, err
+		}
+	case rukpakv1alpha2.SourceTypeInline:
+		inline := bundleDeployment.Spec.Source.Inline
+		if inline == nil {
+			return nil, fmt.Errorf(`bundledeployment.spec.source.inline must be set for source type "inline"`)
+		}
+		if (len(inline.Manifests) == 0) == (len(inline.Gzipped) == 0) {
+			return nil, fmt.Errorf("bundledeployment.spec.source.inline must set exactly one of manifests and gzipped")
+		}
 	case rukpakv1alpha2.SourceTypeConfigMaps:
 		if len(bundleDeployment.Spec.Source.ConfigMaps) == 0 {
 			return nil, fmt.Errorf(`bundledeployment.spec.source.configmaps must be set for source type "configmaps"`)
@@ -87,10 +260,109 @@ func (b *BundleDeployment) checkBundleDeploymentSource(ctx context.Context, bund
 		if len(errs) > 0 {
 			return nil, utilerrors.NewAggregate(errs)
 		}
+	case rukpakv1alpha2.SourceTypeSecrets:
+		if len(bundleDeployment.Spec.Source.Secrets) == 0 {
+			return nil, fmt.Errorf(`bundledeployment.spec.source.secrets must be set for source type "secrets"`)
+		}
+		errs := []error{}
+		for i, secretSource := range bundleDeployment.Spec.Source.Secrets {
+			if strings.HasPrefix(filepath.Clean(secretSource.Path), ".."+string(filepath.Separator)) {
+				errs = append(errs, fmt.Errorf("bundledeployment.spec.source.secrets[%d].path is invalid: %q is outside bundle root", i, secretSource.Path))
+			}
+			if err := b.verifySecretImmutable(ctx, secretSource.Secret.Name); err != nil {
+				errs = append(errs, fmt.Errorf("bundledeployment.spec.source.secrets[%d].secret.name is invalid: %v", i, err))
+			}
+		}
+		if len(errs) > 0 {
+			return nil, utilerrors.NewAggregate(errs)
+		}
 	}
 	return nil, nil
 }
 
+// validateObjectMetadata validates the labels and annotations that are added
+// to every object of the bundle. Keys of the core.rukpak.io domain are
+// reserved for the labels and annotations that rukpak sets itself.
+func validateObjectMetadata(labels, annotations map[string]string) error {
+	labelsPath := field.NewPath("bundledeployment", "spec", "labels")
+	annotationsPath := field.NewPath("bundledeployment", "spec", "annotations")
+	errs := metav1validation.ValidateLabels(labels, labelsPath)
+	errs = append(errs, apivalidation.ValidateAnnotations(annotations, annotationsPath)...)
+	errs = append(errs, validateUnreservedKeys(labels, labelsPath)...)
+	errs = append(errs, validateUnreservedKeys(annotations, annotationsPath)...)
+	return errs.ToAggregate()
+}
+
+func validateUnreservedKeys(m map[string]string, fldPath *field.Path) field.ErrorList {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs field.ErrorList
+	for _, k := range keys {
+		domain, _, ok := strings.Cut(k, "/")
+		if ok && (domain == rukpakv1alpha2.GroupVersion.Group || strings.HasSuffix(domain, "."+rukpakv1alpha2.GroupVersion.Group)) {
+			errs = append(errs, field.Forbidden(fldPath.Key(k), "keys of the core.rukpak.io domain are reserved"))
+		}
+	}
+	return errs
+}
+
+// validateImageDigest rejects image sources that do not reference their image
+// by digest. Node-local images are referenced by the name that they are
+// loaded under, so they are exempt.
+func validateImageDigest(src *rukpakv1alpha2.ImageSource) error {
+	if src.NodeLocal {
+		return nil
+	}
+	ref, err := name.ParseReference(src.Ref)
+	if err != nil {
+		return fmt.Errorf("bundledeployment.spec.source.image.ref is invalid: %v", err)
+	}
+	if _, ok := ref.(name.Digest); !ok {
+		return fmt.Errorf("bundledeployment.spec.source.image.ref %q must reference the image by digest, e.g. %s@sha256:<digest>", src.Ref, ref.Context().Name())
+	}
+	return nil
+}
+
+// imageRefUnchangedFrom reports whether oldBundle referenced the image ref
+// already. Tags that were admitted before digests were required are not
+// rejected on unrelated updates, such as the finalizer updates of the
+// controllers, as the BundleDeployment could not be deleted otherwise.
+func imageRefUnchangedFrom(oldBundle *rukpakv1alpha2.BundleDeployment, ref string) bool {
+	return oldBundle != nil && oldBundle.Spec.Source.Image != nil && oldBundle.Spec.Source.Image.Ref == ref
+}
+
+func validatePathFilters(fieldPath string, filters rukpakv1alpha2.PathFilters) error {
+	errs := []error{}
+	for i, pattern := range filters.IncludePaths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s.includePaths[%d] is invalid: %q: %v", fieldPath, i, pattern, err))
+		}
+	}
+	for i, pattern := range filters.ExcludePaths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s.excludePaths[%d] is invalid: %q: %v", fieldPath, i, pattern, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateRetryPolicy rejects backoffs and timeouts that are not positive or
+// that exceed the limits that the sources apply to them, since retries block
+// the reconcile of the BundleDeployment.
+func validateRetryPolicy(fieldPath string, policy rukpakv1alpha2.RetryPolicy) error {
+	errs := []error{}
+	if policy.Backoff != nil && (policy.Backoff.Duration <= 0 || policy.Backoff.Duration > source.MaxRetryBackoff) {
+		errs = append(errs, fmt.Errorf("%s.backoff is invalid: %s: must be positive and at most %s", fieldPath, policy.Backoff.Duration, source.MaxRetryBackoff))
+	}
+	if policy.Timeout != nil && (policy.Timeout.Duration <= 0 || policy.Timeout.Duration > source.MaxRetryTimeout) {
+		errs = append(errs, fmt.Errorf("%s.timeout is invalid: %s: must be positive and at most %s", fieldPath, policy.Timeout.Duration, source.MaxRetryTimeout))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 func (b *BundleDeployment) verifyConfigMapImmutable(ctx context.Context, configMapName string) error {
 	var cm corev1.ConfigMap
 	err := b.Client.Get(ctx, client.ObjectKey{Namespace: b.SystemNamespace, Name: configMapName}, &cm)
@@ -103,6 +375,18 @@ func (b *BundleDeployment) verifyConfigMapImmutable(ctx context.Context, configM
 	return nil
 }
 
+func (b *BundleDeployment) verifySecretImmutable(ctx context.Context, secretName string) error {
+	var secret corev1.Secret
+	err := b.Client.Get(ctx, client.ObjectKey{Namespace: b.SystemNamespace, Name: secretName}, &secret)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if secret.Immutable == nil || !*secret.Immutable {
+		return fmt.Errorf("secret %q is not immutable", secretName)
+	}
+	return nil
+}
+
 func (b *BundleDeployment) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	mgr.GetWebhookServer().Register("/validate-core-rukpak-io-v1alpha2-bundledeployment", admission.WithCustomValidator(mgr.GetScheme(), &rukpakv1alpha2.BundleDeployment{}, b).WithRecoverPanic(true))
 	return nil
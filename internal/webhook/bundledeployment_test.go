@@ -0,0 +1,429 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestValidateUpdateImmutableFields(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system"}
+
+	oldBundle := &rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: rukpakv1alpha2.BundleDeploymentSpec{
+			InstallNamespace:     "test-ns",
+			ProvisionerClassName: "core-rukpak-io-plain",
+			Source: rukpakv1alpha2.BundleSource{
+				Type:  rukpakv1alpha2.SourceTypeImage,
+				Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		description  string
+		mutate       func(*rukpakv1alpha2.BundleDeployment)
+		wantErr      string
+		wantWarnings int
+	}{
+		{
+			description: "other spec changes are allowed",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.Source.Image.Ref = "quay.io/example/bundle:v2"
+			},
+		},
+		{
+			description: "changing the provisioner class is rejected",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.ProvisionerClassName = "core-rukpak-io-helm"
+			},
+			wantErr: `cannot change bundledeployment.spec.provisionerClassName from "core-rukpak-io-plain" to "core-rukpak-io-helm"`,
+		},
+		{
+			description: "changing the install namespace is rejected",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.InstallNamespace = "other-ns"
+			},
+			wantErr: `cannot change bundledeployment.spec.installNamespace from "test-ns" to "other-ns"`,
+		},
+		{
+			description: "changing the target is rejected",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.Target = &rukpakv1alpha2.Target{KubeconfigSecretRef: rukpakv1alpha2.KubeconfigSecretReference{Name: "workload-kubeconfig"}}
+			},
+			wantErr: `cannot change bundledeployment.spec.target from the local cluster to kubeconfig secret "workload-kubeconfig"`,
+		},
+		{
+			description: "changing the install namespace with the migration annotation is allowed with a warning",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Annotations = map[string]string{AllowMigrationAnnotation: "true"}
+				bd.Spec.InstallNamespace = "other-ns"
+			},
+			wantWarnings: 1,
+		},
+		{
+			description: "the migration annotation must be set to true",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Annotations = map[string]string{AllowMigrationAnnotation: "yes"}
+				bd.Spec.ProvisionerClassName = "core-rukpak-io-helm"
+			},
+			wantErr: "cannot change bundledeployment.spec.provisionerClassName",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			newBundle := oldBundle.DeepCopy()
+			tt.mutate(newBundle)
+			warnings, err := validator.ValidateUpdate(context.Background(), oldBundle, newBundle)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, warnings, tt.wantWarnings)
+		})
+	}
+}
+
+func TestValidateCreateObjectMetadata(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system"}
+
+	for _, tt := range []struct {
+		description string
+		labels      map[string]string
+		annotations map[string]string
+		wantErr     string
+	}{
+		{
+			description: "valid labels and annotations are allowed",
+			labels:      map[string]string{"example.com/cost-center": "cc-1234"},
+			annotations: map[string]string{"example.com/team": "Platform Team"},
+		},
+		{
+			description: "invalid label values are rejected",
+			labels:      map[string]string{"example.com/team": "Platform Team"},
+			wantErr:     `bundledeployment.spec.labels: Invalid value: "Platform Team"`,
+		},
+		{
+			description: "invalid annotation keys are rejected",
+			annotations: map[string]string{"example.com/team/name": "platform"},
+			wantErr:     `bundledeployment.spec.annotations: Invalid value: "example.com/team/name"`,
+		},
+		{
+			description: "keys of the core.rukpak.io domain are rejected",
+			labels:      map[string]string{"core.rukpak.io/owner-name": "other"},
+			wantErr:     `bundledeployment.spec.labels[core.rukpak.io/owner-name]: Forbidden`,
+		},
+		{
+			description: "keys of subdomains of core.rukpak.io are rejected",
+			annotations: map[string]string{"helm.core.rukpak.io/foo": "bar"},
+			wantErr:     `bundledeployment.spec.annotations[helm.core.rukpak.io/foo]: Forbidden`,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "test-ns",
+					ProvisionerClassName: "core-rukpak-io-plain",
+					Source: rukpakv1alpha2.BundleSource{
+						Type:  rukpakv1alpha2.SourceTypeImage,
+						Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+					},
+					Labels:      tt.labels,
+					Annotations: tt.annotations,
+				},
+			}
+			_, err := validator.ValidateCreate(context.Background(), bd)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateCreateRegistryConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system"}
+
+	bd := &rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: rukpakv1alpha2.BundleDeploymentSpec{
+			InstallNamespace:     "test-ns",
+			ProvisionerClassName: "core-rukpak-io-registry",
+			Config:               runtime.RawExtension{Raw: []byte(`{"generateRBAC":"yes"}`)},
+			Source: rukpakv1alpha2.BundleSource{
+				Type:  rukpakv1alpha2.SourceTypeImage,
+				Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+			},
+		},
+	}
+	_, err := validator.ValidateCreate(context.Background(), bd)
+	require.ErrorContains(t, err, "parse registry provisioner config")
+
+	bd.Spec.Config.Raw = []byte(`{"generateRBAC":true}`)
+	_, err = validator.ValidateCreate(context.Background(), bd)
+	require.NoError(t, err)
+}
+
+func TestValidateCreateFormat(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system"}
+
+	for _, tc := range []struct {
+		name          string
+		provisioner   string
+		format        rukpakv1alpha2.BundleFormat
+		expectedError string
+	}{
+		{name: "no format", provisioner: "core-rukpak-io-plain"},
+		{name: "format of the provisioner", provisioner: "core-rukpak-io-registry", format: rukpakv1alpha2.FormatRegistry},
+		{name: "any format with the auto provisioner", provisioner: "core-rukpak-io-auto", format: rukpakv1alpha2.FormatHelm},
+		{name: "any format with other provisioners", provisioner: "example-provisioner", format: rukpakv1alpha2.FormatHelm},
+		{
+			name:          "unknown format",
+			provisioner:   "core-rukpak-io-auto",
+			format:        "kustomize+v1",
+			expectedError: `bundledeployment.spec.format "kustomize+v1" is not supported: supported formats are plain+v0, registry+v1, helm+v3`,
+		},
+		{
+			name:          "format of another provisioner",
+			provisioner:   "core-rukpak-io-plain",
+			format:        rukpakv1alpha2.FormatHelm,
+			expectedError: `bundledeployment.spec.format "helm+v3" is not supported by provisioner "core-rukpak-io-plain", which only supports plain+v0`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "test-ns",
+					ProvisionerClassName: tc.provisioner,
+					Format:               tc.format,
+					Source: rukpakv1alpha2.BundleSource{
+						Type:  rukpakv1alpha2.SourceTypeImage,
+						Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+					},
+				},
+			}
+			_, err := validator.ValidateCreate(context.Background(), bd)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateCreateRetryPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system"}
+
+	for _, tc := range []struct {
+		name          string
+		retry         rukpakv1alpha2.RetryPolicy
+		expectedError string
+	}{
+		{name: "no retries"},
+		{
+			name:  "within limits",
+			retry: rukpakv1alpha2.RetryPolicy{Attempts: 10, Backoff: &metav1.Duration{Duration: time.Minute}, Timeout: &metav1.Duration{Duration: 5 * time.Minute}},
+		},
+		{
+			name:          "backoff too long",
+			retry:         rukpakv1alpha2.RetryPolicy{Attempts: 10, Backoff: &metav1.Duration{Duration: time.Hour}},
+			expectedError: "bundledeployment.spec.source.http.retry.backoff is invalid: 1h0m0s: must be positive and at most 1m0s",
+		},
+		{
+			name:          "timeout too long",
+			retry:         rukpakv1alpha2.RetryPolicy{Attempts: 10, Timeout: &metav1.Duration{Duration: time.Hour}},
+			expectedError: "bundledeployment.spec.source.http.retry.timeout is invalid: 1h0m0s: must be positive and at most 5m0s",
+		},
+		{
+			name:          "negative backoff",
+			retry:         rukpakv1alpha2.RetryPolicy{Attempts: 10, Backoff: &metav1.Duration{Duration: -time.Second}},
+			expectedError: "bundledeployment.spec.source.http.retry.backoff is invalid: -1s: must be positive and at most 1m0s",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "test-ns",
+					ProvisionerClassName: "core-rukpak-io-plain",
+					Source: rukpakv1alpha2.BundleSource{
+						Type: rukpakv1alpha2.SourceTypeHTTP,
+						HTTP: &rukpakv1alpha2.HTTPSource{URL: "https://example.com/bundle.tgz", Retry: tc.retry},
+					},
+				},
+			}
+			_, err := validator.ValidateCreate(context.Background(), bd)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateCreateRequireImageDigests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system", RequireImageDigests: true}
+
+	for _, tt := range []struct {
+		description string
+		source      rukpakv1alpha2.ImageSource
+		wantErr     string
+	}{
+		{
+			description: "digests are allowed",
+			source:      rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle@sha256:" + strings.Repeat("a", 64)},
+		},
+		{
+			description: "tags are rejected",
+			source:      rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+			wantErr:     `bundledeployment.spec.source.image.ref "quay.io/example/bundle:v1" must reference the image by digest`,
+		},
+		{
+			description: "node-local images are allowed by tag",
+			source:      rukpakv1alpha2.ImageSource{Ref: "example/bundle:v1", NodeLocal: true},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			source := tt.source
+			bd := &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "test-ns",
+					ProvisionerClassName: "core-rukpak-io-plain",
+					Source:               rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &source},
+				},
+			}
+			_, err := validator.ValidateCreate(context.Background(), bd)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateUpdateRequireImageDigests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system", RequireImageDigests: true}
+
+	// Admitted by tag before digests were required.
+	oldBundle := &rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: rukpakv1alpha2.BundleDeploymentSpec{
+			InstallNamespace:     "test-ns",
+			ProvisionerClassName: "core-rukpak-io-plain",
+			Source: rukpakv1alpha2.BundleSource{
+				Type:  rukpakv1alpha2.SourceTypeImage,
+				Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		description string
+		mutate      func(*rukpakv1alpha2.BundleDeployment)
+		wantErr     string
+	}{
+		{
+			description: "finalizer updates of an existing tag are allowed",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Finalizers = []string{"core.rukpak.io/uninstall-release"}
+			},
+		},
+		{
+			description: "spec updates that keep the tag are allowed",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.Labels = map[string]string{"app": "example"}
+			},
+		},
+		{
+			description: "changing the tag is rejected",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.Source.Image.Ref = "quay.io/example/bundle:v2"
+			},
+			wantErr: `bundledeployment.spec.source.image.ref "quay.io/example/bundle:v2" must reference the image by digest`,
+		},
+		{
+			description: "changing the tag to a digest is allowed",
+			mutate: func(bd *rukpakv1alpha2.BundleDeployment) {
+				bd.Spec.Source.Image.Ref = "quay.io/example/bundle@sha256:" + strings.Repeat("a", 64)
+			},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			newBundle := oldBundle.DeepCopy()
+			tt.mutate(newBundle)
+			_, err := validator.ValidateUpdate(context.Background(), oldBundle, newBundle)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateCreateBundleDigest(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	validator := &BundleDeployment{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), SystemNamespace: "rukpak-system"}
+
+	bd := &rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: rukpakv1alpha2.BundleDeploymentSpec{
+			InstallNamespace:     "test-ns",
+			ProvisionerClassName: "core-rukpak-io-plain",
+			Source: rukpakv1alpha2.BundleSource{
+				Type:         rukpakv1alpha2.SourceTypeImage,
+				Image:        &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+				BundleDigest: "sha256:content",
+			},
+		},
+	}
+	_, err := validator.ValidateCreate(context.Background(), bd)
+	require.ErrorContains(t, err, "bundledeployment.spec.source.bundleDigest must not be set")
+
+	_, err = validator.ValidateUpdate(context.Background(), bd, bd)
+	require.ErrorContains(t, err, "bundledeployment.spec.source.bundleDigest must not be set")
+
+	bd.Spec.Source.BundleDigest = ""
+	_, err = validator.ValidateCreate(context.Background(), bd)
+	require.NoError(t, err)
+}
@@ -0,0 +1,408 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+
+	defaultCertValidity   = 90 * 24 * time.Hour
+	defaultRotateBefore   = 30 * 24 * time.Hour
+	defaultRotateInterval = time.Minute
+)
+
+// CertRotator provisions a self-signed certificate for the webhook server,
+// rotates it before it expires, and injects its certificate authority into
+// the webhook configuration.
+//
+// The certificate authority and the serving certificate are stored in a
+// secret so that all replicas of the webhook server share them. Every replica
+// copies the serving certificate from the secret into CertDir, where the
+// webhook server picks it up without a restart. When the certificate is
+// rotated, the previous certificate authority remains in the CA bundle of
+// the webhook configuration until it expires, so that replicas that have not
+// picked up the new certificate yet continue to be trusted.
+type CertRotator struct {
+	Client client.Client
+
+	// Secret is the secret that stores the certificate authority and the
+	// serving certificate.
+	Secret types.NamespacedName
+	// DNSNames are the names the serving certificate is valid for.
+	DNSNames []string
+	// WebhookConfigurationName is the name of the validating webhook
+	// configuration that the certificate authority is injected into.
+	WebhookConfigurationName string
+	// MutatingWebhookConfigurationName is the name of the mutating webhook
+	// configuration that the certificate authority is injected into, if
+	// any.
+	MutatingWebhookConfigurationName string
+	// CertDir is the directory the webhook server reads its serving
+	// certificate from.
+	CertDir string
+
+	// CertValidity is how long generated certificates are valid for.
+	CertValidity time.Duration
+	// RotateBefore is how long before their expiry certificates are rotated.
+	RotateBefore time.Duration
+	// Interval is how often the certificates are checked.
+	Interval time.Duration
+}
+
+var _ manager.LeaderElectionRunnable = &CertRotator{}
+
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;update
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;update
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;update
+
+// Start periodically ensures that the certificates are valid until ctx is done.
+func (r *CertRotator) Start(ctx context.Context) error {
+	l := log.FromContext(ctx).WithName("cert-rotator")
+	ticker := time.NewTicker(r.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.Ensure(ctx); err != nil {
+				l.Error(err, "failed to ensure webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, because every replica of the webhook
+// server must keep its own copy of the serving certificate up-to-date.
+func (r *CertRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure generates new certificates if the stored ones are missing, invalid or
+// about to expire, writes the serving certificate to CertDir and injects the
+// certificate authority into the webhook configuration.
+func (r *CertRotator) Ensure(ctx context.Context) error {
+	data, err := r.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.writeCertFiles(data); err != nil {
+		return fmt.Errorf("write serving certificate: %v", err)
+	}
+	if err := r.injectCABundle(ctx, data[caCertKey]); err != nil {
+		return fmt.Errorf("inject CA bundle: %v", err)
+	}
+	return nil
+}
+
+// maxStoreAttempts is how often the certificate secret is read again after
+// another replica stored it concurrently.
+const maxStoreAttempts = 3
+
+// ensureSecret returns the certificates of the secret, and stores new ones
+// first if they cannot be used. Replicas that start or rotate at the same
+// time race to store the secret. The losers read the certificates of the
+// winner again and use them if they are valid, rather than failing.
+func (r *CertRotator) ensureSecret(ctx context.Context) (map[string][]byte, error) {
+	for attempt := 1; ; attempt++ {
+		secret := &corev1.Secret{}
+		exists := true
+		if err := r.Client.Get(ctx, r.Secret, secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("get certificate secret: %v", err)
+			}
+			exists = false
+			secret.Name, secret.Namespace = r.Secret.Name, r.Secret.Namespace
+			secret.Type = corev1.SecretTypeTLS
+		}
+
+		now := time.Now()
+		err := r.validate(secret.Data, now)
+		if err == nil {
+			return secret.Data, nil
+		}
+		log.FromContext(ctx).Info("generating new webhook certificates", "reason", err.Error())
+		data, err := r.generate(secret.Data[caCertKey], now)
+		if err != nil {
+			return nil, fmt.Errorf("generate certificates: %v", err)
+		}
+		secret.Data = data
+		if exists {
+			err = r.Client.Update(ctx, secret)
+		} else {
+			err = r.Client.Create(ctx, secret)
+		}
+		if err == nil {
+			return data, nil
+		}
+		if (apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err)) && attempt < maxStoreAttempts {
+			log.FromContext(ctx).Info("certificate secret was stored concurrently, reading it again", "reason", err.Error())
+			continue
+		}
+		return nil, fmt.Errorf("store certificate secret: %v", err)
+	}
+}
+
+func (r *CertRotator) interval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return defaultRotateInterval
+}
+
+func (r *CertRotator) certValidity() time.Duration {
+	if r.CertValidity > 0 {
+		return r.CertValidity
+	}
+	return defaultCertValidity
+}
+
+func (r *CertRotator) rotateBefore() time.Duration {
+	if r.RotateBefore > 0 {
+		return r.RotateBefore
+	}
+	return defaultRotateBefore
+}
+
+// validate returns an error describing why the certificates in data cannot
+// be used anymore, or nil if they are valid.
+func (r *CertRotator) validate(data map[string][]byte, now time.Time) error {
+	cas, err := parseCerts(data[caCertKey])
+	if err != nil || len(cas) == 0 {
+		return errors.New("no valid certificate authority found")
+	}
+	if _, err := parseKey(data[caKeyKey]); err != nil {
+		return errors.New("no valid certificate authority key found")
+	}
+	certs, err := parseCerts(data[corev1.TLSCertKey])
+	if err != nil || len(certs) == 0 {
+		return errors.New("no valid serving certificate found")
+	}
+	if _, err := parseKey(data[corev1.TLSPrivateKeyKey]); err != nil {
+		return errors.New("no valid serving certificate key found")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cas[0])
+	for _, name := range r.DNSNames {
+		if _, err := certs[0].Verify(x509.VerifyOptions{DNSName: name, Roots: roots, CurrentTime: now}); err != nil {
+			return fmt.Errorf("serving certificate is not valid for %q: %v", name, err)
+		}
+	}
+	if certs[0].NotAfter.Sub(now) < r.rotateBefore() {
+		return fmt.Errorf("serving certificate expires at %s", certs[0].NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// generate returns a new certificate authority and serving certificate. The
+// CA bundle retains the previous certificate authority for as long as it is
+// valid.
+func (r *CertRotator) generate(previousCABundle []byte, now time.Time) (map[string][]byte, error) {
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(r.certValidity())
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%s-ca@%d", r.Secret.Name, now.Unix())},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	certTemplate := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: r.DNSNames[0]},
+		DNSNames:     r.DNSNames,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, certTemplate, ca, &certKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	caKeyPEM, err := encodeKey(caKey)
+	if err != nil {
+		return nil, err
+	}
+	certKeyPEM, err := encodeKey(certKey)
+	if err != nil {
+		return nil, err
+	}
+
+	caBundle := encodeCert(caDER)
+	// Malformed previous bundles are dropped, they are replaced anyway.
+	previousCAs, _ := parseCerts(previousCABundle)
+	for _, previous := range previousCAs {
+		if previous.NotAfter.After(now) {
+			caBundle = append(caBundle, encodeCert(previous.Raw)...)
+			// Only the most recent previous certificate authority is retained.
+			break
+		}
+	}
+
+	return map[string][]byte{
+		caCertKey:               caBundle,
+		caKeyKey:                caKeyPEM,
+		corev1.TLSCertKey:       encodeCert(certDER),
+		corev1.TLSPrivateKeyKey: certKeyPEM,
+	}, nil
+}
+
+// writeCertFiles writes the serving certificate to CertDir, replacing the
+// files atomically so that the webhook server never reads a partial file.
+func (r *CertRotator) writeCertFiles(data map[string][]byte) error {
+	if err := os.MkdirAll(r.CertDir, 0700); err != nil {
+		return err
+	}
+	for _, name := range []string{corev1.TLSPrivateKeyKey, corev1.TLSCertKey} {
+		path := filepath.Join(r.CertDir, name)
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data[name]) {
+			continue
+		}
+		tmp, err := os.CreateTemp(r.CertDir, "."+name)
+		if err != nil {
+			return err
+		}
+		if _, err := tmp.Write(data[name]); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *CertRotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: r.WebhookConfigurationName}, vwc); err != nil {
+		return err
+	}
+	changed := false
+	for i := range vwc.Webhooks {
+		if !bytes.Equal(vwc.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			vwc.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if changed {
+		if err := r.Client.Update(ctx, vwc); err != nil {
+			return err
+		}
+	}
+	if r.MutatingWebhookConfigurationName == "" {
+		return nil
+	}
+
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: r.MutatingWebhookConfigurationName}, mwc); err != nil {
+		return err
+	}
+	changed = false
+	for i := range mwc.Webhooks {
+		if !bytes.Equal(mwc.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			mwc.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return r.Client.Update(ctx, mwc)
+}
+
+func newSerialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		// crypto/rand never fails on supported platforms.
+		panic(err)
+	}
+	return serial
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func parseKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
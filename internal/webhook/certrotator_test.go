@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestCertRotator(t *testing.T) {
+	ctx := context.Background()
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhooks"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "vbundledeployments.core.rukpak.io"},
+			{Name: "vconfigmaps.core.rukpak.io"},
+		},
+	}
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "mutating-webhooks"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "mbundledeployments.core.rukpak.io"},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(vwc, mwc).Build()
+	r := &CertRotator{
+		Client:                           cl,
+		Secret:                           types.NamespacedName{Namespace: "rukpak-system", Name: "webhook-certificate"},
+		DNSNames:                         []string{"webhook-service.rukpak-system.svc"},
+		WebhookConfigurationName:         "webhooks",
+		MutatingWebhookConfigurationName: "mutating-webhooks",
+		CertDir:                          t.TempDir(),
+	}
+
+	// The initial certificates are generated, stored and injected.
+	require.NoError(t, r.Ensure(ctx))
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(ctx, r.Secret, secret))
+	require.NoError(t, r.validate(secret.Data, time.Now()))
+	for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		data, err := os.ReadFile(filepath.Join(r.CertDir, name))
+		require.NoError(t, err)
+		require.Equal(t, secret.Data[name], data)
+	}
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Name: "webhooks"}, vwc))
+	for _, wh := range vwc.Webhooks {
+		require.Equal(t, secret.Data[caCertKey], wh.ClientConfig.CABundle)
+	}
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Name: "mutating-webhooks"}, mwc))
+	require.Equal(t, secret.Data[caCertKey], mwc.Webhooks[0].ClientConfig.CABundle)
+
+	// Valid certificates are left untouched.
+	initial := secret.Data
+	require.NoError(t, r.Ensure(ctx))
+	require.NoError(t, cl.Get(ctx, r.Secret, secret))
+	require.Equal(t, initial, secret.Data)
+
+	// Certificates are rotated before they expire and the previous CA remains trusted.
+	require.Error(t, r.validate(initial, time.Now().Add(defaultCertValidity-defaultRotateBefore+time.Hour)))
+	r.CertValidity = defaultRotateBefore - time.Hour
+	rotated, err := r.generate(initial[caCertKey], time.Now())
+	require.NoError(t, err)
+	require.Error(t, r.validate(rotated, time.Now()))
+	cas, err := parseCerts(rotated[caCertKey])
+	require.NoError(t, err)
+	require.Len(t, cas, 2)
+	previous, err := parseCerts(initial[caCertKey])
+	require.NoError(t, err)
+	require.Equal(t, previous[0].Raw, cas[1].Raw)
+
+	// Certificates that are not valid for the service are replaced.
+	r.CertValidity = 0
+	r.DNSNames = []string{"other-service.rukpak-system.svc"}
+	require.ErrorContains(t, r.validate(initial, time.Now()), "not valid for")
+}
+
+func TestCertRotatorConcurrentReplicas(t *testing.T) {
+	secretKey := types.NamespacedName{Namespace: "rukpak-system", Name: "webhook-certificate"}
+	newRotator := func(cl client.Client) *CertRotator {
+		return &CertRotator{
+			Client:                   cl,
+			Secret:                   secretKey,
+			DNSNames:                 []string{"webhook-service.rukpak-system.svc"},
+			WebhookConfigurationName: "webhooks",
+			CertDir:                  t.TempDir(),
+		}
+	}
+	vwc := func() *admissionregistrationv1.ValidatingWebhookConfiguration {
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhooks"},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vbundledeployments.core.rukpak.io"}},
+		}
+	}
+
+	for _, tt := range []struct {
+		description string
+		// expired stores certificates that need to be rotated before the
+		// replicas start.
+		expired bool
+	}{
+		{description: "another replica creates the secret first"},
+		{description: "another replica rotates the secret first", expired: true},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			ctx := context.Background()
+			base := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(vwc()).Build()
+			if tt.expired {
+				expired := newRotator(base)
+				expired.CertValidity = time.Hour
+				require.NoError(t, expired.Ensure(ctx))
+			}
+
+			// The other replica stores its certificates right before this
+			// replica does, as if they raced.
+			winner := newRotator(base)
+			raced := false
+			race := func(ctx context.Context) {
+				if !raced {
+					raced = true
+					require.NoError(t, winner.Ensure(ctx))
+				}
+			}
+			cl := interceptor.NewClient(base, interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					race(ctx)
+					return c.Create(ctx, obj, opts...)
+				},
+				Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if _, ok := obj.(*corev1.Secret); ok {
+						race(ctx)
+					}
+					return c.Update(ctx, obj, opts...)
+				},
+			})
+			r := newRotator(cl)
+			require.NoError(t, r.Ensure(ctx))
+			require.True(t, raced)
+
+			// The certificates of the winner are used rather than replaced.
+			secret := &corev1.Secret{}
+			require.NoError(t, base.Get(ctx, secretKey, secret))
+			require.NoError(t, r.validate(secret.Data, time.Now()))
+			data, err := os.ReadFile(filepath.Join(r.CertDir, corev1.TLSCertKey))
+			require.NoError(t, err)
+			require.Equal(t, secret.Data[corev1.TLSCertKey], data)
+			winnerData, err := os.ReadFile(filepath.Join(winner.CertDir, corev1.TLSCertKey))
+			require.NoError(t, err)
+			require.Equal(t, winnerData, data)
+		})
+	}
+}
+
+func TestCertRotatorStoreConflictsExhausted(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, obj.GetName())
+		},
+	}).Build()
+	r := &CertRotator{
+		Client:   cl,
+		Secret:   types.NamespacedName{Namespace: "rukpak-system", Name: "webhook-certificate"},
+		DNSNames: []string{"webhook-service.rukpak-system.svc"},
+		CertDir:  t.TempDir(),
+	}
+	require.ErrorContains(t, r.Ensure(ctx), "store certificate secret")
+}
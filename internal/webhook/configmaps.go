@@ -18,7 +18,8 @@ import (
 //+kubebuilder:webhook:path=/validate-core-v1-configmap,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=configmaps,verbs=create;delete,versions=v1,name=vconfigmaps.core.rukpak.io,admissionReviewVersions=v1
 
 type ConfigMap struct {
-	Client client.Client
+	Client          client.Client
+	SystemNamespace string
 }
 
 func (w *ConfigMap) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
@@ -56,20 +57,27 @@ func (w *ConfigMap) ValidateUpdate(_ context.Context, _, _ runtime.Object) (admi
 }
 
 func (w *ConfigMap) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	// Sources can only reference configmaps in the system namespace.
 	cm := obj.(*corev1.ConfigMap)
-
-	bundleList := &rukpakv1alpha2.BundleDeploymentList{}
-	if err := w.Client.List(ctx, bundleList); err != nil {
-		return nil, err
+	if cm.Namespace != w.SystemNamespace {
+		return nil, nil
 	}
-	for _, b := range bundleList.Items {
-		for _, cmSource := range b.Spec.Source.ConfigMaps {
+
+	live, deleting, err := listReferrers(ctx, w.Client, func(source rukpakv1alpha2.BundleSource) bool {
+		for _, cmSource := range source.ConfigMaps {
 			if cmSource.ConfigMap.Name == cm.Name {
-				return nil, fmt.Errorf("configmap %q is in-use by bundle %q", cm.Name, b.Name)
+				return true
 			}
 		}
+		return false
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	if len(live) > 0 {
+		return nil, fmt.Errorf("configmap %q is in-use by bundledeployments %v", cm.Name, live)
+	}
+	return referrerWarnings("configmap", cm.Name, deleting), nil
 }
 
 func (w *ConfigMap) SetupWebhookWithManager(mgr ctrl.Manager) error {
@@ -78,3 +86,34 @@ func (w *ConfigMap) SetupWebhookWithManager(mgr ctrl.Manager) error {
 }
 
 var _ webhook.CustomValidator = &ConfigMap{}
+
+// listReferrers returns the names of the bundledeployments whose source refers
+// to an object according to refersTo, split into bundledeployments that are
+// live and bundledeployments that are being deleted.
+func listReferrers(ctx context.Context, cl client.Client, refersTo func(rukpakv1alpha2.BundleSource) bool) ([]string, []string, error) {
+	bundleDeploymentList := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := cl.List(ctx, bundleDeploymentList); err != nil {
+		return nil, nil, err
+	}
+	var live, deleting []string
+	for _, bd := range bundleDeploymentList.Items {
+		if !refersTo(bd.Spec.Source) {
+			continue
+		}
+		if bd.DeletionTimestamp != nil {
+			deleting = append(deleting, bd.Name)
+		} else {
+			live = append(live, bd.Name)
+		}
+	}
+	return live, deleting, nil
+}
+
+// referrerWarnings warns about deleting an object that is still referenced by
+// bundledeployments that are being deleted.
+func referrerWarnings(kind, name string, deleting []string) admission.Warnings {
+	if len(deleting) == 0 {
+		return nil
+	}
+	return admission.Warnings{fmt.Sprintf("%s %q is referenced by bundledeployments %v that are being deleted", kind, name, deleting)}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/source"
+)
+
+// ImageDigestResolver pins the image sources of BundleDeployments to the
+// digest that their tag points to at admission time, so that the installed
+// content does not change when the tag is moved.
+type ImageDigestResolver struct {
+	// Enabled turns on the resolution. The webhook admits every
+	// BundleDeployment unchanged otherwise, so that it can be registered
+	// regardless of whether the resolution is wanted.
+	Enabled bool
+	// AuthNamespace is the namespace of the pull secrets of image sources.
+	AuthNamespace string
+
+	// resolve returns the digest reference of the image of src. Defaults to
+	// source.ResolveImageDigest.
+	resolve func(ctx context.Context, src *rukpakv1alpha2.ImageSource, authNamespace string) (name.Digest, error)
+}
+
+//+kubebuilder:webhook:path=/mutate-core-rukpak-io-v1alpha2-bundledeployment,mutating=true,failurePolicy=fail,sideEffects=None,groups=core.rukpak.io,resources=bundledeployments,verbs=create;update,versions=v1alpha2,name=mbundles.core.rukpak.io,admissionReviewVersions=v1
+
+// Default implements admission.CustomDefaulter. It resolves the tag of an
+// image source when the BundleDeployment is created, or when an update
+// changes the image reference.
+func (r *ImageDigestResolver) Default(ctx context.Context, obj runtime.Object) error {
+	if !r.Enabled {
+		return nil
+	}
+	bd := obj.(*rukpakv1alpha2.BundleDeployment)
+	src := bd.Spec.Source.Image
+	if bd.Spec.Source.Type != rukpakv1alpha2.SourceTypeImage || src == nil || src.NodeLocal {
+		return nil
+	}
+	ref, err := name.ParseReference(src.Ref)
+	if err != nil {
+		// Left for the unpacker to report.
+		return nil
+	}
+	if _, ok := ref.(name.Digest); ok {
+		return nil
+	}
+	if unchanged, err := imageRefUnchanged(ctx, src.Ref); err != nil || unchanged {
+		return err
+	}
+
+	resolve := r.resolve
+	if resolve == nil {
+		resolve = source.ResolveImageDigest
+	}
+	digest, err := resolve(ctx, src, r.AuthNamespace)
+	if err != nil {
+		return fmt.Errorf("resolve the digest of bundledeployment.spec.source.image.ref %q: %v", src.Ref, err)
+	}
+	log.FromContext(ctx).Info("pinned image source to digest", "bundleDeployment", bd.Name, "ref", src.Ref, "digest", digest.String())
+	src.Ref = digest.String()
+	return nil
+}
+
+// imageRefUnchanged reports whether the admission request of ctx updates a
+// BundleDeployment whose image reference was ref before already. Tags that
+// were admitted unresolved, e.g. before the resolution was enabled, are not
+// resolved by unrelated updates such as the ones of the controllers.
+func imageRefUnchanged(ctx context.Context, ref string) (bool, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil || req.Operation != admissionv1.Update {
+		return false, nil
+	}
+	old := &rukpakv1alpha2.BundleDeployment{}
+	if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+		return false, fmt.Errorf("decode the previous BundleDeployment: %v", err)
+	}
+	return old.Spec.Source.Image != nil && old.Spec.Source.Image.Ref == ref, nil
+}
+
+func (r *ImageDigestResolver) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/mutate-core-rukpak-io-v1alpha2-bundledeployment", admission.WithCustomDefaulter(mgr.GetScheme(), &rukpakv1alpha2.BundleDeployment{}, r).WithRecoverPanic(true))
+	return nil
+}
+
+var _ webhook.CustomDefaulter = &ImageDigestResolver{}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestImageDigestResolver(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	newBundle := func(ref string) *rukpakv1alpha2.BundleDeployment {
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: ref}}
+		return bd
+	}
+	updating := func(old *rukpakv1alpha2.BundleDeployment) context.Context {
+		raw, err := json.Marshal(old)
+		require.NoError(t, err)
+		return admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			OldObject: runtime.RawExtension{Raw: raw},
+		}})
+	}
+
+	resolved := 0
+	r := &ImageDigestResolver{
+		Enabled: true,
+		resolve: func(_ context.Context, src *rukpakv1alpha2.ImageSource, _ string) (name.Digest, error) {
+			resolved++
+			if strings.HasSuffix(src.Ref, ":missing") {
+				return name.Digest{}, errors.New("MANIFEST_UNKNOWN")
+			}
+			ref, err := name.ParseReference(src.Ref)
+			require.NoError(t, err)
+			return ref.Context().Digest(digest), nil
+		},
+	}
+
+	for _, tt := range []struct {
+		description  string
+		ctx          context.Context
+		ref          string
+		enabled      bool
+		wantRef      string
+		wantErr      string
+		wantResolved bool
+	}{
+		{
+			description:  "tags are pinned on create",
+			ctx:          context.Background(),
+			ref:          "quay.io/example/bundle:v1",
+			enabled:      true,
+			wantRef:      "quay.io/example/bundle@" + digest,
+			wantResolved: true,
+		},
+		{
+			description: "digests are left as is",
+			ctx:         context.Background(),
+			ref:         "quay.io/example/bundle@" + digest,
+			enabled:     true,
+			wantRef:     "quay.io/example/bundle@" + digest,
+		},
+		{
+			description:  "changed tags are pinned on update",
+			ctx:          updating(newBundle("quay.io/example/bundle@" + digest)),
+			ref:          "quay.io/example/bundle:v2",
+			enabled:      true,
+			wantRef:      "quay.io/example/bundle@" + digest,
+			wantResolved: true,
+		},
+		{
+			description: "unchanged tags are left as is on update",
+			ctx:         updating(newBundle("quay.io/example/bundle:v1")),
+			ref:         "quay.io/example/bundle:v1",
+			enabled:     true,
+			wantRef:     "quay.io/example/bundle:v1",
+		},
+		{
+			description: "tags are left as is when disabled",
+			ctx:         context.Background(),
+			ref:         "quay.io/example/bundle:v1",
+			wantRef:     "quay.io/example/bundle:v1",
+		},
+		{
+			description:  "unresolvable tags are rejected",
+			ctx:          context.Background(),
+			ref:          "quay.io/example/bundle:missing",
+			enabled:      true,
+			wantErr:      `resolve the digest of bundledeployment.spec.source.image.ref "quay.io/example/bundle:missing": MANIFEST_UNKNOWN`,
+			wantResolved: true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			resolved = 0
+			r.Enabled = tt.enabled
+			bd := newBundle(tt.ref)
+			err := r.Default(tt.ctx, bd)
+			require.Equal(t, tt.wantResolved, resolved > 0)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRef, bd.Spec.Source.Image.Ref)
+		})
+	}
+}
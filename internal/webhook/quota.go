@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// QuotaPolicy limits the number of BundleDeployments and their total bundle
+// storage per tenant. A tenant is identified by the value of the TenantLabel
+// of a BundleDeployment, or by its install namespace if the label is unset.
+type QuotaPolicy struct {
+	// TenantLabel is the label key whose value identifies the tenant of a
+	// BundleDeployment.
+	TenantLabel string `json:"tenantLabel,omitempty"`
+	// Default applies to tenants that are not listed in Tenants.
+	Default *Quota `json:"default,omitempty"`
+	// Tenants maps tenants to their quota.
+	Tenants map[string]Quota `json:"tenants,omitempty"`
+}
+
+// Quota is the limit of a single tenant. Unset limits are not enforced.
+type Quota struct {
+	// MaxBundleDeployments is the maximum number of BundleDeployments of the
+	// tenant.
+	MaxBundleDeployments *int `json:"maxBundleDeployments,omitempty"`
+	// MaxStorage is the maximum total size of the stored bundle content of
+	// the BundleDeployments of the tenant, e.g. 1Gi.
+	MaxStorage *resource.Quantity `json:"maxStorage,omitempty"`
+}
+
+// LoadQuotaPolicy reads a QuotaPolicy from a YAML or JSON file.
+func LoadQuotaPolicy(path string) (*QuotaPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read quota policy: %v", err)
+	}
+	policy := &QuotaPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("parse quota policy %q: %v", path, err)
+	}
+	return policy, nil
+}
+
+// tenant returns the tenant of bd.
+func (p *QuotaPolicy) tenant(bd *rukpakv1alpha2.BundleDeployment) string {
+	if p.TenantLabel != "" {
+		if tenant := bd.GetLabels()[p.TenantLabel]; tenant != "" {
+			return tenant
+		}
+	}
+	return bd.Spec.InstallNamespace
+}
+
+// quotaFor returns the quota of tenant, or nil if it has none.
+func (p *QuotaPolicy) quotaFor(tenant string) *Quota {
+	if q, ok := p.Tenants[tenant]; ok {
+		return &q
+	}
+	return p.Default
+}
+
+// checkQuota rejects bd if its tenant has reached any of the limits of its
+// quota with the BundleDeployments that exist already. Since the size of the
+// content of bd is not known before it is unpacked, the storage limit only
+// prevents new BundleDeployments once it is reached.
+func (b *BundleDeployment) checkQuota(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment) error {
+	if b.QuotaPolicy == nil {
+		return nil
+	}
+	tenant := b.QuotaPolicy.tenant(bd)
+	quota := b.QuotaPolicy.quotaFor(tenant)
+	if quota == nil || (quota.MaxBundleDeployments == nil && quota.MaxStorage == nil) {
+		return nil
+	}
+
+	bundleDeploymentList := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := b.Client.List(ctx, bundleDeploymentList); err != nil {
+		return fmt.Errorf("list bundledeployments to check the quota of tenant %q: %v", tenant, err)
+	}
+	count, storage := 0, int64(0)
+	for i := range bundleDeploymentList.Items {
+		existing := &bundleDeploymentList.Items[i]
+		if existing.Name == bd.Name || b.QuotaPolicy.tenant(existing) != tenant {
+			continue
+		}
+		count++
+		storage += existing.Status.ContentSize
+	}
+
+	if quota.MaxBundleDeployments != nil && count >= *quota.MaxBundleDeployments {
+		return fmt.Errorf("tenant %q has reached its quota of %d bundledeployments", tenant, *quota.MaxBundleDeployments)
+	}
+	if quota.MaxStorage != nil && storage >= quota.MaxStorage.Value() {
+		return fmt.Errorf("tenant %q has reached its bundle storage quota of %s with %s in use", tenant, quota.MaxStorage, resource.NewQuantity(storage, resource.BinarySI))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestValidateCreateQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	newBundle := func(name, team, installNamespace string, contentSize int64) *rukpakv1alpha2.BundleDeployment {
+		bd := &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{
+				InstallNamespace:     installNamespace,
+				ProvisionerClassName: "core-rukpak-io-plain",
+				Source: rukpakv1alpha2.BundleSource{
+					Type:  rukpakv1alpha2.SourceTypeImage,
+					Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+				},
+			},
+			Status: rukpakv1alpha2.BundleDeploymentStatus{ContentSize: contentSize},
+		}
+		if team != "" {
+			bd.Labels = map[string]string{"example.com/team": team}
+		}
+		return bd
+	}
+	existing := []client.Object{
+		newBundle("a-1", "a", "a-ns", 512*1024),
+		newBundle("a-2", "a", "a-ns", 512*1024),
+		newBundle("b-1", "b", "b-ns", 1024),
+		newBundle("c-1", "", "c-ns", 1024),
+	}
+	validator := &BundleDeployment{
+		Client:          fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing...).Build(),
+		SystemNamespace: "rukpak-system",
+		QuotaPolicy: &QuotaPolicy{
+			TenantLabel: "example.com/team",
+			Default:     &Quota{MaxBundleDeployments: ptr.To(1)},
+			Tenants: map[string]Quota{
+				"a": {MaxBundleDeployments: ptr.To(5), MaxStorage: ptr.To(resource.MustParse("1Mi"))},
+				"b": {MaxBundleDeployments: ptr.To(2)},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		description string
+		bundle      *rukpakv1alpha2.BundleDeployment
+		wantErr     string
+	}{
+		{
+			description: "tenant below its quota",
+			bundle:      newBundle("b-2", "b", "b-ns", 0),
+		},
+		{
+			description: "tenant over its storage quota",
+			bundle:      newBundle("a-3", "a", "a-ns", 0),
+			wantErr:     `tenant "a" has reached its bundle storage quota of 1Mi with 1Mi in use`,
+		},
+		{
+			description: "unlabeled bundledeployments are counted by install namespace",
+			bundle:      newBundle("c-2", "", "c-ns", 0),
+			wantErr:     `tenant "c-ns" has reached its quota of 1 bundledeployments`,
+		},
+		{
+			description: "default quota applies to unlisted tenants",
+			bundle:      newBundle("d-1", "d", "c-ns", 0),
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			_, err := validator.ValidateCreate(context.Background(), tt.bundle)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("moving to a tenant over its quota is rejected", func(t *testing.T) {
+		oldBundle := newBundle("b-1", "b", "b-ns", 1024)
+		movedBundle := newBundle("b-1", "a", "b-ns", 1024)
+		_, err := validator.ValidateUpdate(context.Background(), oldBundle, movedBundle)
+		require.ErrorContains(t, err, `tenant "a" has reached its bundle storage quota`)
+	})
+}
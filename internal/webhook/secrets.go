@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// The secret webhook ignores failures, so that an unavailable webhook server
+// does not block the deletion of secrets. It is only called for secrets in the
+// system namespace and for secrets in other namespaces with the
+// core.rukpak.io/protected=true label, see the namespace_selectors.yaml patch
+// of the webhook manifests.
+//+kubebuilder:webhook:path=/validate-core-v1-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=create;delete,versions=v1,name=vsecrets.core.rukpak.io,admissionReviewVersions=v1
+
+// Secret prevents the deletion of secrets that are referenced by the image
+// pull secret, the authorization or the content of a bundledeployment source,
+// and requires secrets that provide bundle content to be immutable.
+type Secret struct {
+	Client          client.Client
+	SystemNamespace string
+}
+
+func (w *Secret) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	// Only allow secret to be created if either of the following is true:
+	//   1. The secret is immutable.
+	//   2. The secret is not referenced by the content of a bundle.
+	secret := obj.(*corev1.Secret)
+	if secret.Namespace != w.SystemNamespace || (secret.Immutable != nil && *secret.Immutable) {
+		return nil, nil
+	}
+
+	live, deleting, err := listReferrers(ctx, w.Client, func(source rukpakv1alpha2.BundleSource) bool {
+		for _, secretSource := range source.Secrets {
+			if secretSource.Secret.Name == secret.Name {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	live = append(live, deleting...)
+	if len(live) > 0 {
+		return nil, fmt.Errorf("secret %q is referenced in .spec.source.secrets[].secret.name by bundledeployments %v; referenced secrets must have .immutable == true", secret.Name, live)
+	}
+	return nil, nil
+}
+
+func (w *Secret) ValidateUpdate(_ context.Context, _, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (w *Secret) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	secret := obj.(*corev1.Secret)
+	live, deleting, err := listReferrers(ctx, w.Client, func(source rukpakv1alpha2.BundleSource) bool {
+		for _, key := range sourceSecretKeys(source, w.SystemNamespace) {
+			if key.Namespace == secret.Namespace && key.Name == secret.Name {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(live) > 0 {
+		return nil, fmt.Errorf("secret %q is in-use by bundledeployments %v", secret.Name, live)
+	}
+	return referrerWarnings("secret", secret.Name, deleting), nil
+}
+
+// sourceSecretKeys returns the keys of the secrets that source refers to.
+// Only auth secrets may be in other namespaces than the system namespace.
+func sourceSecretKeys(source rukpakv1alpha2.BundleSource, systemNamespace string) []types.NamespacedName {
+	authKey := func(auth rukpakv1alpha2.Authorization) types.NamespacedName {
+		if auth.Namespace != "" {
+			return types.NamespacedName{Namespace: auth.Namespace, Name: auth.Secret.Name}
+		}
+		return types.NamespacedName{Namespace: systemNamespace, Name: auth.Secret.Name}
+	}
+	switch source.Type {
+	case rukpakv1alpha2.SourceTypeImage:
+		if source.Image != nil {
+			return []types.NamespacedName{{Namespace: systemNamespace, Name: source.Image.ImagePullSecretName}}
+		}
+	case rukpakv1alpha2.SourceTypeGit:
+		if source.Git != nil {
+			return []types.NamespacedName{authKey(source.Git.Auth)}
+		}
+	case rukpakv1alpha2.SourceTypeHTTP:
+		if source.HTTP != nil {
+			return []types.NamespacedName{authKey(source.HTTP.Auth)}
+		}
+	case rukpakv1alpha2.SourceTypeSecrets:
+		keys := make([]types.NamespacedName, 0, len(source.Secrets))
+		for _, secretSource := range source.Secrets {
+			keys = append(keys, types.NamespacedName{Namespace: systemNamespace, Name: secretSource.Secret.Name})
+		}
+		return keys
+	}
+	return nil
+}
+
+func (w *Secret) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/validate-core-v1-secret", admission.WithCustomValidator(mgr.GetScheme(), &corev1.Secret{}, w).WithRecoverPanic(true))
+	return nil
+}
+
+var _ webhook.CustomValidator = &Secret{}
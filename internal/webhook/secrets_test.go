@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestValidateDeleteReferencedObjects(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	now := metav1.Now()
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "git"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{Source: rukpakv1alpha2.BundleSource{
+				Type: rukpakv1alpha2.SourceTypeGit,
+				Git:  &rukpakv1alpha2.GitSource{Auth: rukpakv1alpha2.Authorization{Secret: corev1.LocalObjectReference{Name: "git-auth"}}},
+			}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "http"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{Source: rukpakv1alpha2.BundleSource{
+				Type: rukpakv1alpha2.SourceTypeHTTP,
+				HTTP: &rukpakv1alpha2.HTTPSource{Auth: rukpakv1alpha2.Authorization{Namespace: "team-a", Secret: corev1.LocalObjectReference{Name: "git-auth"}}},
+			}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "configmaps"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{Source: rukpakv1alpha2.BundleSource{
+				Type:       rukpakv1alpha2.SourceTypeConfigMaps,
+				ConfigMaps: []rukpakv1alpha2.ConfigMapSource{{ConfigMap: corev1.LocalObjectReference{Name: "manifests"}}},
+			}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "secrets"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{Source: rukpakv1alpha2.BundleSource{
+				Type:    rukpakv1alpha2.SourceTypeSecrets,
+				Secrets: []rukpakv1alpha2.SecretSource{{Secret: corev1.LocalObjectReference{Name: "sensitive-manifests"}}},
+			}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "image", DeletionTimestamp: &now, Finalizers: []string{"test"}},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{Source: rukpakv1alpha2.BundleSource{
+				Type:  rukpakv1alpha2.SourceTypeImage,
+				Image: &rukpakv1alpha2.ImageSource{ImagePullSecretName: "pull-secret"},
+			}},
+		},
+	).Build()
+	secrets := &Secret{Client: cl, SystemNamespace: "rukpak-system"}
+	configMaps := &ConfigMap{Client: cl, SystemNamespace: "rukpak-system"}
+
+	for _, tt := range []struct {
+		description     string
+		validate        func(context.Context, runtime.Object) (admission.Warnings, error)
+		obj             runtime.Object
+		expectedErr     string
+		expectedWarning string
+	}{
+		{
+			description: "secret referenced by a live bundledeployment",
+			validate:    secrets.ValidateDelete,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "git-auth"}},
+			expectedErr: `secret "git-auth" is in-use by bundledeployments [git]`,
+		},
+		{
+			description:     "secret referenced by a bundledeployment that is being deleted",
+			validate:        secrets.ValidateDelete,
+			obj:             &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "pull-secret"}},
+			expectedWarning: `secret "pull-secret" is referenced by bundledeployments [image] that are being deleted`,
+		},
+		{
+			description: "secret referenced as bundle content",
+			validate:    secrets.ValidateDelete,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "sensitive-manifests"}},
+			expectedErr: `secret "sensitive-manifests" is in-use by bundledeployments [secrets]`,
+		},
+		{
+			description: "creating a mutable secret referenced as bundle content",
+			validate:    secrets.ValidateCreate,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "sensitive-manifests"}},
+			expectedErr: `secret "sensitive-manifests" is referenced in .spec.source.secrets[].secret.name by bundledeployments [secrets]; referenced secrets must have .immutable == true`,
+		},
+		{
+			description: "creating an immutable secret referenced as bundle content",
+			validate:    secrets.ValidateCreate,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "sensitive-manifests"}, Immutable: ptr.To(true)},
+		},
+		{
+			description: "secret in another namespace",
+			validate:    secrets.ValidateDelete,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "git-auth"}},
+		},
+		{
+			description: "secret in another namespace referenced by a live bundledeployment",
+			validate:    secrets.ValidateDelete,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "git-auth"}},
+			expectedErr: `secret "git-auth" is in-use by bundledeployments [http]`,
+		},
+		{
+			description: "unreferenced secret",
+			validate:    secrets.ValidateDelete,
+			obj:         &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "other"}},
+		},
+		{
+			description: "configmap referenced by a live bundledeployment",
+			validate:    configMaps.ValidateDelete,
+			obj:         &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "manifests"}},
+			expectedErr: `configmap "manifests" is in-use by bundledeployments [configmaps]`,
+		},
+		{
+			description: "configmap in another namespace",
+			validate:    configMaps.ValidateDelete,
+			obj:         &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "manifests"}},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			warnings, err := tt.validate(context.Background(), tt.obj)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectedWarning != "" {
+				require.Equal(t, admission.Warnings{tt.expectedWarning}, warnings)
+			} else {
+				require.Empty(t, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// SkipFinalizerCleanupVerb is the verb on BundleDeployments that a user needs
+// to be granted to set rukpakv1alpha2.SkipFinalizerCleanupAnnotation.
+const SkipFinalizerCleanupVerb = "skip-finalizer-cleanup"
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// checkSkipFinalizerCleanup rejects setting the skip finalizer cleanup
+// annotation of a BundleDeployment by users that are not allowed to skip the
+// cleanup, since it may leave bundle content behind. oldBundle is nil for
+// creates.
+func (b *BundleDeployment) checkSkipFinalizerCleanup(ctx context.Context, oldBundle, newBundle *rukpakv1alpha2.BundleDeployment) error {
+	if newBundle.GetAnnotations()[rukpakv1alpha2.SkipFinalizerCleanupAnnotation] != "true" {
+		return nil
+	}
+	if oldBundle != nil && oldBundle.GetAnnotations()[rukpakv1alpha2.SkipFinalizerCleanupAnnotation] == "true" {
+		return nil
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for k, v := range req.UserInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:    rukpakv1alpha2.GroupVersion.Group,
+				Resource: "bundledeployments",
+				Verb:     SkipFinalizerCleanupVerb,
+				Name:     newBundle.Name,
+			},
+		},
+	}
+	if err := b.Client.Create(ctx, review); err != nil {
+		return fmt.Errorf("check permission to set the %s annotation: %v", rukpakv1alpha2.SkipFinalizerCleanupAnnotation, err)
+	}
+	if !review.Status.Allowed {
+		return fmt.Errorf("setting the %s annotation requires the %s verb on bundledeployments", rukpakv1alpha2.SkipFinalizerCleanupAnnotation, SkipFinalizerCleanupVerb)
+	}
+	return nil
+}
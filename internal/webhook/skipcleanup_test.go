@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestCheckSkipFinalizerCleanup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	var reviews []authorizationv1.SubjectAccessReviewSpec
+	validator := &BundleDeployment{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+				review := obj.(*authorizationv1.SubjectAccessReview)
+				reviews = append(reviews, review.Spec)
+				review.Status.Allowed = review.Spec.User == "admin"
+				return nil
+			},
+		}).Build(),
+	}
+	contextFor := func(user string) context.Context {
+		return admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: user, Groups: []string{"system:authenticated"}},
+		}})
+	}
+	bundle := func(annotation string) *rukpakv1alpha2.BundleDeployment {
+		bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		if annotation != "" {
+			bd.Annotations = map[string]string{rukpakv1alpha2.SkipFinalizerCleanupAnnotation: annotation}
+		}
+		return bd
+	}
+
+	require.NoError(t, validator.checkSkipFinalizerCleanup(contextFor("user"), bundle(""), bundle("false")))
+	require.NoError(t, validator.checkSkipFinalizerCleanup(contextFor("user"), bundle("true"), bundle("true")), "unchanged annotations are not checked")
+	require.Empty(t, reviews)
+
+	require.NoError(t, validator.checkSkipFinalizerCleanup(contextFor("admin"), bundle(""), bundle("true")))
+	require.Len(t, reviews, 1)
+	require.Equal(t, "admin", reviews[0].User)
+	require.Equal(t, &authorizationv1.ResourceAttributes{
+		Group:    "core.rukpak.io",
+		Resource: "bundledeployments",
+		Verb:     SkipFinalizerCleanupVerb,
+		Name:     "test",
+	}, reviews[0].ResourceAttributes)
+
+	require.ErrorContains(t, validator.checkSkipFinalizerCleanup(contextFor("user"), nil, bundle("true")), "requires the skip-finalizer-cleanup verb")
+}
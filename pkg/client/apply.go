@@ -0,0 +1,40 @@
+// Package client contains helpers for clients that manage rukpak APIs with
+// controller-runtime.
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acv1alpha2 "github.com/operator-framework/rukpak/pkg/client/applyconfiguration/api/v1alpha2"
+)
+
+// ApplyBundleDeployment applies bd with server-side apply, so that the fields
+// set in bd are owned by fieldManager. Fields that fieldManager set in a
+// previous apply and that are not set in bd are removed. Conflicts with other
+// field managers fail the apply, unless client.ForceOwnership is passed.
+//
+// The controller-runtime client does not accept apply configurations, so bd
+// is sent as an unstructured object.
+func ApplyBundleDeployment(ctx context.Context, cl client.Client, bd *acv1alpha2.BundleDeploymentApplyConfiguration, fieldManager string, opts ...client.PatchOption) error {
+	obj, err := toUnstructured(bd)
+	if err != nil {
+		return err
+	}
+	return cl.Patch(ctx, obj, client.Apply, append([]client.PatchOption{client.FieldOwner(fieldManager)}, opts...)...)
+}
+
+func toUnstructured(ac interface{}) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(ac)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
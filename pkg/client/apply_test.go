@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	acv1alpha2 "github.com/operator-framework/rukpak/pkg/client/applyconfiguration/api/v1alpha2"
+)
+
+func TestApplyBundleDeployment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	var (
+		patched   *unstructured.Unstructured
+		patchType types.PatchType
+		options   client.PatchOptions
+	)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patched = obj.(*unstructured.Unstructured)
+			patchType = patch.Type()
+			options.ApplyOptions(opts)
+			return nil
+		},
+	}).Build()
+
+	bd := acv1alpha2.BundleDeployment("test").
+		WithSpec(acv1alpha2.BundleDeploymentSpec().
+			WithInstallNamespace("test-ns").
+			WithProvisionerClassName("core-rukpak-io-plain").
+			WithSource(acv1alpha2.BundleSource().
+				WithType(rukpakv1alpha2.SourceTypeImage).
+				WithImage(acv1alpha2.ImageSource().WithRef("quay.io/example/bundle:v1"))))
+	require.NoError(t, ApplyBundleDeployment(context.Background(), cl, bd, "operator-controller", client.ForceOwnership))
+
+	require.Equal(t, types.ApplyPatchType, patchType)
+	require.Equal(t, "operator-controller", options.FieldManager)
+	require.True(t, *options.Force)
+	require.Equal(t, map[string]interface{}{
+		"apiVersion": "core.rukpak.io/v1alpha2",
+		"kind":       "BundleDeployment",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"spec": map[string]interface{}{
+			"installNamespace":     "test-ns",
+			"provisionerClassName": "core-rukpak-io-plain",
+			"source": map[string]interface{}{
+				"type":  "image",
+				"image": map[string]interface{}{"ref": "quay.io/example/bundle:v1"},
+			},
+		},
+	}, patched.Object)
+}
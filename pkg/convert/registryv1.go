@@ -2,15 +2,18 @@ package convert
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing/fstest"
 	"time"
 
+	"github.com/blang/semver/v4"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -19,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
 	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
@@ -32,18 +36,69 @@ import (
 
 type RegistryV1 struct {
 	PackageName string
-	CSV         v1alpha1.ClusterServiceVersion
-	CRDs        []apiextensionsv1.CustomResourceDefinition
-	Others      []unstructured.Unstructured
+	// Version is the version of the bundle as declared by its olm.package
+	// property, or by the CSV if the bundle does not declare one.
+	Version string
+	// ProvidedAPIs are the APIs provided by the bundle as declared by its
+	// olm.gvk properties, or by the CSV if the bundle does not declare any.
+	ProvidedAPIs []metav1.GroupVersionKind
+	// Channels are the channels that the bundle is in, as declared by its
+	// annotations.
+	Channels []string
+	// DefaultChannel is the default channel of the package as declared by
+	// the annotations of the bundle, or its only channel if it declares
+	// none.
+	DefaultChannel string
+	// MaxOpenShiftVersion is the latest OpenShift version that the bundle
+	// supports, as declared by its olm.maxOpenShiftVersion property. Only the
+	// major and minor version are significant.
+	MaxOpenShiftVersion string
+	// RelatedImages are the images that the CSV declares in its
+	// relatedImages, followed by the images of the containers of its
+	// deployments that it does not declare there.
+	RelatedImages []v1alpha1.RelatedImage
+	CSV           v1alpha1.ClusterServiceVersion
+	CRDs          []apiextensionsv1.CustomResourceDefinition
+	Others        []unstructured.Unstructured
 }
 
 type Plain struct {
 	Objects []client.Object
 }
 
-func RegistryV1ToPlain(rv1 fs.FS, installNamespace string, watchNamespaces []string) (fs.FS, error) {
+// Option configures a conversion.
+type Option func(*options)
+
+type options struct {
+	rbacOwner string
+}
+
+// WithGeneratedRBAC runs the deployments of the bundle as ServiceAccounts
+// that are dedicated to the install named owner, rather than the
+// ServiceAccounts named by the CSV, which may be shared with other workloads
+// of the install namespace. The Roles and ClusterRoles that grant the
+// permissions of the CSV are named after owner as well, so that no two
+// installs share them, and every generated object is labeled with
+// util.GeneratedRBACKey.
+func WithGeneratedRBAC(owner string) Option {
+	return func(o *options) {
+		o.rbacOwner = owner
+	}
+}
+
+func RegistryV1ToPlain(rv1 fs.FS, installNamespace string, watchNamespaces []string, opts ...Option) (fs.FS, error) {
+	reg, err := ParseRegistryV1(rv1)
+	if err != nil {
+		return nil, err
+	}
+	return PlainFS(*reg, installNamespace, watchNamespaces, opts...)
+}
+
+// ParseRegistryV1 reads the metadata and manifests of the registry+v1 bundle
+// represented by rv1.
+func ParseRegistryV1(rv1 fs.FS) (*RegistryV1, error) {
 	reg := RegistryV1{}
-	fileData, err := fs.ReadFile(rv1, filepath.Join("metadata", "annotations.yaml"))
+	fileData, err := readManifest(rv1, filepath.Join("metadata", "annotations.yaml"))
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +107,15 @@ func RegistryV1ToPlain(rv1 fs.FS, installNamespace string, watchNamespaces []str
 		return nil, err
 	}
 	reg.PackageName = annotationsFile.Annotations.PackageName
+	for _, channel := range strings.Split(annotationsFile.Annotations.Channels, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			reg.Channels = append(reg.Channels, channel)
+		}
+	}
+	reg.DefaultChannel = annotationsFile.Annotations.DefaultChannelName
+	if reg.DefaultChannel == "" && len(reg.Channels) == 1 {
+		reg.DefaultChannel = reg.Channels[0]
+	}
 
 	var objects []*unstructured.Unstructured
 	const manifestsDir = "manifests"
@@ -64,7 +128,7 @@ func RegistryV1ToPlain(rv1 fs.FS, installNamespace string, watchNamespaces []str
 		if e.IsDir() {
 			return nil, fmt.Errorf("subdirectories are not allowed within the %q directory of the bundle image filesystem: found %q", manifestsDir, filepath.Join(manifestsDir, e.Name()))
 		}
-		fileData, err := fs.ReadFile(rv1, filepath.Join(manifestsDir, e.Name()))
+		fileData, err := readManifest(rv1, filepath.Join(manifestsDir, e.Name()))
 		if err != nil {
 			return nil, err
 		}
@@ -103,7 +167,147 @@ func RegistryV1ToPlain(rv1 fs.FS, installNamespace string, watchNamespaces []str
 		}
 	}
 
-	plain, err := Convert(reg, installNamespace, watchNamespaces)
+	if err := parseProperties(rv1, &reg); err != nil {
+		return nil, err
+	}
+	reg.RelatedImages = relatedImages(reg.CSV)
+	return &reg, nil
+}
+
+// relatedImages returns the images that csv declares in its relatedImages,
+// and the images of the containers and init containers of its deployments
+// that it does not declare, named after their containers. Mirroring tools
+// need both, since the declared images need not include the operator.
+func relatedImages(csv v1alpha1.ClusterServiceVersion) []v1alpha1.RelatedImage {
+	seen := sets.New[string]()
+	var images []v1alpha1.RelatedImage
+	add := func(name, image string) {
+		if image == "" || seen.Has(image) {
+			return
+		}
+		seen.Insert(image)
+		images = append(images, v1alpha1.RelatedImage{Name: name, Image: image})
+	}
+	for _, image := range csv.Spec.RelatedImages {
+		add(image.Name, image.Image)
+	}
+	for _, deployment := range csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+		podSpec := deployment.Spec.Template.Spec
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			add(container.Name, container.Image)
+		}
+	}
+	return images
+}
+
+// readManifest reads the YAML or JSON file at path and normalizes its encoding
+// and line endings.
+func readManifest(rv1 fs.FS, path string) ([]byte, error) {
+	data, err := fs.ReadFile(rv1, path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = util.NormalizeManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %v", path, err)
+	}
+	return data, nil
+}
+
+// parseProperties populates the package name, version, provided APIs and
+// maximum OpenShift version of reg from the optional metadata/properties.yaml
+// file of the bundle, falling back to the information declared by the CSV.
+func parseProperties(rv1 fs.FS, reg *RegistryV1) error {
+	fileData, err := readManifest(rv1, filepath.Join("metadata", "properties.yaml"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	propertiesFile := registry.PropertiesFile{}
+	if err := yaml.Unmarshal(fileData, &propertiesFile); err != nil {
+		return fmt.Errorf("parse metadata/properties.yaml: %v", err)
+	}
+	if err := parseMaxOpenShiftVersion(propertiesFile.Properties, reg); err != nil {
+		return fmt.Errorf("parse metadata/properties.yaml: %v", err)
+	}
+	if annotation, ok := reg.CSV.Annotations[registry.PropertiesAnnotationKey]; ok && reg.MaxOpenShiftVersion == "" {
+		var csvProperties []registry.Property
+		if err := json.Unmarshal([]byte(annotation), &csvProperties); err != nil {
+			return fmt.Errorf("parse CSV annotation %q: %v", registry.PropertiesAnnotationKey, err)
+		}
+		if err := parseMaxOpenShiftVersion(csvProperties, reg); err != nil {
+			return fmt.Errorf("parse CSV annotation %q: %v", registry.PropertiesAnnotationKey, err)
+		}
+	}
+
+	providedAPIs := sets.New[metav1.GroupVersionKind]()
+	for i, p := range propertiesFile.Properties {
+		switch p.Type {
+		case registry.PropertyTypePackage:
+			pkg := registry.PackageProperty{}
+			if err := json.Unmarshal(p.Value, &pkg); err != nil {
+				return fmt.Errorf("parse metadata/properties.yaml: properties[%d]: %v", i, err)
+			}
+			if pkg.PackageName != "" {
+				reg.PackageName = pkg.PackageName
+			}
+			reg.Version = pkg.Version
+		case registry.PropertyTypeGVK:
+			gvk := registry.GVKProperty{}
+			if err := json.Unmarshal(p.Value, &gvk); err != nil {
+				return fmt.Errorf("parse metadata/properties.yaml: properties[%d]: %v", i, err)
+			}
+			providedAPIs.Insert(metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind})
+		}
+	}
+
+	if reg.Version == "" && !reg.CSV.Spec.Version.Equals(semver.Version{}) {
+		reg.Version = reg.CSV.Spec.Version.String()
+	}
+	if providedAPIs.Len() == 0 {
+		for _, crd := range reg.CSV.Spec.CustomResourceDefinitions.Owned {
+			_, group, _ := strings.Cut(crd.Name, ".")
+			providedAPIs.Insert(metav1.GroupVersionKind{Group: group, Version: crd.Version, Kind: crd.Kind})
+		}
+		for _, api := range reg.CSV.Spec.APIServiceDefinitions.Owned {
+			providedAPIs.Insert(metav1.GroupVersionKind{Group: api.Group, Version: api.Version, Kind: api.Kind})
+		}
+	}
+	reg.ProvidedAPIs = providedAPIs.UnsortedList()
+	sort.Slice(reg.ProvidedAPIs, func(i, j int) bool {
+		a, b := reg.ProvidedAPIs[i], reg.ProvidedAPIs[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Version < b.Version
+	})
+	return nil
+}
+
+// parseMaxOpenShiftVersion populates the maximum OpenShift version of reg
+// from the olm.maxOpenShiftVersion property in properties, if any.
+func parseMaxOpenShiftVersion(properties []registry.Property, reg *RegistryV1) error {
+	for i, p := range properties {
+		if p.Type != registry.PropertyTypeMaxOpenShiftVersion {
+			continue
+		}
+		var maxVersion string
+		if err := json.Unmarshal(p.Value, &maxVersion); err != nil {
+			return fmt.Errorf("properties[%d]: %v", i, err)
+		}
+		if _, err := version.ParseGeneric(maxVersion); err != nil {
+			return fmt.Errorf("properties[%d]: invalid %s: %v", i, registry.PropertyTypeMaxOpenShiftVersion, err)
+		}
+		reg.MaxOpenShiftVersion = maxVersion
+	}
+	return nil
+}
+
+// PlainFS converts reg into the filesystem of a plain+v0 bundle.
+func PlainFS(reg RegistryV1, installNamespace string, watchNamespaces []string, opts ...Option) (fs.FS, error) {
+	plain, err := Convert(reg, installNamespace, watchNamespaces, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +375,29 @@ func saNameOrDefault(saName string) string {
 	return saName
 }
 
-func Convert(in RegistryV1, installNamespace string, targetNamespaces []string) (*Plain, error) {
+func Convert(in RegistryV1, installNamespace string, targetNamespaces []string, opts ...Option) (*Plain, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// serviceAccountFor returns the ServiceAccount that runs as the
+	// ServiceAccount named by the CSV, and rbacName the base name of the RBAC
+	// objects that grant its permissions.
+	serviceAccountFor := saNameOrDefault
+	rbacName := func(sa string) string {
+		return fmt.Sprintf("%s-%s", in.CSV.Name, sa)
+	}
+	var rbacLabels map[string]string
+	if o.rbacOwner != "" {
+		serviceAccountFor = func(name string) string {
+			return fmt.Sprintf("%s-%s", o.rbacOwner, saNameOrDefault(name))
+		}
+		rbacName = func(sa string) string {
+			return sa
+		}
+		rbacLabels = map[string]string{util.GeneratedRBACKey: "true"}
+	}
+
 	if installNamespace == "" {
 		installNamespace = in.CSV.Annotations["operatorframework.io/suggested-namespace"]
 	}
@@ -207,6 +433,10 @@ func Convert(in RegistryV1, installNamespace string, targetNamespaces []string)
 	deployments := []appsv1.Deployment{}
 	serviceAccounts := map[string]corev1.ServiceAccount{}
 	for _, depSpec := range in.CSV.Spec.InstallStrategy.StrategySpec.DeploymentSpecs {
+		saName := serviceAccountFor(depSpec.Spec.Template.Spec.ServiceAccountName)
+		if o.rbacOwner != "" {
+			depSpec.Spec.Template.Spec.ServiceAccountName = saName
+		}
 		annotations := util.MergeMaps(in.CSV.Annotations, depSpec.Spec.Template.Annotations)
 		annotations["olm.targetNamespaces"] = strings.Join(targetNamespaces, ",")
 		deployments = append(deployments, appsv1.Deployment{
@@ -223,8 +453,7 @@ func Convert(in RegistryV1, installNamespace string, targetNamespaces []string)
 			},
 			Spec: depSpec.Spec,
 		})
-		saName := saNameOrDefault(depSpec.Spec.Template.Spec.ServiceAccountName)
-		serviceAccounts[saName] = newServiceAccount(installNamespace, saName)
+		serviceAccounts[saName] = newServiceAccount(installNamespace, saName, rbacLabels)
 	}
 
 	// NOTES:
@@ -247,9 +476,9 @@ func Convert(in RegistryV1, installNamespace string, targetNamespaces []string)
 
 	// Create all the service accounts
 	for _, permission := range allPermissions {
-		saName := saNameOrDefault(permission.ServiceAccountName)
+		saName := serviceAccountFor(permission.ServiceAccountName)
 		if _, ok := serviceAccounts[saName]; !ok {
-			serviceAccounts[saName] = newServiceAccount(installNamespace, saName)
+			serviceAccounts[saName] = newServiceAccount(installNamespace, saName, rbacLabels)
 		}
 	}
 
@@ -268,24 +497,24 @@ func Convert(in RegistryV1, installNamespace string, targetNamespaces []string)
 
 	for _, ns := range targetNamespaces {
 		for _, permission := range permissions {
-			saName := saNameOrDefault(permission.ServiceAccountName)
-			name, err := generateName(fmt.Sprintf("%s-%s", in.CSV.Name, saName), permission)
+			saName := serviceAccountFor(permission.ServiceAccountName)
+			name, err := generateName(rbacName(saName), permission)
 			if err != nil {
 				return nil, err
 			}
-			roles = append(roles, newRole(ns, name, permission.Rules))
-			roleBindings = append(roleBindings, newRoleBinding(ns, name, name, installNamespace, saName))
+			roles = append(roles, newRole(ns, name, permission.Rules, rbacLabels))
+			roleBindings = append(roleBindings, newRoleBinding(ns, name, name, installNamespace, rbacLabels, saName))
 		}
 	}
 
 	for _, permission := range clusterPermissions {
-		saName := saNameOrDefault(permission.ServiceAccountName)
-		name, err := generateName(fmt.Sprintf("%s-%s", in.CSV.Name, saName), permission)
+		saName := serviceAccountFor(permission.ServiceAccountName)
+		name, err := generateName(rbacName(saName), permission)
 		if err != nil {
 			return nil, err
 		}
-		clusterRoles = append(clusterRoles, newClusterRole(name, permission.Rules))
-		clusterRoleBindings = append(clusterRoleBindings, newClusterRoleBinding(name, name, installNamespace, saName))
+		clusterRoles = append(clusterRoles, newClusterRole(name, permission.Rules, rbacLabels))
+		clusterRoleBindings = append(clusterRoleBindings, newClusterRoleBinding(name, name, installNamespace, rbacLabels, saName))
 	}
 
 	objs := []client.Object{}
@@ -347,7 +576,7 @@ func generateName(base string, o interface{}) (string, error) {
 	return fmt.Sprintf("%s-%s", base, hashStr), nil
 }
 
-func newServiceAccount(namespace, name string) corev1.ServiceAccount {
+func newServiceAccount(namespace, name string, labels map[string]string) corev1.ServiceAccount {
 	return corev1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ServiceAccount",
@@ -356,11 +585,12 @@ func newServiceAccount(namespace, name string) corev1.ServiceAccount {
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 			Name:      name,
+			Labels:    labels,
 		},
 	}
 }
 
-func newRole(namespace, name string, rules []rbacv1.PolicyRule) rbacv1.Role {
+func newRole(namespace, name string, rules []rbacv1.PolicyRule, labels map[string]string) rbacv1.Role {
 	return rbacv1.Role{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Role",
@@ -369,25 +599,27 @@ func newRole(namespace, name string, rules []rbacv1.PolicyRule) rbacv1.Role {
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 			Name:      name,
+			Labels:    labels,
 		},
 		Rules: rules,
 	}
 }
 
-func newClusterRole(name string, rules []rbacv1.PolicyRule) rbacv1.ClusterRole {
+func newClusterRole(name string, rules []rbacv1.PolicyRule, labels map[string]string) rbacv1.ClusterRole {
 	return rbacv1.ClusterRole{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ClusterRole",
 			APIVersion: rbacv1.SchemeGroupVersion.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
+			Name:   name,
+			Labels: labels,
 		},
 		Rules: rules,
 	}
 }
 
-func newRoleBinding(namespace, name, roleName, saNamespace string, saNames ...string) rbacv1.RoleBinding {
+func newRoleBinding(namespace, name, roleName, saNamespace string, labels map[string]string, saNames ...string) rbacv1.RoleBinding {
 	subjects := make([]rbacv1.Subject, 0, len(saNames))
 	for _, saName := range saNames {
 		subjects = append(subjects, rbacv1.Subject{
@@ -404,6 +636,7 @@ func newRoleBinding(namespace, name, roleName, saNamespace string, saNames ...st
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 			Name:      name,
+			Labels:    labels,
 		},
 		Subjects: subjects,
 		RoleRef: rbacv1.RoleRef{
@@ -414,7 +647,7 @@ func newRoleBinding(namespace, name, roleName, saNamespace string, saNames ...st
 	}
 }
 
-func newClusterRoleBinding(name, roleName, saNamespace string, saNames ...string) rbacv1.ClusterRoleBinding {
+func newClusterRoleBinding(name, roleName, saNamespace string, labels map[string]string, saNames ...string) rbacv1.ClusterRoleBinding {
 	subjects := make([]rbacv1.Subject, 0, len(saNames))
 	for _, saName := range saNames {
 		subjects = append(subjects, rbacv1.Subject{
@@ -429,7 +662,8 @@ func newClusterRoleBinding(name, roleName, saNamespace string, saNames ...string
 			APIVersion: rbacv1.SchemeGroupVersion.String(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
+			Name:   name,
+			Labels: labels,
 		},
 		Subjects: subjects,
 		RoleRef: rbacv1.RoleRef{
@@ -1,11 +1,14 @@
 package convert
 
 import (
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	schedulingv1 "k8s.io/api/scheduling/v1"
@@ -382,6 +385,66 @@ var _ = Describe("RegistryV1 Suite", func() {
 			})
 		})
 
+		Context("Should generate RBAC dedicated to the install", func() {
+			var csv v1alpha1.ClusterServiceVersion
+
+			BeforeEach(func() {
+				rules := []rbacv1.PolicyRule{{APIGroups: []string{"test"}, Resources: []string{"pods"}, Verbs: []string{"get"}}}
+				csv = v1alpha1.ClusterServiceVersion{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "testCSV",
+					},
+					Spec: v1alpha1.ClusterServiceVersionSpec{
+						InstallModes: []v1alpha1.InstallMode{{Type: v1alpha1.InstallModeTypeOwnNamespace, Supported: true}},
+						InstallStrategy: v1alpha1.NamedInstallStrategy{
+							StrategySpec: v1alpha1.StrategyDetailsDeployment{
+								DeploymentSpecs: []v1alpha1.StrategyDeploymentSpec{{Name: "testDeployment"}},
+								Permissions:     []v1alpha1.StrategyDeploymentPermissions{{Rules: rules}},
+								ClusterPermissions: []v1alpha1.StrategyDeploymentPermissions{
+									{ServiceAccountName: "testServiceAccount", Rules: rules},
+								},
+							},
+						},
+					},
+				}
+				installNamespace = "testInstallNamespace"
+			})
+
+			It("should run the operator as service accounts of the install", func() {
+				By("converting to plain")
+				plainBundle, err := Convert(RegistryV1{PackageName: "testPkg", CSV: csv}, installNamespace, []string{installNamespace}, WithGeneratedRBAC("my-install"))
+				Expect(err).NotTo(HaveOccurred())
+
+				By("verifying the names and labels of the generated objects")
+				kinds := map[string][]string{}
+				for _, obj := range plainBundle.Objects {
+					kind := obj.GetObjectKind().GroupVersionKind().Kind
+					kinds[kind] = append(kinds[kind], obj.GetName())
+					if kind == "Deployment" {
+						Expect(obj.GetLabels()).NotTo(HaveKey("core.rukpak.io/generated-rbac"))
+						Expect(obj.(*appsv1.Deployment).Spec.Template.Spec.ServiceAccountName).To(Equal("my-install-default"))
+						continue
+					}
+					Expect(obj.GetLabels()).To(HaveKeyWithValue("core.rukpak.io/generated-rbac", "true"))
+					Expect(obj.GetName()).To(HavePrefix("my-install-"))
+				}
+				Expect(kinds["ServiceAccount"]).To(ConsistOf("my-install-default", "my-install-testServiceAccount"))
+				Expect(kinds["Role"]).To(HaveLen(1))
+				Expect(kinds["ClusterRole"]).To(HaveLen(1))
+			})
+
+			It("should not change the service accounts without the option", func() {
+				plainBundle, err := Convert(RegistryV1{PackageName: "testPkg", CSV: csv}, installNamespace, []string{installNamespace})
+				Expect(err).NotTo(HaveOccurred())
+				for _, obj := range plainBundle.Objects {
+					Expect(obj.GetLabels()).NotTo(HaveKey("core.rukpak.io/generated-rbac"))
+					if obj.GetObjectKind().GroupVersionKind().Kind == "ServiceAccount" {
+						Expect(obj.GetName()).To(Equal("testServiceAccount"))
+					}
+				}
+			})
+		})
+
 		Context("Should enforce limitations", func() {
 			It("should not allow bundles with webhooks", func() {
 				By("creating a registry v1 bundle")
@@ -432,6 +495,148 @@ var _ = Describe("RegistryV1 Suite", func() {
 			})
 		})
 	})
+
+	var _ = Describe("ParseRegistryV1", func() {
+		const (
+			annotations = `annotations:
+  operators.operatorframework.io.bundle.package.v1: annotated-package
+`
+			csv = `apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: test.v1.2.3
+spec:
+  version: 1.2.3
+  customresourcedefinitions:
+    owned:
+    - name: widgets.example.com
+      version: v1
+      kind: Widget
+    - name: gadgets.example.com
+      version: v1alpha1
+      kind: Gadget
+`
+			properties = `properties:
+- type: olm.package
+  value:
+    packageName: test-package
+    version: 2.0.0
+- type: olm.gvk
+  value:
+    group: example.com
+    kind: Widget
+    version: v2
+`
+		)
+		var bundle fstest.MapFS
+
+		BeforeEach(func() {
+			bundle = fstest.MapFS{
+				"metadata/annotations.yaml": &fstest.MapFile{Data: []byte(annotations)},
+				"manifests/csv.yaml":        &fstest.MapFile{Data: []byte(csv)},
+			}
+		})
+
+		It("should fall back to the annotations and CSV without properties", func() {
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.PackageName).To(Equal("annotated-package"))
+			Expect(reg.Version).To(Equal("1.2.3"))
+			Expect(reg.ProvidedAPIs).To(Equal([]metav1.GroupVersionKind{
+				{Group: "example.com", Version: "v1alpha1", Kind: "Gadget"},
+				{Group: "example.com", Version: "v1", Kind: "Widget"},
+			}))
+		})
+
+		It("should read the channels from the annotations", func() {
+			bundle["metadata/annotations.yaml"] = &fstest.MapFile{Data: []byte(annotations + `  operators.operatorframework.io.bundle.channels.v1: stable, fast
+  operators.operatorframework.io.bundle.channel.default.v1: stable
+`)}
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.Channels).To(Equal([]string{"stable", "fast"}))
+			Expect(reg.DefaultChannel).To(Equal("stable"))
+		})
+
+		It("should default to the only channel", func() {
+			bundle["metadata/annotations.yaml"] = &fstest.MapFile{Data: []byte(annotations + "  operators.operatorframework.io.bundle.channels.v1: alpha\n")}
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.Channels).To(Equal([]string{"alpha"}))
+			Expect(reg.DefaultChannel).To(Equal("alpha"))
+		})
+
+		It("should prefer the declared properties", func() {
+			bundle["metadata/properties.yaml"] = &fstest.MapFile{Data: []byte(properties)}
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.PackageName).To(Equal("test-package"))
+			Expect(reg.Version).To(Equal("2.0.0"))
+			Expect(reg.ProvidedAPIs).To(Equal([]metav1.GroupVersionKind{
+				{Group: "example.com", Version: "v2", Kind: "Widget"},
+			}))
+		})
+
+		It("should read the maximum OpenShift version from the properties", func() {
+			bundle["metadata/properties.yaml"] = &fstest.MapFile{Data: []byte(properties + "- type: olm.maxOpenShiftVersion\n  value: \"4.14\"\n")}
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.MaxOpenShiftVersion).To(Equal("4.14"))
+		})
+
+		It("should read the maximum OpenShift version from the CSV annotation", func() {
+			bundle["manifests/csv.yaml"] = &fstest.MapFile{Data: []byte(strings.Replace(csv, "  name: test.v1.2.3\n",
+				"  name: test.v1.2.3\n  annotations:\n    olm.properties: '[{\"type\": \"olm.maxOpenShiftVersion\", \"value\": \"4.12\"}]'\n", 1))}
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.MaxOpenShiftVersion).To(Equal("4.12"))
+		})
+
+		It("should error on an invalid maximum OpenShift version", func() {
+			bundle["metadata/properties.yaml"] = &fstest.MapFile{Data: []byte("properties:\n- type: olm.maxOpenShiftVersion\n  value: next\n")}
+			_, err := ParseRegistryV1(bundle)
+			Expect(err).To(MatchError(ContainSubstring("invalid olm.maxOpenShiftVersion")))
+		})
+
+		It("should collect the related images and the images of the deployments", func() {
+			bundle["manifests/csv.yaml"] = &fstest.MapFile{Data: []byte(csv + `  relatedImages:
+  - name: operator
+    image: quay.io/example/operator:v1
+  - name: database
+    image: quay.io/example/database:v1
+  install:
+    strategy: deployment
+    spec:
+      deployments:
+      - name: operator
+        spec:
+          template:
+            spec:
+              initContainers:
+              - name: migrate
+                image: quay.io/example/migrate:v1
+              containers:
+              - name: manager
+                image: quay.io/example/operator:v1
+              - name: proxy
+                image: quay.io/example/proxy:v1
+`)}
+			reg, err := ParseRegistryV1(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reg.RelatedImages).To(Equal([]v1alpha1.RelatedImage{
+				{Name: "operator", Image: "quay.io/example/operator:v1"},
+				{Name: "database", Image: "quay.io/example/database:v1"},
+				{Name: "migrate", Image: "quay.io/example/migrate:v1"},
+				{Name: "proxy", Image: "quay.io/example/proxy:v1"},
+			}))
+		})
+
+		It("should error on malformed properties", func() {
+			bundle["metadata/properties.yaml"] = &fstest.MapFile{Data: []byte("properties:\n- type: olm.package\n  value: [1, 2]\n")}
+			_, err := ParseRegistryV1(bundle)
+			Expect(err).To(MatchError(ContainSubstring("properties[0]")))
+		})
+	})
 })
 
 func convertToUnstructured(obj interface{}) unstructured.Unstructured {
@@ -0,0 +1,16 @@
+package errors
+
+// Transient represents an error that is expected to resolve
+// itself, such as a network failure, so that the request is
+// likely to succeed when it is retried.
+type Transient struct {
+	error
+}
+
+func NewTransient(err error) *Transient {
+	return &Transient{err}
+}
+
+func (t *Transient) Unwrap() error {
+	return t.error
+}
@@ -0,0 +1,144 @@
+// Package fanout generates one BundleDeployment per bundle directory of a git
+// repository, so that a repository with a directory per application can be
+// deployed as a whole, like an app of apps.
+package fanout
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+const (
+	// Label is set on generated BundleDeployments to the name of the fan-out
+	// that generated them, so that the BundleDeployments of directories that
+	// were removed from the repository can be found and deleted.
+	Label = "core.rukpak.io/fanout"
+
+	// ConfigFile is the name of the optional file in a bundle directory that
+	// overrides the conventions for its BundleDeployment. It contains a
+	// partial BundleDeployment, whose metadata.name, labels, annotations and
+	// spec are used. Its spec.source must not be set.
+	ConfigFile = ".rukpak.yaml"
+)
+
+// Options configure the generated BundleDeployments.
+type Options struct {
+	// Name is the name of the fan-out, which the Label of the generated
+	// BundleDeployments is set to.
+	Name string
+	// Repository and Ref are the git repository and reference that the
+	// generated BundleDeployments are sourced from.
+	Repository string
+	Ref        rukpakv1alpha2.GitRef
+	// Auth is the authorization of the generated git sources.
+	Auth rukpakv1alpha2.Authorization
+	// Root is the directory of the repository whose subdirectories are the
+	// bundle directories. Defaults to the root of the repository.
+	Root string
+	// NamePrefix is prepended to the names of the generated
+	// BundleDeployments, which default to the name of their directory.
+	NamePrefix string
+	// ProvisionerClassName is the provisioner class of BundleDeployments
+	// whose config file does not set one.
+	ProvisionerClassName string
+}
+
+// Generate returns a BundleDeployment for each subdirectory of opts.Root in
+// repo, which is a checkout of opts.Repository at opts.Ref. Hidden
+// directories are skipped. By convention, the BundleDeployment of a directory
+// is named after it, prefixed with opts.NamePrefix, and installs into the
+// namespace of the same name as the directory. The ConfigFile of a directory
+// overrides these conventions.
+func Generate(repo fs.FS, opts Options) ([]rukpakv1alpha2.BundleDeployment, error) {
+	if errs := validation.IsValidLabelValue(opts.Name); opts.Name == "" || len(errs) > 0 {
+		return nil, fmt.Errorf("invalid fan-out name %q: %s", opts.Name, strings.Join(errs, ", "))
+	}
+	root := path.Clean(strings.TrimPrefix(opts.Root, "/"))
+	entries, err := fs.ReadDir(repo, root)
+	if err != nil {
+		return nil, fmt.Errorf("read root directory %q: %v", root, err)
+	}
+
+	var (
+		bds  []rukpakv1alpha2.BundleDeployment
+		errs []error
+	)
+	names := map[string]string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := path.Join(root, entry.Name())
+		bd, err := generate(repo, dir, entry.Name(), opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("directory %q: %v", dir, err))
+			continue
+		}
+		if other, ok := names[bd.Name]; ok {
+			errs = append(errs, fmt.Errorf("directories %q and %q both generate bundledeployment %q", other, dir, bd.Name))
+			continue
+		}
+		names[bd.Name] = dir
+		bds = append(bds, *bd)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	sort.Slice(bds, func(i, j int) bool { return bds[i].Name < bds[j].Name })
+	return bds, nil
+}
+
+func generate(repo fs.FS, dir, dirName string, opts Options) (*rukpakv1alpha2.BundleDeployment, error) {
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	data, err := fs.ReadFile(repo, path.Join(dir, ConfigFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := yaml.UnmarshalStrict(data, bd); err != nil {
+			return nil, fmt.Errorf("parse %s: %v", ConfigFile, err)
+		}
+		if bd.Spec.Source.Type != "" {
+			return nil, fmt.Errorf("%s must not set spec.source, which is generated", ConfigFile)
+		}
+	}
+
+	bd.TypeMeta = metav1.TypeMeta{APIVersion: rukpakv1alpha2.GroupVersion.String(), Kind: rukpakv1alpha2.BundleDeploymentKind}
+	if bd.Name == "" {
+		bd.Name = opts.NamePrefix + strings.ToLower(dirName)
+	}
+	if errs := validation.IsDNS1123Subdomain(bd.Name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid bundledeployment name %q: %s", bd.Name, strings.Join(errs, ", "))
+	}
+	metav1.SetMetaDataLabel(&bd.ObjectMeta, Label, opts.Name)
+	if bd.Spec.InstallNamespace == "" {
+		bd.Spec.InstallNamespace = strings.ToLower(dirName)
+	}
+	if bd.Spec.ProvisionerClassName == "" {
+		bd.Spec.ProvisionerClassName = opts.ProvisionerClassName
+	}
+	if bd.Spec.ProvisionerClassName == "" {
+		return nil, fmt.Errorf("no provisioner class name: set it in %s or as the default of the fan-out", ConfigFile)
+	}
+	bd.Spec.Source = rukpakv1alpha2.BundleSource{
+		Type: rukpakv1alpha2.SourceTypeGit,
+		Git: &rukpakv1alpha2.GitSource{
+			Repository: opts.Repository,
+			Directory:  "./" + dir,
+			Ref:        opts.Ref,
+			Auth:       opts.Auth,
+		},
+	}
+	return bd, nil
+}
@@ -0,0 +1,104 @@
+package fanout
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestGenerate(t *testing.T) {
+	repo := fstest.MapFS{
+		"README.md":                     {Data: []byte("apps")},
+		".github/workflows/ci.yaml":     {Data: []byte("{}")},
+		"apps/frontend/deployment.yaml": {Data: []byte("{}")},
+		"apps/backend/deployment.yaml":  {Data: []byte("{}")},
+		"apps/backend/.rukpak.yaml": {Data: []byte(`
+metadata:
+  name: api
+  labels:
+    team: payments
+spec:
+  installNamespace: payments
+  provisionerClassName: core-rukpak-io-helm
+`)},
+	}
+	opts := Options{
+		Name:                 "apps",
+		Repository:           "https://github.com/example/apps",
+		Ref:                  rukpakv1alpha2.GitRef{Branch: "main"},
+		Root:                 "apps",
+		NamePrefix:           "example-",
+		ProvisionerClassName: "core-rukpak-io-plain",
+	}
+
+	bds, err := Generate(repo, opts)
+	require.NoError(t, err)
+	require.Len(t, bds, 2)
+
+	api, frontend := bds[0], bds[1]
+	require.Equal(t, "api", api.Name)
+	require.Equal(t, map[string]string{"team": "payments", Label: "apps"}, api.Labels)
+	require.Equal(t, "payments", api.Spec.InstallNamespace)
+	require.Equal(t, "core-rukpak-io-helm", api.Spec.ProvisionerClassName)
+	require.Equal(t, "./apps/backend", api.Spec.Source.Git.Directory)
+
+	require.Equal(t, "example-frontend", frontend.Name)
+	require.Equal(t, rukpakv1alpha2.BundleDeploymentKind, frontend.Kind)
+	require.Equal(t, "frontend", frontend.Spec.InstallNamespace)
+	require.Equal(t, "core-rukpak-io-plain", frontend.Spec.ProvisionerClassName)
+	require.Equal(t, rukpakv1alpha2.BundleSource{
+		Type: rukpakv1alpha2.SourceTypeGit,
+		Git: &rukpakv1alpha2.GitSource{
+			Repository: "https://github.com/example/apps",
+			Directory:  "./apps/frontend",
+			Ref:        rukpakv1alpha2.GitRef{Branch: "main"},
+		},
+	}, frontend.Spec.Source)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		repo      fstest.MapFS
+		opts      Options
+		expectErr string
+	}{
+		{
+			name:      "no fan-out name",
+			repo:      fstest.MapFS{"app/deployment.yaml": {}},
+			opts:      Options{ProvisionerClassName: "core-rukpak-io-plain"},
+			expectErr: `invalid fan-out name ""`,
+		},
+		{
+			name: "source in config file",
+			repo: fstest.MapFS{
+				"app/.rukpak.yaml": {Data: []byte("spec:\n  source:\n    type: image\n")},
+			},
+			opts:      Options{Name: "apps", ProvisionerClassName: "core-rukpak-io-plain"},
+			expectErr: `directory "app": .rukpak.yaml must not set spec.source`,
+		},
+		{
+			name: "duplicate names",
+			repo: fstest.MapFS{
+				"a/.rukpak.yaml": {Data: []byte("metadata:\n  name: app\n")},
+				"b/.rukpak.yaml": {Data: []byte("metadata:\n  name: app\n")},
+			},
+			opts:      Options{Name: "apps", ProvisionerClassName: "core-rukpak-io-plain"},
+			expectErr: `directories "a" and "b" both generate bundledeployment "app"`,
+		},
+		{
+			name:      "no provisioner class",
+			repo:      fstest.MapFS{"app/deployment.yaml": {}},
+			opts:      Options{Name: "apps"},
+			expectErr: "no provisioner class name",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Generate(tc.repo, tc.opts)
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
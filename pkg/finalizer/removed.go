@@ -0,0 +1,34 @@
+package finalizer
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/finalizer"
+)
+
+var _ finalizer.Finalizers = &withRemovedKeys{}
+
+type withRemovedKeys struct {
+	finalizer.Finalizers
+	keys []string
+}
+
+// WithRemovedKeys returns finalizers that, in addition to running f, remove
+// the given finalizer keys from objects without running any finalization for
+// them. This releases objects that were created while a finalizer was
+// registered that is no longer used, which would otherwise never be deleted.
+func WithRemovedKeys(f finalizer.Finalizers, keys ...string) finalizer.Finalizers {
+	return &withRemovedKeys{Finalizers: f, keys: keys}
+}
+
+func (f *withRemovedKeys) Finalize(ctx context.Context, obj client.Object) (finalizer.Result, error) {
+	res, err := f.Finalizers.Finalize(ctx, obj)
+	for _, key := range f.keys {
+		if controllerutil.RemoveFinalizer(obj, key) {
+			res.Updated = true
+		}
+	}
+	return res, err
+}
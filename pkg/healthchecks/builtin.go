@@ -0,0 +1,180 @@
+// Package healthchecks evaluates the health of the objects that a bundle
+// deployment consists of.
+package healthchecks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Status is the health of an object.
+type Status string
+
+const (
+	// StatusHealthy means that the object has reached its desired state.
+	StatusHealthy Status = "Healthy"
+	// StatusProgressing means that the object is expected to reach its
+	// desired state without intervention, e.g. while a rollout is in flight.
+	StatusProgressing Status = "Progressing"
+	// StatusDegraded means that the object failed to reach its desired
+	// state, or that its state could not be determined.
+	StatusDegraded Status = "Degraded"
+)
+
+// Result is the health of a single object.
+type Result struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+
+	Status Status
+	// Message describes why the object is not healthy. It is empty for
+	// healthy objects.
+	Message string
+}
+
+func (r Result) String() string {
+	// If the resource is namespaced, include the namespace in the key.
+	if r.Namespace != "" {
+		return fmt.Sprintf("(%s)(%s/%s): %s", r.GroupVersionKind.String(), r.Namespace, r.Name, r.Message)
+	}
+	return fmt.Sprintf("(%s)(%s): %s", r.GroupVersionKind.String(), r.Name, r.Message)
+}
+
+// Results are the health of a set of objects.
+type Results []Result
+
+// Status returns the combined health of the objects: degraded if any object is
+// degraded, otherwise progressing if any object is progressing, otherwise
+// healthy.
+func (rs Results) Status() Status {
+	combined := StatusHealthy
+	for _, r := range rs {
+		switch r.Status {
+		case StatusDegraded:
+			return StatusDegraded
+		case StatusProgressing:
+			combined = StatusProgressing
+		}
+	}
+	return combined
+}
+
+// Unhealthy returns the results of the objects that are not healthy.
+func (rs Results) Unhealthy() Results {
+	var unhealthy Results
+	for _, r := range rs {
+		if r.Status != StatusHealthy {
+			unhealthy = append(unhealthy, r)
+		}
+	}
+	return unhealthy
+}
+
+// Err returns nil if all objects are healthy. Otherwise, the error contains the
+// GVK + namespace/resourceName and the message of each unhealthy object.
+func (rs Results) Err() error {
+	var errs []error
+	for _, r := range rs.Unhealthy() {
+		errs = append(errs, errors.New(r.String()))
+	}
+	return errors.Join(errs...)
+}
+
+// Evaluate returns the health of each of the given objects, as read by reader.
+//
+// The current list of supported resources is:
+// - Deployments
+// - StatefulSets
+// - DaemonSets
+// - ReplicaSets
+// - Pods
+// - APIServices
+// - CustomResourceDefinitions
+// - Jobs
+// - Services
+// - PersistentVolumeClaims
+// - PodDisruptionBudgets
+//
+// If the resource is not supported, it is assumed to be healthy.
+func Evaluate(ctx context.Context, reader client.Reader, objects []client.Object) Results {
+	results := make(Results, 0, len(objects))
+	for _, object := range objects {
+		results = append(results, evaluate(ctx, reader, object))
+	}
+	return results
+}
+
+// AreObjectsHealthy checks if the given resources are healthy.
+// It returns a nil error if all the resources are healthy, if any resource is not healthy, the error will
+// contain the GVK + namespace/resourceName and the error message of each unhealthy resource.
+//
+// See Evaluate for the list of supported resources.
+func AreObjectsHealthy(ctx context.Context, reader client.Reader, objects []client.Object) error {
+	return Evaluate(ctx, reader, objects).Err()
+}
+
+func evaluate(ctx context.Context, reader client.Reader, object client.Object) Result {
+	gvk := object.GetObjectKind().GroupVersionKind()
+	result := Result{
+		GroupVersionKind: gvk,
+		Namespace:        object.GetNamespace(),
+		Name:             object.GetName(),
+		Status:           StatusHealthy,
+	}
+	degraded := func(message string) Result {
+		result.Status, result.Message = StatusDegraded, message
+		return result
+	}
+	progressing := func(message string) Result {
+		result.Status, result.Message = StatusProgressing, message
+		return result
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := reader.Get(ctx, types.NamespacedName{Name: object.GetName(), Namespace: object.GetNamespace()}, u); err != nil {
+		return degraded(err.Error())
+	}
+
+	if gvk == apiregistrationv1.SchemeGroupVersion.WithKind("APIService") {
+		obj := &apiregistrationv1.APIService{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+			return degraded(err.Error())
+		}
+		// Check if the APIService is available.
+		for _, condition := range obj.Status.Conditions {
+			if condition.Type != apiregistrationv1.Available {
+				continue
+			}
+			if condition.Status == apiregistrationv1.ConditionFalse {
+				return degraded(condition.Message)
+			}
+			return result
+		}
+		return progressing("Available condition not found")
+	}
+
+	computed, err := status.Compute(u)
+	if err != nil {
+		return degraded(err.Error())
+	}
+	message := fmt.Sprintf("object %s: %s", computed.Status, computed.Message)
+	switch computed.Status {
+	case status.CurrentStatus:
+		return result
+	case status.InProgressStatus, status.TerminatingStatus:
+		return progressing(message)
+	default:
+		return degraded(message)
+	}
+}
@@ -3,9 +3,12 @@ package healthchecks
 import (
 	"context"
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -751,6 +754,71 @@ func TestAreObjectsHealthy(t *testing.T) {
 	}
 }
 
+func TestEvaluate(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "rolling-out", Namespace: "ns", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           2,
+			UpdatedReplicas:    1,
+			ReadyReplicas:      2,
+			AvailableReplicas:  2,
+		},
+	}
+	job := &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{Kind: "Job", APIVersion: "batch/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "ns"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}},
+		},
+	}
+	apiService := &apiregistrationv1.APIService{
+		TypeMeta:   metav1.TypeMeta{Kind: "APIService", APIVersion: "apiregistration.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "v1.example.com"},
+	}
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns"},
+	}
+	missing := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "ns"},
+	}
+
+	cl := fakeClient{}
+	cl.setResources([]client.Object{deployment, job, apiService, configMap})
+	results := Evaluate(context.Background(), cl, []client.Object{deployment, job, apiService, configMap, missing})
+
+	statuses := map[string]Status{}
+	for _, r := range results {
+		statuses[r.Name] = r.Status
+	}
+	expected := map[string]Status{
+		"rolling-out":    StatusProgressing,
+		"failed":         StatusDegraded,
+		"v1.example.com": StatusProgressing,
+		"config":         StatusHealthy,
+		"missing":        StatusDegraded,
+	}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Evaluate() statuses = %v, expected %v", statuses, expected)
+	}
+	if status := results.Status(); status != StatusDegraded {
+		t.Errorf("Results.Status() = %q, expected %q", status, StatusDegraded)
+	}
+	if status := results[:1].Status(); status != StatusProgressing {
+		t.Errorf("Results.Status() = %q, expected %q", status, StatusProgressing)
+	}
+	if unhealthy := results.Unhealthy(); len(unhealthy) != 4 {
+		t.Errorf("Results.Unhealthy() returned %d results, expected 4", len(unhealthy))
+	}
+	if msg := results[1].String(); !strings.HasPrefix(msg, "(batch/v1, Kind=Job)(ns/failed): object Failed") {
+		t.Errorf("Result.String() = %q", msg)
+	}
+}
+
 // Fake client for testing, implementing the client.Client interface.
 type fakeClient struct {
 	client.Client
@@ -19,6 +19,7 @@ package predicate
 import (
 	"reflect"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -79,3 +80,21 @@ func DependentPredicateFuncs[T client.Object]() crtpredicate.TypedFuncs[T] {
 
 	return dependentPredicate
 }
+
+// DependentMetadataPredicateFuncs is like DependentPredicateFuncs for dependent
+// resources that are watched by their metadata only. As the content of those
+// resources is not known, every update that changes the resourceVersion is
+// reconciled, so they should only be used for resources without a status,
+// such as ConfigMaps and Secrets.
+func DependentMetadataPredicateFuncs() crtpredicate.TypedFuncs[*metav1.PartialObjectMetadata] {
+	dependentPredicate := DependentPredicateFuncs[*metav1.PartialObjectMetadata]()
+	dependentPredicate.UpdateFunc = func(e event.TypedUpdateEvent[*metav1.PartialObjectMetadata]) bool {
+		if e.ObjectOld.GetResourceVersion() == e.ObjectNew.GetResourceVersion() {
+			return false
+		}
+		o := e.ObjectNew
+		log.V(1).Info("Reconciling due to dependent resource update", "name", o.GetName(), "namespace", o.GetNamespace(), "apiVersion", o.GroupVersionKind().GroupVersion(), "kind", o.GroupVersionKind().Kind)
+		return true
+	}
+	return dependentPredicate
+}
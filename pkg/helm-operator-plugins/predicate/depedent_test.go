@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 )
@@ -130,3 +131,34 @@ func TestDependentPredicateFuncsUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestDependentMetadataPredicateFuncsUpdate(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		arg         event.TypedUpdateEvent[*metav1.PartialObjectMetadata]
+		result      bool
+	}{
+		{
+			description: "Resync - return false",
+			arg: event.TypedUpdateEvent[*metav1.PartialObjectMetadata]{
+				ObjectOld: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}},
+				ObjectNew: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}},
+			},
+			result: false,
+		},
+		{
+			description: "With update - return true",
+			arg: event.TypedUpdateEvent[*metav1.PartialObjectMetadata]{
+				ObjectOld: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}},
+				ObjectNew: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "2"}},
+			},
+			result: true,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			funcs := DependentMetadataPredicateFuncs()
+			result := funcs.UpdateFunc(tt.arg)
+			require.Equal(t, tt.result, result)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Package installreport defines the install reports that provisioners record
+// for every successful install, upgrade and rollback of a BundleDeployment.
+//
+// Reports are wrapped in a DSSE envelope
+// (https://github.com/secure-systems-lab/dsse) that is signed with the
+// provisioner's signing key, if one is configured, so that auditors can
+// verify that a report was produced by the provisioner and has not been
+// tampered with.
+package installreport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// PayloadType is the DSSE payload type of install reports.
+const PayloadType = "application/vnd.rukpak.install-report+json"
+
+// Action is the release operation that a report records.
+type Action string
+
+const (
+	ActionInstall Action = "Install"
+	ActionUpgrade Action = "Upgrade"
+	// ActionRollback records that an upgrade was rolled back to the previous
+	// release after a breached analysis.
+	ActionRollback Action = "Rollback"
+)
+
+// Report records a successful install, upgrade or rollback of a BundleDeployment.
+type Report struct {
+	// BundleDeployment is the name of the BundleDeployment.
+	BundleDeployment string `json:"bundleDeployment"`
+	// Generation is the generation of the BundleDeployment spec that was installed.
+	Generation int64 `json:"generation"`
+	// Provisioner is the ID of the provisioner that performed the action.
+	Provisioner string `json:"provisioner"`
+	// RequestedBy is the field manager that last changed the BundleDeployment spec.
+	RequestedBy string `json:"requestedBy,omitempty"`
+	// Time is when the release was deployed.
+	Time metav1.Time `json:"time"`
+	// Action is the release operation that was performed.
+	Action Action `json:"action"`
+	// Revision is the revision of the release that was deployed.
+	Revision int `json:"revision"`
+	// Source is the resolved source of the installed bundle content.
+	Source *rukpakv1alpha2.BundleSource `json:"source,omitempty"`
+	// ManifestDigest is the digest of the rendered release manifest.
+	ManifestDigest string `json:"manifestDigest"`
+	// Objects are the objects of the release.
+	Objects []Object `json:"objects"`
+	// Conditions are the conditions of the BundleDeployment after the release was deployed.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Object identifies an object of a release.
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// Envelope is a DSSE envelope.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     []byte      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a DSSE signature.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   []byte `json:"sig"`
+}
+
+// ManifestDigest returns the digest of a rendered release manifest.
+func ManifestDigest(manifest string) string {
+	sum := sha256.Sum256([]byte(manifest))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Sign returns the JSON encoding of an envelope that contains report and,
+// if signer is not nil, its signature.
+func Sign(report *Report, signer crypto.Signer) ([]byte, error) {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	env := Envelope{PayloadType: PayloadType, Payload: payload, Signatures: []Signature{}}
+	if signer != nil {
+		keyID, err := KeyID(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		digest, opts := digestFor(signer.Public(), pae(PayloadType, payload))
+		sig, err := signer.Sign(rand.Reader, digest, opts)
+		if err != nil {
+			return nil, fmt.Errorf("sign install report: %v", err)
+		}
+		env.Signatures = append(env.Signatures, Signature{KeyID: keyID, Sig: sig})
+	}
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// Verify checks that data is an envelope signed by the private key of pub
+// and returns the report it contains.
+func Verify(data []byte, pub crypto.PublicKey) (*Report, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("decode install report envelope: %v", err)
+	}
+	if env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	keyID, err := KeyID(pub)
+	if err != nil {
+		return nil, err
+	}
+	digest, _ := digestFor(pub, pae(env.PayloadType, env.Payload))
+	verified := false
+	for _, sig := range env.Signatures {
+		if sig.KeyID == keyID && verifySignature(pub, digest, sig.Sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("no valid signature found for the given key")
+	}
+	report := &Report{}
+	if err := json.Unmarshal(env.Payload, report); err != nil {
+		return nil, fmt.Errorf("decode install report: %v", err)
+	}
+	return report, nil
+}
+
+// KeyID returns the ID of a public key: the digest of its PKIX encoding.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("marshal public key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// LoadSigner reads a PEM encoded PKCS #8 Ed25519, ECDSA or RSA private key
+// from path.
+func LoadSigner(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %q", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %q: %v", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// pae returns the DSSE pre-authentication encoding of a payload.
+func pae(payloadType string, payload []byte) []byte {
+	return append([]byte(fmt.Sprintf("DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))), payload...)
+}
+
+// digestFor returns the message that is signed for msg, which Ed25519 keys
+// sign directly while other keys sign its SHA-256 digest.
+func digestFor(pub crypto.PublicKey, msg []byte) ([]byte, crypto.SignerOpts) {
+	if _, ok := pub.(ed25519.PublicKey); ok {
+		return msg, crypto.Hash(0)
+	}
+	sum := sha256.Sum256(msg)
+	return sum[:], crypto.SHA256
+}
+
+func verifySignature(pub crypto.PublicKey, digest, sig []byte) bool {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, digest, sig)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig) == nil
+	default:
+		return false
+	}
+}
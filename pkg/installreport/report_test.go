@@ -0,0 +1,68 @@
+package installreport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	report := &Report{
+		BundleDeployment: "test",
+		Action:           ActionUpgrade,
+		Revision:         2,
+		ManifestDigest:   ManifestDigest("kind: ConfigMap"),
+		Objects:          []Object{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "cm"}},
+	}
+	for _, tt := range []struct {
+		description string
+		signer      crypto.Signer
+	}{
+		{description: "ed25519", signer: edKey},
+		{description: "ecdsa", signer: ecKey},
+		{description: "rsa", signer: rsaKey},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			data, err := Sign(report, tt.signer)
+			require.NoError(t, err)
+
+			verified, err := Verify(data, tt.signer.Public())
+			require.NoError(t, err)
+			require.Equal(t, report, verified)
+
+			_, err = Verify(data, otherKey)
+			require.ErrorContains(t, err, "no valid signature")
+
+			var env Envelope
+			require.NoError(t, json.Unmarshal(data, &env))
+			env.Payload = []byte(`{"bundleDeployment":"tampered"}`)
+			tampered, err := json.Marshal(env)
+			require.NoError(t, err)
+			_, err = Verify(tampered, tt.signer.Public())
+			require.ErrorContains(t, err, "no valid signature")
+		})
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		data, err := Sign(report, nil)
+		require.NoError(t, err)
+		_, err = Verify(data, edKey.Public())
+		require.ErrorContains(t, err, "no valid signature")
+	})
+}
@@ -0,0 +1,144 @@
+package crdvalidation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+// Preflight validates the CRDs of a release before it is installed or
+// upgraded. The schema of every version of a CRD must be structural, and the
+// API server must accept the CRD in a dry-run create, or a dry-run update if
+// the CRD already exists.
+type Preflight struct {
+	crdClient apiextensionsv1client.CustomResourceDefinitionInterface
+}
+
+func NewPreflight(crdCli apiextensionsv1client.CustomResourceDefinitionInterface) *Preflight {
+	return &Preflight{crdClient: crdCli}
+}
+
+func (p *Preflight) Install(ctx context.Context, rel *release.Release) error {
+	return p.validate(ctx, rel)
+}
+
+func (p *Preflight) Upgrade(ctx context.Context, rel *release.Release) error {
+	return p.validate(ctx, rel)
+}
+
+// Error is returned by the Preflight when CRDs of a release are invalid, so
+// that these failures can be told apart from other install failures.
+type Error struct {
+	Failures []Failure
+}
+
+// Failure is the validation failure of a single CRD.
+type Failure struct {
+	Name string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		msgs = append(msgs, fmt.Sprintf("CRD %q: %v", f.Name, f.Err))
+	}
+	return fmt.Sprintf("invalid CRDs: %s", strings.Join(msgs, "; "))
+}
+
+func (p *Preflight) validate(ctx context.Context, rel *release.Release) error {
+	if rel == nil {
+		return nil
+	}
+
+	relObjects, err := util.ManifestObjects(strings.NewReader(rel.Manifest), fmt.Sprintf("%s-release-manifest", rel.Name))
+	if err != nil {
+		return fmt.Errorf("parsing release %q objects: %w", rel.Name, err)
+	}
+
+	var failures []Failure
+	for _, obj := range relObjects {
+		if obj.GetObjectKind().GroupVersionKind() != apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition") {
+			continue
+		}
+
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		uMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return fmt.Errorf("converting object %q to unstructured: %w", obj.GetName(), err)
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(uMap, crd); err != nil {
+			return fmt.Errorf("converting unstructured to CRD object: %w", err)
+		}
+
+		if err := ValidateStructural(crd); err != nil {
+			failures = append(failures, Failure{Name: crd.Name, Err: err})
+			continue
+		}
+		if err := p.dryRun(ctx, crd); err != nil {
+			if !apierrors.IsInvalid(err) && !apierrors.IsBadRequest(err) {
+				return fmt.Errorf("dry-run apply of CRD %q: %w", crd.Name, err)
+			}
+			failures = append(failures, Failure{Name: crd.Name, Err: err})
+		}
+	}
+
+	if len(failures) > 0 {
+		return &Error{Failures: failures}
+	}
+	return nil
+}
+
+// dryRun creates crd, or updates it if it exists, without persisting it.
+func (p *Preflight) dryRun(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) error {
+	existing, err := p.crdClient.Get(ctx, crd.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = p.crdClient.Create(ctx, crd, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	crd = crd.DeepCopy()
+	crd.ResourceVersion = existing.ResourceVersion
+	_, err = p.crdClient.Update(ctx, crd, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	return err
+}
+
+// ValidateStructural returns an error if the schema of any version of crd is
+// missing or not structural, as required for CRDs of apiextensions.k8s.io/v1.
+func ValidateStructural(crd *apiextensionsv1.CustomResourceDefinition) error {
+	var errs field.ErrorList
+	versionsPath := field.NewPath("spec", "versions")
+	for i, v := range crd.Spec.Versions {
+		fldPath := versionsPath.Index(i).Child("schema", "openAPIV3Schema")
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			errs = append(errs, field.Required(fldPath, "schemas are required"))
+			continue
+		}
+		internal := &apiextensions.JSONSchemaProps{}
+		if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(v.Schema.OpenAPIV3Schema, internal, nil); err != nil {
+			errs = append(errs, field.Invalid(fldPath, "", err.Error()))
+			continue
+		}
+		s, err := structuralschema.NewStructural(internal)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath, "", err.Error()))
+			continue
+		}
+		errs = append(errs, structuralschema.ValidateStructural(fldPath, s)...)
+	}
+	return errs.ToAggregate()
+}
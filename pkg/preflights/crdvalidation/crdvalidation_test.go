@@ -0,0 +1,58 @@
+package crdvalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestValidateStructural(t *testing.T) {
+	crd := func(schema *apiextensionsv1.JSONSchemaProps) *apiextensionsv1.CustomResourceDefinition {
+		version := apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1", Served: true, Storage: true}
+		if schema != nil {
+			version.Schema = &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: schema}
+		}
+		return &apiextensionsv1.CustomResourceDefinition{
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{Versions: []apiextensionsv1.CustomResourceDefinitionVersion{version}},
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		crd       *apiextensionsv1.CustomResourceDefinition
+		expectErr string
+	}{
+		{
+			name: "structural",
+			crd: crd(&apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"spec": {Type: "object", Properties: map[string]apiextensionsv1.JSONSchemaProps{"replicas": {Type: "integer"}}},
+				},
+			}),
+		},
+		{
+			name:      "missing schema",
+			crd:       crd(nil),
+			expectErr: "spec.versions[0].schema.openAPIV3Schema: Required value",
+		},
+		{
+			name: "missing type",
+			crd: crd(&apiextensionsv1.JSONSchemaProps{
+				Type:       "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{"spec": {}},
+			}),
+			expectErr: "spec.versions[0].schema.openAPIV3Schema.properties[spec].type: Required value",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStructural(tc.crd)
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package auto provisions bundles of any format that the core provisioners
+// support, by detecting the format from the layout of the bundle and
+// delegating to the handler of that format.
+package auto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/handler"
+)
+
+const (
+	// ProvisionerID is the unique auto provisioner ID
+	ProvisionerID = "core-rukpak-io-auto"
+)
+
+// Handler converts bundles with the handler of their format.
+type Handler struct {
+	Helm     handler.Handler
+	Registry handler.Handler
+	Plain    handler.Handler
+}
+
+// Handle converts the bundle in fsys with the handler of the format that bd
+// declares, or of the format that Detect detects if bd declares none.
+func (h *Handler) Handle(ctx context.Context, fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+	format := bd.Spec.Format
+	if format == "" {
+		var err error
+		format, err = Detect(fsys)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.FromContext(ctx).V(1).Info("detected bundle format", "format", format)
+	}
+
+	var delegate handler.Handler
+	switch format {
+	case rukpakv1alpha2.FormatHelm:
+		delegate = h.Helm
+	case rukpakv1alpha2.FormatRegistry:
+		delegate = h.Registry
+	case rukpakv1alpha2.FormatPlain:
+		delegate = h.Plain
+	default:
+		return nil, nil, fmt.Errorf("unknown bundle format %q", format)
+	}
+	if delegate == nil {
+		return nil, nil, fmt.Errorf("%s bundles are not supported", format)
+	}
+	return delegate.Handle(ctx, fsys, bd)
+}
+
+// Detect returns the format of the bundle in fsys:
+//
+//   - helm+v3, if it contains a Chart.yaml, either in its root or in its only
+//     directory,
+//   - registry+v1, if it contains a manifests and a metadata directory,
+//   - plain+v0, if it contains a manifests directory only.
+//
+// Kustomizations are detected, but rejected as they are not supported.
+func Detect(fsys fs.FS) (rukpakv1alpha2.BundleFormat, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", fmt.Errorf("read bundle root: %v", err)
+	}
+	dirs := map[string]bool{}
+	files := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs[e.Name()] = true
+		} else {
+			files[e.Name()] = true
+		}
+	}
+
+	switch {
+	case files["Chart.yaml"]:
+		return rukpakv1alpha2.FormatHelm, nil
+	case len(entries) == 1 && len(dirs) == 1 && exists(fsys, entries[0].Name()+"/Chart.yaml"):
+		return rukpakv1alpha2.FormatHelm, nil
+	case dirs["manifests"] && dirs["metadata"]:
+		return rukpakv1alpha2.FormatRegistry, nil
+	case dirs["manifests"]:
+		return rukpakv1alpha2.FormatPlain, nil
+	case files["kustomization.yaml"] || files["kustomization.yml"] || files["Kustomization"]:
+		return "", errors.New("kustomize bundles are not supported")
+	}
+	return "", errors.New("unknown bundle format: expected a helm chart, a registry+v1 bundle with manifests and metadata directories, or a plain+v0 bundle with a manifests directory")
+}
+
+func exists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
@@ -0,0 +1,93 @@
+package auto
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/handler"
+)
+
+func TestDetect(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("test")}
+	for _, tc := range []struct {
+		name      string
+		fsys      fstest.MapFS
+		expected  rukpakv1alpha2.BundleFormat
+		expectErr string
+	}{
+		{
+			name:     "chart in root",
+			fsys:     fstest.MapFS{"Chart.yaml": file, "templates/deployment.yaml": file},
+			expected: rukpakv1alpha2.FormatHelm,
+		},
+		{
+			name:     "chart in directory",
+			fsys:     fstest.MapFS{"hello-world/Chart.yaml": file, "hello-world/templates/deployment.yaml": file},
+			expected: rukpakv1alpha2.FormatHelm,
+		},
+		{
+			name:     "registry bundle",
+			fsys:     fstest.MapFS{"manifests/csv.yaml": file, "metadata/annotations.yaml": file},
+			expected: rukpakv1alpha2.FormatRegistry,
+		},
+		{
+			name:     "plain bundle",
+			fsys:     fstest.MapFS{"manifests/deployment.yaml": file},
+			expected: rukpakv1alpha2.FormatPlain,
+		},
+		{
+			name:      "kustomization",
+			fsys:      fstest.MapFS{"kustomization.yaml": file, "deployment.yaml": file},
+			expectErr: "kustomize bundles are not supported",
+		},
+		{
+			name:      "unknown",
+			fsys:      fstest.MapFS{"deployment.yaml": file},
+			expectErr: "unknown bundle format",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := Detect(tc.fsys)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, format)
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	handlerFor := func(name string) handler.Handler {
+		return handler.HandlerFunc(func(context.Context, fs.FS, *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+			return &chart.Chart{Metadata: &chart.Metadata{Name: name}}, nil, nil
+		})
+	}
+	h := &Handler{Helm: handlerFor("helm"), Registry: handlerFor("registry"), Plain: handlerFor("plain")}
+	file := &fstest.MapFile{Data: []byte("test")}
+
+	chrt, _, err := h.Handle(context.Background(), fstest.MapFS{"manifests/csv.yaml": file, "metadata/annotations.yaml": file}, &rukpakv1alpha2.BundleDeployment{})
+	require.NoError(t, err)
+	require.Equal(t, "registry", chrt.Name())
+
+	_, _, err = h.Handle(context.Background(), fstest.MapFS{"kustomization.yaml": file}, &rukpakv1alpha2.BundleDeployment{})
+	require.EqualError(t, err, "kustomize bundles are not supported")
+
+	// A declared format takes precedence over the layout of the bundle.
+	declared := &rukpakv1alpha2.BundleDeployment{Spec: rukpakv1alpha2.BundleDeploymentSpec{Format: rukpakv1alpha2.FormatPlain}}
+	chrt, _, err = h.Handle(context.Background(), fstest.MapFS{"manifests/csv.yaml": file, "metadata/annotations.yaml": file}, declared)
+	require.NoError(t, err)
+	require.Equal(t, "plain", chrt.Name())
+
+	declared.Spec.Format = "kustomize+v1"
+	_, _, err = h.Handle(context.Background(), fstest.MapFS{"manifests/csv.yaml": file}, declared)
+	require.EqualError(t, err, `unknown bundle format "kustomize+v1"`)
+}
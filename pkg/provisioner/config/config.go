@@ -0,0 +1,291 @@
+// Package config provisions bundles that consist of custom resources only,
+// such as the configuration of an operator that is installed by another
+// bundle.
+//
+// Config bundles have the layout of plain+v0 bundles. Every object must be an
+// instance of a CRD that is installed in the cluster, and is validated
+// against the schema of its version before anything is applied. Objects are
+// applied in stages that are ordered by their apply order annotation: a stage
+// is only applied once every object of the stages before it exists and meets
+// the status condition of its wait-for annotation, if any.
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/provisioner/plain"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// ProvisionerID is the unique config provisioner ID
+	ProvisionerID = "core-rukpak-io-config"
+
+	// ApplyOrderAnnotation orders the objects of a config bundle. Objects
+	// with a lower apply order are applied first. Defaults to 0.
+	ApplyOrderAnnotation = "config.rukpak.io/apply-order"
+
+	// WaitForAnnotation holds back the objects with a higher apply order until
+	// the annotated object has the given status conditions. Its value is a
+	// comma-separated list of condition types, each optionally followed by
+	// "=" and the expected status, which defaults to True, e.g.
+	// "Ready,Degraded=False".
+	WaitForAnnotation = "config.rukpak.io/wait-for"
+)
+
+// Handler converts config bundles into charts.
+type Handler struct {
+	// Reader reads the installed CRDs and the applied objects of the
+	// bundle.
+	Reader client.Reader
+}
+
+func (h *Handler) Handle(ctx context.Context, fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+	chrt, _, err := plain.HandleBundleDeployment(ctx, fsys, bd)
+	if err != nil {
+		return nil, nil, err
+	}
+	stages, err := h.stages(ctx, bd, chrt.Templates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chrt.Templates = nil
+	for i, s := range stages {
+		for _, obj := range s.objects {
+			chrt.Templates = append(chrt.Templates, obj.template)
+		}
+		if i == len(stages)-1 {
+			break
+		}
+		// Stages that were applied before are kept, even if the objects
+		// that they waited for do not have their conditions anymore.
+		applied, err := h.applied(ctx, stages[i+1])
+		if err != nil {
+			return nil, nil, err
+		}
+		if applied {
+			continue
+		}
+		waiting, err := h.waitingFor(ctx, s)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(waiting) > 0 {
+			util.SetChartPending(chrt, fmt.Sprintf("Objects with apply order %d and above wait for %s", stages[i+1].order, strings.Join(waiting, ", ")))
+			break
+		}
+	}
+	return chrt, nil, nil
+}
+
+// stage holds the objects that are applied together.
+type stage struct {
+	order   int
+	objects []object
+}
+
+type object struct {
+	template   *chart.File
+	key        client.ObjectKey
+	gvk        schema.GroupVersionKind
+	conditions []condition
+}
+
+// condition is a status condition that an object is waited for.
+type condition struct {
+	condType string
+	status   metav1.ConditionStatus
+}
+
+// stages validates the objects of templates against the schemas of their
+// CRDs, and groups them by their apply order.
+func (h *Handler) stages(ctx context.Context, bd *rukpakv1alpha2.BundleDeployment, templates []*chart.File) ([]stage, error) {
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := h.Reader.List(ctx, crds); err != nil {
+		return nil, fmt.Errorf("list CRDs: %v", err)
+	}
+	crdsByGroupKind := map[schema.GroupKind]*apiextensionsv1.CustomResourceDefinition{}
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+		crdsByGroupKind[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = crd
+	}
+
+	byOrder := map[int][]object{}
+	var errs []error
+	for _, t := range templates {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(t.Data, &u.Object); err != nil {
+			return nil, err
+		}
+		obj, order, err := parseObject(bd, u, crdsByGroupKind)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		obj.template = t
+		byOrder[order] = append(byOrder[order], obj)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config bundle: %v", utilerrors.NewAggregate(errs))
+	}
+
+	stages := make([]stage, 0, len(byOrder))
+	for order, objs := range byOrder {
+		stages = append(stages, stage{order: order, objects: objs})
+	}
+	sort.Slice(stages, func(i, j int) bool {
+		return stages[i].order < stages[j].order
+	})
+	return stages, nil
+}
+
+func parseObject(bd *rukpakv1alpha2.BundleDeployment, u *unstructured.Unstructured, crds map[schema.GroupKind]*apiextensionsv1.CustomResourceDefinition) (object, int, error) {
+	gvk := u.GroupVersionKind()
+	desc := describe(gvk, u.GetNamespace(), u.GetName())
+	crd, ok := crds[gvk.GroupKind()]
+	if !ok {
+		return object{}, 0, fmt.Errorf("%s is not an instance of an installed CRD", desc)
+	}
+	if err := validate(u, crd); err != nil {
+		return object{}, 0, fmt.Errorf("%s: %v", desc, err)
+	}
+
+	obj := object{gvk: gvk, key: client.ObjectKeyFromObject(u)}
+	if obj.key.Namespace == "" && crd.Spec.Scope == apiextensionsv1.NamespaceScoped {
+		obj.key.Namespace = bd.Spec.InstallNamespace
+	}
+	order := 0
+	if value, ok := u.GetAnnotations()[ApplyOrderAnnotation]; ok {
+		var err error
+		if order, err = strconv.Atoi(value); err != nil {
+			return object{}, 0, fmt.Errorf("%s: invalid %s annotation %q: must be an integer", desc, ApplyOrderAnnotation, value)
+		}
+	}
+	if value := u.GetAnnotations()[WaitForAnnotation]; value != "" {
+		for _, c := range strings.Split(value, ",") {
+			condType, status, _ := strings.Cut(strings.TrimSpace(c), "=")
+			if status == "" {
+				status = string(metav1.ConditionTrue)
+			}
+			if condType == "" {
+				return object{}, 0, fmt.Errorf("%s: invalid %s annotation %q: missing condition type", desc, WaitForAnnotation, value)
+			}
+			obj.conditions = append(obj.conditions, condition{condType: condType, status: metav1.ConditionStatus(status)})
+		}
+	}
+	return obj, order, nil
+}
+
+// validate validates u against the schema of its version of crd, like the
+// API server does when u is applied.
+func validate(u *unstructured.Unstructured, crd *apiextensionsv1.CustomResourceDefinition) error {
+	version := u.GroupVersionKind().Version
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version {
+			continue
+		}
+		if !v.Served {
+			return fmt.Errorf("version %q of CRD %q is not served", version, crd.Name)
+		}
+		if v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			return nil
+		}
+		converted := &apiextensions.CustomResourceValidation{}
+		if err := apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(v.Schema, converted, nil); err != nil {
+			return err
+		}
+		validator, _, err := validation.NewSchemaValidator(converted.OpenAPIV3Schema)
+		if err != nil {
+			return fmt.Errorf("create validator for the schema of version %q of CRD %q: %v", version, crd.Name, err)
+		}
+		return validation.ValidateCustomResource(field.NewPath(""), u.UnstructuredContent(), validator).ToAggregate()
+	}
+	return fmt.Errorf("CRD %q has no version %q", crd.Name, version)
+}
+
+// applied reports whether every object of s exists.
+func (h *Handler) applied(ctx context.Context, s stage) (bool, error) {
+	for _, obj := range s.objects {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(obj.gvk)
+		err := h.Reader.Get(ctx, obj.key, u)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("get %s: %v", describe(obj.gvk, obj.key.Namespace, obj.key.Name), err)
+		}
+	}
+	return true, nil
+}
+
+// waitingFor describes the objects of s that do not exist yet or do not have
+// the status conditions that they are waited for.
+func (h *Handler) waitingFor(ctx context.Context, s stage) ([]string, error) {
+	var waiting []string
+	for _, obj := range s.objects {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(obj.gvk)
+		desc := describe(obj.gvk, obj.key.Namespace, obj.key.Name)
+		err := h.Reader.Get(ctx, obj.key, u)
+		if apierrors.IsNotFound(err) {
+			waiting = append(waiting, fmt.Sprintf("%s to be created", desc))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %v", desc, err)
+		}
+		for _, c := range obj.conditions {
+			if !hasCondition(u, c) {
+				waiting = append(waiting, fmt.Sprintf("%s to have condition %s=%s", desc, c.condType, c.status))
+			}
+		}
+	}
+	return waiting, nil
+}
+
+// hasCondition reports whether u has the status condition c, and the
+// condition was observed for the current generation of u, if it records the
+// generation that it was observed for.
+func hasCondition(u *unstructured.Unstructured, c condition) bool {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, item := range conditions {
+		cond, ok := item.(map[string]interface{})
+		if !ok || cond["type"] != c.condType {
+			continue
+		}
+		if observed, ok, _ := unstructured.NestedInt64(cond, "observedGeneration"); ok && observed < u.GetGeneration() {
+			return false
+		}
+		return cond["status"] == string(c.status)
+	}
+	return false
+}
+
+func describe(gvk schema.GroupVersionKind, namespace, name string) string {
+	if namespace != "" {
+		name = namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %q (%s)", gvk.Kind, name, gvk.GroupVersion())
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	database = `apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: db
+  annotations:
+    config.rukpak.io/wait-for: Ready
+spec:
+  size: 3
+`
+	user = `apiVersion: example.com/v1
+kind: User
+metadata:
+  name: admin
+  annotations:
+    config.rukpak.io/apply-order: "1"
+spec:
+  database: db
+`
+)
+
+var crds = []client.Object{
+	newCRD("Database", "databases"),
+	newCRD("User", "users"),
+}
+
+func newCRD(kind, plural string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + ".example.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: kind, Plural: plural},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:   "v1",
+				Served: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"spec": {
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"size":     {Type: "integer"},
+								"database": {Type: "string"},
+							},
+						},
+					},
+				}},
+			}},
+		},
+	}
+}
+
+func newHandler(t *testing.T, objs ...client.Object) *Handler {
+	scheme := runtime.NewScheme()
+	require.NoError(t, apiextensionsv1.AddToScheme(scheme))
+	return &Handler{Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, crds...)...).Build()}
+}
+
+func handle(t *testing.T, h *Handler, manifests ...string) (int, string, error) {
+	fsys := fstest.MapFS{}
+	for i, m := range manifests {
+		fsys["manifests/"+string(rune('a'+i))+".yaml"] = &fstest.MapFile{Data: []byte(m)}
+	}
+	bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	bd.Spec.InstallNamespace = "ns"
+	chrt, _, err := h.Handle(context.Background(), fsys, bd)
+	if err != nil {
+		return 0, "", err
+	}
+	return len(chrt.Templates), util.ChartPending(chrt), nil
+}
+
+func appliedDatabase(status string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Database",
+		"metadata":   map[string]interface{}{"name": "db", "namespace": "ns"},
+	}}
+	if status != "" {
+		u.Object["status"] = map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": status}},
+		}
+	}
+	return u
+}
+
+func TestHandleStages(t *testing.T) {
+	for _, tt := range []struct {
+		description     string
+		objects         []client.Object
+		expectedObjects int
+		expectedPending string
+	}{
+		{
+			description:     "nothing applied yet",
+			expectedObjects: 1,
+			expectedPending: `Objects with apply order 1 and above wait for Database "ns/db" (example.com/v1) to be created`,
+		},
+		{
+			description:     "waited for object not ready",
+			objects:         []client.Object{appliedDatabase("False")},
+			expectedObjects: 1,
+			expectedPending: `Objects with apply order 1 and above wait for Database "ns/db" (example.com/v1) to have condition Ready=True`,
+		},
+		{
+			description:     "waited for object ready",
+			objects:         []client.Object{appliedDatabase("True")},
+			expectedObjects: 2,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			objects, pending, err := handle(t, newHandler(t, tt.objects...), user, database)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedObjects, objects)
+			require.Equal(t, tt.expectedPending, pending)
+		})
+	}
+}
+
+func TestHandleKeepsAppliedStages(t *testing.T) {
+	applied := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "User",
+		"metadata":   map[string]interface{}{"name": "admin", "namespace": "ns"},
+	}}
+	objects, pending, err := handle(t, newHandler(t, appliedDatabase("False"), applied), user, database)
+	require.NoError(t, err)
+	require.Equal(t, 2, objects)
+	require.Empty(t, pending)
+}
+
+func TestHandleRejectsInvalidObjects(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		manifest    string
+		expectedErr string
+	}{
+		{
+			description: "built-in kind",
+			manifest:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+			expectedErr: `ConfigMap "config" (v1) is not an instance of an installed CRD`,
+		},
+		{
+			description: "schema violation",
+			manifest:    "apiVersion: example.com/v1\nkind: Database\nmetadata:\n  name: db\nspec:\n  size: three\n",
+			expectedErr: "spec.size: Invalid value",
+		},
+		{
+			description: "unknown version",
+			manifest:    "apiVersion: example.com/v2\nkind: Database\nmetadata:\n  name: db\n",
+			expectedErr: `CRD "databases.example.com" has no version "v2"`,
+		},
+		{
+			description: "invalid apply order",
+			manifest:    "apiVersion: example.com/v1\nkind: Database\nmetadata:\n  name: db\n  annotations:\n    config.rukpak.io/apply-order: first\n",
+			expectedErr: "invalid config.rukpak.io/apply-order annotation",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			_, _, err := handle(t, newHandler(t), tt.manifest)
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
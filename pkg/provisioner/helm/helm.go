@@ -3,16 +3,20 @@ package helm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"path"
 
 	"golang.org/x/sync/errgroup"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/ignore"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/handler"
 	"github.com/operator-framework/rukpak/pkg/util"
 )
 
@@ -21,7 +25,21 @@ const (
 	ProvisionerID = "core-rukpak-io-helm"
 )
 
-func HandleBundleDeployment(_ context.Context, fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+// HandleBundleDeployment is the handler of BundleDeployments in clusters with
+// the DefaultClusterDomain.
+func HandleBundleDeployment(ctx context.Context, fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+	return NewHandler(DefaultClusterDomain).Handle(ctx, fsys, bd)
+}
+
+// NewHandler returns the handler of BundleDeployments in a cluster with the
+// given DNS domain, which is substituted into the values of the release.
+func NewHandler(clusterDomain string) handler.HandlerFunc {
+	return func(_ context.Context, fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
+		return handleBundleDeployment(fsys, bd, clusterDomain)
+	}
+}
+
+func handleBundleDeployment(fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment, clusterDomain string) (*chart.Chart, chartutil.Values, error) {
 	// Helm expects an FS whose root contains a single chart directory. Depending on how
 	// the bundle is sourced, the FS may or may not contain this single chart directory in
 	// its root. This FS wrapper adds this base directory unless the FS already has a base
@@ -30,8 +48,11 @@ func HandleBundleDeployment(_ context.Context, fsys fs.FS, bd *rukpakv1alpha2.Bu
 	if err != nil {
 		return nil, nil, err
 	}
+	if chartFS, err = ignoreChartFiles(chartFS); err != nil {
+		return nil, nil, err
+	}
 
-	values, err := loadValues(bd)
+	values, err := loadValues(chartFS, bd, clusterDomain)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -42,27 +63,109 @@ func HandleBundleDeployment(_ context.Context, fsys fs.FS, bd *rukpakv1alpha2.Bu
 	return chart, values, nil
 }
 
-func loadValues(bd *rukpakv1alpha2.BundleDeployment) (chartutil.Values, error) {
+// loadValues returns the values of the release. The values files that the
+// config names are read from the chart directory of chartFS and merged in
+// order, so that later files override earlier ones, and the inline values
+// override all of them. References to the substitution variables in the
+// inline values are replaced by their values.
+func loadValues(chartFS fs.FS, bd *rukpakv1alpha2.BundleDeployment, clusterDomain string) (chartutil.Values, error) {
 	data, err := json.Marshal(bd.Spec.Config)
 	if err != nil {
 		return nil, fmt.Errorf("marshal JSON for deployment config: %v", err)
 	}
-	var config map[string]string
+	// The config may hold keys that are not specific to this provisioner, such
+	// as requirements, so only the values are parsed.
+	var config struct {
+		Values      string   `json:"values"`
+		ValuesFiles []string `json:"valuesFiles"`
+	}
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("parse deployment config: %v", err)
 	}
-	valuesString := config["values"]
 
 	var values chartutil.Values
-	if valuesString == "" {
-		return nil, nil
+	if len(config.ValuesFiles) > 0 {
+		chartDir, err := chartDirFS(chartFS)
+		if err != nil {
+			return nil, err
+		}
+		values = chartutil.Values{}
+		for _, name := range config.ValuesFiles {
+			if !fs.ValidPath(name) {
+				return nil, fmt.Errorf("invalid values file path %q: must be relative to the chart directory", name)
+			}
+			fileData, err := fs.ReadFile(chartDir, name)
+			if err != nil {
+				return nil, fmt.Errorf("read values file %q: %v", name, err)
+			}
+			fileValues, err := chartutil.ReadValues(fileData)
+			if err != nil {
+				return nil, fmt.Errorf("read chart values from file %q: %v", name, err)
+			}
+			values = mergeValues(values, fileValues)
+		}
 	}
 
-	values, err = chartutil.ReadValues([]byte(valuesString))
+	if config.Values == "" {
+		return values, nil
+	}
+	inlineValues, err := chartutil.ReadValues([]byte(config.Values))
 	if err != nil {
 		return nil, fmt.Errorf("read chart values: %v", err)
 	}
-	return values, nil
+	substitute(map[string]interface{}(inlineValues), substitutionVariables(bd, clusterDomain))
+	if values == nil {
+		return inlineValues, nil
+	}
+	return mergeValues(values, inlineValues), nil
+}
+
+// chartDirFS returns the chart directory in the root of chartFS, as ensured
+// by util.EnsureBaseDirFS.
+func chartDirFS(chartFS fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(chartFS, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil, errors.New("bundle does not contain a single chart directory")
+	}
+	return fs.Sub(chartFS, entries[0].Name())
+}
+
+// ignoreChartFiles hides the files of the chart directory in the root of
+// chartFS that the .helmignore file of the chart ignores, like helm package
+// does.
+func ignoreChartFiles(chartFS fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(chartFS, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		// Reported when the chart is loaded.
+		return chartFS, nil
+	}
+	return util.IgnoreFS(chartFS, path.Join(entries[0].Name(), ignore.HelmIgnore))
+}
+
+// mergeValues merges override into base like helm does for multiple values
+// files: nested maps are merged, and any other value of override replaces
+// the value of base.
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range override {
+		if v, ok := v.(map[string]interface{}); ok {
+			if bv, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergeValues(bv, v)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
 }
 
 func getChart(chartfs fs.FS) (*chart.Chart, error) {
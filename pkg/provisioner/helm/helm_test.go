@@ -0,0 +1,117 @@
+package helm
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chartutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestLoadValues(t *testing.T) {
+	chartFS := fstest.MapFS{
+		"chart/Chart.yaml":       &fstest.MapFile{Data: []byte("name: test\nversion: 0.1.0\n")},
+		"chart/values.yaml":      &fstest.MapFile{Data: []byte("replicaCount: 1\n")},
+		"chart/values-prod.yaml": &fstest.MapFile{Data: []byte("replicaCount: 2\nimage:\n  tag: v1\n  pullPolicy: Always\n")},
+		"chart/values-east.yaml": &fstest.MapFile{Data: []byte("image:\n  tag: v2\n")},
+	}
+	bd := func(config map[string]interface{}) *rukpakv1alpha2.BundleDeployment {
+		data, err := json.Marshal(config)
+		require.NoError(t, err)
+		return &rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       rukpakv1alpha2.BundleDeploymentSpec{InstallNamespace: "test-ns", Config: runtime.RawExtension{Raw: data}},
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		config    map[string]interface{}
+		expected  chartutil.Values
+		expectErr string
+	}{
+		{
+			name:   "no values",
+			config: map[string]interface{}{},
+		},
+		{
+			name:     "inline values",
+			config:   map[string]interface{}{"values": "replicaCount: 3\n"},
+			expected: chartutil.Values{"replicaCount": float64(3)},
+		},
+		{
+			name:   "values files merged in order",
+			config: map[string]interface{}{"valuesFiles": []string{"values-prod.yaml", "values-east.yaml"}},
+			expected: chartutil.Values{
+				"replicaCount": float64(2),
+				"image":        map[string]interface{}{"tag": "v2", "pullPolicy": "Always"},
+			},
+		},
+		{
+			name:   "inline values override values files",
+			config: map[string]interface{}{"valuesFiles": []string{"values-prod.yaml"}, "values": "replicaCount: 3\n"},
+			expected: chartutil.Values{
+				"replicaCount": float64(3),
+				"image":        map[string]interface{}{"tag": "v1", "pullPolicy": "Always"},
+			},
+		},
+		{
+			name: "substituted inline values",
+			config: map[string]interface{}{"values": `
+url: http://$(RUKPAK_BUNDLE_DEPLOYMENT_NAME).$(RUKPAK_INSTALL_NAMESPACE).svc.$(RUKPAK_CLUSTER_DOMAIN)
+args: ["--namespace=$(RUKPAK_INSTALL_NAMESPACE)", "--escaped=$$(RUKPAK_INSTALL_NAMESPACE)", "--pod=$(POD_NAME)", "--unknown=$(RUKPAK_UNKNOWN)"]
+`},
+			expected: chartutil.Values{
+				"url":  "http://test.test-ns.svc.example.org",
+				"args": []interface{}{"--namespace=test-ns", "--escaped=$(RUKPAK_INSTALL_NAMESPACE)", "--pod=$(POD_NAME)", "--unknown=$(RUKPAK_UNKNOWN)"},
+			},
+		},
+		{
+			name:      "missing values file",
+			config:    map[string]interface{}{"valuesFiles": []string{"values-dev.yaml"}},
+			expectErr: `read values file "values-dev.yaml"`,
+		},
+		{
+			name:      "values file outside of the chart",
+			config:    map[string]interface{}{"valuesFiles": []string{"../values.yaml"}},
+			expectErr: "invalid values file path",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := loadValues(chartFS, bd(tc.config), "example.org")
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, values)
+		})
+	}
+}
+
+func TestHandleBundleDeploymentHelmIgnore(t *testing.T) {
+	fsys := fstest.MapFS{
+		"chart/Chart.yaml":             &fstest.MapFile{Data: []byte("apiVersion: v2\nname: test\nversion: 0.1.0\n")},
+		"chart/.helmignore":            &fstest.MapFile{Data: []byte("ci/\n*.tgz\n")},
+		"chart/templates/cm.yaml":      &fstest.MapFile{Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n")},
+		"chart/ci/values.yaml":         &fstest.MapFile{Data: []byte("replicaCount: 3\n")},
+		"chart/fixtures/large.tgz":     &fstest.MapFile{Data: []byte("not a chart")},
+		"chart/templates/snapshot.tgz": &fstest.MapFile{Data: []byte("not a template")},
+	}
+	bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	chrt, _, err := handleBundleDeployment(fsys, bd, DefaultClusterDomain)
+	require.NoError(t, err)
+	require.Len(t, chrt.Templates, 1)
+	require.Equal(t, "templates/cm.yaml", chrt.Templates[0].Name)
+	var files []string
+	for _, f := range chrt.Files {
+		files = append(files, f.Name)
+	}
+	require.Equal(t, []string{".helmignore"}, files)
+}
@@ -0,0 +1,60 @@
+package helm
+
+import (
+	"regexp"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// DefaultClusterDomain is the DNS domain of clusters whose domain is not
+// configured.
+const DefaultClusterDomain = "cluster.local"
+
+// The variables that are substituted into the inline values of a
+// BundleDeployment.
+const (
+	VarInstallNamespace     = "RUKPAK_INSTALL_NAMESPACE"
+	VarBundleDeploymentName = "RUKPAK_BUNDLE_DEPLOYMENT_NAME"
+	VarClusterDomain        = "RUKPAK_CLUSTER_DOMAIN"
+)
+
+var variableReference = regexp.MustCompile(`(\$?)\$\((RUKPAK_[A-Z_]+)\)`)
+
+func substitutionVariables(bd *rukpakv1alpha2.BundleDeployment, clusterDomain string) map[string]string {
+	return map[string]string{
+		VarInstallNamespace:     bd.Spec.InstallNamespace,
+		VarBundleDeploymentName: bd.Name,
+		VarClusterDomain:        clusterDomain,
+	}
+}
+
+// substitute replaces references of the form $(NAME) to the variables in
+// the strings of values, like the kubelet does for the command of a
+// container. $$(NAME) escapes a reference. References to unknown variables
+// are left as they are.
+func substitute(value interface{}, vars map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return variableReference.ReplaceAllStringFunc(v, func(ref string) string {
+			m := variableReference.FindStringSubmatch(ref)
+			val, ok := vars[m[2]]
+			switch {
+			case !ok:
+				return ref
+			case m[1] != "":
+				return ref[1:]
+			default:
+				return val
+			}
+		})
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = substitute(e, vars)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = substitute(e, vars)
+		}
+	}
+	return value
+}
@@ -10,7 +10,8 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/yaml"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
@@ -24,11 +25,9 @@ const (
 	manifestsDir = "manifests"
 )
 
-func HandleBundleDeployment(_ context.Context, fsys fs.FS, _ *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
-	if err := ValidateBundle(fsys); err != nil {
-		return nil, nil, err
-	}
+var errNoObjects = errors.New("invalid bundle: found zero objects: plain+v0 bundles are required to contain at least one object")
 
+func HandleBundleDeployment(_ context.Context, fsys fs.FS, _ *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
 	chrt, err := chartFromBundle(fsys)
 	if err != nil {
 		return nil, nil, err
@@ -37,66 +36,118 @@ func HandleBundleDeployment(_ context.Context, fsys fs.FS, _ *rukpakv1alpha2.Bun
 }
 
 func ValidateBundle(fsys fs.FS) error {
-	objects, err := getBundleObjects(fsys)
-	if err != nil {
+	count := 0
+	if err := visitBundleObjects(fsys, func(*unstructured.Unstructured) error {
+		count++
+		return nil
+	}); err != nil {
 		return fmt.Errorf("get objects from bundle manifests: %v", err)
 	}
-	if len(objects) == 0 {
-		return errors.New("invalid bundle: found zero objects: plain+v0 bundles are required to contain at least one object")
+	if count == 0 {
+		return errNoObjects
 	}
 	return nil
 }
 
-func getBundleObjects(bundleFS fs.FS) ([]client.Object, error) {
+// visitBundleObjects calls visit with every object of the manifests of the
+// bundle. Manifests are decoded one object at a time, so that the objects of
+// large bundles are not all held in memory at once.
+//
+// Objects that are defined more than once are visited only once if all of
+// their definitions are identical, and rejected otherwise, naming the
+// manifests that define them, so that conflicts are not left for Helm to
+// report as an opaque error about duplicate resources.
+func visitBundleObjects(bundleFS fs.FS, visit func(*unstructured.Unstructured) error) error {
 	entries, err := fs.ReadDir(bundleFS, manifestsDir)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var bundleObjects []client.Object
+	seen := map[objectKey]objectDefinition{}
 	for _, e := range entries {
 		if e.IsDir() {
-			return nil, fmt.Errorf("subdirectories are not allowed within the %q directory of the bundle image filesystem: found %q", manifestsDir, filepath.Join(manifestsDir, e.Name()))
+			return fmt.Errorf("subdirectories are not allowed within the %q directory of the bundle image filesystem: found %q", manifestsDir, filepath.Join(manifestsDir, e.Name()))
 		}
-
-		manifestObjects, err := getObjects(bundleFS, e)
-		if err != nil {
-			return nil, err
+		manifestPath := filepath.Join(manifestsDir, e.Name())
+		if err := visitObjects(bundleFS, manifestPath, func(obj *unstructured.Unstructured) error {
+			data, err := obj.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			key := objectKey{
+				gvk:       obj.GroupVersionKind(),
+				namespace: obj.GetNamespace(),
+				name:      obj.GetName(),
+			}
+			def := objectDefinition{path: manifestPath, hash: sha256.Sum256(data)}
+			if prev, ok := seen[key]; ok {
+				if prev.hash != def.hash {
+					return fmt.Errorf("conflicting definitions of %s found in %q and %q", key, prev.path, def.path)
+				}
+				return nil
+			}
+			seen[key] = def
+			return visit(obj)
+		}); err != nil {
+			return err
 		}
-		bundleObjects = append(bundleObjects, manifestObjects...)
 	}
-	return bundleObjects, nil
+	return nil
+}
+
+// objectKey identifies the object that a bundle manifest defines.
+type objectKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
 }
 
-func getObjects(bundle fs.FS, manifest fs.DirEntry) ([]client.Object, error) {
-	manifestPath := filepath.Join(manifestsDir, manifest.Name())
+func (k objectKey) String() string {
+	name := k.name
+	if k.namespace != "" {
+		name = k.namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %q (%s)", k.gvk.Kind, name, k.gvk.GroupVersion())
+}
+
+// objectDefinition records where an object was first defined, and a hash of
+// that definition.
+type objectDefinition struct {
+	path string
+	hash [sha256.Size]byte
+}
+
+func visitObjects(bundle fs.FS, manifestPath string, visit func(*unstructured.Unstructured) error) error {
 	manifestReader, err := bundle.Open(manifestPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer manifestReader.Close()
-	return util.ManifestObjects(manifestReader, manifestPath)
+	return util.VisitManifestObjects(manifestReader, manifestPath, visit)
 }
 
+// chartFromBundle validates the bundle and converts its objects into chart
+// templates in a single pass, so that only the templates are kept in memory.
 func chartFromBundle(fsys fs.FS) (*chart.Chart, error) {
-	objects, err := getBundleObjects(fsys)
-	if err != nil {
-		return nil, fmt.Errorf("read bundle objects from bundle: %v", err)
-	}
-
 	chrt := &chart.Chart{
 		Metadata: &chart.Metadata{},
 	}
-	for _, obj := range objects {
+	if err := visitBundleObjects(fsys, func(obj *unstructured.Unstructured) error {
 		yamlData, err := yaml.Marshal(obj)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		hash := sha256.Sum256(yamlData)
 		chrt.Templates = append(chrt.Templates, &chart.File{
 			Name: fmt.Sprintf("object-%x.yaml", hash[0:8]),
 			Data: yamlData,
 		})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get objects from bundle manifests: %v", err)
+	}
+	if len(chrt.Templates) == 0 {
+		return nil, errNoObjects
 	}
 	return chrt, nil
 }
@@ -0,0 +1,138 @@
+package plain
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBundle(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		manifest    string
+		expectedErr string
+	}{
+		{
+			description: "windows workload with CRLF line endings",
+			manifest: "apiVersion: v1\r\nkind: Pod\r\nmetadata:\r\n  name: windows-pod\r\nspec:\r\n" +
+				"  nodeSelector:\r\n    kubernetes.io/os: windows\r\n  containers:\r\n  - name: app\r\n    image: mcr.microsoft.com/windows/nanoserver:ltsc2022\r\n" +
+				"---\r\napiVersion: v1\r\nkind: ConfigMap\r\nmetadata:\r\n  name: config\r\n",
+		},
+		{
+			description: "byte order mark and lone CR line endings",
+			manifest:    "\ufeffapiVersion: v1\rkind: ConfigMap\rmetadata:\r  name: config\r",
+		},
+		{
+			description: "no objects",
+			manifest:    "\ufeff\r\n",
+			expectedErr: "found zero objects",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			err := ValidateBundle(fstest.MapFS{"manifests/manifest.yaml": &fstest.MapFile{Data: []byte(tt.manifest)}})
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateBundleDuplicateObjects(t *testing.T) {
+	const (
+		configA = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: ns\ndata:\n  key: a\n"
+		configB = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: ns\ndata:\n  key: b\n"
+	)
+	for _, tt := range []struct {
+		description string
+		manifests   fstest.MapFS
+		expectedErr string
+	}{
+		{
+			description: "identical definitions",
+			manifests: fstest.MapFS{
+				"manifests/a.yaml": &fstest.MapFile{Data: []byte(configA)},
+				"manifests/b.yaml": &fstest.MapFile{Data: []byte(configA)},
+			},
+		},
+		{
+			description: "same name in other namespaces",
+			manifests: fstest.MapFS{
+				"manifests/a.yaml": &fstest.MapFile{Data: []byte(configA)},
+				"manifests/b.yaml": &fstest.MapFile{Data: []byte(strings.Replace(configB, "ns", "other", 1))},
+			},
+		},
+		{
+			description: "conflicting definitions",
+			manifests: fstest.MapFS{
+				"manifests/a.yaml": &fstest.MapFile{Data: []byte(configA)},
+				"manifests/b.yaml": &fstest.MapFile{Data: []byte(configB)},
+			},
+			expectedErr: `conflicting definitions of ConfigMap "ns/config" (v1) found in "manifests/a.yaml" and "manifests/b.yaml"`,
+		},
+		{
+			description: "conflicting definitions in one manifest",
+			manifests: fstest.MapFS{
+				"manifests/a.yaml": &fstest.MapFile{Data: []byte(configA + "---\n" + configB)},
+			},
+			expectedErr: `conflicting definitions of ConfigMap "ns/config" (v1) found in "manifests/a.yaml" and "manifests/a.yaml"`,
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			err := ValidateBundle(tt.manifests)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	chrt, _, err := HandleBundleDeployment(context.Background(), fstest.MapFS{
+		"manifests/a.yaml": &fstest.MapFile{Data: []byte(configA)},
+		"manifests/b.yaml": &fstest.MapFile{Data: []byte(configA)},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, chrt.Templates, 1)
+}
+
+func TestHandleBundleDeployment(t *testing.T) {
+	const manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: c
+`
+	chrt, _, err := HandleBundleDeployment(context.Background(), fstest.MapFS{
+		"manifests/a.yaml":     &fstest.MapFile{Data: []byte(manifest)},
+		"manifests/empty.yaml": &fstest.MapFile{Data: []byte("---\n")},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, chrt.Templates, 3)
+	for i, name := range []string{"a", "b", "c"} {
+		data := fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n", name)
+		hash := sha256.Sum256([]byte(data))
+		require.Equal(t, fmt.Sprintf("object-%x.yaml", hash[0:8]), chrt.Templates[i].Name)
+		require.Equal(t, data, string(chrt.Templates[i].Data))
+	}
+
+	_, _, err = HandleBundleDeployment(context.Background(), fstest.MapFS{"manifests/empty.yaml": &fstest.MapFile{Data: []byte("---\n")}}, nil)
+	require.ErrorContains(t, err, "found zero objects")
+}
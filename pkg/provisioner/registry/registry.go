@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 
@@ -10,8 +11,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/requirements"
 	"github.com/operator-framework/rukpak/pkg/convert"
 	"github.com/operator-framework/rukpak/pkg/provisioner/plain"
+	"github.com/operator-framework/rukpak/pkg/util"
 )
 
 const (
@@ -19,10 +22,67 @@ const (
 	ProvisionerID = "core-rukpak-io-registry"
 )
 
+// Config is the registry provisioner specific configuration of a
+// BundleDeployment, read from its spec.config. Other keys of the config are
+// ignored.
+type Config struct {
+	// GenerateRBAC runs the operator as ServiceAccounts that are dedicated
+	// to the BundleDeployment, and names the Roles and ClusterRoles that
+	// grant the permissions of its CSV after the BundleDeployment, rather
+	// than reusing the ServiceAccounts named by the CSV.
+	GenerateRBAC bool `json:"generateRBAC,omitempty"`
+}
+
+// ConfigFrom returns the registry provisioner specific configuration of bd.
+func ConfigFrom(bd *rukpakv1alpha2.BundleDeployment) (*Config, error) {
+	cfg := &Config{}
+	if len(bd.Spec.Config.Raw) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(bd.Spec.Config.Raw, cfg); err != nil {
+		return nil, fmt.Errorf("parse registry provisioner config: %v", err)
+	}
+	return cfg, nil
+}
+
 func HandleBundleDeployment(ctx context.Context, fsys fs.FS, bd *rukpakv1alpha2.BundleDeployment) (*chart.Chart, chartutil.Values, error) {
-	plainFS, err := convert.RegistryV1ToPlain(fsys, bd.Spec.InstallNamespace, []string{metav1.NamespaceAll})
+	cfg, err := ConfigFrom(bd)
+	if err != nil {
+		return nil, nil, err
+	}
+	var opts []convert.Option
+	if cfg.GenerateRBAC {
+		opts = append(opts, convert.WithGeneratedRBAC(bd.Name))
+	}
+	reg, err := convert.ParseRegistryV1(fsys)
+	if err != nil {
+		return nil, nil, fmt.Errorf("convert registry+v1 bundle to plain+v0 bundle: %v", err)
+	}
+	plainFS, err := convert.PlainFS(*reg, bd.Spec.InstallNamespace, []string{metav1.NamespaceAll}, opts...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("convert registry+v1 bundle to plain+v0 bundle: %v", err)
 	}
-	return plain.HandleBundleDeployment(ctx, plainFS, bd)
+	chrt, values, err := plain.HandleBundleDeployment(ctx, plainFS, bd)
+	if err != nil {
+		return nil, nil, err
+	}
+	relatedImages := make([]rukpakv1alpha2.RelatedImage, 0, len(reg.RelatedImages))
+	for _, image := range reg.RelatedImages {
+		relatedImages = append(relatedImages, rukpakv1alpha2.RelatedImage{Name: image.Name, Image: image.Image})
+	}
+	util.SetChartBundleMetadata(chrt, rukpakv1alpha2.BundleMetadata{
+		PackageName:    reg.PackageName,
+		Version:        reg.Version,
+		ProvidedAPIs:   reg.ProvidedAPIs,
+		Channels:       reg.Channels,
+		DefaultChannel: reg.DefaultChannel,
+		RelatedImages:  relatedImages,
+	})
+	// The cluster is checked against these requirements before the chart is
+	// installed, like OLM refuses to install bundles on unsupported clusters.
+	requirements.AnnotateChart(chrt, requirements.Capabilities{
+		MinKubernetesVersion: reg.CSV.Spec.MinKubeVersion,
+		MaxOpenShiftVersion:  reg.MaxOpenShiftVersion,
+	})
+	return chrt, values, nil
 }
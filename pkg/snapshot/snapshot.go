@@ -0,0 +1,180 @@
+// Package snapshot defines portable snapshots of BundleDeployments, which
+// capture the resolved source, config and rendered manifest of a deployed
+// BundleDeployment, so that exactly that deployment can be restored, e.g. as
+// part of a disaster recovery runbook.
+//
+// Snapshots are gzipped tarballs that contain the following files:
+//
+//	bundledeployment.yaml  the BundleDeployment, including its status
+//	manifest.yaml          the rendered manifest of the deployed release
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// DigestAnnotationKey is set on BundleDeployments that are restored from a
+// snapshot. Provisioners refuse to deploy bundle content whose digest differs
+// from its value.
+const DigestAnnotationKey = "core.rukpak.io/snapshot-bundle-digest"
+
+const (
+	bundleDeploymentFile = "bundledeployment.yaml"
+	manifestFile         = "manifest.yaml"
+
+	// maxFileSize limits the size of the files that are read from a snapshot.
+	maxFileSize = 64 << 20
+)
+
+// Snapshot is a BundleDeployment as it was deployed at a point in time.
+type Snapshot struct {
+	// BundleDeployment is the BundleDeployment at the time of the snapshot,
+	// including its status, without server-populated metadata.
+	BundleDeployment *rukpakv1alpha2.BundleDeployment
+	// Manifest is the rendered manifest of the deployed release.
+	Manifest string
+}
+
+// New returns a snapshot of bd, given the rendered manifest of its deployed
+// release. It fails if the content of bd has not been unpacked yet, since the
+// snapshot could not pin it.
+func New(bd *rukpakv1alpha2.BundleDeployment, manifest string) (*Snapshot, error) {
+	if bd.Status.ResolvedSource == nil || bd.Status.ResolvedSource.BundleDigest == "" {
+		return nil, fmt.Errorf("bundle deployment %q has no resolved bundle digest", bd.Name)
+	}
+	captured := bd.DeepCopy()
+	captured.TypeMeta = metav1.TypeMeta{
+		APIVersion: rukpakv1alpha2.GroupVersion.String(),
+		Kind:       rukpakv1alpha2.BundleDeploymentKind,
+	}
+	captured.ObjectMeta = metav1.ObjectMeta{
+		Name:        bd.Name,
+		Labels:      bd.Labels,
+		Annotations: bd.Annotations,
+		Generation:  bd.Generation,
+	}
+	return &Snapshot{BundleDeployment: captured, Manifest: manifest}, nil
+}
+
+// Digest returns the digest of the bundle content that was deployed.
+func (s *Snapshot) Digest() string {
+	return s.BundleDeployment.Status.ResolvedSource.BundleDigest
+}
+
+// Restore returns the BundleDeployment that restores the snapshot. Its source
+// is the resolved source of the snapshot, so that image and git sources are
+// pinned to the digest and commit that were deployed, and it is annotated
+// with the bundle digest, so that provisioners refuse to deploy any other
+// content.
+func (s *Snapshot) Restore() *rukpakv1alpha2.BundleDeployment {
+	captured := s.BundleDeployment
+	bd := &rukpakv1alpha2.BundleDeployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rukpakv1alpha2.GroupVersion.String(),
+			Kind:       rukpakv1alpha2.BundleDeploymentKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   captured.Name,
+			Labels: captured.Labels,
+		},
+		Spec: *captured.Spec.DeepCopy(),
+	}
+	// The resolved source of inline bundles does not carry their content, so
+	// the source of the spec is restored instead, which is pinned by the
+	// digest annotation.
+	if resolved := captured.Status.ResolvedSource; resolved.Type != rukpakv1alpha2.SourceTypeInline {
+		bd.Spec.Source = *resolved.DeepCopy()
+		bd.Spec.Source.BundleDigest = ""
+	}
+	bd.Annotations = map[string]string{}
+	for k, v := range captured.Annotations {
+		bd.Annotations[k] = v
+	}
+	bd.Annotations[DigestAnnotationKey] = s.Digest()
+	return bd
+}
+
+// Write writes the snapshot as a gzipped tarball to w.
+func Write(w io.Writer, s *Snapshot) error {
+	bdData, err := yaml.Marshal(s.BundleDeployment)
+	if err != nil {
+		return fmt.Errorf("marshal bundle deployment: %v", err)
+	}
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	now := time.Now()
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{bundleDeploymentFile, bdData},
+		{manifestFile, []byte(s.Manifest)},
+	} {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.name,
+			Mode:     0600,
+			Size:     int64(len(file.data)),
+			ModTime:  now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// Read reads a snapshot that was written by Write from r.
+func Read(r io.Reader) (*Snapshot, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %v", err)
+	}
+	defer gzr.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read snapshot: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxFileSize {
+			return nil, fmt.Errorf("read snapshot: file %q exceeds %d bytes", hdr.Name, maxFileSize)
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, fmt.Errorf("read snapshot: %v", err)
+		}
+	}
+
+	bdData, ok := files[bundleDeploymentFile]
+	if !ok {
+		return nil, fmt.Errorf("read snapshot: missing %s", bundleDeploymentFile)
+	}
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	if err := yaml.UnmarshalStrict(bdData, bd); err != nil {
+		return nil, fmt.Errorf("read snapshot: parse %s: %v", bundleDeploymentFile, err)
+	}
+	return New(bd, string(files[manifestFile]))
+}
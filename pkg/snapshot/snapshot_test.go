@@ -0,0 +1,89 @@
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func newBundleDeployment() *rukpakv1alpha2.BundleDeployment {
+	return &rukpakv1alpha2.BundleDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Labels:          map[string]string{"app": "test"},
+			ResourceVersion: "42",
+			UID:             "1234",
+		},
+		Spec: rukpakv1alpha2.BundleDeploymentSpec{
+			InstallNamespace:     "test-ns",
+			ProvisionerClassName: "core-rukpak-io-plain",
+			Source: rukpakv1alpha2.BundleSource{
+				Type:  rukpakv1alpha2.SourceTypeImage,
+				Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle:v1"},
+			},
+			Config: runtime.RawExtension{Raw: []byte(`{"watchNamespaces":["test-ns"]}`)},
+		},
+		Status: rukpakv1alpha2.BundleDeploymentStatus{
+			ResolvedSource: &rukpakv1alpha2.BundleSource{
+				Type:         rukpakv1alpha2.SourceTypeImage,
+				Image:        &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle@sha256:1"},
+				BundleDigest: "sha256:content",
+			},
+		},
+	}
+}
+
+func TestNewRequiresBundleDigest(t *testing.T) {
+	bd := newBundleDeployment()
+	bd.Status.ResolvedSource = nil
+	_, err := New(bd, "")
+	require.ErrorContains(t, err, `bundle deployment "test" has no resolved bundle digest`)
+}
+
+func TestWriteRead(t *testing.T) {
+	s, err := New(newBundleDeployment(), "kind: ConfigMap\n")
+	require.NoError(t, err)
+	require.Empty(t, s.BundleDeployment.ResourceVersion)
+	require.Empty(t, s.BundleDeployment.UID)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, s))
+	read, err := Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, s, read)
+	require.Equal(t, "sha256:content", read.Digest())
+}
+
+func TestRestore(t *testing.T) {
+	s, err := New(newBundleDeployment(), "")
+	require.NoError(t, err)
+
+	bd := s.Restore()
+	require.Equal(t, "test", bd.Name)
+	require.Equal(t, map[string]string{"app": "test"}, bd.Labels)
+	require.Equal(t, "sha256:content", bd.Annotations[DigestAnnotationKey])
+	require.Equal(t, rukpakv1alpha2.BundleSource{
+		Type:  rukpakv1alpha2.SourceTypeImage,
+		Image: &rukpakv1alpha2.ImageSource{Ref: "quay.io/example/bundle@sha256:1"},
+	}, bd.Spec.Source)
+	require.JSONEq(t, `{"watchNamespaces":["test-ns"]}`, string(bd.Spec.Config.Raw))
+	require.Equal(t, rukpakv1alpha2.BundleDeploymentStatus{}, bd.Status)
+}
+
+func TestRestoreInline(t *testing.T) {
+	bd := newBundleDeployment()
+	bd.Spec.Source = rukpakv1alpha2.BundleSource{
+		Type:   rukpakv1alpha2.SourceTypeInline,
+		Inline: &rukpakv1alpha2.InlineSource{Manifests: []string{"kind: ConfigMap"}},
+	}
+	bd.Status.ResolvedSource = &rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeInline, BundleDigest: "sha256:content"}
+	s, err := New(bd, "")
+	require.NoError(t, err)
+
+	require.Equal(t, bd.Spec.Source, s.Restore().Spec.Source)
+}
@@ -0,0 +1,82 @@
+package source
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestConfigMapsUnpack(t *testing.T) {
+	cl := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "manifests"},
+			Data:       map[string]string{"configmap.yaml": "kind: ConfigMap\n"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "manifests-copy"},
+			Data:       map[string]string{"configmap.yaml": "kind: ConfigMap\n"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "metadata"},
+			BinaryData: map[string][]byte{"annotations.yaml": []byte("annotations: {}\n")},
+		},
+	).Build()
+	unpacker := &ConfigMaps{Reader: cl, ConfigMapNamespace: "rukpak-system"}
+	source := func(sources ...rukpakv1alpha2.ConfigMapSource) *rukpakv1alpha2.BundleDeployment {
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeConfigMaps, ConfigMaps: sources}
+		return bd
+	}
+
+	for _, tc := range []struct {
+		name      string
+		bd        *rukpakv1alpha2.BundleDeployment
+		files     map[string]string
+		expectErr string
+	}{
+		{
+			name: "data and binary data",
+			bd: source(
+				rukpakv1alpha2.ConfigMapSource{ConfigMap: corev1.LocalObjectReference{Name: "manifests"}, Path: "manifests"},
+				rukpakv1alpha2.ConfigMapSource{ConfigMap: corev1.LocalObjectReference{Name: "metadata"}, Path: "metadata"},
+			),
+			files: map[string]string{"manifests/configmap.yaml": "kind: ConfigMap\n", "metadata/annotations.yaml": "annotations: {}\n"},
+		},
+		{
+			name: "duplicate paths",
+			bd: source(
+				rukpakv1alpha2.ConfigMapSource{ConfigMap: corev1.LocalObjectReference{Name: "manifests"}},
+				rukpakv1alpha2.ConfigMapSource{ConfigMap: corev1.LocalObjectReference{Name: "manifests-copy"}},
+			),
+			expectErr: `duplicate path "configmap.yaml" found in configmaps [manifests manifests-copy]`,
+		},
+		{
+			name:      "missing configmap",
+			bd:        source(rukpakv1alpha2.ConfigMapSource{ConfigMap: corev1.LocalObjectReference{Name: "missing"}}),
+			expectErr: "get configmap rukpak-system/missing",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := unpacker.Unpack(context.Background(), tc.bd)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, StateUnpacked, result.State)
+			require.Equal(t, tc.bd.Spec.Source.ConfigMaps, result.ResolvedSource.ConfigMaps)
+			for name, content := range tc.files {
+				data, err := fs.ReadFile(result.Bundle, name)
+				require.NoError(t, err)
+				require.Equal(t, content, string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Package source unpacks the bundle content that BundleDeployments reference.
+//
+// Every source type is implemented by an Unpacker of its own, which can be
+// used without the provisioners: ImageRegistry pulls images from registries
+// in-process, Image pulls them with pods, and Git, HTTP, ConfigMaps, Secrets
+// and Inline unpack the sources of the same names. Unpackers only read the
+// cluster through the clients they are configured with, so projects that
+// embed them, such as operator-controller, can construct them directly rather
+// than through NewDefaultUnpacker, which requires a manager.
+//
+// NewUnpacker combines unpackers into one that dispatches on the source type
+// of a BundleDeployment and applies the path filters, ignore files and
+// sanitization that are common to all sources.
+//
+// All unpackers stop when the context that they are called with is canceled,
+// including requests to registries, repositories and servers that are in
+// flight.
+package source
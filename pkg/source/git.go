@@ -7,29 +7,46 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
+	nethttp "net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/go-git/go-billy/v5"
-	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	sshgit "github.com/go-git/go-git/v5/plumbing/transport/ssh"
-	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 	"golang.org/x/crypto/ssh"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
+	"github.com/operator-framework/rukpak/pkg/util"
 )
 
 type Git struct {
 	client.Reader
 	SecretNamespace string
+	// CrossNamespaceReader reads auth secrets that are referenced in other
+	// namespaces than SecretNamespace, and the SecretReferenceGrants that allow
+	// them to be referenced. The embedded Reader is used if unset.
+	CrossNamespaceReader client.Reader
+	// TempDir is the directory that repositories are cloned to, and from
+	// which the content of bundles is read. The default directory for
+	// temporary files is used if unset.
+	TempDir string
 }
 
 func (r *Git) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (*Result, error) {
@@ -53,6 +70,13 @@ func (r *Git) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeploymen
 		Tags:            git.NoTags,
 		InsecureSkipTLS: bundle.Spec.Source.Git.Auth.InsecureSkipVerify,
 	}
+	if gitsource.Proxy != nil {
+		proxyOpts, err := gitProxyOptions(gitsource)
+		if err != nil {
+			return nil, rukpakerrors.NewUnrecoverable(err)
+		}
+		cloneOpts.ProxyOptions = proxyOpts
+	}
 
 	if bundle.Spec.Source.Git.Auth.Secret.Name != "" {
 		auth, err := r.configAuth(ctx, bundle)
@@ -72,45 +96,93 @@ func (r *Git) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeploymen
 		cloneOpts.Depth = 1
 	}
 
-	// Clone
-	repo, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), &cloneOpts)
+	// Clone to disk rather than into memory, so that large repositories do
+	// not have to fit in the memory of the provisioner. The clone is removed
+	// once the result is closed.
+	cloneDir, err := os.MkdirTemp(r.TempDir, "git-")
 	if err != nil {
-		return nil, fmt.Errorf("bundle unpack git clone error: %v - %s", err, progress.String())
+		return nil, fmt.Errorf("create clone directory: %v", err)
 	}
-	wt, err := repo.Worktree()
+	result, err := r.unpack(ctx, bundle, cloneDir, &cloneOpts, &progress)
 	if err != nil {
-		return nil, fmt.Errorf("bundle unpack error: %v", err)
+		_ = os.RemoveAll(cloneDir)
+		return nil, err
 	}
+	result.Closer = removeDir(cloneDir)
+	return result, nil
+}
 
-	// Checkout commit
-	if gitsource.Ref.Commit != "" {
-		commitHash := plumbing.NewHash(gitsource.Ref.Commit)
-		if err := wt.Reset(&git.ResetOptions{
-			Commit: commitHash,
-			Mode:   git.HardReset,
-		}); err != nil {
-			return nil, fmt.Errorf("checkout commit %q: %v", commitHash.String(), err)
+// unpack clones the repository of the git source of bundle to cloneDir, and
+// returns the bundle in the worktree of the clone.
+func (r *Git) unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, cloneDir string, cloneOpts *git.CloneOptions, progress *bytes.Buffer) (*Result, error) {
+	gitsource := bundle.Spec.Source.Git
+
+	// Clone. The repository is cloned without a worktree, so that only the
+	// files that end up in the bundle are checked out below.
+	var repo *git.Repository
+	if err := fetchWithRetry(ctx, gitsource.Retry, func(ctx context.Context) error {
+		progress.Reset()
+		// Start every attempt from an empty directory.
+		if err := os.RemoveAll(cloneDir); err != nil {
+			return err
 		}
+		storer := filesystem.NewStorage(osfs.New(filepath.Join(cloneDir, "git"), osfs.WithBoundOS()), cache.NewObjectLRUDefault())
+		var err error
+		repo, err = git.CloneContext(ctx, storer, nil, cloneOpts)
+		if err != nil {
+			return classifyGitError(fmt.Errorf("bundle unpack git clone error: %w - %s", err, progress.String()))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	var bundleFS fs.FS = &billyFS{wt.Filesystem}
+	commitHash, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("resolve commit hash: %v", err)
+	}
+	if gitsource.Ref.Commit != "" {
+		hash := plumbing.NewHash(gitsource.Ref.Commit)
+		commitHash = &hash
+	}
+	commit, err := repo.CommitObject(*commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("checkout commit %q: %v", commitHash.String(), err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("checkout commit %q: %v", commitHash.String(), err)
+	}
 
 	// Subdirectory
 	if gitsource.Directory != "" {
 		directory := filepath.Clean(gitsource.Directory)
-		if directory[:3] == "../" || directory[0] == '/' {
+		if strings.HasPrefix(directory, "../") || filepath.IsAbs(directory) {
 			return nil, fmt.Errorf("get subdirectory %q for repository %q: %s", gitsource.Directory, gitsource.Repository, "directory can not start with '../' or '/'")
 		}
-		sub, err := wt.Filesystem.Chroot(filepath.Clean(directory))
-		if err != nil {
-			return nil, fmt.Errorf("get subdirectory %q for repository %q: %v", gitsource.Directory, gitsource.Repository, err)
+		if directory != "." {
+			if tree, err = tree.Tree(filepath.ToSlash(directory)); err != nil {
+				return nil, fmt.Errorf("get subdirectory %q for repository %q: %v", gitsource.Directory, gitsource.Repository, err)
+			}
 		}
-		bundleFS = &billyFS{sub}
 	}
 
-	commitHash, err := repo.ResolveRevision("HEAD")
+	// Checkout. The path filters are applied to the files of the tree, so
+	// that the files they drop are never written to disk.
+	filter, err := newPathFilter(gitsource.PathFilters)
 	if err != nil {
-		return nil, fmt.Errorf("resolve commit hash: %v", err)
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("error parsing path filters: %w", err))
+	}
+	worktree := osfs.New(filepath.Join(cloneDir, "worktree"), osfs.WithBoundOS())
+	if err := checkoutTree(tree, worktree, filter); err != nil {
+		return nil, fmt.Errorf("checkout commit %q: %v", commitHash.String(), err)
+	}
+	bundleFS := &billyFS{worktree}
+
+	if gitsource.Verify != nil {
+		if err := r.verifySignature(ctx, repo, gitsource, *commitHash); err != nil {
+			return nil, err
+		}
 	}
 
 	resolvedGit := bundle.Spec.Source.Git.DeepCopy()
@@ -128,10 +200,113 @@ func (r *Git) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeploymen
 	return &Result{Bundle: bundleFS, ResolvedSource: resolvedSource, State: StateUnpacked, Message: message}, nil
 }
 
+// checkoutTree writes the files of tree that filter keeps to wt. A nil filter
+// keeps every file.
+func checkoutTree(tree *object.Tree, wt billy.Filesystem, filter *util.PathFilter) error {
+	if err := wt.MkdirAll(".", 0755); err != nil {
+		return err
+	}
+	return tree.Files().ForEach(func(f *object.File) error {
+		if filter != nil && !filter.Visible(f.Name, false) {
+			return nil
+		}
+		if !fs.ValidPath(f.Name) {
+			return fmt.Errorf("invalid path %q", f.Name)
+		}
+		if err := wt.MkdirAll(path.Dir(f.Name), 0755); err != nil {
+			return err
+		}
+		if f.Mode == filemode.Symlink {
+			target, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			return wt.Symlink(target, f.Name)
+		}
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		out, err := wt.OpenFile(f.Name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
 func (r *Git) Cleanup(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) error {
 	return nil
 }
 
+// removeDir is an io.Closer that removes the directory at its path.
+type removeDir string
+
+func (d removeDir) Close() error {
+	return os.RemoveAll(string(d))
+}
+
+// gitProxyOptions returns the proxy options of cloning the repository of a
+// git source that sets a proxy of its own. Only repositories that are cloned
+// over http(s) are proxied. Since go-git falls back to the proxy of the
+// environment when no proxy is set, a source cannot opt out of the proxy of
+// the provisioner, which has to list the repository in NO_PROXY instead.
+func gitProxyOptions(gitsource *rukpakv1alpha2.GitSource) (transport.ProxyOptions, error) {
+	repoURL, err := url.Parse(gitsource.Repository)
+	if err != nil || (repoURL.Scheme != "http" && repoURL.Scheme != "https") {
+		return transport.ProxyOptions{}, nil
+	}
+	proxyURL, err := proxyConfig(gitsource.Proxy).ProxyFunc()(repoURL)
+	if err != nil {
+		return transport.ProxyOptions{}, fmt.Errorf("invalid proxy of repository %q: %v", gitsource.Repository, err)
+	}
+	if proxyURL == nil {
+		return transport.ProxyOptions{}, nil
+	}
+	return transport.ProxyOptions{URL: proxyURL.String()}, nil
+}
+
+// classifyGitError marks errors that point at a problem with the source, such
+// as a missing repository or reference, as unrecoverable, and network
+// failures and server errors as transient.
+func classifyGitError(err error) error {
+	var noMatchingRefSpec git.NoMatchingRefSpecError
+	switch {
+	case errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, transport.ErrEmptyRemoteRepository),
+		errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, plumbing.ErrReferenceNotFound),
+		errors.As(err, &noMatchingRefSpec):
+		return rukpakerrors.NewUnrecoverable(err)
+	}
+
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		var httpErr *http.Err
+		if errors.As(unexpected.Err, &httpErr) {
+			if code := httpErr.StatusCode(); code == nethttp.StatusRequestTimeout || code == nethttp.StatusTooManyRequests || code >= nethttp.StatusInternalServerError {
+				return rukpakerrors.NewTransient(err)
+			}
+			return rukpakerrors.NewUnrecoverable(err)
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return rukpakerrors.NewTransient(err)
+	}
+	return err
+}
+
 func (r *Git) configAuth(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (transport.AuthMethod, error) {
 	var auth transport.AuthMethod
 	if strings.HasPrefix(bundle.Spec.Source.Git.Repository, "http") {
@@ -182,7 +357,7 @@ func (r *Git) configAuth(ctx context.Context, bundle *rukpakv1alpha2.BundleDeplo
 // It returns the username ane password when they are in the secret
 func (r *Git) getCredentials(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (string, string, error) {
 	secret := &corev1.Secret{}
-	err := r.Get(ctx, client.ObjectKey{Namespace: r.SecretNamespace, Name: bundle.Spec.Source.Git.Auth.Secret.Name}, secret)
+	err := getAuthSecret(ctx, r.Reader, r.CrossNamespaceReader, r.SecretNamespace, bundle, bundle.Spec.Source.Git.Auth, secret)
 	if err != nil {
 		return "", "", err
 	}
@@ -196,7 +371,7 @@ func (r *Git) getCredentials(ctx context.Context, bundle *rukpakv1alpha2.BundleD
 // It returns the privatekey and the entry of the host in known_hosts when they are in the secret
 func (r *Git) getCertificate(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) ([]byte, []byte, error) {
 	secret := &corev1.Secret{}
-	err := r.Get(ctx, client.ObjectKey{Namespace: r.SecretNamespace, Name: bundle.Spec.Source.Git.Auth.Secret.Name}, secret)
+	err := getAuthSecret(ctx, r.Reader, r.CrossNamespaceReader, r.SecretNamespace, bundle, bundle.Spec.Source.Git.Auth, secret)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -239,6 +414,10 @@ func (f *billyFS) Open(path string) (fs.File, error) {
 	return &billyFile{file, fi}, nil
 }
 
+func (f *billyFS) ReadLink(name string) (string, error) {
+	return f.Filesystem.Readlink(name)
+}
+
 func (f *billyFS) ReadDir(name string) ([]fs.DirEntry, error) {
 	fis, err := f.Filesystem.ReadDir(name)
 	if err != nil {
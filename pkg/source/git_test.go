@@ -0,0 +1,143 @@
+package source
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/file"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// newGitFixture creates a git repository in a temporary directory with a
+// commit of every set of files, and returns its URL and the commit hashes.
+// The file transport of go-git runs the git binaries, so the repository is
+// served in-process instead.
+func newGitFixture(t *testing.T, commits ...map[string]string) (string, []plumbing.Hash) {
+	client.InstallProtocol("file", server.DefaultServer)
+	t.Cleanup(func() { client.InstallProtocol("file", file.DefaultClient) })
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	for _, files := range commits {
+		for name, content := range files {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			_, err := wt.Add(name)
+			require.NoError(t, err)
+		}
+		hash, err := wt.Commit("commit", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}})
+		require.NoError(t, err)
+		hashes = append(hashes, hash)
+	}
+	return "file://" + filepath.Join(dir, git.GitDirName), hashes
+}
+
+func TestGitUnpack(t *testing.T) {
+	repository, commits := newGitFixture(t,
+		map[string]string{"manifests/configmap.yaml": "v1", "other/configmap.yaml": "other"},
+		map[string]string{"manifests/configmap.yaml": "v2"},
+	)
+
+	for _, tc := range []struct {
+		name           string
+		source         rukpakv1alpha2.GitSource
+		files          map[string]string
+		resolvedCommit plumbing.Hash
+	}{
+		{
+			name:           "default branch",
+			source:         rukpakv1alpha2.GitSource{Repository: repository},
+			files:          map[string]string{"manifests/configmap.yaml": "v2", "other/configmap.yaml": "other"},
+			resolvedCommit: commits[1],
+		},
+		{
+			name:           "commit",
+			source:         rukpakv1alpha2.GitSource{Repository: repository, Ref: rukpakv1alpha2.GitRef{Commit: commits[0].String()}},
+			files:          map[string]string{"manifests/configmap.yaml": "v1", "other/configmap.yaml": "other"},
+			resolvedCommit: commits[0],
+		},
+		{
+			name:           "directory",
+			source:         rukpakv1alpha2.GitSource{Repository: repository, Directory: "./manifests"},
+			files:          map[string]string{"configmap.yaml": "v2"},
+			resolvedCommit: commits[1],
+		},
+		{
+			name:           "path filters",
+			source:         rukpakv1alpha2.GitSource{Repository: repository, PathFilters: rukpakv1alpha2.PathFilters{ExcludePaths: []string{"other"}}},
+			files:          map[string]string{"manifests/configmap.yaml": "v2"},
+			resolvedCommit: commits[1],
+		},
+		{
+			name:           "path filters of a directory",
+			source:         rukpakv1alpha2.GitSource{Repository: repository, Directory: "manifests", PathFilters: rukpakv1alpha2.PathFilters{IncludePaths: []string{"*.json"}}},
+			files:          map[string]string{},
+			resolvedCommit: commits[1],
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{}
+			bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeGit, Git: &tc.source}
+
+			tempDir := t.TempDir()
+			result, err := (&Git{TempDir: tempDir}).Unpack(context.Background(), bd)
+			require.NoError(t, err)
+			require.Equal(t, StateUnpacked, result.State)
+			require.Equal(t, tc.resolvedCommit.String(), result.ResolvedSource.Git.Ref.Commit)
+			files := map[string]string{}
+			require.NoError(t, fs.WalkDir(result.Bundle, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				data, err := fs.ReadFile(result.Bundle, path)
+				files[path] = string(data)
+				return err
+			}))
+			require.Equal(t, tc.files, files)
+
+			// Only the files of the bundle are checked out.
+			worktrees, err := filepath.Glob(filepath.Join(tempDir, "*", "worktree"))
+			require.NoError(t, err)
+			require.Len(t, worktrees, 1)
+			checkedOut := 0
+			require.NoError(t, filepath.WalkDir(worktrees[0], func(_ string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					checkedOut++
+				}
+				return err
+			}))
+			require.Equal(t, len(tc.files), checkedOut)
+
+			require.NoError(t, result.Close())
+			clones, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			require.Empty(t, clones)
+		})
+	}
+
+	t.Run("canceled context", func(t *testing.T) {
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeGit, Git: &rukpakv1alpha2.GitSource{Repository: repository}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := (&Git{}).Unpack(ctx, bd)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
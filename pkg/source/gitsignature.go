@@ -0,0 +1,224 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// ErrSignatureVerification is wrapped by the unpack errors of git sources
+// whose commit or tag signature could not be verified.
+var ErrSignatureVerification = errors.New("signature verification failed")
+
+const (
+	// sshSignatureNamespace is the namespace that git signs commits and
+	// tags in with SSH keys.
+	sshSignatureNamespace = "git"
+	sshSignatureMagic     = "SSHSIG"
+)
+
+// signingKeys are the keys that are allowed to sign commits and tags.
+type signingKeys struct {
+	gpg string
+	ssh []ssh.PublicKey
+}
+
+// verifySignature verifies the signature of the tag that the source refers
+// to if it is an annotated tag, and of the commit at head otherwise.
+func (r *Git) verifySignature(ctx context.Context, repo *git.Repository, gitsource *rukpakv1alpha2.GitSource, head plumbing.Hash) error {
+	keys, err := r.signingKeys(ctx, gitsource.Verify)
+	if err != nil {
+		return err
+	}
+
+	if gitsource.Ref.Tag != "" {
+		tagRef, err := repo.Tag(gitsource.Ref.Tag)
+		if err != nil {
+			return fmt.Errorf("resolve tag %q: %v", gitsource.Ref.Tag, err)
+		}
+		tag, err := repo.TagObject(tagRef.Hash())
+		switch {
+		case err == nil:
+			if err := keys.verify(tag.PGPSignature, tag.EncodeWithoutSignature, func(k string) error { _, err := tag.Verify(k); return err }); err != nil {
+				return fmt.Errorf("%w: tag %q: %v", ErrSignatureVerification, gitsource.Ref.Tag, err)
+			}
+			return nil
+		case !errors.Is(err, plumbing.ErrObjectNotFound):
+			return fmt.Errorf("get tag %q: %v", gitsource.Ref.Tag, err)
+		}
+		// Lightweight tags carry no signature, so the commit that they
+		// point to must be signed instead.
+	}
+
+	commit, err := repo.CommitObject(head)
+	if err != nil {
+		return fmt.Errorf("get commit %q: %v", head, err)
+	}
+	if err := keys.verify(commit.PGPSignature, commit.EncodeWithoutSignature, func(k string) error { _, err := commit.Verify(k); return err }); err != nil {
+		return fmt.Errorf("%w: commit %q: %v", ErrSignatureVerification, head, err)
+	}
+	return nil
+}
+
+// signingKeys reads the keys that are allowed to sign from the secret of
+// verify.
+func (r *Git) signingKeys(ctx context.Context, verify *rukpakv1alpha2.GitVerification) (*signingKeys, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: r.SecretNamespace, Name: verify.Secret.Name}, secret); err != nil {
+		return nil, fmt.Errorf("get signature verification secret: %v", err)
+	}
+	sshKeys, err := parseAllowedSigners(secret.Data["ssh-allowed-signers"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse ssh-allowed-signers of secret %q: %v", ErrSignatureVerification, verify.Secret.Name, err)
+	}
+	keys := &signingKeys{gpg: string(secret.Data["gpg-keys"]), ssh: sshKeys}
+	if keys.gpg == "" && len(keys.ssh) == 0 {
+		return nil, fmt.Errorf("%w: secret %q contains neither gpg-keys nor ssh-allowed-signers", ErrSignatureVerification, verify.Secret.Name)
+	}
+	return keys, nil
+}
+
+// verify verifies the signature of an object. SSH signatures are verified
+// against the payload that encode writes, and OpenPGP signatures with
+// verifyGPG.
+func (k *signingKeys) verify(signature string, encode func(plumbing.EncodedObject) error, verifyGPG func(armoredKeyRing string) error) error {
+	switch {
+	case signature == "":
+		return errors.New("not signed")
+	case strings.HasPrefix(strings.TrimSpace(signature), "-----BEGIN SSH SIGNATURE-----"):
+		if len(k.ssh) == 0 {
+			return errors.New("signed with an SSH key, but no SSH keys are allowed")
+		}
+		obj := &plumbing.MemoryObject{}
+		if err := encode(obj); err != nil {
+			return err
+		}
+		rd, err := obj.Reader()
+		if err != nil {
+			return err
+		}
+		defer rd.Close()
+		payload, err := io.ReadAll(rd)
+		if err != nil {
+			return err
+		}
+		return verifySSHSignature(signature, payload, k.ssh)
+	default:
+		if k.gpg == "" {
+			return errors.New("signed with a GPG key, but no GPG keys are allowed")
+		}
+		return verifyGPG(k.gpg)
+	}
+}
+
+// verifySSHSignature verifies an armored signature in the format of
+// ssh-keygen -Y sign over payload, and that it was made by one of the
+// allowed keys.
+func verifySSHSignature(armored string, payload []byte, allowed []ssh.PublicKey) error {
+	block, _ := pem.Decode([]byte(armored))
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return errors.New("malformed SSH signature")
+	}
+	var sig struct {
+		Magic         [6]byte
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(block.Bytes, &sig); err != nil {
+		return fmt.Errorf("malformed SSH signature: %v", err)
+	}
+	if string(sig.Magic[:]) != sshSignatureMagic || sig.Version != 1 {
+		return errors.New("malformed SSH signature: unsupported format")
+	}
+	if sig.Namespace != sshSignatureNamespace {
+		return fmt.Errorf("SSH signature is for namespace %q instead of %q", sig.Namespace, sshSignatureNamespace)
+	}
+
+	pubKey, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return fmt.Errorf("parse public key of SSH signature: %v", err)
+	}
+	isAllowed := false
+	for _, key := range allowed {
+		if bytes.Equal(key.Marshal(), pubKey.Marshal()) {
+			isAllowed = true
+			break
+		}
+	}
+	if !isAllowed {
+		return fmt.Errorf("signed with SSH key %s, which is not allowed", ssh.FingerprintSHA256(pubKey))
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported SSH signature hash algorithm %q", sig.HashAlgorithm)
+	}
+	h.Write(payload)
+	signedData := ssh.Marshal(struct {
+		Magic         [6]byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sig.Magic, sig.Namespace, sig.Reserved, sig.HashAlgorithm, h.Sum(nil)})
+
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(sig.Signature, signature); err != nil {
+		return fmt.Errorf("malformed SSH signature: %v", err)
+	}
+	return pubKey.Verify(signedData, signature)
+}
+
+// parseAllowedSigners returns the public keys of data in the allowed signers
+// format of ssh-keygen. Principals and options are ignored, since any of the
+// keys may sign.
+func parseAllowedSigners(data []byte) ([]ssh.PublicKey, error) {
+	var keys []ssh.PublicKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// The key follows the principals and the options, which may
+		// themselves contain spaces, so every suffix of the line is tried.
+		fields := strings.Fields(line)
+		var key ssh.PublicKey
+		for i := range fields {
+			if k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[i:], " "))); err == nil {
+				key = k
+				break
+			}
+		}
+		if key == nil {
+			return nil, fmt.Errorf("line %d: no public key found", lineNum)
+		}
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
@@ -0,0 +1,104 @@
+package source
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestVerifySSHSignature(t *testing.T) {
+	newSigner := func() ssh.Signer {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		signer, err := ssh.NewSignerFromKey(key)
+		require.NoError(t, err)
+		return signer
+	}
+	// sign creates an armored signature like ssh-keygen -Y sign.
+	sign := func(signer ssh.Signer, namespace string, payload []byte) string {
+		var magic [6]byte
+		copy(magic[:], sshSignatureMagic)
+		digest := sha512.Sum512(payload)
+		signedData := ssh.Marshal(struct {
+			Magic         [6]byte
+			Namespace     string
+			Reserved      string
+			HashAlgorithm string
+			Hash          []byte
+		}{magic, namespace, "", "sha512", digest[:]})
+		sig, err := signer.Sign(rand.Reader, signedData)
+		require.NoError(t, err)
+		blob := ssh.Marshal(struct {
+			Magic         [6]byte
+			Version       uint32
+			PublicKey     []byte
+			Namespace     string
+			Reserved      string
+			HashAlgorithm string
+			Signature     []byte
+		}{magic, 1, signer.PublicKey().Marshal(), namespace, "", "sha512", ssh.Marshal(sig)})
+		return string(pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}))
+	}
+
+	signer, other := newSigner(), newSigner()
+	allowed, err := parseAllowedSigners([]byte("# signers\n\ndev@example.com namespaces=\"git\" " + string(ssh.MarshalAuthorizedKey(signer.PublicKey()))))
+	require.NoError(t, err)
+	require.Len(t, allowed, 1)
+
+	payload := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nsigned commit\n")
+	for _, tc := range []struct {
+		name      string
+		signature string
+		payload   []byte
+		expectErr string
+	}{
+		{
+			name:      "allowed key",
+			signature: sign(signer, "git", payload),
+			payload:   payload,
+		},
+		{
+			name:      "key not allowed",
+			signature: sign(other, "git", payload),
+			payload:   payload,
+			expectErr: "not allowed",
+		},
+		{
+			name:      "tampered payload",
+			signature: sign(signer, "git", payload),
+			payload:   append([]byte("parent 0000\n"), payload...),
+			expectErr: "did not verify",
+		},
+		{
+			name:      "other namespace",
+			signature: sign(signer, "file", payload),
+			payload:   payload,
+			expectErr: `namespace "file"`,
+		},
+		{
+			name:      "malformed",
+			signature: "-----BEGIN SSH SIGNATURE-----\nbm90IGEgc2lnbmF0dXJl\n-----END SSH SIGNATURE-----\n",
+			payload:   payload,
+			expectErr: "malformed",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifySSHSignature(tc.signature, tc.payload, allowed)
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestParseAllowedSignersRejectsLinesWithoutKey(t *testing.T) {
+	_, err := parseAllowedSigners([]byte("dev@example.com not-a-key\n"))
+	require.ErrorContains(t, err, "line 1")
+}
@@ -1,24 +1,55 @@
 package source
 
 import (
+	"archive/zip"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/nlepage/go-tarfs"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	// httpAuthTokenKey is the key of the bearer token in an http auth secret.
+	httpAuthTokenKey = "token"
+	// httpAuthHeaderPrefix is the prefix of keys in an http auth secret that
+	// set arbitrary request headers.
+	httpAuthHeaderPrefix = "header."
+
+	// DefaultHTTPMaxSize is the default limit of the size of bundle archives,
+	// both as downloaded and once decompressed.
+	DefaultHTTPMaxSize = 100 << 20
 )
 
 // http is a bundle source that sources bundles from the specified url.
 type HTTP struct {
 	client.Reader
 	SecretNamespace string
+	// CrossNamespaceReader reads auth secrets that are referenced in other
+	// namespaces than SecretNamespace, and the SecretReferenceGrants that allow
+	// them to be referenced. The embedded Reader is used if unset.
+	CrossNamespaceReader client.Reader
+	// MaxSize limits the size of bundle archives, both as downloaded and
+	// once decompressed. DefaultHTTPMaxSize is used if unset.
+	MaxSize int64
+	// TempDir is the directory that bundle archives are downloaded to, and
+	// from which the content of their files is streamed. The default
+	// directory for temporary files is used if unset.
+	TempDir string
 }
 
 // Unpack unpacks a bundle by requesting the bundle contents from a specified URL
@@ -29,64 +60,279 @@ func (b *HTTP) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployme
 
 	url := bundle.Spec.Source.HTTP.URL
 	action := fmt.Sprintf("%s %s", http.MethodGet, url)
+	maxSize := b.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultHTTPMaxSize
+	}
+
+	var archive *os.File
+	if err := fetchWithRetry(ctx, bundle.Spec.Source.HTTP.Retry, func(ctx context.Context) error {
+		var err error
+		archive, err = b.fetch(ctx, bundle, action, maxSize)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	bundleFS, err := archiveToFS(archive, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("error creating FS: %s", err)
+	}
+
+	message := generateMessage("http")
+
+	return &Result{Bundle: bundleFS, Closer: bundleFS, ResolvedSource: bundle.Spec.Source.DeepCopy(), State: StateUnpacked, Message: message}, nil
+}
+
+func (b *HTTP) Cleanup(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) error {
+	return nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// fetch downloads the bundle archive of the bundle to a temporary file. Errors that are likely to
+// resolve themselves, such as network failures and server errors, are
+// transient, while errors such as a missing archive or an untrusted server
+// certificate are unrecoverable.
+func (b *HTTP) fetch(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, action string, maxSize int64) (*os.File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bundle.Spec.Source.HTTP.URL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("create http request %q for bundle content: %v", action, err)
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("create http request %q for bundle content: %v", action, err))
 	}
-	var userName, password string
+	var authHeaders []string
 	if bundle.Spec.Source.HTTP.Auth.Secret.Name != "" {
-		userName, password, err = b.getCredentials(ctx, bundle)
+		authHeaders, err = b.configAuth(ctx, bundle, req)
 		if err != nil {
 			return nil, err
 		}
-		req.SetBasicAuth(userName, password)
 	}
 
-	httpClient := http.Client{Timeout: 10 * time.Second}
-	if bundle.Spec.Source.HTTP.Auth.InsecureSkipVerify {
-		tr := http.DefaultTransport.(*http.Transport).Clone()
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // nolint:gosec
-		httpClient.Transport = tr
+	transport, err := httpTransport(bundle.Spec.Source.HTTP)
+	if err != nil {
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("%s: %v", action, err))
 	}
+	httpClient := http.Client{Timeout: 10 * time.Second, Transport: transport, CheckRedirect: stripAuthHeadersOnRedirect(authHeaders)}
 
 	resp, err := httpClient.Do(req)
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("%s: http request for bundle content failed: %v", action, err))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%s: http request for bundle content failed: %v", action, err)
+		return nil, rukpakerrors.NewTransient(fmt.Errorf("%s: http request for bundle content failed: %v", action, err))
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: unexpected status %q", action, resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+		return nil, rukpakerrors.NewTransient(fmt.Errorf("%s: unexpected status %q", action, resp.Status))
+	default:
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("%s: unexpected status %q", action, resp.Status))
 	}
 
-	tarReader, err := gzip.NewReader(resp.Body)
+	if resp.ContentLength > maxSize {
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("%s: bundle archive size %d exceeds the limit of %d bytes", action, resp.ContentLength, maxSize))
+	}
+	archive, err := util.SpoolFile(b.TempDir, &limitedReader{r: resp.Body, n: maxSize})
+	if errors.Is(err, errSizeLimitExceeded) {
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("%s: read bundle archive: %v", action, err))
+	}
 	if err != nil {
+		return nil, rukpakerrors.NewTransient(fmt.Errorf("%s: read bundle archive: %v", action, err))
+	}
+	return archive, nil
+}
+
+// httpTransport returns the transport of the requests for the content of src.
+// It trusts the certificate authorities of src in addition to the system ones.
+// A nil transport is returned when the default one suffices.
+func httpTransport(src *rukpakv1alpha2.HTTPSource) (http.RoundTripper, error) {
+	if !src.Auth.InsecureSkipVerify && src.CertificateData == "" && src.Proxy == nil {
+		return nil, nil
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = proxyFunc(src.Proxy)
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: src.Auth.InsecureSkipVerify, MinVersion: tls.VersionTLS12} // nolint:gosec
+	if src.CertificateData != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(src.CertificateData)) {
+			return nil, errors.New("certificateData does not contain any PEM encoded certificate")
+		}
+		tr.TLSClientConfig.RootCAs = pool
+	}
+	return tr, nil
+}
+
+// archiveToFS detects the format of the archive in file by its magic bytes,
+// and returns a filesystem that streams the content of its files from file.
+// Gzipped tar, plain tar and zip archives are supported. The decompressed
+// content of the archive is limited to maxSize bytes. Closing the filesystem
+// closes file, and file is closed if an error is returned.
+func archiveToFS(file *os.File, maxSize int64) (util.ClosableFS, error) {
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+	n, err := file.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		file.Close()
 		return nil, err
 	}
-	fs, err := tarfs.New(tarReader)
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return util.TarFileFS(file, func(r io.Reader) (io.ReadCloser, error) {
+			gzr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return &readCloser{Reader: &limitedReader{r: gzr, n: maxSize}, Closer: gzr}, nil
+		})
+	case bytes.HasPrefix(header, zipMagic):
+		if err := checkZipSize(file, maxSize); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return util.ZipFileFS(file)
+	case len(header) == tarMagicOffset+len(tarMagic) && bytes.Equal(header[tarMagicOffset:], tarMagic):
+		return util.TarFileFS(file, nil)
+	}
+	file.Close()
+	return nil, errors.New("unsupported archive format: expected a gzipped tar, tar or zip archive")
+}
+
+// checkZipSize verifies that the files of the zip archive in file add up to
+// at most maxSize bytes once decompressed.
+func checkZipSize(file *os.File, maxSize int64) error {
+	info, err := file.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("error creating FS: %s", err)
+		return err
+	}
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return fmt.Errorf("read zip archive: %v", err)
 	}
+	var size uint64
+	for _, f := range zr.File {
+		size += f.UncompressedSize64
+	}
+	if size > uint64(maxSize) {
+		return fmt.Errorf("decompressed zip archive size %d exceeds the limit of %d bytes", size, maxSize)
+	}
+	return nil
+}
 
-	message := generateMessage("http")
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	// tarMagic is the prefix of the magic field of the ustar header, shared
+	// by the POSIX and GNU formats.
+	tarMagic = []byte("ustar")
+)
 
-	return &Result{Bundle: fs, ResolvedSource: bundle.Spec.Source.DeepCopy(), State: StateUnpacked, Message: message}, nil
+const tarMagicOffset = 257
+
+// limitedReader reads from r, failing once more than n bytes have been read.
+// Unlike io.LimitedReader, exceeding the limit is an error rather than EOF.
+// The error is returned by every read after it, since readers such as
+// io.CopyN drop errors of reads that complete what they asked for.
+type limitedReader struct {
+	r io.Reader
+	n int64
 }
 
-func (b *HTTP) Cleanup(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) error {
-	return nil
+var errSizeLimitExceeded = errors.New("content exceeds the size limit")
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errSizeLimitExceeded
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errSizeLimitExceeded
+	}
+	return n, err
 }
 
-// getCredentials reads credentials from the secret specified in the bundle
-// It returns the username ane password when they are in the secret
-func (b *HTTP) getCredentials(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (string, string, error) {
+// configAuth adds the credentials from the secret specified in the bundle to
+// req. The authorization scheme is chosen based on the keys in the secret:
+//
+//   - A "token" key sets a bearer token in the Authorization header.
+//   - "username" and "password" keys set basic authentication credentials.
+//   - Keys with the "header." prefix set the header named by the rest of the
+//     key, e.g. "header.X-Api-Key", to their value. Headers can be combined
+//     with either of the schemes above.
+//
+// It returns the names of the headers that it set.
+func (b *HTTP) configAuth(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, req *http.Request) ([]string, error) {
+	secretName := bundle.Spec.Source.HTTP.Auth.Secret.Name
 	secret := &corev1.Secret{}
-	err := b.Get(ctx, client.ObjectKey{Namespace: b.SecretNamespace, Name: bundle.Spec.Source.HTTP.Auth.Secret.Name}, secret)
+	if err := getAuthSecret(ctx, b.Reader, b.CrossNamespaceReader, b.SecretNamespace, bundle, bundle.Spec.Source.HTTP.Auth, secret); err != nil {
+		return nil, fmt.Errorf("get http auth secret %q: %v", secretName, err)
+	}
+	headers, err := setHTTPAuth(req, secret.Data)
 	if err != nil {
-		return "", "", err
+		return nil, fmt.Errorf("http auth secret %q: %v", secretName, err)
+	}
+	return headers, nil
+}
+
+func setHTTPAuth(req *http.Request, data map[string][]byte) ([]string, error) {
+	var headers []string
+	for key, value := range data {
+		name, ok := strings.CutPrefix(key, httpAuthHeaderPrefix)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("key %q does not specify a header name", key)
+		}
+		req.Header.Set(name, string(value))
+		headers = append(headers, name)
 	}
-	userName := string(secret.Data["username"])
-	password := string(secret.Data["password"])
 
-	return userName, password, nil
+	token, hasToken := data[httpAuthTokenKey]
+	username, hasUsername := data[corev1.BasicAuthUsernameKey]
+	password, hasPassword := data[corev1.BasicAuthPasswordKey]
+	switch {
+	case hasToken && (hasUsername || hasPassword):
+		return nil, fmt.Errorf("only one of %q or %q and %q may be set", httpAuthTokenKey, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+	case hasToken:
+		if len(token) == 0 {
+			return nil, fmt.Errorf("key %q is empty", httpAuthTokenKey)
+		}
+		req.Header.Set("Authorization", "Bearer "+string(token))
+		headers = append(headers, "Authorization")
+	case hasUsername || hasPassword:
+		if !hasUsername || !hasPassword {
+			return nil, fmt.Errorf("both %q and %q must be set for basic authentication", corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+		}
+		req.SetBasicAuth(string(username), string(password))
+		headers = append(headers, "Authorization")
+	case len(headers) == 0:
+		return nil, fmt.Errorf("no credentials found: expected %q, %q and %q, or %q-prefixed keys", httpAuthTokenKey, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey, httpAuthHeaderPrefix)
+	}
+	return headers, nil
+}
+
+// stripAuthHeadersOnRedirect returns a redirect policy that drops the given
+// headers when a request is redirected to another host. The http client
+// forwards headers other than Authorization to any host, and Authorization
+// to other ports of the same host.
+func stripAuthHeadersOnRedirect(headers []string) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		// Match the default redirect policy of the http client.
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if req.URL.Host != via[0].URL.Host {
+			for _, name := range headers {
+				req.Header.Del(name)
+			}
+		}
+		return nil
+	}
 }
@@ -1,17 +1,20 @@
 package source
 
 import (
-	"bytes"
+	"bufio"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/nlepage/go-tarfs"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -31,9 +34,26 @@ type Image struct {
 	KubeClient   kubernetes.Interface
 	PodNamespace string
 	UnpackImage  string
+	// FailedPodRetention is the number of failed unpack pods that are kept
+	// per bundle for debugging. Older failed pods are deleted. If it is zero,
+	// failed pods are deleted right away.
+	FailedPodRetention int
+	// TempDir is the directory that the bundle content that unpack pods
+	// emit is written to, and from which the content of its files is
+	// streamed. The default directory for temporary files is used if unset.
+	TempDir string
 }
 
-const imageBundleUnpackContainerName = "bundle"
+const (
+	imageBundleUnpackContainerName = "bundle"
+
+	// unpackAttemptLabel holds the number of the unpack attempt of a pod.
+	// When failed pods are retained, every attempt creates a pod of its own.
+	unpackAttemptLabel = "core.rukpak.io/unpack-attempt"
+	// failedPodRetainedLabel marks failed unpack pods that are retained for
+	// debugging and no longer belong to the current unpack attempt.
+	failedPodRetainedLabel = "core.rukpak.io/failed-unpack-pod"
+)
 
 func (i *Image) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (*Result, error) {
 	if bundle.Spec.Source.Type != rukpakv1alpha2.SourceTypeImage {
@@ -43,8 +63,12 @@ func (i *Image) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeploym
 		return nil, fmt.Errorf("bundle source image configuration is unset")
 	}
 
+	podName, attempt, err := i.unpackPodName(ctx, bundle)
+	if err != nil {
+		return nil, err
+	}
 	pod := &corev1.Pod{}
-	op, err := i.ensureUnpackPod(ctx, bundle, pod)
+	op, err := i.ensureUnpackPod(ctx, bundle, podName, attempt, pod)
 	if err != nil {
 		return nil, err
 	} else if op == controllerutil.OperationResultCreated || op == controllerutil.OperationResultUpdated || pod.DeletionTimestamp != nil {
@@ -57,7 +81,7 @@ func (i *Image) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeploym
 	case corev1.PodRunning:
 		return &Result{State: StateUnpacking}, nil
 	case corev1.PodFailed:
-		return nil, i.failedPodResult(ctx, pod)
+		return nil, i.failedPodResult(ctx, bundle, pod)
 	case corev1.PodSucceeded:
 		return i.succeededPodResult(ctx, pod)
 	default:
@@ -65,13 +89,58 @@ func (i *Image) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeploym
 	}
 }
 
-func (i *Image) ensureUnpackPod(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, pod *corev1.Pod) (controllerutil.OperationResult, error) {
-	existingPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: i.PodNamespace, Name: bundle.Name}}
+// unpackPodName returns the name and the attempt number of the unpack pod of
+// bundle. Unless failed pods are retained, there is a single unpack pod that
+// is named after the bundle. Otherwise, every attempt gets a pod of its own,
+// and the attempt after the last retained failed pod is the current one.
+func (i *Image) unpackPodName(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (string, int, error) {
+	if i.FailedPodRetention <= 0 {
+		return bundle.Name, 0, nil
+	}
+	pods, err := i.listUnpackPods(ctx, bundle)
+	if err != nil {
+		return "", 0, err
+	}
+	next := 0
+	for _, pod := range pods {
+		if _, retained := pod.Labels[failedPodRetainedLabel]; !retained {
+			return pod.Name, unpackAttempt(&pod), nil
+		}
+		if attempt := unpackAttempt(&pod); attempt >= next {
+			next = attempt + 1
+		}
+	}
+	if next == 0 {
+		return bundle.Name, 0, nil
+	}
+	return fmt.Sprintf("%s-%d", bundle.Name, next), next, nil
+}
+
+func (i *Image) listUnpackPods(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := i.Client.List(ctx, pods, client.InNamespace(i.PodNamespace), client.MatchingLabels{
+		util.CoreOwnerKindKey: bundle.Kind,
+		util.CoreOwnerNameKey: bundle.Name,
+	}); err != nil {
+		return nil, fmt.Errorf("list unpack pods: %v", err)
+	}
+	return pods.Items, nil
+}
+
+// unpackAttempt returns the attempt number of an unpack pod. Pods without
+// the label were created for the first attempt.
+func unpackAttempt(pod *corev1.Pod) int {
+	attempt, _ := strconv.Atoi(pod.Labels[unpackAttemptLabel])
+	return attempt
+}
+
+func (i *Image) ensureUnpackPod(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, podName string, attempt int, pod *corev1.Pod) (controllerutil.OperationResult, error) {
+	existingPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: i.PodNamespace, Name: podName}}
 	if err := i.Client.Get(ctx, client.ObjectKeyFromObject(existingPod), existingPod); client.IgnoreNotFound(err) != nil {
 		return controllerutil.OperationResultNone, err
 	}
 
-	podApplyConfig := i.getDesiredPodApplyConfig(bundle)
+	podApplyConfig := i.getDesiredPodApplyConfig(bundle, podName, attempt)
 	updatedPod, err := i.KubeClient.CoreV1().Pods(i.PodNamespace).Apply(ctx, podApplyConfig, metav1.ApplyOptions{Force: true, FieldManager: "rukpak-core"})
 	if err != nil {
 		if !apierrors.IsInvalid(err) {
@@ -100,7 +169,7 @@ func (i *Image) ensureUnpackPod(ctx context.Context, bundle *rukpakv1alpha2.Bund
 	return controllerutil.OperationResultUpdated, nil
 }
 
-func (i *Image) getDesiredPodApplyConfig(bundle *rukpakv1alpha2.BundleDeployment) *applyconfigurationcorev1.PodApplyConfiguration {
+func (i *Image) getDesiredPodApplyConfig(bundle *rukpakv1alpha2.BundleDeployment, podName string, attempt int) *applyconfigurationcorev1.PodApplyConfiguration {
 	// TODO (tyslaton): Address unpacker pod allowing root users for image sources
 	//
 	// In our current implementation, we are creating a pod that uses the image
@@ -125,11 +194,15 @@ func (i *Image) getDesiredPodApplyConfig(bundle *rukpakv1alpha2.BundleDeployment
 			WithDrop("ALL"),
 		)
 
-	podApply := applyconfigurationcorev1.Pod(bundle.Name, i.PodNamespace).
-		WithLabels(map[string]string{
-			util.CoreOwnerKindKey: bundle.Kind,
-			util.CoreOwnerNameKey: bundle.Name,
-		}).
+	labels := map[string]string{
+		util.CoreOwnerKindKey: bundle.Kind,
+		util.CoreOwnerNameKey: bundle.Name,
+	}
+	if attempt > 0 {
+		labels[unpackAttemptLabel] = strconv.Itoa(attempt)
+	}
+	podApply := applyconfigurationcorev1.Pod(podName, i.PodNamespace).
+		WithLabels(labels).
 		WithOwnerReferences(v1.OwnerReference().
 			WithName(bundle.Name).
 			WithKind(bundle.Kind).
@@ -170,6 +243,7 @@ func (i *Image) getDesiredPodApplyConfig(bundle *rukpakv1alpha2.BundleDeployment
 					return volumeMounts
 				}()...).
 				WithEnv(applyconfigurationcorev1.EnvVar().WithName("GOCOVERDIR").WithValue(gocoverdirEnv)).
+				WithEnv(proxyEnv(bundle.Spec.Source.Image.Proxy)...).
 				WithSecurityContext(containerSecurityContext.WithRunAsUser(1001)).
 				WithTerminationMessagePolicy(corev1.TerminationMessageFallbackToLogsOnError),
 			).
@@ -209,13 +283,49 @@ func unsetNonComparedPodFields(pods ...*corev1.Pod) {
 	}
 }
 
-func (i *Image) failedPodResult(ctx context.Context, pod *corev1.Pod) error {
-	logs, err := i.getPodLogs(ctx, pod)
-	if err != nil {
-		return fmt.Errorf("unpack failed: failed to retrieve failed pod logs: %v", err)
+// unpackPodLogTailLines is the number of log lines of a failed unpack pod
+// that are included in the unpack error.
+const unpackPodLogTailLines = 20
+
+// failedPodResult returns the error of a failed unpack pod. It carries the
+// termination state of the failed containers and the last lines of their
+// logs, so that failures can be diagnosed without access to the namespace
+// of the pod. The pod is deleted, or retained if failed pods are retained.
+func (i *Image) failedPodResult(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, pod *corev1.Pod) error {
+	var diagnostics []string
+	for _, cStatus := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		terminated := cStatus.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+		diagnostic := fmt.Sprintf("container %q terminated with exit code %d", cStatus.Name, terminated.ExitCode)
+		if terminated.Reason != "" {
+			diagnostic += fmt.Sprintf(" (%s)", terminated.Reason)
+		}
+		// With the FallbackToLogsOnError termination message policy, the
+		// message already holds the end of the logs if the container did not
+		// write a termination message itself.
+		logs := strings.TrimSpace(terminated.Message)
+		if logs == "" {
+			tail, err := i.getContainerLogTail(ctx, pod, cStatus.Name)
+			if err != nil {
+				logs = fmt.Sprintf("failed to retrieve logs: %v", err)
+			} else {
+				logs = strings.TrimSpace(string(tail))
+			}
+		}
+		if logs != "" {
+			diagnostic += ": " + logs
+		}
+		diagnostics = append(diagnostics, diagnostic)
 	}
-	_ = i.Client.Delete(ctx, pod)
-	return fmt.Errorf("unpack failed: %v", string(logs))
+	if err := i.releaseFailedPod(ctx, bundle, pod); err != nil {
+		return fmt.Errorf("unpack failed: release failed pod: %v", err)
+	}
+	if len(diagnostics) == 0 {
+		return fmt.Errorf("unpack failed: pod failed: %s", pod.Status.Message)
+	}
+	return fmt.Errorf("unpack failed: %s", strings.Join(diagnostics, "; "))
 }
 
 func (i *Image) succeededPodResult(ctx context.Context, pod *corev1.Pod) (*Result, error) {
@@ -236,27 +346,111 @@ func (i *Image) succeededPodResult(ctx context.Context, pod *corev1.Pod) (*Resul
 
 	message := generateMessage("image")
 
-	return &Result{Bundle: bundleFS, ResolvedSource: resolvedSource, State: StateUnpacked, Message: message}, nil
+	return &Result{Bundle: bundleFS, Closer: bundleFS, ResolvedSource: resolvedSource, State: StateUnpacked, Message: message}, nil
 }
 
-func (i *Image) getBundleContents(ctx context.Context, pod *corev1.Pod) (fs.FS, error) {
-	bundleData, err := i.getPodLogs(ctx, pod)
+func (i *Image) getBundleContents(ctx context.Context, pod *corev1.Pod) (util.ClosableFS, error) {
+	logReader, err := i.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).Stream(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("get bundle contents: %v", err)
+		return nil, fmt.Errorf("get bundle contents: get pod logs: %v", err)
+	}
+	defer logReader.Close()
+	return parseBundleContents(logReader, i.TempDir)
+}
+
+// parseBundleContents reads the bundle contents from the output of the
+// unpack container. The SHA-256 digest of the content, which the container
+// emits along with it, is verified, so that content that was truncated or
+// altered on its way from the pod is never stored. Output of unpack
+// containers of older versions, which emit no digest, is not verified.
+//
+// The content is decoded as it is read and written to a temporary file in
+// dir, from which the content of files is streamed, rather than held in
+// memory. This relies on the unpack container emitting the content first,
+// which encoding/json does since it sorts the keys of maps.
+func parseBundleContents(r io.Reader, dir string) (util.ClosableFS, error) {
+	br := bufio.NewReader(r)
+	prefix := make([]byte, len(bundleContentPrefix))
+	if _, err := io.ReadFull(br, prefix); err != nil || string(prefix) != bundleContentPrefix {
+		return nil, fmt.Errorf("parse bundle data: expected the output to start with %s", bundleContentPrefix)
+	}
+	hash := sha256.New()
+	content, err := util.SpoolFile(dir, io.TeeReader(base64.NewDecoder(base64.StdEncoding, &jsonStringReader{r: br}), hash))
+	if err != nil {
+		return nil, fmt.Errorf("parse bundle data: %v", err)
+	}
+
+	// The rest of the output holds the digest, and is parsed as an object
+	// whose content has been read already.
+	rest, err := io.ReadAll(io.LimitReader(br, maxBundleDataSuffixSize))
+	if err != nil {
+		content.Close()
+		return nil, fmt.Errorf("parse bundle data: %v", err)
 	}
 	bd := struct {
-		Content []byte `json:"content"`
+		ContentDigest string `json:"contentDigest"`
 	}{}
-
-	if err := json.Unmarshal(bundleData, &bd); err != nil {
+	if err := json.Unmarshal(append([]byte(`{"content":null`), rest...), &bd); err != nil {
+		content.Close()
 		return nil, fmt.Errorf("parse bundle data: %v", err)
 	}
+	if bd.ContentDigest != "" {
+		if digest := fmt.Sprintf("sha256:%x", hash.Sum(nil)); digest != bd.ContentDigest {
+			content.Close()
+			return nil, fmt.Errorf("bundle content digest %s does not match the digest %s emitted by the unpack container", digest, bd.ContentDigest)
+		}
+	}
 
-	gzr, err := gzip.NewReader(bytes.NewReader(bd.Content))
+	bundleFS, err := util.TarFileFS(content, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("read bundle content gzip: %v", err)
+		return nil, fmt.Errorf("read bundle content: %v", err)
 	}
-	return tarfs.New(gzr)
+	return bundleFS, nil
+}
+
+const (
+	// bundleContentPrefix is the start of the output of the unpack container,
+	// up to the base64 encoded content.
+	bundleContentPrefix = `{"content":"`
+	// maxBundleDataSuffixSize limits the size of the output of the unpack
+	// container that follows the content.
+	maxBundleDataSuffixSize = 4 << 10
+)
+
+// jsonStringReader reads the rest of a JSON string from r, up to its closing
+// quote. The string must not contain escape sequences, like base64 encoded
+// data.
+type jsonStringReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) {
+		b, err := s.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return n, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return n, err
+		}
+		if b == '"' {
+			s.done = true
+			return n, nil
+		}
+		if b == '\\' {
+			return n, errors.New("unexpected escape sequence in base64 encoded content")
+		}
+		p[n] = b
+		n++
+	}
+	return n, nil
 }
 
 func (i *Image) getBundleImageDigest(pod *corev1.Pod) (string, error) {
@@ -268,17 +462,49 @@ func (i *Image) getBundleImageDigest(pod *corev1.Pod) (string, error) {
 	return "", fmt.Errorf("bundle image digest not found")
 }
 
-func (i *Image) getPodLogs(ctx context.Context, pod *corev1.Pod) ([]byte, error) {
-	logReader, err := i.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).Stream(ctx)
+// releaseFailedPod frees the way for the next unpack attempt after pod
+// failed. Unless failed pods are retained, the pod is deleted. Otherwise, it
+// is marked as retained, and the oldest retained pods of the bundle beyond
+// the retention limit are deleted.
+func (i *Image) releaseFailedPod(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, pod *corev1.Pod) error {
+	if i.FailedPodRetention <= 0 {
+		_ = i.Client.Delete(ctx, pod)
+		return nil
+	}
+	if _, retained := pod.Labels[failedPodRetainedLabel]; !retained {
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[failedPodRetainedLabel] = "true"
+		if err := i.Client.Patch(ctx, pod, patch); err != nil {
+			return err
+		}
+	}
+
+	pods, err := i.listUnpackPods(ctx, bundle)
 	if err != nil {
-		return nil, fmt.Errorf("get pod logs: %v", err)
+		return err
 	}
-	defer logReader.Close()
-	buf := &bytes.Buffer{}
-	if _, err := io.Copy(buf, logReader); err != nil {
-		return nil, err
+	var retained []corev1.Pod
+	for _, p := range pods {
+		if _, ok := p.Labels[failedPodRetainedLabel]; ok {
+			retained = append(retained, p)
+		}
 	}
-	return buf.Bytes(), nil
+	sort.Slice(retained, func(a, b int) bool { return unpackAttempt(&retained[a]) > unpackAttempt(&retained[b]) })
+	for j := i.FailedPodRetention; j < len(retained); j++ {
+		if err := i.Client.Delete(ctx, &retained[j]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getContainerLogTail returns the last lines of the logs of a container of pod.
+func (i *Image) getContainerLogTail(ctx context.Context, pod *corev1.Pod, container string) ([]byte, error) {
+	tailLines := int64(unpackPodLogTailLines)
+	return i.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: container, TailLines: &tailLines}).DoRaw(ctx)
 }
 
 func (i *Image) handleUnexpectedPod(ctx context.Context, pod *corev1.Pod) error {
@@ -286,12 +512,14 @@ func (i *Image) handleUnexpectedPod(ctx context.Context, pod *corev1.Pod) error
 	return fmt.Errorf("unexpected pod phase: %v", pod.Status.Phase)
 }
 
+// pendingImagePodResult returns the result of a pending unpack pod, whose
+// message names the containers that wait for their image to be pulled.
 func pendingImagePodResult(pod *corev1.Pod) *Result {
 	var messages []string
 	for _, cStatus := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
 		if waiting := cStatus.State.Waiting; waiting != nil {
 			if waiting.Reason == "ErrImagePull" || waiting.Reason == "ImagePullBackOff" {
-				messages = append(messages, waiting.Message)
+				messages = append(messages, fmt.Sprintf("container %q: %s: %s", cStatus.Name, waiting.Reason, waiting.Message))
 			}
 		}
 	}
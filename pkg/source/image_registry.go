@@ -2,14 +2,18 @@ package source
 
 import (
 	"archive/tar"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -17,12 +21,15 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	gcrkube "github.com/google/go-containerregistry/pkg/authn/kubernetes"
 	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	apimacherrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/internal/nodeimage"
 	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
+	"github.com/operator-framework/rukpak/pkg/util"
 )
 
 // TODO: Make asynchronous
@@ -30,6 +37,9 @@ import (
 type ImageRegistry struct {
 	BaseCachePath string
 	AuthNamespace string
+	// NodeImageURL is the URL of the node image server that node-local
+	// images are read from. Node-local images are not supported if unset.
+	NodeImageURL string
 }
 
 func (i *ImageRegistry) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (*Result, error) {
@@ -47,54 +57,30 @@ func (i *ImageRegistry) Unpack(ctx context.Context, bundle *rukpakv1alpha2.Bundl
 		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("error parsing image reference: %w", err))
 	}
 
-	remoteOpts := []remote.Option{}
-	if bundle.Spec.Source.Image.ImagePullSecretName != "" {
-		chainOpts := k8schain.Options{
-			ImagePullSecrets: []string{bundle.Spec.Source.Image.ImagePullSecretName},
-			Namespace:        i.AuthNamespace,
-			// TODO: Do we want to use any secrets that are included in the rukpak service account?
-			// If so, we will need to add the permission to get service accounts and specify
-			// the rukpak service account name here.
-			ServiceAccountName: gcrkube.NoServiceAccount,
-		}
-		authChain, err := k8schain.NewInCluster(ctx, chainOpts)
-		if err != nil {
-			return nil, fmt.Errorf("error getting auth keychain: %w", err)
-		}
-
-		remoteOpts = append(remoteOpts, remote.WithAuthFromKeychain(authChain))
+	remoteOpts, err := imageRemoteOptions(ctx, bundle.Spec.Source.Image, i.AuthNamespace)
+	if err != nil {
+		return nil, err
 	}
 
-	transport := remote.DefaultTransport.(*http.Transport).Clone()
-	if transport.TLSClientConfig == nil {
-		transport.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: false,
-			MinVersion:         tls.VersionTLS12,
-		} // nolint:gosec
-	}
-	if bundle.Spec.Source.Image.InsecureSkipTLSVerify {
-		transport.TLSClientConfig.InsecureSkipVerify = true // nolint:gosec
-	}
-	if bundle.Spec.Source.Image.CertificateData != "" {
-		pool, err := x509.SystemCertPool()
-		if err != nil || pool == nil {
-			pool = x509.NewCertPool()
-		}
-		transport.TLSClientConfig.RootCAs = pool
-		transport.TLSClientConfig.RootCAs.AppendCertsFromPEM([]byte(bundle.Spec.Source.Image.CertificateData))
+	filter, cacheSuffix, err := imagePathFilter(bundle.Spec.Source.Image.PathFilters)
+	if err != nil {
+		return nil, rukpakerrors.NewUnrecoverable(fmt.Errorf("error parsing path filters: %w", err))
 	}
-	remoteOpts = append(remoteOpts, remote.WithTransport(transport))
 
 	digest, isDigest := imgRef.(name.Digest)
 	if isDigest {
 		hexVal := strings.TrimPrefix(digest.DigestStr(), "sha256:")
-		unpackPath := filepath.Join(i.BaseCachePath, bundle.Name, hexVal)
+		unpackPath := filepath.Join(i.BaseCachePath, bundle.Name, hexVal+cacheSuffix)
 		if stat, err := os.Stat(unpackPath); err == nil && stat.IsDir() {
 			l.V(1).Info("found image in filesystem cache", "digest", hexVal)
 			return unpackedResult(os.DirFS(unpackPath), bundle, digest.String()), nil
 		}
 	}
 
+	if bundle.Spec.Source.Image.NodeLocal {
+		return i.unpackNodeImage(ctx, bundle, imgRef, filter, cacheSuffix)
+	}
+
 	// always fetch the hash
 	imgDesc, err := remote.Head(imgRef, remoteOpts...)
 	if err != nil {
@@ -102,7 +88,7 @@ func (i *ImageRegistry) Unpack(ctx context.Context, bundle *rukpakv1alpha2.Bundl
 	}
 	l.V(1).Info("resolved image descriptor", "digest", imgDesc.Digest.String())
 
-	unpackPath := filepath.Join(i.BaseCachePath, bundle.Name, imgDesc.Digest.Hex)
+	unpackPath := filepath.Join(i.BaseCachePath, bundle.Name, imgDesc.Digest.Hex+cacheSuffix)
 	if _, err = os.Stat(unpackPath); errors.Is(err, os.ErrNotExist) { //nolint: nestif
 		// Ensure any previous unpacked bundle is cleaned up before unpacking the new catalog.
 		if err := i.Cleanup(ctx, bundle); err != nil {
@@ -113,7 +99,7 @@ func (i *ImageRegistry) Unpack(ctx context.Context, bundle *rukpakv1alpha2.Bundl
 			return nil, fmt.Errorf("error creating unpack path: %w", err)
 		}
 
-		if err = unpackImage(ctx, imgRef, unpackPath, remoteOpts...); err != nil {
+		if err = unpackImage(ctx, imgRef, unpackPath, filter, remoteOpts...); err != nil {
 			cleanupErr := os.RemoveAll(unpackPath)
 			if cleanupErr != nil {
 				err = apimacherrors.NewAggregate(
@@ -130,7 +116,90 @@ func (i *ImageRegistry) Unpack(ctx context.Context, bundle *rukpakv1alpha2.Bundl
 	}
 
 	resolvedRef := fmt.Sprintf("%s@sha256:%s", imgRef.Context().Name(), imgDesc.Digest.Hex)
-	return unpackedResult(os.DirFS(unpackPath), bundle, resolvedRef), nil
+	return unpackedResult(util.DirFS(unpackPath), bundle, resolvedRef), nil
+}
+
+// imagePathFilter returns the filter that image layers are extracted with,
+// or nil if filters is empty, along with the suffix of the cache directory of
+// content extracted with that filter. The suffix keeps content extracted with
+// different filters apart, since it may be missing files the other needs.
+func imagePathFilter(filters rukpakv1alpha2.PathFilters) (*util.PathFilter, string, error) {
+	filter, err := newPathFilter(filters)
+	if filter == nil || err != nil {
+		return nil, "", err
+	}
+	hash, err := util.DeepHashObject(filters)
+	if err != nil {
+		return nil, "", err
+	}
+	return filter, "-" + hash, nil
+}
+
+// imageRemoteOptions returns the options to access the registry of src with,
+// which abort requests once ctx is canceled. Pull secrets are read from
+// authNamespace.
+func imageRemoteOptions(ctx context.Context, src *rukpakv1alpha2.ImageSource, authNamespace string) ([]remote.Option, error) {
+	remoteOpts := []remote.Option{remote.WithContext(ctx)}
+	if src.ImagePullSecretName != "" {
+		chainOpts := k8schain.Options{
+			ImagePullSecrets: []string{src.ImagePullSecretName},
+			Namespace:        authNamespace,
+			// TODO: Do we want to use any secrets that are included in the rukpak service account?
+			// If so, we will need to add the permission to get service accounts and specify
+			// the rukpak service account name here.
+			ServiceAccountName: gcrkube.NoServiceAccount,
+		}
+		authChain, err := k8schain.NewInCluster(ctx, chainOpts)
+		if err != nil {
+			return nil, fmt.Errorf("error getting auth keychain: %w", err)
+		}
+
+		remoteOpts = append(remoteOpts, remote.WithAuthFromKeychain(authChain))
+	}
+
+	transport := remote.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(src.Proxy)
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: false,
+			MinVersion:         tls.VersionTLS12,
+		} // nolint:gosec
+	}
+	if src.InsecureSkipTLSVerify {
+		transport.TLSClientConfig.InsecureSkipVerify = true // nolint:gosec
+	}
+	if src.CertificateData != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		transport.TLSClientConfig.RootCAs = pool
+		transport.TLSClientConfig.RootCAs.AppendCertsFromPEM([]byte(src.CertificateData))
+	}
+	remoteOpts = append(remoteOpts, remote.WithTransport(transport))
+	return remoteOpts, nil
+}
+
+// ResolveImageDigest returns the digest reference of the image that the tag
+// of src currently points to. References that already name a digest are
+// returned as is. Pull secrets are read from authNamespace.
+func ResolveImageDigest(ctx context.Context, src *rukpakv1alpha2.ImageSource, authNamespace string) (name.Digest, error) {
+	imgRef, err := name.ParseReference(src.Ref)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("error parsing image reference: %w", err)
+	}
+	if digest, ok := imgRef.(name.Digest); ok {
+		return digest, nil
+	}
+	remoteOpts, err := imageRemoteOptions(ctx, src, authNamespace)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	imgDesc, err := remote.Head(imgRef, remoteOpts...)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("error fetching image descriptor: %w", err)
+	}
+	return imgRef.Context().Digest(imgDesc.Digest.String()), nil
 }
 
 func wrapUnrecoverable(err error, isUnrecoverable bool) error {
@@ -154,6 +223,8 @@ func unpackedResult(fsys fs.FS, bundle *rukpakv1alpha2.BundleDeployment, ref str
 				ImagePullSecretName:   bundle.Spec.Source.Image.ImagePullSecretName,
 				InsecureSkipTLSVerify: bundle.Spec.Source.Image.InsecureSkipTLSVerify,
 				CertificateData:       bundle.Spec.Source.Image.CertificateData,
+				NodeLocal:             bundle.Spec.Source.Image.NodeLocal,
+				PathFilters:           bundle.Spec.Source.Image.PathFilters,
 			},
 		},
 		State: StateUnpacked,
@@ -161,8 +232,9 @@ func unpackedResult(fsys fs.FS, bundle *rukpakv1alpha2.BundleDeployment, ref str
 }
 
 // unpackImage unpacks a bundle image reference to the provided unpackPath,
-// returning an error if any errors are encountered along the way.
-func unpackImage(ctx context.Context, imgRef name.Reference, unpackPath string, remoteOpts ...remote.Option) error {
+// returning an error if any errors are encountered along the way. If filter
+// is not nil, only the layer entries visible through it are extracted.
+func unpackImage(ctx context.Context, imgRef name.Reference, unpackPath string, filter *util.PathFilter, remoteOpts ...remote.Option) error {
 	img, err := remote.Image(imgRef, remoteOpts...)
 	if err != nil {
 		return fmt.Errorf("error fetching remote image %q: %w", imgRef.Name(), err)
@@ -173,24 +245,138 @@ func unpackImage(ctx context.Context, imgRef name.Reference, unpackPath string,
 		return fmt.Errorf("error getting image layers: %w", err)
 	}
 
+	l := log.FromContext(ctx)
 	for _, layer := range layers {
 		layerRc, err := layer.Uncompressed()
 		if err != nil {
 			return fmt.Errorf("error getting uncompressed layer data: %w", err)
 		}
 
-		// This filter ensures that the files created have the proper UID and GID
-		// for the filesystem they will be stored on to ensure no permission errors occur when attempting to create the
-		// files.
-		_, err = archive.Apply(ctx, unpackPath, layerRc, archive.WithFilter(func(th *tar.Header) (bool, error) {
-			th.Uid = os.Getuid()
-			th.Gid = os.Getgid()
-			return true, nil
-		}))
+		applied, err := applyLayer(ctx, unpackPath, layerRc, filter)
+		layerRc.Close()
 		if err != nil {
 			return fmt.Errorf("error applying layer to archive: %w", err)
 		}
+		if applied == 0 {
+			if layerDigest, err := layer.Digest(); err == nil {
+				l.V(1).Info("skipped image layer without matching paths", "layer", layerDigest.String())
+			}
+		}
 	}
 
 	return nil
 }
+
+// applyLayer applies the uncompressed layer read from r to unpackPath and
+// returns the number of entries that were applied. If filter is not nil, the
+// entries it hides are skipped by their tar headers, so that their content is
+// never written.
+func applyLayer(ctx context.Context, unpackPath string, r io.Reader, filter *util.PathFilter) (int, error) {
+	applied := 0
+	// This filter ensures that the files created have the proper UID and GID
+	// for the filesystem they will be stored on to ensure no permission errors occur when attempting to create the
+	// files.
+	_, err := archive.Apply(ctx, unpackPath, r, archive.WithFilter(func(th *tar.Header) (bool, error) {
+		if filter != nil && !layerEntryVisible(filter, th) {
+			return false, nil
+		}
+		th.Uid = os.Getuid()
+		th.Gid = os.Getgid()
+		applied++
+		return true, nil
+	}))
+	return applied, err
+}
+
+const (
+	// whiteoutPrefix prefixes the names of layer entries that remove the
+	// path of the same name without the prefix from lower layers.
+	whiteoutPrefix = ".wh."
+	// whiteoutOpaqueDir is the name of layer entries that remove all
+	// content of their directory from lower layers.
+	whiteoutOpaqueDir = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// layerEntryVisible reports whether the layer entry of th is visible through
+// filter. Whiteouts are visible if the path they remove is, and hard links
+// are only visible if their target is, because it would not be extracted
+// otherwise.
+func layerEntryVisible(filter *util.PathFilter, th *tar.Header) bool {
+	name := path.Clean("/" + th.Name)[1:]
+	if name == "" {
+		return true
+	}
+	dir, base := path.Split(name)
+	switch {
+	case base == whiteoutOpaqueDir:
+		return filter.Visible(path.Clean(dir), true)
+	case strings.HasPrefix(base, whiteoutPrefix):
+		return filter.Visible(path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)), true)
+	}
+	if !filter.Visible(name, th.Typeflag == tar.TypeDir) {
+		return false
+	}
+	if th.Typeflag == tar.TypeLink {
+		return filter.Visible(path.Clean("/" + th.Linkname)[1:], false)
+	}
+	return true
+}
+
+// unpackNodeImage unpacks an image from the content store of the cluster
+// nodes through the node image server, for images that were loaded onto the
+// nodes rather than pushed to a registry.
+func (i *ImageRegistry) unpackNodeImage(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment, imgRef name.Reference, filter *util.PathFilter, cacheSuffix string) (*Result, error) {
+	if i.NodeImageURL == "" {
+		return nil, rukpakerrors.NewUnrecoverable(errors.New("node-local images are not supported: the node image server is not configured"))
+	}
+
+	u := fmt.Sprintf("%s%s?%s", strings.TrimSuffix(i.NodeImageURL, "/"), nodeimage.Path, url.Values{"ref": {bundle.Spec.Source.Image.Ref}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request for node image: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request node image: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("request node image: unexpected status %q: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	digest, err := ggcrv1.NewHash(resp.Header.Get(nodeimage.DigestHeader))
+	if err != nil {
+		return nil, fmt.Errorf("parse node image digest: %v", err)
+	}
+
+	unpackPath := filepath.Join(i.BaseCachePath, bundle.Name, digest.Hex+cacheSuffix)
+	if _, err = os.Stat(unpackPath); errors.Is(err, os.ErrNotExist) {
+		if err := i.Cleanup(ctx, bundle); err != nil {
+			return nil, fmt.Errorf("error cleaning up bundle cache: %w", err)
+		}
+		if err = os.MkdirAll(unpackPath, 0700); err != nil {
+			return nil, fmt.Errorf("error creating unpack path: %w", err)
+		}
+		if err := unpackNodeImageContent(ctx, unpackPath, resp.Body, filter); err != nil {
+			if cleanupErr := os.RemoveAll(unpackPath); cleanupErr != nil {
+				err = apimacherrors.NewAggregate([]error{err, fmt.Errorf("error cleaning up unpack path after unpack failed: %w", cleanupErr)})
+			}
+			return nil, fmt.Errorf("error unpacking node image: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("error checking if image is in filesystem cache: %w", err)
+	}
+
+	resolvedRef := fmt.Sprintf("%s@%s", imgRef.Context().Name(), digest)
+	return unpackedResult(util.DirFS(unpackPath), bundle, resolvedRef), nil
+}
+
+func unpackNodeImageContent(ctx context.Context, unpackPath string, r io.Reader, filter *util.PathFilter) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+	_, err = applyLayer(ctx, unpackPath, gzr, filter)
+	return err
+}
@@ -0,0 +1,136 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+func TestApplyLayerFiltered(t *testing.T) {
+	layer := func(entries ...tar.Header) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range entries {
+			if hdr.Typeflag == tar.TypeReg {
+				hdr.Size = int64(len(hdr.Name))
+			}
+			require.NoError(t, tw.WriteHeader(&hdr))
+			if hdr.Typeflag == tar.TypeReg {
+				_, err := tw.Write([]byte(hdr.Name))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tw.Close())
+		return buf.Bytes()
+	}
+	dir := func(name string) tar.Header { return tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755} }
+	file := func(name string) tar.Header { return tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644} }
+
+	filter, err := util.NewPathFilter([]string{"manifests", "metadata"}, []string{"manifests/tests"})
+	require.NoError(t, err)
+	unpackPath := t.TempDir()
+
+	_, err = applyLayer(context.Background(), unpackPath, bytes.NewReader(layer(
+		dir("usr/"), dir("usr/bin/"), file("usr/bin/operator"),
+	)), filter)
+	require.NoError(t, err)
+
+	_, err = applyLayer(context.Background(), unpackPath, bytes.NewReader(layer(
+		dir("manifests/"), file("manifests/deployment.yaml"), file("manifests/service.yaml"),
+		dir("manifests/tests/"), file("manifests/tests/pod.yaml"),
+		dir("metadata/"), file("metadata/annotations.yaml"),
+		file("LICENSE"),
+	)), filter)
+	require.NoError(t, err)
+
+	_, err = applyLayer(context.Background(), unpackPath, bytes.NewReader(layer(
+		file("manifests/.wh.service.yaml"),
+	)), filter)
+	require.NoError(t, err)
+
+	var files []string
+	require.NoError(t, fs.WalkDir(os.DirFS(unpackPath), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	}))
+	require.ElementsMatch(t, []string{"manifests/deployment.yaml", "metadata/annotations.yaml"}, files)
+}
+
+func TestImageRegistryUnpack(t *testing.T) {
+	var (
+		blockRequests atomic.Bool
+		aborted       atomic.Bool
+		cancel        context.CancelFunc
+	)
+	reg := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blockRequests.Load() {
+			cancel()
+			select {
+			case <-r.Context().Done():
+				aborted.Store(true)
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	img, err := crane.Image(map[string][]byte{"manifests/configmap.yaml": []byte("kind: ConfigMap\n")})
+	require.NoError(t, err)
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/bundle:v1"
+	require.NoError(t, crane.Push(img, ref))
+
+	newBundleDeployment := func() *rukpakv1alpha2.BundleDeployment {
+		bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeImage, Image: &rukpakv1alpha2.ImageSource{Ref: ref}}
+		return bd
+	}
+
+	t.Run("unpacks the image", func(t *testing.T) {
+		unpacker := &ImageRegistry{BaseCachePath: t.TempDir()}
+		result, err := unpacker.Unpack(context.Background(), newBundleDeployment())
+		require.NoError(t, err)
+		require.Equal(t, StateUnpacked, result.State)
+		require.Contains(t, result.ResolvedSource.Image.Ref, "@sha256:")
+		data, err := fs.ReadFile(result.Bundle, "manifests/configmap.yaml")
+		require.NoError(t, err)
+		require.Equal(t, "kind: ConfigMap\n", string(data))
+	})
+
+	t.Run("aborts requests when the context is canceled", func(t *testing.T) {
+		unpacker := &ImageRegistry{BaseCachePath: t.TempDir()}
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		blockRequests.Store(true)
+		defer blockRequests.Store(false)
+
+		_, err := unpacker.Unpack(ctx, newBundleDeployment())
+		require.ErrorIs(t, err, context.Canceled)
+		require.True(t, aborted.Load(), "the request to the registry was not aborted")
+	})
+}
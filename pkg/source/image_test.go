@@ -0,0 +1,189 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+func TestImageFailedPodResult(t *testing.T) {
+	terminated := func(name string, exitCode int32, reason, message string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: reason, Message: message}}}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		status    corev1.PodStatus
+		expectErr string
+	}{
+		{
+			name: "out of memory",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{terminated("install-unpacker", 0, "Completed", "")},
+				ContainerStatuses:     []corev1.ContainerStatus{terminated(imageBundleUnpackContainerName, 137, "OOMKilled", "")},
+			},
+			// The fake clientset returns these logs for every container.
+			expectErr: `unpack failed: container "bundle" terminated with exit code 137 (OOMKilled): fake logs`,
+		},
+		{
+			name: "termination message",
+			status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{terminated(imageBundleUnpackContainerName, 1, "Error", "open /manifests: no such file or directory\n")},
+			},
+			expectErr: `unpack failed: container "bundle" terminated with exit code 1 (Error): open /manifests: no such file or directory`,
+		},
+		{
+			name:      "no failed containers",
+			status:    corev1.PodStatus{Message: "Pod was terminated in response to imminent node shutdown."},
+			expectErr: "unpack failed: pod failed: Pod was terminated in response to imminent node shutdown.",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "test"}, Status: tc.status}
+			i := &Image{
+				Client:     fake.NewClientBuilder().WithObjects(pod.DeepCopy()).Build(),
+				KubeClient: kubefake.NewSimpleClientset(pod.DeepCopy()),
+			}
+			err := i.failedPodResult(context.Background(), &rukpakv1alpha2.BundleDeployment{}, pod)
+			require.EqualError(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestImageFailedPodRetention(t *testing.T) {
+	bundle := &rukpakv1alpha2.BundleDeployment{
+		TypeMeta:   metav1.TypeMeta{Kind: rukpakv1alpha2.BundleDeploymentKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}
+	unpackPod := func(name, attempt string, retained bool) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: name, Labels: map[string]string{
+				util.CoreOwnerKindKey: bundle.Kind,
+				util.CoreOwnerNameKey: bundle.Name,
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodFailed},
+		}
+		if attempt != "" {
+			pod.Labels[unpackAttemptLabel] = attempt
+		}
+		if retained {
+			pod.Labels[failedPodRetainedLabel] = "true"
+		}
+		return pod
+	}
+	failed := unpackPod("test-3", "3", false)
+	i := &Image{
+		Client: fake.NewClientBuilder().WithObjects(
+			unpackPod("test", "", true),
+			unpackPod("test-1", "1", true),
+			unpackPod("test-2", "2", true),
+			failed.DeepCopy(),
+		).Build(),
+		KubeClient:         kubefake.NewSimpleClientset(),
+		PodNamespace:       "rukpak-system",
+		FailedPodRetention: 2,
+	}
+
+	name, attempt, err := i.unpackPodName(context.Background(), bundle)
+	require.NoError(t, err)
+	require.Equal(t, "test-3", name)
+	require.Equal(t, 3, attempt)
+
+	require.Error(t, i.failedPodResult(context.Background(), bundle, failed))
+
+	pods := &corev1.PodList{}
+	require.NoError(t, i.Client.List(context.Background(), pods, client.InNamespace("rukpak-system")))
+	var names []string
+	for _, pod := range pods.Items {
+		require.Contains(t, pod.Labels, failedPodRetainedLabel)
+		names = append(names, pod.Name)
+	}
+	require.ElementsMatch(t, []string{"test-2", "test-3"}, names)
+
+	name, attempt, err = i.unpackPodName(context.Background(), bundle)
+	require.NoError(t, err)
+	require.Equal(t, "test-4", name)
+	require.Equal(t, 4, attempt)
+}
+
+func TestImageParseBundleContents(t *testing.T) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/cm.yaml", Mode: 0644, Size: 4, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("kind"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	content := buf.Bytes()
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+
+	for _, tc := range []struct {
+		name      string
+		content   []byte
+		digest    string
+		data      string
+		expectErr string
+	}{
+		{
+			name:    "matching digest",
+			content: content,
+			digest:  digest,
+		},
+		{
+			name:    "no digest",
+			content: content,
+		},
+		{
+			name:      "truncated content",
+			content:   content[:len(content)-8],
+			digest:    digest,
+			expectErr: "does not match the digest " + digest,
+		},
+		{
+			name:      "output without content",
+			data:      `{"contentDigest":"` + digest + `"}`,
+			expectErr: "expected the output to start with",
+		},
+		{
+			name:      "unterminated content",
+			data:      `{"content":"H4sI`,
+			expectErr: "unexpected EOF",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := []byte(tc.data)
+			if tc.data == "" {
+				var err error
+				data, err = json.Marshal(map[string]interface{}{"content": tc.content, "contentDigest": tc.digest})
+				require.NoError(t, err)
+			}
+			bundleFS, err := parseBundleContents(bytes.NewReader(data), t.TempDir())
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			defer bundleFS.Close()
+			b, err := fs.ReadFile(bundleFS, "manifests/cm.yaml")
+			require.NoError(t, err)
+			require.Equal(t, "kind", string(b))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing/fstest"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/errors"
+)
+
+// maxInlineGzippedSize is the maximum size of the decompressed content of
+// an inline source.
+const maxInlineGzippedSize = 4 << 20
+
+// Inline unpacks bundles whose content is embedded in the BundleDeployment.
+// The manifests are unpacked into the manifests directory of the bundle.
+type Inline struct{}
+
+func (i *Inline) Unpack(_ context.Context, bundle *rukpakv1alpha2.BundleDeployment) (*Result, error) {
+	if bundle.Spec.Source.Type != rukpakv1alpha2.SourceTypeInline {
+		return nil, fmt.Errorf("bundle source type %q not supported", bundle.Spec.Source.Type)
+	}
+	if bundle.Spec.Source.Inline == nil {
+		return nil, fmt.Errorf("bundle source inline configuration is unset")
+	}
+	inline := bundle.Spec.Source.Inline
+
+	bundleFS := fstest.MapFS{}
+	switch {
+	case len(inline.Manifests) > 0 && len(inline.Gzipped) > 0:
+		return nil, errors.NewUnrecoverable(fmt.Errorf("inline source must set exactly one of manifests and gzipped"))
+	case len(inline.Manifests) > 0:
+		for idx, manifest := range inline.Manifests {
+			bundleFS[fmt.Sprintf("manifests/manifest-%d.yaml", idx)] = &fstest.MapFile{Data: []byte(manifest)}
+		}
+	case len(inline.Gzipped) > 0:
+		data, err := gunzipLimited(inline.Gzipped, maxInlineGzippedSize)
+		if err != nil {
+			return nil, errors.NewUnrecoverable(fmt.Errorf("decompress inline manifests: %v", err))
+		}
+		bundleFS["manifests/manifests.yaml"] = &fstest.MapFile{Data: data}
+	default:
+		return nil, errors.NewUnrecoverable(fmt.Errorf("inline source must set one of manifests and gzipped"))
+	}
+
+	// The content is not copied into the resolved source, since that would
+	// double the size of the BundleDeployment. The bundle digest identifies
+	// the content instead.
+	resolvedSource := &rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeInline}
+
+	message := generateMessage("inline")
+	return &Result{Bundle: bundleFS, ResolvedSource: resolvedSource, State: StateUnpacked, Message: message}, nil
+}
+
+func (i *Inline) Cleanup(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) error {
+	return nil
+}
+
+// gunzipLimited decompresses data, failing if the decompressed content is
+// larger than limit bytes.
+func gunzipLimited(data []byte, limit int64) ([]byte, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+	out, err := io.ReadAll(io.LimitReader(gzr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("decompressed content exceeds %d bytes", limit)
+	}
+	return out, nil
+}
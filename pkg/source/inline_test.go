@@ -0,0 +1,77 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestInlineUnpack(t *testing.T) {
+	gzipped := func(data []byte) []byte {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		_, err := gzw.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, gzw.Close())
+		return buf.Bytes()
+	}
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"
+
+	for _, tc := range []struct {
+		name      string
+		inline    *rukpakv1alpha2.InlineSource
+		files     map[string]string
+		expectErr string
+	}{
+		{
+			name:   "manifests",
+			inline: &rukpakv1alpha2.InlineSource{Manifests: []string{configMap, configMap}},
+			files:  map[string]string{"manifests/manifest-0.yaml": configMap, "manifests/manifest-1.yaml": configMap},
+		},
+		{
+			name:   "gzipped",
+			inline: &rukpakv1alpha2.InlineSource{Gzipped: gzipped([]byte(configMap))},
+			files:  map[string]string{"manifests/manifests.yaml": configMap},
+		},
+		{
+			name:      "gzipped content too large",
+			inline:    &rukpakv1alpha2.InlineSource{Gzipped: gzipped(make([]byte, maxInlineGzippedSize+1))},
+			expectErr: "exceeds",
+		},
+		{
+			name:      "not gzipped",
+			inline:    &rukpakv1alpha2.InlineSource{Gzipped: []byte(configMap)},
+			expectErr: "decompress inline manifests",
+		},
+		{
+			name:      "both set",
+			inline:    &rukpakv1alpha2.InlineSource{Manifests: []string{configMap}, Gzipped: gzipped([]byte(configMap))},
+			expectErr: "exactly one",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{}
+			bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeInline, Inline: tc.inline}
+
+			result, err := (&Inline{}).Unpack(context.Background(), bd)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, StateUnpacked, result.State)
+			require.Equal(t, rukpakv1alpha2.SourceTypeInline, result.ResolvedSource.Type)
+			for name, content := range tc.files {
+				data, err := fs.ReadFile(result.Bundle, name)
+				require.NoError(t, err)
+				require.Equal(t, content, string(data))
+			}
+		})
+	}
+}
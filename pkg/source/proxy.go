@@ -0,0 +1,55 @@
+package source
+
+import (
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+	applyconfigurationcorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// proxyConfig returns the proxy of a source: its own proxy if it sets one,
+// and the proxy that the provisioner is configured with by its HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables otherwise.
+func proxyConfig(proxy *rukpakv1alpha2.ProxyConfig) *httpproxy.Config {
+	if proxy == nil {
+		return httpproxy.FromEnvironment()
+	}
+	return &httpproxy.Config{
+		HTTPProxy:  proxy.HTTPProxy,
+		HTTPSProxy: proxy.HTTPSProxy,
+		NoProxy:    proxy.NoProxy,
+	}
+}
+
+// proxyFunc returns the Proxy function of the http.Transport that requests
+// the content of a source with the given proxy.
+func proxyFunc(proxy *rukpakv1alpha2.ProxyConfig) func(*http.Request) (*url.URL, error) {
+	selectProxy := proxyConfig(proxy).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return selectProxy(req.URL)
+	}
+}
+
+// proxyEnv returns the environment variables that hand the proxy of a source
+// to the containers of unpack pods. Both the upper and the lower case
+// variables are set, since tools differ in which of them they read.
+func proxyEnv(proxy *rukpakv1alpha2.ProxyConfig) []*applyconfigurationcorev1.EnvVarApplyConfiguration {
+	cfg := proxyConfig(proxy)
+	var env []*applyconfigurationcorev1.EnvVarApplyConfiguration
+	for _, v := range []struct{ name, value string }{
+		{"HTTP_PROXY", cfg.HTTPProxy},
+		{"HTTPS_PROXY", cfg.HTTPSProxy},
+		{"NO_PROXY", cfg.NoProxy},
+		{"http_proxy", cfg.HTTPProxy},
+		{"https_proxy", cfg.HTTPSProxy},
+		{"no_proxy", cfg.NoProxy},
+	} {
+		if v.value != "" {
+			env = append(env, applyconfigurationcorev1.EnvVar().WithName(v.name).WithValue(v.value))
+		}
+	}
+	return env
+}
@@ -0,0 +1,94 @@
+package source
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestProxyFunc(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	for _, tc := range []struct {
+		name        string
+		proxy       *rukpakv1alpha2.ProxyConfig
+		url         string
+		expectProxy string
+	}{
+		{name: "environment", url: "https://registry.example.com/v2/", expectProxy: "http://env-proxy:3128"},
+		{name: "environment no proxy", url: "https://internal.example.com/v2/"},
+		{
+			name:        "source proxy",
+			proxy:       &rukpakv1alpha2.ProxyConfig{HTTPSProxy: "http://source-proxy:8080"},
+			url:         "https://internal.example.com/v2/",
+			expectProxy: "http://source-proxy:8080",
+		},
+		{
+			name:  "source no proxy",
+			proxy: &rukpakv1alpha2.ProxyConfig{HTTPSProxy: "http://source-proxy:8080", NoProxy: ".example.com"},
+			url:   "https://registry.example.com/v2/",
+		},
+		{name: "source without proxy", proxy: &rukpakv1alpha2.ProxyConfig{}, url: "https://registry.example.com/v2/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			proxyURL, err := proxyFunc(tc.proxy)(req)
+			require.NoError(t, err)
+			if tc.expectProxy == "" {
+				require.Nil(t, proxyURL)
+				return
+			}
+			require.Equal(t, tc.expectProxy, proxyURL.String())
+		})
+	}
+}
+
+func TestProxyEnv(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	envVars := func(proxy *rukpakv1alpha2.ProxyConfig) map[string]string {
+		env := map[string]string{}
+		for _, v := range proxyEnv(proxy) {
+			env[*v.Name] = *v.Value
+		}
+		return env
+	}
+	require.Equal(t, map[string]string{
+		"HTTPS_PROXY": "http://env-proxy:3128",
+		"https_proxy": "http://env-proxy:3128",
+		"NO_PROXY":    "internal.example.com",
+		"no_proxy":    "internal.example.com",
+	}, envVars(nil))
+	require.Equal(t, map[string]string{
+		"HTTP_PROXY": "http://source-proxy:8080",
+		"http_proxy": "http://source-proxy:8080",
+	}, envVars(&rukpakv1alpha2.ProxyConfig{HTTPProxy: "http://source-proxy:8080"}))
+	require.Empty(t, envVars(&rukpakv1alpha2.ProxyConfig{}), "a source without proxy does not inherit the proxy of the provisioner")
+}
+
+func TestGitProxyOptions(t *testing.T) {
+	proxy := &rukpakv1alpha2.ProxyConfig{HTTPSProxy: "http://source-proxy:8080", NoProxy: "internal.example.com"}
+	for _, tc := range []struct {
+		repository string
+		expected   transport.ProxyOptions
+	}{
+		{repository: "https://github.com/operator-framework/rukpak", expected: transport.ProxyOptions{URL: "http://source-proxy:8080"}},
+		{repository: "https://internal.example.com/rukpak"},
+		{repository: "http://github.com/operator-framework/rukpak"},
+		{repository: "git@github.com:operator-framework/rukpak.git"},
+	} {
+		t.Run(tc.repository, func(t *testing.T) {
+			opts, err := gitProxyOptions(&rukpakv1alpha2.GitSource{Repository: tc.repository, Proxy: proxy})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, opts)
+		})
+	}
+}
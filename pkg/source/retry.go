@@ -0,0 +1,59 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
+)
+
+const (
+	defaultRetryBackoff = time.Second
+
+	// MaxRetryBackoff is the longest delay between two attempts. Retries
+	// block the reconcile of the BundleDeployment, so the delay is capped even
+	// if a longer backoff is configured.
+	MaxRetryBackoff = time.Minute
+	// MaxRetryTimeout is the longest time that all attempts of a retry policy
+	// with more than one attempt may take together, including policies
+	// without a timeout.
+	MaxRetryTimeout = 5 * time.Minute
+)
+
+// fetchWithRetry calls fetch until it succeeds, it fails with an error that
+// is not transient, or the attempts or the timeout of policy are exhausted.
+// The context passed to fetch is canceled once the timeout expires.
+func fetchWithRetry(ctx context.Context, policy rukpakv1alpha2.RetryPolicy, fetch func(context.Context) error) error {
+	var timeout time.Duration
+	if policy.Timeout != nil {
+		timeout = policy.Timeout.Duration
+	}
+	if policy.Attempts > 1 && (timeout == 0 || timeout > MaxRetryTimeout) {
+		timeout = MaxRetryTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	backoff := defaultRetryBackoff
+	if policy.Backoff != nil {
+		backoff = min(policy.Backoff.Duration, MaxRetryBackoff)
+	}
+
+	for attempt := int32(1); ; attempt++ {
+		err := fetch(ctx)
+		var transient *rukpakerrors.Transient
+		if err == nil || !errors.As(err, &transient) || attempt >= policy.Attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, MaxRetryBackoff)
+	}
+}
@@ -0,0 +1,57 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestFetchWithRetryTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		policy        rukpakv1alpha2.RetryPolicy
+		expectTimeout time.Duration
+	}{
+		{
+			name:   "no retries",
+			policy: rukpakv1alpha2.RetryPolicy{},
+		},
+		{
+			name:          "no retries with timeout",
+			policy:        rukpakv1alpha2.RetryPolicy{Timeout: &metav1.Duration{Duration: time.Hour}},
+			expectTimeout: time.Hour,
+		},
+		{
+			name:          "retries without timeout",
+			policy:        rukpakv1alpha2.RetryPolicy{Attempts: 3},
+			expectTimeout: MaxRetryTimeout,
+		},
+		{
+			name:          "retries with short timeout",
+			policy:        rukpakv1alpha2.RetryPolicy{Attempts: 3, Timeout: &metav1.Duration{Duration: time.Minute}},
+			expectTimeout: time.Minute,
+		},
+		{
+			name:          "retries with long timeout",
+			policy:        rukpakv1alpha2.RetryPolicy{Attempts: 3, Timeout: &metav1.Duration{Duration: time.Hour}},
+			expectTimeout: MaxRetryTimeout,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			require.NoError(t, fetchWithRetry(context.Background(), tc.policy, func(ctx context.Context) error {
+				deadline, ok := ctx.Deadline()
+				require.Equal(t, tc.expectTimeout != 0, ok)
+				if ok {
+					require.WithinDuration(t, start.Add(tc.expectTimeout), deadline, time.Second)
+				}
+				return nil
+			}))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package source
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=core.rukpak.io,resources=secretreferencegrants,verbs=list
+
+// getAuthSecret reads the secret of auth, which bundle refers to, into secret.
+// Secrets in secretNamespace are read from reader. Secrets in other namespaces
+// are only read when a SecretReferenceGrant in their namespace allows bundle to
+// reference them, and are read from crossNamespaceReader, since the cache of
+// the provisioner only includes secrets of its own namespace. reader is used
+// for both if crossNamespaceReader is nil.
+func getAuthSecret(ctx context.Context, reader, crossNamespaceReader client.Reader, secretNamespace string, bundle *rukpakv1alpha2.BundleDeployment, auth rukpakv1alpha2.Authorization, secret *corev1.Secret) error {
+	namespace := auth.Namespace
+	if namespace == "" || namespace == secretNamespace {
+		return reader.Get(ctx, client.ObjectKey{Namespace: secretNamespace, Name: auth.Secret.Name}, secret)
+	}
+	if crossNamespaceReader == nil {
+		crossNamespaceReader = reader
+	}
+
+	grants := &rukpakv1alpha2.SecretReferenceGrantList{}
+	if err := crossNamespaceReader.List(ctx, grants, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("list secret reference grants in namespace %q: %v", namespace, err)
+	}
+	for _, grant := range grants.Items {
+		if grant.Allows(bundle.Name, auth.Secret.Name) {
+			return crossNamespaceReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: auth.Secret.Name}, secret)
+		}
+	}
+	return fmt.Errorf("secret %s/%s may not be referenced by bundledeployment %q: no SecretReferenceGrant in namespace %q allows it", namespace, auth.Secret.Name, bundle.Name, namespace)
+}
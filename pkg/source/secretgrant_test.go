@@ -0,0 +1,96 @@
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestGetAuthSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "auth"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "auth"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "other"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "auth"}},
+		&rukpakv1alpha2.SecretReferenceGrant{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "grant"},
+			Spec:       rukpakv1alpha2.SecretReferenceGrantSpec{BundleDeployments: []string{"granted"}, Secrets: []string{"auth"}},
+		},
+		&rukpakv1alpha2.SecretReferenceGrant{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "grant"},
+			Spec:       rukpakv1alpha2.SecretReferenceGrantSpec{BundleDeployments: []string{"granted"}},
+		},
+	).Build()
+
+	for _, tc := range []struct {
+		name             string
+		bundleDeployment string
+		namespace        string
+		secret           string
+		expectErr        string
+	}{
+		{
+			name:             "system namespace",
+			bundleDeployment: "other",
+			secret:           "auth",
+		},
+		{
+			name:             "granted secret",
+			bundleDeployment: "granted",
+			namespace:        "team-a",
+			secret:           "auth",
+		},
+		{
+			name:             "secret not listed by the grant",
+			bundleDeployment: "granted",
+			namespace:        "team-a",
+			secret:           "other",
+			expectErr:        `secret team-a/other may not be referenced by bundledeployment "granted": no SecretReferenceGrant in namespace "team-a" allows it`,
+		},
+		{
+			name:             "bundledeployment not listed by the grant",
+			bundleDeployment: "other",
+			namespace:        "team-a",
+			secret:           "auth",
+			expectErr:        `secret team-a/auth may not be referenced by bundledeployment "other"`,
+		},
+		{
+			name:             "grant of all secrets in the namespace",
+			bundleDeployment: "granted",
+			namespace:        "team-b",
+			secret:           "auth",
+		},
+		{
+			name:             "namespace without grants",
+			bundleDeployment: "granted",
+			namespace:        "team-c",
+			secret:           "auth",
+			expectErr:        `no SecretReferenceGrant in namespace "team-c" allows it`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: tc.bundleDeployment}}
+			auth := rukpakv1alpha2.Authorization{Namespace: tc.namespace, Secret: corev1.LocalObjectReference{Name: tc.secret}}
+			secret := &corev1.Secret{}
+			err := getAuthSecret(context.Background(), cl, nil, "rukpak-system", bundle, auth, secret)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.secret, secret.Name)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing/fstest"
+
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// Secrets unpacks bundles from secrets with the same path mapping as the
+// ConfigMaps source, for bundles that contain sensitive values. Reader should
+// not be backed by a cache, so that the content of the secrets is not kept in
+// memory.
+type Secrets struct {
+	Reader          client.Reader
+	SecretNamespace string
+}
+
+func (o *Secrets) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) (*Result, error) {
+	if bundle.Spec.Source.Type != rukpakv1alpha2.SourceTypeSecrets {
+		return nil, fmt.Errorf("bundle source type %q not supported", bundle.Spec.Source.Type)
+	}
+	if bundle.Spec.Source.Secrets == nil {
+		return nil, fmt.Errorf("bundle source secrets configuration is unset")
+	}
+
+	bundleFS := fstest.MapFS{}
+	seenFilepaths := map[string]sets.Set[string]{}
+
+	for _, secretSource := range bundle.Spec.Source.Secrets {
+		secretName := secretSource.Secret.Name
+		dir := filepath.Clean(secretSource.Path)
+
+		// Validating admission webhook handles validation for paths outside
+		// the bundle root. Immutability is checked here as well, since the
+		// webhook that guards secrets fails open.
+
+		var secret corev1.Secret
+		if err := o.Reader.Get(ctx, client.ObjectKey{Name: secretName, Namespace: o.SecretNamespace}, &secret); err != nil {
+			return nil, fmt.Errorf("get secret %s/%s: %v", o.SecretNamespace, secretName, err)
+		}
+		if secret.Immutable == nil || !*secret.Immutable {
+			return nil, fmt.Errorf("secret %s/%s is not immutable", o.SecretNamespace, secretName)
+		}
+
+		for filename, data := range secret.Data {
+			filepath := filepath.Join(dir, filename)
+			if _, ok := seenFilepaths[filepath]; !ok {
+				seenFilepaths[filepath] = sets.New[string]()
+			}
+			seenFilepaths[filepath].Insert(secretName)
+			bundleFS[filepath] = &fstest.MapFile{
+				Data: data,
+			}
+		}
+	}
+
+	errs := []error{}
+	for filepath, secretNames := range seenFilepaths {
+		if len(secretNames) > 1 {
+			errs = append(errs, fmt.Errorf("duplicate path %q found in secrets %v", filepath, sets.List(secretNames)))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	resolvedSource := &rukpakv1alpha2.BundleSource{
+		Type:    rukpakv1alpha2.SourceTypeSecrets,
+		Secrets: bundle.Spec.Source.DeepCopy().Secrets,
+	}
+
+	message := generateMessage("secrets")
+	return &Result{Bundle: bundleFS, ResolvedSource: resolvedSource, State: StateUnpacked, Message: message}, nil
+}
+
+func (o *Secrets) Cleanup(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestSecretsUnpack(t *testing.T) {
+	cl := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "manifests"},
+			Immutable:  ptr.To(true),
+			Data:       map[string][]byte{"secret.yaml": []byte("kind: Secret\n")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "manifests-copy"},
+			Immutable:  ptr.To(true),
+			Data:       map[string][]byte{"secret.yaml": []byte("kind: Secret\n")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "mutable"},
+			Data:       map[string][]byte{"secret.yaml": []byte("kind: Secret\n")},
+		},
+	).Build()
+	unpacker := &Secrets{Reader: cl, SecretNamespace: "rukpak-system"}
+	source := func(sources ...rukpakv1alpha2.SecretSource) *rukpakv1alpha2.BundleDeployment {
+		bd := &rukpakv1alpha2.BundleDeployment{}
+		bd.Spec.Source = rukpakv1alpha2.BundleSource{Type: rukpakv1alpha2.SourceTypeSecrets, Secrets: sources}
+		return bd
+	}
+
+	for _, tc := range []struct {
+		name      string
+		bd        *rukpakv1alpha2.BundleDeployment
+		files     map[string]string
+		expectErr string
+	}{
+		{
+			name:  "data",
+			bd:    source(rukpakv1alpha2.SecretSource{Secret: corev1.LocalObjectReference{Name: "manifests"}, Path: "manifests"}),
+			files: map[string]string{"manifests/secret.yaml": "kind: Secret\n"},
+		},
+		{
+			name: "duplicate paths",
+			bd: source(
+				rukpakv1alpha2.SecretSource{Secret: corev1.LocalObjectReference{Name: "manifests"}},
+				rukpakv1alpha2.SecretSource{Secret: corev1.LocalObjectReference{Name: "manifests-copy"}},
+			),
+			expectErr: `duplicate path "secret.yaml" found in secrets [manifests manifests-copy]`,
+		},
+		{
+			name:      "mutable secret",
+			bd:        source(rukpakv1alpha2.SecretSource{Secret: corev1.LocalObjectReference{Name: "mutable"}}),
+			expectErr: "secret rukpak-system/mutable is not immutable",
+		},
+		{
+			name:      "missing secret",
+			bd:        source(rukpakv1alpha2.SecretSource{Secret: corev1.LocalObjectReference{Name: "missing"}}),
+			expectErr: "get secret rukpak-system/missing",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := unpacker.Unpack(context.Background(), tc.bd)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, StateUnpacked, result.State)
+			require.Equal(t, tc.bd.Spec.Source.Secrets, result.ResolvedSource.Secrets)
+			for name, content := range tc.files {
+				data, err := fs.ReadFile(result.Bundle, name)
+				require.NoError(t, err)
+				require.Equal(t, content, string(data))
+			}
+		})
+	}
+}
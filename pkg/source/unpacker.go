@@ -3,11 +3,16 @@ package source
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
 
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	rukpakerrors "github.com/operator-framework/rukpak/pkg/errors"
+	"github.com/operator-framework/rukpak/pkg/util"
 )
 
 // Unpacker unpacks bundle content, either synchronously or asynchronously and
@@ -32,8 +37,16 @@ type Unpacker interface {
 // Result conveys progress information about unpacking bundle content.
 type Result struct {
 	// Bundle contains the full filesystem of a bundle's root directory.
+	// It may stream the content of files from disk or over the network
+	// rather than hold it in memory, so files that are opened must be
+	// closed, and should be read in the order of fs.WalkDir where possible.
 	Bundle fs.FS
 
+	// Closer, if set, releases the resources that Bundle holds, such as the
+	// temporary files that it streams content from. Callers release them
+	// with Close once they no longer use Bundle.
+	Closer io.Closer
+
 	// ResolvedSource is a reproducible view of a Bundle's Source.
 	// When possible, source implementations should return a ResolvedSource
 	// that pins the Source such that future fetches of the bundle content can
@@ -53,6 +66,14 @@ type Result struct {
 	Message string
 }
 
+// Close releases the resources that the bundle of r holds, if any.
+func (r *Result) Close() error {
+	if r == nil || r.Closer == nil {
+		return nil
+	}
+	return r.Closer.Close()
+}
+
 type State string
 
 const (
@@ -84,7 +105,74 @@ func (s *unpacker) Unpack(ctx context.Context, bundle *rukpakv1alpha2.BundleDepl
 	if !ok {
 		return nil, fmt.Errorf("source type %q not supported", bundle.Spec.Source.Type)
 	}
-	return source.Unpack(ctx, bundle)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := source.Unpack(ctx, bundle)
+	if err != nil || result.State != StateUnpacked {
+		return result, err
+	}
+	if err := processBundle(bundle.Spec.Source, result); err != nil {
+		_ = result.Close()
+		return nil, err
+	}
+	return result, nil
+}
+
+// processBundle filters and sanitizes the bundle of result, and records its
+// digest in the resolved source.
+func processBundle(source rukpakv1alpha2.BundleSource, result *Result) error {
+	var err error
+	if filters := sourcePathFilters(source); filters != nil {
+		if result.Bundle, err = util.FilterFS(result.Bundle, filters.IncludePaths, filters.ExcludePaths); err != nil {
+			return rukpakerrors.NewUnrecoverable(fmt.Errorf("filter bundle content: %v", err))
+		}
+	}
+	if honorsIgnoreFile(source) {
+		if result.Bundle, err = util.IgnoreFS(result.Bundle, util.RukpakIgnore); err != nil {
+			return rukpakerrors.NewUnrecoverable(fmt.Errorf("filter bundle content: %v", err))
+		}
+	}
+	if result.Bundle, err = util.SanitizeFS(result.Bundle, util.SymlinkPolicyResolve); err != nil {
+		return err
+	}
+	if result.ResolvedSource != nil {
+		if result.ResolvedSource.BundleDigest, err = util.DigestFS(result.Bundle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sourcePathFilters returns the path filters configured for the given source,
+// or nil if the source type does not support path filtering.
+func sourcePathFilters(source rukpakv1alpha2.BundleSource) *rukpakv1alpha2.PathFilters {
+	switch {
+	case source.Type == rukpakv1alpha2.SourceTypeImage && source.Image != nil:
+		return &source.Image.PathFilters
+	case source.Type == rukpakv1alpha2.SourceTypeGit && source.Git != nil:
+		return &source.Git.PathFilters
+	case source.Type == rukpakv1alpha2.SourceTypeHTTP && source.HTTP != nil:
+		return &source.HTTP.PathFilters
+	}
+	return nil
+}
+
+// newPathFilter returns the filter of filters that sources apply while they
+// unpack content, or nil if filters is empty.
+func newPathFilter(filters rukpakv1alpha2.PathFilters) (*util.PathFilter, error) {
+	if len(filters.IncludePaths) == 0 && len(filters.ExcludePaths) == 0 {
+		return nil, nil
+	}
+	return util.NewPathFilter(filters.IncludePaths, filters.ExcludePaths)
+}
+
+// honorsIgnoreFile reports whether the files that the util.RukpakIgnore file in
+// the root of the bundle lists are dropped when the source is unpacked. Only
+// sources whose content is maintained as a directory tree by bundle authors,
+// rather than built into an artifact, honor it.
+func honorsIgnoreFile(source rukpakv1alpha2.BundleSource) bool {
+	return source.Type == rukpakv1alpha2.SourceTypeGit || source.Type == rukpakv1alpha2.SourceTypeConfigMaps
 }
 
 func (s *unpacker) Cleanup(ctx context.Context, bundle *rukpakv1alpha2.BundleDeployment) error {
@@ -100,23 +188,44 @@ func (s *unpacker) Cleanup(ctx context.Context, bundle *rukpakv1alpha2.BundleDep
 // source types.
 //
 // TODO: refactor NewDefaultUnpacker due to growing parameter list
-func NewDefaultUnpacker(mgr manager.Manager, namespace, cacheDir string) (Unpacker, error) {
+func NewDefaultUnpacker(mgr manager.Manager, namespace, cacheDir, nodeImageURL string) (Unpacker, error) {
+	// Git and HTTP sources unpack to temporary files in the cache directory,
+	// in a directory that no bundle is cached in, since names of bundles
+	// cannot start with a dot. Files that a previous run left behind when
+	// it was killed are removed.
+	tempDir := filepath.Join(cacheDir, ".tmp")
+	if err := os.RemoveAll(tempDir); err != nil {
+		return nil, fmt.Errorf("remove temporary directory: %v", err)
+	}
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		return nil, fmt.Errorf("create temporary directory: %v", err)
+	}
 	return NewUnpacker(map[rukpakv1alpha2.SourceType]Unpacker{
 		rukpakv1alpha2.SourceTypeImage: &ImageRegistry{
 			BaseCachePath: cacheDir,
 			AuthNamespace: namespace,
+			NodeImageURL:  nodeImageURL,
 		},
 		rukpakv1alpha2.SourceTypeGit: &Git{
-			Reader:          mgr.GetClient(),
-			SecretNamespace: namespace,
+			Reader:               mgr.GetClient(),
+			SecretNamespace:      namespace,
+			CrossNamespaceReader: mgr.GetAPIReader(),
+			TempDir:              tempDir,
 		},
 		rukpakv1alpha2.SourceTypeConfigMaps: &ConfigMaps{
 			Reader:             mgr.GetClient(),
 			ConfigMapNamespace: namespace,
 		},
-		rukpakv1alpha2.SourceTypeHTTP: &HTTP{
-			Reader:          mgr.GetClient(),
+		rukpakv1alpha2.SourceTypeSecrets: &Secrets{
+			Reader:          mgr.GetAPIReader(),
 			SecretNamespace: namespace,
 		},
+		rukpakv1alpha2.SourceTypeHTTP: &HTTP{
+			Reader:               mgr.GetClient(),
+			SecretNamespace:      namespace,
+			CrossNamespaceReader: mgr.GetAPIReader(),
+			TempDir:              tempDir,
+		},
+		rukpakv1alpha2.SourceTypeInline: &Inline{},
 	}), nil
 }
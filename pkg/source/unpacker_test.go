@@ -0,0 +1,98 @@
+package source
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+type staticUnpacker struct {
+	fsys   fs.FS
+	closer io.Closer
+}
+
+func (u staticUnpacker) Unpack(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) (*Result, error) {
+	return &Result{Bundle: u.fsys, Closer: u.closer, State: StateUnpacked}, nil
+}
+
+func (u staticUnpacker) Cleanup(_ context.Context, _ *rukpakv1alpha2.BundleDeployment) error {
+	return nil
+}
+
+func TestUnpackerIgnoreFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		".rukpakignore":             &fstest.MapFile{Data: []byte("test/\n")},
+		"manifests/deployment.yaml": &fstest.MapFile{},
+		"test/fixture.yaml":         &fstest.MapFile{},
+	}
+	unpacker := NewUnpacker(map[rukpakv1alpha2.SourceType]Unpacker{
+		rukpakv1alpha2.SourceTypeGit:  staticUnpacker{fsys: fsys},
+		rukpakv1alpha2.SourceTypeHTTP: staticUnpacker{fsys: fsys},
+	})
+
+	for _, tc := range []struct {
+		sourceType rukpakv1alpha2.SourceType
+		expected   []string
+	}{
+		{sourceType: rukpakv1alpha2.SourceTypeGit, expected: []string{".rukpakignore", "manifests/deployment.yaml"}},
+		{sourceType: rukpakv1alpha2.SourceTypeHTTP, expected: []string{".rukpakignore", "manifests/deployment.yaml", "test/fixture.yaml"}},
+	} {
+		t.Run(string(tc.sourceType), func(t *testing.T) {
+			bd := &rukpakv1alpha2.BundleDeployment{}
+			bd.Spec.Source.Type = tc.sourceType
+
+			result, err := unpacker.Unpack(context.Background(), bd)
+			require.NoError(t, err)
+			var files []string
+			require.NoError(t, fs.WalkDir(result.Bundle, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					files = append(files, path)
+				}
+				return nil
+			}))
+			require.Equal(t, tc.expected, files)
+		})
+	}
+}
+
+func TestUnpackerCanceledContext(t *testing.T) {
+	unpacker := NewUnpacker(map[rukpakv1alpha2.SourceType]Unpacker{
+		rukpakv1alpha2.SourceTypeInline: staticUnpacker{fsys: fstest.MapFS{}},
+	})
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	bd.Spec.Source.Type = rukpakv1alpha2.SourceTypeInline
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := unpacker.Unpack(ctx, bd)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+type closeCounter int
+
+func (c *closeCounter) Close() error {
+	*c++
+	return nil
+}
+
+func TestUnpackerClosesRejectedBundles(t *testing.T) {
+	var closes closeCounter
+	unpacker := NewUnpacker(map[rukpakv1alpha2.SourceType]Unpacker{
+		rukpakv1alpha2.SourceTypeHTTP: staticUnpacker{fsys: fstest.MapFS{"pipe": &fstest.MapFile{Mode: fs.ModeNamedPipe}}, closer: &closes},
+	})
+	bd := &rukpakv1alpha2.BundleDeployment{}
+	bd.Spec.Source.Type = rukpakv1alpha2.SourceTypeHTTP
+
+	_, err := unpacker.Unpack(context.Background(), bd)
+	require.ErrorContains(t, err, "unsupported file type")
+	require.Equal(t, closeCounter(1), closes)
+}
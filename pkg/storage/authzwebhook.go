@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// RemoteUserHeader and RemoteGroupsHeader are the headers that the proxy
+	// in front of the content server sets to the authenticated user and its
+	// groups, when it is run with --auth-header-fields-enabled.
+	RemoteUserHeader   = "X-Remote-User"
+	RemoteGroupsHeader = "X-Remote-Groups"
+
+	remoteGroupsSeparator = "|"
+)
+
+// AuthorizationWebhook authorizes requests of bundle content with an external
+// authorization service, in addition to the RBAC checks of the proxy in front
+// of the content server. The request of the service follows the data API of
+// Open Policy Agent, so that OPA can be used as the service directly:
+//
+//	POST <URL>
+//	{"input": {"user": "...", "groups": ["..."], "method": "GET", "path": "/bundles/foo.tgz", "bundleDeployment": "foo"}}
+//
+// The service responds with {"result": {"allowed": true}} to allow the
+// request. Any other result denies it, with the optional "reason" of the
+// result as the message. Requests are denied if the service fails.
+type AuthorizationWebhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// AuthorizationInput describes a request of bundle content to the
+// authorization service.
+type AuthorizationInput struct {
+	User             string   `json:"user"`
+	Groups           []string `json:"groups,omitempty"`
+	Method           string   `json:"method"`
+	Path             string   `json:"path"`
+	BundleDeployment string   `json:"bundleDeployment,omitempty"`
+}
+
+// AuthorizationResult is the decision of the authorization service.
+type AuthorizationResult struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Handler serves requests with next if the authorization service allows
+// them. Other requests are rejected.
+func (w *AuthorizationWebhook) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		result, err := w.authorize(req)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("authorize request: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		if !result.Allowed {
+			reason := result.Reason
+			if reason == "" {
+				reason = "request denied by the authorization webhook"
+			}
+			http.Error(resp, reason, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(resp, req)
+	})
+}
+
+func (w *AuthorizationWebhook) authorize(req *http.Request) (*AuthorizationResult, error) {
+	input := AuthorizationInput{
+		User:             req.Header.Get(RemoteUserHeader),
+		Method:           req.Method,
+		Path:             req.URL.Path,
+		BundleDeployment: bundleDeploymentFromPath(req.URL.Path),
+	}
+	if groups := req.Header.Get(RemoteGroupsHeader); groups != "" {
+		input.Groups = strings.Split(groups, remoteGroupsSeparator)
+	}
+	body, err := json.Marshal(struct {
+		Input AuthorizationInput `json:"input"`
+	}{Input: input})
+	if err != nil {
+		return nil, err
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	authzReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	authzReq.Header.Set("Content-Type", "application/json")
+	authzResp, err := client.Do(authzReq)
+	if err != nil {
+		return nil, err
+	}
+	defer authzResp.Body.Close()
+	if authzResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("authorization webhook responded with status %s", authzResp.Status)
+	}
+
+	var decision struct {
+		Result *AuthorizationResult `json:"result"`
+	}
+	if err := json.NewDecoder(authzResp.Body).Decode(&decision); err != nil {
+		return nil, fmt.Errorf("decode authorization webhook response: %v", err)
+	}
+	if decision.Result == nil {
+		// OPA omits the result if the policy is not defined for the input.
+		return &AuthorizationResult{}, nil
+	}
+	return decision.Result, nil
+}
+
+// bundleDeploymentFromPath returns the name of the BundleDeployment whose
+// content is requested at path, e.g. "foo" for "/bundles/foo.tgz".
+func bundleDeploymentFromPath(path string) string {
+	name, ok := strings.CutPrefix(path, "/bundles/")
+	if !ok {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, "/")
+	return strings.TrimSuffix(name, ".tgz")
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthorizationWebhook", func() {
+	var (
+		input    AuthorizationInput
+		response string
+		status   int
+		server   *httptest.Server
+		handler  http.Handler
+	)
+
+	BeforeEach(func() {
+		input = AuthorizationInput{}
+		response = `{"result": {"allowed": true}}`
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			var body struct {
+				Input AuthorizationInput `json:"input"`
+			}
+			Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+			input = body.Input
+			resp.WriteHeader(status)
+			_, _ = resp.Write([]byte(response))
+		}))
+		DeferCleanup(server.Close)
+
+		webhook := &AuthorizationWebhook{URL: server.URL, Client: server.Client()}
+		handler = webhook.Handler(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+			_, _ = resp.Write([]byte("content"))
+		}))
+	})
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bundles/test.tgz", nil)
+		req.Header.Set(RemoteUserHeader, "alice")
+		req.Header.Set(RemoteGroupsHeader, "system:authenticated|team-a")
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	It("serves allowed requests and sends the identity of the user", func() {
+		resp := get()
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(Equal("content"))
+		Expect(input).To(Equal(AuthorizationInput{
+			User:             "alice",
+			Groups:           []string{"system:authenticated", "team-a"},
+			Method:           http.MethodGet,
+			Path:             "/bundles/test.tgz",
+			BundleDeployment: "test",
+		}))
+	})
+
+	It("rejects denied requests with the reason of the decision", func() {
+		response = `{"result": {"allowed": false, "reason": "team-a may not read test"}}`
+		resp := get()
+		Expect(resp.Code).To(Equal(http.StatusForbidden))
+		Expect(resp.Body.String()).To(ContainSubstring("team-a may not read test"))
+	})
+
+	It("rejects requests without a result", func() {
+		response = `{}`
+		Expect(get().Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects requests when the authorization service fails", func() {
+		status = http.StatusInternalServerError
+		Expect(get().Code).To(Equal(http.StatusServiceUnavailable))
+	})
+})
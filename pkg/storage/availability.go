@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultAvailabilityMinBackoff = 5 * time.Second
+	defaultAvailabilityMaxBackoff = 5 * time.Minute
+)
+
+// UnavailableError is returned by the storage operations of an
+// AvailabilityTracker that failed, or were not attempted, because the storage
+// is unavailable, e.g. because its disk is full or its backend is down.
+type UnavailableError struct {
+	// Err is the error of the operation that found the storage to be
+	// unavailable.
+	Err error
+	// RetryAt is the time at which the storage is tried again.
+	RetryAt time.Time
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("bundle storage is unavailable until %s: %v", e.RetryAt.UTC().Format(time.RFC3339), e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// AvailabilityTracker is a Storage that tracks whether the storage it wraps
+// is available. Once an operation fails because the storage is unavailable,
+// further writes fail immediately with an UnavailableError until the backoff
+// expires, so that a down storage is not retried by every BundleDeployment
+// separately. The backoff doubles with every consecutive failure.
+type AvailabilityTracker struct {
+	Storage
+
+	// MinBackoff and MaxBackoff bound the time that writes are refused after
+	// a failure. They default to 5 seconds and 5 minutes.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Log        logr.Logger
+
+	mu       sync.Mutex
+	failures int
+	lastErr  error
+	retryAt  time.Time
+	now      func() time.Time
+}
+
+var _ Storage = &AvailabilityTracker{}
+
+// WithAvailabilityTracking returns an AvailabilityTracker for s.
+func WithAvailabilityTracking(s Storage, log logr.Logger) *AvailabilityTracker {
+	return &AvailabilityTracker{Storage: s, Log: log}
+}
+
+// Available reports whether the last operation of the storage succeeded or
+// failed for reasons other than the storage being unavailable.
+func (t *AvailabilityTracker) Available() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failures == 0
+}
+
+func (t *AvailabilityTracker) Store(ctx context.Context, owner client.Object, bundle fs.FS) error {
+	return t.do(func() error { return t.Storage.Store(ctx, owner, bundle) })
+}
+
+func (t *AvailabilityTracker) StoreReport(ctx context.Context, owner client.Object, report []byte) error {
+	return t.do(func() error { return t.Storage.StoreReport(ctx, owner, report) })
+}
+
+func (t *AvailabilityTracker) StoreDiff(ctx context.Context, owner client.Object, revision int, diff []byte) error {
+	return t.do(func() error { return t.Storage.StoreDiff(ctx, owner, revision, diff) })
+}
+
+// do runs op unless the storage is backing off, and records whether it found
+// the storage to be unavailable.
+func (t *AvailabilityTracker) do(op func() error) error {
+	t.mu.Lock()
+	if t.failures > 0 && t.clock().Before(t.retryAt) {
+		err := &UnavailableError{Err: t.lastErr, RetryAt: t.retryAt}
+		t.mu.Unlock()
+		return err
+	}
+	t.mu.Unlock()
+
+	err := op()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !isUnavailable(err) {
+		if t.failures > 0 {
+			t.Log.Info("bundle storage is available again")
+		}
+		t.failures, t.lastErr = 0, nil
+		return err
+	}
+	if t.failures == 0 {
+		t.Log.Error(err, "bundle storage is unavailable")
+	}
+	t.failures++
+	t.lastErr = err
+	t.retryAt = t.clock().Add(t.backoff())
+	return &UnavailableError{Err: err, RetryAt: t.retryAt}
+}
+
+func (t *AvailabilityTracker) backoff() time.Duration {
+	minBackoff, maxBackoff := t.MinBackoff, t.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultAvailabilityMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultAvailabilityMaxBackoff
+	}
+	backoff := minBackoff
+	for i := 1; i < t.failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func (t *AvailabilityTracker) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// isUnavailable reports whether err means that the storage itself is
+// unavailable, rather than that a single operation failed.
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, errno := range []syscall.Errno{syscall.ENOSPC, syscall.EDQUOT, syscall.EROFS, syscall.EIO} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing/fstest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// failingStorage fails its stores with err and counts them.
+type failingStorage struct {
+	Storage
+	err    error
+	stores int
+}
+
+func (s *failingStorage) Store(_ context.Context, _ client.Object, _ fs.FS) error {
+	s.stores++
+	return s.err
+}
+
+var _ = Describe("AvailabilityTracker", func() {
+	var (
+		ctx     context.Context
+		owner   *rukpakv1alpha2.BundleDeployment
+		backend *failingStorage
+		tracker *AvailabilityTracker
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		owner = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		backend = &failingStorage{err: fmt.Errorf("write bundle: %w", syscall.ENOSPC)}
+		now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		tracker = WithAvailabilityTracking(backend, logr.Discard())
+		tracker.now = func() time.Time { return now }
+	})
+
+	It("refuses stores until the backoff expires", func() {
+		var unavailable *UnavailableError
+		Expect(errors.As(tracker.Store(ctx, owner, fstest.MapFS{}), &unavailable)).To(BeTrue())
+		Expect(errors.Is(unavailable, syscall.ENOSPC)).To(BeTrue())
+		Expect(unavailable.RetryAt).To(Equal(now.Add(defaultAvailabilityMinBackoff)))
+		Expect(tracker.Available()).To(BeFalse())
+
+		Expect(tracker.Store(ctx, owner, fstest.MapFS{})).To(BeAssignableToTypeOf(&UnavailableError{}))
+		Expect(backend.stores).To(Equal(1))
+
+		now = now.Add(defaultAvailabilityMinBackoff)
+		Expect(errors.As(tracker.Store(ctx, owner, fstest.MapFS{}), &unavailable)).To(BeTrue())
+		Expect(backend.stores).To(Equal(2))
+		Expect(unavailable.RetryAt).To(Equal(now.Add(2 * defaultAvailabilityMinBackoff)))
+	})
+
+	It("becomes available once a store succeeds", func() {
+		Expect(tracker.Store(ctx, owner, fstest.MapFS{})).To(HaveOccurred())
+		backend.err = nil
+		now = now.Add(defaultAvailabilityMinBackoff)
+		Expect(tracker.Store(ctx, owner, fstest.MapFS{})).To(Succeed())
+		Expect(tracker.Available()).To(BeTrue())
+	})
+
+	It("does not back off on other errors", func() {
+		backend.err = errors.New("convert bundle to tar: invalid file")
+		Expect(tracker.Store(ctx, owner, fstest.MapFS{})).To(MatchError(backend.err))
+		Expect(tracker.Store(ctx, owner, fstest.MapFS{})).To(MatchError(backend.err))
+		Expect(backend.stores).To(Equal(2))
+		Expect(tracker.Available()).To(BeTrue())
+	})
+})
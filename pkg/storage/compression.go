@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression of stored bundle content.
+type Compression string
+
+const (
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+	CompressionNone Compression = "none"
+)
+
+// ParseCompression returns the compression named s.
+func ParseCompression(s string) (Compression, error) {
+	switch c := Compression(s); c {
+	case CompressionGzip, CompressionZstd, CompressionNone:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown compression %q: must be one of %q, %q or %q", s, CompressionGzip, CompressionZstd, CompressionNone)
+}
+
+func (c Compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", c)
+}
+
+func (c Compression) newReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", c)
+}
+
+// contentType returns the content type that stored bundle content with
+// compression c is served with.
+func (c Compression) contentType() string {
+	switch c {
+	case CompressionZstd:
+		return "application/zstd"
+	case CompressionNone:
+		return "application/x-tar"
+	}
+	return "application/gzip"
+}
+
+// compressionForContentType returns the compression of bundle content that
+// was served with the given content type. Content that is served with any
+// other content type is assumed to be gzipped, which is how bundle content
+// was stored before its compression became configurable.
+func compressionForContentType(contentType string) Compression {
+	for _, c := range []Compression{CompressionZstd, CompressionNone} {
+		if contentType == c.contentType() {
+			return c
+		}
+	}
+	return CompressionGzip
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// compressedMagics are the leading bytes of the compressed file formats that
+// bundles commonly contain, such as Helm chart archives.
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b},                         // gzip
+	{0x28, 0xb5, 0x2f, 0xfd},             // zstd
+	{0x50, 0x4b, 0x03, 0x04},             // zip
+	{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}, // xz
+	{0x42, 0x5a, 0x68},                   // bzip2
+}
+
+// isMostlyCompressed reports whether most of the content of fsys, by size, is
+// already compressed, in which case compressing it again costs CPU time but
+// saves next to no space.
+func isMostlyCompressed(fsys fs.FS) (bool, error) {
+	var total, compressed int64
+	magic := make([]byte, 6)
+	if err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := io.ReadFull(f, magic)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		for _, m := range compressedMagics {
+			if bytes.HasPrefix(magic[:n], m) {
+				compressed += info.Size()
+				break
+			}
+		}
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	return compressed*2 > total, nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+var _ manager.LeaderElectionRunnable = &GarbageCollector{}
+
+// GarbageCollector periodically deletes the stored content of
+// BundleDeployments that no longer exist. It replaces the DeleteCachedBundle
+// finalizer for storages that are shared by all replicas of a provisioner, so
+// that BundleDeployments can be deleted while no provisioner is running.
+//
+// Deletes are idempotent, so the collector runs on every replica rather than
+// only on the leader.
+type GarbageCollector struct {
+	Storage interface {
+		Storer
+		Lister
+	}
+	// Reader should read from the API server rather than a cache, so that the
+	// content of a BundleDeployment that was just created is not collected.
+	Reader   client.Reader
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+func (gc *GarbageCollector) NeedLeaderElection() bool {
+	return false
+}
+
+func (gc *GarbageCollector) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := gc.Collect(ctx); err != nil {
+			gc.Log.Error(err, "failed to garbage collect bundle storage")
+		}
+	}, gc.Interval)
+	return nil
+}
+
+// Collect deletes the stored content of BundleDeployments that do not exist
+// or are being deleted.
+func (gc *GarbageCollector) Collect(ctx context.Context) error {
+	names, err := gc.Storage.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list stored content: %v", err)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	bds := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := gc.Reader.List(ctx, bds); err != nil {
+		return fmt.Errorf("list bundle deployments: %v", err)
+	}
+	live := map[string]struct{}{}
+	for _, bd := range bds.Items {
+		if bd.DeletionTimestamp.IsZero() {
+			live[bd.Name] = struct{}{}
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := live[name]; ok {
+			continue
+		}
+		owner := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := gc.Storage.Delete(ctx, owner); err != nil {
+			return fmt.Errorf("delete stored content of %q: %v", name, err)
+		}
+		gc.Log.V(1).Info("deleted stored content of removed bundle deployment", "name", name)
+	}
+	return nil
+}
@@ -1,21 +1,21 @@
 package storage
 
 import (
-	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
-	"io/fs"
 	"net/http"
 	"time"
 
-	"github.com/nlepage/go-tarfs"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/util"
 )
 
+var _ Checker = &HTTP{}
+
 type HTTP struct {
 	client      http.Client
 	requestOpts []func(*http.Request)
@@ -68,7 +68,10 @@ func NewHTTP(opts ...HTTPOption) *HTTP {
 	return s
 }
 
-func (s *HTTP) Load(ctx context.Context, owner client.Object) (fs.FS, error) {
+// Load downloads the bundle content of owner to a temporary file, from which
+// the content of files is streamed when they are read. The file is removed
+// once the returned filesystem is closed.
+func (s *HTTP) Load(ctx context.Context, owner client.Object) (util.ClosableFS, error) {
 	bundledeployment := owner.(*rukpakv1alpha2.BundleDeployment)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, bundledeployment.Status.ContentURL, nil)
 	if err != nil {
@@ -85,9 +88,32 @@ func (s *HTTP) Load(ctx context.Context, owner client.Object) (fs.FS, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
 	}
-	tarReader, err := gzip.NewReader(resp.Body)
+	bundleFile, err := util.SpoolFile("", resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("download bundle content: %v", err)
+	}
+	return util.TarFileFS(bundleFile, compressionForContentType(resp.Header.Get("Content-Type")).newReader)
+}
+
+// Check verifies that content is retrievable at url. Only the first byte of
+// the content is requested, with a GET rather than a HEAD request, so that
+// the same permissions are required as for loading it.
+func (s *HTTP) Check(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	for _, f := range s.requestOpts {
+		f(req)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
 	}
-	return tarfs.New(tarReader)
+	return nil
 }
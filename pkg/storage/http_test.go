@@ -92,6 +92,22 @@ var _ = Describe("HTTP", func() {
 					Expect(fsEqual(testFS, loadedTestFS)).To(BeTrue())
 					Expect(err).ToNot(HaveOccurred())
 				})
+				It("should pass the check", func() {
+					store := NewHTTP(opts...)
+					Expect(store.Check(ctx, bundleDeployment.Status.ContentURL)).To(Succeed())
+				})
+			})
+			Context("with a zstd-compressed bundle", func() {
+				BeforeEach(func() {
+					localStore.Compression = CompressionZstd
+					Expect(localStore.Store(ctx, bundleDeployment, testFS)).To(Succeed())
+				})
+				It("should succeed", func() {
+					store := NewHTTP(opts...)
+					loadedTestFS, err := store.Load(ctx, bundleDeployment)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(fsEqual(testFS, loadedTestFS)).To(BeTrue())
+				})
 			})
 			Context("with non-existing bundle", func() {
 				BeforeEach(func() {
@@ -103,6 +119,10 @@ var _ = Describe("HTTP", func() {
 					Expect(loadedTestFS).To(BeNil())
 					Expect(err).To(MatchError(ContainSubstring("404 Not Found")))
 				})
+				It("should fail the check", func() {
+					store := NewHTTP(opts...)
+					Expect(store.Check(ctx, bundleDeployment.Status.ContentURL)).To(MatchError(ContainSubstring("404 Not Found")))
+				})
 			})
 		})
 		Context("with incorrect bearer token", func() {
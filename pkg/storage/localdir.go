@@ -1,8 +1,6 @@
 package storage
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -11,75 +9,351 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 
-	"github.com/nlepage/go-tarfs"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/operator-framework/rukpak/pkg/util"
 )
 
-var _ Storage = &LocalDirectory{}
+var (
+	_ Storage  = &LocalDirectory{}
+	_ Lister   = &LocalDirectory{}
+	_ Retainer = &LocalDirectory{}
+)
+
+const (
+	DefaultBundleCacheDir = "/var/cache/bundles"
 
-const DefaultBundleCacheDir = "/var/cache/bundles"
+	localDirectoryReportFile = "report"
+	localDirectoryDiffsDir   = "diffs"
+)
 
 type LocalDirectory struct {
 	RootDirectory string
 	URL           url.URL
+
+	// ExternalAddress, if set, returns the current external address of the
+	// content server, whose scheme and host replace those of URL in content
+	// URLs, so that content URLs follow changes of the address.
+	ExternalAddress func(ctx context.Context) (*url.URL, error)
+
+	// Compression is the compression of stored bundle content, which defaults
+	// to gzip. Bundles whose content is mostly compressed already, such as
+	// Helm chart archives, are stored uncompressed regardless. The compression
+	// of every bundle is recorded alongside it, so that content stored with a
+	// previous compression can still be loaded.
+	Compression Compression
+
+	// RetentionLimit is the number of sources of each owner whose bundle
+	// content is retained. Retain does not keep any content if it is zero.
+	RetentionLimit int
+
+	// MaxBundleSize limits the total size in bytes of the files of the
+	// bundle content of each owner, as reported by the ContentSize of its
+	// status. Store refuses larger bundles and keeps the content that was
+	// stored before. Bundles are not limited if it is zero.
+	MaxBundleSize int64
+}
+
+// BundleTooLargeError is returned by Store for bundles that exceed the
+// MaxBundleSize of the storage.
+type BundleTooLargeError struct {
+	Size, MaxSize int64
+}
+
+func (e *BundleTooLargeError) Error() string {
+	return fmt.Sprintf("bundle content of %d bytes exceeds the limit of %d bytes", e.Size, e.MaxSize)
 }
 
-func (s *LocalDirectory) Load(_ context.Context, owner client.Object) (fs.FS, error) {
-	bundleFile, err := os.Open(s.bundlePath(owner.GetName()))
+func (s *LocalDirectory) Load(_ context.Context, owner client.Object) (util.ClosableFS, error) {
+	return loadBundle(s.bundlePath(owner.GetName()), s.compressionPath(owner.GetName()))
+}
+
+// loadBundle loads the bundle content at bundlePath, which is compressed with
+// the compression that is recorded at compressionPath. The content of files is
+// streamed from the bundle file when they are read, rather than loaded into
+// memory upfront.
+//
+// The bundle file is kept open until the returned filesystem is closed, since
+// a Store that replaces it in the meantime renames a new file over it.
+func loadBundle(bundlePath, compressionPath string) (util.ClosableFS, error) {
+	bundleFile, err := os.Open(bundlePath)
 	if err != nil {
 		return nil, err
 	}
-	defer bundleFile.Close()
-	tarReader, err := gzip.NewReader(bundleFile)
+	compression, err := readCompression(compressionPath)
 	if err != nil {
+		bundleFile.Close()
 		return nil, err
 	}
-	return tarfs.New(tarReader)
+	return util.TarFileFS(bundleFile, compression.newReader)
 }
 
 func (s *LocalDirectory) Store(_ context.Context, owner client.Object, bundle fs.FS) error {
-	buf := &bytes.Buffer{}
-	if err := util.FSToTarGZ(buf, bundle); err != nil {
-		return fmt.Errorf("convert bundle %q to tar.gz: %v", owner.GetName(), err)
+	if s.MaxBundleSize > 0 {
+		size, err := util.SizeFS(bundle)
+		if err != nil {
+			return fmt.Errorf("inspect bundle %q: %v", owner.GetName(), err)
+		}
+		if size > s.MaxBundleSize {
+			return &BundleTooLargeError{Size: size, MaxSize: s.MaxBundleSize}
+		}
 	}
 
-	bundleFile, err := os.Create(s.bundlePath(owner.GetName()))
-	if err != nil {
-		return err
+	compression := s.Compression
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	if compression != CompressionNone {
+		compressed, err := isMostlyCompressed(bundle)
+		if err != nil {
+			return fmt.Errorf("inspect bundle %q: %v", owner.GetName(), err)
+		}
+		if compressed {
+			compression = CompressionNone
+		}
 	}
-	defer bundleFile.Close()
 
-	if _, err := io.Copy(bundleFile, buf); err != nil {
+	// The archive is streamed to the file rather than buffered, so that
+	// bundles larger than the memory of the provisioner can be stored.
+	if err := writeFileAtomicFrom(s.bundlePath(owner.GetName()), 0644, func(file io.Writer) error {
+		w, err := compression.newWriter(file)
+		if err != nil {
+			return err
+		}
+		if err := util.FSToTar(w, bundle); err != nil {
+			return fmt.Errorf("convert bundle %q to tar: %v", owner.GetName(), err)
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("compress bundle %q: %v", owner.GetName(), err)
+		}
+		return nil
+	}); err != nil {
 		return err
 	}
+	if err := writeFileAtomic(s.compressionPath(owner.GetName()), []byte(compression), 0600); err != nil {
+		// The new content must not be loaded with the compression of the
+		// content that it replaced, so it is removed and stored again by
+		// the next reconcile.
+		return errors.Join(err, ignoreNotExist(os.Remove(s.bundlePath(owner.GetName()))))
+	}
 	return nil
 }
 
 func (s *LocalDirectory) Delete(_ context.Context, owner client.Object) error {
+	if err := os.RemoveAll(s.reportDir(owner.GetName())); err != nil {
+		return err
+	}
+	if err := s.deleteTmpFiles(owner.GetName()); err != nil {
+		return err
+	}
+	if err := ignoreNotExist(os.Remove(s.compressionPath(owner.GetName()))); err != nil {
+		return err
+	}
 	return ignoreNotExist(os.Remove(s.bundlePath(owner.GetName())))
 }
 
+// deleteTmpFiles deletes the temporary files of the content of the named
+// bundle, which provisioners that were killed while they stored the content
+// leave behind.
+func (s *LocalDirectory) deleteTmpFiles(bundleName string) error {
+	entries, err := os.ReadDir(s.RootDirectory)
+	if err != nil {
+		return ignoreNotExist(err)
+	}
+	patterns := []string{tmpPattern(s.bundlePath(bundleName)), tmpPattern(s.compressionPath(bundleName))}
+	for _, entry := range entries {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, entry.Name()); !ok {
+				continue
+			}
+			if err := ignoreNotExist(os.Remove(filepath.Join(s.RootDirectory, entry.Name()))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StoreReport stores the report so that it is served at <URL>/<name>/report.
+func (s *LocalDirectory) StoreReport(_ context.Context, owner client.Object, report []byte) error {
+	dir := s.reportDir(owner.GetName())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, localDirectoryReportFile), report, 0600)
+}
+
+// StoreDiff stores the diff so that it is served at
+// <URL>/<name>/diffs/<revision>.
+func (s *LocalDirectory) StoreDiff(_ context.Context, owner client.Object, revision int, diff []byte) error {
+	dir := filepath.Join(s.reportDir(owner.GetName()), localDirectoryDiffsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, strconv.Itoa(revision)), diff, 0600)
+}
+
+// List returns the names of the owners that bundle content or a report is
+// stored for.
+func (s *LocalDirectory) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.RootDirectory)
+	if err != nil {
+		return nil, ignoreNotExist(err)
+	}
+	var names []string
+	seen := map[string]struct{}{}
+	for _, entry := range entries {
+		name, isBundle := strings.CutSuffix(entry.Name(), ".tgz")
+		if isBundle == entry.IsDir() {
+			continue
+		}
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Size returns the size in bytes of the stored bundle content of owner.
+func (s *LocalDirectory) Size(_ context.Context, owner client.Object) (int64, error) {
+	info, err := os.Stat(s.bundlePath(owner.GetName()))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// ServeHTTP serves stored content. Bundle content is served with a content
+// type that names its compression.
 func (s *LocalDirectory) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if name, ok := strings.CutSuffix(strings.TrimPrefix(req.URL.Path, s.URL.Path), ".tgz"); ok && !strings.Contains(name, "/") {
+		if compression, err := s.storedCompression(name); err == nil {
+			resp.Header().Set("Content-Type", compression.contentType())
+		}
+	}
+	// Retained content is only kept for rollbacks, and not served.
+	if isRetainedPath(strings.TrimPrefix(req.URL.Path, s.URL.Path)) {
+		http.NotFound(resp, req)
+		return
+	}
 	fsys := &util.FilesOnlyFilesystem{FS: os.DirFS(s.RootDirectory)}
 	http.StripPrefix(s.URL.Path, http.FileServer(http.FS(fsys))).ServeHTTP(resp, req)
 }
 
-func (s *LocalDirectory) URLFor(_ context.Context, owner client.Object) (string, error) {
-	return fmt.Sprintf("%s%s", s.URL.String(), localDirectoryBundleFile(owner.GetName())), nil
+// isRetainedPath reports whether the request path p, relative to the root
+// directory, is in the retained content of a bundle. The path is cleaned
+// like http.FileServer cleans it.
+func isRetainedPath(p string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(path.Clean("/"+p), "/"), "/", 3)
+	return len(parts) >= 2 && parts[1] == localDirectoryRetainedDir
+}
+
+// Check reports whether the root directory of the storage is accessible, so
+// that a replica only receives content requests once it can serve them, e.g.
+// when the directory is a shared volume that is mounted over the network.
+// It is a healthz.Checker.
+func (s *LocalDirectory) Check(_ *http.Request) error {
+	info, err := os.Stat(s.RootDirectory)
+	if err != nil {
+		return fmt.Errorf("bundle storage is not accessible: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("bundle storage %q is not a directory", s.RootDirectory)
+	}
+	return nil
+}
+
+func (s *LocalDirectory) URLFor(ctx context.Context, owner client.Object) (string, error) {
+	base := s.URL
+	if s.ExternalAddress != nil {
+		addr, err := s.ExternalAddress(ctx)
+		if err != nil {
+			return "", fmt.Errorf("discover external address: %v", err)
+		}
+		base.Scheme, base.Host = addr.Scheme, addr.Host
+	}
+	return fmt.Sprintf("%s%s", base.String(), localDirectoryBundleFile(owner.GetName())), nil
 }
 
 func (s *LocalDirectory) bundlePath(bundleName string) string {
 	return filepath.Join(s.RootDirectory, localDirectoryBundleFile(bundleName))
 }
 
+// storedCompression returns the compression that the bundle content of the
+// named bundle was stored with. Content stored before its compression was
+// recorded is gzipped.
+func (s *LocalDirectory) storedCompression(bundleName string) (Compression, error) {
+	return readCompression(s.compressionPath(bundleName))
+}
+
+func readCompression(path string) (Compression, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return CompressionGzip, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ParseCompression(string(data))
+}
+
+func (s *LocalDirectory) compressionPath(bundleName string) string {
+	return filepath.Join(s.RootDirectory, fmt.Sprintf("%s.compression", bundleName))
+}
+
+func (s *LocalDirectory) reportDir(bundleName string) string {
+	return filepath.Join(s.RootDirectory, bundleName)
+}
+
 func localDirectoryBundleFile(bundleName string) string {
 	return fmt.Sprintf("%s.tgz", bundleName)
 }
 
+// writeFileAtomic writes data to the named file by renaming a temporary file
+// over it, so that content requests that are served concurrently, possibly by
+// other replicas that share the directory, never read a partially written
+// file. Temporary files are named so that List skips them.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicFrom(name, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFrom is writeFileAtomic for content that write streams to
+// the file.
+func writeFileAtomicFrom(name string, perm os.FileMode, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), tmpPattern(name))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// tmpPattern is the pattern of the names of the temporary files that the
+// named file is written to.
+func tmpPattern(name string) string {
+	return "." + filepath.Base(name) + ".tmp-*"
+}
+
 func ignoreNotExist(err error) error {
 	if errors.Is(err, os.ErrNotExist) {
 		return nil
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -15,8 +18,10 @@ import (
 	. "github.com/onsi/gomega"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
 )
@@ -61,6 +66,13 @@ var _ = Describe("LocalDirectory", func() {
 				Expect(store.Delete(ctx, owner)).To(Succeed())
 			})
 		})
+
+		Describe("Size", func() {
+			It("should fail due to file not existing", func() {
+				_, err := store.Size(ctx, owner)
+				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+			})
+		})
 	})
 	When("a bundleDeployment is stored", func() {
 		BeforeEach(func() {
@@ -70,6 +82,36 @@ var _ = Describe("LocalDirectory", func() {
 			It("should re-store a bundleDeployment FS", func() {
 				Expect(store.Store(ctx, owner, testFS)).To(Succeed())
 			})
+			It("should not leave temporary files behind", func() {
+				Expect(store.Store(ctx, owner, testFS)).To(Succeed())
+				entries, err := os.ReadDir(store.RootDirectory)
+				Expect(err).NotTo(HaveOccurred())
+				var names []string
+				for _, entry := range entries {
+					names = append(names, entry.Name())
+				}
+				Expect(names).To(ConsistOf(owner.GetName()+".tgz", owner.GetName()+".compression"))
+			})
+			It("should refuse bundles that exceed the maximum size and keep the stored content", func() {
+				store.MaxBundleSize = 10
+				err := store.Store(ctx, owner, fstest.MapFS{"large": &fstest.MapFile{Data: []byte("more than ten bytes")}})
+				var tooLarge *BundleTooLargeError
+				Expect(errors.As(err, &tooLarge)).To(BeTrue())
+				Expect(tooLarge.Size).To(Equal(int64(19)))
+
+				loadedTestFS, err := store.Load(ctx, owner)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fsEqual(testFS, loadedTestFS)).To(BeTrue())
+			})
+			It("should remove the new content if its compression cannot be recorded", func() {
+				compressionPath := filepath.Join(store.RootDirectory, owner.GetName()+".compression")
+				Expect(os.Remove(compressionPath)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(compressionPath, "blocked"), 0755)).To(Succeed())
+
+				Expect(store.Store(ctx, owner, testFS)).NotTo(Succeed())
+				_, err := store.Load(ctx, owner)
+				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+			})
 		})
 
 		Describe("Load", func() {
@@ -80,16 +122,247 @@ var _ = Describe("LocalDirectory", func() {
 			})
 		})
 
+		Describe("Size", func() {
+			It("should return the size of the stored content", func() {
+				info, err := os.Stat(filepath.Join(store.RootDirectory, fmt.Sprintf("%s.tgz", owner.GetName())))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(store.Size(ctx, owner)).To(Equal(info.Size()))
+			})
+		})
+
+		Describe("URLFor", func() {
+			It("should use the discovered external address", func() {
+				store.URL = url.URL{Scheme: "http", Host: "localhost:8080", Path: "/bundles/"}
+				store.ExternalAddress = func(context.Context) (*url.URL, error) {
+					return &url.URL{Scheme: "https", Host: "rukpak.example.com"}, nil
+				}
+				Expect(store.URLFor(ctx, owner)).To(Equal(fmt.Sprintf("https://rukpak.example.com/bundles/%s.tgz", owner.GetName())))
+			})
+			It("should fail while no external address is discovered", func() {
+				store.ExternalAddress = func(context.Context) (*url.URL, error) {
+					return nil, errors.New("no service or ingress is annotated")
+				}
+				_, err := store.URLFor(ctx, owner)
+				Expect(err).To(MatchError(ContainSubstring("discover external address: no service or ingress is annotated")))
+			})
+		})
+
 		Describe("Delete", func() {
 			It("should delete the bundleDeployment", func() {
 				Expect(store.Delete(ctx, owner)).To(Succeed())
 				_, err := os.Stat(filepath.Join(store.RootDirectory, fmt.Sprintf("%s.tgz", owner.GetName())))
 				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
 			})
+			It("should delete temporary files that were left behind", func() {
+				tmp := filepath.Join(store.RootDirectory, fmt.Sprintf(".%s.tgz.tmp-123", owner.GetName()))
+				other := filepath.Join(store.RootDirectory, ".other.tgz.tmp-123")
+				Expect(os.WriteFile(tmp, []byte("partial"), 0600)).To(Succeed())
+				Expect(os.WriteFile(other, []byte("partial"), 0600)).To(Succeed())
+				Expect(store.Delete(ctx, owner)).To(Succeed())
+				_, err := os.Stat(tmp)
+				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+				Expect(other).To(BeAnExistingFile())
+			})
+		})
+
+		Describe("StoreReport", func() {
+			BeforeEach(func() {
+				store.URL = url.URL{Path: "/bundles/"}
+				Expect(store.StoreReport(ctx, owner, []byte(`{"payloadType":"test"}`))).To(Succeed())
+			})
+			It("should serve the report", func() {
+				resp := httptest.NewRecorder()
+				store.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bundles/%s/report", owner.GetName()), nil))
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body.String()).To(Equal(`{"payloadType":"test"}`))
+			})
+			It("should delete the report with the bundleDeployment", func() {
+				Expect(store.Delete(ctx, owner)).To(Succeed())
+				_, err := os.Stat(filepath.Join(store.RootDirectory, owner.GetName()))
+				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+			})
+			It("should list the bundleDeployment once", func() {
+				Expect(store.List(ctx)).To(Equal([]string{owner.GetName()}))
+			})
+		})
+
+		Describe("StoreDiff", func() {
+			BeforeEach(func() {
+				store.URL = url.URL{Path: "/bundles/"}
+				Expect(store.StoreDiff(ctx, owner, 2, []byte("diff 2"))).To(Succeed())
+				Expect(store.StoreDiff(ctx, owner, 3, []byte("diff 3"))).To(Succeed())
+			})
+			It("should serve the diff of every revision", func() {
+				for revision, expected := range map[int]string{2: "diff 2", 3: "diff 3"} {
+					resp := httptest.NewRecorder()
+					store.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bundles/%s/diffs/%d", owner.GetName(), revision), nil))
+					Expect(resp.Code).To(Equal(http.StatusOK))
+					Expect(resp.Body.String()).To(Equal(expected))
+				}
+			})
+			It("should delete the diffs with the bundleDeployment", func() {
+				Expect(store.Delete(ctx, owner)).To(Succeed())
+				_, err := os.Stat(filepath.Join(store.RootDirectory, owner.GetName()))
+				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+			})
+		})
+
+		Describe("Retain", func() {
+			It("should not retain content without a retention limit", func() {
+				Expect(store.Retain(ctx, owner, "v1", []byte("metadata"))).To(Succeed())
+				Expect(store.Retained(ctx, owner)).To(BeEmpty())
+			})
+			It("should load the retained content after it was replaced", func() {
+				store.RetentionLimit = 2
+				Expect(store.Retain(ctx, owner, "v1", []byte("metadata v1"))).To(Succeed())
+				Expect(store.Store(ctx, owner, fstest.MapFS{"v2": &fstest.MapFile{Data: []byte("v2")}})).To(Succeed())
+
+				retainedFS, metadata, err := store.LoadRetained(ctx, owner, "v1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fsEqual(testFS, retainedFS)).To(BeTrue())
+				Expect(metadata).To(Equal([]byte("metadata v1")))
+			})
+			It("should keep the most recently retained sources", func() {
+				store.RetentionLimit = 2
+				for _, source := range []string{"v1", "v2", "v3", "v2"} {
+					Expect(store.Retain(ctx, owner, source, []byte(source))).To(Succeed())
+				}
+				Expect(store.Retained(ctx, owner)).To(Equal([]string{"v2", "v3"}))
+				_, _, err := store.LoadRetained(ctx, owner, "v1")
+				Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+			})
+			It("should not serve the retained content", func() {
+				store.RetentionLimit = 2
+				store.URL = url.URL{Path: "/bundles/"}
+				Expect(store.Retain(ctx, owner, "v1", []byte("metadata v1"))).To(Succeed())
+				for _, p := range []string{"retained/v1/bundle.tgz", "retained/v1/metadata", "./retained/v1/metadata", "../" + owner.GetName() + "/retained/v1/metadata"} {
+					resp := httptest.NewRecorder()
+					store.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/bundles/%s/%s", owner.GetName(), p), nil))
+					Expect(resp.Code).To(Equal(http.StatusNotFound), p)
+				}
+			})
+			It("should delete the retained content with the bundleDeployment", func() {
+				store.RetentionLimit = 2
+				Expect(store.Retain(ctx, owner, "v1", []byte("v1"))).To(Succeed())
+				Expect(store.Delete(ctx, owner)).To(Succeed())
+				Expect(store.Retained(ctx, owner)).To(BeEmpty())
+			})
 		})
 	})
 })
 
+var _ = Describe("LocalDirectory readiness", func() {
+	It("should be ready while its root directory is accessible", func() {
+		store := LocalDirectory{RootDirectory: GinkgoT().TempDir()}
+		Expect(store.Check(nil)).To(Succeed())
+
+		store.RootDirectory = filepath.Join(store.RootDirectory, "missing")
+		Expect(store.Check(nil)).To(MatchError(ContainSubstring("bundle storage is not accessible")))
+	})
+})
+
+var _ = Describe("LocalDirectory compression", func() {
+	var (
+		ctx   context.Context
+		owner *rukpakv1alpha2.BundleDeployment
+		store LocalDirectory
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		owner = &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		store = LocalDirectory{RootDirectory: GinkgoT().TempDir(), URL: url.URL{Path: "/bundles/"}}
+	})
+
+	DescribeTable("should load and serve content with the compression it was stored with",
+		func(compression Compression, contentType string) {
+			testFS := generateFS()
+			store.Compression = compression
+			Expect(store.Store(ctx, owner, testFS)).To(Succeed())
+
+			store.Compression = CompressionGzip
+			loadedTestFS, err := store.Load(ctx, owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fsEqual(testFS, loadedTestFS)).To(BeTrue())
+
+			resp := httptest.NewRecorder()
+			store.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/bundles/test.tgz", nil))
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Header().Get("Content-Type")).To(Equal(contentType))
+		},
+		Entry("default", Compression(""), "application/gzip"),
+		Entry("gzip", CompressionGzip, "application/gzip"),
+		Entry("zstd", CompressionZstd, "application/zstd"),
+		Entry("none", CompressionNone, "application/x-tar"),
+	)
+
+	It("should store content that is mostly compressed already uncompressed", func() {
+		chart := make([]byte, 1024)
+		copy(chart, []byte{0x1f, 0x8b})
+		store.Compression = CompressionZstd
+		Expect(store.Store(ctx, owner, fstest.MapFS{
+			"charts/chart.tgz": &fstest.MapFile{Data: chart},
+			"values.yaml":      &fstest.MapFile{Data: []byte("replicas: 1\n")},
+		})).To(Succeed())
+		Expect(store.storedCompression(owner.GetName())).To(Equal(CompressionNone))
+	})
+
+	It("should load content stored before its compression was recorded as gzipped", func() {
+		testFS := generateFS()
+		Expect(store.Store(ctx, owner, testFS)).To(Succeed())
+		Expect(os.Remove(store.compressionPath(owner.GetName()))).To(Succeed())
+		loadedTestFS, err := store.Load(ctx, owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fsEqual(testFS, loadedTestFS)).To(BeTrue())
+	})
+
+	It("should delete the recorded compression with the content", func() {
+		Expect(store.Store(ctx, owner, generateFS())).To(Succeed())
+		Expect(store.Delete(ctx, owner)).To(Succeed())
+		_, err := os.Stat(store.compressionPath(owner.GetName()))
+		Expect(err).To(WithTransform(func(err error) bool { return errors.Is(err, os.ErrNotExist) }, BeTrue()))
+		Expect(store.List(ctx)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("GarbageCollector", func() {
+	var (
+		ctx   context.Context
+		store *LocalDirectory
+		gc    *GarbageCollector
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		store = &LocalDirectory{RootDirectory: GinkgoT().TempDir()}
+		for _, name := range []string{"live", "deleting", "deleted"} {
+			owner := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			Expect(store.Store(ctx, owner, generateFS())).To(Succeed())
+			Expect(store.StoreReport(ctx, owner, []byte("report"))).To(Succeed())
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(rukpakv1alpha2.AddToScheme(scheme)).To(Succeed())
+		gc = &GarbageCollector{
+			Storage: store,
+			Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "live"}},
+				&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{
+					Name:              "deleting",
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Finalizers:        []string{"test"},
+				}},
+			).Build(),
+			Log: GinkgoLogr,
+		}
+	})
+
+	It("should delete the content of bundleDeployments that do not exist or are being deleted", func() {
+		Expect(gc.Collect(ctx)).To(Succeed())
+		Expect(store.List(ctx)).To(Equal([]string{"live"}))
+	})
+})
+
 func generateFS() fs.FS {
 	gen := fstest.MapFS{}
 
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+const (
+	localDirectoryRetainedDir             = "retained"
+	localDirectoryRetainedBundleFile      = "bundle.tgz"
+	localDirectoryRetainedCompressionFile = "compression"
+	localDirectoryRetainedMetadataFile    = "metadata"
+)
+
+// Retain keeps the bundle content that is stored for owner in
+// <name>/retained/<source>, and deletes the retained content of the sources
+// beyond RetentionLimit that were retained the longest time ago. The content
+// is hard linked rather than copied where possible, since stored content is
+// replaced rather than modified.
+func (s *LocalDirectory) Retain(_ context.Context, owner client.Object, source string, metadata []byte) error {
+	if s.RetentionLimit <= 0 {
+		return nil
+	}
+	name := owner.GetName()
+	dir := s.retainedSourceDir(name, source)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := linkOrCopy(s.bundlePath(name), filepath.Join(dir, localDirectoryRetainedBundleFile)); err != nil {
+		return err
+	}
+	if err := linkOrCopy(s.compressionPath(name), filepath.Join(dir, localDirectoryRetainedCompressionFile)); ignoreNotExist(err) != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, localDirectoryRetainedMetadataFile), metadata, 0600); err != nil {
+		return err
+	}
+	// The modification time of the directory orders the retained sources.
+	now := time.Now()
+	if err := os.Chtimes(dir, now, now); err != nil {
+		return err
+	}
+
+	sources, err := s.retained(name)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources[min(len(sources), s.RetentionLimit):] {
+		if err := os.RemoveAll(s.retainedSourceDir(name, source)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retained returns the sources that bundle content is retained for, most
+// recently retained first.
+func (s *LocalDirectory) Retained(_ context.Context, owner client.Object) ([]string, error) {
+	return s.retained(owner.GetName())
+}
+
+func (s *LocalDirectory) retained(bundleName string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.reportDir(bundleName), localDirectoryRetainedDir))
+	if err != nil {
+		return nil, ignoreNotExist(err)
+	}
+	type retainedSource struct {
+		name    string
+		modTime time.Time
+	}
+	var sources []retainedSource
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, ignoreNotExist(err)
+		}
+		sources = append(sources, retainedSource{entry.Name(), info.ModTime()})
+	}
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].modTime.After(sources[j].modTime)
+	})
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.name)
+	}
+	return names, nil
+}
+
+// LoadRetained returns the bundle content that is retained for source and its
+// metadata. The error wraps fs.ErrNotExist if no content is retained for
+// source.
+func (s *LocalDirectory) LoadRetained(_ context.Context, owner client.Object, source string) (util.ClosableFS, []byte, error) {
+	dir := s.retainedSourceDir(owner.GetName(), source)
+	metadata, err := os.ReadFile(filepath.Join(dir, localDirectoryRetainedMetadataFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle, err := loadBundle(filepath.Join(dir, localDirectoryRetainedBundleFile), filepath.Join(dir, localDirectoryRetainedCompressionFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	return bundle, metadata, nil
+}
+
+func (s *LocalDirectory) retainedSourceDir(bundleName, source string) string {
+	return filepath.Join(s.reportDir(bundleName), localDirectoryRetainedDir, filepath.Base(source))
+}
+
+// linkOrCopy replaces dst with a hard link to src, or with a copy of src if
+// the file system does not support hard links.
+func linkOrCopy(src, dst string) error {
+	if err := ignoreNotExist(os.Remove(dst)); err != nil {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil || errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileAtomicFrom(dst, 0644, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
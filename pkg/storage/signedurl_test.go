@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("URLSigner", func() {
+	var (
+		signer  *URLSigner
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		signer = &URLSigner{Key: []byte("0123456789abcdef0123456789abcdef"), TTL: time.Minute}
+		handler = signer.Handler(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			_, _ = resp.Write([]byte(req.URL.Path))
+		}))
+	})
+
+	get := func(target string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, target, nil))
+		return resp
+	}
+
+	It("serves signed URLs with the prefix stripped", func() {
+		signed, expires, err := signer.Sign("https://core.rukpak-system.svc/bundles/test.tgz", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expires).To(BeTemporally("~", time.Now().Add(time.Minute), 2*time.Second))
+		Expect(signed).To(HavePrefix("https://core.rukpak-system.svc/signed/bundles/test.tgz?"))
+
+		resp := get(signed)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(Equal("/bundles/test.tgz"))
+	})
+
+	It("rejects expired URLs", func() {
+		signed, _, err := signer.Sign("https://core.rukpak-system.svc/bundles/test.tgz", time.Now().Add(-2*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		resp := get(signed)
+		Expect(resp.Code).To(Equal(http.StatusForbidden))
+		Expect(resp.Body.String()).To(ContainSubstring("URL has expired"))
+	})
+
+	It("rejects URLs whose path or expiry was changed", func() {
+		signed, _, err := signer.Sign("https://core.rukpak-system.svc/bundles/test.tgz", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		resp := get(strings.Replace(signed, "test.tgz", "other.tgz", 1))
+		Expect(resp.Code).To(Equal(http.StatusForbidden))
+
+		u, err := url.Parse(signed)
+		Expect(err).NotTo(HaveOccurred())
+		q := u.Query()
+		q.Set("expires", "99999999999")
+		u.RawQuery = q.Encode()
+		resp = get(u.String())
+		Expect(resp.Code).To(Equal(http.StatusForbidden))
+		Expect(resp.Body.String()).To(ContainSubstring("URL has no valid signature"))
+	})
+
+	It("rejects URLs signed with another key", func() {
+		other := &URLSigner{Key: []byte("fedcba9876543210fedcba9876543210"), TTL: time.Minute}
+		signed, _, err := other.Sign("https://core.rukpak-system.svc/bundles/test.tgz", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(get(signed).Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects unsigned URLs", func() {
+		Expect(get("/signed/bundles/test.tgz").Code).To(Equal(http.StatusForbidden))
+		Expect(get("/bundles/test.tgz").Code).To(Equal(http.StatusForbidden))
+	})
+	It("rejects requests other than GET and HEAD", func() {
+		signed, _, err := signer.Sign("https://core.rukpak-system.svc/bundles/test.tgz", time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, signed, nil))
+		Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/pkg/util"
 )
 
 type Storage interface {
@@ -14,17 +16,61 @@ type Storage interface {
 }
 
 type Loader interface {
-	Load(ctx context.Context, owner client.Object) (fs.FS, error)
+	// Load returns the bundle content of owner. Like the bundles of unpack
+	// results, the returned filesystem may stream the content of files when
+	// they are read, so files must be closed, and so must the filesystem
+	// once it is no longer used.
+	Load(ctx context.Context, owner client.Object) (util.ClosableFS, error)
 }
 
 type Storer interface {
 	Store(ctx context.Context, owner client.Object, bundle fs.FS) error
 	Delete(ctx context.Context, owner client.Object) error
 
+	// StoreReport stores the install report of the most recent install or
+	// upgrade of owner, replacing any previous report. Reports are deleted
+	// along with the bundle content of owner.
+	StoreReport(ctx context.Context, owner client.Object, report []byte) error
+
+	// StoreDiff stores the diff between the manifests of the previous release
+	// of owner and the given revision, which is about to be deployed. Diffs
+	// of all revisions are kept until the bundle content of owner is deleted.
+	StoreDiff(ctx context.Context, owner client.Object, revision int, diff []byte) error
+
 	http.Handler
 	URLFor(ctx context.Context, owner client.Object) (string, error)
 }
 
+// Lister is implemented by storages that can enumerate their content, which
+// allows content of deleted owners to be garbage collected.
+type Lister interface {
+	// List returns the names of the owners that content is stored for.
+	List(ctx context.Context) ([]string, error)
+}
+
+// Retainer is implemented by storages that keep the bundle content that was
+// stored for the previous sources of an owner, so that an owner can be rolled
+// back to one of them without unpacking it again.
+type Retainer interface {
+	// Retain keeps the bundle content that is currently stored for owner as
+	// the content of source, a key that identifies the source that it was
+	// unpacked from, along with metadata about it. Only the content of the
+	// most recently retained sources is kept.
+	Retain(ctx context.Context, owner client.Object, source string, metadata []byte) error
+	// Retained returns the sources that bundle content is retained for,
+	// most recently retained first.
+	Retained(ctx context.Context, owner client.Object) ([]string, error)
+	// LoadRetained returns the bundle content that is retained for source
+	// and its metadata. The filesystem must be closed like the one of Load.
+	LoadRetained(ctx context.Context, owner client.Object, source string) (util.ClosableFS, []byte, error)
+}
+
+// Checker verifies that content is retrievable at the URL that a storage
+// serves it at.
+type Checker interface {
+	Check(ctx context.Context, url string) error
+}
+
 type fallbackLoaderStorage struct {
 	Storage
 	fallbackLoader Loader
@@ -37,7 +83,7 @@ func WithFallbackLoader(s Storage, fallback Loader) Storage {
 	}
 }
 
-func (s *fallbackLoaderStorage) Load(ctx context.Context, owner client.Object) (fs.FS, error) {
+func (s *fallbackLoaderStorage) Load(ctx context.Context, owner client.Object) (util.ClosableFS, error) {
 	fsys, err := s.Storage.Load(ctx, owner)
 	if err != nil {
 		return s.fallbackLoader.Load(ctx, owner)
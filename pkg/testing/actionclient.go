@@ -0,0 +1,133 @@
+package testing
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	helmclient "github.com/operator-framework/helm-operator-plugins/pkg/client"
+)
+
+var (
+	_ helmclient.ActionClientGetter = &ActionClientGetter{}
+	_ helmclient.ActionInterface    = &ActionClient{}
+)
+
+// ActionClientGetter is a helmclient.ActionClientGetter that returns an
+// ActionClient per object name, so that the releases of an object are kept
+// across reconciles.
+//
+// The zero value is ready to use.
+type ActionClientGetter struct {
+	mu      sync.Mutex
+	clients map[string]*ActionClient
+}
+
+func (g *ActionClientGetter) ActionClientFor(_ context.Context, obj client.Object) (helmclient.ActionInterface, error) {
+	return g.ActionClient(obj.GetName()), nil
+}
+
+// ActionClient returns the client for the object with the given name.
+func (g *ActionClientGetter) ActionClient(name string) *ActionClient {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.clients == nil {
+		g.clients = map[string]*ActionClient{}
+	}
+	cl, ok := g.clients[name]
+	if !ok {
+		cl = NewActionClient()
+		g.clients[name] = cl
+	}
+	return cl
+}
+
+// ActionClient is a helmclient.ActionInterface that runs the Helm actions
+// against an in-memory release store and does not contact a cluster. Charts
+// are rendered, post-rendered and recorded like they are by Helm, so the
+// manifests of the returned releases can be inspected.
+type ActionClient struct {
+	conf *action.Configuration
+
+	mu         sync.Mutex
+	reconciled []*release.Release
+}
+
+// NewActionClient returns an ActionClient without any releases.
+func NewActionClient() *ActionClient {
+	return &ActionClient{
+		conf: &action.Configuration{
+			Releases:     storage.Init(driver.NewMemory()),
+			KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+			Capabilities: chartutil.DefaultCapabilities.Copy(),
+			Log:          func(string, ...interface{}) {},
+		},
+	}
+}
+
+func (c *ActionClient) Get(name string, opts ...helmclient.GetOption) (*release.Release, error) {
+	get := action.NewGet(c.conf)
+	for _, o := range opts {
+		if err := o(get); err != nil {
+			return nil, err
+		}
+	}
+	return get.Run(name)
+}
+
+func (c *ActionClient) Install(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.InstallOption) (*release.Release, error) {
+	install := action.NewInstall(c.conf)
+	for _, o := range opts {
+		if err := o(install); err != nil {
+			return nil, err
+		}
+	}
+	install.ReleaseName = name
+	install.Namespace = namespace
+	return install.Run(chrt, vals)
+}
+
+func (c *ActionClient) Upgrade(name, namespace string, chrt *chart.Chart, vals map[string]interface{}, opts ...helmclient.UpgradeOption) (*release.Release, error) {
+	upgrade := action.NewUpgrade(c.conf)
+	for _, o := range opts {
+		if err := o(upgrade); err != nil {
+			return nil, err
+		}
+	}
+	upgrade.Namespace = namespace
+	return upgrade.Run(name, chrt, vals)
+}
+
+func (c *ActionClient) Uninstall(name string, opts ...helmclient.UninstallOption) (*release.UninstallReleaseResponse, error) {
+	uninstall := action.NewUninstall(c.conf)
+	for _, o := range opts {
+		if err := o(uninstall); err != nil {
+			return nil, err
+		}
+	}
+	return uninstall.Run(name)
+}
+
+// Reconcile records rel, which can be retrieved with Reconciled.
+func (c *ActionClient) Reconcile(rel *release.Release) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconciled = append(c.reconciled, rel)
+	return nil
+}
+
+// Reconciled returns the releases that were passed to Reconcile, in order.
+func (c *ActionClient) Reconciled() []*release.Release {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*release.Release(nil), c.reconciled...)
+}
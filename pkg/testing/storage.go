@@ -0,0 +1,162 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing/fstest"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/rukpak/pkg/storage"
+	"github.com/operator-framework/rukpak/pkg/util"
+)
+
+var _ storage.Storage = &Storage{}
+
+// Storage is a storage.Storage that keeps bundle content, install reports and
+// upgrade diffs in memory. Bundles are served at <URL>/<name>.tgz, reports at
+// <URL>/<name>/report and diffs at <URL>/<name>/diffs/<revision>, as by
+// storage.LocalDirectory.
+//
+// The zero value is ready to use.
+type Storage struct {
+	URL url.URL
+
+	mu      sync.Mutex
+	bundles map[string]fstest.MapFS
+	reports map[string][]byte
+	diffs   map[string]map[int][]byte
+}
+
+func (s *Storage) Load(_ context.Context, owner client.Object) (util.ClosableFS, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bundle, ok := s.bundles[owner.GetName()]
+	if !ok {
+		return nil, fmt.Errorf("load bundle %q: %w", owner.GetName(), fs.ErrNotExist)
+	}
+	return util.NopCloserFS(bundle), nil
+}
+
+func (s *Storage) Store(_ context.Context, owner client.Object, bundle fs.FS) error {
+	mapFS := fstest.MapFS{}
+	if err := fs.WalkDir(bundle, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file := &fstest.MapFile{Mode: info.Mode(), ModTime: info.ModTime()}
+		if !d.IsDir() {
+			if file.Data, err = fs.ReadFile(bundle, path); err != nil {
+				return err
+			}
+		}
+		mapFS[path] = file
+		return nil
+	}); err != nil {
+		return fmt.Errorf("copy bundle %q: %v", owner.GetName(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bundles == nil {
+		s.bundles = map[string]fstest.MapFS{}
+	}
+	s.bundles[owner.GetName()] = mapFS
+	return nil
+}
+
+func (s *Storage) Delete(_ context.Context, owner client.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bundles, owner.GetName())
+	delete(s.reports, owner.GetName())
+	delete(s.diffs, owner.GetName())
+	return nil
+}
+
+func (s *Storage) StoreReport(_ context.Context, owner client.Object, report []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reports == nil {
+		s.reports = map[string][]byte{}
+	}
+	s.reports[owner.GetName()] = append([]byte(nil), report...)
+	return nil
+}
+
+// Report returns the install report that was most recently stored for the
+// owner with the given name, or nil if there is none.
+func (s *Storage) Report(name string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reports[name]
+}
+
+func (s *Storage) StoreDiff(_ context.Context, owner client.Object, revision int, diff []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.diffs == nil {
+		s.diffs = map[string]map[int][]byte{}
+	}
+	if s.diffs[owner.GetName()] == nil {
+		s.diffs[owner.GetName()] = map[int][]byte{}
+	}
+	s.diffs[owner.GetName()][revision] = append([]byte(nil), diff...)
+	return nil
+}
+
+// Diff returns the upgrade diff that was stored for the given revision of the
+// owner with the given name, or nil if there is none.
+func (s *Storage) Diff(name string, revision int) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.diffs[name][revision]
+}
+
+func (s *Storage) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, s.URL.Path), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bundleName, ok := strings.CutSuffix(name, ".tgz"); ok {
+		if bundle, ok := s.bundles[bundleName]; ok {
+			buf := &bytes.Buffer{}
+			if err := util.FSToTarGZ(buf, bundle); err != nil {
+				http.Error(resp, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			_, _ = resp.Write(buf.Bytes())
+			return
+		}
+	}
+	if reportName, ok := strings.CutSuffix(name, "/report"); ok {
+		if report, ok := s.reports[reportName]; ok {
+			_, _ = resp.Write(report)
+			return
+		}
+	}
+	if diffName, revision, ok := strings.Cut(name, "/diffs/"); ok {
+		if rev, err := strconv.Atoi(revision); err == nil {
+			if diff, ok := s.diffs[diffName][rev]; ok {
+				_, _ = resp.Write(diff)
+				return
+			}
+		}
+	}
+	http.NotFound(resp, req)
+}
+
+func (s *Storage) URLFor(_ context.Context, owner client.Object) (string, error) {
+	return fmt.Sprintf("%s%s.tgz", s.URL.String(), owner.GetName()), nil
+}
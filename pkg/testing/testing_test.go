@@ -0,0 +1,130 @@
+package testing_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/source"
+	rukpaktesting "github.com/operator-framework/rukpak/pkg/testing"
+)
+
+func TestUnpacker(t *testing.T) {
+	u := &rukpaktesting.Unpacker{}
+	bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	_, err := u.Unpack(context.Background(), bd)
+	require.ErrorContains(t, err, "no unpack result configured")
+
+	result := &source.Result{State: source.StateUnpacked, Bundle: fstest.MapFS{}}
+	u.SetResult("test", result)
+	got, err := u.Unpack(context.Background(), bd)
+	require.NoError(t, err)
+	require.Equal(t, result, got)
+
+	u.SetError("test", errors.New("boom"))
+	_, err = u.Unpack(context.Background(), bd)
+	require.EqualError(t, err, "boom")
+
+	require.NoError(t, u.Cleanup(context.Background(), bd))
+	require.Equal(t, []string{"test", "test", "test"}, u.Unpacked())
+	require.Equal(t, []string{"test"}, u.CleanedUp())
+}
+
+func TestStorage(t *testing.T) {
+	s := &rukpaktesting.Storage{URL: url.URL{Scheme: "http", Host: "localhost", Path: "/bundles/"}}
+	bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	ctx := context.Background()
+
+	_, err := s.Load(ctx, bd)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, s.Store(ctx, bd, fstest.MapFS{"manifests/cm.yaml": &fstest.MapFile{Data: []byte("kind: ConfigMap")}}))
+	bundle, err := s.Load(ctx, bd)
+	require.NoError(t, err)
+	data, err := fs.ReadFile(bundle, "manifests/cm.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "kind: ConfigMap", string(data))
+
+	require.NoError(t, s.StoreReport(ctx, bd, []byte("report")))
+	require.Equal(t, []byte("report"), s.Report("test"))
+	require.NoError(t, s.StoreDiff(ctx, bd, 2, []byte("diff")))
+	require.Equal(t, []byte("diff"), s.Diff("test", 2))
+
+	contentURL, err := s.URLFor(ctx, bd)
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost/bundles/test.tgz", contentURL)
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	for path, expected := range map[string]int{
+		"/bundles/test.tgz":     http.StatusOK,
+		"/bundles/test/report":  http.StatusOK,
+		"/bundles/test/diffs/2": http.StatusOK,
+		"/bundles/test/diffs/3": http.StatusNotFound,
+		"/bundles/other.tgz":    http.StatusNotFound,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		require.Equal(t, expected, resp.StatusCode, path)
+	}
+
+	require.NoError(t, s.Delete(ctx, bd))
+	_, err = s.Load(ctx, bd)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.Nil(t, s.Report("test"))
+	require.Nil(t, s.Diff("test", 2))
+}
+
+func TestActionClient(t *testing.T) {
+	g := &rukpaktesting.ActionClientGetter{}
+	bd := &rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	cl, err := g.ActionClientFor(context.Background(), bd)
+	require.NoError(t, err)
+	require.Same(t, g.ActionClient("test"), cl)
+
+	chrt := &chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test", Version: "1.0.0"},
+		Templates: []*chart.File{{Name: "templates/cm.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.name }}\n")}},
+	}
+
+	_, err = cl.Get("test")
+	require.ErrorIs(t, err, driver.ErrReleaseNotFound)
+
+	rel, err := cl.Install("test", "ns", chrt, map[string]interface{}{"name": "a"})
+	require.NoError(t, err)
+	require.Equal(t, 1, rel.Version)
+	require.Contains(t, rel.Manifest, "name: a")
+
+	rel, err = cl.Upgrade("test", "ns", chrt, map[string]interface{}{"name": "b"})
+	require.NoError(t, err)
+	require.Equal(t, 2, rel.Version)
+	require.Contains(t, rel.Manifest, "name: b")
+
+	rel, err = cl.Get("test")
+	require.NoError(t, err)
+	require.Equal(t, release.StatusDeployed, rel.Info.Status)
+
+	require.NoError(t, cl.Reconcile(rel))
+	require.Equal(t, []*release.Release{rel}, g.ActionClient("test").Reconciled())
+
+	_, err = cl.Uninstall("test")
+	require.NoError(t, err)
+	_, err = cl.Get("test")
+	require.ErrorIs(t, err, driver.ErrReleaseNotFound)
+}
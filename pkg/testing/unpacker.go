@@ -0,0 +1,87 @@
+// Package testing provides in-memory implementations of the interfaces that a
+// BundleDeployment provisioner is built from, so that provisioner authors can
+// unit test their reconcile flows without a cluster, registry or git server.
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+	"github.com/operator-framework/rukpak/pkg/source"
+)
+
+var _ source.Unpacker = &Unpacker{}
+
+// Unpacker is a source.Unpacker that returns preconfigured results.
+//
+// The zero value is ready to use and fails to unpack every BundleDeployment.
+type Unpacker struct {
+	mu       sync.Mutex
+	results  map[string]*source.Result
+	errs     map[string]error
+	unpacked []string
+	cleaned  []string
+}
+
+// SetResult configures the result that is returned when the BundleDeployment
+// with the given name is unpacked.
+func (u *Unpacker) SetResult(name string, result *source.Result) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.results == nil {
+		u.results = map[string]*source.Result{}
+	}
+	u.results[name] = result
+	delete(u.errs, name)
+}
+
+// SetError configures the error that is returned when the BundleDeployment
+// with the given name is unpacked.
+func (u *Unpacker) SetError(name string, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.errs == nil {
+		u.errs = map[string]error{}
+	}
+	u.errs[name] = err
+	delete(u.results, name)
+}
+
+func (u *Unpacker) Unpack(_ context.Context, bd *rukpakv1alpha2.BundleDeployment) (*source.Result, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.unpacked = append(u.unpacked, bd.Name)
+	if err, ok := u.errs[bd.Name]; ok {
+		return nil, err
+	}
+	result, ok := u.results[bd.Name]
+	if !ok {
+		return nil, fmt.Errorf("no unpack result configured for bundle deployment %q", bd.Name)
+	}
+	return result, nil
+}
+
+func (u *Unpacker) Cleanup(_ context.Context, bd *rukpakv1alpha2.BundleDeployment) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.cleaned = append(u.cleaned, bd.Name)
+	return nil
+}
+
+// Unpacked returns the names of the unpacked BundleDeployments, in the order
+// in which they were unpacked.
+func (u *Unpacker) Unpacked() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.unpacked...)
+}
+
+// CleanedUp returns the names of the BundleDeployments that were cleaned up,
+// in the order in which they were cleaned up.
+func (u *Unpacker) CleanedUp() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.cleaned...)
+}
@@ -1,9 +1,12 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"testing/fstest"
@@ -88,3 +91,217 @@ func (f baseDirFS) Open(name string) (fs.File, error) {
 	}
 	return nil, fs.ErrNotExist
 }
+
+// SizedFS is a filesystem whose total size of regular files is known.
+type SizedFS interface {
+	fs.FS
+	Size() int64
+}
+
+type sizedFS struct {
+	fs.FS
+	size int64
+}
+
+func (f sizedFS) Size() int64 {
+	return f.size
+}
+
+// WithSize returns fsys as a SizedFS of the given size, so that SizeFS does
+// not walk it again. The size must have been computed by SizeFS.
+func WithSize(fsys fs.FS, size int64) SizedFS {
+	return sizedFS{FS: fsys, size: size}
+}
+
+// SizeFS returns the total size in bytes of the regular files of fsys. The
+// size of a SizedFS is returned without walking it.
+func SizeFS(fsys fs.FS) (int64, error) {
+	if sized, ok := fsys.(SizedFS); ok {
+		return sized.Size(), nil
+	}
+	var size int64
+	if err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("compute bundle size: %v", err)
+	}
+	return size, nil
+}
+
+// FilterFS returns an fs.FS that only exposes the regular files of fsys whose
+// paths match at least one of the include patterns and none of the exclude
+// patterns. An empty include list includes every file. A pattern matches a
+// path if it matches the path itself or any of its parent directories, using
+// the syntax of path.Match. Directories remain visible so that the filtered
+// filesystem can still be walked, unless they are excluded.
+//
+// If both include and exclude are empty, fsys is returned unchanged.
+func FilterFS(fsys fs.FS, include, exclude []string) (fs.FS, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return fsys, nil
+	}
+	filter, err := NewPathFilter(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &filterFS{fsys: fsys, filter: filter}, nil
+}
+
+// PathFilter matches paths against include and exclude patterns with the
+// semantics of FilterFS.
+type PathFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewPathFilter returns a PathFilter for the given patterns, or an error if
+// any of them is malformed.
+func NewPathFilter(include, exclude []string) (*PathFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %v", pattern, err)
+		}
+	}
+	return &PathFilter{include: include, exclude: exclude}, nil
+}
+
+// Visible reports whether the slash-separated path name, relative to the
+// root of the filtered filesystem, is exposed by FilterFS. Directories are
+// visible unless they are excluded.
+func (f *PathFilter) Visible(name string, isDir bool) bool {
+	if name == "." {
+		return true
+	}
+	if matchesPathOrParent(f.exclude, name) {
+		return false
+	}
+	return isDir || len(f.include) == 0 || matchesPathOrParent(f.include, name)
+}
+
+// pathMatcher decides which paths a filterFS exposes.
+type pathMatcher interface {
+	Visible(name string, isDir bool) bool
+}
+
+type filterFS struct {
+	fsys   fs.FS
+	filter pathMatcher
+}
+
+func (f *filterFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !f.visible(name, stat.IsDir()) {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if stat.IsDir() {
+		return &readDirFile{File: file, readDir: func() ([]fs.DirEntry, error) { return f.ReadDir(name) }}, nil
+	}
+	return file, nil
+}
+
+func (f *filterFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if !f.visible(name, true) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.filterEntries(name, entries), nil
+}
+
+func (f *filterFS) ReadLink(name string) (string, error) {
+	rlfs, ok := f.fsys.(ReadLinkFS)
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+	}
+	if !f.visible(name, false) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	return rlfs.ReadLink(name)
+}
+
+func (f *filterFS) filterEntries(dir string, entries []fs.DirEntry) []fs.DirEntry {
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if f.visible(path.Join(dir, entry.Name()), entry.IsDir()) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+func (f *filterFS) visible(name string, isDir bool) bool {
+	return f.filter.Visible(name, isDir)
+}
+
+func matchesPathOrParent(patterns []string, name string) bool {
+	for p := name; p != "."; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			// Patterns are validated by FilterFS, so errors can be ignored.
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readDirFile is a directory fs.File whose entries are listed by readDir
+// when they are first requested.
+type readDirFile struct {
+	fs.File
+	readDir func() ([]fs.DirEntry, error)
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *readDirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.readDir()
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
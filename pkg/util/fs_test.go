@@ -0,0 +1,153 @@
+package util
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifests/deployment.yaml": &fstest.MapFile{},
+		"manifests/service.yaml":    &fstest.MapFile{},
+		"manifests/tests/pod.yaml":  &fstest.MapFile{},
+		"docs/README.md":            &fstest.MapFile{},
+		"LICENSE":                   &fstest.MapFile{},
+	}
+
+	for _, tt := range []struct {
+		description string
+		include     []string
+		exclude     []string
+		expected    []string
+	}{
+		{
+			description: "no filters",
+			expected:    []string{"LICENSE", "docs/README.md", "manifests/deployment.yaml", "manifests/service.yaml", "manifests/tests/pod.yaml"},
+		},
+		{
+			description: "include directory",
+			include:     []string{"manifests"},
+			expected:    []string{"manifests/deployment.yaml", "manifests/service.yaml", "manifests/tests/pod.yaml"},
+		},
+		{
+			description: "include glob",
+			include:     []string{"manifests/*.yaml"},
+			expected:    []string{"manifests/deployment.yaml", "manifests/service.yaml"},
+		},
+		{
+			description: "exclude directories",
+			exclude:     []string{"docs", "*/tests"},
+			expected:    []string{"LICENSE", "manifests/deployment.yaml", "manifests/service.yaml"},
+		},
+		{
+			description: "exclude takes precedence over include",
+			include:     []string{"manifests"},
+			exclude:     []string{"manifests/service.yaml"},
+			expected:    []string{"manifests/deployment.yaml", "manifests/tests/pod.yaml"},
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			filtered, err := FilterFS(fsys, tt.include, tt.exclude)
+			require.NoError(t, err)
+
+			var files []string
+			require.NoError(t, fs.WalkDir(filtered, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					files = append(files, path)
+				}
+				return nil
+			}))
+			require.Equal(t, tt.expected, files)
+
+			for _, name := range tt.expected {
+				_, err := fs.Stat(filtered, name)
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("excluded files cannot be opened", func(t *testing.T) {
+		filtered, err := FilterFS(fsys, nil, []string{"docs"})
+		require.NoError(t, err)
+		_, err = filtered.Open("docs/README.md")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = filtered.Open("docs")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := FilterFS(fsys, []string{"[a-"}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestIgnoreFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"chart/.helmignore":           &fstest.MapFile{Data: []byte("# CI artifacts\n*.tgz\ntests/\n/ci\n")},
+		"chart/Chart.yaml":            &fstest.MapFile{},
+		"chart/templates/deploy.yaml": &fstest.MapFile{},
+		"chart/templates/tests/a.yml": &fstest.MapFile{},
+		"chart/charts/dep.tgz":        &fstest.MapFile{},
+		"chart/ci/values.yaml":        &fstest.MapFile{},
+		"chart/docs/ci/README.md":     &fstest.MapFile{},
+		"other.tgz":                   &fstest.MapFile{},
+	}
+
+	filtered, err := IgnoreFS(fsys, "chart/.helmignore")
+	require.NoError(t, err)
+
+	var files []string
+	require.NoError(t, fs.WalkDir(filtered, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	}))
+	require.Equal(t, []string{"chart/.helmignore", "chart/Chart.yaml", "chart/docs/ci/README.md", "chart/templates/deploy.yaml", "other.tgz"}, files)
+
+	_, err = filtered.Open("chart/templates/tests/a.yml")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	t.Run("without an ignore file", func(t *testing.T) {
+		unchanged, err := IgnoreFS(fsys, RukpakIgnore)
+		require.NoError(t, err)
+		require.Equal(t, fs.FS(fsys), unchanged)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		_, err := IgnoreFS(fstest.MapFS{RukpakIgnore: &fstest.MapFile{Data: []byte("docs/**\n")}}, RukpakIgnore)
+		require.ErrorContains(t, err, "double-star")
+	})
+}
+
+type failingFS struct{}
+
+func (failingFS) Open(string) (fs.File, error) {
+	return nil, fs.ErrPermission
+}
+
+func TestSizeFS(t *testing.T) {
+	size, err := SizeFS(fstest.MapFS{
+		"a":     &fstest.MapFile{Data: []byte("aa")},
+		"b/c":   &fstest.MapFile{Data: []byte("ccc")},
+		"b/dir": &fstest.MapFile{Mode: fs.ModeDir},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), size)
+
+	_, err = SizeFS(failingFS{})
+	require.ErrorContains(t, err, "permission denied")
+
+	size, err = SizeFS(WithSize(failingFS{}, 42))
+	require.NoError(t, err)
+	require.Equal(t, int64(42), size)
+}
@@ -2,8 +2,11 @@ package util
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"math/big"
 )
 
@@ -37,3 +40,34 @@ func DeepHashObject(obj interface{}) (string, error) {
 	i.SetBytes(hash[:])
 	return i.Text(36), nil
 }
+
+// DigestFS computes a canonical digest of the regular files in fsys. The
+// digest covers the sorted file paths and the SHA-256 hashes of their
+// contents, but neither directories nor file metadata such as mode bits and
+// modification times, so that filesystems with identical content produce the
+// same digest regardless of the source they were unpacked from.
+func DigestFS(fsys fs.FS) (string, error) {
+	hasher := sha256.New()
+	if err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fileHasher := sha256.New()
+		if _, err := io.Copy(fileHasher, f); err != nil {
+			return fmt.Errorf("read %q: %v", path, err)
+		}
+		_, err = fmt.Fprintf(hasher, "%s\x00%s\n", path, hex.EncodeToString(fileHasher.Sum(nil)))
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("compute bundle digest: %v", err)
+	}
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifests/cm.yaml":  &fstest.MapFile{Data: []byte("kind: ConfigMap"), Mode: 0644},
+		"manifests/svc.yaml": &fstest.MapFile{Data: []byte("kind: Service"), Mode: 0644},
+	}
+	digest, err := DigestFS(fsys)
+	require.NoError(t, err)
+	require.Regexp(t, "^sha256:[0-9a-f]{64}$", digest)
+
+	t.Run("ignores metadata and directories", func(t *testing.T) {
+		other := fstest.MapFS{
+			"manifests/svc.yaml": &fstest.MapFile{Data: []byte("kind: Service"), Mode: 0755},
+			"manifests/cm.yaml":  &fstest.MapFile{Data: []byte("kind: ConfigMap"), Mode: 0600},
+			"empty":              &fstest.MapFile{Mode: fs.ModeDir | 0755},
+		}
+		otherDigest, err := DigestFS(other)
+		require.NoError(t, err)
+		require.Equal(t, digest, otherDigest)
+	})
+
+	t.Run("changes with content", func(t *testing.T) {
+		other := fstest.MapFS{
+			"manifests/cm.yaml":  &fstest.MapFile{Data: []byte("kind: ConfigMap"), Mode: 0644},
+			"manifests/svc.yaml": &fstest.MapFile{Data: []byte("kind: Secret"), Mode: 0644},
+		}
+		otherDigest, err := DigestFS(other)
+		require.NoError(t, err)
+		require.NotEqual(t, digest, otherDigest)
+	})
+
+	t.Run("changes with paths", func(t *testing.T) {
+		other := fstest.MapFS{
+			"manifests/cm.yaml":      &fstest.MapFile{Data: []byte("kind: ConfigMap"), Mode: 0644},
+			"manifests/service.yaml": &fstest.MapFile{Data: []byte("kind: Service"), Mode: 0644},
+		}
+		otherDigest, err := DigestFS(other)
+		require.NoError(t, err)
+		require.NotEqual(t, digest, otherDigest)
+	})
+}
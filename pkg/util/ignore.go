@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/ignore"
+)
+
+// RukpakIgnore is the name of the ignore file in the root of a bundle, which
+// lists the files of the bundle that are not unpacked.
+const RukpakIgnore = ".rukpakignore"
+
+// IgnoreFS returns an fs.FS that hides the files of fsys that are matched by
+// the rules of the ignore file at ignoreFile, in the syntax of .helmignore
+// files. The rules apply to the paths within the directory of ignoreFile,
+// relative to that directory, and an ignored directory hides everything
+// below it. The ignore file itself remains visible.
+//
+// If fsys does not contain the ignore file, it is returned unchanged.
+func IgnoreFS(fsys fs.FS, ignoreFile string) (fs.FS, error) {
+	data, err := fs.ReadFile(fsys, ignoreFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fsys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ignore.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse ignore file %q: %v", ignoreFile, err)
+	}
+	return &filterFS{fsys: fsys, filter: &ignoreFilter{file: ignoreFile, rules: rules}}, nil
+}
+
+type ignoreFilter struct {
+	file  string
+	rules *ignore.Rules
+}
+
+func (f *ignoreFilter) Visible(name string, isDir bool) bool {
+	if name == f.file {
+		return true
+	}
+	rel := name
+	if dir := path.Dir(f.file); dir != "." {
+		var ok bool
+		if rel, ok = strings.CutPrefix(name, dir+"/"); !ok {
+			return true
+		}
+	}
+	for p := rel; p != "."; p = path.Dir(p) {
+		if f.rules.Ignore(p, ignoredFileInfo{name: path.Base(p), dir: p != rel || isDir}) {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoredFileInfo is the fs.FileInfo of a path that is matched against ignore
+// rules, which only look at its name and whether it is a directory.
+type ignoredFileInfo struct {
+	name string
+	dir  bool
+}
+
+func (i ignoredFileInfo) Name() string { return i.name }
+func (i ignoredFileInfo) Size() int64  { return 0 }
+func (i ignoredFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+func (i ignoredFileInfo) ModTime() time.Time { return time.Time{} }
+func (i ignoredFileInfo) IsDir() bool        { return i.dir }
+func (i ignoredFileInfo) Sys() any           { return nil }
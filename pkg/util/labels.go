@@ -3,4 +3,8 @@ package util
 const (
 	CoreOwnerKindKey = "core.rukpak.io/owner-kind"
 	CoreOwnerNameKey = "core.rukpak.io/owner-name"
+
+	// GeneratedRBACKey marks the ServiceAccounts and RBAC objects that a
+	// provisioner generated for a single BundleDeployment.
+	GeneratedRBACKey = "core.rukpak.io/generated-rbac"
 )
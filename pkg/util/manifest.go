@@ -0,0 +1,106 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	apimachyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// NormalizeManifest returns manifest as UTF-8 without byte order marks and
+// with LF line endings, so that manifests that were authored on Windows parse
+// like any other manifest.
+//
+// UTF-16 manifests are recognized by their byte order mark. UTF-8 byte order
+// marks are also removed from the start of lines, which is where they end up
+// when files are concatenated into multi-document manifests. CRLF and lone CR
+// line endings are both replaced by LF, since the YAML document splitter only
+// recognizes document separators on lines that end with LF.
+func NormalizeManifest(manifest []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(manifest, utf16LEBOM):
+		return decodeUTF16(manifest[len(utf16LEBOM):], binary.LittleEndian)
+	case bytes.HasPrefix(manifest, utf16BEBOM):
+		return decodeUTF16(manifest[len(utf16BEBOM):], binary.BigEndian)
+	}
+	if !bytes.Contains(manifest, utf8BOM) && !bytes.ContainsRune(manifest, '\r') {
+		return manifest, nil
+	}
+	manifest = bytes.TrimPrefix(manifest, utf8BOM)
+	manifest = bytes.ReplaceAll(manifest, []byte("\r\n"), []byte("\n"))
+	manifest = bytes.ReplaceAll(manifest, []byte("\r"), []byte("\n"))
+	return bytes.ReplaceAll(manifest, append([]byte("\n"), utf8BOM...), []byte("\n")), nil
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, errors.New("invalid UTF-16 manifest: odd number of bytes")
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return NormalizeManifest([]byte(string(utf16.Decode(units))))
+}
+
+// VisitManifestObjects calls visit with every object of the manifest read
+// from r, one document at a time, and flattens lists into their items. Unlike
+// ManifestObjects, it does not hold all objects of the manifest in memory at
+// once, so that large manifests can be processed object by object. Objects
+// are decoded like ManifestObjects decodes them.
+func VisitManifestObjects(r io.Reader, name string, visit func(*unstructured.Unstructured) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if data, err = NormalizeManifest(data); err != nil {
+		return fmt.Errorf("read %s: %v", name, err)
+	}
+	docs := apimachyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := docs.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %v", name, err)
+		}
+		jsonData, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return fmt.Errorf("error converting YAML to JSON in %s: %v", name, err)
+		}
+		if bytes.Equal(bytes.TrimSpace(jsonData), []byte("null")) {
+			continue
+		}
+		obj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, jsonData)
+		if err != nil {
+			return fmt.Errorf("error decoding object in %s: %v", name, err)
+		}
+		switch obj := obj.(type) {
+		case *unstructured.Unstructured:
+			if err := visit(obj); err != nil {
+				return err
+			}
+		case *unstructured.UnstructuredList:
+			for i := range obj.Items {
+				if err := visit(&obj.Items[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
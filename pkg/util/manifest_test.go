@@ -0,0 +1,128 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+func encodeUTF16(s string, order binary.ByteOrder, bom []byte) []byte {
+	buf := bytes.NewBuffer(append([]byte(nil), bom...))
+	for _, u := range utf16.Encode([]rune(s)) {
+		_ = binary.Write(buf, order, u)
+	}
+	return buf.Bytes()
+}
+
+func TestNormalizeManifest(t *testing.T) {
+	const expected = "a: b\n---\nc: d\n"
+	for _, tt := range []struct {
+		description string
+		manifest    []byte
+		expectedErr string
+	}{
+		{description: "LF", manifest: []byte(expected)},
+		{description: "CRLF", manifest: []byte("a: b\r\n---\r\nc: d\r\n")},
+		{description: "CR", manifest: []byte("a: b\r---\rc: d\r")},
+		{description: "mixed line endings", manifest: []byte("a: b\r\n---\nc: d\r")},
+		{description: "UTF-8 BOM", manifest: []byte("\ufeffa: b\r\n---\r\nc: d\r\n")},
+		{description: "UTF-8 BOM in every document", manifest: []byte("\ufeffa: b\n---\n\ufeffc: d\n")},
+		{description: "UTF-16LE", manifest: encodeUTF16("a: b\r\n---\r\nc: d\r\n", binary.LittleEndian, utf16LEBOM)},
+		{description: "UTF-16BE", manifest: encodeUTF16("a: b\n---\nc: d\n", binary.BigEndian, utf16BEBOM)},
+		{description: "truncated UTF-16", manifest: encodeUTF16("a: b\n", binary.LittleEndian, utf16LEBOM)[:5], expectedErr: "odd number of bytes"},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			actual, err := NormalizeManifest(tt.manifest)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, expected, string(actual))
+		})
+	}
+}
+
+func TestManifestObjectsLineEndings(t *testing.T) {
+	const windowsDeployment = "apiVersion: apps/v1\r\n" +
+		"kind: Deployment\r\n" +
+		"metadata:\r\n" +
+		"  name: windows-app\r\n" +
+		"spec:\r\n" +
+		"  template:\r\n" +
+		"    spec:\r\n" +
+		"      nodeSelector:\r\n" +
+		"        kubernetes.io/os: windows\r\n" +
+		"      containers:\r\n" +
+		"      - name: app\r\n" +
+		"        image: mcr.microsoft.com/windows/nanoserver:ltsc2022\r\n"
+	const configMap = "apiVersion: v1\rkind: ConfigMap\rmetadata:\r  name: config\r"
+
+	objs, err := ManifestObjects(strings.NewReader("\ufeff"+windowsDeployment+"---\r\n"+configMap+"---\n\ufeff"+configMap[:len(configMap)-1]+"-2\n"), "test")
+	require.NoError(t, err)
+	require.Len(t, objs, 3)
+	require.Equal(t, "windows-app", objs[0].GetName())
+	require.Equal(t, "config", objs[1].GetName())
+	require.Equal(t, "config-2", objs[2].GetName())
+}
+
+func TestVisitManifestObjects(t *testing.T) {
+	const manifest = `# leading comment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+  template:
+    spec:
+      terminationGracePeriodSeconds: 30
+      containers:
+      - name: app
+        image: quay.io/example/app:v1
+        resources:
+          limits:
+            cpu: 0.5
+---
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: listed-a
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: listed-b
+---
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "json"}, "data": {"enabled": "true"}}
+`
+	expected, err := ManifestObjects(strings.NewReader(manifest), "test")
+	require.NoError(t, err)
+
+	var visited []client.Object
+	require.NoError(t, VisitManifestObjects(strings.NewReader(manifest), "test", func(obj *unstructured.Unstructured) error {
+		visited = append(visited, obj)
+		return nil
+	}))
+	require.Len(t, visited, len(expected))
+	for i := range expected {
+		expectedData, err := yaml.Marshal(expected[i])
+		require.NoError(t, err)
+		visitedData, err := yaml.Marshal(visited[i])
+		require.NoError(t, err)
+		require.Equal(t, string(expectedData), string(visitedData))
+	}
+
+	err = VisitManifestObjects(strings.NewReader("apiVersion: v1\nmetadata:\n  name: no-kind\n"), "test", func(*unstructured.Unstructured) error { return nil })
+	require.ErrorContains(t, err, "Object 'Kind' is missing")
+}
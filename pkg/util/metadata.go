@@ -0,0 +1,138 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+// ProvidedAPIsAnnotationKey is the chart annotation that lists the APIs
+// provided by a bundle. It uses the comma-separated "Kind.version.group"
+// format of OLM's olm.providedAPIs annotation.
+const ProvidedAPIsAnnotationKey = "core.rukpak.io/provided-apis"
+
+// ChannelsAnnotationKey and DefaultChannelAnnotationKey are the chart
+// annotations that hold the comma-separated channels of a bundle and the
+// default channel of its package.
+const (
+	ChannelsAnnotationKey       = "core.rukpak.io/channels"
+	DefaultChannelAnnotationKey = "core.rukpak.io/default-channel"
+)
+
+// RelatedImagesAnnotationKey is the chart annotation that lists the related
+// images of a bundle, comma-separated, as name=image, or as image if the
+// image has no name.
+const RelatedImagesAnnotationKey = "core.rukpak.io/related-images"
+
+// SetChartBundleMetadata records md in the metadata of chrt, so that it is
+// persisted with the Helm release that installs the chart.
+func SetChartBundleMetadata(chrt *chart.Chart, md rukpakv1alpha2.BundleMetadata) {
+	if chrt.Metadata == nil {
+		chrt.Metadata = &chart.Metadata{}
+	}
+	chrt.Metadata.Name = md.PackageName
+	chrt.Metadata.Version = md.Version
+	setAnnotation := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if chrt.Metadata.Annotations == nil {
+			chrt.Metadata.Annotations = map[string]string{}
+		}
+		chrt.Metadata.Annotations[key] = value
+	}
+	apis := make([]string, 0, len(md.ProvidedAPIs))
+	for _, gvk := range md.ProvidedAPIs {
+		apis = append(apis, fmt.Sprintf("%s.%s.%s", gvk.Kind, gvk.Version, gvk.Group))
+	}
+	setAnnotation(ProvidedAPIsAnnotationKey, strings.Join(apis, ","))
+	setAnnotation(ChannelsAnnotationKey, strings.Join(md.Channels, ","))
+	setAnnotation(DefaultChannelAnnotationKey, md.DefaultChannel)
+	images := make([]string, 0, len(md.RelatedImages))
+	for _, image := range md.RelatedImages {
+		if image.Name == "" {
+			images = append(images, image.Image)
+		} else {
+			images = append(images, image.Name+"="+image.Image)
+		}
+	}
+	setAnnotation(RelatedImagesAnnotationKey, strings.Join(images, ","))
+}
+
+// BundleMetadataFromChart returns the bundle metadata recorded in the metadata
+// of chrt, or nil if chrt does not declare a name.
+func BundleMetadataFromChart(chrt *chart.Chart) *rukpakv1alpha2.BundleMetadata {
+	if chrt == nil || chrt.Metadata == nil || chrt.Metadata.Name == "" {
+		return nil
+	}
+	md := &rukpakv1alpha2.BundleMetadata{
+		PackageName: chrt.Metadata.Name,
+		Version:     chrt.Metadata.Version,
+	}
+	if apis := chrt.Metadata.Annotations[ProvidedAPIsAnnotationKey]; apis != "" {
+		for _, api := range strings.Split(apis, ",") {
+			// The group may itself contain dots, so only split off the kind and version.
+			parts := strings.SplitN(api, ".", 3)
+			gvk := metav1.GroupVersionKind{Kind: parts[0]}
+			if len(parts) > 1 {
+				gvk.Version = parts[1]
+			}
+			if len(parts) > 2 {
+				gvk.Group = parts[2]
+			}
+			md.ProvidedAPIs = append(md.ProvidedAPIs, gvk)
+		}
+	}
+	if channels := chrt.Metadata.Annotations[ChannelsAnnotationKey]; channels != "" {
+		md.Channels = strings.Split(channels, ",")
+	}
+	md.DefaultChannel = chrt.Metadata.Annotations[DefaultChannelAnnotationKey]
+	if images := chrt.Metadata.Annotations[RelatedImagesAnnotationKey]; images != "" {
+		for _, image := range strings.Split(images, ",") {
+			// Image references never contain "=".
+			name, ref, ok := strings.Cut(image, "=")
+			if !ok {
+				name, ref = "", image
+			}
+			md.RelatedImages = append(md.RelatedImages, rukpakv1alpha2.RelatedImage{Name: name, Image: ref})
+		}
+	}
+	return md
+}
+
+// PendingAnnotationKey is the chart annotation with which handlers declare
+// that the chart holds only part of the bundle, and describe what the rest of
+// the bundle waits for.
+const PendingAnnotationKey = "core.rukpak.io/pending"
+
+// SetChartPending records in the metadata of chrt that the rest of the bundle
+// is pending for the given reason, or clears it if reason is empty.
+func SetChartPending(chrt *chart.Chart, reason string) {
+	if reason == "" {
+		if chrt.Metadata != nil {
+			delete(chrt.Metadata.Annotations, PendingAnnotationKey)
+		}
+		return
+	}
+	if chrt.Metadata == nil {
+		chrt.Metadata = &chart.Metadata{}
+	}
+	if chrt.Metadata.Annotations == nil {
+		chrt.Metadata.Annotations = map[string]string{}
+	}
+	chrt.Metadata.Annotations[PendingAnnotationKey] = reason
+}
+
+// ChartPending returns why the rest of the bundle of chrt is pending, as
+// recorded by SetChartPending, or an empty string if chrt holds the whole
+// bundle.
+func ChartPending(chrt *chart.Chart) string {
+	if chrt == nil || chrt.Metadata == nil {
+		return ""
+	}
+	return chrt.Metadata.Annotations[PendingAnnotationKey]
+}
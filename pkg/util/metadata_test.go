@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestBundleMetadataFromChart(t *testing.T) {
+	md := rukpakv1alpha2.BundleMetadata{
+		PackageName:    "test-package",
+		Version:        "1.2.3",
+		ProvidedAPIs:   []metav1.GroupVersionKind{{Group: "example.com", Version: "v1", Kind: "Widget"}},
+		Channels:       []string{"stable", "fast"},
+		DefaultChannel: "stable",
+		RelatedImages: []rukpakv1alpha2.RelatedImage{
+			{Name: "operator", Image: "quay.io/example/operator@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"},
+			{Image: "quay.io/example/proxy:v1"},
+		},
+	}
+	chrt := &chart.Chart{}
+	SetChartBundleMetadata(chrt, md)
+	require.Equal(t, &md, BundleMetadataFromChart(chrt))
+
+	chrt = &chart.Chart{}
+	SetChartBundleMetadata(chrt, rukpakv1alpha2.BundleMetadata{PackageName: "test-package", Version: "1.2.3"})
+	require.Empty(t, chrt.Metadata.Annotations)
+	require.Equal(t, &rukpakv1alpha2.BundleMetadata{PackageName: "test-package", Version: "1.2.3"}, BundleMetadataFromChart(chrt))
+}
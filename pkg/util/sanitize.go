@@ -0,0 +1,230 @@
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// SymlinkPolicy defines how SanitizeFS handles symbolic links.
+type SymlinkPolicy string
+
+const (
+	// SymlinkPolicyResolve replaces symbolic links to regular files within the
+	// filesystem root with the files they point to. Links that point to
+	// directories, links whose target is outside the filesystem root and
+	// dangling links are rejected.
+	SymlinkPolicyResolve SymlinkPolicy = "Resolve"
+
+	// SymlinkPolicyReject rejects any symbolic link.
+	SymlinkPolicyReject SymlinkPolicy = "Reject"
+
+	// maxSymlinkHops matches the limit of the Linux kernel for following
+	// chains of symbolic links.
+	maxSymlinkHops = 40
+)
+
+// ReadLinkFS is an fs.FS that is able to report the target of a symbolic link.
+type ReadLinkFS interface {
+	fs.FS
+
+	// ReadLink returns the destination of the named symbolic link.
+	ReadLink(name string) (string, error)
+}
+
+// SanitizeFS validates that fsys only contains directories, regular files
+// and, depending on the policy, symbolic links, and returns an fs.FS that
+// presents the bundle with a uniform set of file types and mode bits, no
+// matter which source it was unpacked from:
+//
+//   - Directories are presented with mode 0755.
+//   - Regular files are presented with mode 0755 if any executable bit is set,
+//     or 0644 otherwise. Hard links are indistinguishable from regular files
+//     and are presented as copies of the file they link to.
+//   - Symbolic links are handled according to policy. Resolving symbolic links
+//     requires fsys to implement ReadLinkFS.
+//   - Any other file type (devices, named pipes, sockets, etc.) is rejected.
+func SanitizeFS(fsys fs.FS, policy SymlinkPolicy) (fs.FS, error) {
+	links := map[string]string{}
+	if err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch typ := d.Type(); {
+		case typ.IsDir(), typ.IsRegular():
+			return nil
+		case typ&fs.ModeSymlink != 0:
+			if policy != SymlinkPolicyResolve {
+				return fmt.Errorf("%q is a symbolic link: symbolic links are not allowed", name)
+			}
+			target, err := resolveSymlink(fsys, name)
+			if err != nil {
+				return err
+			}
+			links[name] = target
+			return nil
+		default:
+			return fmt.Errorf("%q has unsupported file type %q", name, typ.Type().String())
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("sanitize bundle content: %v", err)
+	}
+	return &sanitizedFS{fsys: fsys, links: links}, nil
+}
+
+// resolveSymlink follows the symbolic link at name until it reaches a regular
+// file and returns the path of that file.
+func resolveSymlink(fsys fs.FS, name string) (string, error) {
+	rlfs, ok := fsys.(ReadLinkFS)
+	if !ok {
+		return "", fmt.Errorf("%q is a symbolic link: reading symbolic links is not supported by the bundle source", name)
+	}
+	current := name
+	for i := 0; i < maxSymlinkHops; i++ {
+		target, err := rlfs.ReadLink(current)
+		if err != nil {
+			return "", fmt.Errorf("read symbolic link %q: %v", current, err)
+		}
+		target = filepath.ToSlash(target)
+		if path.IsAbs(target) {
+			return "", fmt.Errorf("symbolic link %q points to absolute path %q: symbolic links must point within the bundle", current, target)
+		}
+		resolved := path.Join(path.Dir(current), target)
+		if !fs.ValidPath(resolved) {
+			return "", fmt.Errorf("symbolic link %q points to %q outside of the bundle: symbolic links must point within the bundle", current, target)
+		}
+		info, err := lstat(fsys, resolved)
+		if err != nil {
+			return "", fmt.Errorf("symbolic link %q points to %q: %v", current, target, err)
+		}
+		switch mode := info.Mode(); {
+		case mode.IsRegular():
+			return resolved, nil
+		case mode&fs.ModeSymlink != 0:
+			current = resolved
+		default:
+			return "", fmt.Errorf("symbolic link %q points to %q: only symbolic links to regular files are supported", current, target)
+		}
+	}
+	return "", fmt.Errorf("symbolic link %q: too many levels of symbolic links", name)
+}
+
+// lstat returns the file info of name without following a symbolic link at name.
+func lstat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	entries, err := fs.ReadDir(fsys, path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	base := path.Base(name)
+	for _, entry := range entries {
+		if entry.Name() == base {
+			return entry.Info()
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+type sanitizedFS struct {
+	fsys fs.FS
+	// links maps the paths of symbolic links to the regular files they resolve to.
+	links map[string]string
+}
+
+func (f *sanitizedFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	target := name
+	if resolved, ok := f.links[name]; ok {
+		target = resolved
+	}
+	file, err := f.fsys.Open(target)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	sanitized := &sanitizedFile{File: file, info: sanitizedFileInfo{stat, path.Base(name)}}
+	if stat.IsDir() {
+		return &readDirFile{File: sanitized, readDir: func() ([]fs.DirEntry, error) { return f.ReadDir(name) }}, nil
+	}
+	return sanitized, nil
+}
+
+func (f *sanitizedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	sanitized := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		var info fs.FileInfo
+		if target, ok := f.links[path.Join(name, entry.Name())]; ok {
+			info, err = fs.Stat(f.fsys, target)
+		} else {
+			info, err = entry.Info()
+		}
+		if err != nil {
+			return nil, err
+		}
+		sanitized = append(sanitized, fs.FileInfoToDirEntry(sanitizedFileInfo{info, entry.Name()}))
+	}
+	return sanitized, nil
+}
+
+type sanitizedFile struct {
+	fs.File
+	info fs.FileInfo
+}
+
+func (f *sanitizedFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// sanitizedFileInfo presents a file under the given name with normalized mode bits.
+type sanitizedFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i sanitizedFileInfo) Name() string {
+	return i.name
+}
+
+func (i sanitizedFileInfo) Mode() fs.FileMode {
+	mode := i.FileInfo.Mode()
+	switch {
+	case mode.IsDir():
+		return fs.ModeDir | 0755
+	case mode.Perm()&0111 != 0:
+		return 0755
+	default:
+		return 0644
+	}
+}
+
+// DirFS returns a file system for the tree of files rooted at dir, like
+// os.DirFS, that additionally implements ReadLinkFS.
+func DirFS(dir string) fs.FS {
+	return &dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func (d *dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Readlink(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
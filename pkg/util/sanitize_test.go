@@ -0,0 +1,177 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	header tar.Header
+	data   string
+}
+
+func newTar(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		h := e.header
+		h.Size = int64(len(e.data))
+		require.NoError(t, tw.WriteHeader(&h))
+		_, err := tw.Write([]byte(e.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf
+}
+
+func TestSanitizeFS(t *testing.T) {
+	manifest := tarEntry{header: tar.Header{Name: "manifests/cm.yaml", Typeflag: tar.TypeReg, Mode: 0600}, data: "kind: ConfigMap"}
+	for _, tt := range []struct {
+		description string
+		entries     []tarEntry
+		policy      SymlinkPolicy
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			description: "regular files and hard links",
+			entries: []tarEntry{
+				{header: tar.Header{Name: "manifests/", Typeflag: tar.TypeDir, Mode: 0700}},
+				manifest,
+				{header: tar.Header{Name: "manifests/hardlink.yaml", Typeflag: tar.TypeLink, Linkname: "manifests/cm.yaml"}},
+			},
+			expected: map[string]string{"manifests/cm.yaml": "kind: ConfigMap", "manifests/hardlink.yaml": "kind: ConfigMap"},
+		},
+		{
+			description: "symbolic links within the bundle are resolved",
+			entries: []tarEntry{
+				manifest,
+				{header: tar.Header{Name: "link.yaml", Typeflag: tar.TypeSymlink, Linkname: "manifests/cm.yaml"}},
+				{header: tar.Header{Name: "manifests/chained.yaml", Typeflag: tar.TypeSymlink, Linkname: "../link.yaml"}},
+			},
+			policy: SymlinkPolicyResolve,
+			expected: map[string]string{
+				"manifests/cm.yaml":      "kind: ConfigMap",
+				"link.yaml":              "kind: ConfigMap",
+				"manifests/chained.yaml": "kind: ConfigMap",
+			},
+		},
+		{
+			description: "symbolic links are rejected",
+			entries: []tarEntry{
+				manifest,
+				{header: tar.Header{Name: "link.yaml", Typeflag: tar.TypeSymlink, Linkname: "manifests/cm.yaml"}},
+			},
+			policy:      SymlinkPolicyReject,
+			expectedErr: "symbolic links are not allowed",
+		},
+		{
+			description: "symbolic links outside of the bundle are rejected",
+			entries: []tarEntry{
+				{header: tar.Header{Name: "manifests/link.yaml", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}},
+			},
+			policy:      SymlinkPolicyResolve,
+			expectedErr: "outside of the bundle",
+		},
+		{
+			description: "absolute symbolic links are rejected",
+			entries: []tarEntry{
+				{header: tar.Header{Name: "link.yaml", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+			},
+			policy:      SymlinkPolicyResolve,
+			expectedErr: "absolute path",
+		},
+		{
+			description: "symbolic links to directories are rejected",
+			entries: []tarEntry{
+				manifest,
+				{header: tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "manifests"}},
+			},
+			policy:      SymlinkPolicyResolve,
+			expectedErr: "only symbolic links to regular files are supported",
+		},
+		{
+			description: "symbolic link loops are rejected",
+			entries: []tarEntry{
+				{header: tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "b"}},
+				{header: tar.Header{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a"}},
+			},
+			policy:      SymlinkPolicyResolve,
+			expectedErr: "too many levels of symbolic links",
+		},
+		{
+			description: "device files are rejected",
+			entries: []tarEntry{
+				{header: tar.Header{Name: "dev", Typeflag: tar.TypeChar, Mode: 0600}},
+			},
+			expectedErr: "unsupported file type",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			fsys, err := TarToFS(newTar(t, tt.entries...))
+			require.NoError(t, err)
+
+			sanitized, err := SanitizeFS(fsys, tt.policy)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			actual := map[string]string{}
+			require.NoError(t, fs.WalkDir(sanitized, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					require.Equal(t, fs.ModeDir|0755, info.Mode(), path)
+					return nil
+				}
+				require.Equal(t, fs.FileMode(0644), info.Mode(), path)
+				data, err := fs.ReadFile(sanitized, path)
+				if err != nil {
+					return err
+				}
+				actual[path] = string(data)
+				return nil
+			}))
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestSanitizeDirFS(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh"), 0700))
+	require.NoError(t, os.Symlink("run.sh", filepath.Join(dir, "link.sh")))
+
+	sanitized, err := SanitizeFS(DirFS(dir), SymlinkPolicyResolve)
+	require.NoError(t, err)
+
+	info, err := fs.Stat(sanitized, "link.sh")
+	require.NoError(t, err)
+	require.Equal(t, "link.sh", info.Name())
+	require.Equal(t, fs.FileMode(0755), info.Mode())
+
+	data, err := fs.ReadFile(sanitized, "link.sh")
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh", string(data))
+
+	_, err = SanitizeFS(DirFS(dir), SymlinkPolicyReject)
+	require.ErrorContains(t, err, "symbolic links are not allowed")
+}
+
+func TestTarToFSRejectsPathsOutsideRoot(t *testing.T) {
+	_, err := TarToFS(newTar(t, tarEntry{header: tar.Header{Name: "../escape.yaml", Typeflag: tar.TypeReg}}))
+	require.ErrorContains(t, err, "outside of the archive root")
+}
@@ -3,10 +3,14 @@ package util
 import (
 	"archive/tar"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
+	"strings"
+	"testing/fstest"
 )
 
 // FSToTarGZ writes the filesystem represented by fsys to w as a gzipped tar archive.
@@ -15,7 +19,16 @@ import (
 // permissions between source and destination filesystems.
 func FSToTarGZ(w io.Writer, fsys fs.FS) error {
 	gzw := gzip.NewWriter(w)
-	tw := tar.NewWriter(gzw)
+	if err := FSToTar(gzw, fsys); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// FSToTar writes the filesystem represented by fsys to w as an uncompressed
+// tar archive, with user and group information unset like FSToTarGZ does.
+func FSToTar(w io.Writer, fsys fs.FS) error {
+	tw := tar.NewWriter(w)
 	if err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -49,15 +62,75 @@ func FSToTarGZ(w io.Writer, fsys fs.FS) error {
 		if err != nil {
 			return fmt.Errorf("open file %q: %v", path, err)
 		}
+		defer f.Close()
 		if _, err := io.Copy(tw, f); err != nil {
 			return fmt.Errorf("write tar data for %q: %v", path, err)
 		}
 		return nil
 	}); err != nil {
-		return fmt.Errorf("generate tar.gz from FS: %v", err)
+		return fmt.Errorf("generate tar from FS: %v", err)
 	}
-	if err := tw.Close(); err != nil {
-		return err
+	return tw.Close()
+}
+
+// TarToFS reads the tar archive from r into an in-memory filesystem.
+// Hard links are replaced by copies of the files they link to, which must
+// precede them in the archive. Symbolic links are preserved, and the
+// returned filesystem implements ReadLinkFS so that they can be resolved
+// by SanitizeFS. Other special files are preserved with their file type so
+// that SanitizeFS can reject them.
+func TarToFS(r io.Reader) (fs.FS, error) {
+	tfs := &tarFS{MapFS: fstest.MapFS{}, links: map[string]string{}}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar archive: %v", err)
+		}
+		if h.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(h.Name, "/"))
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("tar archive entry %q: path is outside of the archive root", h.Name)
+		}
+		file := &fstest.MapFile{Mode: h.FileInfo().Mode(), ModTime: h.ModTime}
+		switch h.Typeflag {
+		case tar.TypeReg:
+			if file.Data, err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("read tar archive entry %q: %v", h.Name, err)
+			}
+		case tar.TypeLink:
+			target := path.Clean(strings.TrimPrefix(h.Linkname, "/"))
+			linked, ok := tfs.MapFS[target]
+			if !ok || !linked.Mode.IsRegular() {
+				return nil, fmt.Errorf("tar archive entry %q: hard link target %q is not a regular file in the archive", h.Name, h.Linkname)
+			}
+			file.Data, file.Mode = linked.Data, linked.Mode
+		case tar.TypeSymlink:
+			tfs.links[name] = h.Linkname
+			file.Data = []byte(h.Linkname)
+		}
+		tfs.MapFS[name] = file
 	}
-	return gzw.Close()
+	return tfs, nil
+}
+
+type tarFS struct {
+	fstest.MapFS
+	links map[string]string
+}
+
+func (t *tarFS) ReadLink(name string) (string, error) {
+	target, ok := t.links[name]
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return target, nil
 }
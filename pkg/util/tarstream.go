@@ -0,0 +1,338 @@
+package util
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClosableFS is a filesystem that holds resources, such as an open file,
+// until it is closed.
+type ClosableFS interface {
+	fs.FS
+	io.Closer
+}
+
+// NopCloserFS returns fsys as a ClosableFS whose Close does nothing, for
+// filesystems that hold no resources.
+func NopCloserFS(fsys fs.FS) ClosableFS {
+	return nopCloserFS{fsys}
+}
+
+type nopCloserFS struct {
+	fs.FS
+}
+
+func (nopCloserFS) Close() error { return nil }
+
+// SpoolFile copies r to a temporary file in dir, or in the default directory
+// for temporary files if dir is empty, and returns the file positioned at its
+// start. The file is unlinked right away, so that its space is freed once it
+// is closed, even if the process is killed before it gets to close it.
+func SpoolFile(dir string, r io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp(dir, "spool-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// TarFileFS returns a filesystem of the tar archive in file that streams the
+// content of files from it, like StreamTarFS. The archive is decompressed
+// with decompress, unless it is nil. Closing the filesystem closes file, and
+// file is closed if an error is returned.
+func TarFileFS(file *os.File, decompress func(io.Reader) (io.ReadCloser, error)) (ClosableFS, error) {
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	fsys, err := streamTar(func() (io.ReadCloser, error) {
+		section := io.NewSectionReader(file, 0, info.Size())
+		if decompress == nil {
+			return io.NopCloser(section), nil
+		}
+		return decompress(section)
+	}, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return fsys, nil
+}
+
+// StreamTarFS returns a read-only filesystem of the tar archive that open
+// returns a reader of. Unlike TarToFS, only the headers of the archive are
+// kept in memory: the content of a file is streamed from the archive when
+// the file is read, by reading the archive up to the file, so that archives
+// far larger than the available memory can be read.
+//
+// Files that are read in the order of the archive, such as by fs.WalkDir
+// for archives that FSToTar wrote, continue reading the same stream, so the
+// archive is only read once as long as every file is closed before the next
+// one is opened. open must return a reader of the same archive every time.
+//
+// Links are handled like TarToFS does: hard links are read from the files
+// they link to, and symbolic links can be resolved through ReadLinkFS.
+// Closing the filesystem closes the stream that it keeps for the next file.
+func StreamTarFS(open func() (io.ReadCloser, error)) (ClosableFS, error) {
+	return streamTar(open, nil)
+}
+
+func streamTar(open func() (io.ReadCloser, error), closer io.Closer) (*streamedTarFS, error) {
+	tfs := &streamedTarFS{open: open, closer: closer, entries: map[string]*streamedTarEntry{
+		".": {name: ".", mode: fs.ModeDir | 0755, index: -1},
+	}}
+	stream, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	tr := tar.NewReader(stream)
+	for index := 0; ; index++ {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar archive: %v", err)
+		}
+		name := path.Clean(strings.TrimPrefix(h.Name, "/"))
+		if h.Typeflag == tar.TypeXGlobalHeader || name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("tar archive entry %q: path is outside of the archive root", h.Name)
+		}
+		entry := &streamedTarEntry{name: name, mode: h.FileInfo().Mode(), size: h.Size, modTime: h.ModTime, index: index}
+		switch h.Typeflag {
+		case tar.TypeLink:
+			linked, ok := tfs.entries[path.Clean(strings.TrimPrefix(h.Linkname, "/"))]
+			if !ok || !linked.mode.IsRegular() {
+				return nil, fmt.Errorf("tar archive entry %q: hard link target %q is not a regular file in the archive", h.Name, h.Linkname)
+			}
+			entry.mode, entry.size, entry.index = linked.mode, linked.size, linked.index
+		case tar.TypeSymlink:
+			entry.linkname = h.Linkname
+		}
+		tfs.add(entry)
+	}
+	for _, entry := range tfs.entries {
+		sort.Slice(entry.children, func(i, j int) bool {
+			return entry.children[i].name < entry.children[j].name
+		})
+	}
+	return tfs, nil
+}
+
+type streamedTarFS struct {
+	open    func() (io.ReadCloser, error)
+	closer  io.Closer
+	entries map[string]*streamedTarEntry
+
+	mu sync.Mutex
+	// idle is a stream that was left behind by a file that was closed, and
+	// is continued by the next file that is opened after it.
+	idle *tarStream
+}
+
+type streamedTarEntry struct {
+	name     string
+	mode     fs.FileMode
+	size     int64
+	modTime  time.Time
+	index    int
+	linkname string
+	children []*streamedTarEntry
+}
+
+// tarStream is a reader of the archive, positioned in front of the entry at
+// index next.
+type tarStream struct {
+	closer io.Closer
+	tr     *tar.Reader
+	next   int
+}
+
+// add adds entry, and the parent directories that the archive does not list
+// before it.
+func (t *streamedTarFS) add(entry *streamedTarEntry) {
+	if existing, ok := t.entries[entry.name]; ok {
+		existing.mode, existing.size, existing.modTime, existing.index, existing.linkname = entry.mode, entry.size, entry.modTime, entry.index, entry.linkname
+		return
+	}
+	t.entries[entry.name] = entry
+	parentName := path.Dir(entry.name)
+	parent, ok := t.entries[parentName]
+	if !ok {
+		parent = &streamedTarEntry{name: parentName, mode: fs.ModeDir | 0755, index: -1}
+		t.add(parent)
+	}
+	parent.children = append(parent.children, entry)
+}
+
+func (t *streamedTarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.mode.IsDir() {
+		return &streamedTarDir{entry: entry}, nil
+	}
+	stream, err := t.streamTo(entry.index)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &streamedTarFile{fsys: t, entry: entry, stream: stream}, nil
+}
+
+func (t *streamedTarFS) ReadLink(name string) (string, error) {
+	entry, ok := t.entries[name]
+	if !ok || entry.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return entry.linkname, nil
+}
+
+// Close closes the stream that is kept for the next file, and the file that
+// the archive is read from, if any.
+func (t *streamedTarFS) Close() error {
+	t.mu.Lock()
+	idle := t.idle
+	t.idle = nil
+	t.mu.Unlock()
+	var errs []error
+	if idle != nil {
+		errs = append(errs, idle.closer.Close())
+	}
+	if t.closer != nil {
+		errs = append(errs, t.closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// streamTo returns a stream of the archive that is positioned at the content
+// of the entry at index. The idle stream is continued if it is not past the
+// entry yet.
+func (t *streamedTarFS) streamTo(index int) (*tarStream, error) {
+	t.mu.Lock()
+	stream := t.idle
+	if stream != nil && stream.next <= index {
+		t.idle = nil
+	} else {
+		stream = nil
+	}
+	t.mu.Unlock()
+
+	if stream == nil {
+		rc, err := t.open()
+		if err != nil {
+			return nil, err
+		}
+		stream = &tarStream{closer: rc, tr: tar.NewReader(rc)}
+	}
+	for stream.next <= index {
+		if _, err := stream.tr.Next(); err != nil {
+			stream.closer.Close()
+			return nil, fmt.Errorf("read tar archive: %v", err)
+		}
+		stream.next++
+	}
+	return stream, nil
+}
+
+// release keeps stream for the next file that is opened, closing the stream
+// that was kept before.
+func (t *streamedTarFS) release(stream *tarStream) {
+	t.mu.Lock()
+	previous := t.idle
+	t.idle = stream
+	t.mu.Unlock()
+	if previous != nil {
+		previous.closer.Close()
+	}
+}
+
+type streamedTarFile struct {
+	fsys   *streamedTarFS
+	entry  *streamedTarEntry
+	stream *tarStream
+}
+
+func (f *streamedTarFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+
+func (f *streamedTarFile) Read(p []byte) (int, error) {
+	if f.stream == nil {
+		return 0, &fs.PathError{Op: "read", Path: f.entry.name, Err: fs.ErrClosed}
+	}
+	return f.stream.tr.Read(p)
+}
+
+func (f *streamedTarFile) Close() error {
+	if f.stream == nil {
+		return &fs.PathError{Op: "close", Path: f.entry.name, Err: fs.ErrClosed}
+	}
+	f.fsys.release(f.stream)
+	f.stream = nil
+	return nil
+}
+
+type streamedTarDir struct {
+	entry  *streamedTarEntry
+	offset int
+}
+
+func (d *streamedTarDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+
+func (d *streamedTarDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: errors.New("is a directory")}
+}
+
+func (d *streamedTarDir) Close() error { return nil }
+
+func (d *streamedTarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entry.children[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	entries := make([]fs.DirEntry, 0, len(remaining))
+	for _, child := range remaining {
+		entries = append(entries, fs.FileInfoToDirEntry(child))
+	}
+	d.offset += len(remaining)
+	return entries, nil
+}
+
+// streamedTarEntry is the fs.FileInfo of its file.
+func (e *streamedTarEntry) Name() string       { return path.Base(e.name) }
+func (e *streamedTarEntry) Size() int64        { return e.size }
+func (e *streamedTarEntry) Mode() fs.FileMode  { return e.mode }
+func (e *streamedTarEntry) ModTime() time.Time { return e.modTime }
+func (e *streamedTarEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *streamedTarEntry) Sys() any           { return nil }
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTarFS(t *testing.T) {
+	source := fstest.MapFS{
+		"manifests/cm.yaml":        &fstest.MapFile{Data: []byte("kind: ConfigMap"), Mode: 0644},
+		"manifests/svc.yaml":       &fstest.MapFile{Data: []byte("kind: Service"), Mode: 0644},
+		"metadata/annotations.yml": &fstest.MapFile{Data: []byte("annotations: {}"), Mode: 0644},
+		"empty":                    &fstest.MapFile{Mode: fs.ModeDir | 0755},
+	}
+	var archive bytes.Buffer
+	require.NoError(t, FSToTar(&archive, source))
+
+	opens := 0
+	open := func() (io.ReadCloser, error) {
+		opens++
+		return io.NopCloser(bytes.NewReader(archive.Bytes())), nil
+	}
+	fsys, err := StreamTarFS(open)
+	require.NoError(t, err)
+
+	t.Run("reads the archive once when walked", func(t *testing.T) {
+		opens = 0
+		digest, err := DigestFS(fsys)
+		require.NoError(t, err)
+		expected, err := DigestFS(source)
+		require.NoError(t, err)
+		require.Equal(t, expected, digest)
+		require.Equal(t, 1, opens)
+	})
+
+	t.Run("reads files out of order", func(t *testing.T) {
+		for _, name := range []string{"metadata/annotations.yml", "manifests/cm.yaml"} {
+			data, err := fs.ReadFile(fsys, name)
+			require.NoError(t, err)
+			require.Equal(t, source[name].Data, data)
+		}
+	})
+
+	t.Run("is a valid file system", func(t *testing.T) {
+		require.NoError(t, fstest.TestFS(fsys, "manifests/cm.yaml", "manifests/svc.yaml", "metadata/annotations.yml", "empty"))
+	})
+}
+
+func TestStreamTarFSImplicitDirectories(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/cm.yaml", Typeflag: tar.TypeReg, Mode: 0644, Size: 4}))
+	_, err := tw.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	fsys, err := StreamTarFS(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(archive.Bytes())), nil
+	})
+	require.NoError(t, err)
+	info, err := fs.Stat(fsys, "manifests")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+	data, err := fs.ReadFile(fsys, "manifests/cm.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+}
+
+func TestTarFileFS(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/cm.yaml", Typeflag: tar.TypeReg, Mode: 0644, Size: 4}))
+	_, err := tw.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/hardlink.yaml", Typeflag: tar.TypeLink, Linkname: "manifests/cm.yaml"}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifests/symlink.yaml", Typeflag: tar.TypeSymlink, Linkname: "cm.yaml", Mode: 0777}))
+	require.NoError(t, tw.Close())
+
+	file, err := SpoolFile(t.TempDir(), &archive)
+	require.NoError(t, err)
+	fsys, err := TarFileFS(file, nil)
+	require.NoError(t, err)
+
+	data, err := fs.ReadFile(fsys, "manifests/hardlink.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	sanitized, err := SanitizeFS(fsys, SymlinkPolicyResolve)
+	require.NoError(t, err)
+	data, err = fs.ReadFile(sanitized, "manifests/symlink.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	require.NoError(t, fsys.Close())
+	_, err = fs.ReadFile(fsys, "manifests/cm.yaml")
+	require.ErrorContains(t, err, os.ErrClosed.Error())
+}
+
+func TestSpoolFile(t *testing.T) {
+	dir := t.TempDir()
+	file, err := SpoolFile(dir, bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"context"
 	"crypto/x509"
 	"encoding/pem"
@@ -8,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -18,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/resource"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -44,6 +47,13 @@ func BundleDeploymentProvisionerFilter(provisionerClassName string) predicate.Pr
 	})
 }
 
+// NamespaceFilter filters out the events of objects in other namespaces.
+func NamespaceFilter(namespace string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == namespace
+	})
+}
+
 type ProvisionerClassNameGetter interface {
 	client.Object
 	ProvisionerClassName() string
@@ -140,6 +150,102 @@ func MapConfigMapToBundleDeploymentHandler(cl client.Client, configMapNamespace
 	})
 }
 
+// SecretReferencesIndex is the field index of BundleDeployments by the names
+// of the secrets in the system namespace that they depend on.
+const SecretReferencesIndex = "core.rukpak.io/secret-references"
+
+// IndexSecretReferences registers SecretReferencesIndex with the indexer. It
+// must be registered once per manager, before the BundleDeployment controllers
+// start, since they map secret events through it.
+func IndexSecretReferences(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &rukpakv1alpha2.BundleDeployment{}, SecretReferencesIndex, func(obj client.Object) []string {
+		return secretReferences(obj.(*rukpakv1alpha2.BundleDeployment))
+	})
+}
+
+func secretReferences(b *rukpakv1alpha2.BundleDeployment) []string {
+	names := sets.New[string]()
+	if b.Spec.Target != nil {
+		names.Insert(b.Spec.Target.KubeconfigSecretRef.Name)
+	}
+	for _, secretSource := range b.Spec.Source.Secrets {
+		names.Insert(secretSource.Secret.Name)
+	}
+	return sets.List(names)
+}
+
+// MapSecretToBundleDeployment returns the BundleDeployments that depend on the
+// secret. It requires SecretReferencesIndex to be registered with the cache of
+// the client.
+func MapSecretToBundleDeployment(ctx context.Context, cl client.Client, secretNamespace string, secret client.Object) []*rukpakv1alpha2.BundleDeployment {
+	if secret.GetNamespace() != secretNamespace {
+		return nil
+	}
+	bundleDeploymentList := &rukpakv1alpha2.BundleDeploymentList{}
+	if err := cl.List(ctx, bundleDeploymentList, client.MatchingFields{SecretReferencesIndex: secret.GetName()}); err != nil {
+		return nil
+	}
+	bs := make([]*rukpakv1alpha2.BundleDeployment, 0, len(bundleDeploymentList.Items))
+	for i := range bundleDeploymentList.Items {
+		bs = append(bs, &bundleDeploymentList.Items[i])
+	}
+	return bs
+}
+
+// MapSecretToBundleDeploymentHandler enqueues the BundleDeployments of the
+// provisioner that depend on a secret. Secrets are only needed by their
+// metadata, so the handler is meant for metadata-only watches.
+func MapSecretToBundleDeploymentHandler(cl client.Client, secretNamespace string, provisionerClassName string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+		for _, b := range MapSecretToBundleDeployment(ctx, cl, secretNamespace, object) {
+			if b.Spec.ProvisionerClassName != provisionerClassName {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(b)})
+		}
+		return requests
+	})
+}
+
+// MapNamespaceToBundleDeploymentHandler enqueues the BundleDeployments of the
+// provisioner that install into a namespace.
+func MapNamespaceToBundleDeploymentHandler(cl client.Client, provisionerClassName string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		bundleDeploymentList := &rukpakv1alpha2.BundleDeploymentList{}
+		if err := cl.List(ctx, bundleDeploymentList); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, b := range bundleDeploymentList.Items {
+			if b.Spec.ProvisionerClassName != provisionerClassName || b.Spec.InstallNamespace != object.GetName() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&b)})
+		}
+		return requests
+	})
+}
+
+// MapToAllBundleDeploymentsHandler enqueues every BundleDeployment of the
+// provisioner for events of objects that all of them depend on.
+func MapToAllBundleDeploymentsHandler(cl client.Client, provisionerClassName string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		bundleDeploymentList := &rukpakv1alpha2.BundleDeploymentList{}
+		if err := cl.List(ctx, bundleDeploymentList); err != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, b := range bundleDeploymentList.Items {
+			if b.Spec.ProvisionerClassName != provisionerClassName {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&b)})
+		}
+		return requests
+	})
+}
+
 const (
 	// maxBundleNameLength must be aligned with the Bundle CRD metadata.name length validation, defined in:
 	// <repoRoot>/manifests/base/apis/crds/patches/bundle_validation.yaml
@@ -259,6 +365,46 @@ func MergeMaps(maps ...map[string]string) map[string]string {
 	return out
 }
 
+// ParseGroupKinds parses a comma-separated list of kinds in the Kind.group
+// format. Kinds of the core group are specified without a group.
+func ParseGroupKinds(s string) []schema.GroupKind {
+	var gks []schema.GroupKind
+	for _, gk := range strings.Split(s, ",") {
+		if gk = strings.TrimSpace(gk); gk != "" {
+			gks = append(gks, schema.ParseGroupKind(gk))
+		}
+	}
+	return gks
+}
+
+// ParseKindFields parses a comma-separated list of fields of kinds in the
+// Kind.group=/json/pointer format, e.g. "Deployment.apps=/spec/replicas".
+// Kinds of the core group are specified without a group. The fields are
+// JSON pointers as defined by RFC 6901, so "~1" stands for a "/" in a key,
+// and "~0" for a "~".
+func ParseKindFields(s string) (map[schema.GroupKind][][]string, error) {
+	fields := map[schema.GroupKind][][]string{}
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		kind, pointer, ok := strings.Cut(entry, "=")
+		if !ok || kind == "" {
+			return nil, fmt.Errorf("invalid field %q: must be in the Kind.group=/json/pointer format", entry)
+		}
+		if !strings.HasPrefix(pointer, "/") || pointer == "/" {
+			return nil, fmt.Errorf("invalid field %q: %q is not a JSON pointer to a field", entry, pointer)
+		}
+		var path []string
+		for _, key := range strings.Split(pointer[1:], "/") {
+			path = append(path, strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~"))
+		}
+		gk := schema.ParseGroupKind(kind)
+		fields[gk] = append(fields[gk], path)
+	}
+	return fields, nil
+}
+
 func LoadCertPool(certFile string) (*x509.CertPool, error) {
 	rootCAPEM, err := os.ReadFile(certFile)
 	if err != nil {
@@ -279,7 +425,14 @@ func LoadCertPool(certFile string) (*x509.CertPool, error) {
 }
 
 func ManifestObjects(r io.Reader, name string) ([]client.Object, error) {
-	result := resource.NewLocalBuilder().Flatten().Unstructured().Stream(r, name).Do()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = NormalizeManifest(data); err != nil {
+		return nil, fmt.Errorf("read %s: %v", name, err)
+	}
+	result := resource.NewLocalBuilder().Flatten().Unstructured().Stream(bytes.NewReader(data), name).Do()
 	if err := result.Err(); err != nil {
 		return nil, err
 	}
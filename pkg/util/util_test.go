@@ -0,0 +1,70 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rukpakv1alpha2 "github.com/operator-framework/rukpak/api/v1alpha2"
+)
+
+func TestParseKindFields(t *testing.T) {
+	fields, err := ParseKindFields("Deployment.apps=/spec/replicas, Service=/spec/clusterIP,*=/metadata/annotations/example.com~1team,Deployment.apps=/spec/paused")
+	require.NoError(t, err)
+	require.Equal(t, map[schema.GroupKind][][]string{
+		{Group: "apps", Kind: "Deployment"}: {{"spec", "replicas"}, {"spec", "paused"}},
+		{Kind: "Service"}:                   {{"spec", "clusterIP"}},
+		{Kind: "*"}:                         {{"metadata", "annotations", "example.com/team"}},
+	}, fields)
+
+	for _, s := range []string{"Deployment.apps", "=/spec", "Service=spec/clusterIP", "Service=/"} {
+		_, err := ParseKindFields(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestMapSecretToBundleDeployment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, rukpakv1alpha2.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithIndex(&rukpakv1alpha2.BundleDeployment{}, SecretReferencesIndex, func(obj client.Object) []string {
+		return secretReferences(obj.(*rukpakv1alpha2.BundleDeployment))
+	}).WithObjects(
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "source"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{Source: rukpakv1alpha2.BundleSource{
+				Type:    rukpakv1alpha2.SourceTypeSecrets,
+				Secrets: []rukpakv1alpha2.SecretSource{{Secret: corev1.LocalObjectReference{Name: "manifests"}}},
+			}},
+		},
+		&rukpakv1alpha2.BundleDeployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "target"},
+			Spec: rukpakv1alpha2.BundleDeploymentSpec{
+				Target: &rukpakv1alpha2.Target{KubeconfigSecretRef: rukpakv1alpha2.KubeconfigSecretReference{Name: "manifests"}},
+			},
+		},
+		&rukpakv1alpha2.BundleDeployment{ObjectMeta: metav1.ObjectMeta{Name: "unrelated"}},
+	).Build()
+
+	names := func(secret client.Object) []string {
+		var names []string
+		for _, b := range MapSecretToBundleDeployment(context.Background(), cl, "rukpak-system", secret) {
+			names = append(names, b.Name)
+		}
+		return names
+	}
+	secret := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "manifests"}}
+	require.ElementsMatch(t, []string{"source", "target"}, names(secret))
+
+	secret.Namespace = "default"
+	require.Empty(t, names(secret))
+
+	secret = &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: "rukpak-system", Name: "other"}}
+	require.Empty(t, names(secret))
+}
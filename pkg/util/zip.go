@@ -0,0 +1,102 @@
+package util
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+// ZipToFS reads the zip archive from r into an in-memory filesystem, in the
+// same way as TarToFS. Symbolic links are preserved, and the returned
+// filesystem implements ReadLinkFS so that they can be resolved by SanitizeFS.
+func ZipToFS(r io.ReaderAt, size int64) (fs.FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("read zip archive: %v", err)
+	}
+	zfs := &tarFS{MapFS: fstest.MapFS{}, links: map[string]string{}}
+	for _, f := range zr.File {
+		name := path.Clean(strings.TrimPrefix(f.Name, "/"))
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("zip archive entry %q: path is outside of the archive root", f.Name)
+		}
+		file := &fstest.MapFile{Mode: f.Mode(), ModTime: f.Modified}
+		if !f.Mode().IsDir() {
+			if file.Data, err = readZipFile(f); err != nil {
+				return nil, fmt.Errorf("read zip archive entry %q: %v", f.Name, err)
+			}
+		}
+		if f.Mode()&fs.ModeSymlink != 0 {
+			zfs.links[name] = string(file.Data)
+		}
+		zfs.MapFS[name] = file
+	}
+	return zfs, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ZipFileFS returns a filesystem of the zip archive in file that reads the
+// content of files from it when they are read, rather than into memory like
+// ZipToFS. Symbolic links can be resolved through ReadLinkFS. Closing the
+// filesystem closes file, and file is closed if an error is returned.
+func ZipFileFS(file *os.File) (ClosableFS, error) {
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read zip archive: %v", err)
+	}
+	for _, f := range zr.File {
+		// zip.Reader drops the leading ".." elements of paths instead of
+		// rejecting them.
+		if name := path.Clean(strings.TrimPrefix(f.Name, "/")); name != "." && !fs.ValidPath(name) {
+			file.Close()
+			return nil, fmt.Errorf("zip archive entry %q: path is outside of the archive root", f.Name)
+		}
+	}
+	return &zipFS{Reader: zr, file: file}, nil
+}
+
+type zipFS struct {
+	*zip.Reader
+	file *os.File
+}
+
+func (z *zipFS) ReadLink(name string) (string, error) {
+	info, err := fs.Stat(z.Reader, name)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := fs.ReadFile(z.Reader, name)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(target), nil
+}
+
+func (z *zipFS) Close() error {
+	return z.file.Close()
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipFileFS(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		files     map[string]string
+		expectErr string
+	}{
+		{
+			name:  "files and symbolic links",
+			files: map[string]string{"manifests/cm.yaml": "data", "manifests/symlink.yaml": "@cm.yaml"},
+		},
+		{
+			name:      "path outside of the archive root",
+			files:     map[string]string{"../cm.yaml": "data"},
+			expectErr: "path is outside of the archive root",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var archive bytes.Buffer
+			zw := zip.NewWriter(&archive)
+			for name, content := range tc.files {
+				h := &zip.FileHeader{Name: name}
+				h.SetMode(0644)
+				if content[0] == '@' {
+					h.SetMode(fs.ModeSymlink | 0777)
+					content = content[1:]
+				}
+				w, err := zw.CreateHeader(h)
+				require.NoError(t, err)
+				_, err = w.Write([]byte(content))
+				require.NoError(t, err)
+			}
+			require.NoError(t, zw.Close())
+
+			file, err := SpoolFile(t.TempDir(), &archive)
+			require.NoError(t, err)
+			fsys, err := ZipFileFS(file)
+			if tc.expectErr != "" {
+				require.ErrorContains(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			sanitized, err := SanitizeFS(fsys, SymlinkPolicyResolve)
+			require.NoError(t, err)
+			data, err := fs.ReadFile(sanitized, "manifests/symlink.yaml")
+			require.NoError(t, err)
+			require.Equal(t, "data", string(data))
+
+			require.NoError(t, fsys.Close())
+			_, err = fs.ReadFile(fsys, "manifests/cm.yaml")
+			require.ErrorIs(t, err, os.ErrClosed)
+		})
+	}
+}
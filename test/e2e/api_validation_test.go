@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
@@ -284,4 +285,66 @@ var _ = Describe("bundle api validation", func() {
 			))
 		})
 	})
+	When("a BundleDeployment uses an inline source", func() {
+		var (
+			bd  *rukpakv1alpha2.BundleDeployment
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			bd = &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("bd-inline-%s", rand.String(6)),
+				},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "default",
+					ProvisionerClassName: plain.ProvisionerID,
+					Source: rukpakv1alpha2.BundleSource{
+						Type: rukpakv1alpha2.SourceTypeInline,
+						Inline: &rukpakv1alpha2.InlineSource{
+							Manifests: []string{"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: inline\n"},
+						},
+					},
+				},
+			}
+		})
+		AfterEach(func() {
+			By("deleting the testing BundleDeployment resource")
+			Expect(client.IgnoreNotFound(c.Delete(ctx, bd))).To(Succeed())
+		})
+		It("should be admitted", func() {
+			Expect(c.Create(ctx, bd)).To(Succeed())
+		})
+	})
+	When("a BundleDeployment uses a secrets source", func() {
+		var (
+			bd  *rukpakv1alpha2.BundleDeployment
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			bd = &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("bd-secrets-%s", rand.String(6)),
+				},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "default",
+					ProvisionerClassName: plain.ProvisionerID,
+					Source: rukpakv1alpha2.BundleSource{
+						Type: rukpakv1alpha2.SourceTypeSecrets,
+						Secrets: []rukpakv1alpha2.SecretSource{{
+							Secret: corev1.LocalObjectReference{Name: "bundle-manifests"},
+						}},
+					},
+				},
+			}
+		})
+		AfterEach(func() {
+			By("deleting the testing BundleDeployment resource")
+			Expect(client.IgnoreNotFound(c.Delete(ctx, bd))).To(Succeed())
+		})
+		It("should be admitted", func() {
+			Expect(c.Create(ctx, bd)).To(Succeed())
+		})
+	})
 })
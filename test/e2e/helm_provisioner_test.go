@@ -512,4 +512,82 @@ var _ = Describe("helm provisioner bundledeployment", func() {
 			))
 		})
 	})
+	When("a BundleDeployment targets a chart in a private chart repository", func() {
+		var (
+			bd  *rukpakv1alpha2.BundleDeployment
+			ctx context.Context
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+
+			By("reading the certificate authority of the chart repository")
+			caSecret := &corev1.Secret{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "chartmuseum-tls", Namespace: "rukpak-e2e"}, caSecret)).To(Succeed())
+
+			bd = &rukpakv1alpha2.BundleDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "ahoy-",
+				},
+				Spec: rukpakv1alpha2.BundleDeploymentSpec{
+					InstallNamespace:     "default",
+					ProvisionerClassName: helm.ProvisionerID,
+					Source: rukpakv1alpha2.BundleSource{
+						Type: rukpakv1alpha2.SourceTypeHTTP,
+						HTTP: &rukpakv1alpha2.HTTPSource{
+							URL:             "https://chartmuseum.rukpak-e2e.svc.cluster.local:8443/charts/hello-world-0.1.0.tgz",
+							CertificateData: string(caSecret.Data["ca.crt"]),
+							Auth: rukpakv1alpha2.Authorization{
+								Secret: corev1.LocalObjectReference{Name: "chartmuseum-auth"},
+							},
+						},
+					},
+				},
+			}
+			err := c.Create(ctx, bd)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			By("deleting the testing resources")
+			Expect(c.Delete(ctx, bd)).To(Succeed())
+		})
+
+		It("should rollout the bundle contents successfully", func() {
+			By("eventually writing a successful installation state back to the bundledeployment status")
+			Eventually(func() (*metav1.Condition, error) {
+				if err := c.Get(ctx, client.ObjectKeyFromObject(bd), bd); err != nil {
+					return nil, err
+				}
+				return meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeInstalled), nil
+			}).Should(And(
+				Not(BeNil()),
+				WithTransform(func(c *metav1.Condition) metav1.ConditionStatus { return c.Status }, Equal(metav1.ConditionTrue)),
+				WithTransform(func(c *metav1.Condition) string { return c.Reason }, Equal(rukpakv1alpha2.ReasonInstallationSucceeded)),
+			))
+		})
+
+		When("the certificate authority of the chart repository is not trusted", func() {
+			BeforeEach(func() {
+				Eventually(func() error {
+					if err := c.Get(ctx, client.ObjectKeyFromObject(bd), bd); err != nil {
+						return err
+					}
+					bd.Spec.Source.HTTP.CertificateData = ""
+					return c.Update(ctx, bd)
+				}).Should(Succeed())
+			})
+
+			It("should fail to unpack the bundle", func() {
+				Eventually(func() (*metav1.Condition, error) {
+					if err := c.Get(ctx, client.ObjectKeyFromObject(bd), bd); err != nil {
+						return nil, err
+					}
+					return meta.FindStatusCondition(bd.Status.Conditions, rukpakv1alpha2.TypeUnpacked), nil
+				}).Should(And(
+					Not(BeNil()),
+					WithTransform(func(c *metav1.Condition) metav1.ConditionStatus { return c.Status }, Equal(metav1.ConditionFalse)),
+					WithTransform(func(c *metav1.Condition) string { return c.Message }, ContainSubstring("certificate signed by unknown authority")),
+				))
+			})
+		})
+	})
 })
@@ -0,0 +1,135 @@
+#! /bin/bash
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+help="
+chart-museum-secure.sh is a script to stand up a chart repository within a cluster that is served over TLS with a
+self-signed certificate and requires basic authentication.
+Usage:
+  chart-museum-secure.sh [NAMESPACE] [NAME]
+
+Argument Descriptions:
+  - NAMESPACE is the namespace that should be created and is the namespace in which the chart repository will be created
+  - NAME is the name that should be used for the chart repository Deployment and Service
+"
+
+if [[ "$#" -ne 2 ]]; then
+  echo "Illegal number of arguments passed"
+  echo "${help}"
+  exit 1
+fi
+
+namespace=$1
+name=$2
+
+kubectl apply -f - << EOF
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ${namespace}
+---
+apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata:
+  name: selfsigned-issuer
+  namespace: ${namespace}
+spec:
+  selfSigned: {}
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: ${name}
+  namespace: ${namespace}
+spec:
+  secretName: ${name}-tls
+  isCA: true
+  dnsNames:
+    - ${name}.${namespace}.svc
+    - ${name}.${namespace}.svc.cluster.local
+  privateKey:
+    algorithm: ECDSA
+    size: 256
+  issuerRef:
+    name: selfsigned-issuer
+    kind: Issuer
+    group: cert-manager.io
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ${name}
+  namespace: ${namespace}
+  labels:
+    app: ${name}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: ${name}
+  template:
+    metadata:
+      labels:
+        app: ${name}
+    spec:
+      initContainers:
+      - name: charts
+        image: curlimages/curl:8.9.1
+        command:
+        - "curl"
+        - "-fsSL"
+        - "-o"
+        - "/charts/hello-world-0.1.0.tgz"
+        - "https://github.com/helm/examples/releases/download/hello-world-0.1.0/hello-world-0.1.0.tgz"
+        terminationMessagePolicy: FallbackToLogsOnError
+        volumeMounts:
+        - name: charts-vol
+          mountPath: "/charts"
+      containers:
+      - name: chartmuseum
+        image: ghcr.io/helm/chartmuseum:v0.16.2
+        volumeMounts:
+        - name: certs-vol
+          mountPath: "/certs"
+          readOnly: true
+        - name: charts-vol
+          mountPath: "/charts"
+        env:
+        - name: PORT
+          value: "8443"
+        - name: STORAGE
+          value: "local"
+        - name: STORAGE_LOCAL_ROOTDIR
+          value: "/charts"
+        - name: TLS_CERT
+          value: "/certs/tls.crt"
+        - name: TLS_KEY
+          value: "/certs/tls.key"
+        - name: BASIC_AUTH_USER
+          value: "myuser"
+        - name: BASIC_AUTH_PASS
+          value: "mypasswd"
+      volumes:
+        - name: certs-vol
+          secret:
+            secretName: ${name}-tls
+        - name: charts-vol
+          emptyDir: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: ${name}
+  namespace: ${namespace}
+spec:
+  selector:
+    app: ${name}
+  ports:
+  - port: 8443
+    targetPort: 8443
+EOF
+
+kubectl create secret generic "${name}-auth" --type "kubernetes.io/basic-auth" --from-literal=username="myuser" --from-literal=password="mypasswd" -n rukpak-system
+kubectl wait --for=condition=Available -n "${namespace}" "deploy/${name}" --timeout=60s